@@ -1,15 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"smrtmart-go-postgresql/internal/api"
 	"smrtmart-go-postgresql/internal/config"
 	"smrtmart-go-postgresql/internal/database"
+	"smrtmart-go-postgresql/internal/health"
+	"smrtmart-go-postgresql/internal/lifecycle"
+	"smrtmart-go-postgresql/internal/logging"
+	"smrtmart-go-postgresql/internal/metrics"
 	"smrtmart-go-postgresql/internal/middleware"
 	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/search"
 	"smrtmart-go-postgresql/internal/service"
+	"smrtmart-go-postgresql/internal/tracing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -46,18 +60,19 @@ func main() {
 
 	// Load configuration
 	cfg := config.Load()
+	logging.Setup(cfg.Log.Format, cfg.Log.Level)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatal("Failed to set up tracing:", err)
+	}
 
 	// Initialize database
 	db, err := database.Initialize(cfg.Database)
 	if err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
-	defer db.Close()
-
-	// Run migrations
-	if err := database.RunMigrations(cfg.Database); err != nil {
-		log.Fatal("Failed to run migrations:", err)
-	}
+	metrics.RegisterDB(db)
 
 	// Initialize repositories
 	repos := repository.NewRepositories(db)
@@ -65,6 +80,28 @@ func main() {
 	// Initialize services
 	services := service.NewServices(repos, cfg)
 
+	// Readiness checks; the server reports not ready until everything has started
+	latestMigration, err := database.LatestMigration()
+	if err != nil {
+		log.Fatal("Failed to read migrations:", err)
+	}
+	checker := health.NewChecker(cfg.Health.CheckTimeout)
+	checker.Add("database", true, health.Database(db))
+	checker.Add("migrations", true, health.Migrations(db, latestMigration))
+	checker.Add("migrations_clean", true, health.MigrationsClean(db))
+	for _, name := range cfg.Health.Checks {
+		switch name {
+		case "redis":
+			checker.Add(name, false, health.Redis(net.JoinHostPort(cfg.Redis.Host, cfg.Redis.Port), cfg.Redis.Password))
+		case "storage":
+			checker.Add(name, false, health.Storage(cfg.Upload.Path))
+		case "smtp":
+			checker.Add(name, false, health.SMTP(cfg.Email.SMTPHost, cfg.Email.SMTPPort))
+		default:
+			log.Printf("Unknown health check %q ignored", name)
+		}
+	}
+
 	// Initialize Gin router
 	if cfg.Server.Mode == "release" {
 		gin.SetMode(gin.ReleaseMode)
@@ -73,25 +110,157 @@ func main() {
 	router := gin.New()
 	
 	// Add middleware
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
+	router.Use(middleware.Logger())
+	router.Use(middleware.Metrics())
+	router.Use(middleware.Recovery())
+	// Until everything has started, e.g. while migrations run, only the probes answer
+	router.Use(middleware.StartupGate(func() bool { return checker.State() != health.StateStarting }, "/livez", "/readyz"))
 	router.Use(middleware.CORS(cfg.Server.CORSOrigins))
 	router.Use(middleware.SecurityHeaders())
 	router.Use(middleware.RateLimit())
 
 	// Setup routes
-	api.SetupRoutes(router, services, cfg)
+	api.SetupRoutes(router, services, cfg, checker)
 
-	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	server := &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 	}
 
-	log.Printf("🚀 SmrtMart API server starting on port %s", port)
-	log.Printf("📚 API Documentation: http://localhost:%s/swagger/index.html", port)
-	
-	if err := router.Run(":" + port); err != nil {
+	// Subsystems start in this order and stop in reverse. The listener comes up
+	// first so probes answer while migrations run, but the API stays closed until
+	// the server is marked ready once everything has started. On shutdown it is
+	// marked not ready, keeps serving for the drain delay and is then drained
+	// before the background work stops. The database closes last, then buffered
+	// spans are flushed.
+	app := lifecycle.New()
+	app.Add("tracing", nil, shutdownTracing)
+	app.Add("database", nil, func(context.Context) error { return db.Close() })
+
+	serveErr := make(chan error, 1)
+	app.Add("http listener",
+		func(context.Context) error {
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					serveErr <- err
+				}
+			}()
+
+			log.Printf("🚀 SmrtMart API server starting on port %s", cfg.Server.Port)
+			log.Printf("📚 API Documentation: http://localhost:%s/swagger/index.html", cfg.Server.Port)
+			return nil
+		},
+		// Drops connections that did not drain in time
+		func(context.Context) error { return server.Close() },
+	)
+
+	app.Add("migrations", func(context.Context) error {
+		if err := database.RunMigrations(cfg.Database); err != nil {
+			return err
+		}
+
+		// The configured text-search language only seeds the setting; once it is
+		// set, changes go through the admin endpoint. If seeding changed it, the
+		// stored search vectors are refreshed by background jobs.
+		if cfg.Search.Language != "" {
+			if changed, err := services.Product.SeedSearchLanguage(context.Background(), cfg.Search.Language); err != nil {
+				log.Printf("Failed to seed search language: %v", err)
+			} else if changed {
+				log.Printf("Search language seeded as %s, refreshing search vectors in the background", cfg.Search.Language)
+			}
+		}
+
+		// The embedded search index lives in memory and must be loaded on startup
+		if cfg.Search.Backend == search.BackendMemory {
+			count, err := services.Product.RebuildSearchIndex(context.Background())
+			if err != nil {
+				return fmt.Errorf("failed to build search index: %w", err)
+			}
+			log.Printf("Indexed %d products in memory search index", count)
+		}
+		return nil
+	}, nil)
+
+	// The embedded search index follows product changes made by other processes
+	if cfg.Search.Backend == search.BackendMemory {
+		app.Go("search index sync", func(ctx context.Context) {
+			services.Product.RunSearchIndexSync(ctx, cfg.Search.SyncInterval)
+		})
+	}
+
+	// Record searches in the query log behind the requests that made them
+	app.Go("search query recorder", func(ctx context.Context) {
+		services.Product.RunQueryRecorder(ctx)
+	})
+
+	// Start and end scheduled sale prices in the background
+	app.Go("sale scheduler", func(ctx context.Context) {
+		services.Pricing.RunSaleScheduler(ctx, cfg.Pricing.SaleCheckInterval)
+	})
+
+	// Poll carriers for tracking events of shipments on their way
+	app.Go("tracking poller", func(ctx context.Context) {
+		services.Shipment.RunTrackingPoller(ctx, cfg.Carrier.TrackingInterval)
+	})
+
+	// Fan new domain events out to their subscribers as jobs
+	app.Go("event dispatcher", func(ctx context.Context) {
+		services.Event.RunDispatcher(ctx, cfg.Events.DispatchInterval)
+	})
+
+	// Run background jobs in this process unless they are left to cmd/worker
+	app.Go("job workers", func(ctx context.Context) {
+		services.Job.Run(ctx, cfg.Jobs.Workers)
+	})
+
+	app.Add("http server",
+		func(context.Context) error {
+			checker.SetState(health.StateReady)
+			return nil
+		},
+		func(ctx context.Context) error {
+			checker.SetState(health.StateStopping)
+
+			// Keep serving until load balancers have seen the failing readiness probe
+			select {
+			case <-time.After(cfg.Server.DrainDelay):
+			case <-ctx.Done():
+			}
+			return server.Shutdown(ctx)
+		},
+	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.Start(ctx); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
-}
\ No newline at end of file
+
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down")
+	case err := <-serveErr:
+		log.Printf("Server failed, shutting down: %v", err)
+	}
+	// A second signal kills the process without waiting
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := app.Stop(shutdownCtx); err != nil {
+		log.Printf("Shutdown incomplete: %v", err)
+		os.Exit(1)
+	}
+	log.Println("Server stopped")
+}
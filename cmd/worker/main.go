@@ -0,0 +1,56 @@
+// Command worker runs background jobs without serving HTTP, so job capacity can
+// be scaled apart from the API. Set JOB_WORKERS=0 on the API when running it.
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/database"
+	"smrtmart-go-postgresql/internal/logging"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/service"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	cfg := config.Load()
+	logging.Setup(cfg.Log.Format, cfg.Log.Level)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatal("Failed to set up tracing:", err)
+	}
+	defer shutdownTracing(context.Background())
+	if cfg.Jobs.Workers <= 0 {
+		log.Fatal("JOB_WORKERS must be greater than zero")
+	}
+
+	db, err := database.Initialize(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	defer db.Close()
+
+	if err := database.RunMigrations(cfg.Database); err != nil {
+		log.Fatal("Failed to run migrations:", err)
+	}
+
+	services := service.NewServices(repository.NewRepositories(db), cfg)
+
+	// Stop claiming jobs on SIGINT or SIGTERM and let the running ones finish
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	services.Job.Run(ctx, cfg.Jobs.Workers)
+	log.Println("Job workers stopped")
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthHandler handles authentication endpoints
+type AuthHandler struct {
+	service service.AuthService
+}
+
+func NewAuthHandler(service service.AuthService) *AuthHandler {
+	return &AuthHandler{service: service}
+}
+
+type loginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type tokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Register creates a customer account and emails a verification link
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req service.RegisterRequest
+	if !bindAuthRequest(c, &req) {
+		return
+	}
+
+	user, err := h.service.Register(c.Request.Context(), req, requestLanguage(c))
+	if err != nil {
+		respondAuthError(c, err, "Failed to register")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Account created, check your email to verify it",
+		Data:    user,
+	})
+}
+
+// Login exchanges an email and password for an access token
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req loginRequest
+	if !bindAuthRequest(c, &req) {
+		return
+	}
+
+	result, err := h.service.Login(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		respondAuthError(c, err, "Failed to log in")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Logged in successfully",
+		Data:    result,
+	})
+}
+
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "Refresh token endpoint - TODO"})
+}
+
+// VerifyEmail confirms an account's email address with the token from its verification link
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req tokenRequest
+	if !bindAuthRequest(c, &req) {
+		return
+	}
+
+	if err := h.service.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		respondAuthError(c, err, "Failed to verify email")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Email verified",
+	})
+}
+
+// ForgotPassword emails a password reset link. The response is the same whether
+// or not the address is registered.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if !bindAuthRequest(c, &req) {
+		return
+	}
+
+	if err := h.service.ForgotPassword(c.Request.Context(), req.Email, requestLanguage(c)); err != nil {
+		respondAuthError(c, err, "Failed to send password reset")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "If the address is registered, a password reset link has been sent",
+	})
+}
+
+// ResetPassword sets a new password with the token from a password reset link
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if !bindAuthRequest(c, &req) {
+		return
+	}
+
+	if err := h.service.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		respondAuthError(c, err, "Failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Password reset",
+	})
+}
+
+func bindAuthRequest(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			},
+		})
+		return false
+	}
+	return true
+}
+
+// requestLanguage is the client's preferred language, or empty to use the default
+func requestLanguage(c *gin.Context) string {
+	lang, _, _ := strings.Cut(c.GetHeader("Accept-Language"), ",")
+	lang, _, _ = strings.Cut(lang, ";")
+	return strings.TrimSpace(lang)
+}
+
+func respondAuthError(c *gin.Context, err error, message string) {
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch err.Error() {
+	case "invalid email or password":
+		status, code = http.StatusUnauthorized, "INVALID_CREDENTIALS"
+	case "account is not active":
+		status, code = http.StatusForbidden, "ACCOUNT_INACTIVE"
+	case "email already registered":
+		status, code = http.StatusConflict, "EMAIL_IN_USE"
+	case "invalid or expired token":
+		status, code = http.StatusBadRequest, "INVALID_TOKEN"
+	case "invalid email address", "name is required", "password too short", "password too long":
+		status, code = http.StatusBadRequest, "INVALID_REQUEST"
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+}
@@ -1,94 +1,364 @@
-package api
-
-import (
-	"net/http"
-
-	"smrtmart-go-postgresql/internal/models"
-	"smrtmart-go-postgresql/internal/service"
-
-	"github.com/gin-gonic/gin"
-)
-
-type CategoryHandler struct {
-	service service.CategoryService
-}
-
-func NewCategoryHandler(service service.CategoryService) *CategoryHandler {
-	return &CategoryHandler{service: service}
-}
-
-// GetCategories godoc
-// @Summary Get all categories
-// @Description Get a list of all active categories
-// @Tags categories
-// @Accept json
-// @Produce json
-// @Success 200 {object} models.APIResponse
-// @Failure 500 {object} models.APIResponse
-// @Router /categories [get]
-func (h *CategoryHandler) GetCategories(c *gin.Context) {
-	categories, err := h.service.GetAll()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Message: "Failed to get categories",
-			Error: &models.APIError{
-				Code:    "INTERNAL_ERROR",
-				Message: err.Error(),
-			},
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Categories retrieved successfully",
-		Data:    categories,
-	})
-}
-
-// GetCategory godoc
-// @Summary Get a category by ID
-// @Description Get details of a specific category
-// @Tags categories
-// @Accept json
-// @Produce json
-// @Param id path string true "Category ID"
-// @Success 200 {object} models.APIResponse
-// @Failure 404 {object} models.APIResponse
-// @Failure 500 {object} models.APIResponse
-// @Router /categories/{id} [get]
-func (h *CategoryHandler) GetCategory(c *gin.Context) {
-	id := c.Param("id")
-
-	category, err := h.service.GetByID(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success: false,
-			Message: "Category not found",
-			Error: &models.APIError{
-				Code:    "NOT_FOUND",
-				Message: err.Error(),
-			},
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Message: "Category retrieved successfully",
-		Data:    category,
-	})
-}
-
-func (h *CategoryHandler) CreateCategory(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Create category endpoint - TODO"})
-}
-
-func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Update category endpoint - TODO"})
-}
-
-func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Delete category endpoint - TODO"})
-}
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CategoryHandler struct {
+	service service.CategoryService
+}
+
+func NewCategoryHandler(service service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{service: service}
+}
+
+// GetCategories godoc
+// @Summary Get all categories
+// @Description Get a list of all active categories
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /categories [get]
+func (h *CategoryHandler) GetCategories(c *gin.Context) {
+	categories, err := h.service.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get categories",
+			Error: &models.APIError{
+				Code:    "INTERNAL_ERROR",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Categories retrieved successfully",
+		Data:    categories,
+	})
+}
+
+// GetCategoryTree godoc
+// @Summary Get the category tree
+// @Description Get all active categories nested under their parent categories
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse{data=[]models.Category}
+// @Failure 500 {object} models.APIResponse
+// @Router /categories/tree [get]
+func (h *CategoryHandler) GetCategoryTree(c *gin.Context) {
+	tree, err := h.service.GetTree(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get category tree",
+			Error: &models.APIError{
+				Code:    "INTERNAL_ERROR",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Category tree retrieved successfully",
+		Data:    tree,
+	})
+}
+
+// GetCategory godoc
+// @Summary Get a category by ID or slug
+// @Description Get details of a specific category
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID or slug"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /categories/{id} [get]
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	id := c.Param("id")
+
+	category, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondCategoryError(c, err, "Failed to get category")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Category retrieved successfully",
+		Data:    category,
+	})
+}
+
+// GetCategoryBreadcrumbs godoc
+// @Summary Get category breadcrumbs
+// @Description Get the path from the root category down to the given category
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID or slug"
+// @Success 200 {object} models.APIResponse{data=[]models.Category}
+// @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /categories/{id}/breadcrumbs [get]
+func (h *CategoryHandler) GetCategoryBreadcrumbs(c *gin.Context) {
+	breadcrumbs, err := h.service.GetBreadcrumbs(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondCategoryError(c, err, "Failed to get category breadcrumbs")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Category breadcrumbs retrieved successfully",
+		Data:    breadcrumbs,
+	})
+}
+
+// CategoryRequest is the payload for creating or updating a category
+type CategoryRequest struct {
+	Name        string     `json:"name" binding:"required"`
+	Slug        string     `json:"slug,omitempty"` // Generated from the name if empty
+	Description *string    `json:"description,omitempty"`
+	Image       *string    `json:"image,omitempty"`
+	ParentID    *uuid.UUID `json:"parent_id,omitempty"`
+	SortOrder   int        `json:"sort_order"`
+	IsActive    *bool      `json:"is_active,omitempty"` // Defaults to true on create; kept on update when omitted
+}
+
+// toModel builds the category to save; IsActive defaults to true for a new category
+func (r CategoryRequest) toModel() *models.Category {
+	category := &models.Category{
+		Name:        r.Name,
+		Slug:        r.Slug,
+		Description: r.Description,
+		Image:       r.Image,
+		ParentID:    r.ParentID,
+		SortOrder:   r.SortOrder,
+		IsActive:    true,
+	}
+	if r.IsActive != nil {
+		category.IsActive = *r.IsActive
+	}
+	return category
+}
+
+// CreateCategory godoc
+// @Summary Create a category (Admin only)
+// @Description Create a category; the slug is generated from the name when omitted and made unique
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param category body CategoryRequest true "Category data"
+// @Success 201 {object} models.APIResponse{data=models.Category}
+// @Failure 400 {object} models.APIResponse
+// @Router /admin/categories [post]
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	category := req.toModel()
+	if err := h.service.Create(c.Request.Context(), category); err != nil {
+		respondCategoryWriteError(c, err, "Failed to create category", "CREATION_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Category created successfully",
+		Data:    category,
+	})
+}
+
+// UpdateCategory godoc
+// @Summary Update a category (Admin only)
+// @Description Update a category; moving it under one of its own subcategories is refused
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Param category body CategoryRequest true "Category data"
+// @Success 200 {object} models.APIResponse{data=models.Category}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/categories/{id} [put]
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid category ID",
+			Error: &models.APIError{
+				Code:    "INVALID_ID",
+				Message: "Category ID must be a valid UUID",
+			},
+		})
+		return
+	}
+
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	category := req.toModel()
+	category.ID = id
+	if err := h.service.Update(c.Request.Context(), category, req.IsActive); err != nil {
+		respondCategoryWriteError(c, err, "Failed to update category", "UPDATE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Category updated successfully",
+		Data:    category,
+	})
+}
+
+// ReorderCategories godoc
+// @Summary Reorder categories (Admin only)
+// @Description Set the sort order of several categories in one transaction
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param order body object{items=[]models.CategorySortOrder} true "New sort orders"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/categories/reorder [put]
+func (h *CategoryHandler) ReorderCategories(c *gin.Context) {
+	var req struct {
+		Items []models.CategorySortOrder `json:"items" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	if err := h.service.Reorder(c.Request.Context(), req.Items); err != nil {
+		respondCategoryWriteError(c, err, "Failed to reorder categories", "UPDATE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Categories reordered successfully",
+	})
+}
+
+// DeleteCategory godoc
+// @Summary Delete a category (Admin only)
+// @Description Delete a category. If it still has products or subcategories the request is refused unless reassign_to names a category to move them to.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Param reassign_to query string false "Category ID to move products and subcategories to"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/categories/{id} [delete]
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	if err := h.service.Delete(c.Request.Context(), c.Param("id"), c.Query("reassign_to")); err != nil {
+		respondCategoryWriteError(c, err, "Failed to delete category", "DELETION_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Category deleted successfully",
+	})
+}
+
+func respondCategoryWriteError(c *gin.Context, err error, message, code string) {
+	switch {
+	case err.Error() == "category not found":
+		respondCategoryError(c, err, message)
+	case strings.HasPrefix(err.Error(), "category is in use"):
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: message,
+			Error: &models.APIError{
+				Code:    "CATEGORY_IN_USE",
+				Message: err.Error(),
+			},
+		})
+	default:
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: message,
+			Error: &models.APIError{
+				Code:    code,
+				Message: err.Error(),
+			},
+		})
+	}
+}
+
+func respondCategoryError(c *gin.Context, err error, message string) {
+	if err.Error() == "category not found" {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Category not found",
+			Error: &models.APIError{
+				Code:    "NOT_FOUND",
+				Message: "Category with the specified ID or slug does not exist",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		},
+	})
+}
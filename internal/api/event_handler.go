@@ -0,0 +1,105 @@
+package api
+
+import (
+	"net/http"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventHandler handles the domain event log
+type EventHandler struct {
+	service service.EventService
+}
+
+func NewEventHandler(service service.EventService) *EventHandler {
+	return &EventHandler{service: service}
+}
+
+// GetEvents godoc
+// @Summary List domain events (Admin only)
+// @Description Get domain events, newest first, optionally of one type or aggregate
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param type query string false "Only events of this type, e.g. order.status_changed"
+// @Param aggregate_type query string false "Only events about this kind of aggregate, e.g. product"
+// @Param aggregate_id query string false "Only events about this aggregate"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Router /admin/events [get]
+func (h *EventHandler) GetEvents(c *gin.Context) {
+	var eventType *models.EventType
+	if t := c.Query("type"); t != "" {
+		value := models.EventType(t)
+		eventType = &value
+	}
+	var aggregateType, aggregateID *string
+	if t := c.Query("aggregate_type"); t != "" {
+		aggregateType = &t
+	}
+	if id := c.Query("aggregate_id"); id != "" {
+		aggregateID = &id
+	}
+
+	page, limit := pageParams(c)
+	result, err := h.service.GetEvents(c.Request.Context(), eventType, aggregateType, aggregateID, page, limit)
+	if err != nil {
+		respondEventError(c, err, "Failed to get events")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Events retrieved successfully",
+		Data:    result,
+	})
+}
+
+// GetEvent godoc
+// @Summary Get a domain event (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Event ID"
+// @Success 200 {object} models.APIResponse{data=models.DomainEvent}
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/events/{id} [get]
+func (h *EventHandler) GetEvent(c *gin.Context) {
+	id, ok := parseIDParam(c, "event")
+	if !ok {
+		return
+	}
+
+	event, err := h.service.GetEvent(c.Request.Context(), id)
+	if err != nil {
+		respondEventError(c, err, "Failed to get event")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Event retrieved successfully",
+		Data:    event,
+	})
+}
+
+func respondEventError(c *gin.Context, err error, message string) {
+	msg := err.Error()
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	if msg == "event not found" {
+		status, code = http.StatusNotFound, "NOT_FOUND"
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    code,
+			Message: msg,
+		},
+	})
+}
@@ -0,0 +1,402 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/middleware"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GiftCardHandler handles gift card and store credit endpoints
+type GiftCardHandler struct {
+	service service.GiftCardService
+}
+
+func NewGiftCardHandler(service service.GiftCardService) *GiftCardHandler {
+	return &GiftCardHandler{service: service}
+}
+
+// IssueGiftCardRequest is the payload for issuing a gift card
+type IssueGiftCardRequest struct {
+	Code           string     `json:"code,omitempty"` // Generated when empty
+	InitialBalance float64    `json:"initial_balance" binding:"required,gt=0"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	RecipientEmail *string    `json:"recipient_email,omitempty" binding:"omitempty,email"`
+	Message        *string    `json:"message,omitempty"`
+}
+
+// GiftCardStatusRequest is the payload for enabling or disabling a gift card
+type GiftCardStatusRequest struct {
+	Status models.GiftCardStatus `json:"status" binding:"required"`
+}
+
+// BalanceAdjustmentRequest is the payload for changing a gift card or store credit balance
+type BalanceAdjustmentRequest struct {
+	Amount    float64 `json:"amount" binding:"required"` // Negative to take balance away
+	Reference *string `json:"reference,omitempty"`       // E.g. the returned order, for store credit
+	Note      *string `json:"note,omitempty"`
+}
+
+// GiftCardBalanceRequest is the payload for checking a gift card balance
+type GiftCardBalanceRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// CheckBalance godoc
+// @Summary Check gift card balance
+// @Description Get the remaining balance and expiry of a gift card
+// @Tags gift-cards
+// @Accept json
+// @Produce json
+// @Param request body GiftCardBalanceRequest true "Gift card code"
+// @Success 200 {object} models.APIResponse{data=models.GiftCardBalance}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /gift-cards/balance [post]
+func (h *GiftCardHandler) CheckBalance(c *gin.Context) {
+	var req GiftCardBalanceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	balance, err := h.service.CheckBalance(c.Request.Context(), req.Code)
+	if err != nil {
+		respondGiftCardError(c, err, "Failed to check gift card balance")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Gift card balance retrieved successfully",
+		Data:    balance,
+	})
+}
+
+// GetMyStoreCredit godoc
+// @Summary Get my store credit
+// @Description Get the authenticated user's store credit balance and transactions, newest first
+// @Tags gift-cards
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.StoreCreditAccount}
+// @Failure 401 {object} models.APIResponse
+// @Router /store-credit [get]
+func (h *GiftCardHandler) GetMyStoreCredit(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	page, limit := pageParams(c)
+	account, err := h.service.GetStoreCredit(c.Request.Context(), userID, page, limit)
+	if err != nil {
+		respondGiftCardError(c, err, "Failed to get store credit")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Store credit retrieved successfully",
+		Data:    account,
+	})
+}
+
+// GetGiftCards godoc
+// @Summary List gift cards (Admin only)
+// @Description Get all gift cards, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Router /admin/gift-cards [get]
+func (h *GiftCardHandler) GetGiftCards(c *gin.Context) {
+	page, limit := pageParams(c)
+	result, err := h.service.GetGiftCards(c.Request.Context(), page, limit)
+	if err != nil {
+		respondGiftCardError(c, err, "Failed to get gift cards")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Gift cards retrieved successfully",
+		Data:    result,
+	})
+}
+
+// IssueGiftCard godoc
+// @Summary Issue a gift card (Admin only)
+// @Description Create a gift card with a balance and optional expiry. A code is generated unless one is given.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param giftCard body IssueGiftCardRequest true "Gift card"
+// @Success 201 {object} models.APIResponse{data=models.GiftCard}
+// @Failure 400 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/gift-cards [post]
+func (h *GiftCardHandler) IssueGiftCard(c *gin.Context) {
+	var req IssueGiftCardRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	card := &models.GiftCard{
+		Code:           req.Code,
+		InitialBalance: req.InitialBalance,
+		ExpiresAt:      req.ExpiresAt,
+		RecipientEmail: req.RecipientEmail,
+		Message:        req.Message,
+	}
+	if adminID, ok := middleware.CurrentUserID(c); ok {
+		card.IssuedBy = &adminID
+	}
+
+	if err := h.service.IssueGiftCard(c.Request.Context(), card); err != nil {
+		respondGiftCardError(c, err, "Failed to issue gift card")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Gift card issued successfully",
+		Data:    card,
+	})
+}
+
+// GetGiftCard godoc
+// @Summary Get a gift card (Admin only)
+// @Description Get a gift card with its transaction ledger
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Gift card ID"
+// @Success 200 {object} models.APIResponse{data=models.GiftCardDetail}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/gift-cards/{id} [get]
+func (h *GiftCardHandler) GetGiftCard(c *gin.Context) {
+	id, ok := parseIDParam(c, "gift card")
+	if !ok {
+		return
+	}
+
+	card, err := h.service.GetGiftCard(c.Request.Context(), id)
+	if err != nil {
+		respondGiftCardError(c, err, "Failed to get gift card")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Gift card retrieved successfully",
+		Data:    card,
+	})
+}
+
+// UpdateGiftCardStatus godoc
+// @Summary Enable or disable a gift card (Admin only)
+// @Description A disabled gift card cannot be checked or spent
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Gift card ID"
+// @Param status body GiftCardStatusRequest true "New status"
+// @Success 200 {object} models.APIResponse{data=models.GiftCard}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/gift-cards/{id}/status [put]
+func (h *GiftCardHandler) UpdateGiftCardStatus(c *gin.Context) {
+	id, ok := parseIDParam(c, "gift card")
+	if !ok {
+		return
+	}
+
+	var req GiftCardStatusRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	card, err := h.service.SetGiftCardStatus(c.Request.Context(), id, req.Status)
+	if err != nil {
+		respondGiftCardError(c, err, "Failed to update gift card")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Gift card updated successfully",
+		Data:    card,
+	})
+}
+
+// AdjustGiftCard godoc
+// @Summary Adjust a gift card balance (Admin only)
+// @Description Add to or take from a gift card balance. A note explaining the correction is required.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Gift card ID"
+// @Param adjustment body BalanceAdjustmentRequest true "Signed amount and note"
+// @Success 200 {object} models.APIResponse{data=models.GiftCard}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/gift-cards/{id}/adjust [post]
+func (h *GiftCardHandler) AdjustGiftCard(c *gin.Context) {
+	id, ok := parseIDParam(c, "gift card")
+	if !ok {
+		return
+	}
+
+	var req BalanceAdjustmentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var adminID *uuid.UUID
+	if userID, ok := middleware.CurrentUserID(c); ok {
+		adminID = &userID
+	}
+
+	card, err := h.service.AdjustGiftCard(c.Request.Context(), id, req.Amount, req.Note, adminID)
+	if err != nil {
+		respondGiftCardError(c, err, "Failed to adjust gift card")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Gift card adjusted successfully",
+		Data:    card,
+	})
+}
+
+// GetStoreCredit godoc
+// @Summary Get a user's store credit (Admin only)
+// @Description Get a user's store credit balance and transactions, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.StoreCreditAccount}
+// @Failure 400 {object} models.APIResponse
+// @Router /admin/store-credit/{id} [get]
+func (h *GiftCardHandler) GetStoreCredit(c *gin.Context) {
+	userID, ok := parseIDParam(c, "user")
+	if !ok {
+		return
+	}
+
+	page, limit := pageParams(c)
+	account, err := h.service.GetStoreCredit(c.Request.Context(), userID, page, limit)
+	if err != nil {
+		respondGiftCardError(c, err, "Failed to get store credit")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Store credit retrieved successfully",
+		Data:    account,
+	})
+}
+
+// AdjustStoreCredit godoc
+// @Summary Adjust a user's store credit (Admin only)
+// @Description Issue store credit, e.g. for a return, or take it away with a negative amount
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param adjustment body BalanceAdjustmentRequest true "Signed amount, reference and note"
+// @Success 200 {object} models.APIResponse{data=models.StoreCreditAccount}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/store-credit/{id}/adjust [post]
+func (h *GiftCardHandler) AdjustStoreCredit(c *gin.Context) {
+	userID, ok := parseIDParam(c, "user")
+	if !ok {
+		return
+	}
+
+	var req BalanceAdjustmentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var adminID *uuid.UUID
+	if id, ok := middleware.CurrentUserID(c); ok {
+		adminID = &id
+	}
+
+	account, err := h.service.AdjustStoreCredit(c.Request.Context(), userID, req.Amount, req.Reference, req.Note, adminID)
+	if err != nil {
+		respondGiftCardError(c, err, "Failed to adjust store credit")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Store credit adjusted successfully",
+		Data:    account,
+	})
+}
+
+// pageParams reads the page and limit query parameters, defaulting to 1 and 20 with limit capped at 100
+func pageParams(c *gin.Context) (int, int) {
+	page, limit := 1, 20
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	return page, limit
+}
+
+func respondGiftCardError(c *gin.Context, err error, message string) {
+	msg := err.Error()
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case msg == "gift card not found" || msg == "user not found":
+		status, code = http.StatusNotFound, "NOT_FOUND"
+	case msg == "code already in use":
+		status, code = http.StatusConflict, "CODE_IN_USE"
+	case msg == "adjustment would make the balance negative":
+		status, code = http.StatusConflict, "INSUFFICIENT_BALANCE"
+	case msg == "sign in to use store credit":
+		status, code = http.StatusUnauthorized, "UNAUTHORIZED"
+	case strings.HasPrefix(msg, "gift card "):
+		status, code = http.StatusBadRequest, "INVALID_GIFT_CARD"
+	case strings.Contains(msg, " must ") || strings.HasSuffix(msg, " is required"):
+		status, code = http.StatusBadRequest, "INVALID_REQUEST"
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    code,
+			Message: msg,
+		},
+	})
+}
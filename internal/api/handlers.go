@@ -3,41 +3,77 @@ package api
 import (
 	"net/http"
 
+	"smrtmart-go-postgresql/internal/middleware"
+	"smrtmart-go-postgresql/internal/models"
 	"smrtmart-go-postgresql/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
-// Placeholder handlers for other entities
-
-// AuthHandler handles authentication endpoints
-type AuthHandler struct {
-	service service.AuthService
-}
-
-func NewAuthHandler(service service.AuthService) *AuthHandler {
-	return &AuthHandler{service: service}
-}
-
-func (h *AuthHandler) Register(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Register endpoint - TODO"})
+// currentUserID returns the authenticated user's ID, responding with 401 if there is none
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Message: "Authentication required",
+			Error: &models.APIError{
+				Code:    "UNAUTHORIZED",
+				Message: "A valid authenticated user is required",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return userID, true
 }
 
-func (h *AuthHandler) Login(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Login endpoint - TODO"})
-}
+// currentVendor resolves the approved vendor of the authenticated user,
+// responding with an error if there is none or it is not approved
+func currentVendor(c *gin.Context, vendorService service.VendorService) (*models.Vendor, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return nil, false
+	}
 
-func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Refresh token endpoint - TODO"})
-}
+	vendor, err := vendorService.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		respondVendorError(c, err, "Failed to get vendor")
+		return nil, false
+	}
+	if vendor.Status != models.VendorStatusApproved {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "Vendor is not approved",
+			Error: &models.APIError{
+				Code:    "VENDOR_NOT_APPROVED",
+				Message: "Vendor status is " + string(vendor.Status),
+			},
+		})
+		return nil, false
+	}
 
-func (h *AuthHandler) ForgotPassword(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Forgot password endpoint - TODO"})
+	return vendor, true
+}
+
+// parseIDParam parses the :id path parameter, responding with 400 if it is not a UUID
+func parseIDParam(c *gin.Context, entity string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid " + entity + " ID",
+			Error: &models.APIError{
+				Code:    "INVALID_ID",
+				Message: "ID must be a valid UUID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
 }
 
-func (h *AuthHandler) ResetPassword(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Reset password endpoint - TODO"})
-}
+// Placeholder handlers for other entities
 
 // UserHandler handles user-related endpoints
 type UserHandler struct {
@@ -76,30 +112,7 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Admin delete user endpoint - TODO"})
 }
 
-// VendorHandler handles vendor-related endpoints
-type VendorHandler struct {
-	service service.VendorService
-}
-
-func NewVendorHandler(service service.VendorService) *VendorHandler {
-	return &VendorHandler{service: service}
-}
-
-func (h *VendorHandler) GetVendors(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get vendors endpoint - TODO"})
-}
-
-func (h *VendorHandler) GetVendor(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Get vendor endpoint - TODO"})
-}
-
-func (h *VendorHandler) UpdateVendorStatus(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Update vendor status endpoint - TODO"})
-}
-
-func (h *VendorHandler) VerifyVendor(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Verify vendor endpoint - TODO"})
-}
+// VendorHandler is now implemented in vendor_handler.go
 
 // CategoryHandler is now implemented in category_handler.go
 
@@ -208,26 +221,7 @@ func (h *OrderHandler) GetAllOrders(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Admin get all orders endpoint - TODO"})
 }
 
-// ReviewHandler handles review endpoints
-type ReviewHandler struct {
-	service service.ReviewService
-}
-
-func NewReviewHandler(service service.ReviewService) *ReviewHandler {
-	return &ReviewHandler{service: service}
-}
-
-func (h *ReviewHandler) CreateReview(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Create review endpoint - TODO"})
-}
-
-func (h *ReviewHandler) UpdateReview(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Update review endpoint - TODO"})
-}
-
-func (h *ReviewHandler) DeleteReview(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Delete review endpoint - TODO"})
-}
+// ReviewHandler is now implemented in review_handler.go
 
 // PaymentHandler is now implemented in payment_handler.go
 
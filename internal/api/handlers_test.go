@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/middleware"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// stubVendorService returns the same vendor for every user
+type stubVendorService struct {
+	service.VendorService
+	vendor *models.Vendor
+}
+
+func (s *stubVendorService) GetProfile(context.Context, uuid.UUID) (*models.Vendor, error) {
+	return s.vendor, nil
+}
+
+func TestCurrentVendorRequiresApproval(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		status models.VendorStatus
+		want   int
+	}{
+		{models.VendorStatusApproved, http.StatusOK},
+		{models.VendorStatusPending, http.StatusForbidden},
+		{models.VendorStatusRejected, http.StatusForbidden},
+		{models.VendorStatusSuspended, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			vendors := &stubVendorService{vendor: &models.Vendor{ID: uuid.New(), Status: tt.status}}
+			router := gin.New()
+			router.GET("/vendor/promotions", func(c *gin.Context) {
+				c.Set(middleware.ContextUserID, uuid.New())
+				if _, ok := currentVendor(c, vendors); ok {
+					c.Status(http.StatusOK)
+				}
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/vendor/promotions", nil))
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
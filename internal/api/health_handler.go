@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+
+	"smrtmart-go-postgresql/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Livez godoc
+// @Summary Liveness probe
+// @Description Reports that the process is up and serving. It does not check dependencies, so a database outage does not get the process restarted.
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /livez [get]
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"state":  h.checker.State(),
+	})
+}
+
+// Readyz godoc
+// @Summary Readiness probe
+// @Description Checks the database, its migration version and any configured optional dependencies. Responds 503 while starting, while shutting down, or when a critical check fails; failing optional checks report degraded with 200.
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
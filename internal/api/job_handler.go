@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobHandler handles the background job queue
+type JobHandler struct {
+	service service.JobService
+}
+
+func NewJobHandler(service service.JobService) *JobHandler {
+	return &JobHandler{service: service}
+}
+
+// GetJobs godoc
+// @Summary List background jobs (Admin only)
+// @Description Get background jobs, newest first, optionally filtered by status or kind
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Only jobs with this status" Enums(pending, running, succeeded, dead)
+// @Param kind query string false "Only jobs of this kind"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Failure 400 {object} models.APIResponse
+// @Router /admin/jobs [get]
+func (h *JobHandler) GetJobs(c *gin.Context) {
+	var status *models.JobStatus
+	if s := c.Query("status"); s != "" {
+		value := models.JobStatus(s)
+		status = &value
+	}
+	var kind *string
+	if k := c.Query("kind"); k != "" {
+		kind = &k
+	}
+
+	page, limit := pageParams(c)
+	result, err := h.service.GetJobs(c.Request.Context(), status, kind, page, limit)
+	if err != nil {
+		respondJobError(c, err, "Failed to get jobs")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Jobs retrieved successfully",
+		Data:    result,
+	})
+}
+
+// GetJobStats godoc
+// @Summary Count background jobs by status (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=models.JobStats}
+// @Router /admin/jobs/stats [get]
+func (h *JobHandler) GetJobStats(c *gin.Context) {
+	stats, err := h.service.GetStats(c.Request.Context())
+	if err != nil {
+		respondJobError(c, err, "Failed to get job stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Job stats retrieved successfully",
+		Data:    stats,
+	})
+}
+
+// GetJob godoc
+// @Summary Get a background job (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.APIResponse{data=models.Job}
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/jobs/{id} [get]
+func (h *JobHandler) GetJob(c *gin.Context) {
+	id, ok := parseIDParam(c, "job")
+	if !ok {
+		return
+	}
+
+	job, err := h.service.GetJob(c.Request.Context(), id)
+	if err != nil {
+		respondJobError(c, err, "Failed to get job")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Job retrieved successfully",
+		Data:    job,
+	})
+}
+
+// RetryJob godoc
+// @Summary Retry a dead job (Admin only)
+// @Description Move a job from the dead letters back to the queue with fresh attempts
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.APIResponse{data=models.Job}
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/jobs/{id}/retry [post]
+func (h *JobHandler) RetryJob(c *gin.Context) {
+	id, ok := parseIDParam(c, "job")
+	if !ok {
+		return
+	}
+
+	job, err := h.service.RetryJob(c.Request.Context(), id)
+	if err != nil {
+		respondJobError(c, err, "Failed to retry job")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Job queued for retry",
+		Data:    job,
+	})
+}
+
+func respondJobError(c *gin.Context, err error, message string) {
+	msg := err.Error()
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case msg == "job not found":
+		status, code = http.StatusNotFound, "NOT_FOUND"
+	case strings.HasPrefix(msg, "only dead jobs"):
+		status, code = http.StatusConflict, "NOT_DEAD"
+	case strings.Contains(msg, " must "):
+		status, code = http.StatusBadRequest, "INVALID_REQUEST"
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    code,
+			Message: msg,
+		},
+	})
+}
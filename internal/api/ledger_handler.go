@@ -0,0 +1,492 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// LedgerHandler handles commission, vendor ledger and payout endpoints
+type LedgerHandler struct {
+	service       service.LedgerService
+	vendorService service.VendorService
+}
+
+func NewLedgerHandler(service service.LedgerService, vendorService service.VendorService) *LedgerHandler {
+	return &LedgerHandler{service: service, vendorService: vendorService}
+}
+
+// CommissionRateRequest is the payload for setting a commission rate
+type CommissionRateRequest struct {
+	Scope      models.CommissionScope `json:"scope" binding:"required"`
+	CategoryID *uuid.UUID             `json:"category_id,omitempty"`
+	VendorID   *uuid.UUID             `json:"vendor_id,omitempty"`
+	Rate       *float64               `json:"rate" binding:"required"` // Fraction of the item total, e.g. 0.12
+}
+
+// RefundRequest is the payload for recording a refund against an order item
+type RefundRequest struct {
+	OrderItemID uuid.UUID `json:"order_item_id" binding:"required"`
+	Amount      float64   `json:"amount" binding:"required"`
+}
+
+// PayoutBatchRequest is the payload for creating a payout batch
+type PayoutBatchRequest struct {
+	PeriodEnd *time.Time `json:"period_end,omitempty"` // Defaults to now
+}
+
+// GetCommissionRates godoc
+// @Summary List commission rates (Admin only)
+// @Description Get the global commission rate and all category and vendor overrides
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.CommissionRate}
+// @Failure 500 {object} models.APIResponse
+// @Router /admin/commissions [get]
+func (h *LedgerHandler) GetCommissionRates(c *gin.Context) {
+	rates, err := h.service.GetCommissionRates(c.Request.Context())
+	if err != nil {
+		respondLedgerError(c, err, "Failed to get commission rates")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Commission rates retrieved successfully",
+		Data:    rates,
+	})
+}
+
+// SetCommissionRate godoc
+// @Summary Set a commission rate (Admin only)
+// @Description Set the global rate or a category or vendor override. A vendor rate wins over a category rate, which wins over the global rate.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param rate body CommissionRateRequest true "Commission rate"
+// @Success 200 {object} models.APIResponse{data=models.CommissionRate}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/commissions [put]
+func (h *LedgerHandler) SetCommissionRate(c *gin.Context) {
+	var req CommissionRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	rate := &models.CommissionRate{
+		Scope:      req.Scope,
+		CategoryID: req.CategoryID,
+		VendorID:   req.VendorID,
+		Rate:       *req.Rate,
+	}
+	if err := h.service.SetCommissionRate(c.Request.Context(), rate); err != nil {
+		respondLedgerWriteError(c, err, "Failed to set commission rate", "UPDATE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Commission rate saved successfully",
+		Data:    rate,
+	})
+}
+
+// DeleteCommissionRate godoc
+// @Summary Delete a commission override (Admin only)
+// @Description Remove a category or vendor commission override. The global rate cannot be deleted.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Commission rate ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/commissions/{id} [delete]
+func (h *LedgerHandler) DeleteCommissionRate(c *gin.Context) {
+	id, ok := parseIDParam(c, "commission rate")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteCommissionRate(c.Request.Context(), id); err != nil {
+		respondLedgerWriteError(c, err, "Failed to delete commission rate", "DELETION_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Commission rate deleted successfully",
+	})
+}
+
+// RecordOrder godoc
+// @Summary Record order earnings (Admin only)
+// @Description Credit vendors with an order's items and charge commission. Items already recorded are skipped.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/ledger/orders/{id} [post]
+func (h *LedgerHandler) RecordOrder(c *gin.Context) {
+	id, ok := parseIDParam(c, "order")
+	if !ok {
+		return
+	}
+
+	count, err := h.service.RecordOrder(c.Request.Context(), id)
+	if err != nil {
+		respondLedgerWriteError(c, err, "Failed to record order", "RECORDING_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Order recorded in vendor ledger",
+		Data:    gin.H{"entries_added": count},
+	})
+}
+
+// RecordRefund godoc
+// @Summary Record a refund (Admin only)
+// @Description Debit the vendor for a full or partial refund of an order item and return the commission on it
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param refund body RefundRequest true "Refund"
+// @Success 201 {object} models.APIResponse{data=[]models.LedgerEntry}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/ledger/refunds [post]
+func (h *LedgerHandler) RecordRefund(c *gin.Context) {
+	var req RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	entries, err := h.service.RecordRefund(c.Request.Context(), req.OrderItemID, req.Amount)
+	if err != nil {
+		respondLedgerWriteError(c, err, "Failed to record refund", "RECORDING_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Refund recorded in vendor ledger",
+		Data:    entries,
+	})
+}
+
+// GetVendorBalance godoc
+// @Summary Get vendor balance (Vendor only)
+// @Description Get what the platform owes the authenticated vendor, split into available, in payout and paid out
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=models.VendorBalance}
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/balance [get]
+func (h *LedgerHandler) GetVendorBalance(c *gin.Context) {
+	vendor, ok := currentVendor(c, h.vendorService)
+	if !ok {
+		return
+	}
+
+	balance, err := h.service.GetBalance(c.Request.Context(), vendor.ID)
+	if err != nil {
+		respondLedgerError(c, err, "Failed to get vendor balance")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vendor balance retrieved successfully",
+		Data:    balance,
+	})
+}
+
+// GetVendorStatement godoc
+// @Summary Get vendor statement (Vendor only)
+// @Description Get the authenticated vendor's ledger entries for a period with opening and closing balances
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "Start date (YYYY-MM-DD), defaults to 30 days ago"
+// @Param to query string false "End date (YYYY-MM-DD, inclusive), defaults to today"
+// @Success 200 {object} models.APIResponse{data=models.VendorStatement}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/statement [get]
+func (h *LedgerHandler) GetVendorStatement(c *gin.Context) {
+	vendor, ok := currentVendor(c, h.vendorService)
+	if !ok {
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to := today.AddDate(0, 0, 1)
+	if toStr := c.Query("to"); toStr != "" {
+		date, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			respondInvalidDate(c, "to")
+			return
+		}
+		to = date.AddDate(0, 0, 1)
+	}
+
+	from := to.AddDate(0, 0, -30)
+	if fromStr := c.Query("from"); fromStr != "" {
+		date, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			respondInvalidDate(c, "from")
+			return
+		}
+		from = date
+	}
+
+	statement, err := h.service.GetStatement(c.Request.Context(), vendor.ID, from, to)
+	if err != nil {
+		respondLedgerWriteError(c, err, "Failed to get vendor statement", "INVALID_PERIOD")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vendor statement retrieved successfully",
+		Data:    statement,
+	})
+}
+
+// CreatePayoutBatch godoc
+// @Summary Create a payout batch (Admin only)
+// @Description Roll up every approved vendor's unpaid balance up to the end of the period into pending payouts
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param batch body PayoutBatchRequest false "Payout period"
+// @Success 201 {object} models.APIResponse{data=models.PayoutBatch}
+// @Failure 400 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/payouts [post]
+func (h *LedgerHandler) CreatePayoutBatch(c *gin.Context) {
+	var req PayoutBatchRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid request data",
+				Error: &models.APIError{
+					Code:    "INVALID_REQUEST",
+					Message: err.Error(),
+				},
+			})
+			return
+		}
+	}
+
+	periodEnd := time.Now()
+	if req.PeriodEnd != nil {
+		periodEnd = *req.PeriodEnd
+	}
+
+	batch, err := h.service.CreatePayoutBatch(c.Request.Context(), periodEnd)
+	if err != nil {
+		respondLedgerWriteError(c, err, "Failed to create payout batch", "CREATION_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Payout batch created successfully",
+		Data:    batch,
+	})
+}
+
+// GetPayoutBatches godoc
+// @Summary List payout batches (Admin only)
+// @Description Get payout batches, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Failure 500 {object} models.APIResponse
+// @Router /admin/payouts [get]
+func (h *LedgerHandler) GetPayoutBatches(c *gin.Context) {
+	page, limit := 1, 20
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	result, err := h.service.GetPayoutBatches(c.Request.Context(), page, limit)
+	if err != nil {
+		respondLedgerError(c, err, "Failed to get payout batches")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Payout batches retrieved successfully",
+		Data:    result,
+	})
+}
+
+// GetPayoutBatch godoc
+// @Summary Get a payout batch (Admin only)
+// @Description Get a payout batch with its vendor payouts
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payout batch ID"
+// @Success 200 {object} models.APIResponse{data=models.PayoutBatch}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/payouts/{id} [get]
+func (h *LedgerHandler) GetPayoutBatch(c *gin.Context) {
+	id, ok := parseIDParam(c, "payout batch")
+	if !ok {
+		return
+	}
+
+	batch, err := h.service.GetPayoutBatch(c.Request.Context(), id)
+	if err != nil {
+		respondLedgerError(c, err, "Failed to get payout batch")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Payout batch retrieved successfully",
+		Data:    batch,
+	})
+}
+
+// ExecutePayoutBatch godoc
+// @Summary Execute a payout batch (Admin only)
+// @Description Transfer each pending payout in the batch to the vendor. Failed payouts roll into the next batch.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payout batch ID"
+// @Success 200 {object} models.APIResponse{data=models.PayoutBatch}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/payouts/{id}/execute [post]
+func (h *LedgerHandler) ExecutePayoutBatch(c *gin.Context) {
+	id, ok := parseIDParam(c, "payout batch")
+	if !ok {
+		return
+	}
+
+	batch, err := h.service.ExecutePayoutBatch(c.Request.Context(), id)
+	if err != nil {
+		respondLedgerWriteError(c, err, "Failed to execute payout batch", "PAYOUT_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Payout batch executed",
+		Data:    batch,
+	})
+}
+
+func respondInvalidDate(c *gin.Context, param string) {
+	c.JSON(http.StatusBadRequest, models.APIResponse{
+		Success: false,
+		Message: "Invalid date",
+		Error: &models.APIError{
+			Code:    "INVALID_DATE",
+			Message: param + " must be a date in YYYY-MM-DD format",
+		},
+	})
+}
+
+func respondLedgerWriteError(c *gin.Context, err error, message, code string) {
+	switch {
+	case strings.HasSuffix(err.Error(), "not found"):
+		respondLedgerError(c, err, message)
+	case err.Error() == "payout batch has already been executed" || err.Error() == "payout batch is already being executed" || err.Error() == "no vendor balances to pay out":
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: message,
+			Error: &models.APIError{
+				Code:    "CONFLICT",
+				Message: err.Error(),
+			},
+		})
+	default:
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: message,
+			Error: &models.APIError{
+				Code:    code,
+				Message: err.Error(),
+			},
+		})
+	}
+}
+
+func respondLedgerError(c *gin.Context, err error, message string) {
+	if strings.HasSuffix(err.Error(), "not found") {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: message,
+			Error: &models.APIError{
+				Code:    "NOT_FOUND",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		},
+	})
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/metrics"
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsHandler serves Prometheus metrics
+type MetricsHandler struct {
+	token   string
+	handler http.Handler
+}
+
+// NewMetricsHandler returns a handler that requires the given bearer token, or
+// no authentication when it is empty
+func NewMetricsHandler(token string) *MetricsHandler {
+	return &MetricsHandler{token: token, handler: metrics.Handler()}
+}
+
+// Metrics godoc
+// @Summary Prometheus metrics
+// @Description Request counts and latencies by route, database pool statistics, rate-limit rejections, webhook outcomes and business counters, in the Prometheus text format. Requires the METRICS_TOKEN bearer token when one is configured.
+// @Tags health
+// @Produce plain
+// @Success 200 {string} string
+// @Failure 401 {object} models.APIResponse
+// @Router /metrics [get]
+func (h *MetricsHandler) Metrics(c *gin.Context) {
+	if h.token != "" {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			c.JSON(http.StatusUnauthorized, models.APIResponse{
+				Success: false,
+				Message: "Unauthorized",
+				Error: &models.APIError{
+					Code:    "UNAUTHORIZED",
+					Message: "A valid metrics token is required",
+				},
+			})
+			return
+		}
+	}
+	h.handler.ServeHTTP(c.Writer, c.Request)
+}
@@ -1,27 +1,40 @@
 package api
 
 import (
+	"context"
 	"io"
+	"log/slog"
 	"net/http"
+	"strings"
 
+	"smrtmart-go-postgresql/internal/middleware"
 	"smrtmart-go-postgresql/internal/models"
 	"smrtmart-go-postgresql/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stripe/stripe-go/v76"
 )
 
 type PaymentHandler struct {
-	service service.PaymentService
+	service          service.PaymentService
+	promotionService service.PromotionService
+	giftCardService  service.GiftCardService
+	shippingService  service.ShippingService
 }
 
-func NewPaymentHandler(service service.PaymentService) *PaymentHandler {
-	return &PaymentHandler{service: service}
+func NewPaymentHandler(service service.PaymentService, promotionService service.PromotionService, giftCardService service.GiftCardService, shippingService service.ShippingService) *PaymentHandler {
+	return &PaymentHandler{
+		service:          service,
+		promotionService: promotionService,
+		giftCardService:  giftCardService,
+		shippingService:  shippingService,
+	}
 }
 
 // CreateCheckoutSession godoc
 // @Summary Create Stripe checkout session
-// @Description Create a Stripe checkout session for payment processing
+// @Description Create a Stripe checkout session for payment processing. Items are charged at the products' current prices. Coupon codes and automatic promotions are validated again against current prices and applied as a discount. Shipping options are priced from the shipping zone covering the shipping address, or limited to the countries with a shipping zone when no address is given. Gift cards and store credit are then taken as tender and Stripe charges the remainder; if they cover the whole order, shipped with the first option for the shipping address, the order is completed without a session.
 // @Tags payments
 // @Accept json
 // @Produce json
@@ -69,17 +82,58 @@ func (h *PaymentHandler) CreateCheckoutSession(c *gin.Context) {
 		return
 	}
 
-	// Convert request items to service items
-	var items []service.CheckoutItem
-	for _, item := range req.Items {
-		items = append(items, service.CheckoutItem{
-			ProductID:   item.ProductID,
-			Name:        item.Name,
-			Description: item.Description,
-			Price:       item.Price,
-			Quantity:    item.Quantity,
-			Images:      item.Images,
+	lines := make([]service.CartLine, len(req.Items))
+	for i, item := range req.Items {
+		lines[i] = service.CartLine{ProductRef: item.ProductID, Quantity: item.Quantity}
+	}
+
+	// The cart is charged at the current product prices, whatever the client sent
+	items, err := h.service.PriceItems(c.Request.Context(), lines)
+	if err != nil {
+		respondShippingError(c, err, "Failed to price cart")
+		return
+	}
+
+	discount, err := h.checkoutDiscount(c, req, lines, items)
+	if err != nil {
+		respondPromotionError(c, err, "Failed to apply coupons")
+		return
+	}
+
+	fullInfo := req.CustomerInfo.FirstName != "" && req.CustomerInfo.LastName != "" &&
+		req.ShippingAddress.AddressLine1 != "" && req.ShippingAddress.City != ""
+
+	shipping, err := h.checkoutShipping(c.Request.Context(), req, lines, fullInfo)
+	if err != nil {
+		respondShippingError(c, err, "Failed to calculate shipping")
+		return
+	}
+
+	tenders, paidInFull, err := h.redeemTenders(c, req, items, discount, shipping)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "coupon ") {
+			respondPromotionError(c, err, "Failed to apply coupons")
+			return
+		}
+		respondGiftCardError(c, err, "Failed to apply gift cards")
+		return
+	}
+	if paidInFull {
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Order paid with gift cards and store credit",
+			Data: CheckoutResponse{
+				PaidInFull: true,
+				Tenders:    tenders,
+			},
 		})
+		return
+	}
+	if tenders != nil {
+		if discount == nil {
+			discount = &service.CheckoutDiscount{}
+		}
+		discount.Tenders = tenders
 	}
 
 	// Set default URLs if not provided
@@ -95,12 +149,11 @@ func (h *PaymentHandler) CreateCheckoutSession(c *gin.Context) {
 
 	// Check if this is a full customer info request or simple email-only request
 	var session *stripe.CheckoutSession
-	var err error
 	
-	if req.CustomerInfo.FirstName != "" && req.CustomerInfo.LastName != "" && 
-	   req.ShippingAddress.AddressLine1 != "" && req.ShippingAddress.City != "" {
+	if fullInfo {
 		// Full customer info checkout
 		session, err = h.service.CreateCheckoutSessionWithFullInfo(
+			c.Request.Context(),
 			items, 
 			service.CustomerInfo{
 				FirstName: req.CustomerInfo.FirstName,
@@ -137,15 +190,24 @@ func (h *PaymentHandler) CreateCheckoutSession(c *gin.Context) {
 					Phone:        req.BillingAddress.Phone,
 				}
 			}(),
+			shipping,
+			discount,
 			successURL, 
 			cancelURL,
 		)
 	} else {
 		// Simple email-only checkout (fallback to original method)
-		session, err = h.service.CreateCheckoutSession(items, req.CustomerEmail, successURL, cancelURL)
+		session, err = h.service.CreateCheckoutSession(c.Request.Context(), items, req.CustomerEmail, shipping, discount, successURL, cancelURL)
 	}
 	
 	if err != nil {
+		// The customer did not get a session to pay in, so give the balances back
+		if tenders != nil {
+			if releaseErr := h.giftCardService.ReleaseTenders(c.Request.Context(), tenders.Reference); releaseErr != nil {
+				slog.ErrorContext(c.Request.Context(), "Failed to release tenders", "reference", tenders.Reference, "error", releaseErr)
+			}
+		}
+
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "Failed to create checkout session",
@@ -163,13 +225,140 @@ func (h *PaymentHandler) CreateCheckoutSession(c *gin.Context) {
 		Data: CheckoutResponse{
 			SessionID:  session.ID,
 			SessionURL: session.URL,
+			Tenders:    tenders,
 		},
 	})
 }
 
+// checkoutDiscount re-validates the cart's promotions at checkout. Coupon errors
+// are returned to the client; without coupons a cart that cannot be priced simply
+// checks out without automatic discounts.
+func (h *PaymentHandler) checkoutDiscount(c *gin.Context, req CheckoutRequest, lines []service.CartLine, items []service.CheckoutItem) (*service.CheckoutDiscount, error) {
+	var customerID *uuid.UUID
+	if userID, ok := middleware.CurrentUserID(c); ok {
+		customerID = &userID
+	}
+
+	quote, err := h.promotionService.Quote(c.Request.Context(), customerID, lines, req.CouponCodes)
+	if err != nil {
+		if len(req.CouponCodes) > 0 {
+			return nil, err
+		}
+		slog.WarnContext(c.Request.Context(), "Skipping automatic promotions at checkout", "error", err)
+		return nil, nil
+	}
+	if len(quote.Applied) == 0 {
+		return nil, nil
+	}
+
+	// Prices may have changed since the quote, so never discount below zero
+	amount := quote.Discount
+	if itemsTotal := checkoutItemsTotal(items); amount > itemsTotal {
+		amount = itemsTotal
+	}
+
+	return &service.CheckoutDiscount{
+		Amount:       amount,
+		FreeShipping: quote.FreeShipping,
+		Quote:        quote,
+		CustomerID:   customerID,
+	}, nil
+}
+
+// checkoutShipping prices the shipping options for the shipping address. Without
+// a full address the customer picks one in Stripe, so only the countries are limited.
+func (h *PaymentHandler) checkoutShipping(ctx context.Context, req CheckoutRequest, lines []service.CartLine, fullInfo bool) (*service.CheckoutShipping, error) {
+	countries, err := h.shippingService.AllowedCountries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	shipping := &service.CheckoutShipping{AllowedCountries: countries}
+	if !fullInfo {
+		return shipping, nil
+	}
+
+	quote, err := h.shippingService.Quote(ctx, lines, req.ShippingAddress.Country, req.ShippingAddress.PostalCode)
+	if err != nil {
+		return nil, err
+	}
+	shipping.Options = quote.Options
+	return shipping, nil
+}
+
+// redeemTenders takes the requested gift cards and store credit toward what is left
+// after the discount. With a shipping address they pay for the whole order,
+// shipped with the first option, if they can; the order is then completed and
+// paidInFull is true. It returns nil when none were requested or none had a balance.
+func (h *PaymentHandler) redeemTenders(c *gin.Context, req CheckoutRequest, items []service.CheckoutItem, discount *service.CheckoutDiscount, shipping *service.CheckoutShipping) (_ *models.TenderRedemption, paidInFull bool, _ error) {
+	if len(req.GiftCardCodes) == 0 && !req.UseStoreCredit {
+		return nil, false, nil
+	}
+
+	var customerID *uuid.UUID
+	if userID, ok := middleware.CurrentUserID(c); ok {
+		customerID = &userID
+	}
+
+	amountDue := checkoutItemsTotal(items)
+	if discount != nil {
+		amountDue -= discount.Amount
+	}
+	amountDue = max(amountDue, 0)
+
+	// Without a shipping address Stripe has to collect one, so the tenders only
+	// go toward the items
+	var checkout *service.TenderCheckout
+	if len(shipping.Options) > 0 {
+		rate := shipping.Options[0].Rate
+		if discount != nil && discount.FreeShipping {
+			rate = 0
+		}
+		checkout = &service.TenderCheckout{
+			Total: amountDue + rate,
+			Completed: models.CheckoutEventPayload{
+				CustomerEmail: req.CustomerEmail,
+				CustomerName:  strings.TrimSpace(req.CustomerInfo.FirstName + " " + req.CustomerInfo.LastName),
+				Currency:      "usd",
+				AmountTotal:   amountDue + rate, // Settled with gift cards and store credit
+				Shipping:      rate,
+			},
+		}
+		if req.CustomerInfo.Email != "" {
+			checkout.Completed.CustomerEmail = req.CustomerInfo.Email
+		}
+		if discount != nil {
+			checkout.Completed.Discount = discount.Amount
+			if discount.Quote != nil {
+				checkout.Promotions = discount.Quote.Applied
+			}
+		}
+	}
+	if amountDue <= 0 && (checkout == nil || checkout.Total <= 0) {
+		return nil, false, nil
+	}
+
+	tenders, err := h.giftCardService.RedeemTenders(c.Request.Context(), customerID, req.GiftCardCodes, req.UseStoreCredit, amountDue, checkout)
+	if err != nil {
+		return nil, false, err
+	}
+	if tenders.Total <= 0 {
+		return nil, false, nil
+	}
+	return tenders, checkout != nil && tenders.Total >= checkout.Total-0.005, nil
+}
+
+// checkoutItemsTotal is what the session charges for the items before any discount
+func checkoutItemsTotal(items []service.CheckoutItem) float64 {
+	var total float64
+	for _, item := range items {
+		total += item.Price * float64(item.Quantity)
+	}
+	return total
+}
+
 // StripeWebhook godoc
 // @Summary Handle Stripe webhooks
-// @Description Handle Stripe webhook events for payment processing
+// @Description Handle Stripe webhook events for payment processing. Unsigned or unreadable events are rejected with 400; events that fail to process return 500 so Stripe delivers them again.
 // @Tags payments
 // @Accept json
 // @Produce json
@@ -180,8 +369,7 @@ func (h *PaymentHandler) CreateCheckoutSession(c *gin.Context) {
 func (h *PaymentHandler) StripeWebhook(c *gin.Context) {
 	payload, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		// Log error but return 200 to acknowledge receipt
-		c.JSON(http.StatusOK, models.APIResponse{
+		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Failed to read request body",
 		})
@@ -190,18 +378,28 @@ func (h *PaymentHandler) StripeWebhook(c *gin.Context) {
 
 	signature := c.GetHeader("Stripe-Signature")
 	if signature == "" {
-		// Log error but return 200 to acknowledge receipt
-		c.JSON(http.StatusOK, models.APIResponse{
+		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Missing Stripe signature",
 		})
 		return
 	}
 
-	if err := h.service.HandleWebhook(payload, signature); err != nil {
-		// Log error but ALWAYS return 200 to prevent Stripe from retrying
-		// Stripe requires 2xx status code to consider webhook delivered
-		c.JSON(http.StatusOK, models.APIResponse{
+	if err := h.service.HandleWebhook(c.Request.Context(), payload, signature); err != nil {
+		// A request Stripe did not sign, or an event we cannot read, will not get
+		// better by retrying. Anything else failed on our side, so Stripe must
+		// deliver the event again.
+		msg := err.Error()
+		if strings.HasPrefix(msg, "failed to verify webhook signature") || strings.HasPrefix(msg, "failed to unmarshal ") {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid webhook",
+			})
+			return
+		}
+
+		slog.ErrorContext(c.Request.Context(), "Failed to process Stripe webhook", "error", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
 			Message: "Failed to process webhook",
 		})
@@ -223,6 +421,9 @@ type CheckoutRequest struct {
 	BillingAddress  *Address              `json:"billing_address,omitempty"` // Optional, if different from shipping
 	SuccessURL      string                `json:"success_url,omitempty"`
 	CancelURL       string                `json:"cancel_url,omitempty"`
+	CouponCodes     []string              `json:"coupon_codes,omitempty"`
+	GiftCardCodes   []string              `json:"gift_card_codes,omitempty"`
+	UseStoreCredit  bool                  `json:"use_store_credit,omitempty"` // Requires a signed-in customer
 }
 
 type CustomerInfo struct {
@@ -245,16 +446,21 @@ type Address struct {
 	Phone        string `json:"phone,omitempty"`
 }
 
+// CheckoutItemRequest is a cart line. The name, description, price and images
+// are still accepted from older clients but ignored: the product's current
+// details are charged.
 type CheckoutItemRequest struct {
 	ProductID   string   `json:"product_id" binding:"required"`
-	Name        string   `json:"name" binding:"required"`
+	Name        string   `json:"name"`
 	Description string   `json:"description"`
-	Price       float64  `json:"price" binding:"required,gt=0"`
+	Price       float64  `json:"price"`
 	Quantity    int      `json:"quantity" binding:"required,gt=0"`
 	Images      []string `json:"images"`
 }
 
 type CheckoutResponse struct {
-	SessionID  string `json:"session_id"`
-	SessionURL string `json:"session_url"`
+	SessionID  string                   `json:"session_id,omitempty"`
+	SessionURL string                   `json:"session_url,omitempty"`
+	PaidInFull bool                     `json:"paid_in_full,omitempty"` // Gift cards and store credit covered the order
+	Tenders    *models.TenderRedemption `json:"tenders,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// stubPaymentService fails webhooks with a fixed error
+type stubPaymentService struct {
+	service.PaymentService
+	err error
+}
+
+func (s *stubPaymentService) HandleWebhook(context.Context, []byte, string) error {
+	return s.err
+}
+
+func TestStripeWebhookStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		signature string
+		err       error
+		want      int
+	}{
+		{"processed", "t=1,v1=abc", nil, http.StatusOK},
+		{"missing signature", "", nil, http.StatusBadRequest},
+		{"bad signature", "t=1,v1=abc", errors.New("failed to verify webhook signature: no valid signature"), http.StatusBadRequest},
+		{"unreadable event", "t=1,v1=abc", errors.New("failed to unmarshal session: unexpected end of JSON input"), http.StatusBadRequest},
+		{"processing failed", "t=1,v1=abc", errors.New("failed to record completed session cs_1: connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewPaymentHandler(&stubPaymentService{err: tt.err}, nil, nil, nil)
+			router := gin.New()
+			router.POST("/webhooks/stripe", handler.StripeWebhook)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", strings.NewReader(`{}`))
+			if tt.signature != "" {
+				req.Header.Set("Stripe-Signature", tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+// coveringGiftCardService covers whatever checkout it is given in full
+type coveringGiftCardService struct {
+	service.GiftCardService
+	checkout *service.TenderCheckout
+}
+
+func (s *coveringGiftCardService) RedeemTenders(_ context.Context, _ *uuid.UUID, _ []string, _ bool, amount float64, checkout *service.TenderCheckout) (*models.TenderRedemption, error) {
+	s.checkout = checkout
+	return &models.TenderRedemption{Reference: "chk_1", Total: checkout.Total}, nil
+}
+
+func TestTenderPaidCheckoutCarriesSettledTotal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	giftCards := &coveringGiftCardService{}
+	handler := NewPaymentHandler(nil, nil, giftCards, nil)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/checkout", nil)
+
+	req := CheckoutRequest{
+		CustomerEmail: "asa@example.com",
+		CustomerInfo:  CustomerInfo{FirstName: "Asa", LastName: "Lind"},
+		GiftCardCodes: []string{"GIFT-1"},
+	}
+	items := []service.CheckoutItem{{Price: 20, Quantity: 2}}
+	discount := &service.CheckoutDiscount{Amount: 5}
+	shipping := &service.CheckoutShipping{Options: []models.ShippingOption{{Rate: 4.99}}}
+
+	tenders, paidInFull, err := handler.redeemTenders(c, req, items, discount, shipping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !paidInFull || tenders.Total != 39.99 {
+		t.Fatalf("paid in full %v with %+v", paidInFull, tenders)
+	}
+	completed := giftCards.checkout.Completed
+	if completed.AmountTotal != 39.99 || completed.Shipping != 4.99 || completed.Discount != 5 || completed.CustomerName != "Asa Lind" {
+		t.Errorf("checkout.completed payload %+v", completed)
+	}
+}
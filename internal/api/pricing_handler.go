@@ -0,0 +1,307 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/middleware"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PricingHandler handles scheduled sale prices and product price history
+type PricingHandler struct {
+	service       service.PricingService
+	vendorService service.VendorService
+}
+
+func NewPricingHandler(service service.PricingService, vendorService service.VendorService) *PricingHandler {
+	return &PricingHandler{
+		service:       service,
+		vendorService: vendorService,
+	}
+}
+
+// SaleRequest is the payload for scheduling a sale price
+type SaleRequest struct {
+	SalePrice float64    `json:"sale_price" binding:"required,gt=0"`
+	StartsAt  *time.Time `json:"starts_at,omitempty"` // Defaults to now
+	EndsAt    time.Time  `json:"ends_at" binding:"required"`
+}
+
+// GetVendorSales godoc
+// @Summary List product sales (Vendor only)
+// @Description Get the scheduled, active and past sale prices of one of your products
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Success 200 {object} models.APIResponse{data=[]models.SalePrice}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/products/{id}/sales [get]
+func (h *PricingHandler) GetVendorSales(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.getSales(c, &vendor.ID)
+	}
+}
+
+// ScheduleVendorSale godoc
+// @Summary Schedule a sale price (Vendor only)
+// @Description Schedule a sale price for one of your products. During the sale the regular price is shown as the compare price; both are restored when it ends. A sale without a start, or starting in the past, takes effect immediately.
+// @Tags vendors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param sale body SaleRequest true "Sale price and period"
+// @Success 201 {object} models.APIResponse{data=models.SalePrice}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /vendor/products/{id}/sales [post]
+func (h *PricingHandler) ScheduleVendorSale(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.scheduleSale(c, &vendor.ID)
+	}
+}
+
+// CancelVendorSale godoc
+// @Summary Cancel a sale (Vendor only)
+// @Description Cancel a scheduled sale, or end an active sale early and restore the regular price
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param saleId path string true "Sale ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /vendor/products/{id}/sales/{saleId} [delete]
+func (h *PricingHandler) CancelVendorSale(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.cancelSale(c, &vendor.ID)
+	}
+}
+
+// GetVendorPriceHistory godoc
+// @Summary Get product price history (Vendor only)
+// @Description Get every price change of one of your products, newest first
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param limit query int false "Maximum entries" default(50)
+// @Success 200 {object} models.APIResponse{data=[]models.PriceHistoryEntry}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/products/{id}/price-history [get]
+func (h *PricingHandler) GetVendorPriceHistory(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.getPriceHistory(c, &vendor.ID)
+	}
+}
+
+// GetSales godoc
+// @Summary List product sales (Admin only)
+// @Description Get the scheduled, active and past sale prices of a product
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Success 200 {object} models.APIResponse{data=[]models.SalePrice}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/products/{id}/sales [get]
+func (h *PricingHandler) GetSales(c *gin.Context) {
+	h.getSales(c, nil)
+}
+
+// ScheduleSale godoc
+// @Summary Schedule a sale price (Admin only)
+// @Description Schedule a sale price for any product
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param sale body SaleRequest true "Sale price and period"
+// @Success 201 {object} models.APIResponse{data=models.SalePrice}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/products/{id}/sales [post]
+func (h *PricingHandler) ScheduleSale(c *gin.Context) {
+	h.scheduleSale(c, nil)
+}
+
+// CancelSale godoc
+// @Summary Cancel a sale (Admin only)
+// @Description Cancel a scheduled sale, or end an active sale early and restore the regular price
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param saleId path string true "Sale ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/products/{id}/sales/{saleId} [delete]
+func (h *PricingHandler) CancelSale(c *gin.Context) {
+	h.cancelSale(c, nil)
+}
+
+// GetPriceHistory godoc
+// @Summary Get product price history (Admin only)
+// @Description Get every price change of a product, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param limit query int false "Maximum entries" default(50)
+// @Success 200 {object} models.APIResponse{data=[]models.PriceHistoryEntry}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/products/{id}/price-history [get]
+func (h *PricingHandler) GetPriceHistory(c *gin.Context) {
+	h.getPriceHistory(c, nil)
+}
+
+func (h *PricingHandler) getSales(c *gin.Context, vendorID *uuid.UUID) {
+	productID, ok := parseIDParam(c, "product")
+	if !ok {
+		return
+	}
+
+	sales, err := h.service.GetSales(c.Request.Context(), vendorID, productID)
+	if err != nil {
+		respondPricingError(c, err, "Failed to get sales")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Sales retrieved successfully",
+		Data:    sales,
+	})
+}
+
+func (h *PricingHandler) scheduleSale(c *gin.Context, vendorID *uuid.UUID) {
+	productID, ok := parseIDParam(c, "product")
+	if !ok {
+		return
+	}
+
+	var req SaleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	sale := &models.SalePrice{
+		ProductID: productID,
+		SalePrice: req.SalePrice,
+		EndsAt:    req.EndsAt,
+	}
+	if req.StartsAt != nil {
+		sale.StartsAt = *req.StartsAt
+	}
+	if userID, ok := middleware.CurrentUserID(c); ok {
+		sale.CreatedBy = &userID
+	}
+
+	if err := h.service.ScheduleSale(c.Request.Context(), vendorID, sale); err != nil {
+		respondPricingError(c, err, "Failed to schedule sale")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Sale scheduled successfully",
+		Data:    sale,
+	})
+}
+
+func (h *PricingHandler) cancelSale(c *gin.Context, vendorID *uuid.UUID) {
+	productID, ok := parseIDParam(c, "product")
+	if !ok {
+		return
+	}
+
+	saleID, err := uuid.Parse(c.Param("saleId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid sale ID",
+			Error: &models.APIError{
+				Code:    "INVALID_ID",
+				Message: "ID must be a valid UUID",
+			},
+		})
+		return
+	}
+
+	if err := h.service.CancelSale(c.Request.Context(), vendorID, productID, saleID); err != nil {
+		respondPricingError(c, err, "Failed to cancel sale")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Sale cancelled successfully",
+	})
+}
+
+func (h *PricingHandler) getPriceHistory(c *gin.Context, vendorID *uuid.UUID) {
+	productID, ok := parseIDParam(c, "product")
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	history, err := h.service.GetPriceHistory(c.Request.Context(), vendorID, productID, limit)
+	if err != nil {
+		respondPricingError(c, err, "Failed to get price history")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Price history retrieved successfully",
+		Data:    history,
+	})
+}
+
+func respondPricingError(c *gin.Context, err error, message string) {
+	msg := err.Error()
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case msg == "product not found" || msg == "sale not found":
+		status, code = http.StatusNotFound, "NOT_FOUND"
+	case msg == "sale overlaps another scheduled sale" || msg == "sale has already ended":
+		status, code = http.StatusConflict, "SALE_CONFLICT"
+	case msg == "invalid product ID" || strings.Contains(msg, " must ") || strings.HasSuffix(msg, " is required"):
+		status, code = http.StatusBadRequest, "INVALID_REQUEST"
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    code,
+			Message: msg,
+		},
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 	"regexp"
+	"strings"
 
 	"smrtmart-go-postgresql/internal/models"
 	"smrtmart-go-postgresql/internal/repository"
@@ -79,7 +80,7 @@ func (h *ProductHandler) GetProducts(c *gin.Context) {
 		}
 	}
 
-	result, err := h.service.GetProducts(filters)
+	result, err := h.service.GetProducts(c.Request.Context(), filters)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
@@ -121,7 +122,7 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 	// Try parsing as numeric ID first (simpler and more user-friendly)
 	if numericID, numErr := strconv.Atoi(idStr); numErr == nil && numericID >= 1 && numericID <= 50 {
 		// It's a valid numeric ID, get product by numeric_id
-		product, err = h.service.GetProductByNumericID(numericID)
+		product, err = h.service.GetProductByNumericID(c.Request.Context(), numericID)
 	} else {
 		// Try parsing as UUID
 		uuidRegex := regexp.MustCompile(`^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-[a-fA-F0-9]{4}-[a-fA-F0-9]{4}-[a-fA-F0-9]{12}$`)
@@ -150,7 +151,7 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 			return
 		}
 		
-		product, err = h.service.GetProduct(id)
+		product, err = h.service.GetProduct(c.Request.Context(), id)
 	}
 	if err != nil {
 		if err.Error() == "product not found" {
@@ -183,9 +184,70 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 	})
 }
 
+// GetCategoryProducts godoc
+// @Summary Get products in a category
+// @Description Get products in a category and all of its subcategories
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID or slug"
+// @Param min_price query number false "Minimum price filter"
+// @Param max_price query number false "Maximum price filter"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param sort_by query string false "Sort by field" Enums(name, price, created_at)
+// @Param sort_dir query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Success 200 {object} models.PaginatedResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /categories/{id}/products [get]
+func (h *ProductHandler) GetCategoryProducts(c *gin.Context) {
+	filters := repository.ProductFilters{
+		Status:  "active",
+		SortBy:  c.Query("sort_by"),
+		SortDir: c.Query("sort_dir"),
+	}
+
+	// Parse price filters
+	if minPriceStr := c.Query("min_price"); minPriceStr != "" {
+		if minPrice, err := strconv.ParseFloat(minPriceStr, 64); err == nil {
+			filters.MinPrice = &minPrice
+		}
+	}
+	if maxPriceStr := c.Query("max_price"); maxPriceStr != "" {
+		if maxPrice, err := strconv.ParseFloat(maxPriceStr, 64); err == nil {
+			filters.MaxPrice = &maxPrice
+		}
+	}
+
+	// Parse pagination
+	if pageStr := c.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
+			filters.Page = page
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			filters.Limit = limit
+		}
+	}
+
+	result, err := h.service.GetCategoryProducts(c.Request.Context(), c.Param("id"), filters)
+	if err != nil {
+		respondCategoryError(c, err, "Failed to get category products")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Category products retrieved successfully",
+		Data:    result,
+	})
+}
+
 // SearchProducts godoc
 // @Summary Search products
-// @Description Search products by name and description
+// @Description Full-text search over product name, tags, SKU and description with typo tolerance, ranked by relevance
 // @Tags products
 // @Accept json
 // @Produce json
@@ -195,7 +257,10 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 // @Param max_price query number false "Maximum price filter"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(20)
-// @Success 200 {object} models.PaginatedResponse
+// @Param sort_by query string false "Sort by field" Enums(relevance, name, price, created_at) default(relevance)
+// @Param sort_dir query string false "Sort direction" Enums(asc, desc)
+// @Param facets query string false "Comma-separated facets to count" example(category,price)
+// @Success 200 {object} models.APIResponse{data=models.SearchResponse}
 // @Failure 400 {object} models.APIResponse
 // @Failure 500 {object} models.APIResponse
 // @Router /products/search [get]
@@ -216,6 +281,8 @@ func (h *ProductHandler) SearchProducts(c *gin.Context) {
 	filters := repository.ProductFilters{
 		Category: c.Query("category"),
 		Status:   "active", // Only search active products
+		SortBy:   c.Query("sort_by"),
+		SortDir:  c.Query("sort_dir"),
 	}
 
 	// Parse price filters
@@ -242,7 +309,12 @@ func (h *ProductHandler) SearchProducts(c *gin.Context) {
 		}
 	}
 
-	result, err := h.service.SearchProducts(query, filters)
+	var facets []string
+	if facetsStr := c.Query("facets"); facetsStr != "" {
+		facets = strings.Split(facetsStr, ",")
+	}
+
+	result, err := h.service.SearchProducts(c.Request.Context(), query, filters, facets)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
@@ -262,6 +334,59 @@ func (h *ProductHandler) SearchProducts(c *gin.Context) {
 	})
 }
 
+// SuggestProducts godoc
+// @Summary Search autocomplete suggestions
+// @Description Get matching product names, categories and popular queries for a partial search term
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param q query string true "Partial search query"
+// @Param limit query int false "Suggestions per group" default(5)
+// @Success 200 {object} models.APIResponse{data=models.SearchSuggestions}
+// @Failure 400 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /products/suggest [get]
+func (h *ProductHandler) SuggestProducts(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Search query is required",
+			Error: &models.APIError{
+				Code:    "MISSING_QUERY",
+				Message: "Search query parameter 'q' is required",
+			},
+		})
+		return
+	}
+
+	limit := 5
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	suggestions, err := h.service.SuggestProducts(c.Request.Context(), query, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get suggestions",
+			Error: &models.APIError{
+				Code:    "INTERNAL_ERROR",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Suggestions retrieved successfully",
+		Data:    suggestions,
+	})
+}
+
 // GetFeaturedProducts godoc
 // @Summary Get featured products
 // @Description Get a list of featured products
@@ -280,7 +405,7 @@ func (h *ProductHandler) GetFeaturedProducts(c *gin.Context) {
 		}
 	}
 
-	products, err := h.service.GetFeaturedProducts(limit)
+	products, err := h.service.GetFeaturedProducts(c.Request.Context(), limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
@@ -331,7 +456,7 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	// For now, we'll use a placeholder
 	product.VendorID = uuid.New()
 
-	if err := h.service.CreateProduct(&product); err != nil {
+	if err := h.service.CreateProduct(c.Request.Context(), &product); err != nil {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
 			Message: "Failed to create product",
@@ -395,7 +520,7 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 
 	product.ID = id
 
-	if err := h.service.UpdateProduct(&product); err != nil {
+	if err := h.service.UpdateProduct(c.Request.Context(), &product); err != nil {
 		if err.Error() == "product not found" {
 			c.JSON(http.StatusNotFound, models.APIResponse{
 				Success: false,
@@ -455,7 +580,7 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.DeleteProduct(id); err != nil {
+	if err := h.service.DeleteProduct(c.Request.Context(), id); err != nil {
 		if err.Error() == "product not found" {
 			c.JSON(http.StatusNotFound, models.APIResponse{
 				Success: false,
@@ -519,7 +644,7 @@ func (h *ProductHandler) GetVendorProducts(c *gin.Context) {
 		}
 	}
 
-	result, err := h.service.GetVendorProducts(vendorID, filters)
+	result, err := h.service.GetVendorProducts(c.Request.Context(), vendorID, filters)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
@@ -585,7 +710,7 @@ func (h *ProductHandler) UpdateProductStock(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.UpdateProductStock(id, req.Stock); err != nil {
+	if err := h.service.UpdateProductStock(c.Request.Context(), id, req.Stock); err != nil {
 		if err.Error() == "product not found" {
 			c.JSON(http.StatusNotFound, models.APIResponse{
 				Success: false,
@@ -626,4 +751,122 @@ func (h *ProductHandler) ToggleFeatured(c *gin.Context) {
 
 func (h *ProductHandler) UpdateProductStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Admin update product status endpoint"})
-}
\ No newline at end of file
+}
+
+// GetZeroResultSearches godoc
+// @Summary Get searches with no results (Admin only)
+// @Description List customer search queries that returned no products, most frequent first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Number of queries to return" default(50)
+// @Success 200 {object} models.APIResponse{data=[]models.SearchQueryStat}
+// @Failure 500 {object} models.APIResponse
+// @Router /admin/search/zero-results [get]
+func (h *ProductHandler) GetZeroResultSearches(c *gin.Context) {
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	stats, err := h.service.GetZeroResultSearches(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get zero-result searches",
+			Error: &models.APIError{
+				Code:    "INTERNAL_ERROR",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Zero-result searches retrieved successfully",
+		Data:    stats,
+	})
+}
+
+// RebuildSearchIndex godoc
+// @Summary Rebuild the product search index (Admin only)
+// @Description Reload every product from the database into the configured search index
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /admin/search/reindex [post]
+func (h *ProductHandler) RebuildSearchIndex(c *gin.Context) {
+	count, err := h.service.RebuildSearchIndex(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to rebuild search index",
+			Error: &models.APIError{
+				Code:    "INTERNAL_ERROR",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Search index rebuilt successfully",
+		Data:    gin.H{"indexed": count},
+	})
+}
+
+// SearchLanguageRequest is the payload for changing the search language
+type SearchLanguageRequest struct {
+	Language string `json:"language" binding:"required"` // PostgreSQL text search configuration, e.g. "swedish" or "english"
+}
+
+// SetSearchLanguage godoc
+// @Summary Set the search language (Admin only)
+// @Description Switch the text-search configuration used to stem products. If it changed, stored search vectors are refreshed by background jobs. The setting takes precedence over SEARCH_LANGUAGE from then on.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param language body SearchLanguageRequest true "Search language"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Router /admin/search/language [put]
+func (h *ProductHandler) SetSearchLanguage(c *gin.Context) {
+	var req SearchLanguageRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	changed, err := h.service.SetSearchLanguage(c.Request.Context(), req.Language)
+	if err != nil {
+		status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+		if strings.HasPrefix(err.Error(), "invalid search language") || err.Error() == "search language is required" {
+			status, code = http.StatusBadRequest, "INVALID_REQUEST"
+		}
+		c.JSON(status, models.APIResponse{
+			Success: false,
+			Message: "Failed to set search language",
+			Error: &models.APIError{
+				Code:    code,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	message := "Search language unchanged"
+	if changed {
+		message = "Search language changed, search vectors are being refreshed"
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    gin.H{"changed": changed},
+	})
+}
@@ -0,0 +1,445 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/middleware"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PromotionHandler handles promotion and coupon endpoints
+type PromotionHandler struct {
+	service       service.PromotionService
+	vendorService service.VendorService
+}
+
+func NewPromotionHandler(service service.PromotionService, vendorService service.VendorService) *PromotionHandler {
+	return &PromotionHandler{
+		service:       service,
+		vendorService: vendorService,
+	}
+}
+
+// PromotionRequest is the payload for creating or replacing a promotion
+type PromotionRequest struct {
+	Code             *string              `json:"code,omitempty"` // Leave out for an automatic promotion
+	Name             string               `json:"name" binding:"required"`
+	Description      *string              `json:"description,omitempty"`
+	Type             models.PromotionType `json:"type" binding:"required"`
+	Value            float64              `json:"value"`
+	MaxDiscount      *float64             `json:"max_discount,omitempty"`
+	MinSubtotal      *float64             `json:"min_subtotal,omitempty"`
+	BuyQuantity      *int                 `json:"buy_quantity,omitempty"`
+	GetQuantity      *int                 `json:"get_quantity,omitempty"`
+	VendorID         *uuid.UUID           `json:"vendor_id,omitempty"` // Admin only; vendors' promotions are always their own
+	ProductIDs       []int64              `json:"product_ids,omitempty"`
+	CategoryIDs      []uuid.UUID          `json:"category_ids,omitempty"`
+	Stackable        bool                 `json:"stackable"`
+	UsageLimit       *int                 `json:"usage_limit,omitempty"`
+	PerCustomerLimit *int                 `json:"per_customer_limit,omitempty"`
+	StartsAt         *time.Time           `json:"starts_at,omitempty"`
+	EndsAt           *time.Time           `json:"ends_at,omitempty"`
+	IsActive         *bool                `json:"is_active,omitempty"` // Defaults to true
+}
+
+func (r PromotionRequest) toModel() *models.Promotion {
+	isActive := true
+	if r.IsActive != nil {
+		isActive = *r.IsActive
+	}
+
+	return &models.Promotion{
+		Code:             r.Code,
+		Name:             r.Name,
+		Description:      r.Description,
+		Type:             r.Type,
+		Value:            r.Value,
+		MaxDiscount:      r.MaxDiscount,
+		MinSubtotal:      r.MinSubtotal,
+		BuyQuantity:      r.BuyQuantity,
+		GetQuantity:      r.GetQuantity,
+		VendorID:         r.VendorID,
+		ProductIDs:       r.ProductIDs,
+		CategoryIDs:      r.CategoryIDs,
+		Stackable:        r.Stackable,
+		UsageLimit:       r.UsageLimit,
+		PerCustomerLimit: r.PerCustomerLimit,
+		StartsAt:         r.StartsAt,
+		EndsAt:           r.EndsAt,
+		IsActive:         isActive,
+	}
+}
+
+// CartLineRequest is a product and quantity in the cart
+type CartLineRequest struct {
+	ProductID ProductRef `json:"product_id"`
+	Quantity  int        `json:"quantity" binding:"required,gt=0"`
+}
+
+// ApplyCouponsRequest is the payload for pricing a cart with coupons
+type ApplyCouponsRequest struct {
+	Items       []CartLineRequest `json:"items" binding:"required,min=1,dive"`
+	CouponCodes []string          `json:"coupon_codes,omitempty"`
+}
+
+// ApplyCoupons godoc
+// @Summary Apply coupons to cart
+// @Description Price the cart with the given coupon codes and any automatic promotions, using current product prices. Coupons are validated again at checkout.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param cart body ApplyCouponsRequest true "Cart items and coupon codes"
+// @Success 200 {object} models.APIResponse{data=models.PromotionQuote}
+// @Failure 400 {object} models.APIResponse
+// @Router /cart/coupons [post]
+func (h *PromotionHandler) ApplyCoupons(c *gin.Context) {
+	var req ApplyCouponsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var customerID *uuid.UUID
+	if userID, ok := middleware.CurrentUserID(c); ok {
+		customerID = &userID
+	}
+
+	lines := make([]service.CartLine, len(req.Items))
+	for i, item := range req.Items {
+		lines[i] = service.CartLine{ProductRef: string(item.ProductID), Quantity: item.Quantity}
+	}
+
+	quote, err := h.service.Quote(c.Request.Context(), customerID, lines, req.CouponCodes)
+	if err != nil {
+		respondPromotionError(c, err, "Failed to apply coupons")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Coupons applied successfully",
+		Data:    quote,
+	})
+}
+
+// GetPromotions godoc
+// @Summary List promotions (Admin only)
+// @Description Get all promotions, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Router /admin/promotions [get]
+func (h *PromotionHandler) GetPromotions(c *gin.Context) {
+	h.listPromotions(c, nil)
+}
+
+// GetPromotion godoc
+// @Summary Get promotion (Admin only)
+// @Description Get a promotion by ID
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} models.APIResponse{data=models.Promotion}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/promotions/{id} [get]
+func (h *PromotionHandler) GetPromotion(c *gin.Context) {
+	h.getPromotion(c, nil)
+}
+
+// CreatePromotion godoc
+// @Summary Create promotion (Admin only)
+// @Description Create a coupon, or an automatic promotion if no code is given
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param promotion body PromotionRequest true "Promotion"
+// @Success 201 {object} models.APIResponse{data=models.Promotion}
+// @Failure 400 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/promotions [post]
+func (h *PromotionHandler) CreatePromotion(c *gin.Context) {
+	h.createPromotion(c, nil)
+}
+
+// UpdatePromotion godoc
+// @Summary Update promotion (Admin only)
+// @Description Replace a promotion's settings
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Param promotion body PromotionRequest true "Promotion"
+// @Success 200 {object} models.APIResponse{data=models.Promotion}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/promotions/{id} [put]
+func (h *PromotionHandler) UpdatePromotion(c *gin.Context) {
+	h.updatePromotion(c, nil)
+}
+
+// DeletePromotion godoc
+// @Summary Delete promotion (Admin only)
+// @Description Delete a promotion that has never been redeemed
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/promotions/{id} [delete]
+func (h *PromotionHandler) DeletePromotion(c *gin.Context) {
+	h.deletePromotion(c, nil)
+}
+
+// GetVendorPromotions godoc
+// @Summary List own promotions (Vendor only)
+// @Description Get the authenticated vendor's promotions, newest first
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Router /vendor/promotions [get]
+func (h *PromotionHandler) GetVendorPromotions(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.listPromotions(c, &vendor.ID)
+	}
+}
+
+// GetVendorPromotion godoc
+// @Summary Get own promotion (Vendor only)
+// @Description Get one of the authenticated vendor's promotions
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} models.APIResponse{data=models.Promotion}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/promotions/{id} [get]
+func (h *PromotionHandler) GetVendorPromotion(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.getPromotion(c, &vendor.ID)
+	}
+}
+
+// CreateVendorPromotion godoc
+// @Summary Create promotion (Vendor only)
+// @Description Create a coupon or automatic promotion that applies only to your products
+// @Tags vendors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param promotion body PromotionRequest true "Promotion"
+// @Success 201 {object} models.APIResponse{data=models.Promotion}
+// @Failure 400 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /vendor/promotions [post]
+func (h *PromotionHandler) CreateVendorPromotion(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.createPromotion(c, &vendor.ID)
+	}
+}
+
+// UpdateVendorPromotion godoc
+// @Summary Update own promotion (Vendor only)
+// @Description Replace the settings of one of your promotions
+// @Tags vendors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Param promotion body PromotionRequest true "Promotion"
+// @Success 200 {object} models.APIResponse{data=models.Promotion}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /vendor/promotions/{id} [put]
+func (h *PromotionHandler) UpdateVendorPromotion(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.updatePromotion(c, &vendor.ID)
+	}
+}
+
+// DeleteVendorPromotion godoc
+// @Summary Delete own promotion (Vendor only)
+// @Description Delete one of your promotions that has never been redeemed
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/promotions/{id} [delete]
+func (h *PromotionHandler) DeleteVendorPromotion(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.deletePromotion(c, &vendor.ID)
+	}
+}
+
+func (h *PromotionHandler) listPromotions(c *gin.Context, vendorID *uuid.UUID) {
+	page, limit := 1, 20
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	result, err := h.service.GetPromotions(c.Request.Context(), vendorID, page, limit)
+	if err != nil {
+		respondPromotionError(c, err, "Failed to get promotions")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Promotions retrieved successfully",
+		Data:    result,
+	})
+}
+
+func (h *PromotionHandler) getPromotion(c *gin.Context, vendorID *uuid.UUID) {
+	id, ok := parseIDParam(c, "promotion")
+	if !ok {
+		return
+	}
+
+	promotion, err := h.service.GetPromotion(c.Request.Context(), vendorID, id)
+	if err != nil {
+		respondPromotionError(c, err, "Failed to get promotion")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Promotion retrieved successfully",
+		Data:    promotion,
+	})
+}
+
+func (h *PromotionHandler) createPromotion(c *gin.Context, vendorID *uuid.UUID) {
+	var req PromotionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	promotion := req.toModel()
+	if userID, ok := middleware.CurrentUserID(c); ok {
+		promotion.CreatedBy = &userID
+	}
+
+	if err := h.service.CreatePromotion(c.Request.Context(), vendorID, promotion); err != nil {
+		respondPromotionError(c, err, "Failed to create promotion")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Promotion created successfully",
+		Data:    promotion,
+	})
+}
+
+func (h *PromotionHandler) updatePromotion(c *gin.Context, vendorID *uuid.UUID) {
+	id, ok := parseIDParam(c, "promotion")
+	if !ok {
+		return
+	}
+
+	var req PromotionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	promotion, err := h.service.UpdatePromotion(c.Request.Context(), vendorID, id, req.toModel())
+	if err != nil {
+		respondPromotionError(c, err, "Failed to update promotion")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Promotion updated successfully",
+		Data:    promotion,
+	})
+}
+
+func (h *PromotionHandler) deletePromotion(c *gin.Context, vendorID *uuid.UUID) {
+	id, ok := parseIDParam(c, "promotion")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeletePromotion(c.Request.Context(), vendorID, id); err != nil {
+		respondPromotionError(c, err, "Failed to delete promotion")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Promotion deleted successfully",
+	})
+}
+
+// isPromotionValidationError reports whether err is a client error from the promotion service
+func isPromotionValidationError(err error) bool {
+	msg := err.Error()
+	switch {
+	case strings.HasPrefix(msg, "coupon "), strings.HasPrefix(msg, "product "):
+		return true
+	case strings.Contains(msg, " must "), strings.Contains(msg, " is required"), strings.Contains(msg, " are required"):
+		return true
+	}
+
+	switch msg {
+	case "cart is empty", "category not found", "min_subtotal cannot be negative",
+		"promotion has been redeemed; deactivate it instead":
+		return true
+	}
+	return false
+}
+
+func respondPromotionError(c *gin.Context, err error, message string) {
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case err.Error() == "promotion not found":
+		status, code = http.StatusNotFound, "NOT_FOUND"
+	case err.Error() == "code already in use":
+		status, code = http.StatusConflict, "CODE_IN_USE"
+	case strings.HasPrefix(err.Error(), "coupon "):
+		status, code = http.StatusBadRequest, "INVALID_COUPON"
+	case isPromotionValidationError(err):
+		status, code = http.StatusBadRequest, "INVALID_REQUEST"
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+}
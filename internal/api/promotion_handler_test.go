@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// recordingPromotionService records the promotions created
+type recordingPromotionService struct {
+	service.PromotionService
+	created []*models.Promotion
+}
+
+func (s *recordingPromotionService) CreatePromotion(_ context.Context, _ *uuid.UUID, promotion *models.Promotion) error {
+	promotion.ID = uuid.New()
+	s.created = append(s.created, promotion)
+	return nil
+}
+
+func TestAdminPromotionsOnlyForAdmins(t *testing.T) {
+	promotions := &recordingPromotionService{}
+	router := newTestRouter(t, &service.Services{Promotion: promotions})
+	assertAdminOnly(t, router, "/api/v1/admin/promotions")
+
+	body := `{"code":"SUMMER","name":"Summer sale","type":"percentage","value":90}`
+	for _, role := range []models.UserRole{models.RoleCustomer, models.RoleVendor, models.RoleAdmin} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/promotions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", bearerToken(t, role))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		want := http.StatusForbidden
+		if role == models.RoleAdmin {
+			want = http.StatusCreated
+		}
+		if w.Code != want {
+			t.Errorf("create as %s: status %d, want %d", role, w.Code, want)
+		}
+	}
+	if len(promotions.created) != 1 || promotions.created[0].CreatedBy == nil {
+		t.Errorf("created %+v, want only the admin's promotion with its creator", promotions.created)
+	}
+}
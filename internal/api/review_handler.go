@@ -0,0 +1,693 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"smrtmart-go-postgresql/internal/middleware"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ReviewHandler handles review endpoints
+type ReviewHandler struct {
+	service       service.ReviewService
+	vendorService service.VendorService
+}
+
+func NewReviewHandler(service service.ReviewService, vendorService service.VendorService) *ReviewHandler {
+	return &ReviewHandler{
+		service:       service,
+		vendorService: vendorService,
+	}
+}
+
+// ProductRef identifies a product by its numeric ID or UUID. It accepts a JSON number or string.
+type ProductRef string
+
+func (r *ProductRef) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*r = ProductRef(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return errors.New("product_id must be a numeric ID or UUID")
+	}
+	*r = ProductRef(n.String())
+	return nil
+}
+
+// ReviewRequest is the payload for writing a review
+type ReviewRequest struct {
+	ProductID ProductRef `json:"product_id,omitempty"` // Required when creating a review
+	Rating    int        `json:"rating" binding:"required,min=1,max=5"`
+	Title     *string    `json:"title,omitempty"`
+	Comment   *string    `json:"comment,omitempty"`
+}
+
+// ReviewReportRequest is the payload for reporting a review
+type ReviewReportRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ReviewReplyRequest is the payload for a vendor's reply to a review
+type ReviewReplyRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// ReviewModerationRequest is the payload for publishing or rejecting a review
+type ReviewModerationRequest struct {
+	Status models.ReviewStatus `json:"status" binding:"required"`
+	Reason *string             `json:"reason,omitempty"` // Required when rejecting
+}
+
+// RemoveReplyRequest is the payload for removing a vendor reply
+type RemoveReplyRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+func (r ReviewRequest) toModel() *models.Review {
+	return &models.Review{
+		Rating:  r.Rating,
+		Title:   r.Title,
+		Comment: r.Comment,
+	}
+}
+
+// CreateReview godoc
+// @Summary Review a product
+// @Description Write a review of a product. Each customer can review a product once; reviews of delivered purchases are marked verified. Reviews containing links or blocked language are held for moderation.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param review body ReviewRequest true "Review"
+// @Success 201 {object} models.APIResponse{data=models.Review}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /reviews [post]
+func (h *ReviewHandler) CreateReview(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.ProductID == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: "product_id is required",
+			},
+		})
+		return
+	}
+
+	review := req.toModel()
+	if err := h.service.CreateReview(c.Request.Context(), userID, string(req.ProductID), review); err != nil {
+		respondReviewError(c, err, "Failed to create review", "CREATION_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Review created successfully",
+		Data:    review,
+	})
+}
+
+// UpdateReview godoc
+// @Summary Update own review
+// @Description Change the rating, title or comment of one of your reviews. Editing a rejected review resubmits it for moderation.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Param review body ReviewRequest true "Review"
+// @Success 200 {object} models.APIResponse{data=models.Review}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /reviews/{id} [put]
+func (h *ReviewHandler) UpdateReview(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "review")
+	if !ok {
+		return
+	}
+
+	var req ReviewRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	review, err := h.service.UpdateReview(c.Request.Context(), userID, id, req.toModel())
+	if err != nil {
+		respondReviewError(c, err, "Failed to update review", "UPDATE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Review updated successfully",
+		Data:    review,
+	})
+}
+
+// DeleteReview godoc
+// @Summary Delete own review
+// @Description Delete one of your reviews
+// @Tags reviews
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /reviews/{id} [delete]
+func (h *ReviewHandler) DeleteReview(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "review")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteReview(c.Request.Context(), userID, id); err != nil {
+		respondReviewError(c, err, "Failed to delete review", "DELETION_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Review deleted successfully",
+	})
+}
+
+// GetProductReviews godoc
+// @Summary List product reviews
+// @Description Get a page of a product's published reviews
+// @Tags reviews
+// @Produce json
+// @Param id path string true "Product ID (numeric or UUID)"
+// @Param sort query string false "Sort order" Enums(newest, helpful, rating, rating_asc) default(newest)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /products/{id}/reviews [get]
+func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
+	page, limit := 1, 10
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	result, err := h.service.GetProductReviews(c.Request.Context(), c.Param("id"), c.Query("sort"), page, limit)
+	if err != nil {
+		if err.Error() == "invalid sort" {
+			respondReviewError(c, err, "Failed to get reviews", "INVALID_SORT")
+			return
+		}
+		if err.Error() == "product not found" {
+			respondReviewError(c, err, "Failed to get reviews", "")
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get reviews",
+			Error: &models.APIError{
+				Code:    "INTERNAL_ERROR",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Reviews retrieved successfully",
+		Data:    result,
+	})
+}
+
+// VoteHelpful godoc
+// @Summary Mark review as helpful
+// @Description Vote that a published review was helpful. Voting again has no effect.
+// @Tags reviews
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Success 200 {object} models.APIResponse{data=models.Review}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /reviews/{id}/helpful [post]
+func (h *ReviewHandler) VoteHelpful(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "review")
+	if !ok {
+		return
+	}
+
+	review, err := h.service.VoteHelpful(c.Request.Context(), userID, id)
+	if err != nil {
+		respondReviewError(c, err, "Failed to vote on review", "VOTE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vote recorded successfully",
+		Data:    review,
+	})
+}
+
+// RemoveHelpfulVote godoc
+// @Summary Withdraw helpful vote
+// @Description Withdraw your helpful vote from a review
+// @Tags reviews
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Success 200 {object} models.APIResponse{data=models.Review}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /reviews/{id}/helpful [delete]
+func (h *ReviewHandler) RemoveHelpfulVote(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "review")
+	if !ok {
+		return
+	}
+
+	review, err := h.service.RemoveHelpfulVote(c.Request.Context(), userID, id)
+	if err != nil {
+		respondReviewError(c, err, "Failed to remove vote", "VOTE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vote removed successfully",
+		Data:    review,
+	})
+}
+
+// ReportReview godoc
+// @Summary Report review
+// @Description Report a published review as abusive. Reviews with enough reports are held for moderation.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Param report body ReviewReportRequest true "Report"
+// @Success 201 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /reviews/{id}/report [post]
+func (h *ReviewHandler) ReportReview(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "review")
+	if !ok {
+		return
+	}
+
+	var req ReviewReportRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.service.ReportReview(c.Request.Context(), userID, id, req.Reason); err != nil {
+		respondReviewError(c, err, "Failed to report review", "REPORT_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Review reported successfully",
+	})
+}
+
+// SetReply godoc
+// @Summary Reply to review (Vendor only)
+// @Description Add or replace your public reply to a published review of one of your products
+// @Tags vendors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Param reply body ReviewReplyRequest true "Reply"
+// @Success 200 {object} models.APIResponse{data=models.Review}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/reviews/{id}/reply [put]
+func (h *ReviewHandler) SetReply(c *gin.Context) {
+	vendor, ok := currentVendor(c, h.vendorService)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "review")
+	if !ok {
+		return
+	}
+
+	var req ReviewReplyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	review, err := h.service.SetReply(c.Request.Context(), vendor.ID, id, req.Body)
+	if err != nil {
+		respondReviewError(c, err, "Failed to reply to review", "REPLY_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Reply saved successfully",
+		Data:    review,
+	})
+}
+
+// DeleteReply godoc
+// @Summary Delete review reply (Vendor only)
+// @Description Delete your reply to a review of one of your products
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/reviews/{id}/reply [delete]
+func (h *ReviewHandler) DeleteReply(c *gin.Context) {
+	vendor, ok := currentVendor(c, h.vendorService)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "review")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteReply(c.Request.Context(), vendor.ID, id); err != nil {
+		respondReviewError(c, err, "Failed to delete reply", "DELETION_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Reply deleted successfully",
+	})
+}
+
+// GetModerationQueue godoc
+// @Summary List reviews for moderation (Admin only)
+// @Description Get reviews by moderation status. Pending reviews are listed oldest first.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Review status" Enums(pending, published, rejected, all) default(pending)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Failure 400 {object} models.APIResponse
+// @Router /admin/reviews [get]
+func (h *ReviewHandler) GetModerationQueue(c *gin.Context) {
+	page, limit := 1, 20
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	result, err := h.service.GetModerationQueue(c.Request.Context(), c.Query("status"), page, limit)
+	if err != nil {
+		if err.Error() == "invalid review status" {
+			respondReviewError(c, err, "Invalid review status", "INVALID_STATUS")
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get reviews",
+			Error: &models.APIError{
+				Code:    "INTERNAL_ERROR",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Reviews retrieved successfully",
+		Data:    result,
+	})
+}
+
+// GetReviewModeration godoc
+// @Summary Get review for moderation (Admin only)
+// @Description Get a review with its reports and moderation history
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Success 200 {object} models.APIResponse{data=models.ReviewModeration}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/reviews/{id} [get]
+func (h *ReviewHandler) GetReviewModeration(c *gin.Context) {
+	id, ok := parseIDParam(c, "review")
+	if !ok {
+		return
+	}
+
+	moderation, err := h.service.GetReviewModeration(c.Request.Context(), id)
+	if err != nil {
+		if err.Error() == "review not found" {
+			respondReviewError(c, err, "Failed to get review", "")
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get review",
+			Error: &models.APIError{
+				Code:    "INTERNAL_ERROR",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Review retrieved successfully",
+		Data:    moderation,
+	})
+}
+
+// ModerateReview godoc
+// @Summary Publish or reject review (Admin only)
+// @Description Publish or reject a review. A reason is required when rejecting. The decision is recorded in the moderation log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Param decision body ReviewModerationRequest true "Moderation decision"
+// @Success 200 {object} models.APIResponse{data=models.Review}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/reviews/{id}/status [put]
+func (h *ReviewHandler) ModerateReview(c *gin.Context) {
+	id, ok := parseIDParam(c, "review")
+	if !ok {
+		return
+	}
+
+	var req ReviewModerationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var actorID *uuid.UUID
+	if adminID, ok := middleware.CurrentUserID(c); ok {
+		actorID = &adminID
+	}
+
+	review, err := h.service.Moderate(c.Request.Context(), id, req.Status, req.Reason, actorID)
+	if err != nil {
+		respondReviewError(c, err, "Failed to moderate review", "UPDATE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Review status updated successfully",
+		Data:    review,
+	})
+}
+
+// RemoveReply godoc
+// @Summary Remove vendor reply (Admin only)
+// @Description Remove an abusive vendor reply from a review. The removal is recorded in the moderation log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Param removal body RemoveReplyRequest true "Removal reason"
+// @Success 200 {object} models.APIResponse{data=models.Review}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/reviews/{id}/reply [delete]
+func (h *ReviewHandler) RemoveReply(c *gin.Context) {
+	id, ok := parseIDParam(c, "review")
+	if !ok {
+		return
+	}
+
+	var req RemoveReplyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var actorID *uuid.UUID
+	if adminID, ok := middleware.CurrentUserID(c); ok {
+		actorID = &adminID
+	}
+
+	review, err := h.service.RemoveReply(c.Request.Context(), id, req.Reason, actorID)
+	if err != nil {
+		respondReviewError(c, err, "Failed to remove reply", "DELETION_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Reply removed successfully",
+		Data:    review,
+	})
+}
+
+// bindJSON binds the request body, responding with 400 if it is invalid
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			},
+		})
+		return false
+	}
+	return true
+}
+
+func respondReviewError(c *gin.Context, err error, message, code string) {
+	switch err.Error() {
+	case "review not found", "product not found", "reply not found":
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: message,
+			Error: &models.APIError{
+				Code:    "NOT_FOUND",
+				Message: err.Error(),
+			},
+		})
+	case "product already reviewed":
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: message,
+			Error: &models.APIError{
+				Code:    "ALREADY_REVIEWED",
+				Message: "You have already reviewed this product",
+			},
+		})
+	case "review already reported":
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Message: message,
+			Error: &models.APIError{
+				Code:    "ALREADY_REPORTED",
+				Message: "You have already reported this review",
+			},
+		})
+	default:
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: message,
+			Error: &models.APIError{
+				Code:    code,
+				Message: err.Error(),
+			},
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/health"
 	"smrtmart-go-postgresql/internal/middleware"
 	"smrtmart-go-postgresql/internal/service"
 
@@ -12,7 +13,15 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Config) {
+func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Config, checker *health.Checker) {
+	// Liveness and readiness probes
+	healthHandler := NewHealthHandler(checker)
+	router.GET("/livez", healthHandler.Livez)
+	router.GET("/readyz", healthHandler.Readyz)
+
+	// Prometheus metrics
+	router.GET("/metrics", NewMetricsHandler(cfg.Metrics.Token).Metrics)
+
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -50,15 +59,30 @@ func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Con
 				products.GET("", productHandler.GetProducts)
 				products.GET("/:id", productHandler.GetProduct)
 				products.GET("/search", productHandler.SearchProducts)
+				products.GET("/suggest", productHandler.SuggestProducts)
 				products.GET("/featured", productHandler.GetFeaturedProducts)
+
+				reviewHandler := NewReviewHandler(services.Review, services.Vendor)
+				products.GET("/:id/reviews", reviewHandler.GetProductReviews)
 			}
 
 			// Categories
 			categories := public.Group("/categories")
 			{
 				categoryHandler := NewCategoryHandler(services.Category)
+				productHandler := NewProductHandler(services.Product)
 				categories.GET("", categoryHandler.GetCategories)
+				categories.GET("/tree", categoryHandler.GetCategoryTree)
 				categories.GET("/:id", categoryHandler.GetCategory)
+				categories.GET("/:id/breadcrumbs", categoryHandler.GetCategoryBreadcrumbs)
+				categories.GET("/:id/products", productHandler.GetCategoryProducts)
+			}
+
+			// Vendor storefronts
+			vendors := public.Group("/vendors")
+			{
+				vendorHandler := NewVendorHandler(services.Vendor)
+				vendors.GET("/:id", vendorHandler.GetStorefront)
 			}
 
 			// Authentication
@@ -68,6 +92,7 @@ func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Con
 				auth.POST("/register", authHandler.Register)
 				auth.POST("/login", authHandler.Login)
 				auth.POST("/refresh", authHandler.RefreshToken)
+				auth.POST("/verify-email", authHandler.VerifyEmail)
 				auth.POST("/forgot-password", authHandler.ForgotPassword)
 				auth.POST("/reset-password", authHandler.ResetPassword)
 			}
@@ -76,33 +101,47 @@ func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Con
 			cart := public.Group("/cart")
 			{
 				cartHandler := NewCartHandler(services.Cart)
-				cart.Use(middleware.OptionalJWTAuth())
+				cart.Use(middleware.OptionalJWTAuth(cfg.JWT.Secret))
 				cart.GET("", cartHandler.GetCart)
 				cart.POST("/items", cartHandler.AddItem)
 				cart.PUT("/items/:id", cartHandler.UpdateItem)
 				cart.DELETE("/items/:id", cartHandler.RemoveItem)
 				cart.DELETE("", cartHandler.ClearCart)
 				cart.POST("/clear", cartHandler.ClearCart)  // Additional endpoint for frontend compatibility
+
+				promotionHandler := NewPromotionHandler(services.Promotion, services.Vendor)
+				cart.POST("/coupons", promotionHandler.ApplyCoupons)
+
+				shippingHandler := NewShippingHandler(services.Shipping)
+				cart.POST("/shipping", shippingHandler.QuoteShipping)
 			}
 
+			// Shared wishlists (read-only)
+			wishlistHandler := NewWishlistHandler(services.Wishlist)
+			public.GET("/wishlists/shared/:token", wishlistHandler.GetSharedWishlist)
+
+			// Gift card balance check
+			giftCardHandler := NewGiftCardHandler(services.GiftCard)
+			public.POST("/gift-cards/balance", giftCardHandler.CheckBalance)
+
 			// Orders (checkout)
 			orders := public.Group("/orders")
 			{
-				paymentHandler := NewPaymentHandler(services.Payment)
-				orders.POST("/checkout", paymentHandler.CreateCheckoutSession)
+				paymentHandler := NewPaymentHandler(services.Payment, services.Promotion, services.GiftCard, services.Shipping)
+				orders.POST("/checkout", middleware.OptionalJWTAuth(cfg.JWT.Secret), paymentHandler.CreateCheckoutSession)
 			}
 
 			// Payment webhooks
 			webhooks := public.Group("/webhooks")
 			{
-				paymentHandler := NewPaymentHandler(services.Payment)
+				paymentHandler := NewPaymentHandler(services.Payment, services.Promotion, services.GiftCard, services.Shipping)
 				webhooks.POST("/stripe", paymentHandler.StripeWebhook)
 			}
 		}
 
 		// Protected routes (require authentication)
 		protected := v1.Group("/")
-		protected.Use(middleware.JWTAuth())
+		protected.Use(middleware.JWTAuth(cfg.JWT.Secret))
 		{
 			// User profile
 			users := protected.Group("/users")
@@ -120,27 +159,65 @@ func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Con
 				orders.GET("", orderHandler.GetUserOrders)
 				orders.GET("/:id", orderHandler.GetOrder)
 				orders.POST("/:id/cancel", orderHandler.CancelOrder)
+
+				shipmentHandler := NewShipmentHandler(services.Shipment, services.Vendor)
+				orders.GET("/:id/shipments", shipmentHandler.GetMyOrderShipments)
 			}
 
 			// Reviews
 			reviews := protected.Group("/reviews")
 			{
-				reviewHandler := NewReviewHandler(services.Review)
+				reviewHandler := NewReviewHandler(services.Review, services.Vendor)
 				reviews.POST("", reviewHandler.CreateReview)
 				reviews.PUT("/:id", reviewHandler.UpdateReview)
 				reviews.DELETE("/:id", reviewHandler.DeleteReview)
+				reviews.POST("/:id/helpful", reviewHandler.VoteHelpful)
+				reviews.DELETE("/:id/helpful", reviewHandler.RemoveHelpfulVote)
+				reviews.POST("/:id/report", reviewHandler.ReportReview)
+			}
+
+			// Wishlists
+			wishlists := protected.Group("/wishlists")
+			{
+				wishlistHandler := NewWishlistHandler(services.Wishlist)
+				wishlists.GET("", wishlistHandler.GetWishlists)
+				wishlists.POST("", wishlistHandler.CreateWishlist)
+				wishlists.POST("/items", wishlistHandler.AddItem)
+				wishlists.POST("/move-from-cart", wishlistHandler.MoveFromCart)
+				wishlists.GET("/:id", wishlistHandler.GetWishlist)
+				wishlists.PUT("/:id", wishlistHandler.UpdateWishlist)
+				wishlists.DELETE("/:id", wishlistHandler.DeleteWishlist)
+				wishlists.DELETE("/:id/items/:productId", wishlistHandler.RemoveItem)
+				wishlists.POST("/:id/items/:productId/move-to-cart", wishlistHandler.MoveToCart)
+				wishlists.POST("/:id/share", wishlistHandler.ShareWishlist)
+				wishlists.DELETE("/:id/share", wishlistHandler.UnshareWishlist)
+			}
+
+			// Store credit
+			giftCardHandler := NewGiftCardHandler(services.GiftCard)
+			protected.GET("/store-credit", giftCardHandler.GetMyStoreCredit)
+
+			// Vendor applications
+			vendors := protected.Group("/vendors")
+			{
+				vendorHandler := NewVendorHandler(services.Vendor)
+				vendors.POST("/apply", vendorHandler.ApplyAsVendor)
 			}
 		}
 
 		// Vendor routes
 		vendor := v1.Group("/vendor")
-		vendor.Use(middleware.JWTAuth(), middleware.RequireVendor())
+		vendor.Use(middleware.JWTAuth(cfg.JWT.Secret), middleware.RequireVendor())
 		{
 			// Vendor profile
-			vendor.GET("/profile", func(c *gin.Context) {
-				// TODO: Implement vendor profile handler
-				c.JSON(http.StatusOK, gin.H{"message": "Vendor profile endpoint"})
-			})
+			vendorHandler := NewVendorHandler(services.Vendor)
+			vendor.GET("/profile", vendorHandler.GetProfile)
+			vendor.PUT("/profile", vendorHandler.UpdateProfile)
+
+			// Vendor earnings
+			ledgerHandler := NewLedgerHandler(services.Ledger, services.Vendor)
+			vendor.GET("/balance", ledgerHandler.GetVendorBalance)
+			vendor.GET("/statement", ledgerHandler.GetVendorStatement)
 
 			// Vendor products
 			products := vendor.Group("/products")
@@ -151,6 +228,31 @@ func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Con
 				products.PUT("/:id", productHandler.UpdateProduct)
 				products.DELETE("/:id", productHandler.DeleteProduct)
 				products.PATCH("/:id/stock", productHandler.UpdateProductStock)
+
+				pricingHandler := NewPricingHandler(services.Pricing, services.Vendor)
+				products.GET("/:id/sales", pricingHandler.GetVendorSales)
+				products.POST("/:id/sales", pricingHandler.ScheduleVendorSale)
+				products.DELETE("/:id/sales/:saleId", pricingHandler.CancelVendorSale)
+				products.GET("/:id/price-history", pricingHandler.GetVendorPriceHistory)
+			}
+
+			// Vendor replies to product reviews
+			reviews := vendor.Group("/reviews")
+			{
+				reviewHandler := NewReviewHandler(services.Review, services.Vendor)
+				reviews.PUT("/:id/reply", reviewHandler.SetReply)
+				reviews.DELETE("/:id/reply", reviewHandler.DeleteReply)
+			}
+
+			// Vendor promotions
+			promotions := vendor.Group("/promotions")
+			{
+				promotionHandler := NewPromotionHandler(services.Promotion, services.Vendor)
+				promotions.GET("", promotionHandler.GetVendorPromotions)
+				promotions.POST("", promotionHandler.CreateVendorPromotion)
+				promotions.GET("/:id", promotionHandler.GetVendorPromotion)
+				promotions.PUT("/:id", promotionHandler.UpdateVendorPromotion)
+				promotions.DELETE("/:id", promotionHandler.DeleteVendorPromotion)
 			}
 
 			// Vendor orders
@@ -159,12 +261,39 @@ func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Con
 				orderHandler := NewOrderHandler(services.Order)
 				orders.GET("", orderHandler.GetVendorOrders)
 				orders.PUT("/:id/status", orderHandler.UpdateOrderStatus)
+
+				shipmentHandler := NewShipmentHandler(services.Shipment, services.Vendor)
+				orders.GET("/:id/shipments", shipmentHandler.GetVendorOrderShipments)
+				orders.POST("/:id/shipments", shipmentHandler.CreateVendorShipment)
+			}
+
+			// Vendor shipments
+			shipments := vendor.Group("/shipments")
+			{
+				shipmentHandler := NewShipmentHandler(services.Shipment, services.Vendor)
+				shipments.GET("/:id/label", shipmentHandler.GetVendorShipmentLabel)
+				shipments.POST("/:id/events", shipmentHandler.AddVendorShipmentEvent)
+				shipments.DELETE("/:id", shipmentHandler.CancelVendorShipment)
+			}
+
+			// Vendor webhook endpoints
+			webhooks := vendor.Group("/webhooks")
+			{
+				webhookHandler := NewWebhookHandler(services.Webhook, services.Vendor)
+				webhooks.GET("", webhookHandler.GetVendorWebhooks)
+				webhooks.POST("", webhookHandler.CreateVendorWebhook)
+				webhooks.GET("/:id", webhookHandler.GetVendorWebhook)
+				webhooks.PUT("/:id", webhookHandler.UpdateVendorWebhook)
+				webhooks.POST("/:id/rotate-secret", webhookHandler.RotateVendorWebhookSecret)
+				webhooks.DELETE("/:id", webhookHandler.DeleteVendorWebhook)
+				webhooks.GET("/:id/deliveries", webhookHandler.GetVendorWebhookDeliveries)
+				webhooks.POST("/:id/deliveries/:deliveryId/redeliver", webhookHandler.RedeliverVendorWebhook)
 			}
 		}
 
 		// Admin routes
 		admin := v1.Group("/admin")
-		admin.Use(middleware.JWTAuth(), middleware.RequireAdmin())
+		admin.Use(middleware.JWTAuth(cfg.JWT.Secret), middleware.RequireAdmin())
 		{
 			// User management
 			users := admin.Group("/users")
@@ -184,6 +313,88 @@ func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Con
 				vendors.GET("/:id", vendorHandler.GetVendor)
 				vendors.PUT("/:id/status", vendorHandler.UpdateVendorStatus)
 				vendors.POST("/:id/verify", vendorHandler.VerifyVendor)
+				vendors.PUT("/:id/payout-account", vendorHandler.SetPayoutAccount)
+			}
+
+			// Commissions
+			commissions := admin.Group("/commissions")
+			{
+				ledgerHandler := NewLedgerHandler(services.Ledger, services.Vendor)
+				commissions.GET("", ledgerHandler.GetCommissionRates)
+				commissions.PUT("", ledgerHandler.SetCommissionRate)
+				commissions.DELETE("/:id", ledgerHandler.DeleteCommissionRate)
+			}
+
+			// Vendor ledger
+			ledger := admin.Group("/ledger")
+			{
+				ledgerHandler := NewLedgerHandler(services.Ledger, services.Vendor)
+				ledger.POST("/orders/:id", ledgerHandler.RecordOrder)
+				ledger.POST("/refunds", ledgerHandler.RecordRefund)
+			}
+
+			// Vendor payouts
+			payouts := admin.Group("/payouts")
+			{
+				ledgerHandler := NewLedgerHandler(services.Ledger, services.Vendor)
+				payouts.GET("", ledgerHandler.GetPayoutBatches)
+				payouts.POST("", ledgerHandler.CreatePayoutBatch)
+				payouts.GET("/:id", ledgerHandler.GetPayoutBatch)
+				payouts.POST("/:id/execute", ledgerHandler.ExecutePayoutBatch)
+			}
+
+			// Review moderation
+			reviews := admin.Group("/reviews")
+			{
+				reviewHandler := NewReviewHandler(services.Review, services.Vendor)
+				reviews.GET("", reviewHandler.GetModerationQueue)
+				reviews.GET("/:id", reviewHandler.GetReviewModeration)
+				reviews.PUT("/:id/status", reviewHandler.ModerateReview)
+				reviews.DELETE("/:id/reply", reviewHandler.RemoveReply)
+			}
+
+			// Promotions
+			promotions := admin.Group("/promotions")
+			{
+				promotionHandler := NewPromotionHandler(services.Promotion, services.Vendor)
+				promotions.GET("", promotionHandler.GetPromotions)
+				promotions.POST("", promotionHandler.CreatePromotion)
+				promotions.GET("/:id", promotionHandler.GetPromotion)
+				promotions.PUT("/:id", promotionHandler.UpdatePromotion)
+				promotions.DELETE("/:id", promotionHandler.DeletePromotion)
+			}
+
+			// Gift cards
+			giftCards := admin.Group("/gift-cards")
+			{
+				giftCardHandler := NewGiftCardHandler(services.GiftCard)
+				giftCards.GET("", giftCardHandler.GetGiftCards)
+				giftCards.GET("/:id", giftCardHandler.GetGiftCard)
+				giftCards.PUT("/:id/status", giftCardHandler.UpdateGiftCardStatus)
+				giftCards.POST("", giftCardHandler.IssueGiftCard)
+				giftCards.POST("/:id/adjust", giftCardHandler.AdjustGiftCard)
+			}
+
+			// Store credit
+			storeCredit := admin.Group("/store-credit")
+			{
+				giftCardHandler := NewGiftCardHandler(services.GiftCard)
+				storeCredit.GET("/:id", giftCardHandler.GetStoreCredit)
+				storeCredit.POST("/:id/adjust", giftCardHandler.AdjustStoreCredit)
+			}
+
+			// Shipping zones and methods
+			shipping := admin.Group("/shipping")
+			{
+				shippingHandler := NewShippingHandler(services.Shipping)
+				shipping.GET("/zones", shippingHandler.GetShippingZones)
+				shipping.POST("/zones", shippingHandler.CreateShippingZone)
+				shipping.GET("/zones/:id", shippingHandler.GetShippingZone)
+				shipping.PUT("/zones/:id", shippingHandler.UpdateShippingZone)
+				shipping.DELETE("/zones/:id", shippingHandler.DeleteShippingZone)
+				shipping.POST("/zones/:id/methods", shippingHandler.CreateShippingMethod)
+				shipping.PUT("/methods/:id", shippingHandler.UpdateShippingMethod)
+				shipping.DELETE("/methods/:id", shippingHandler.DeleteShippingMethod)
 			}
 
 			// Product management
@@ -193,6 +404,21 @@ func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Con
 				products.GET("", productHandler.GetAllProducts)
 				products.PUT("/:id/featured", productHandler.ToggleFeatured)
 				products.PUT("/:id/status", productHandler.UpdateProductStatus)
+
+				pricingHandler := NewPricingHandler(services.Pricing, services.Vendor)
+				products.GET("/:id/sales", pricingHandler.GetSales)
+				products.POST("/:id/sales", pricingHandler.ScheduleSale)
+				products.DELETE("/:id/sales/:saleId", pricingHandler.CancelSale)
+				products.GET("/:id/price-history", pricingHandler.GetPriceHistory)
+			}
+
+			// Search insights
+			search := admin.Group("/search")
+			{
+				productHandler := NewProductHandler(services.Product)
+				search.GET("/zero-results", productHandler.GetZeroResultSearches)
+				search.POST("/reindex", productHandler.RebuildSearchIndex)
+				search.PUT("/language", productHandler.SetSearchLanguage)
 			}
 
 			// Category management
@@ -200,6 +426,7 @@ func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Con
 			{
 				categoryHandler := NewCategoryHandler(services.Category)
 				categories.POST("", categoryHandler.CreateCategory)
+				categories.PUT("/reorder", categoryHandler.ReorderCategories)
 				categories.PUT("/:id", categoryHandler.UpdateCategory)
 				categories.DELETE("/:id", categoryHandler.DeleteCategory)
 			}
@@ -210,6 +437,51 @@ func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Con
 				orderHandler := NewOrderHandler(services.Order)
 				orders.GET("", orderHandler.GetAllOrders)
 				orders.PUT("/:id/status", orderHandler.UpdateOrderStatus)
+
+				shipmentHandler := NewShipmentHandler(services.Shipment, services.Vendor)
+				orders.GET("/:id/shipments", shipmentHandler.GetOrderShipments)
+				orders.POST("/:id/shipments", shipmentHandler.CreateShipment)
+			}
+
+			// Shipment management
+			shipments := admin.Group("/shipments")
+			{
+				shipmentHandler := NewShipmentHandler(services.Shipment, services.Vendor)
+				shipments.GET("/:id/label", shipmentHandler.GetShipmentLabel)
+				shipments.POST("/:id/events", shipmentHandler.AddShipmentEvent)
+				shipments.DELETE("/:id", shipmentHandler.CancelShipment)
+			}
+
+			// Background job queue
+			jobs := admin.Group("/jobs")
+			{
+				jobHandler := NewJobHandler(services.Job)
+				jobs.GET("", jobHandler.GetJobs)
+				jobs.GET("/stats", jobHandler.GetJobStats)
+				jobs.GET("/:id", jobHandler.GetJob)
+				jobs.POST("/:id/retry", jobHandler.RetryJob)
+			}
+
+			// Domain event log
+			events := admin.Group("/events")
+			{
+				eventHandler := NewEventHandler(services.Event)
+				events.GET("", eventHandler.GetEvents)
+				events.GET("/:id", eventHandler.GetEvent)
+			}
+
+			// Outbound webhook endpoints
+			webhooks := admin.Group("/webhooks")
+			{
+				webhookHandler := NewWebhookHandler(services.Webhook, services.Vendor)
+				webhooks.GET("", webhookHandler.GetWebhooks)
+				webhooks.POST("", webhookHandler.CreateWebhook)
+				webhooks.GET("/:id", webhookHandler.GetWebhook)
+				webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+				webhooks.POST("/:id/rotate-secret", webhookHandler.RotateWebhookSecret)
+				webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+				webhooks.GET("/:id/deliveries", webhookHandler.GetWebhookDeliveries)
+				webhooks.POST("/:id/deliveries/:deliveryId/redeliver", webhookHandler.RedeliverWebhook)
 			}
 
 			// Database migration management
@@ -220,7 +492,7 @@ func SetupRoutes(router *gin.Engine, services *service.Services, cfg *config.Con
 
 		// File upload routes
 		upload := v1.Group("/upload")
-		upload.Use(middleware.JWTAuth())
+		upload.Use(middleware.JWTAuth(cfg.JWT.Secret))
 		{
 			uploadHandler := NewUploadHandler(services.Upload)
 			upload.POST("/image", uploadHandler.UploadImage)
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/health"
+	"smrtmart-go-postgresql/internal/jwt"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const testJWTSecret = "test-secret"
+
+// newTestRouter builds the real route table over the given services. Requests
+// reaching a service left nil panic, so with empty services only send requests
+// that authentication rejects.
+func newTestRouter(t *testing.T, services *service.Services) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetupRoutes(router, services, &config.Config{JWT: config.JWTConfig{Secret: testJWTSecret}}, health.NewChecker(time.Second))
+	return router
+}
+
+func bearerToken(t *testing.T, role models.UserRole) string {
+	t.Helper()
+	signed, err := jwt.Sign(testJWTSecret, jwt.Claims{UserID: uuid.New(), Role: string(role), ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + signed
+}
+
+// assertAdminOnly checks every route under prefix refuses anonymous callers
+// with 401 and customers and vendors with 403
+func assertAdminOnly(t *testing.T, router *gin.Engine, prefix string) {
+	t.Helper()
+	callers := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"customer", bearerToken(t, models.RoleCustomer), http.StatusForbidden},
+		{"vendor", bearerToken(t, models.RoleVendor), http.StatusForbidden},
+	}
+
+	var checked int
+	for _, route := range router.Routes() {
+		if !strings.HasPrefix(route.Path, prefix) {
+			continue
+		}
+		checked++
+		path := strings.NewReplacer(":id", uuid.NewString(), ":code", "GIFT-CODE").Replace(route.Path)
+		for _, caller := range callers {
+			req := httptest.NewRequest(route.Method, path, nil)
+			if caller.authorization != "" {
+				req.Header.Set("Authorization", caller.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != caller.want {
+				t.Errorf("%s %s as %s: status = %d, want %d", route.Method, route.Path, caller.name, w.Code, caller.want)
+			}
+		}
+	}
+	if checked == 0 {
+		t.Fatalf("no routes registered under %s", prefix)
+	}
+}
+
+func TestAdminRoutesRequireAdminRole(t *testing.T) {
+	assertAdminOnly(t, newTestRouter(t, &service.Services{}), "/api/v1/admin")
+}
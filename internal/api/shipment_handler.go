@@ -0,0 +1,405 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/middleware"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ShipmentHandler handles order shipments and their tracking
+type ShipmentHandler struct {
+	service       service.ShipmentService
+	vendorService service.VendorService
+}
+
+func NewShipmentHandler(service service.ShipmentService, vendorService service.VendorService) *ShipmentHandler {
+	return &ShipmentHandler{
+		service:       service,
+		vendorService: vendorService,
+	}
+}
+
+// ShipmentRequest is the payload for shipping items of an order
+type ShipmentRequest struct {
+	Carrier        string                `json:"carrier" binding:"required"` // e.g. postnord, dhl, or any other carrier tracked by hand
+	Service        *string               `json:"service,omitempty"`          // Carrier product code
+	TrackingNumber *string               `json:"tracking_number,omitempty"`
+	CreateLabel    bool                  `json:"create_label"`                   // Book the parcel with the carrier instead of giving a tracking number
+	Items          []ShipmentItemRequest `json:"items,omitempty" binding:"dive"` // Defaults to everything not yet shipped
+}
+
+// ShipmentItemRequest is a quantity of an order item to ship
+type ShipmentItemRequest struct {
+	OrderItemID uuid.UUID `json:"order_item_id" binding:"required"`
+	Quantity    int       `json:"quantity" binding:"required,gt=0"`
+}
+
+// ShipmentEventRequest is the payload for recording a tracking event by hand
+type ShipmentEventRequest struct {
+	Status      models.ShipmentStatus `json:"status" binding:"required"`
+	Description string                `json:"description,omitempty"`
+	Location    *string               `json:"location,omitempty"`
+	OccurredAt  *time.Time            `json:"occurred_at,omitempty"` // Defaults to now
+}
+
+// GetMyOrderShipments godoc
+// @Summary Get order shipments
+// @Description Get the shipments of one of your orders with their tracking events
+// @Tags orders
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Success 200 {object} models.APIResponse{data=[]models.Shipment}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /orders/{id}/shipments [get]
+func (h *ShipmentHandler) GetMyOrderShipments(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	orderID, ok := parseIDParam(c, "order")
+	if !ok {
+		return
+	}
+
+	shipments, err := h.service.GetCustomerShipments(c.Request.Context(), userID, orderID)
+	if err != nil {
+		respondShipmentError(c, err, "Failed to get shipments")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Shipments retrieved successfully",
+		Data:    shipments,
+	})
+}
+
+// GetVendorOrderShipments godoc
+// @Summary Get order shipments (Vendor only)
+// @Description Get your shipments for an order containing your products
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Success 200 {object} models.APIResponse{data=[]models.Shipment}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/orders/{id}/shipments [get]
+func (h *ShipmentHandler) GetVendorOrderShipments(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.getOrderShipments(c, &vendor.ID)
+	}
+}
+
+// CreateVendorShipment godoc
+// @Summary Ship order items (Vendor only)
+// @Description Ship your items of an order, either with a tracking number or by booking a label with an integrated carrier (postnord, dhl) from your business address. Without items, everything of yours not yet shipped is included. The order becomes shipped once all of its items are.
+// @Tags vendors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Param shipment body ShipmentRequest true "Shipment"
+// @Success 201 {object} models.APIResponse{data=models.Shipment}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Failure 502 {object} models.APIResponse
+// @Router /vendor/orders/{id}/shipments [post]
+func (h *ShipmentHandler) CreateVendorShipment(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.createShipment(c, &vendor.ID)
+	}
+}
+
+// GetVendorShipmentLabel godoc
+// @Summary Download shipping label (Vendor only)
+// @Description Download the label booked for one of your shipments
+// @Tags vendors
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path string true "Shipment ID"
+// @Success 200 {file} file
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/shipments/{id}/label [get]
+func (h *ShipmentHandler) GetVendorShipmentLabel(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.getLabel(c, &vendor.ID)
+	}
+}
+
+// AddVendorShipmentEvent godoc
+// @Summary Record a tracking event (Vendor only)
+// @Description Record a tracking event for one of your shipments, for carriers without an integration. Once every item of the order is delivered the order becomes delivered.
+// @Tags vendors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shipment ID"
+// @Param event body ShipmentEventRequest true "Tracking event"
+// @Success 200 {object} models.APIResponse{data=models.Shipment}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/shipments/{id}/events [post]
+func (h *ShipmentHandler) AddVendorShipmentEvent(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.addEvent(c, &vendor.ID)
+	}
+}
+
+// CancelVendorShipment godoc
+// @Summary Cancel a shipment (Vendor only)
+// @Description Cancel one of your shipments that has not arrived, so its items can be shipped again
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shipment ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /vendor/shipments/{id} [delete]
+func (h *ShipmentHandler) CancelVendorShipment(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.cancelShipment(c, &vendor.ID)
+	}
+}
+
+// GetOrderShipments godoc
+// @Summary Get order shipments (Admin only)
+// @Description Get all shipments of an order with their tracking events
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Success 200 {object} models.APIResponse{data=[]models.Shipment}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/orders/{id}/shipments [get]
+func (h *ShipmentHandler) GetOrderShipments(c *gin.Context) {
+	h.getOrderShipments(c, nil)
+}
+
+// CreateShipment godoc
+// @Summary Ship order items (Admin only)
+// @Description Ship items of an order, either with a tracking number or by booking a label with an integrated carrier. Labels need the items of a single vendor, whose address is the sender.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Order ID"
+// @Param shipment body ShipmentRequest true "Shipment"
+// @Success 201 {object} models.APIResponse{data=models.Shipment}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Failure 502 {object} models.APIResponse
+// @Router /admin/orders/{id}/shipments [post]
+func (h *ShipmentHandler) CreateShipment(c *gin.Context) {
+	h.createShipment(c, nil)
+}
+
+// GetShipmentLabel godoc
+// @Summary Download shipping label (Admin only)
+// @Tags admin
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path string true "Shipment ID"
+// @Success 200 {file} file
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/shipments/{id}/label [get]
+func (h *ShipmentHandler) GetShipmentLabel(c *gin.Context) {
+	h.getLabel(c, nil)
+}
+
+// AddShipmentEvent godoc
+// @Summary Record a tracking event (Admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shipment ID"
+// @Param event body ShipmentEventRequest true "Tracking event"
+// @Success 200 {object} models.APIResponse{data=models.Shipment}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/shipments/{id}/events [post]
+func (h *ShipmentHandler) AddShipmentEvent(c *gin.Context) {
+	h.addEvent(c, nil)
+}
+
+// CancelShipment godoc
+// @Summary Cancel a shipment (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shipment ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/shipments/{id} [delete]
+func (h *ShipmentHandler) CancelShipment(c *gin.Context) {
+	h.cancelShipment(c, nil)
+}
+
+func (h *ShipmentHandler) getOrderShipments(c *gin.Context, vendorID *uuid.UUID) {
+	orderID, ok := parseIDParam(c, "order")
+	if !ok {
+		return
+	}
+
+	shipments, err := h.service.GetOrderShipments(c.Request.Context(), vendorID, orderID)
+	if err != nil {
+		respondShipmentError(c, err, "Failed to get shipments")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Shipments retrieved successfully",
+		Data:    shipments,
+	})
+}
+
+func (h *ShipmentHandler) createShipment(c *gin.Context, vendorID *uuid.UUID) {
+	orderID, ok := parseIDParam(c, "order")
+	if !ok {
+		return
+	}
+
+	var req ShipmentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	input := service.ShipmentInput{
+		Carrier:        req.Carrier,
+		Service:        req.Service,
+		TrackingNumber: req.TrackingNumber,
+		CreateLabel:    req.CreateLabel,
+	}
+	for _, item := range req.Items {
+		input.Items = append(input.Items, models.ShipmentItem{OrderItemID: item.OrderItemID, Quantity: item.Quantity})
+	}
+
+	var createdBy *uuid.UUID
+	if userID, ok := middleware.CurrentUserID(c); ok {
+		createdBy = &userID
+	}
+
+	shipment, err := h.service.CreateShipment(c.Request.Context(), vendorID, orderID, input, createdBy)
+	if err != nil {
+		respondShipmentError(c, err, "Failed to create shipment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Shipment created successfully",
+		Data:    shipment,
+	})
+}
+
+func (h *ShipmentHandler) getLabel(c *gin.Context, vendorID *uuid.UUID) {
+	id, ok := parseIDParam(c, "shipment")
+	if !ok {
+		return
+	}
+
+	label, err := h.service.GetLabel(c.Request.Context(), vendorID, id)
+	if err != nil {
+		respondShipmentError(c, err, "Failed to get label")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="label-%s.pdf"`, id))
+	c.Data(http.StatusOK, label.ContentType, label.Data)
+}
+
+func (h *ShipmentHandler) addEvent(c *gin.Context, vendorID *uuid.UUID) {
+	id, ok := parseIDParam(c, "shipment")
+	if !ok {
+		return
+	}
+
+	var req ShipmentEventRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	event := &models.ShipmentEvent{
+		Status:      req.Status,
+		Description: req.Description,
+		Location:    req.Location,
+	}
+	if req.OccurredAt != nil {
+		event.OccurredAt = *req.OccurredAt
+	}
+
+	shipment, err := h.service.AddEvent(c.Request.Context(), vendorID, id, event)
+	if err != nil {
+		respondShipmentError(c, err, "Failed to record tracking event")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Tracking event recorded successfully",
+		Data:    shipment,
+	})
+}
+
+func (h *ShipmentHandler) cancelShipment(c *gin.Context, vendorID *uuid.UUID) {
+	id, ok := parseIDParam(c, "shipment")
+	if !ok {
+		return
+	}
+
+	if err := h.service.CancelShipment(c.Request.Context(), vendorID, id); err != nil {
+		respondShipmentError(c, err, "Failed to cancel shipment")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Shipment cancelled successfully",
+	})
+}
+
+func respondShipmentError(c *gin.Context, err error, message string) {
+	msg := err.Error()
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case msg == "order not found" || msg == "shipment not found" || msg == "shipment has no label" || msg == "vendor not found":
+		status, code = http.StatusNotFound, "NOT_FOUND"
+	case msg == "order cannot be shipped in its current status" || msg == "order has nothing left to ship" ||
+		msg == "quantity exceeds what is left to ship" || msg == "shipment is cancelled" ||
+		msg == "shipment can no longer be cancelled":
+		status, code = http.StatusConflict, "INVALID_STATE"
+	case strings.HasPrefix(msg, "failed to create label"):
+		status, code = http.StatusBadGateway, "CARRIER_ERROR"
+	case strings.HasPrefix(msg, "order item ") || strings.HasPrefix(msg, "carrier ") ||
+		strings.HasPrefix(msg, "labels ") || strings.HasPrefix(msg, "product weights "):
+		status, code = http.StatusBadRequest, "INVALID_REQUEST"
+	case strings.Contains(msg, " must "):
+		status, code = http.StatusBadRequest, "INVALID_REQUEST"
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    code,
+			Message: msg,
+		},
+	})
+}
@@ -0,0 +1,382 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShippingHandler handles shipping quote and shipping zone endpoints
+type ShippingHandler struct {
+	service service.ShippingService
+}
+
+func NewShippingHandler(service service.ShippingService) *ShippingHandler {
+	return &ShippingHandler{service: service}
+}
+
+// ShippingQuoteRequest is the payload for pricing shipping for a cart
+type ShippingQuoteRequest struct {
+	Items      []CartLineRequest `json:"items" binding:"required,min=1,dive"`
+	Country    string            `json:"country" binding:"required"` // ISO 3166-1 alpha-2
+	PostalCode string            `json:"postal_code,omitempty"`
+}
+
+// ShippingZoneRequest is the payload for creating or updating a shipping zone
+type ShippingZoneRequest struct {
+	Name     string                  `json:"name" binding:"required"`
+	Regions  []models.ShippingRegion `json:"regions" binding:"required,min=1"`
+	Priority int                     `json:"priority"`
+	IsActive *bool                   `json:"is_active,omitempty"` // Defaults to true
+}
+
+// ShippingMethodRequest is the payload for creating or updating a shipping method
+type ShippingMethodRequest struct {
+	Name                  string   `json:"name" binding:"required"`
+	Description           *string  `json:"description,omitempty"`
+	BaseRate              float64  `json:"base_rate" binding:"gte=0"`
+	RatePerKg             float64  `json:"rate_per_kg" binding:"gte=0"`
+	MinWeight             *float64 `json:"min_weight,omitempty"`
+	MaxWeight             *float64 `json:"max_weight,omitempty"`
+	FreeShippingThreshold *float64 `json:"free_shipping_threshold,omitempty"`
+	MinDeliveryDays       *int     `json:"min_delivery_days,omitempty"`
+	MaxDeliveryDays       *int     `json:"max_delivery_days,omitempty"`
+	SortOrder             int      `json:"sort_order"`
+	IsActive              *bool    `json:"is_active,omitempty"` // Defaults to true
+}
+
+func (r ShippingZoneRequest) toZone() *models.ShippingZone {
+	return &models.ShippingZone{
+		Name:     r.Name,
+		Regions:  r.Regions,
+		Priority: r.Priority,
+		IsActive: r.IsActive == nil || *r.IsActive,
+	}
+}
+
+func (r ShippingMethodRequest) toMethod() *models.ShippingMethod {
+	return &models.ShippingMethod{
+		Name:                  r.Name,
+		Description:           r.Description,
+		BaseRate:              r.BaseRate,
+		RatePerKg:             r.RatePerKg,
+		MinWeight:             r.MinWeight,
+		MaxWeight:             r.MaxWeight,
+		FreeShippingThreshold: r.FreeShippingThreshold,
+		MinDeliveryDays:       r.MinDeliveryDays,
+		MaxDeliveryDays:       r.MaxDeliveryDays,
+		SortOrder:             r.SortOrder,
+		IsActive:              r.IsActive == nil || *r.IsActive,
+	}
+}
+
+// QuoteShipping godoc
+// @Summary Quote shipping for cart
+// @Description Price the shipping methods available for the cart at the destination, using current product prices, weights and dimensions. Shipping is priced again at checkout.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param request body ShippingQuoteRequest true "Cart items and destination"
+// @Success 200 {object} models.APIResponse{data=models.ShippingQuote}
+// @Failure 400 {object} models.APIResponse
+// @Failure 422 {object} models.APIResponse
+// @Router /cart/shipping [post]
+func (h *ShippingHandler) QuoteShipping(c *gin.Context) {
+	var req ShippingQuoteRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	lines := make([]service.CartLine, len(req.Items))
+	for i, item := range req.Items {
+		lines[i] = service.CartLine{ProductRef: string(item.ProductID), Quantity: item.Quantity}
+	}
+
+	quote, err := h.service.Quote(c.Request.Context(), lines, req.Country, req.PostalCode)
+	if err != nil {
+		respondShippingError(c, err, "Failed to quote shipping")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Shipping quoted successfully",
+		Data:    quote,
+	})
+}
+
+// GetShippingZones godoc
+// @Summary List shipping zones (Admin only)
+// @Description Get all shipping zones with their methods, in the order they are matched
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.ShippingZone}
+// @Router /admin/shipping/zones [get]
+func (h *ShippingHandler) GetShippingZones(c *gin.Context) {
+	zones, err := h.service.GetZones(c.Request.Context())
+	if err != nil {
+		respondShippingError(c, err, "Failed to get shipping zones")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Shipping zones retrieved successfully",
+		Data:    zones,
+	})
+}
+
+// GetShippingZone godoc
+// @Summary Get a shipping zone (Admin only)
+// @Description Get a shipping zone with all of its methods
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shipping zone ID"
+// @Success 200 {object} models.APIResponse{data=models.ShippingZone}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/shipping/zones/{id} [get]
+func (h *ShippingHandler) GetShippingZone(c *gin.Context) {
+	id, ok := parseIDParam(c, "shipping zone")
+	if !ok {
+		return
+	}
+
+	zone, err := h.service.GetZone(c.Request.Context(), id)
+	if err != nil {
+		respondShippingError(c, err, "Failed to get shipping zone")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Shipping zone retrieved successfully",
+		Data:    zone,
+	})
+}
+
+// CreateShippingZone godoc
+// @Summary Create a shipping zone (Admin only)
+// @Description Create a zone of countries or postal code ranges. When zones overlap, the one with the lowest priority is used.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param zone body ShippingZoneRequest true "Shipping zone"
+// @Success 201 {object} models.APIResponse{data=models.ShippingZone}
+// @Failure 400 {object} models.APIResponse
+// @Router /admin/shipping/zones [post]
+func (h *ShippingHandler) CreateShippingZone(c *gin.Context) {
+	var req ShippingZoneRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	zone := req.toZone()
+	if err := h.service.CreateZone(c.Request.Context(), zone); err != nil {
+		respondShippingError(c, err, "Failed to create shipping zone")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Shipping zone created successfully",
+		Data:    zone,
+	})
+}
+
+// UpdateShippingZone godoc
+// @Summary Update a shipping zone (Admin only)
+// @Description Replace a shipping zone's name, regions, priority and status
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shipping zone ID"
+// @Param zone body ShippingZoneRequest true "Shipping zone"
+// @Success 200 {object} models.APIResponse{data=models.ShippingZone}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/shipping/zones/{id} [put]
+func (h *ShippingHandler) UpdateShippingZone(c *gin.Context) {
+	id, ok := parseIDParam(c, "shipping zone")
+	if !ok {
+		return
+	}
+
+	var req ShippingZoneRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	zone, err := h.service.UpdateZone(c.Request.Context(), id, req.toZone())
+	if err != nil {
+		respondShippingError(c, err, "Failed to update shipping zone")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Shipping zone updated successfully",
+		Data:    zone,
+	})
+}
+
+// DeleteShippingZone godoc
+// @Summary Delete a shipping zone (Admin only)
+// @Description Delete a shipping zone together with its methods
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shipping zone ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/shipping/zones/{id} [delete]
+func (h *ShippingHandler) DeleteShippingZone(c *gin.Context) {
+	id, ok := parseIDParam(c, "shipping zone")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteZone(c.Request.Context(), id); err != nil {
+		respondShippingError(c, err, "Failed to delete shipping zone")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Shipping zone deleted successfully",
+	})
+}
+
+// CreateShippingMethod godoc
+// @Summary Add a shipping method to a zone (Admin only)
+// @Description Add a method priced at a base rate plus a rate for every started kilogram, optionally free above an order subtotal
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shipping zone ID"
+// @Param method body ShippingMethodRequest true "Shipping method"
+// @Success 201 {object} models.APIResponse{data=models.ShippingMethod}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/shipping/zones/{id}/methods [post]
+func (h *ShippingHandler) CreateShippingMethod(c *gin.Context) {
+	zoneID, ok := parseIDParam(c, "shipping zone")
+	if !ok {
+		return
+	}
+
+	var req ShippingMethodRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	method := req.toMethod()
+	if err := h.service.CreateMethod(c.Request.Context(), zoneID, method); err != nil {
+		respondShippingError(c, err, "Failed to create shipping method")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Shipping method created successfully",
+		Data:    method,
+	})
+}
+
+// UpdateShippingMethod godoc
+// @Summary Update a shipping method (Admin only)
+// @Description Replace a shipping method's rates, limits and delivery estimate
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shipping method ID"
+// @Param method body ShippingMethodRequest true "Shipping method"
+// @Success 200 {object} models.APIResponse{data=models.ShippingMethod}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/shipping/methods/{id} [put]
+func (h *ShippingHandler) UpdateShippingMethod(c *gin.Context) {
+	id, ok := parseIDParam(c, "shipping method")
+	if !ok {
+		return
+	}
+
+	var req ShippingMethodRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	method, err := h.service.UpdateMethod(c.Request.Context(), id, req.toMethod())
+	if err != nil {
+		respondShippingError(c, err, "Failed to update shipping method")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Shipping method updated successfully",
+		Data:    method,
+	})
+}
+
+// DeleteShippingMethod godoc
+// @Summary Delete a shipping method (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Shipping method ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/shipping/methods/{id} [delete]
+func (h *ShippingHandler) DeleteShippingMethod(c *gin.Context) {
+	id, ok := parseIDParam(c, "shipping method")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteMethod(c.Request.Context(), id); err != nil {
+		respondShippingError(c, err, "Failed to delete shipping method")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Shipping method deleted successfully",
+	})
+}
+
+func respondShippingError(c *gin.Context, err error, message string) {
+	msg := err.Error()
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case msg == "shipping zone not found" || msg == "shipping method not found":
+		status, code = http.StatusNotFound, "NOT_FOUND"
+	case msg == "shipping is not available to this address" || msg == "no shipping method is available for this cart":
+		status, code = http.StatusUnprocessableEntity, "SHIPPING_UNAVAILABLE"
+	case msg == "cart is empty" || (strings.HasPrefix(msg, "product ") && strings.HasSuffix(msg, " not found")):
+		status, code = http.StatusBadRequest, "INVALID_CART"
+	case strings.Contains(msg, " must ") || strings.HasSuffix(msg, " is required") || strings.HasSuffix(msg, " are required"):
+		status, code = http.StatusBadRequest, "INVALID_REQUEST"
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    code,
+			Message: msg,
+		},
+	})
+}
@@ -0,0 +1,537 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"smrtmart-go-postgresql/internal/middleware"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// VendorHandler handles vendor-related endpoints
+type VendorHandler struct {
+	service service.VendorService
+}
+
+func NewVendorHandler(service service.VendorService) *VendorHandler {
+	return &VendorHandler{service: service}
+}
+
+// VendorProfileRequest is the payload for applying as a vendor or editing the vendor profile
+type VendorProfileRequest struct {
+	BusinessName string                  `json:"business_name" binding:"required"`
+	BusinessType string                  `json:"business_type"`
+	Description  *string                 `json:"description,omitempty"`
+	Logo         *string                 `json:"logo,omitempty"`
+	Website      *string                 `json:"website,omitempty"`
+	Address      models.Address          `json:"address"`
+	Documents    *models.VendorDocuments `json:"documents,omitempty"` // Organisation number, VAT ID and bank proof
+}
+
+// VendorStatusRequest is the payload for an admin review decision
+type VendorStatusRequest struct {
+	Status models.VendorStatus `json:"status" binding:"required"`
+	Reason string              `json:"reason" binding:"required"`
+}
+
+func (r VendorProfileRequest) toModel() *models.Vendor {
+	return &models.Vendor{
+		BusinessName: r.BusinessName,
+		BusinessType: r.BusinessType,
+		Description:  r.Description,
+		Logo:         r.Logo,
+		Website:      r.Website,
+		Address:      r.Address,
+		Documents:    r.Documents,
+	}
+}
+
+// ApplyAsVendor godoc
+// @Summary Apply to become a vendor
+// @Description Submit a vendor application for the authenticated user; it starts as pending until approved
+// @Tags vendors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param application body VendorProfileRequest true "Vendor application"
+// @Success 201 {object} models.APIResponse{data=models.Vendor}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /vendors/apply [post]
+func (h *VendorHandler) ApplyAsVendor(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req VendorProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	vendor := req.toModel()
+	if err := h.service.Apply(c.Request.Context(), userID, vendor); err != nil {
+		if err.Error() == "vendor application already exists" {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Message: "Vendor application already exists",
+				Error: &models.APIError{
+					Code:    "ALREADY_EXISTS",
+					Message: err.Error(),
+				},
+			})
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Failed to submit vendor application",
+			Error: &models.APIError{
+				Code:    "CREATION_FAILED",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Vendor application submitted successfully",
+		Data:    vendor,
+	})
+}
+
+// GetProfile godoc
+// @Summary Get vendor profile (Vendor only)
+// @Description Get the business profile of the authenticated vendor
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=models.Vendor}
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /vendor/profile [get]
+func (h *VendorHandler) GetProfile(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	vendor, err := h.service.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		respondVendorError(c, err, "Failed to get vendor profile")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vendor profile retrieved successfully",
+		Data:    vendor,
+	})
+}
+
+// UpdateProfile godoc
+// @Summary Update vendor profile (Vendor only)
+// @Description Update the business name, description, logo, website and address of the authenticated vendor
+// @Tags vendors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param profile body VendorProfileRequest true "Vendor profile"
+// @Success 200 {object} models.APIResponse{data=models.Vendor}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/profile [put]
+func (h *VendorHandler) UpdateProfile(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req VendorProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	vendor, err := h.service.UpdateProfile(c.Request.Context(), userID, req.toModel())
+	if err != nil {
+		if err.Error() == "vendor not found" {
+			respondVendorError(c, err, "Failed to update vendor profile")
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Failed to update vendor profile",
+			Error: &models.APIError{
+				Code:    "UPDATE_FAILED",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vendor profile updated successfully",
+		Data:    vendor,
+	})
+}
+
+// GetStorefront godoc
+// @Summary Get a vendor storefront
+// @Description Get the public profile of an approved vendor and its active products
+// @Tags vendors
+// @Produce json
+// @Param id path string true "Vendor ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Param sort_by query string false "Sort by field" Enums(name, price, created_at)
+// @Param sort_dir query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Success 200 {object} models.APIResponse{data=models.VendorStorefront}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /vendors/{id} [get]
+func (h *VendorHandler) GetStorefront(c *gin.Context) {
+	id, ok := parseVendorID(c)
+	if !ok {
+		return
+	}
+
+	filters := repository.ProductFilters{
+		SortBy:  c.Query("sort_by"),
+		SortDir: c.Query("sort_dir"),
+	}
+
+	// Parse pagination
+	if pageStr := c.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
+			filters.Page = page
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			filters.Limit = limit
+		}
+	}
+
+	storefront, err := h.service.GetStorefront(c.Request.Context(), id, filters)
+	if err != nil {
+		respondVendorError(c, err, "Failed to get vendor storefront")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vendor storefront retrieved successfully",
+		Data:    storefront,
+	})
+}
+
+// GetVendors godoc
+// @Summary List vendors for review (Admin only)
+// @Description Get the vendor review queue, oldest first, optionally filtered by status
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Vendor status" Enums(pending, approved, rejected, suspended)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 500 {object} models.APIResponse
+// @Router /admin/vendors [get]
+func (h *VendorHandler) GetVendors(c *gin.Context) {
+	page, limit := 1, 20
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	result, err := h.service.GetVendors(c.Request.Context(), c.Query("status"), page, limit)
+	if err != nil {
+		if err.Error() == "invalid vendor status" {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid vendor status",
+				Error: &models.APIError{
+					Code:    "INVALID_STATUS",
+					Message: err.Error(),
+				},
+			})
+			return
+		}
+
+		respondVendorError(c, err, "Failed to get vendors")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vendors retrieved successfully",
+		Data:    result,
+	})
+}
+
+// GetVendor godoc
+// @Summary Get vendor for review (Admin only)
+// @Description Get a vendor with its verification documents and status history
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Vendor ID"
+// @Success 200 {object} models.APIResponse{data=models.VendorReview}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/vendors/{id} [get]
+func (h *VendorHandler) GetVendor(c *gin.Context) {
+	id, ok := parseVendorID(c)
+	if !ok {
+		return
+	}
+
+	review, err := h.service.GetVendorReview(c.Request.Context(), id)
+	if err != nil {
+		respondVendorError(c, err, "Failed to get vendor")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vendor retrieved successfully",
+		Data:    review,
+	})
+}
+
+// UpdateVendorStatus godoc
+// @Summary Update vendor status (Admin only)
+// @Description Approve, reject or suspend a vendor with a reason. Approval requires verified documents and promotes the vendor's user to the vendor role; suspension deactivates the vendor's products.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Vendor ID"
+// @Param status body VendorStatusRequest true "New status and reason"
+// @Success 200 {object} models.APIResponse{data=models.Vendor}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/vendors/{id}/status [put]
+func (h *VendorHandler) UpdateVendorStatus(c *gin.Context) {
+	id, ok := parseVendorID(c)
+	if !ok {
+		return
+	}
+
+	var req VendorStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	var changedBy *uuid.UUID
+	if adminID, ok := middleware.CurrentUserID(c); ok {
+		changedBy = &adminID
+	}
+
+	vendor, err := h.service.UpdateStatus(c.Request.Context(), id, req.Status, req.Reason, changedBy)
+	if err != nil {
+		if err.Error() == "vendor not found" {
+			respondVendorError(c, err, "Failed to update vendor status")
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Failed to update vendor status",
+			Error: &models.APIError{
+				Code:    "UPDATE_FAILED",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vendor status updated successfully",
+		Data:    vendor,
+	})
+}
+
+// VerifyVendor godoc
+// @Summary Verify vendor documents (Admin only)
+// @Description Mark the vendor's organisation number, VAT ID and bank proof as checked
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Vendor ID"
+// @Success 200 {object} models.APIResponse{data=models.Vendor}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/vendors/{id}/verify [post]
+func (h *VendorHandler) VerifyVendor(c *gin.Context) {
+	id, ok := parseVendorID(c)
+	if !ok {
+		return
+	}
+
+	vendor, err := h.service.Verify(c.Request.Context(), id)
+	if err != nil {
+		if err.Error() == "vendor not found" {
+			respondVendorError(c, err, "Failed to verify vendor")
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Failed to verify vendor",
+			Error: &models.APIError{
+				Code:    "VERIFICATION_FAILED",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vendor verified successfully",
+		Data:    vendor,
+	})
+}
+
+// SetPayoutAccount godoc
+// @Summary Set vendor payout account (Admin only)
+// @Description Link the vendor to the connected Stripe account that receives its payouts. An empty ID unlinks it.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Vendor ID"
+// @Param account body object{stripe_account_id=string} true "Connected Stripe account"
+// @Success 200 {object} models.APIResponse{data=models.Vendor}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/vendors/{id}/payout-account [put]
+func (h *VendorHandler) SetPayoutAccount(c *gin.Context) {
+	id, ok := parseVendorID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		StripeAccountID string `json:"stripe_account_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	vendor, err := h.service.SetPayoutAccount(c.Request.Context(), id, req.StripeAccountID)
+	if err != nil {
+		if err.Error() == "vendor not found" {
+			respondVendorError(c, err, "Failed to set payout account")
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Failed to set payout account",
+			Error: &models.APIError{
+				Code:    "UPDATE_FAILED",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Vendor payout account updated successfully",
+		Data:    vendor,
+	})
+}
+
+func parseVendorID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid vendor ID",
+			Error: &models.APIError{
+				Code:    "INVALID_ID",
+				Message: "Vendor ID must be a valid UUID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func respondVendorError(c *gin.Context, err error, message string) {
+	if err.Error() == "vendor not found" {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Vendor not found",
+			Error: &models.APIError{
+				Code:    "NOT_FOUND",
+				Message: "Vendor does not exist",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		},
+	})
+}
@@ -0,0 +1,489 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/middleware"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WebhookHandler handles outbound webhook endpoints and their delivery logs
+type WebhookHandler struct {
+	service       service.WebhookService
+	vendorService service.VendorService
+}
+
+func NewWebhookHandler(service service.WebhookService, vendorService service.VendorService) *WebhookHandler {
+	return &WebhookHandler{
+		service:       service,
+		vendorService: vendorService,
+	}
+}
+
+// WebhookEndpointRequest is the payload for creating or replacing a webhook endpoint
+type WebhookEndpointRequest struct {
+	URL         string             `json:"url" binding:"required"`
+	Description *string            `json:"description,omitempty"`
+	EventTypes  []models.EventType `json:"event_types,omitempty"` // Leave out for every type
+	VendorID    *uuid.UUID         `json:"vendor_id,omitempty"`   // Admin only, on create; vendors' endpoints are always their own
+	IsActive    *bool              `json:"is_active,omitempty"`   // Defaults to true; true re-enables a disabled endpoint
+}
+
+func (r WebhookEndpointRequest) toModel() *models.WebhookEndpoint {
+	isActive := true
+	if r.IsActive != nil {
+		isActive = *r.IsActive
+	}
+
+	return &models.WebhookEndpoint{
+		URL:         r.URL,
+		Description: r.Description,
+		EventTypes:  r.EventTypes,
+		VendorID:    r.VendorID,
+		IsActive:    isActive,
+	}
+}
+
+// GetWebhooks godoc
+// @Summary List webhook endpoints (Admin only)
+// @Description Get all webhook endpoints, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Router /admin/webhooks [get]
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	h.listWebhooks(c, nil)
+}
+
+// GetWebhook godoc
+// @Summary Get webhook endpoint (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Success 200 {object} models.APIResponse{data=models.WebhookEndpoint}
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	h.getWebhook(c, nil)
+}
+
+// CreateWebhook godoc
+// @Summary Create webhook endpoint (Admin only)
+// @Description Register an endpoint for domain events. Without a vendor_id it receives every subscribed event. Deliveries are signed with the returned secret, which is only shown here and when it is rotated.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook body WebhookEndpointRequest true "Webhook endpoint"
+// @Success 201 {object} models.APIResponse{data=models.WebhookEndpointWithSecret}
+// @Failure 400 {object} models.APIResponse
+// @Router /admin/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	h.createWebhook(c, nil)
+}
+
+// UpdateWebhook godoc
+// @Summary Update webhook endpoint (Admin only)
+// @Description Replace an endpoint's URL, description, event types and whether it is active
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Param webhook body WebhookEndpointRequest true "Webhook endpoint"
+// @Success 200 {object} models.APIResponse{data=models.WebhookEndpoint}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	h.updateWebhook(c, nil)
+}
+
+// RotateWebhookSecret godoc
+// @Summary Rotate webhook signing secret (Admin only)
+// @Description Replace an endpoint's signing secret. Deliveries are signed with the returned secret from now on; the old one stops working immediately.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Success 200 {object} models.APIResponse{data=models.WebhookEndpointWithSecret}
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/webhooks/{id}/rotate-secret [post]
+func (h *WebhookHandler) RotateWebhookSecret(c *gin.Context) {
+	h.rotateSecret(c, nil)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete webhook endpoint (Admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	h.deleteWebhook(c, nil)
+}
+
+// GetWebhookDeliveries godoc
+// @Summary List webhook deliveries (Admin only)
+// @Description Get an endpoint's delivery attempts with their response codes, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Failure 404 {object} models.APIResponse
+// @Router /admin/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetWebhookDeliveries(c *gin.Context) {
+	h.listDeliveries(c, nil)
+}
+
+// RedeliverWebhook godoc
+// @Summary Redeliver a webhook (Admin only)
+// @Description Send the event of a logged delivery to the endpoint again
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 202 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /admin/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *WebhookHandler) RedeliverWebhook(c *gin.Context) {
+	h.redeliver(c, nil)
+}
+
+// GetVendorWebhooks godoc
+// @Summary List own webhook endpoints (Vendor only)
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Router /vendor/webhooks [get]
+func (h *WebhookHandler) GetVendorWebhooks(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.listWebhooks(c, &vendor.ID)
+	}
+}
+
+// GetVendorWebhook godoc
+// @Summary Get own webhook endpoint (Vendor only)
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Success 200 {object} models.APIResponse{data=models.WebhookEndpoint}
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/webhooks/{id} [get]
+func (h *WebhookHandler) GetVendorWebhook(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.getWebhook(c, &vendor.ID)
+	}
+}
+
+// CreateVendorWebhook godoc
+// @Summary Create webhook endpoint (Vendor only)
+// @Description Register an endpoint for events about your products, stock, orders and shipments. Deliveries are signed with the returned secret, which is only shown here and when it is rotated.
+// @Tags vendors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param webhook body WebhookEndpointRequest true "Webhook endpoint"
+// @Success 201 {object} models.APIResponse{data=models.WebhookEndpointWithSecret}
+// @Failure 400 {object} models.APIResponse
+// @Router /vendor/webhooks [post]
+func (h *WebhookHandler) CreateVendorWebhook(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.createWebhook(c, &vendor.ID)
+	}
+}
+
+// UpdateVendorWebhook godoc
+// @Summary Update own webhook endpoint (Vendor only)
+// @Tags vendors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Param webhook body WebhookEndpointRequest true "Webhook endpoint"
+// @Success 200 {object} models.APIResponse{data=models.WebhookEndpoint}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/webhooks/{id} [put]
+func (h *WebhookHandler) UpdateVendorWebhook(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.updateWebhook(c, &vendor.ID)
+	}
+}
+
+// RotateVendorWebhookSecret godoc
+// @Summary Rotate own webhook signing secret (Vendor only)
+// @Description Replace an endpoint's signing secret. Deliveries are signed with the returned secret from now on; the old one stops working immediately.
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Success 200 {object} models.APIResponse{data=models.WebhookEndpointWithSecret}
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/webhooks/{id}/rotate-secret [post]
+func (h *WebhookHandler) RotateVendorWebhookSecret(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.rotateSecret(c, &vendor.ID)
+	}
+}
+
+// DeleteVendorWebhook godoc
+// @Summary Delete own webhook endpoint (Vendor only)
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteVendorWebhook(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.deleteWebhook(c, &vendor.ID)
+	}
+}
+
+// GetVendorWebhookDeliveries godoc
+// @Summary List own webhook deliveries (Vendor only)
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Failure 404 {object} models.APIResponse
+// @Router /vendor/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetVendorWebhookDeliveries(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.listDeliveries(c, &vendor.ID)
+	}
+}
+
+// RedeliverVendorWebhook godoc
+// @Summary Redeliver a webhook (Vendor only)
+// @Tags vendors
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook endpoint ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 202 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /vendor/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *WebhookHandler) RedeliverVendorWebhook(c *gin.Context) {
+	if vendor, ok := currentVendor(c, h.vendorService); ok {
+		h.redeliver(c, &vendor.ID)
+	}
+}
+
+func (h *WebhookHandler) listWebhooks(c *gin.Context, vendorID *uuid.UUID) {
+	page, limit := pageParams(c)
+	result, err := h.service.GetEndpoints(c.Request.Context(), vendorID, page, limit)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to get webhook endpoints")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Webhook endpoints retrieved successfully",
+		Data:    result,
+	})
+}
+
+func (h *WebhookHandler) getWebhook(c *gin.Context, vendorID *uuid.UUID) {
+	id, ok := parseIDParam(c, "webhook endpoint")
+	if !ok {
+		return
+	}
+
+	endpoint, err := h.service.GetEndpoint(c.Request.Context(), vendorID, id)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to get webhook endpoint")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Webhook endpoint retrieved successfully",
+		Data:    endpoint,
+	})
+}
+
+func (h *WebhookHandler) createWebhook(c *gin.Context, vendorID *uuid.UUID) {
+	var req WebhookEndpointRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	endpoint := req.toModel()
+	if userID, ok := middleware.CurrentUserID(c); ok {
+		endpoint.CreatedBy = &userID
+	}
+
+	if err := h.service.CreateEndpoint(c.Request.Context(), vendorID, endpoint); err != nil {
+		respondWebhookError(c, err, "Failed to create webhook endpoint")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Webhook endpoint created successfully",
+		Data:    models.NewWebhookEndpointWithSecret(endpoint),
+	})
+}
+
+func (h *WebhookHandler) updateWebhook(c *gin.Context, vendorID *uuid.UUID) {
+	id, ok := parseIDParam(c, "webhook endpoint")
+	if !ok {
+		return
+	}
+
+	var req WebhookEndpointRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	endpoint, err := h.service.UpdateEndpoint(c.Request.Context(), vendorID, id, req.toModel())
+	if err != nil {
+		respondWebhookError(c, err, "Failed to update webhook endpoint")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Webhook endpoint updated successfully",
+		Data:    endpoint,
+	})
+}
+
+func (h *WebhookHandler) rotateSecret(c *gin.Context, vendorID *uuid.UUID) {
+	id, ok := parseIDParam(c, "webhook endpoint")
+	if !ok {
+		return
+	}
+
+	endpoint, err := h.service.RotateSecret(c.Request.Context(), vendorID, id)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to rotate webhook secret")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Webhook secret rotated successfully",
+		Data:    models.NewWebhookEndpointWithSecret(endpoint),
+	})
+}
+
+func (h *WebhookHandler) deleteWebhook(c *gin.Context, vendorID *uuid.UUID) {
+	id, ok := parseIDParam(c, "webhook endpoint")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteEndpoint(c.Request.Context(), vendorID, id); err != nil {
+		respondWebhookError(c, err, "Failed to delete webhook endpoint")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Webhook endpoint deleted successfully",
+	})
+}
+
+func (h *WebhookHandler) listDeliveries(c *gin.Context, vendorID *uuid.UUID) {
+	id, ok := parseIDParam(c, "webhook endpoint")
+	if !ok {
+		return
+	}
+
+	page, limit := pageParams(c)
+	result, err := h.service.GetDeliveries(c.Request.Context(), vendorID, id, page, limit)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to get webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Webhook deliveries retrieved successfully",
+		Data:    result,
+	})
+}
+
+func (h *WebhookHandler) redeliver(c *gin.Context, vendorID *uuid.UUID) {
+	id, ok := parseIDParam(c, "webhook endpoint")
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(c.Param("deliveryId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid delivery ID",
+			Error: &models.APIError{
+				Code:    "INVALID_ID",
+				Message: "ID must be a valid UUID",
+			},
+		})
+		return
+	}
+
+	if err := h.service.Redeliver(c.Request.Context(), vendorID, id, deliveryID); err != nil {
+		respondWebhookError(c, err, "Failed to redeliver webhook")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.APIResponse{
+		Success: true,
+		Message: "Webhook redelivery queued",
+	})
+}
+
+func respondWebhookError(c *gin.Context, err error, message string) {
+	msg := err.Error()
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case msg == "webhook endpoint not found", msg == "delivery not found":
+		status, code = http.StatusNotFound, "NOT_FOUND"
+	case msg == "webhook endpoint is disabled":
+		status, code = http.StatusConflict, "ENDPOINT_DISABLED"
+	case msg == "redelivery already queued":
+		status, code = http.StatusConflict, "ALREADY_QUEUED"
+	case strings.HasPrefix(msg, "url "), strings.HasPrefix(msg, "unknown event type "):
+		status, code = http.StatusBadRequest, "INVALID_REQUEST"
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    code,
+			Message: msg,
+		},
+	})
+}
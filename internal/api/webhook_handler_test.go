@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// stubWebhookService creates and rotates endpoints with a fixed secret
+type stubWebhookService struct {
+	service.WebhookService
+}
+
+func (s *stubWebhookService) CreateEndpoint(_ context.Context, _ *uuid.UUID, endpoint *models.WebhookEndpoint) error {
+	endpoint.ID = uuid.New()
+	endpoint.Secret = "whsec_created"
+	return nil
+}
+
+func (s *stubWebhookService) RotateSecret(_ context.Context, _ *uuid.UUID, id uuid.UUID) (*models.WebhookEndpoint, error) {
+	return &models.WebhookEndpoint{ID: id, URL: "https://hooks.example.com", Secret: "whsec_rotated"}, nil
+}
+
+func TestAdminWebhookSecretsOnlyForAdmins(t *testing.T) {
+	router := newTestRouter(t, &service.Services{Webhook: &stubWebhookService{}})
+	assertAdminOnly(t, router, "/api/v1/admin/webhooks")
+
+	requests := []struct {
+		path, body, secret string
+		want               int
+	}{
+		{"/api/v1/admin/webhooks", `{"url":"https://hooks.example.com","event_types":["order.paid"]}`, "whsec_created", http.StatusCreated},
+		{"/api/v1/admin/webhooks/" + uuid.NewString() + "/rotate-secret", "", "whsec_rotated", http.StatusOK},
+	}
+	for _, r := range requests {
+		for _, role := range []models.UserRole{models.RoleCustomer, models.RoleVendor, models.RoleAdmin} {
+			req := httptest.NewRequest(http.MethodPost, r.path, strings.NewReader(r.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", bearerToken(t, role))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			exposed := strings.Contains(w.Body.String(), r.secret)
+			if role != models.RoleAdmin {
+				if w.Code != http.StatusForbidden || exposed {
+					t.Errorf("POST %s as %s: status %d, secret exposed %v", r.path, role, w.Code, exposed)
+				}
+				continue
+			}
+
+			var resp struct {
+				Data struct {
+					Secret string `json:"secret"`
+				} `json:"data"`
+			}
+			if w.Code != r.want || json.Unmarshal(w.Body.Bytes(), &resp) != nil || resp.Data.Secret != r.secret {
+				t.Errorf("POST %s as admin: status %d, body %s", r.path, w.Code, w.Body)
+			}
+		}
+	}
+}
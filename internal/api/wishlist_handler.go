@@ -0,0 +1,523 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WishlistHandler handles wishlist endpoints
+type WishlistHandler struct {
+	service service.WishlistService
+}
+
+func NewWishlistHandler(service service.WishlistService) *WishlistHandler {
+	return &WishlistHandler{service: service}
+}
+
+// WishlistRequest is the payload for creating a wishlist
+type WishlistRequest struct {
+	Name      string `json:"name" binding:"required"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// WishlistUpdateRequest is the payload for renaming a wishlist or making it the default
+type WishlistUpdateRequest struct {
+	Name      *string `json:"name,omitempty"`
+	IsDefault *bool   `json:"is_default,omitempty"`
+}
+
+// WishlistItemRequest is the payload for saving a product to a wishlist
+type WishlistItemRequest struct {
+	ProductID  ProductRef `json:"product_id"`
+	WishlistID *uuid.UUID `json:"wishlist_id,omitempty"` // Defaults to the default wishlist
+}
+
+// MoveToCartRequest is the optional payload for moving a wishlist item to the cart
+type MoveToCartRequest struct {
+	Quantity int `json:"quantity" binding:"omitempty,gt=0"` // Defaults to 1
+}
+
+// GetWishlists godoc
+// @Summary List wishlists
+// @Description Get the authenticated user's wishlists. The default wishlist is created on first use.
+// @Tags wishlists
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.Wishlist}
+// @Failure 401 {object} models.APIResponse
+// @Router /wishlists [get]
+func (h *WishlistHandler) GetWishlists(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	wishlists, err := h.service.GetWishlists(c.Request.Context(), userID)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to get wishlists")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Wishlists retrieved successfully",
+		Data:    wishlists,
+	})
+}
+
+// CreateWishlist godoc
+// @Summary Create wishlist
+// @Description Create a named wishlist, optionally making it the default
+// @Tags wishlists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param wishlist body WishlistRequest true "Wishlist"
+// @Success 201 {object} models.APIResponse{data=models.Wishlist}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /wishlists [post]
+func (h *WishlistHandler) CreateWishlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req WishlistRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	wishlist, err := h.service.CreateWishlist(c.Request.Context(), userID, req.Name, req.IsDefault)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to create wishlist")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Wishlist created successfully",
+		Data:    wishlist,
+	})
+}
+
+// GetWishlist godoc
+// @Summary Get wishlist
+// @Description Get one of your wishlists with its items' current price and stock. Items cheaper than when they were added are flagged.
+// @Tags wishlists
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Wishlist ID"
+// @Success 200 {object} models.APIResponse{data=models.Wishlist}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /wishlists/{id} [get]
+func (h *WishlistHandler) GetWishlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "wishlist")
+	if !ok {
+		return
+	}
+
+	wishlist, err := h.service.GetWishlist(c.Request.Context(), userID, id)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to get wishlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Wishlist retrieved successfully",
+		Data:    wishlist,
+	})
+}
+
+// UpdateWishlist godoc
+// @Summary Update wishlist
+// @Description Rename a wishlist or make it the default
+// @Tags wishlists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Wishlist ID"
+// @Param wishlist body WishlistUpdateRequest true "Changes"
+// @Success 200 {object} models.APIResponse{data=models.Wishlist}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /wishlists/{id} [put]
+func (h *WishlistHandler) UpdateWishlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "wishlist")
+	if !ok {
+		return
+	}
+
+	var req WishlistUpdateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	wishlist, err := h.service.UpdateWishlist(c.Request.Context(), userID, id, req.Name, req.IsDefault)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to update wishlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Wishlist updated successfully",
+		Data:    wishlist,
+	})
+}
+
+// DeleteWishlist godoc
+// @Summary Delete wishlist
+// @Description Delete one of your wishlists and its items. The default wishlist cannot be deleted.
+// @Tags wishlists
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Wishlist ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /wishlists/{id} [delete]
+func (h *WishlistHandler) DeleteWishlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "wishlist")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWishlist(c.Request.Context(), userID, id); err != nil {
+		respondWishlistError(c, err, "Failed to delete wishlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Wishlist deleted successfully",
+	})
+}
+
+// AddItem godoc
+// @Summary Save product to wishlist
+// @Description Save a product to a wishlist, or to the default wishlist if none is given. Saving it again has no effect.
+// @Tags wishlists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param item body WishlistItemRequest true "Item"
+// @Success 200 {object} models.APIResponse{data=models.Wishlist}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /wishlists/items [post]
+func (h *WishlistHandler) AddItem(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req WishlistItemRequest
+	if !bindWishlistItemRequest(c, &req) {
+		return
+	}
+
+	wishlist, err := h.service.AddItem(c.Request.Context(), userID, req.WishlistID, string(req.ProductID))
+	if err != nil {
+		respondWishlistError(c, err, "Failed to save product")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Product saved to wishlist",
+		Data:    wishlist,
+	})
+}
+
+// RemoveItem godoc
+// @Summary Remove product from wishlist
+// @Description Remove a product from one of your wishlists
+// @Tags wishlists
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Wishlist ID"
+// @Param productId path string true "Product ID (numeric or UUID)"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /wishlists/{id}/items/{productId} [delete]
+func (h *WishlistHandler) RemoveItem(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "wishlist")
+	if !ok {
+		return
+	}
+
+	if err := h.service.RemoveItem(c.Request.Context(), userID, id, c.Param("productId")); err != nil {
+		respondWishlistError(c, err, "Failed to remove product")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Product removed from wishlist",
+	})
+}
+
+// MoveToCart godoc
+// @Summary Move wishlist item to cart
+// @Description Remove a product from the wishlist and add it to your cart
+// @Tags wishlists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Wishlist ID"
+// @Param productId path string true "Product ID (numeric or UUID)"
+// @Param item body MoveToCartRequest false "Quantity"
+// @Success 200 {object} models.APIResponse{data=models.CartItem}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /wishlists/{id}/items/{productId}/move-to-cart [post]
+func (h *WishlistHandler) MoveToCart(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "wishlist")
+	if !ok {
+		return
+	}
+
+	var req MoveToCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	item, err := h.service.MoveToCart(c.Request.Context(), userID, id, c.Param("productId"), req.Quantity)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to move product to cart")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Product moved to cart",
+		Data:    item,
+	})
+}
+
+// MoveFromCart godoc
+// @Summary Save cart item for later
+// @Description Remove a product from your cart and save it to a wishlist, or to the default wishlist if none is given
+// @Tags wishlists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param item body WishlistItemRequest true "Item"
+// @Success 200 {object} models.APIResponse{data=models.Wishlist}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /wishlists/move-from-cart [post]
+func (h *WishlistHandler) MoveFromCart(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req WishlistItemRequest
+	if !bindWishlistItemRequest(c, &req) {
+		return
+	}
+
+	wishlist, err := h.service.MoveFromCart(c.Request.Context(), userID, req.WishlistID, string(req.ProductID))
+	if err != nil {
+		respondWishlistError(c, err, "Failed to save product for later")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Product saved for later",
+		Data:    wishlist,
+	})
+}
+
+// ShareWishlist godoc
+// @Summary Share wishlist
+// @Description Create a read-only public link to the wishlist. The returned share_token is used with GET /wishlists/shared/{token}.
+// @Tags wishlists
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Wishlist ID"
+// @Success 200 {object} models.APIResponse{data=models.Wishlist}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /wishlists/{id}/share [post]
+func (h *WishlistHandler) ShareWishlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "wishlist")
+	if !ok {
+		return
+	}
+
+	wishlist, err := h.service.Share(c.Request.Context(), userID, id)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to share wishlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Wishlist shared successfully",
+		Data:    wishlist,
+	})
+}
+
+// UnshareWishlist godoc
+// @Summary Stop sharing wishlist
+// @Description Revoke the wishlist's public link
+// @Tags wishlists
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Wishlist ID"
+// @Success 200 {object} models.APIResponse{data=models.Wishlist}
+// @Failure 400 {object} models.APIResponse
+// @Failure 401 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /wishlists/{id}/share [delete]
+func (h *WishlistHandler) UnshareWishlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "wishlist")
+	if !ok {
+		return
+	}
+
+	wishlist, err := h.service.Unshare(c.Request.Context(), userID, id)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to stop sharing wishlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Wishlist is no longer shared",
+		Data:    wishlist,
+	})
+}
+
+// GetSharedWishlist godoc
+// @Summary Get shared wishlist
+// @Description Get a read-only view of a wishlist shared by public link
+// @Tags wishlists
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} models.APIResponse{data=models.Wishlist}
+// @Failure 404 {object} models.APIResponse
+// @Router /wishlists/shared/{token} [get]
+func (h *WishlistHandler) GetSharedWishlist(c *gin.Context) {
+	wishlist, err := h.service.GetSharedWishlist(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		respondWishlistError(c, err, "Failed to get wishlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Wishlist retrieved successfully",
+		Data:    wishlist,
+	})
+}
+
+func bindWishlistItemRequest(c *gin.Context, req *WishlistItemRequest) bool {
+	if !bindJSON(c, req) {
+		return false
+	}
+	if req.ProductID == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request data",
+			Error: &models.APIError{
+				Code:    "INVALID_REQUEST",
+				Message: "product_id is required",
+			},
+		})
+		return false
+	}
+	return true
+}
+
+func respondWishlistError(c *gin.Context, err error, message string) {
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case strings.HasSuffix(err.Error(), "not found") || err.Error() == "product not in cart":
+		status, code = http.StatusNotFound, "NOT_FOUND"
+	case err.Error() == "wishlist name already in use":
+		status, code = http.StatusConflict, "NAME_IN_USE"
+	case err.Error() == "insufficient stock" || err.Error() == "product is not available":
+		status, code = http.StatusBadRequest, "UNAVAILABLE"
+	case err.Error() == "name is required" || err.Error() == "name is too long" ||
+		err.Error() == "wishlist limit reached" || err.Error() == "cannot delete the default wishlist" ||
+		err.Error() == "make another wishlist the default instead":
+		status, code = http.StatusBadRequest, "INVALID_REQUEST"
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &models.APIError{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+}
@@ -0,0 +1,74 @@
+package carrier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+)
+
+// FakeCarrier books parcels without calling a carrier. Tracking numbers it has not
+// been given events for with SetEvents report nothing. Set Err to make every call
+// fail. It is meant for tests and local development.
+type FakeCarrier struct {
+	mu     sync.Mutex
+	Labels []LabelRequest
+	Events map[string][]TrackingEvent
+	Err    error
+}
+
+func NewFakeCarrier() *FakeCarrier {
+	return &FakeCarrier{Events: make(map[string][]TrackingEvent)}
+}
+
+func (f *FakeCarrier) CreateLabel(_ context.Context, req LabelRequest) (*Label, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	f.Labels = append(f.Labels, req)
+	number := "FAKE" + strings.ToUpper(strings.ReplaceAll(req.ShipmentID.String(), "-", "")[:12])
+	return &Label{
+		TrackingNumber: number,
+		Reference:      fmt.Sprintf("fake_bk_%s", req.ShipmentID),
+		Data:           []byte("%PDF-1.4\n% fake label " + number + "\n"),
+		ContentType:    "application/pdf",
+	}, nil
+}
+
+func (f *FakeCarrier) Track(_ context.Context, trackingNumber string) ([]TrackingEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	return append([]TrackingEvent(nil), f.Events[trackingNumber]...), nil
+}
+
+// SetEvents sets the events reported for a tracking number
+func (f *FakeCarrier) SetEvents(trackingNumber string, events ...TrackingEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Events[trackingNumber] = events
+}
+
+// Deliver reports a tracking number as delivered now
+func (f *FakeCarrier) Deliver(trackingNumber string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Events[trackingNumber] = append(f.Events[trackingNumber], TrackingEvent{
+		Status:      models.ShipmentStatusDelivered,
+		Code:        "DELIVERED",
+		Description: "The shipment has been delivered",
+		OccurredAt:  time.Now().UTC().Truncate(time.Second),
+	})
+}
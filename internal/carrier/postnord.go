@@ -0,0 +1,267 @@
+package carrier
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/models"
+)
+
+// postNordDefaultService is MyPack Collect, delivered to a service point
+const postNordDefaultService = "19"
+
+// PostNordCarrier books parcels with the PostNord EDI label API and tracks them
+// with Track and Trace
+type PostNordCarrier struct {
+	cfg    config.PostNordConfig
+	client *http.Client
+}
+
+func NewPostNordCarrier(cfg config.PostNordConfig) *PostNordCarrier {
+	return &PostNordCarrier{cfg: cfg, client: newHTTPClient("postnord")}
+}
+
+type postNordParty struct {
+	PartyIdentification *postNordPartyID `json:"partyIdentification,omitempty"`
+	Party               struct {
+		NameIdentification struct {
+			Name string `json:"name"`
+		} `json:"nameIdentification"`
+		Address struct {
+			Streets     []string `json:"streets"`
+			PostalCode  string   `json:"postalCode"`
+			City        string   `json:"city"`
+			CountryCode string   `json:"countryCode"`
+		} `json:"address"`
+		Contact struct {
+			EmailAddress string `json:"emailAddress,omitempty"`
+			SMSNo        string `json:"smsNo,omitempty"`
+		} `json:"contact"`
+	} `json:"party"`
+}
+
+type postNordPartyID struct {
+	PartyID     string `json:"partyId"`
+	PartyIDType string `json:"partyIdType"`
+}
+
+func (c *PostNordCarrier) CreateLabel(ctx context.Context, req LabelRequest) (*Label, error) {
+	if c.cfg.APIKey == "" || c.cfg.CustomerNumber == "" {
+		return nil, errors.New("postnord is not configured")
+	}
+
+	service := req.Service
+	if service == "" {
+		service = postNordDefaultService
+	}
+
+	consignor := newPostNordParty(req.Sender)
+	consignor.PartyIdentification = &postNordPartyID{PartyID: c.cfg.CustomerNumber, PartyIDType: "160"}
+	consignee := newPostNordParty(req.Recipient)
+
+	parcel := map[string]interface{}{
+		"copies": 1,
+		"weight": map[string]interface{}{"value": req.Weight, "unit": "KGM"},
+	}
+	if d := req.Dimensions; d != nil {
+		parcel["length"] = map[string]interface{}{"value": d.Length, "unit": "CMT"}
+		parcel["width"] = map[string]interface{}{"value": d.Width, "unit": "CMT"}
+		parcel["height"] = map[string]interface{}{"value": d.Height, "unit": "CMT"}
+	}
+
+	body := map[string]interface{}{
+		"messageDate":     time.Now().UTC().Format(time.RFC3339),
+		"messageFunction": "Instruction",
+		"messageId":       req.ShipmentID.String(),
+		"application": map[string]interface{}{
+			"name":    "SmrtMart",
+			"version": "1.0",
+		},
+		"updateIndicator": "Original",
+		"shipment": []interface{}{map[string]interface{}{
+			"shipmentIdentification": map[string]interface{}{"shipmentId": "0"},
+			"dateAndTimes":           map[string]interface{}{"loadingDate": time.Now().UTC().Format(time.RFC3339)},
+			"service":                map[string]interface{}{"basicServiceCode": service},
+			"numberOfPackages":       map[string]interface{}{"value": 1},
+			"totalGrossWeight":       map[string]interface{}{"value": req.Weight, "unit": "KGM"},
+			"references": []interface{}{
+				map[string]interface{}{"referenceNo": req.ShipmentID.String(), "referenceType": "CU"},
+			},
+			"parties":   map[string]interface{}{"consignor": consignor, "consignee": consignee},
+			"goodsItem": []interface{}{map[string]interface{}{"packageTypeCode": "PC", "items": []interface{}{parcel}}},
+		}},
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/rest/shipment/v3/edi/labels/pdf?%s", c.cfg.BaseURL, url.Values{
+		"apikey":    {c.cfg.APIKey},
+		"paperSize": {"LABEL"},
+	}.Encode())
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	var resp struct {
+		BookingResponse struct {
+			BookingID     string `json:"bookingId"`
+			IDInformation []struct {
+				IDs []struct {
+					IDType string `json:"idType"`
+					Value  string `json:"value"`
+				} `json:"ids"`
+			} `json:"idInformation"`
+		} `json:"bookingResponse"`
+		LabelPrintout []struct {
+			Printout struct {
+				Data string `json:"data"`
+			} `json:"printout"`
+		} `json:"labelPrintout"`
+	}
+	if err := doJSON(c.client, httpReq, &resp); err != nil {
+		return nil, fmt.Errorf("failed to book postnord label: %w", err)
+	}
+
+	label := &Label{Reference: resp.BookingResponse.BookingID, ContentType: "application/pdf"}
+	for _, info := range resp.BookingResponse.IDInformation {
+		for _, id := range info.IDs {
+			if label.TrackingNumber == "" && id.Value != "" {
+				label.TrackingNumber = id.Value
+			}
+		}
+	}
+	if label.TrackingNumber == "" {
+		return nil, errors.New("postnord returned no tracking number")
+	}
+	if len(resp.LabelPrintout) > 0 {
+		data, err := base64.StdEncoding.DecodeString(resp.LabelPrintout[0].Printout.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid postnord label: %w", err)
+		}
+		label.Data = data
+	}
+	return label, nil
+}
+
+func (c *PostNordCarrier) Track(ctx context.Context, trackingNumber string) ([]TrackingEvent, error) {
+	if c.cfg.APIKey == "" {
+		return nil, errors.New("postnord is not configured")
+	}
+
+	endpoint := fmt.Sprintf("%s/rest/shipment/v5/trackandtrace/findByIdentifier.json?%s", c.cfg.BaseURL, url.Values{
+		"apikey": {c.cfg.APIKey},
+		"id":     {trackingNumber},
+		"locale": {"en"},
+	}.Encode())
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		TrackingInformationResponse struct {
+			Shipments []struct {
+				Items []struct {
+					Events []struct {
+						EventTime        string `json:"eventTime"`
+						EventCode        string `json:"eventCode"`
+						EventDescription string `json:"eventDescription"`
+						Status           string `json:"status"`
+						Location         struct {
+							DisplayName string `json:"displayName"`
+						} `json:"location"`
+					} `json:"events"`
+				} `json:"items"`
+			} `json:"shipments"`
+		} `json:"TrackingInformationResponse"`
+	}
+	if err := doJSON(c.client, httpReq, &resp); err != nil {
+		if err == errNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to track postnord shipment: %w", err)
+	}
+
+	var events []TrackingEvent
+	for _, shipment := range resp.TrackingInformationResponse.Shipments {
+		for _, item := range shipment.Items {
+			for _, e := range item.Events {
+				occurredAt, err := parsePostNordTime(e.EventTime)
+				if err != nil {
+					continue
+				}
+				events = append(events, TrackingEvent{
+					Status:      postNordStatus(e.Status),
+					Code:        e.EventCode,
+					Description: e.EventDescription,
+					Location:    e.Location.DisplayName,
+					OccurredAt:  occurredAt,
+				})
+			}
+		}
+	}
+	return events, nil
+}
+
+func newPostNordParty(p Party) postNordParty {
+	var party postNordParty
+	party.Party.NameIdentification.Name = p.Name
+	party.Party.Address.Streets = []string{p.Address.Street}
+	party.Party.Address.PostalCode = p.Address.PostalCode
+	party.Party.Address.City = p.Address.City
+	party.Party.Address.CountryCode = strings.ToUpper(p.Address.Country)
+	party.Party.Contact.EmailAddress = p.Email
+	if p.Address.Phone != nil {
+		party.Party.Contact.SMSNo = *p.Address.Phone
+	}
+	return party
+}
+
+// postNordStatus maps a Track and Trace event status to a shipment status
+func postNordStatus(status string) models.ShipmentStatus {
+	switch status {
+	case "DELIVERED":
+		return models.ShipmentStatusDelivered
+	case "AVAILABLE_FOR_DELIVERY", "AVAILABLE_FOR_DELIVERY_PAR_LOC":
+		return models.ShipmentStatusOutForDelivery
+	case "RETURNED":
+		return models.ShipmentStatusReturned
+	case "DELIVERY_IMPOSSIBLE", "DELIVERY_REFUSED", "STOPPED", "DELAYED":
+		return models.ShipmentStatusException
+	case "CREATED", "INFORMED":
+		return models.ShipmentStatusCreated
+	default:
+		return models.ShipmentStatusInTransit
+	}
+}
+
+// parsePostNordTime parses event times, which are given in Swedish local time without an offset
+func parsePostNordTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		loc = time.UTC
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,74 @@
+package carrier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPostNordTrackTracesRequest(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), "test", 1)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer provider.Shutdown(context.Background())
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"TrackingInformationResponse":{"shipments":[{"items":[{"events":[
+			{"eventTime":"2026-03-02T10:15:00","eventCode":"71","eventDescription":"Delivered","status":"DELIVERED","location":{"displayName":"Solna"}}
+		]}]}]}}`))
+	}))
+	defer server.Close()
+
+	pn := NewPostNordCarrier(config.PostNordConfig{APIKey: "key", BaseURL: server.URL})
+	ctx, parent := tracing.Start(context.Background(), "ShipmentService.PollTracking")
+	events, err := pn.Track(ctx, "00370712345678901234")
+	parent.End()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Status != models.ShipmentStatusDelivered || events[0].Location != "Solna" {
+		t.Errorf("events = %+v", events)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want the client span and its parent", len(spans))
+	}
+	client := spans[0]
+	if client.Name != "postnord GET" || client.SpanKind != trace.SpanKindClient || client.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("client span %q, kind %v, parent %s", client.Name, client.SpanKind, client.Parent.SpanID())
+	}
+	if want := "00-" + client.SpanContext.TraceID().String() + "-" + client.SpanContext.SpanID().String() + "-01"; traceparent != want {
+		t.Errorf("traceparent = %q, want %q", traceparent, want)
+	}
+}
+
+func TestPostNordTrackStopsWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewPostNordCarrier(config.PostNordConfig{APIKey: "key", BaseURL: server.URL}).Track(ctx, "00370712345678901234")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
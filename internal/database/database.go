@@ -1,15 +1,24 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
 	"smrtmart-go-postgresql/internal/config"
 
-	_ "github.com/lib/pq"
+	"github.com/XSAM/otelsql"
+	"github.com/lib/pq"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 
@@ -18,7 +27,7 @@ func Initialize(cfg config.DatabaseConfig) (*sql.DB, error) {
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, cfg.SSLMode)
 
-	db, err := sql.Open("postgres", dsn)
+	db, err := Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -34,6 +43,25 @@ func Initialize(cfg config.DatabaseConfig) (*sql.DB, error) {
 	return db, nil
 }
 
+// Open opens a database whose queries get spans with their statement, never
+// their arguments
+func Open(driverName, dsn string) (*sql.DB, error) {
+	return otelsql.Open(driverName, dsn,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			SpanFilter:           tracedQuery,
+		}),
+	)
+}
+
+// tracedQuery traces queries made on behalf of a traced request or job, rather
+// than starting a trace for each query made without a context
+func tracedQuery(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) bool {
+	return trace.SpanContextFromContext(ctx).IsValid()
+}
+
 func RunMigrations(cfg config.DatabaseConfig) error {
 	// PostgreSQL DSN
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
@@ -74,4 +102,44 @@ func RunMigrations(cfg config.DatabaseConfig) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// LatestMigration returns the highest migration version shipped with the binary,
+// which is the version a fully migrated database is at
+func LatestMigration() (uint, error) {
+	entries, err := os.ReadDir("migrations")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var latest uint
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok || !strings.HasSuffix(entry.Name(), ".up.sql") {
+			continue
+		}
+		if version, err := strconv.ParseUint(prefix, 10, 64); err == nil && uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+	return latest, nil
+}
+
+// MigrationVersion returns the version the database is migrated to, and whether
+// a migration is running or failed halfway. It is 0 before the first migration.
+func MigrationVersion(ctx context.Context, db *sql.DB) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "42P01" { // undefined_table
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return uint(version), dirty, nil
+}
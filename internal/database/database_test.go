@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// migrationsDriver is a database/sql driver answering the schema_migrations
+// query with a fixed version
+type migrationsDriver struct{}
+
+func (migrationsDriver) Open(string) (driver.Conn, error) { return migrationsConn{}, nil }
+
+type migrationsConn struct{}
+
+func (migrationsConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (migrationsConn) Close() error                        { return nil }
+func (migrationsConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (migrationsConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	return &migrationsRows{values: [][]driver.Value{{int64(26), false}}}, nil
+}
+
+type migrationsRows struct {
+	values [][]driver.Value
+}
+
+func (r *migrationsRows) Columns() []string { return []string{"version", "dirty"} }
+func (r *migrationsRows) Close() error      { return nil }
+
+func (r *migrationsRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("migrations-test", migrationsDriver{})
+}
+
+func TestOpenTracesQueriesOfTracedContexts(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), "test", 1)
+	otel.SetTracerProvider(provider)
+	defer provider.Shutdown(context.Background())
+
+	db, err := Open("migrations-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Without a span in the context the query is not traced
+	if _, _, err := MigrationVersion(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Fatalf("untraced query recorded %d spans", len(spans))
+	}
+
+	ctx, parent := tracing.Start(context.Background(), "request")
+	version, dirty, err := MigrationVersion(ctx, db)
+	parent.End()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 26 || dirty {
+		t.Fatalf("got version %d dirty %v, want 26 clean", version, dirty)
+	}
+
+	var query *tracetest.SpanStub
+	for i, span := range exporter.GetSpans() {
+		if span.Name == "sql.conn.query" {
+			query = &exporter.GetSpans()[i]
+		}
+	}
+	if query == nil {
+		t.Fatalf("no query span among %v", spanNames(exporter.GetSpans()))
+	}
+	if query.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("query span is not a child of the request span")
+	}
+
+	var statement string
+	for _, attr := range query.Attributes {
+		if attr.Key == "db.statement" || attr.Key == "db.query.text" {
+			statement = attr.Value.AsString()
+		}
+		if strings.HasPrefix(string(attr.Key), "db.sql.args") {
+			t.Errorf("query arguments recorded as %s", attr.Key)
+		}
+	}
+	if !strings.Contains(statement, "FROM schema_migrations") {
+		t.Errorf("query span statement = %q", statement)
+	}
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name
+	}
+	return names
+}
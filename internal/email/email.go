@@ -0,0 +1,33 @@
+package email
+
+import (
+	"context"
+
+	"smrtmart-go-postgresql/internal/config"
+)
+
+// Message is a rendered email ready to send
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers messages
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+const (
+	BackendSMTP = "smtp"
+	BackendFake = "fake"
+)
+
+// New returns the sender for the configured backend, defaulting to SMTP
+func New(cfg config.EmailConfig) Sender {
+	if cfg.Backend == BackendFake {
+		return NewFakeSender()
+	}
+	return NewSMTPSender(cfg)
+}
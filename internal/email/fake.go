@@ -0,0 +1,32 @@
+package email
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// FakeSender logs and records messages instead of sending them. Set Err to make
+// every send fail. It is meant for tests and local development.
+type FakeSender struct {
+	mu   sync.Mutex
+	Sent []Message
+	Err  error
+}
+
+func NewFakeSender() *FakeSender {
+	return &FakeSender{}
+}
+
+func (f *FakeSender) Send(ctx context.Context, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return f.Err
+	}
+
+	f.Sent = append(f.Sent, msg)
+	slog.InfoContext(ctx, "Email", "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+	return nil
+}
@@ -0,0 +1,179 @@
+package email
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/config"
+)
+
+// smtpDelivery is what the stand-in server received in one session
+type smtpDelivery struct {
+	from, to string
+	data     []byte
+}
+
+// serveSMTP runs a stand-in SMTP server without TLS or authentication that
+// accepts one session and reports what it received
+func serveSMTP(t *testing.T) (port string, delivered <-chan smtpDelivery) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	result := make(chan smtpDelivery, 1)
+	go func() {
+		nc, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer nc.Close()
+		conn := textproto.NewConn(nc)
+
+		var d smtpDelivery
+		conn.PrintfLine("220 localhost ESMTP stand-in")
+		for {
+			line, err := conn.ReadLine()
+			if err != nil {
+				return
+			}
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO", "HELO":
+				conn.PrintfLine("250 localhost")
+			case "MAIL":
+				d.from = arg
+				conn.PrintfLine("250 OK")
+			case "RCPT":
+				d.to = arg
+				conn.PrintfLine("250 OK")
+			case "DATA":
+				conn.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+				if d.data, err = conn.ReadDotBytes(); err != nil {
+					return
+				}
+				conn.PrintfLine("250 OK")
+				result <- d
+			case "QUIT":
+				conn.PrintfLine("221 Bye")
+				return
+			default:
+				conn.PrintfLine("502 Command not implemented")
+			}
+		}
+	}()
+
+	_, port, _ = net.SplitHostPort(ln.Addr().String())
+	return port, result
+}
+
+func TestSMTPSenderDeliversMessage(t *testing.T) {
+	port, delivered := serveSMTP(t)
+	sender := NewSMTPSender(config.EmailConfig{
+		SMTPHost: "127.0.0.1",
+		SMTPPort: port,
+		From:     "orders@smrtmart.test",
+		FromName: "SmrtMart",
+	})
+
+	err := sender.Send(context.Background(), Message{
+		To:      "Åsa Berg <asa@example.com>",
+		Subject: "Återbetalning för order 1234",
+		Text:    "Vi har återbetalat 199,00 kr.",
+		HTML:    "<p>Vi har återbetalat <strong>199,00 kr</strong>.</p>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := <-delivered
+	if d.from != "FROM:<orders@smrtmart.test>" || d.to != "TO:<asa@example.com>" {
+		t.Errorf("envelope from %q to %q", d.from, d.to)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(d.data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Återbetalning för order 1234" {
+		t.Errorf("subject = %q (%v)", subject, err)
+	}
+	if to, err := msg.Header.AddressList("To"); err != nil || len(to) != 1 || to[0].Name != "Åsa Berg" {
+		t.Errorf("To = %v (%v)", to, err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type %q (%v)", mediaType, err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "Vi har återbetalat 199,00 kr."},
+		{"text/html; charset=utf-8", "<p>Vi har återbetalat <strong>199,00 kr</strong>.</p>"},
+	}
+	for _, w := range want {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("reading %s part: %v", w.contentType, err)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part.Header.Get("Content-Type") != w.contentType || string(body) != w.body {
+			t.Errorf("%s part = %q", part.Header.Get("Content-Type"), body)
+		}
+	}
+	if _, err := parts.NextPart(); err != io.EOF {
+		t.Errorf("unexpected extra part (%v)", err)
+	}
+}
+
+func TestSMTPSenderReportsRejectedRecipient(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		nc, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer nc.Close()
+		conn := textproto.NewConn(nc)
+		conn.PrintfLine("220 localhost ESMTP stand-in")
+		for {
+			line, err := conn.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb, _, _ := strings.Cut(line, " "); verb {
+			case "RCPT":
+				conn.PrintfLine("550 No such user")
+			case "QUIT":
+				conn.PrintfLine("221 Bye")
+				return
+			default:
+				conn.PrintfLine("250 OK")
+			}
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	sender := NewSMTPSender(config.EmailConfig{SMTPHost: "127.0.0.1", SMTPPort: port, From: "orders@smrtmart.test"})
+	err = sender.Send(context.Background(), Message{To: "nobody@example.com", Subject: "Hi", Text: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "No such user") {
+		t.Errorf("Send = %v, want the server's rejection", err)
+	}
+}
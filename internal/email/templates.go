@@ -0,0 +1,228 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"math"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Kind is a transactional message
+type Kind string
+
+const (
+	KindVerifyEmail          Kind = "verify_email"
+	KindPasswordReset        Kind = "password_reset"
+	KindOrderConfirmation    Kind = "order_confirmation"
+	KindShippingNotification Kind = "shipping_notification"
+	KindRefundIssued         Kind = "refund_issued"
+	KindReviewRequest        Kind = "review_request"
+)
+
+var kinds = []Kind{
+	KindVerifyEmail, KindPasswordReset, KindOrderConfirmation,
+	KindShippingNotification, KindRefundIssued, KindReviewRequest,
+}
+
+// Languages lists the languages messages are written in, the first being the fallback
+var Languages = []string{"en", "sv"}
+
+// VerifyEmailData is the data of a registration verification message
+type VerifyEmailData struct {
+	Name       string
+	Link       string
+	ValidHours int
+}
+
+// PasswordResetData is the data of a password reset message
+type PasswordResetData struct {
+	Name       string
+	Link       string
+	ValidHours int
+}
+
+// OrderConfirmationData is the data of an order confirmation
+type OrderConfirmationData struct {
+	Name        string
+	OrderNumber string
+	Items       []LineItem // May be empty when the items are not known, e.g. for Stripe sessions
+	Shipping    float64
+	Discount    float64
+	Total       float64
+	Currency    string
+	OrderURL    string
+}
+
+// LineItem is a product line in a message
+type LineItem struct {
+	Name     string
+	Quantity int
+	Total    float64
+}
+
+// ShippingNotificationData is the data of a shipping notification
+type ShippingNotificationData struct {
+	Name           string
+	OrderNumber    string
+	Carrier        string
+	TrackingNumber string
+	TrackingURL    string
+	Items          []LineItem
+}
+
+// RefundIssuedData is the data of a refund notification
+type RefundIssuedData struct {
+	Name        string
+	OrderNumber string
+	Amount      float64
+	Currency    string
+	Reason      string
+}
+
+// ReviewRequestData is the data of a review request
+type ReviewRequestData struct {
+	Name        string
+	OrderNumber string
+	Products    []ReviewProduct
+}
+
+// ReviewProduct is a product the customer is asked to review
+type ReviewProduct struct {
+	Name string
+	URL  string
+}
+
+// Renderer renders messages from the embedded templates. Every message has a
+// text template, which also defines the subject, and an HTML template rendered
+// inside the language's layout.
+type Renderer struct {
+	appURL string
+	text   map[string]*texttemplate.Template
+	html   map[string]*htmltemplate.Template
+}
+
+// NewRenderer parses the templates of every kind and language
+func NewRenderer(appURL string) (*Renderer, error) {
+	r := &Renderer{
+		appURL: appURL,
+		text:   make(map[string]*texttemplate.Template),
+		html:   make(map[string]*htmltemplate.Template),
+	}
+
+	for _, lang := range Languages {
+		funcs := templateFuncs(lang, appURL)
+		for _, kind := range kinds {
+			key := lang + "/" + string(kind)
+
+			text, err := texttemplate.New(string(kind)+".txt").Funcs(texttemplate.FuncMap(funcs)).
+				ParseFS(templateFS, "templates/"+key+".txt")
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s text template: %w", key, err)
+			}
+			r.text[key] = text
+
+			html, err := htmltemplate.New("layout.html").Funcs(htmltemplate.FuncMap(funcs)).
+				ParseFS(templateFS, "templates/"+lang+"/layout.html", "templates/"+key+".html")
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s html template: %w", key, err)
+			}
+			r.html[key] = html
+		}
+	}
+	return r, nil
+}
+
+// Render renders a message in lang, falling back to the first language for unknown ones
+func (r *Renderer) Render(kind Kind, lang string, data interface{}) (*Message, error) {
+	lang = Language(lang)
+	key := lang + "/" + string(kind)
+
+	text, ok := r.text[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown email kind %s", kind)
+	}
+
+	var subject, body, html bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := text.Execute(&body, data); err != nil {
+		return nil, err
+	}
+	if err := r.html[key].Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// Language returns lang if messages are written in it, or the fallback language
+func Language(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+	for _, l := range Languages {
+		if l == lang {
+			return l
+		}
+	}
+	return Languages[0]
+}
+
+func templateFuncs(lang, appURL string) map[string]interface{} {
+	return map[string]interface{}{
+		"money":  func(amount float64, currency string) string { return formatMoney(lang, amount, currency) },
+		"appURL": func() string { return appURL },
+	}
+}
+
+// formatMoney formats an amount the way it is written in lang,
+// e.g. "1 234,50 kr" in Swedish and "SEK 1,234.50" in English
+func formatMoney(lang string, amount float64, currency string) string {
+	currency = strings.ToUpper(currency)
+	cents := int64(math.Round(math.Abs(amount) * 100))
+	whole, frac := cents/100, cents%100
+
+	digits := fmt.Sprintf("%d", whole)
+	thousands, decimal := ",", "."
+	if lang == "sv" {
+		thousands, decimal = " ", ","
+	}
+	var grouped strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteString(thousands)
+		}
+		grouped.WriteRune(d)
+	}
+
+	number := fmt.Sprintf("%s%s%02d", grouped.String(), decimal, frac)
+	if amount < 0 {
+		number = "-" + number
+	}
+	if lang == "sv" && currency == "SEK" {
+		return number + " kr"
+	}
+	return currency + " " + number
+}
+
+// MustNewRenderer is NewRenderer for the embedded templates, which only fail to
+// parse because of a programming error
+func MustNewRenderer(appURL string) *Renderer {
+	r, err := NewRenderer(appURL)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
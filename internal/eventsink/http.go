@@ -0,0 +1,78 @@
+package eventsink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/tracing"
+)
+
+const httpTimeout = 10 * time.Second
+
+// HTTPSink POSTs every event as JSON to one endpoint, e.g. a data pipeline. Any
+// 2xx response counts as delivered.
+type HTTPSink struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewHTTPSink(url, secret string) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: httpTimeout, Transport: tracing.Transport("event sink", nil)},
+	}
+}
+
+func (s *HTTPSink) Publish(ctx context.Context, event *models.DomainEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", event.ID.String())
+	req.Header.Set("X-Event-Type", string(event.Type))
+	if s.secret != "" {
+		req.Header.Set("X-Signature", Signature(s.secret, time.Now(), body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("event sink responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Signature signs a request body as "t=<unix time>,v1=<hex HMAC-SHA256>", where
+// the HMAC is computed over "<unix time>.<body>". Receivers recompute it with
+// the shared secret and reject old timestamps to prevent replays.
+func Signature(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
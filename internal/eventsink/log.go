@@ -0,0 +1,27 @@
+package eventsink
+
+import (
+	"context"
+	"log/slog"
+
+	"smrtmart-go-postgresql/internal/models"
+)
+
+// LogSink writes events to the log, for local development and auditing
+type LogSink struct{}
+
+func NewLogSink() *LogSink {
+	return &LogSink{}
+}
+
+func (s *LogSink) Publish(ctx context.Context, event *models.DomainEvent) error {
+	// Payload fields holding personal data are redacted by the logger
+	slog.InfoContext(ctx, "Event",
+		"event_id", event.ID,
+		"type", event.Type,
+		"aggregate_type", event.AggregateType,
+		"aggregate_id", event.AggregateID,
+		"payload", string(event.Payload),
+	)
+	return nil
+}
@@ -0,0 +1,42 @@
+package eventsink
+
+import (
+	"context"
+	"log/slog"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/models"
+)
+
+const (
+	NameLog  = "log"
+	NameHTTP = "http"
+)
+
+// Sink delivers domain events to a system outside the process. Publish may be
+// called again for an event it already received, so receivers should
+// deduplicate on the event ID.
+type Sink interface {
+	Publish(ctx context.Context, event *models.DomainEvent) error
+}
+
+// New returns the configured sinks by name. Unknown names and an http sink
+// without a URL are skipped with a warning.
+func New(cfg config.EventsConfig) map[string]Sink {
+	sinks := make(map[string]Sink)
+	for _, name := range cfg.Sinks {
+		switch name {
+		case NameLog:
+			sinks[name] = NewLogSink()
+		case NameHTTP:
+			if cfg.SinkURL == "" {
+				slog.Warn("Event sink is not configured, skipping it", "sink", name)
+				continue
+			}
+			sinks[name] = NewHTTPSink(cfg.SinkURL, cfg.SinkSecret)
+		default:
+			slog.Warn("Unknown event sink, skipping it", "sink", name)
+		}
+	}
+	return sinks
+}
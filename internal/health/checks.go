@@ -0,0 +1,152 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/database"
+)
+
+// Database checks that a connection to the database can be made
+func Database(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// Migrations checks that the database is migrated at least to the version the
+// binary expects. A newer schema is fine: during a rolling deploy the old
+// binaries keep serving while the new ones have already migrated.
+func Migrations(db *sql.DB, expected uint) CheckFunc {
+	return func(ctx context.Context) error {
+		version, _, err := database.MigrationVersion(ctx, db)
+		if err != nil {
+			return err
+		}
+		if version < expected {
+			return fmt.Errorf("database is at migration %d, expected at least %d", version, expected)
+		}
+		return nil
+	}
+}
+
+// MigrationsClean checks that no migration is running or failed halfway
+func MigrationsClean(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		version, dirty, err := database.MigrationVersion(ctx, db)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is running or failed", version)
+		}
+		return nil
+	}
+}
+
+// Redis checks that a Redis server answers PING
+func Redis(addr, password string) CheckFunc {
+	return func(ctx context.Context) error {
+		conn, err := dial(ctx, addr, false)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		if password != "" {
+			if err := redisCommand(conn, reader, "+OK", "AUTH", password); err != nil {
+				return err
+			}
+		}
+		return redisCommand(conn, reader, "+PONG", "PING")
+	}
+}
+
+// Storage checks that files can be written to the upload directory
+func Storage(path string) CheckFunc {
+	return func(ctx context.Context) error {
+		file, err := os.CreateTemp(path, ".healthcheck-*")
+		if err != nil {
+			return err
+		}
+		file.Close()
+		return os.Remove(file.Name())
+	}
+}
+
+// SMTP checks that a mail server greets new connections. Port 465 is expected
+// to use TLS from the start.
+func SMTP(host, port string) CheckFunc {
+	return func(ctx context.Context) error {
+		conn, err := dial(ctx, net.JoinHostPort(host, port), port == "465")
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		greeting, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(greeting, "220") {
+			return fmt.Errorf("unexpected greeting %q", strings.TrimSpace(greeting))
+		}
+		_, err = conn.Write([]byte("QUIT\r\n"))
+		return err
+	}
+}
+
+// dial connects to addr and bounds every later read and write by ctx
+func dial(ctx context.Context, addr string, useTLS bool) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if useTLS {
+		conn, err = (&tls.Dialer{}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+	}
+	return conn, nil
+}
+
+// redisCommand sends a command and expects a simple string reply
+func redisCommand(conn net.Conn, reader *bufio.Reader, want string, args ...string) error {
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write([]byte(cmd.String())); err != nil {
+		return err
+	}
+
+	reply, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	reply = strings.TrimSpace(reply)
+	if reply != want {
+		if strings.HasPrefix(reply, "-") {
+			return errors.New(strings.TrimPrefix(reply, "-"))
+		}
+		return fmt.Errorf("unexpected reply %q to %s", reply, args[0])
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// schemaDriver answers the schema_migrations query with the version and dirty
+// flag given as the data source name, e.g. "26 false"
+type schemaDriver struct{}
+
+func (schemaDriver) Open(dsn string) (driver.Conn, error) {
+	fields := strings.Fields(dsn)
+	version, _ := strconv.ParseInt(fields[0], 10, 64)
+	dirty, _ := strconv.ParseBool(fields[1])
+	return &schemaConn{version: version, dirty: dirty}, nil
+}
+
+type schemaConn struct {
+	version int64
+	dirty   bool
+}
+
+func (*schemaConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (*schemaConn) Close() error                        { return nil }
+func (*schemaConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (c *schemaConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &schemaRows{row: []driver.Value{c.version, c.dirty}}, nil
+}
+
+type schemaRows struct {
+	row []driver.Value
+}
+
+func (*schemaRows) Columns() []string { return []string{"version", "dirty"} }
+func (*schemaRows) Close() error      { return nil }
+
+func (r *schemaRows) Next(dest []driver.Value) error {
+	if r.row == nil {
+		return io.EOF
+	}
+	copy(dest, r.row)
+	r.row = nil
+	return nil
+}
+
+func init() {
+	sql.Register("health-schema-test", schemaDriver{})
+}
+
+func TestMigrationChecks(t *testing.T) {
+	tests := []struct {
+		schema   string
+		migrated bool
+		clean    bool
+	}{
+		{"26 false", true, true},
+		{"27 false", true, true}, // Migrated by a newer binary during a rolling deploy
+		{"25 false", false, true},
+		{"26 true", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			db, err := sql.Open("health-schema-test", tt.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			if err := Migrations(db, 26)(context.Background()); (err == nil) != tt.migrated {
+				t.Errorf("Migrations: err = %v", err)
+			}
+			if err := MigrationsClean(db)(context.Background()); (err == nil) != tt.clean {
+				t.Errorf("MigrationsClean: err = %v", err)
+			}
+		})
+	}
+}
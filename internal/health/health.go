@@ -0,0 +1,148 @@
+// Package health reports whether the process can serve traffic, for liveness
+// and readiness probes
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// State is where the process is in its lifecycle
+type State string
+
+const (
+	StateStarting State = "starting" // Migrating and starting subsystems
+	StateReady    State = "ready"
+	StateStopping State = "stopping" // Draining before shutdown
+)
+
+// Status is the outcome of a readiness check, or of all of them
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusFailed   Status = "failed"
+	StatusReady    Status = "ready"
+	StatusDegraded Status = "degraded" // Ready, but an optional dependency is down
+	StatusNotReady Status = "not_ready"
+)
+
+// CheckFunc checks a dependency. It must give up when ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of one check
+type Result struct {
+	Status     Status `json:"status"`
+	Critical   bool   `json:"critical"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of a readiness probe
+type Report struct {
+	Status Status            `json:"status"`
+	State  State             `json:"state"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Ready reports whether traffic should be sent to the process
+func (r *Report) Ready() bool {
+	return r.Status != StatusNotReady
+}
+
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+}
+
+// Checker runs the readiness checks. The process is ready when its state is
+// ready and every critical check passes; failing optional checks only degrade it.
+type Checker struct {
+	timeout time.Duration
+	state   atomic.Value
+
+	mu     sync.RWMutex
+	checks []check
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	c := &Checker{timeout: timeout}
+	c.state.Store(StateStarting)
+	return c
+}
+
+// Add registers a check. A failing critical check makes the process not ready.
+func (c *Checker) Add(name string, critical bool, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, critical: critical, fn: fn})
+}
+
+func (c *Checker) SetState(state State) {
+	c.state.Store(state)
+}
+
+func (c *Checker) State() State {
+	return c.state.Load().(State)
+}
+
+// Check runs every check at once, each within the checker's timeout
+func (c *Checker) Check(ctx context.Context) *Report {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func(i int, chk check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, chk)
+		}(i, chk)
+	}
+	wg.Wait()
+
+	report := &Report{
+		Status: StatusReady,
+		State:  c.State(),
+		Checks: make(map[string]Result, len(checks)),
+	}
+	for i, chk := range checks {
+		result := results[i]
+		report.Checks[chk.name] = result
+		if result.Status == StatusOK {
+			continue
+		}
+		if chk.critical {
+			report.Status = StatusNotReady
+		} else if report.Status == StatusReady {
+			report.Status = StatusDegraded
+		}
+	}
+	if report.State != StateReady {
+		report.Status = StatusNotReady
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, chk check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	started := time.Now()
+	err := chk.fn(ctx)
+	result := Result{
+		Status:     StatusOK,
+		Critical:   chk.critical,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,51 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseVerifiesSignedTokens(t *testing.T) {
+	now := time.Now()
+	claims := Claims{UserID: uuid.New(), Role: "admin", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()}
+	token, err := Sign("secret", claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := Parse("secret", token, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *parsed != claims {
+		t.Errorf("claims = %+v, want %+v", parsed, claims)
+	}
+
+	parts := strings.Split(token, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"` + claims.UserID.String() + `","role":"admin","exp":9999999999}`))
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	tests := []struct {
+		name   string
+		secret string
+		token  string
+		at     time.Time
+		want   error
+	}{
+		{"other secret", "other", token, now, ErrInvalidToken},
+		{"no secret configured", "", token, now, ErrInvalidToken},
+		{"changed claims", "secret", parts[0] + "." + forged + "." + parts[2], now, ErrInvalidToken},
+		{"unsigned", "secret", none + "." + parts[1] + ".", now, ErrInvalidToken},
+		{"malformed", "secret", "not-a-token", now, ErrInvalidToken},
+		{"expired", "secret", token, now.Add(time.Hour), ErrExpiredToken},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.secret, tt.token, tt.at); err != tt.want {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package lifecycle starts a process's subsystems in order and stops them in
+// reverse, so that e.g. the HTTP server drains before the workers it feeds stop,
+// and the database closes last.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Hook starts or stops a component. Start must not block; long-running work
+// belongs in a goroutine, or in a component added with Go.
+type Hook func(ctx context.Context) error
+
+type component struct {
+	name  string
+	start Hook
+	stop  Hook
+}
+
+// Manager runs components in the order they were added
+type Manager struct {
+	mu         sync.Mutex
+	components []component
+	started    int
+}
+
+func New() *Manager {
+	return &Manager{}
+}
+
+// Add appends a component. Either hook may be nil.
+func (m *Manager) Add(name string, start, stop Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.components = append(m.components, component{name: name, start: start, stop: stop})
+}
+
+// Go appends a background loop that runs until its context is cancelled. On stop
+// the context is cancelled and the loop is waited for, up to the stop deadline.
+func (m *Manager) Go(name string, run func(ctx context.Context)) {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+
+	m.Add(name,
+		func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+			return nil
+		},
+		func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("did not stop in time: %w", ctx.Err())
+			}
+		},
+	)
+}
+
+// Start starts the components in order. If one fails, those already started are
+// stopped again and the error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for m.started < len(m.components) {
+		c := m.components[m.started]
+		if c.start != nil {
+			if err := c.start(ctx); err != nil {
+				m.stopStarted(ctx)
+				return fmt.Errorf("failed to start %s: %w", c.name, err)
+			}
+		}
+		m.started++
+	}
+	return nil
+}
+
+// Stop stops the started components in reverse order, all within ctx. A
+// component that fails to stop is logged and the rest are stopped regardless.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stopStarted(ctx)
+}
+
+func (m *Manager) stopStarted(ctx context.Context) error {
+	var errs []error
+	for ; m.started > 0; m.started-- {
+		c := m.components[m.started-1]
+		if c.stop == nil {
+			continue
+		}
+
+		slog.InfoContext(ctx, "Stopping", "component", c.name)
+		if err := c.stop(ctx); err != nil {
+			slog.ErrorContext(ctx, "Failed to stop", "component", c.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,105 @@
+// Package logging configures the process-wide structured logger. Records carry
+// the request ID and trace of the context they are logged with, and personal
+// data and credentials are redacted before anything is written.
+package logging
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey struct{}
+
+// WithRequestID returns a context carrying the ID of the request it belongs to
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" if there is none
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Setup makes a logger writing to stderr the default for both slog and the log
+// package, so existing log.Printf calls are redacted too. Format is "json" or
+// "text"; level is debug, info, warn or error.
+func Setup(format, level string) *slog.Logger {
+	logger := New(os.Stderr, format, level)
+	slog.SetDefault(logger)
+	// slog.SetDefault routes the log package through the handler, which adds
+	// its own timestamp
+	log.SetFlags(0)
+	return logger
+}
+
+// New returns a logger writing to w
+func New(w io.Writer, format, level string) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       parseLevel(level),
+		ReplaceAttr: redactAttr,
+	}
+
+	var handler slog.Handler
+	if format == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// contextHandler adds the request ID and trace of the record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	id := RequestID(ctx)
+	span := trace.SpanContextFromContext(ctx)
+	if id == "" && !span.IsValid() {
+		return h.Handler.Handle(ctx, record)
+	}
+
+	record = record.Clone()
+	if id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", span.TraceID().String()),
+			slog.String("span_id", span.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
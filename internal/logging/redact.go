@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are substrings of attribute and JSON keys whose values are
+// never logged
+var sensitiveKeys = []string{
+	"email", "phone", "address", "first_name", "last_name", "customer_name",
+	"password", "token", "secret", "authorization", "cookie", "signature",
+	"card_number", "cvc", "cvv",
+}
+
+var (
+	// "key": "value" pairs with a sensitive key, e.g. in logged event payloads
+	jsonFieldPattern = regexp.MustCompile(`"([A-Za-z0-9_]*(?:` + strings.Join(sensitiveKeys, "|") + `)[A-Za-z0-9_]*)"\s*:\s*"(?:[^"\\]|\\.)*"`)
+	// key=value pairs with a sensitive key, e.g. in query strings
+	queryParamPattern = regexp.MustCompile(`(?i)\b([a-z0-9_]*(?:token|secret|password|signature|key)[a-z0-9_]*)=[^&\s"]+`)
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	bearerPattern     = regexp.MustCompile(`(?i)\bBearer\s+[A-Za-z0-9._~+/\-]+=*`)
+	// JWTs, and Stripe and webhook signing keys
+	tokenPattern = regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+|\b(?:sk|rk|pk)_(?:live|test)_[A-Za-z0-9]+|\bwhsec_[A-Za-z0-9]+`)
+)
+
+// RedactString masks email addresses, bearer tokens, JWTs, API keys and
+// sensitive fields of JSON and query strings in s. Free-text postal addresses
+// cannot be recognised, so they must be logged under a sensitive key if at all.
+func RedactString(s string) string {
+	s = jsonFieldPattern.ReplaceAllString(s, `"$1":"`+redacted+`"`)
+	s = queryParamPattern.ReplaceAllString(s, "$1="+redacted)
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+redacted)
+	s = tokenPattern.ReplaceAllString(s, redacted)
+	return emailPattern.ReplaceAllString(s, redacted)
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr masks the values of sensitive attributes and redacts the rest
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Value.Kind() == slog.KindGroup {
+		return attr
+	}
+	if isSensitiveKey(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(RedactString(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			attr.Value = slog.StringValue(RedactString(err.Error()))
+		}
+	}
+	return attr
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"email", "sent to asa@example.com", "sent to [REDACTED]"},
+		{"bearer token", "Authorization: Bearer abc.def-123", "Authorization: Bearer [REDACTED]"},
+		{"jwt", "token eyJhbGciOi.eyJzdWIiOi.c2lnbmF0dXJl expired", "token [REDACTED] expired"},
+		{"stripe key", "using sk_live_51Habc and whsec_9xyz", "using [REDACTED] and [REDACTED]"},
+		{"query secrets", "/verify?token=abc123&lang=en&api_key=k1", "/verify?token=[REDACTED]&lang=en&api_key=[REDACTED]"},
+		{"json password", `{"password":"hunter2","name":"x"}`, `{"password":"[REDACTED]","name":"x"}`},
+		{"json card fields", `{"card_number":"4242424242424242","cvc":"123","last4":"4242"}`, `{"card_number":"[REDACTED]","cvc":"[REDACTED]","last4":"4242"}`},
+		{"json nested key", `{"client_secret": "pi_1_secret_2"}`, `{"client_secret":"[REDACTED]"}`},
+		{"nothing sensitive", "order CS_1 paid 49.99 usd", "order CS_1 paid 49.99 usd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactString(tt.in); got != tt.want {
+				t.Errorf("RedactString(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggerRedactsAttributes(t *testing.T) {
+	tests := []struct {
+		name string
+		attr slog.Attr
+		want any
+	}{
+		{"password", slog.String("password", "hunter2"), redacted},
+		{"token", slog.String("reset_token", "abc123"), redacted},
+		{"secret", slog.String("webhook_secret", "whsec_1"), redacted},
+		{"authorization", slog.String("Authorization", "Bearer abc"), redacted},
+		{"card number", slog.String("card_number", "4242424242424242"), redacted},
+		{"cvc", slog.Int("cvc", 123), redacted},
+		{"customer email", slog.String("customer_email", "asa@example.com"), redacted},
+		{"email in message text", slog.String("detail", "bounced for asa@example.com"), "bounced for " + redacted},
+		{"error text", slog.Any("error", errors.New("bad key sk_test_abc")), "bad key " + redacted},
+		{"plain value", slog.String("session_id", "cs_1"), "cs_1"},
+		{"number", slog.Float64("amount", 49.99), 49.99},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			New(&buf, "json", "info").LogAttrs(context.Background(), slog.LevelInfo, "test", tt.attr)
+
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("%v in %s", err, buf.String())
+			}
+			if got := record[tt.attr.Key]; got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.attr.Key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggerRedactsGroups(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, "text", "info").With("user", "u1").WithGroup("customer").Info("signed in",
+		"email", "asa@example.com", "password", "hunter2", "country", "SE")
+
+	out := buf.String()
+	for _, leaked := range []string{"asa@example.com", "hunter2"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("logged %q: %s", leaked, out)
+		}
+	}
+	for _, kept := range []string{"customer.country=SE", "user=u1"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("missing %q: %s", kept, out)
+		}
+	}
+}
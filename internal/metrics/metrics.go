@@ -0,0 +1,125 @@
+// Package metrics defines the Prometheus metrics exposed on /metrics
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "smrtmart"
+
+// HTTP
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests being served.",
+	})
+
+	RateLimitRejections = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by the per-IP rate limiter.",
+	})
+)
+
+// Webhooks
+var (
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by outcome: success, failure or disabled.",
+	}, []string{"outcome"})
+
+	WebhookDeliveryDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_duration_seconds",
+		Help:      "Time taken by webhook endpoints to respond.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	})
+)
+
+// Search
+var (
+	SearchQueriesDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "search_queries_dropped_total",
+		Help:      "Searches left out of the query log because its buffer was full.",
+	})
+)
+
+// Business
+var (
+	CheckoutsStarted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checkouts_started_total",
+		Help:      "Checkout sessions created.",
+	})
+
+	OrdersPaid = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_paid_total",
+		Help:      "Orders paid, by currency.",
+	}, []string{"currency"})
+
+	Revenue = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "revenue_total",
+		Help:      "Amount paid for orders, in major units, by currency.",
+	}, []string{"currency"})
+
+	Refunds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refunds_total",
+		Help:      "Refunds recorded, by currency.",
+	}, []string{"currency"})
+
+	RefundedAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refunded_amount_total",
+		Help:      "Amount refunded, in major units, by currency.",
+	}, []string{"currency"})
+)
+
+// RegisterDB exports the connection pool statistics of db
+func RegisterDB(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// OrderPaid counts a paid order and its amount
+func OrderPaid(currency string, amount float64) {
+	currency = strings.ToLower(currency)
+	OrdersPaid.WithLabelValues(currency).Inc()
+	Revenue.WithLabelValues(currency).Add(amount)
+}
+
+// Refunded counts a refund and its amount
+func Refunded(currency string, amount float64) {
+	currency = strings.ToLower(currency)
+	Refunds.WithLabelValues(currency).Inc()
+	RefundedAmount.WithLabelValues(currency).Add(amount)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIDHeader  = "X-Request-ID" // Carries the request ID in requests and responses
+	ContextRequestID = "request_id"
+)
+
+// Request IDs sent by clients or proxies are kept if they look like IDs, so they
+// can't be used to inject text into the logs
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,128}$`)
+
+// RequestID gives every request an ID, taken from the X-Request-ID header when
+// a proxy already set one. The ID is echoed in the response, carried by the
+// request context into services and their logs, and added to JSON error bodies
+// so support can find the request's log lines.
+func RequestID() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set(ContextRequestID, id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+
+		writer := &errorBodyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+		writer.flush(id)
+	})
+}
+
+// errorBodyWriter holds back JSON error bodies so the request ID can be added
+type errorBodyWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	buffering bool
+}
+
+func (w *errorBodyWriter) Write(data []byte) (int, error) {
+	if !w.buffering && !w.ResponseWriter.Written() && w.Status() >= http.StatusBadRequest &&
+		strings.Contains(w.Header().Get("Content-Type"), "json") {
+		w.buffering = true
+	}
+	if w.buffering {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *errorBodyWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *errorBodyWriter) Written() bool {
+	return w.buffering || w.ResponseWriter.Written()
+}
+
+func (w *errorBodyWriter) Size() int {
+	if w.buffering {
+		return w.body.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+// flush writes the held back body with the request ID added to it, if it is a
+// JSON object
+func (w *errorBodyWriter) flush(id string) {
+	if !w.buffering {
+		return
+	}
+
+	body := bytes.TrimSpace(w.body.Bytes())
+	if len(body) > 2 && body[0] == '{' && body[len(body)-1] == '}' {
+		encoded, _ := json.Marshal(id)
+		withID := make([]byte, 0, len(body)+len(encoded)+16)
+		withID = append(withID, body[:len(body)-1]...)
+		withID = append(withID, `,"request_id":`...)
+		withID = append(withID, encoded...)
+		withID = append(withID, '}')
+		body = withID
+	}
+	w.ResponseWriter.Write(body)
+}
+
+// Logger writes a structured log line for every request. The path is logged
+// without its query string, which may hold tokens or personal data.
+func Logger() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("duration_ms", time.Since(started).Milliseconds()),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userID, ok := CurrentUserID(c); ok {
+			attrs = append(attrs, slog.String("user_id", userID.String()))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+		slog.LogAttrs(c.Request.Context(), level, "Request", attrs...)
+	})
+}
+
+// Recovery turns panics into 500 responses and logs them with their stack
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err interface{}) {
+		slog.ErrorContext(c.Request.Context(), "Panic while handling request",
+			"panic", fmt.Sprint(err),
+			"stack", string(debug.Stack()),
+		)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Internal server error",
+			"error": gin.H{
+				"code":    "INTERNAL_ERROR",
+				"message": "An unexpected error occurred",
+			},
+		})
+	})
+}
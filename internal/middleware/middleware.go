@@ -1,15 +1,46 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"smrtmart-go-postgresql/internal/jwt"
+	"smrtmart-go-postgresql/internal/metrics"
+	"smrtmart-go-postgresql/internal/models"
+
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"golang.org/x/time/rate"
 )
 
+// Context keys set by the authentication middleware for the current user
+const (
+	ContextUserID   = "user_id"
+	ContextUserRole = "user_role"
+)
+
+// CurrentUserID returns the authenticated user's ID from the request context
+func CurrentUserID(c *gin.Context) (uuid.UUID, bool) {
+	value, exists := c.Get(ContextUserID)
+	if !exists {
+		return uuid.Nil, false
+	}
+
+	switch id := value.(type) {
+	case uuid.UUID:
+		return id, id != uuid.Nil
+	case string:
+		parsed, err := uuid.Parse(id)
+		return parsed, err == nil
+	default:
+		return uuid.Nil, false
+	}
+}
+
 // CORS middleware
 func CORS(allowedOrigins []string) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
@@ -55,8 +86,8 @@ func CORS(allowedOrigins []string) gin.HandlerFunc {
 		}
 		
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Requested-With")
-		c.Header("Access-Control-Expose-Headers", "Content-Length")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Requested-With, X-Request-ID")
+		c.Header("Access-Control-Expose-Headers", "Content-Length, X-Request-ID")
 		c.Header("Access-Control-Max-Age", "43200") // 12 hours
 		
 		// Debug info
@@ -86,6 +117,55 @@ func SecurityHeaders() gin.HandlerFunc {
 	})
 }
 
+// StartupGate answers 503 to every request but the given paths, typically the
+// health probes, until started reports true. It keeps the API closed while
+// migrations run and subsystems start, with the listener already up for probes.
+func StartupGate(started func() bool, paths ...string) gin.HandlerFunc {
+	open := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		open[path] = true
+	}
+
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if open[c.Request.URL.Path] || started() {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"message": "Service is starting",
+			"error": gin.H{
+				"code":    "SERVICE_STARTING",
+				"message": "The service is starting, please try again shortly",
+			},
+		})
+		c.Abort()
+	})
+}
+
+// Metrics records request counts and latencies. Routes are labelled by their
+// template, e.g. /api/v1/products/:id, so IDs don't create new series; requests
+// that match no route share the "unmatched" label.
+func Metrics() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		started := time.Now()
+		metrics.HTTPRequestsInFlight.Inc()
+		defer metrics.HTTPRequestsInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(started).Seconds())
+	})
+}
+
 // Per-IP rate limiter storage
 type visitor struct {
 	limiter  *rate.Limiter
@@ -137,6 +217,7 @@ func RateLimit() gin.HandlerFunc {
 		limiter := getVisitor(ip)
 
 		if !limiter.Allow() {
+			metrics.RateLimitRejections.Inc()
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"success": false,
 				"message": "Rate limit exceeded",
@@ -152,71 +233,93 @@ func RateLimit() gin.HandlerFunc {
 	})
 }
 
-// JWT Authentication middleware
-func JWTAuth() gin.HandlerFunc {
+// JWTAuth requires a valid bearer token and sets the user's ID and role in
+// the request context. Without a configured secret every token is refused.
+func JWTAuth(secret string) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": "Authorization header required",
-				"error": gin.H{
-					"code":    "UNAUTHORIZED",
-					"message": "Authorization header is required",
-				},
-			})
-			c.Abort()
+			abortUnauthorized(c, "Authorization header required", "UNAUTHORIZED", "Authorization header is required")
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": "Invalid authorization format",
-				"error": gin.H{
-					"code":    "INVALID_TOKEN_FORMAT",
-					"message": "Authorization header must be in format: Bearer <token>",
-				},
-			})
-			c.Abort()
+			abortUnauthorized(c, "Invalid authorization format", "INVALID_TOKEN_FORMAT", "Authorization header must be in format: Bearer <token>")
 			return
 		}
 
-		// TODO: Implement JWT token validation
-		// For now, we'll skip validation and continue
+		claims, err := jwt.Parse(secret, tokenString, time.Now())
+		if err != nil {
+			code := "INVALID_TOKEN"
+			if errors.Is(err, jwt.ErrExpiredToken) {
+				code = "TOKEN_EXPIRED"
+			}
+			abortUnauthorized(c, "Invalid token", code, err.Error())
+			return
+		}
+
+		c.Set(ContextUserID, claims.UserID)
+		c.Set(ContextUserRole, claims.Role)
 		c.Next()
 	})
 }
 
-// Optional JWT Authentication middleware (doesn't block if no token)
-func OptionalJWTAuth() gin.HandlerFunc {
+// OptionalJWTAuth sets the user's ID and role when the request carries a valid
+// bearer token, and otherwise lets it through as a guest
+func OptionalJWTAuth(secret string) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
-		if authHeader != "" {
-			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-			if tokenString != authHeader {
-				// TODO: Implement JWT token validation and set user context
+		if tokenString := strings.TrimPrefix(authHeader, "Bearer "); tokenString != authHeader {
+			if claims, err := jwt.Parse(secret, tokenString, time.Now()); err == nil {
+				c.Set(ContextUserID, claims.UserID)
+				c.Set(ContextUserRole, claims.Role)
 			}
 		}
 		c.Next()
 	})
 }
 
-// Admin role middleware
+// RequireAdmin lets only admins through. It must follow JWTAuth.
 func RequireAdmin() gin.HandlerFunc {
-	return gin.HandlerFunc(func(c *gin.Context) {
-		// TODO: Check if user has admin role
-		// For now, we'll allow all authenticated users
-		c.Next()
-	})
+	return requireRole(models.RoleAdmin)
 }
 
-// Vendor role middleware
+// RequireVendor lets only vendors through. It must follow JWTAuth.
 func RequireVendor() gin.HandlerFunc {
+	return requireRole(models.RoleVendor)
+}
+
+func requireRole(role models.UserRole) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
-		// TODO: Check if user has vendor role
-		// For now, we'll allow all authenticated users
+		if _, ok := CurrentUserID(c); !ok {
+			abortUnauthorized(c, "Authentication required", "UNAUTHORIZED", "A valid authenticated user is required")
+			return
+		}
+		if c.GetString(ContextUserRole) != string(role) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Insufficient permissions",
+				"error": gin.H{
+					"code":    "FORBIDDEN",
+					"message": "This endpoint requires the " + string(role) + " role",
+				},
+			})
+			c.Abort()
+			return
+		}
 		c.Next()
 	})
-}
\ No newline at end of file
+}
+
+func abortUnauthorized(c *gin.Context, message, code, detail string) {
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"success": false,
+		"message": message,
+		"error": gin.H{
+			"code":    code,
+			"message": detail,
+		},
+	})
+	c.Abort()
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/jwt"
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestStartupGateServesOnlyProbesUntilStarted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var started atomic.Bool
+	router := gin.New()
+	router.Use(StartupGate(started.Load, "/livez", "/readyz"))
+	for _, path := range []string{"/livez", "/readyz", "/api/v1/products"} {
+		router.GET(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if w := get("/api/v1/products"); w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("API before start: %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	for _, path := range []string{"/livez", "/readyz"} {
+		if w := get(path); w.Code != http.StatusOK {
+			t.Errorf("%s before start: %d", path, w.Code)
+		}
+	}
+
+	started.Store(true)
+	if w := get("/api/v1/products"); w.Code != http.StatusOK {
+		t.Errorf("API after start: %d", w.Code)
+	}
+}
+
+func TestRequireAdminRejectsAnonymousAndOtherRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const secret = "test-secret"
+	router := gin.New()
+	router.GET("/admin", JWTAuth(secret), RequireAdmin(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	token := func(secret string, role models.UserRole, expiresAt time.Time) string {
+		signed, err := jwt.Sign(secret, jwt.Claims{UserID: uuid.New(), Role: string(role), ExpiresAt: expiresAt.Unix()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + signed
+	}
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"not bearer", "Basic YWRtaW46YWRtaW4=", http.StatusUnauthorized},
+		{"garbage", "Bearer not-a-token", http.StatusUnauthorized},
+		{"wrong secret", token("other-secret", models.RoleAdmin, later), http.StatusUnauthorized},
+		{"expired", token(secret, models.RoleAdmin, time.Now().Add(-time.Minute)), http.StatusUnauthorized},
+		{"customer", token(secret, models.RoleCustomer, later), http.StatusForbidden},
+		{"vendor", token(secret, models.RoleVendor, later), http.StatusForbidden},
+		{"admin", token(secret, models.RoleAdmin, later), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireVendorRejectsCustomers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const secret = "test-secret"
+	router := gin.New()
+	router.GET("/vendor", JWTAuth(secret), RequireVendor(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for role, want := range map[models.UserRole]int{
+		models.RoleCustomer: http.StatusForbidden,
+		models.RoleAdmin:    http.StatusForbidden,
+		models.RoleVendor:   http.StatusOK,
+	} {
+		signed, err := jwt.Sign(secret, jwt.Claims{UserID: uuid.New(), Role: string(role), ExpiresAt: time.Now().Add(time.Hour).Unix()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/vendor", nil)
+		req.Header.Set("Authorization", "Bearer "+signed)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: status = %d, want %d", role, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingContinuesCallerTrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), "test", 1)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer provider.Shutdown(context.Background())
+
+	var handlerSpan trace.SpanContext
+	router := gin.New()
+	router.Use(RequestID(), Tracing())
+	router.GET("/api/v1/products/:id", func(c *gin.Context) {
+		_, span := tracing.Start(c.Request.Context(), "ProductService.GetProduct")
+		handlerSpan = span.SpanContext()
+		span.End()
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/products/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	server := spans[1]
+	if server.Name != "GET /api/v1/products/:id" {
+		t.Errorf("server span named %q, want the route template", server.Name)
+	}
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("server span kind = %v", server.SpanKind)
+	}
+	if got := server.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID %s does not continue the caller's trace", got)
+	}
+	if server.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("server span parent = %s", server.Parent.SpanID())
+	}
+	if handlerSpan.TraceID() != server.SpanContext.TraceID() || spans[0].Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("handler span is not a child of the server span")
+	}
+	if server.Status.Code != codes.Error {
+		t.Errorf("5xx response left span status %v", server.Status.Code)
+	}
+}
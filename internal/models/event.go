@@ -0,0 +1,149 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventProductCreated     EventType = "product.created"
+	EventProductUpdated     EventType = "product.updated"
+	EventProductDeleted     EventType = "product.deleted"
+	EventStockChanged       EventType = "stock.changed"
+	EventStockLow           EventType = "stock.low"     // Stock fell to or below the low-stock threshold
+	EventOrderCreated       EventType = "order.created" // A paid checkout became an order
+	EventOrderPaid          EventType = "order.paid"
+	EventOrderStatusChanged EventType = "order.status_changed"
+	EventShipmentCreated    EventType = "shipment.created"
+	EventCheckoutCompleted  EventType = "checkout.completed" // A Stripe checkout session was paid
+	EventCheckoutRefunded   EventType = "checkout.refunded"  // Some or all of a paid checkout was refunded
+)
+
+// EventTypes lists every event type that is emitted
+var EventTypes = []EventType{
+	EventProductCreated, EventProductUpdated, EventProductDeleted, EventStockChanged, EventStockLow,
+	EventOrderCreated, EventOrderPaid, EventOrderStatusChanged, EventShipmentCreated,
+	EventCheckoutCompleted, EventCheckoutRefunded,
+}
+
+// DomainEvent is a state change other parts of the system, or other systems, may
+// react to. Payload holds the type's payload struct as JSON.
+type DomainEvent struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	Seq           int64           `json:"seq" db:"seq"`
+	Type          EventType       `json:"type" db:"type"`
+	AggregateType string          `json:"aggregate_type" db:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id" db:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	OccurredAt    time.Time       `json:"occurred_at" db:"occurred_at"`
+	DispatchedAt  *time.Time      `json:"dispatched_at,omitempty" db:"dispatched_at"`
+}
+
+// NewDomainEvent creates an event about an aggregate with a payload struct
+func NewDomainEvent(eventType EventType, aggregateType, aggregateID string, payload interface{}) (*DomainEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &DomainEvent{
+		ID:            uuid.New(),
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       data,
+		OccurredAt:    time.Now().UTC(),
+	}, nil
+}
+
+// VendorIDs returns the vendors an event is about, read from the vendor_id or
+// vendor_ids field of its payload. Events about no vendor in particular, such
+// as checkout.completed, return none.
+func (e *DomainEvent) VendorIDs() []uuid.UUID {
+	var payload struct {
+		VendorID  *uuid.UUID  `json:"vendor_id"`
+		VendorIDs []uuid.UUID `json:"vendor_ids"`
+	}
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		return nil
+	}
+	if payload.VendorID != nil {
+		return append(payload.VendorIDs, *payload.VendorID)
+	}
+	return payload.VendorIDs
+}
+
+// ProductEventPayload is the payload of product.created, product.updated and product.deleted
+type ProductEventPayload struct {
+	ProductID uuid.UUID     `json:"product_id"`
+	VendorID  uuid.UUID     `json:"vendor_id"`
+	Name      string        `json:"name"`
+	SKU       *string       `json:"sku,omitempty"`
+	Price     float64       `json:"price"`
+	Stock     int           `json:"stock"`
+	Status    ProductStatus `json:"status"`
+}
+
+// StockEventPayload is the payload of stock.changed and stock.low
+type StockEventPayload struct {
+	ProductID     uuid.UUID `json:"product_id"`
+	VendorID      uuid.UUID `json:"vendor_id"`
+	SKU           *string   `json:"sku,omitempty"`
+	PreviousStock int       `json:"previous_stock"`
+	Stock         int       `json:"stock"`
+	Threshold     int       `json:"threshold,omitempty"` // Only for stock.low
+}
+
+// OrderEventPayload is the payload of order.created and order.paid. An order is
+// created once its checkout is paid, so both are emitted together.
+type OrderEventPayload struct {
+	SessionID     string  `json:"session_id"`
+	OrderNumber   string  `json:"order_number"`
+	CustomerEmail string  `json:"customer_email,omitempty"`
+	Currency      string  `json:"currency"`
+	AmountTotal   float64 `json:"amount_total"`
+}
+
+// OrderStatusEventPayload is the payload of order.status_changed
+type OrderStatusEventPayload struct {
+	OrderID   uuid.UUID   `json:"order_id"`
+	VendorIDs []uuid.UUID `json:"vendor_ids"` // Vendors with items in the order
+	From      OrderStatus `json:"from"`
+	To        OrderStatus `json:"to"`
+}
+
+// ShipmentEventPayload is the payload of shipment.created
+type ShipmentEventPayload struct {
+	ShipmentID     uuid.UUID  `json:"shipment_id"`
+	OrderID        uuid.UUID  `json:"order_id"`
+	VendorID       *uuid.UUID `json:"vendor_id,omitempty"`
+	Carrier        string     `json:"carrier"`
+	TrackingNumber *string    `json:"tracking_number,omitempty"`
+}
+
+// CheckoutEventPayload is the payload of checkout.completed
+type CheckoutEventPayload struct {
+	SessionID     string  `json:"session_id"`
+	CustomerEmail string  `json:"customer_email,omitempty"`
+	CustomerName  string  `json:"customer_name,omitempty"`
+	Locale        string  `json:"locale,omitempty"`
+	Currency      string  `json:"currency"`
+	AmountTotal   float64 `json:"amount_total"`
+	Shipping      float64 `json:"shipping"`
+	Discount      float64 `json:"discount"`
+}
+
+// CheckoutRefundEventPayload is the payload of checkout.refunded
+type CheckoutRefundEventPayload struct {
+	SessionID      string  `json:"session_id"`
+	ChargeID       string  `json:"charge_id"`
+	CustomerEmail  string  `json:"customer_email,omitempty"`
+	CustomerName   string  `json:"customer_name,omitempty"`
+	Currency       string  `json:"currency"`
+	Amount         float64 `json:"amount"`          // Refunded by this refund
+	AmountRefunded float64 `json:"amount_refunded"` // Refunded so far, this refund included
+	FullyRefunded  bool    `json:"fully_refunded"`
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type GiftCardStatus string
+
+const (
+	GiftCardStatusActive   GiftCardStatus = "active"
+	GiftCardStatusDisabled GiftCardStatus = "disabled"
+)
+
+// GiftCard is a code with a balance that can be spent at checkout until it expires
+type GiftCard struct {
+	ID             uuid.UUID      `json:"id" db:"id"`
+	Code           string         `json:"code" db:"code"`
+	InitialBalance float64        `json:"initial_balance" db:"initial_balance"`
+	Balance        float64        `json:"balance" db:"balance"`
+	Status         GiftCardStatus `json:"status" db:"status"`
+	ExpiresAt      *time.Time     `json:"expires_at,omitempty" db:"expires_at"`
+	RecipientEmail *string        `json:"recipient_email,omitempty" db:"recipient_email"`
+	Message        *string        `json:"message,omitempty" db:"message"`
+	IssuedBy       *uuid.UUID     `json:"issued_by,omitempty" db:"issued_by"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// GiftCardDetail is a gift card with its transaction ledger, newest first
+type GiftCardDetail struct {
+	*GiftCard
+	Transactions []BalanceTransaction `json:"transactions"`
+}
+
+// GiftCardBalance is the public view of a gift card, returned by balance checks
+type GiftCardBalance struct {
+	Code      string     `json:"code"` // Masked, only the last characters are shown
+	Balance   float64    `json:"balance"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Usable    bool       `json:"usable"`
+}
+
+// StoreCreditAccount is a user's store credit balance, e.g. from returns
+type StoreCreditAccount struct {
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Balance   float64   `json:"balance" db:"balance"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	Transactions *PaginatedResponse `json:"transactions,omitempty" db:"-"`
+}
+
+type BalanceTransactionType string
+
+const (
+	BalanceIssue   BalanceTransactionType = "issue"   // Gift card created
+	BalanceCredit  BalanceTransactionType = "credit"  // Store credit added
+	BalanceRedeem  BalanceTransactionType = "redeem"  // Spent at checkout
+	BalanceRelease BalanceTransactionType = "release" // Returned after an abandoned checkout
+	BalanceAdjust  BalanceTransactionType = "adjust"  // Manual correction by an admin
+)
+
+// BalanceTransaction is one change to a gift card or store credit balance
+type BalanceTransaction struct {
+	ID           uuid.UUID              `json:"id" db:"id"`
+	GiftCardID   *uuid.UUID             `json:"gift_card_id,omitempty" db:"gift_card_id"`
+	UserID       *uuid.UUID             `json:"user_id,omitempty" db:"user_id"`
+	Type         BalanceTransactionType `json:"type" db:"type"`
+	Amount       float64                `json:"amount" db:"amount"` // Negative when spent
+	BalanceAfter float64                `json:"balance_after" db:"balance_after"`
+	Reference    *string                `json:"reference,omitempty" db:"reference"`
+	Note         *string                `json:"note,omitempty" db:"note"`
+	CreatedBy    *uuid.UUID             `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt    time.Time              `json:"created_at" db:"created_at"`
+}
+
+type TenderType string
+
+const (
+	TenderGiftCard    TenderType = "gift_card"
+	TenderStoreCredit TenderType = "store_credit"
+)
+
+// AppliedTender is an amount taken from one gift card or store credit account at checkout
+type AppliedTender struct {
+	Type       TenderType `json:"type"`
+	GiftCardID *uuid.UUID `json:"-"`
+	Code       string     `json:"code,omitempty"` // Masked gift card code
+	Amount     float64    `json:"amount"`
+}
+
+// TenderRedemption is everything redeemed for one checkout, identified by Reference
+type TenderRedemption struct {
+	Reference string          `json:"reference"`
+	Total     float64         `json:"total"`
+	Applied   []AppliedTender `json:"applied"`
+}
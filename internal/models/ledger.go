@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CommissionScope string
+
+const (
+	CommissionScopeGlobal   CommissionScope = "global"
+	CommissionScopeCategory CommissionScope = "category"
+	CommissionScopeVendor   CommissionScope = "vendor"
+)
+
+// CommissionRate is the platform's share of an order item total, as a fraction between 0 and 1
+type CommissionRate struct {
+	ID         uuid.UUID       `json:"id" db:"id"`
+	Scope      CommissionScope `json:"scope" db:"scope"`
+	CategoryID *uuid.UUID      `json:"category_id,omitempty" db:"category_id"`
+	VendorID   *uuid.UUID      `json:"vendor_id,omitempty" db:"vendor_id"`
+	Rate       float64         `json:"rate" db:"rate"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+type LedgerEntryType string
+
+const (
+	LedgerEntrySale             LedgerEntryType = "sale"
+	LedgerEntryCommission       LedgerEntryType = "commission"
+	LedgerEntryRefund           LedgerEntryType = "refund"
+	LedgerEntryCommissionRefund LedgerEntryType = "commission_refund"
+	LedgerEntryPayout           LedgerEntryType = "payout"
+	LedgerEntryAdjustment       LedgerEntryType = "adjustment"
+)
+
+// LedgerEntry is a signed change to what the platform owes a vendor
+type LedgerEntry struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	VendorID       uuid.UUID       `json:"vendor_id" db:"vendor_id"`
+	OrderID        *uuid.UUID      `json:"order_id,omitempty" db:"order_id"`
+	OrderItemID    *uuid.UUID      `json:"order_item_id,omitempty" db:"order_item_id"`
+	PayoutID       *uuid.UUID      `json:"payout_id,omitempty" db:"payout_id"`
+	Type           LedgerEntryType `json:"type" db:"type"`
+	Amount         float64         `json:"amount" db:"amount"`
+	Currency       string          `json:"currency" db:"currency"`
+	CommissionRate *float64        `json:"commission_rate,omitempty" db:"commission_rate"`
+	Description    *string         `json:"description,omitempty" db:"description"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+}
+
+// VendorBalance summarises a vendor's ledger
+type VendorBalance struct {
+	VendorID  uuid.UUID `json:"vendor_id"`
+	Currency  string    `json:"currency"`
+	Balance   float64   `json:"balance"`   // Everything owed to the vendor, including amounts in a pending payout
+	Available float64   `json:"available"` // Not yet part of any payout
+	InPayout  float64   `json:"in_payout"` // Included in payouts that have not been paid yet
+	PaidOut   float64   `json:"paid_out"`  // Total transferred to the vendor so far
+}
+
+// VendorStatement lists a vendor's ledger entries in a period with opening and closing balances
+type VendorStatement struct {
+	VendorID       uuid.UUID     `json:"vendor_id"`
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	OpeningBalance float64       `json:"opening_balance"`
+	ClosingBalance float64       `json:"closing_balance"`
+	Entries        []LedgerEntry `json:"entries"`
+}
+
+type PayoutStatus string
+
+const (
+	PayoutStatusPending PayoutStatus = "pending"
+	PayoutStatusPaid    PayoutStatus = "paid"
+	PayoutStatusFailed  PayoutStatus = "failed"
+)
+
+type PayoutBatchStatus string
+
+const (
+	PayoutBatchPending         PayoutBatchStatus = "pending"
+	PayoutBatchProcessing      PayoutBatchStatus = "processing"
+	PayoutBatchCompleted       PayoutBatchStatus = "completed"
+	PayoutBatchPartiallyFailed PayoutBatchStatus = "partially_failed"
+)
+
+// PayoutBatch groups the payouts of every vendor with a positive balance up to PeriodEnd
+type PayoutBatch struct {
+	ID          uuid.UUID         `json:"id" db:"id"`
+	PeriodEnd   time.Time         `json:"period_end" db:"period_end"`
+	Status      PayoutBatchStatus `json:"status" db:"status"`
+	Total       float64           `json:"total" db:"total"`
+	PayoutCount int               `json:"payout_count" db:"payout_count"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty" db:"completed_at"`
+	Payouts     []VendorPayout    `json:"payouts,omitempty"`
+}
+
+// VendorPayout is a single transfer to a vendor within a batch
+type VendorPayout struct {
+	ID            uuid.UUID    `json:"id" db:"id"`
+	BatchID       uuid.UUID    `json:"batch_id" db:"batch_id"`
+	VendorID      uuid.UUID    `json:"vendor_id" db:"vendor_id"`
+	Amount        float64      `json:"amount" db:"amount"`
+	Currency      string       `json:"currency" db:"currency"`
+	Status        PayoutStatus `json:"status" db:"status"`
+	TransferID    *string      `json:"transfer_id,omitempty" db:"transfer_id"`
+	FailureReason *string      `json:"failure_reason,omitempty" db:"failure_reason"`
+	PeriodStart   *time.Time   `json:"period_start,omitempty" db:"period_start"`
+	PeriodEnd     time.Time    `json:"period_end" db:"period_end"`
+	CreatedAt     time.Time    `json:"created_at" db:"created_at"`
+	PaidAt        *time.Time   `json:"paid_at,omitempty" db:"paid_at"`
+}
@@ -23,6 +23,7 @@ type User struct {
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
 	LastLoginAt *time.Time `json:"last_login_at,omitempty" db:"last_login_at"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"`
 }
 
 type UserRole string
@@ -41,6 +42,14 @@ const (
 	StatusSuspended UserStatus = "suspended"
 )
 
+// TokenPurpose is what a token emailed to a user is for
+type TokenPurpose string
+
+const (
+	TokenPurposeVerifyEmail   TokenPurpose = "verify_email"
+	TokenPurposePasswordReset TokenPurpose = "password_reset"
+)
+
 // Vendor represents a business/SME on the platform
 type Vendor struct {
 	ID              uuid.UUID    `json:"id" db:"id"`
@@ -51,8 +60,11 @@ type Vendor struct {
 	Logo            *string      `json:"logo,omitempty" db:"logo"`
 	Website         *string      `json:"website,omitempty" db:"website"`
 	Address         Address      `json:"address" db:"address"`
+	Documents       *VendorDocuments `json:"documents,omitempty" db:"-"` // Only shown to the vendor and admins
 	Status          VendorStatus `json:"status" db:"status"`
+	StatusReason    *string      `json:"status_reason,omitempty" db:"status_reason"`
 	VerifiedAt      *time.Time   `json:"verified_at,omitempty" db:"verified_at"`
+	StripeAccountID *string      `json:"stripe_account_id,omitempty" db:"stripe_account_id"` // Connected account that receives payouts
 	CreatedAt       time.Time    `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time    `json:"updated_at" db:"updated_at"`
 }
@@ -66,6 +78,36 @@ const (
 	VendorStatusSuspended VendorStatus = "suspended"
 )
 
+// VendorDocuments holds the details a vendor submits for verification
+type VendorDocuments struct {
+	OrganizationNumber *string `json:"organization_number,omitempty" db:"organization_number"`
+	VATID              *string `json:"vat_id,omitempty" db:"vat_id"`
+	BankProofURL       *string `json:"bank_proof_url,omitempty" db:"bank_proof_url"`
+}
+
+// VendorStatusChange records an admin review decision
+type VendorStatusChange struct {
+	ID         uuid.UUID    `json:"id" db:"id"`
+	VendorID   uuid.UUID    `json:"vendor_id" db:"vendor_id"`
+	FromStatus VendorStatus `json:"from_status" db:"from_status"`
+	ToStatus   VendorStatus `json:"to_status" db:"to_status"`
+	Reason     string       `json:"reason" db:"reason"`
+	ChangedBy  *uuid.UUID   `json:"changed_by,omitempty" db:"changed_by"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+}
+
+// VendorReview is the admin view of a vendor with its review history
+type VendorReview struct {
+	Vendor  *Vendor              `json:"vendor"`
+	History []VendorStatusChange `json:"history"`
+}
+
+// VendorStorefront is the public view of an approved vendor and its active products
+type VendorStorefront struct {
+	Vendor   *Vendor            `json:"vendor"`
+	Products *PaginatedResponse `json:"products"`
+}
+
 // Product represents a product or service
 type Product struct {
 	ID          uuid.UUID     `json:"-" db:"id"`
@@ -77,6 +119,7 @@ type Product struct {
 	ComparePrice *float64     `json:"compare_price,omitempty" db:"compare_price"`
 	SKU         *string       `json:"sku,omitempty" db:"sku"`
 	Category    string        `json:"category" db:"category" validate:"required"`
+	CategoryID  *uuid.UUID    `json:"category_id,omitempty" db:"category_id"`
 	Tags        []string      `json:"tags" db:"tags"`
 	Images      []string      `json:"images" db:"images"`
 	Stock       int           `json:"stock" db:"stock"`
@@ -87,6 +130,9 @@ type Product struct {
 	SEO         SEOData       `json:"seo" db:"seo"`
 	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time     `json:"updated_at" db:"updated_at"`
+	Relevance   *float64      `json:"relevance,omitempty" db:"-"` // Search rank, only set on search results
+	Rating      *RatingSummary `json:"rating,omitempty" db:"-"`
+	LowestPrice30Days *float64 `json:"lowest_price_30_days,omitempty" db:"-"` // Lowest price in the 30 days before the current discount, only set when discounted
 }
 
 type ProductStatus string
@@ -190,6 +236,13 @@ type Category struct {
 	IsActive    bool      `json:"is_active" db:"is_active"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	Children    []*Category `json:"children,omitempty" db:"-"` // Only populated in the category tree
+}
+
+// CategorySortOrder sets the position of a category among its siblings
+type CategorySortOrder struct {
+	ID        uuid.UUID `json:"id" binding:"required"`
+	SortOrder int       `json:"sort_order"`
 }
 
 // Cart represents a shopping cart
@@ -222,10 +275,75 @@ type Review struct {
 	Title      *string   `json:"title,omitempty" db:"title"`
 	Comment    *string   `json:"comment,omitempty" db:"comment"`
 	IsVerified bool      `json:"is_verified" db:"is_verified"`
+	HelpfulCount int     `json:"helpful_count" db:"helpful_count"`
+	ReviewerName string  `json:"reviewer_name,omitempty" db:"-"` // First name and last initial, for public listings
+	Status           ReviewStatus `json:"status" db:"status"`
+	ModerationReason *string      `json:"moderation_reason,omitempty" db:"moderation_reason"`
+	ReportCount      int          `json:"report_count,omitempty" db:"report_count"`
+	Reply            *ReviewReply `json:"reply,omitempty" db:"-"`
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
 }
 
+type ReviewStatus string
+
+const (
+	ReviewStatusPending   ReviewStatus = "pending"
+	ReviewStatusPublished ReviewStatus = "published"
+	ReviewStatusRejected  ReviewStatus = "rejected"
+)
+
+// ReviewReply is the product vendor's public reply to a review
+type ReviewReply struct {
+	Body      string    `json:"body" db:"vendor_reply"`
+	RepliedAt time.Time `json:"replied_at" db:"vendor_replied_at"`
+}
+
+// ReviewReport is a customer's report of an abusive review
+type ReviewReport struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	ReviewID   uuid.UUID `json:"review_id" db:"review_id"`
+	ReporterID uuid.UUID `json:"reporter_id" db:"reporter_id"`
+	Reason     string    `json:"reason" db:"reason"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Review moderation actions recorded in the audit log
+const (
+	ReviewActionAutoHold    = "auto_hold"    // Held by the content filter
+	ReviewActionReportHold  = "report_hold"  // Held after reaching the report threshold
+	ReviewActionPublish     = "publish"
+	ReviewActionReject      = "reject"
+	ReviewActionResubmit    = "resubmit"     // Rejected review edited by the customer
+	ReviewActionRemoveReply = "remove_reply"
+)
+
+// ReviewModerationEntry records a moderation action on a review
+type ReviewModerationEntry struct {
+	ID         uuid.UUID    `json:"id" db:"id"`
+	ReviewID   uuid.UUID    `json:"review_id" db:"review_id"`
+	Action     string       `json:"action" db:"action"`
+	FromStatus ReviewStatus `json:"from_status" db:"from_status"`
+	ToStatus   ReviewStatus `json:"to_status" db:"to_status"`
+	Reason     *string      `json:"reason,omitempty" db:"reason"`
+	ActorID    *uuid.UUID   `json:"actor_id,omitempty" db:"actor_id"` // Unset for automatic actions
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+}
+
+// ReviewModeration is the admin view of a review with its reports and moderation history
+type ReviewModeration struct {
+	Review  *Review                 `json:"review"`
+	Reports []ReviewReport          `json:"reports"`
+	History []ReviewModerationEntry `json:"history"`
+}
+
+// RatingSummary aggregates a product's review ratings
+type RatingSummary struct {
+	Average   float64     `json:"average"`
+	Count     int         `json:"count"`
+	Histogram map[int]int `json:"histogram"` // Number of reviews per star rating, 1 to 5
+}
+
 // APIResponse represents a standard API response
 type APIResponse struct {
 	Success bool        `json:"success"`
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SaleStatus string
+
+const (
+	SaleStatusScheduled SaleStatus = "scheduled"
+	SaleStatusActive    SaleStatus = "active"
+	SaleStatusEnded     SaleStatus = "ended"
+	SaleStatusCancelled SaleStatus = "cancelled"
+)
+
+// SalePrice temporarily replaces a product's price. While the sale is active the
+// regular price is shown as the compare price, and both are restored when it ends.
+type SalePrice struct {
+	ID                   uuid.UUID  `json:"id" db:"id"`
+	ProductID            uuid.UUID  `json:"product_id" db:"product_id"`
+	SalePrice            float64    `json:"sale_price" db:"sale_price"`
+	StartsAt             time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt               time.Time  `json:"ends_at" db:"ends_at"`
+	Status               SaleStatus `json:"status" db:"status"`
+	OriginalPrice        *float64   `json:"original_price,omitempty" db:"original_price"` // Set while active
+	OriginalComparePrice *float64   `json:"original_compare_price,omitempty" db:"original_compare_price"`
+	CreatedBy            *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt            time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type PriceChangeReason string
+
+const (
+	PriceChangeInitial   PriceChangeReason = "initial"
+	PriceChangeManual    PriceChangeReason = "manual"
+	PriceChangeSaleStart PriceChangeReason = "sale_start"
+	PriceChangeSaleEnd   PriceChangeReason = "sale_end"
+)
+
+// PriceHistoryEntry is a product's price and compare price from changed_at until the next entry
+type PriceHistoryEntry struct {
+	ID           uuid.UUID         `json:"id" db:"id"`
+	ProductID    uuid.UUID         `json:"product_id" db:"product_id"`
+	Price        float64           `json:"price" db:"price"`
+	ComparePrice *float64          `json:"compare_price,omitempty" db:"compare_price"`
+	Reason       PriceChangeReason `json:"reason" db:"reason"`
+	ChangedAt    time.Time         `json:"changed_at" db:"changed_at"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PromotionType string
+
+const (
+	PromotionPercentage   PromotionType = "percentage"    // Value percent off eligible items
+	PromotionFixedAmount  PromotionType = "fixed_amount"  // Value off eligible items
+	PromotionFreeShipping PromotionType = "free_shipping" // Free shipping on the order
+	PromotionBuyXGetY     PromotionType = "buy_x_get_y"   // Value percent off the cheapest Y of every X+Y eligible units
+)
+
+// Promotion is a coupon, or an automatic discount if it has no code. Promotions
+// can be limited to a vendor's products and to specific products or categories.
+type Promotion struct {
+	ID               uuid.UUID     `json:"id" db:"id"`
+	Code             *string       `json:"code,omitempty" db:"code"`
+	Name             string        `json:"name" db:"name"`
+	Description      *string       `json:"description,omitempty" db:"description"`
+	Type             PromotionType `json:"type" db:"type"`
+	Value            float64       `json:"value" db:"value"`
+	MaxDiscount      *float64      `json:"max_discount,omitempty" db:"max_discount"`
+	MinSubtotal      *float64      `json:"min_subtotal,omitempty" db:"min_subtotal"` // Minimum order subtotal
+	BuyQuantity      *int          `json:"buy_quantity,omitempty" db:"buy_quantity"`
+	GetQuantity      *int          `json:"get_quantity,omitempty" db:"get_quantity"`
+	VendorID         *uuid.UUID    `json:"vendor_id,omitempty" db:"vendor_id"`
+	ProductIDs       []int64       `json:"product_ids" db:"product_ids"` // Numeric product IDs
+	CategoryIDs      []uuid.UUID   `json:"category_ids" db:"category_ids"`
+	Stackable        bool          `json:"stackable" db:"stackable"` // Can be combined with other stackable promotions
+	UsageLimit       *int          `json:"usage_limit,omitempty" db:"usage_limit"`
+	PerCustomerLimit *int          `json:"per_customer_limit,omitempty" db:"per_customer_limit"`
+	UsageCount       int           `json:"usage_count" db:"usage_count"`
+	StartsAt         *time.Time    `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt           *time.Time    `json:"ends_at,omitempty" db:"ends_at"`
+	IsActive         bool          `json:"is_active" db:"is_active"`
+	CreatedBy        *uuid.UUID    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt        time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// AppliedPromotion is a promotion's effect on a cart
+type AppliedPromotion struct {
+	PromotionID  uuid.UUID     `json:"promotion_id"`
+	Code         *string       `json:"code,omitempty"`
+	Name         string        `json:"name"`
+	Type         PromotionType `json:"type"`
+	Discount     float64       `json:"discount"`
+	FreeShipping bool          `json:"free_shipping"`
+}
+
+// PromotionQuote is the result of applying promotions to a cart
+type PromotionQuote struct {
+	Subtotal     float64            `json:"subtotal"`
+	Discount     float64            `json:"discount"`
+	Total        float64            `json:"total"`
+	FreeShipping bool               `json:"free_shipping"`
+	Applied      []AppliedPromotion `json:"applied"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// SearchSuggestions is returned by the autocomplete endpoint while the user types
+type SearchSuggestions struct {
+	Products   []ProductSuggestion  `json:"products"`
+	Categories []CategorySuggestion `json:"categories"`
+	Queries    []string             `json:"queries"`
+}
+
+type ProductSuggestion struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type CategorySuggestion struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// SearchQueryStat aggregates how often a normalized query was searched
+type SearchQueryStat struct {
+	Query           string    `json:"query" db:"query"`
+	SearchCount     int       `json:"search_count" db:"search_count"`
+	ZeroResultCount int       `json:"zero_result_count" db:"zero_result_count"`
+	LastResultCount int       `json:"last_result_count" db:"last_result_count"`
+	FirstSearchedAt time.Time `json:"first_searched_at" db:"first_searched_at"`
+	LastSearchedAt  time.Time `json:"last_searched_at" db:"last_searched_at"`
+}
+
+// SearchResponse is a paginated search result with optional facet counts
+type SearchResponse struct {
+	Data       interface{}             `json:"data"`
+	Pagination Pagination              `json:"pagination"`
+	Facets     map[string][]FacetCount `json:"facets,omitempty"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// PriceRange is one bucket of the "price" search facet; Max is exclusive and nil means unbounded
+type PriceRange struct {
+	Label string
+	Min   float64
+	Max   *float64
+}
+
+func priceLimit(v float64) *float64 { return &v }
+
+// PriceFacetRanges are the buckets used for the "price" facet, in SEK
+var PriceFacetRanges = []PriceRange{
+	{Label: "0-500", Min: 0, Max: priceLimit(500)},
+	{Label: "500-1000", Min: 500, Max: priceLimit(1000)},
+	{Label: "1000-5000", Min: 1000, Max: priceLimit(5000)},
+	{Label: "5000+", Min: 5000},
+}
+
+// PriceRangeLabel returns the facet bucket label for a price
+func PriceRangeLabel(price float64) string {
+	for _, r := range PriceFacetRanges {
+		if price >= r.Min && (r.Max == nil || price < *r.Max) {
+			return r.Label
+		}
+	}
+	return ""
+}
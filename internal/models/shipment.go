@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Shipment is a parcel sent for an order, or for one vendor's items in it
+type Shipment struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	OrderID        uuid.UUID       `json:"order_id" db:"order_id"`
+	VendorID       *uuid.UUID      `json:"vendor_id,omitempty" db:"vendor_id"`
+	Carrier        string          `json:"carrier" db:"carrier"`
+	Service        *string         `json:"service,omitempty" db:"service"`
+	TrackingNumber *string         `json:"tracking_number,omitempty" db:"tracking_number"`
+	LabelReference *string         `json:"label_reference,omitempty" db:"label_reference"` // The carrier's booking ID
+	HasLabel       bool            `json:"has_label" db:"-"`
+	Status         ShipmentStatus  `json:"status" db:"status"`
+	ShippedAt      *time.Time      `json:"shipped_at,omitempty" db:"shipped_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedBy      *uuid.UUID      `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+	Items          []ShipmentItem  `json:"items" db:"-"`
+	Events         []ShipmentEvent `json:"events" db:"-"`
+}
+
+type ShipmentStatus string
+
+const (
+	ShipmentStatusCreated        ShipmentStatus = "created"
+	ShipmentStatusInTransit      ShipmentStatus = "in_transit"
+	ShipmentStatusOutForDelivery ShipmentStatus = "out_for_delivery"
+	ShipmentStatusDelivered      ShipmentStatus = "delivered"
+	ShipmentStatusException      ShipmentStatus = "exception" // Delayed, damaged or undeliverable
+	ShipmentStatusReturned       ShipmentStatus = "returned"
+	ShipmentStatusCancelled      ShipmentStatus = "cancelled"
+)
+
+// ShipmentItem is a quantity of an order item in a shipment
+type ShipmentItem struct {
+	OrderItemID uuid.UUID `json:"order_item_id" db:"order_item_id"`
+	ProductID   uuid.UUID `json:"product_id" db:"product_id"`
+	Name        string    `json:"name" db:"name"`
+	Quantity    int       `json:"quantity" db:"quantity"`
+}
+
+// ShipmentEvent is a tracking event of a shipment
+type ShipmentEvent struct {
+	ID          uuid.UUID      `json:"id" db:"id"`
+	ShipmentID  uuid.UUID      `json:"shipment_id" db:"shipment_id"`
+	Status      ShipmentStatus `json:"status" db:"status"`
+	Code        string         `json:"code,omitempty" db:"code"` // The carrier's own event code
+	Description string         `json:"description" db:"description"`
+	Location    *string        `json:"location,omitempty" db:"location"`
+	OccurredAt  time.Time      `json:"occurred_at" db:"occurred_at"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
+}
+
+// ShipmentLabel is a shipping label document
+type ShipmentLabel struct {
+	Data        []byte
+	ContentType string
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShippingRegion is a country, optionally narrowed to a range of postal codes.
+// The bounds are compared with the same number of leading characters of the
+// postal code, so a range of "1" to "1" covers every code starting with 1.
+type ShippingRegion struct {
+	Country    string  `json:"country"` // ISO 3166-1 alpha-2
+	PostalFrom *string `json:"postal_from,omitempty"`
+	PostalTo   *string `json:"postal_to,omitempty"` // Defaults to postal_from
+}
+
+type ShippingRegions []ShippingRegion
+
+// ShippingZone groups the regions that share a set of shipping methods
+type ShippingZone struct {
+	ID        uuid.UUID        `json:"id" db:"id"`
+	Name      string           `json:"name" db:"name"`
+	Regions   ShippingRegions  `json:"regions" db:"regions"`
+	Priority  int              `json:"priority" db:"priority"` // Lower is matched first
+	IsActive  bool             `json:"is_active" db:"is_active"`
+	Methods   []ShippingMethod `json:"methods,omitempty" db:"-"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// ShippingMethod is a way of shipping to a zone. Its rate is the base rate plus
+// the per-kg rate for every started kilogram of billable weight.
+type ShippingMethod struct {
+	ID                    uuid.UUID `json:"id" db:"id"`
+	ZoneID                uuid.UUID `json:"zone_id" db:"zone_id"`
+	Name                  string    `json:"name" db:"name"`
+	Description           *string   `json:"description,omitempty" db:"description"`
+	BaseRate              float64   `json:"base_rate" db:"base_rate"`
+	RatePerKg             float64   `json:"rate_per_kg" db:"rate_per_kg"`
+	MinWeight             *float64  `json:"min_weight,omitempty" db:"min_weight"` // kg; the method is offered only within these bounds
+	MaxWeight             *float64  `json:"max_weight,omitempty" db:"max_weight"`
+	FreeShippingThreshold *float64  `json:"free_shipping_threshold,omitempty" db:"free_shipping_threshold"` // Free from this order subtotal
+	MinDeliveryDays       *int      `json:"min_delivery_days,omitempty" db:"min_delivery_days"`             // Business days
+	MaxDeliveryDays       *int      `json:"max_delivery_days,omitempty" db:"max_delivery_days"`
+	SortOrder             int       `json:"sort_order" db:"sort_order"`
+	IsActive              bool      `json:"is_active" db:"is_active"`
+	CreatedAt             time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ShippingOption is a shipping method priced for a cart
+type ShippingOption struct {
+	MethodID        uuid.UUID `json:"method_id"`
+	Name            string    `json:"name"`
+	Description     *string   `json:"description,omitempty"`
+	Rate            float64   `json:"rate"`
+	FreeShipping    bool      `json:"free_shipping"` // The free shipping threshold was reached
+	MinDeliveryDays *int      `json:"min_delivery_days,omitempty"`
+	MaxDeliveryDays *int      `json:"max_delivery_days,omitempty"`
+}
+
+// ShippingQuote lists the shipping options for a cart and destination
+type ShippingQuote struct {
+	Country    string           `json:"country"`
+	PostalCode string           `json:"postal_code,omitempty"`
+	ZoneID     uuid.UUID        `json:"zone_id"`
+	Zone       string           `json:"zone"`
+	Subtotal   float64          `json:"subtotal"`
+	Weight     float64          `json:"weight"` // Billable weight in kg
+	Options    []ShippingOption `json:"options"`
+}
+
+// Scan implements sql.Scanner for ShippingRegions
+func (r *ShippingRegions) Scan(value interface{}) error {
+	if value == nil {
+		*r = ShippingRegions{}
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into ShippingRegions", value)
+	}
+
+	return json.Unmarshal(bytes, r)
+}
+
+// Value implements driver.Valuer for ShippingRegions
+func (r ShippingRegions) Value() (driver.Value, error) {
+	if r == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(r)
+}
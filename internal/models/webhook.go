@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEndpoint is a URL that domain events are POSTed to. Endpoints without a
+// vendor belong to the platform and receive every subscribed event; a vendor's
+// endpoints only receive events about that vendor.
+type WebhookEndpoint struct {
+	ID                  uuid.UUID   `json:"id" db:"id"`
+	VendorID            *uuid.UUID  `json:"vendor_id,omitempty" db:"vendor_id"`
+	URL                 string      `json:"url" db:"url"`
+	Description         *string     `json:"description,omitempty" db:"description"`
+	Secret              string      `json:"-" db:"secret"`                // Only returned on create and rotate
+	EventTypes          []EventType `json:"event_types" db:"event_types"` // Empty for every type
+	IsActive            bool        `json:"is_active" db:"is_active"`
+	ConsecutiveFailures int         `json:"consecutive_failures" db:"consecutive_failures"`
+	DisabledAt          *time.Time  `json:"disabled_at,omitempty" db:"disabled_at"` // Set when disabled for failing
+	DisabledReason      *string     `json:"disabled_reason,omitempty" db:"disabled_reason"`
+	CreatedBy           *uuid.UUID  `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt           time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookEndpointWithSecret is an endpoint with its signing secret, returned only
+// when the secret is created or rotated
+type WebhookEndpointWithSecret struct {
+	*WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+func NewWebhookEndpointWithSecret(endpoint *WebhookEndpoint) *WebhookEndpointWithSecret {
+	return &WebhookEndpointWithSecret{WebhookEndpoint: endpoint, Secret: endpoint.Secret}
+}
+
+// WebhookDelivery records one attempt to deliver an event to an endpoint
+type WebhookDelivery struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	EndpointID     uuid.UUID `json:"endpoint_id" db:"endpoint_id"`
+	EventID        uuid.UUID `json:"event_id" db:"event_id"`
+	EventType      EventType `json:"event_type" db:"event_type"`
+	Attempt        int       `json:"attempt" db:"attempt"`
+	Redelivery     bool      `json:"redelivery" db:"redelivery"` // Requested by hand
+	Success        bool      `json:"success" db:"success"`
+	ResponseStatus *int      `json:"response_status,omitempty" db:"response_status"` // Nil if no response was received
+	ResponseBody   *string   `json:"response_body,omitempty" db:"response_body"`     // The start of the response
+	Error          *string   `json:"error,omitempty" db:"error"`
+	DurationMs     int       `json:"duration_ms" db:"duration_ms"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// WebhookPayload is the body POSTed to webhook endpoints. ID is the event ID,
+// which receivers can use to ignore repeated deliveries.
+type WebhookPayload struct {
+	ID            uuid.UUID       `json:"id"`
+	Type          EventType       `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Wishlist is a named list of products a customer has saved. Each customer has one default list.
+type Wishlist struct {
+	ID         uuid.UUID      `json:"id" db:"id"`
+	UserID     uuid.UUID      `json:"-" db:"user_id"`
+	Name       string         `json:"name" db:"name"`
+	IsDefault  bool           `json:"is_default" db:"is_default"`
+	ShareToken *string        `json:"share_token,omitempty" db:"share_token"` // Set while shared by public link
+	ItemCount  int            `json:"item_count" db:"-"`
+	Items      []WishlistItem `json:"items,omitempty" db:"-"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// WishlistItem is a saved product with its current price and stock
+type WishlistItem struct {
+	ID               uuid.UUID     `json:"id" db:"id"`
+	WishlistID       uuid.UUID     `json:"wishlist_id" db:"wishlist_id"`
+	ProductID        uuid.UUID     `json:"-" db:"product_id"`
+	ProductNumericID int           `json:"product_id" db:"numeric_id"`
+	Name             string        `json:"name" db:"name"`
+	Image            *string       `json:"image,omitempty" db:"-"` // First product image
+	Status           ProductStatus `json:"status" db:"status"`
+	Price            float64       `json:"price" db:"price"`
+	PriceWhenAdded   float64       `json:"price_when_added" db:"price_when_added"`
+	PriceDrop        float64       `json:"price_drop"` // How much cheaper the product is than when it was added
+	PriceDropped     bool          `json:"price_dropped"`
+	Stock            int           `json:"stock" db:"stock"`
+	InStock          bool          `json:"in_stock"` // Active and with stock left
+	AddedAt          time.Time     `json:"added_at" db:"created_at"`
+}
@@ -0,0 +1,42 @@
+package payout
+
+import (
+	"context"
+	"errors"
+
+	"smrtmart-go-postgresql/internal/config"
+
+	"github.com/google/uuid"
+)
+
+// Transfer is a request to move a vendor payout to the vendor's account
+type Transfer struct {
+	PayoutID    uuid.UUID // Used as the idempotency key, so retrying a payout never pays twice
+	BatchID     uuid.UUID
+	Destination string // Connected account ID of the vendor
+	Amount      float64
+	Currency    string
+}
+
+// ErrRejected wraps errors of transfers the provider refused outright, which
+// retrying the same payout cannot fix. Any other error may be temporary, such
+// as a network failure, and the transfer may even have been made.
+var ErrRejected = errors.New("transfer rejected")
+
+// Executor carries out vendor payouts. It returns the provider's transfer ID.
+type Executor interface {
+	Transfer(ctx context.Context, t Transfer) (string, error)
+}
+
+const (
+	BackendStripe = "stripe"
+	BackendFake   = "fake"
+)
+
+// New returns the executor for the configured backend, defaulting to Stripe Connect
+func New(backend string, stripeConfig config.StripeConfig) Executor {
+	if backend == BackendFake {
+		return NewFakeExecutor()
+	}
+	return NewStripeExecutor(stripeConfig)
+}
@@ -0,0 +1,31 @@
+package payout
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FakeExecutor records transfers instead of moving money. Set Err to make every
+// transfer fail. It is meant for tests and local development.
+type FakeExecutor struct {
+	mu        sync.Mutex
+	Transfers []Transfer
+	Err       error
+}
+
+func NewFakeExecutor() *FakeExecutor {
+	return &FakeExecutor{}
+}
+
+func (e *FakeExecutor) Transfer(_ context.Context, t Transfer) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.Err != nil {
+		return "", e.Err
+	}
+
+	e.Transfers = append(e.Transfers, t)
+	return fmt.Sprintf("fake_tr_%s", t.PayoutID), nil
+}
@@ -0,0 +1,70 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/config"
+
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/transfer"
+)
+
+// StripeExecutor pays vendors with Stripe Connect transfers from the platform balance
+type StripeExecutor struct {
+	stripeConfig config.StripeConfig
+}
+
+func NewStripeExecutor(stripeConfig config.StripeConfig) *StripeExecutor {
+	return &StripeExecutor{stripeConfig: stripeConfig}
+}
+
+func (e *StripeExecutor) Transfer(ctx context.Context, t Transfer) (string, error) {
+	if e.stripeConfig.SecretKey == "" {
+		return "", errors.New("stripe is not configured")
+	}
+	if t.Destination == "" {
+		return "", fmt.Errorf("%w: vendor has no connected Stripe account", ErrRejected)
+	}
+
+	params := &stripe.TransferParams{
+		// Stripe uses the smallest currency unit (öre for SEK)
+		Amount:        stripe.Int64(int64(math.Round(t.Amount * 100))),
+		Currency:      stripe.String(strings.ToLower(t.Currency)),
+		Destination:   stripe.String(t.Destination),
+		TransferGroup: stripe.String(t.BatchID.String()),
+	}
+	params.Context = ctx
+	params.AddMetadata("payout_id", t.PayoutID.String())
+	params.SetIdempotencyKey("payout-" + t.PayoutID.String())
+
+	tr, err := transfer.New(params)
+	if err != nil {
+		if rejected(err) {
+			return "", fmt.Errorf("%w: %w", ErrRejected, err)
+		}
+		return "", fmt.Errorf("failed to create transfer: %w", err)
+	}
+
+	return tr.ID, nil
+}
+
+// rejected reports whether Stripe refused the transfer itself, e.g. for an
+// insufficient balance or a closed account, as opposed to failing to answer.
+// Rate limits and conflicting concurrent requests are invalid requests too, but
+// succeed when retried.
+func rejected(err error) bool {
+	var stripeErr *stripe.Error
+	if !errors.As(err, &stripeErr) {
+		return false
+	}
+	switch stripeErr.HTTPStatusCode {
+	case http.StatusConflict, http.StatusTooManyRequests:
+		return false
+	}
+	return stripeErr.Type == stripe.ErrorTypeCard || stripeErr.Type == stripe.ErrorTypeInvalidRequest
+}
@@ -0,0 +1,42 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/config"
+
+	"github.com/stripe/stripe-go/v76"
+)
+
+func TestRejectedOnlyForDefinitiveStripeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"insufficient balance", &stripe.Error{Type: stripe.ErrorTypeInvalidRequest, Code: stripe.ErrorCodeBalanceInsufficient, HTTPStatusCode: http.StatusBadRequest}, true},
+		{"card error", &stripe.Error{Type: stripe.ErrorTypeCard, HTTPStatusCode: http.StatusPaymentRequired}, true},
+		{"wrapped", fmt.Errorf("transfer: %w", &stripe.Error{Type: stripe.ErrorTypeInvalidRequest, HTTPStatusCode: http.StatusBadRequest}), true},
+		{"rate limited", &stripe.Error{Type: stripe.ErrorTypeInvalidRequest, Code: stripe.ErrorCodeRateLimit, HTTPStatusCode: http.StatusTooManyRequests}, false},
+		{"concurrent request", &stripe.Error{Type: stripe.ErrorTypeInvalidRequest, HTTPStatusCode: http.StatusConflict}, false},
+		{"api error", &stripe.Error{Type: stripe.ErrorTypeAPI, HTTPStatusCode: http.StatusInternalServerError}, false},
+		{"idempotency", &stripe.Error{Type: stripe.ErrorTypeIdempotency, HTTPStatusCode: http.StatusBadRequest}, false},
+		{"network", errors.New("dial tcp: connection refused"), false},
+	}
+	for _, tt := range tests {
+		if got := rejected(tt.err); got != tt.want {
+			t.Errorf("%s: rejected = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStripeExecutorRejectsVendorWithoutAccount(t *testing.T) {
+	_, err := NewStripeExecutor(config.StripeConfig{SecretKey: "sk_test_123"}).Transfer(context.Background(), Transfer{Amount: 10, Currency: "SEK"})
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("err = %v, want ErrRejected", err)
+	}
+}
@@ -1,102 +1,419 @@
-package repository
-
-import (
-	"database/sql"
-	"smrtmart-go-postgresql/internal/models"
-)
-
-type CategoryRepository interface {
-	GetAll() ([]models.Category, error)
-	GetByID(id string) (*models.Category, error)
-	Create(category *models.Category) error
-	Update(category *models.Category) error
-	Delete(id string) error
-}
-
-type categoryRepository struct {
-	db *sql.DB
-}
-
-func NewCategoryRepository(db *sql.DB) CategoryRepository {
-	return &categoryRepository{db: db}
-}
-
-func (r *categoryRepository) GetAll() ([]models.Category, error) {
-	query := `
-		SELECT id, name, slug, description, image, parent_id, sort_order, is_active, created_at, updated_at
-		FROM categories
-		WHERE is_active = true
-		ORDER BY sort_order ASC, name ASC
-	`
-
-	rows, err := r.db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var categories []models.Category
-	for rows.Next() {
-		var c models.Category
-		err := rows.Scan(
-			&c.ID,
-			&c.Name,
-			&c.Slug,
-			&c.Description,
-			&c.Image,
-			&c.ParentID,
-			&c.SortOrder,
-			&c.IsActive,
-			&c.CreatedAt,
-			&c.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		categories = append(categories, c)
-	}
-
-	return categories, rows.Err()
-}
-
-func (r *categoryRepository) GetByID(id string) (*models.Category, error) {
-	query := `
-		SELECT id, name, slug, description, image, parent_id, sort_order, is_active, created_at, updated_at
-		FROM categories
-		WHERE id = ? AND is_active = true
-	`
-
-	var c models.Category
-	err := r.db.QueryRow(query, id).Scan(
-		&c.ID,
-		&c.Name,
-		&c.Slug,
-		&c.Description,
-		&c.Image,
-		&c.ParentID,
-		&c.SortOrder,
-		&c.IsActive,
-		&c.CreatedAt,
-		&c.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	return &c, nil
-}
-
-func (r *categoryRepository) Create(category *models.Category) error {
-	// TODO: Implement create
-	return nil
-}
-
-func (r *categoryRepository) Update(category *models.Category) error {
-	// TODO: Implement update
-	return nil
-}
-
-func (r *categoryRepository) Delete(id string) error {
-	// TODO: Implement delete
-	return nil
-}
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type CategoryRepository interface {
+	GetAll(ctx context.Context) ([]models.Category, error)
+	GetByID(ctx context.Context, id string) (*models.Category, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Category, error)
+	GetPath(ctx context.Context, id string) ([]models.Category, error)
+	GetDescendantIDs(ctx context.Context, id string) ([]uuid.UUID, error)
+	FindByID(ctx context.Context, id string) (*models.Category, error)
+	SlugExists(ctx context.Context, slug string, excludeID *uuid.UUID) (bool, error)
+	Create(ctx context.Context, category *models.Category) error
+	Update(ctx context.Context, category *models.Category, isActive *bool, checkParent func(parentPath []models.Category) error) error
+	Reorder(ctx context.Context, orders []models.CategorySortOrder) error
+	CountUsage(ctx context.Context, id string) (int, int, error)
+	Delete(ctx context.Context, id string) error
+	DeleteAndReassign(ctx context.Context, id string, target *models.Category, checkParent func(targetPath []models.Category) error) error
+}
+
+type categoryRepository struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(db *sql.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+func (r *categoryRepository) GetAll(ctx context.Context) ([]models.Category, error) {
+	query := `
+		SELECT id, name, slug, description, image, parent_id, sort_order, is_active, created_at, updated_at
+		FROM categories
+		WHERE is_active = true
+		ORDER BY sort_order ASC, name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		var c models.Category
+		err := rows.Scan(
+			&c.ID,
+			&c.Name,
+			&c.Slug,
+			&c.Description,
+			&c.Image,
+			&c.ParentID,
+			&c.SortOrder,
+			&c.IsActive,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *categoryRepository) GetByID(ctx context.Context, id string) (*models.Category, error) {
+	query := `
+		SELECT id, name, slug, description, image, parent_id, sort_order, is_active, created_at, updated_at
+		FROM categories
+		WHERE id = $1 AND is_active = true
+	`
+
+	return r.getOne(ctx, query, id)
+}
+
+func (r *categoryRepository) GetBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	query := `
+		SELECT id, name, slug, description, image, parent_id, sort_order, is_active, created_at, updated_at
+		FROM categories
+		WHERE slug = $1 AND is_active = true
+	`
+
+	return r.getOne(ctx, query, slug)
+}
+
+// GetPath returns the breadcrumb path from the root category down to the given category
+func (r *categoryRepository) GetPath(ctx context.Context, id string) ([]models.Category, error) {
+	return categoryPath(ctx, r.db, id)
+}
+
+// categoryPath returns the path from the root category down to the given
+// category using either the database or a transaction
+func categoryPath(ctx context.Context, q interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}, id string) ([]models.Category, error) {
+	query := `
+		WITH RECURSIVE path AS (
+			SELECT id, name, slug, description, image, parent_id, sort_order, is_active, created_at, updated_at, 0 AS depth
+			FROM categories
+			WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.name, c.slug, c.description, c.image, c.parent_id, c.sort_order, c.is_active, c.created_at, c.updated_at, p.depth + 1
+			FROM categories c
+			JOIN path p ON c.id = p.parent_id
+			WHERE p.depth < 32
+		)
+		SELECT id, name, slug, description, image, parent_id, sort_order, is_active, created_at, updated_at
+		FROM path
+		ORDER BY depth DESC
+	`
+
+	rows, err := q.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		var c models.Category
+		err := rows.Scan(
+			&c.ID,
+			&c.Name,
+			&c.Slug,
+			&c.Description,
+			&c.Image,
+			&c.ParentID,
+			&c.SortOrder,
+			&c.IsActive,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+
+	return categories, rows.Err()
+}
+
+// GetDescendantIDs returns the ID of the category and of every active category below it
+func (r *categoryRepository) GetDescendantIDs(ctx context.Context, id string) ([]uuid.UUID, error) {
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT id, 0 AS depth FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, t.depth + 1
+			FROM categories c
+			JOIN tree t ON c.parent_id = t.id
+			WHERE c.is_active = true AND t.depth < 32
+		)
+		SELECT id FROM tree
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var categoryID uuid.UUID
+		if err := rows.Scan(&categoryID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, categoryID)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *categoryRepository) getOne(ctx context.Context, query string, arg interface{}) (*models.Category, error) {
+	var c models.Category
+	err := r.db.QueryRowContext(ctx, query, arg).Scan(
+		&c.ID,
+		&c.Name,
+		&c.Slug,
+		&c.Description,
+		&c.Image,
+		&c.ParentID,
+		&c.SortOrder,
+		&c.IsActive,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (r *categoryRepository) FindByID(ctx context.Context, id string) (*models.Category, error) {
+	query := `
+		SELECT id, name, slug, description, image, parent_id, sort_order, is_active, created_at, updated_at
+		FROM categories
+		WHERE id = $1
+	`
+
+	return r.getOne(ctx, query, id)
+}
+
+func (r *categoryRepository) SlugExists(ctx context.Context, slug string, excludeID *uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM categories WHERE slug = $1 AND ($2::uuid IS NULL OR id <> $2::uuid))",
+		slug, excludeID,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (r *categoryRepository) Create(ctx context.Context, category *models.Category) error {
+	query := `
+		INSERT INTO categories (id, name, slug, description, image, parent_id, sort_order, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at`
+
+	if category.ID == uuid.Nil {
+		category.ID = uuid.New()
+	}
+
+	return r.db.QueryRowContext(ctx, query,
+		category.ID, category.Name, category.Slug, category.Description, category.Image,
+		category.ParentID, category.SortOrder, category.IsActive,
+	).Scan(&category.CreatedAt, &category.UpdatedAt)
+}
+
+// Update saves the category and keeps the readable category label on linked
+// products in sync with its slug, recording product.updated for each relabelled product
+// Update saves a category, keeping its active flag when isActive is nil. With a
+// parent, checkParent is given the parent's path while the category tree is
+// locked, so two concurrent moves cannot each pass the check and together form
+// a cycle. An error from checkParent is returned as is.
+func (r *categoryRepository) Update(ctx context.Context, category *models.Category, isActive *bool, checkParent func(parentPath []models.Category) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockCategoryTree(ctx, tx); err != nil {
+		return err
+	}
+
+	var id uuid.UUID
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM categories WHERE id = $1 FOR UPDATE", category.ID).Scan(&id); err != nil {
+		return err
+	}
+
+	if category.ParentID != nil {
+		path, err := categoryPath(ctx, tx, category.ParentID.String())
+		if err != nil {
+			return err
+		}
+		if err := checkParent(path); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE categories SET
+			name = $2, slug = $3, description = $4, image = $5, parent_id = $6,
+			sort_order = $7, is_active = COALESCE($8, is_active), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING is_active, created_at, updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		category.ID, category.Name, category.Slug, category.Description, category.Image,
+		category.ParentID, category.SortOrder, isActive,
+	).Scan(&category.IsActive, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	renamed, err := queryIDs(ctx, tx,
+		"UPDATE products SET category = $2 WHERE category_id = $1 AND category <> $2 RETURNING id",
+		category.ID, category.Slug,
+	)
+	if err != nil {
+		return err
+	}
+	if err := insertProductUpdatedEvents(ctx, tx, renamed); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockCategoryTree serialises changes to category parents for the rest of the
+// transaction
+func lockCategoryTree(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('category_tree'))")
+	return err
+}
+
+// Reorder applies new sort orders to several categories in one transaction
+func (r *categoryRepository) Reorder(ctx context.Context, orders []models.CategorySortOrder) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, order := range orders {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE categories SET sort_order = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+			order.ID, order.SortOrder,
+		)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return sql.ErrNoRows
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CountUsage returns how many products and direct subcategories reference the category
+func (r *categoryRepository) CountUsage(ctx context.Context, id string) (int, int, error) {
+	var products, children int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM products WHERE category_id = $1),
+			(SELECT COUNT(*) FROM categories WHERE parent_id = $1)`, id,
+	).Scan(&products, &children)
+	return products, children, err
+}
+
+func (r *categoryRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM categories WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteAndReassign moves the category's products and subcategories to the target
+// category and deletes it, all in one transaction. Moved products get a
+// product.updated event. checkParent is given the target's path while the
+// category tree is locked.
+func (r *categoryRepository) DeleteAndReassign(ctx context.Context, id string, target *models.Category, checkParent func(targetPath []models.Category) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The children move under the target, so it is checked like a new parent
+	if err := lockCategoryTree(ctx, tx); err != nil {
+		return err
+	}
+	path, err := categoryPath(ctx, tx, target.ID.String())
+	if err != nil {
+		return err
+	}
+	if err := checkParent(path); err != nil {
+		return err
+	}
+
+	moved, err := queryIDs(ctx, tx,
+		"UPDATE products SET category_id = $2, category = $3 WHERE category_id = $1 RETURNING id",
+		id, target.ID, target.Slug,
+	)
+	if err != nil {
+		return err
+	}
+	if err := insertProductUpdatedEvents(ctx, tx, moved); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE categories SET parent_id = $2, updated_at = CURRENT_TIMESTAMP WHERE parent_id = $1", id, target.ID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM categories WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
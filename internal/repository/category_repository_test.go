@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func createTestCategory(t *testing.T, repo CategoryRepository, slug string, parentID *uuid.UUID, active bool) *models.Category {
+	t.Helper()
+	category := &models.Category{ID: uuid.New(), Name: slug, Slug: slug, ParentID: parentID, IsActive: active}
+	if err := repo.Create(context.Background(), category); err != nil {
+		t.Fatal(err)
+	}
+	return category
+}
+
+// refuseCycle is the check the category service passes to Update
+func refuseCycle(id uuid.UUID) func([]models.Category) error {
+	return func(path []models.Category) error {
+		for _, ancestor := range path {
+			if ancestor.ID == id {
+				return errors.New("cycle")
+			}
+		}
+		return nil
+	}
+}
+
+func TestCategoryUpdateKeepsActiveFlagWhenUnset(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCategoryRepository(testDB(t, "categories", "products"))
+	hidden := createTestCategory(t, repo, "hidden", nil, false)
+
+	hidden.Name = "Hidden"
+	if err := repo.Update(ctx, hidden, nil, refuseCycle(hidden.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := repo.FindByID(ctx, hidden.ID.String()); stored.IsActive || hidden.IsActive {
+		t.Errorf("update without is_active activated the category")
+	}
+
+	active := true
+	if err := repo.Update(ctx, hidden, &active, refuseCycle(hidden.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := repo.FindByID(ctx, hidden.ID.String()); !stored.IsActive {
+		t.Errorf("is_active not applied")
+	}
+}
+
+func TestCategoryConcurrentMovesCannotFormCycle(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCategoryRepository(testDB(t, "categories", "products"))
+
+	for i := 0; i < 10; i++ {
+		a := createTestCategory(t, repo, "a-"+uuid.NewString(), nil, true)
+		b := createTestCategory(t, repo, "b-"+uuid.NewString(), nil, true)
+		a.ParentID, b.ParentID = &b.ID, &a.ID
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for j, category := range []*models.Category{a, b} {
+			wg.Add(1)
+			go func(j int, category *models.Category) {
+				defer wg.Done()
+				errs[j] = repo.Update(ctx, category, nil, refuseCycle(category.ID))
+			}(j, category)
+		}
+		wg.Wait()
+
+		if (errs[0] == nil) == (errs[1] == nil) {
+			t.Fatalf("both moves returned %v and %v, want exactly one refused", errs[0], errs[1])
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"smrtmart-go-postgresql/internal/models"
+)
+
+type EmailRepository interface {
+	Enqueue(ctx context.Context, job *models.Job, dedupeKey *string) (bool, error)
+}
+
+type emailRepository struct {
+	db *sql.DB
+}
+
+func NewEmailRepository(db *sql.DB) EmailRepository {
+	return &emailRepository{db: db}
+}
+
+// Enqueue queues the job sending a message. With a dedupe key it returns false
+// without queueing anything if a message with the same key was queued before,
+// however long ago.
+func (r *emailRepository) Enqueue(ctx context.Context, job *models.Job, dedupeKey *string) (bool, error) {
+	if dedupeKey == nil {
+		return insertJob(ctx, r.db, job)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO email_dedupe_keys (dedupe_key) VALUES ($1) ON CONFLICT (dedupe_key) DO NOTHING",
+		*dedupeKey,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if _, err := insertJob(ctx, tx, job); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+)
+
+func TestEmailEnqueueDedupesForGood(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t, "jobs", "email_dedupe_keys")
+	repo := NewEmailRepository(db)
+	jobs := NewJobRepository(db)
+	newJob := func() *models.Job {
+		return &models.Job{Kind: "email.send", Payload: []byte(`{}`), MaxAttempts: 3}
+	}
+	key := "order_confirmation:cs_1"
+
+	first := newJob()
+	if queued, err := repo.Enqueue(ctx, first, &key); err != nil || !queued {
+		t.Fatalf("first enqueue = %v, %v", queued, err)
+	}
+	if queued, err := repo.Enqueue(ctx, newJob(), &key); err != nil || queued {
+		t.Errorf("pending duplicate enqueue = %v, %v", queued, err)
+	}
+
+	// Unlike a job's unique key, the dedupe key stays taken once the message is sent
+	claimed, err := jobs.Claim(ctx, []string{"email.send"}, first.RunAt, first.RunAt.Add(time.Minute), 1)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("claimed %+v, %v", claimed, err)
+	}
+	if err := jobs.Complete(ctx, first.ID, 1, first.RunAt); err != nil {
+		t.Fatal(err)
+	}
+	if queued, err := repo.Enqueue(ctx, newJob(), &key); err != nil || queued {
+		t.Errorf("sent duplicate enqueue = %v, %v", queued, err)
+	}
+
+	// Messages without a key are always queued
+	for i := 0; i < 2; i++ {
+		if queued, err := repo.Enqueue(ctx, newJob(), nil); err != nil || !queued {
+			t.Errorf("enqueue without key = %v, %v", queued, err)
+		}
+	}
+}
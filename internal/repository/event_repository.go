@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type EventRepository interface {
+	Append(ctx context.Context, events ...*models.DomainEvent) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.DomainEvent, error)
+	GetAll(ctx context.Context, eventType *models.EventType, aggregateType, aggregateID *string, page, limit int) ([]*models.DomainEvent, int, error)
+	Dispatch(ctx context.Context, limit int, deliver func(event *models.DomainEvent) error) (int, error)
+	GetSince(ctx context.Context, afterSeq int64, missing []int64, limit int) ([]*models.DomainEvent, error)
+	SeqBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type eventRepository struct {
+	db *sql.DB
+}
+
+func NewEventRepository(db *sql.DB) EventRepository {
+	return &eventRepository{db: db}
+}
+
+const domainEventColumns = "id, seq, type, aggregate_type, aggregate_id, payload, occurred_at, dispatched_at"
+
+// Append stores events that are not part of a state change in this database,
+// e.g. reported by Stripe
+func (r *eventRepository) Append(ctx context.Context, events ...*models.DomainEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *eventRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.DomainEvent, error) {
+	query := fmt.Sprintf("SELECT %s FROM domain_events WHERE id = $1", domainEventColumns)
+	event, err := scanDomainEvent(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// GetAll lists events newest first, optionally of one type or aggregate
+func (r *eventRepository) GetAll(ctx context.Context, eventType *models.EventType, aggregateType, aggregateID *string, page, limit int) ([]*models.DomainEvent, int, error) {
+	where := `WHERE ($1::text IS NULL OR type = $1)
+		AND ($2::text IS NULL OR aggregate_type = $2)
+		AND ($3::text IS NULL OR aggregate_id = $3)`
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM domain_events "+where, eventType, aggregateType, aggregateID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM domain_events
+		%s
+		ORDER BY seq DESC
+		LIMIT $4 OFFSET $5`, domainEventColumns, where)
+
+	rows, err := r.db.QueryContext(ctx, query, eventType, aggregateType, aggregateID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []*models.DomainEvent{}
+	for rows.Next() {
+		event, err := scanDomainEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+
+	return events, total, rows.Err()
+}
+
+// GetSince lists events after afterSeq, and any of the missing seqs that have
+// since been committed, in seq order. Seqs are taken when an event is inserted,
+// so a transaction that commits late can fill a gap behind one already read.
+func (r *eventRepository) GetSince(ctx context.Context, afterSeq int64, missing []int64, limit int) ([]*models.DomainEvent, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM domain_events
+		WHERE seq > $1 OR seq = ANY($2::bigint[])
+		ORDER BY seq
+		LIMIT $3`, domainEventColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, afterSeq, pq.Array(missing), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.DomainEvent
+	for rows.Next() {
+		event, err := scanDomainEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// SeqBefore returns the highest seq of events that occurred before the given
+// time, or 0 if there are none
+func (r *eventRepository) SeqBefore(ctx context.Context, before time.Time) (int64, error) {
+	var seq int64
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(seq), 0) FROM domain_events WHERE occurred_at < $1",
+		before,
+	).Scan(&seq)
+	return seq, err
+}
+
+// Dispatch hands undispatched events to deliver in order and marks those it
+// accepted as dispatched. It stops at the first event deliver fails on, which is
+// tried again on the next run. Events locked by another dispatcher are skipped.
+func (r *eventRepository) Dispatch(ctx context.Context, limit int, deliver func(event *models.DomainEvent) error) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM domain_events
+		WHERE dispatched_at IS NULL
+		ORDER BY seq
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, domainEventColumns)
+
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+	var events []*models.DomainEvent
+	for rows.Next() {
+		event, err := scanDomainEvent(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var deliverErr error
+	var delivered []uuid.UUID
+	for _, event := range events {
+		if deliverErr = deliver(event); deliverErr != nil {
+			break
+		}
+		delivered = append(delivered, event.ID)
+	}
+
+	if len(delivered) > 0 {
+		_, err := tx.ExecContext(ctx, "UPDATE domain_events SET dispatched_at = $2 WHERE id = ANY($1::uuid[])",
+			pq.Array(uuidStrings(delivered)), time.Now())
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(delivered), deliverErr
+}
+
+// insertDomainEvents writes events in the transaction of the change they describe.
+// An event whose ID is already stored is skipped, and keeps a zero Seq, so a
+// source that repeats itself can derive stable IDs to record each event once.
+func insertDomainEvents(ctx context.Context, tx *sql.Tx, events []*models.DomainEvent) error {
+	for _, event := range events {
+		if event.ID == uuid.Nil {
+			event.ID = uuid.New()
+		}
+		if event.OccurredAt.IsZero() {
+			event.OccurredAt = time.Now().UTC()
+		}
+
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO domain_events (id, type, aggregate_type, aggregate_id, payload, occurred_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (id) DO NOTHING
+			RETURNING seq`,
+			event.ID, event.Type, event.AggregateType, event.AggregateID, []byte(event.Payload), event.OccurredAt,
+		).Scan(&event.Seq)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanDomainEvent(row interface{ Scan(...interface{}) error }) (*models.DomainEvent, error) {
+	event := &models.DomainEvent{}
+	var payload []byte
+	err := row.Scan(
+		&event.ID, &event.Seq, &event.Type, &event.AggregateType, &event.AggregateID, &payload,
+		&event.OccurredAt, &event.DispatchedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	event.Payload = payload
+	return event, nil
+}
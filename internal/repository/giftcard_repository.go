@@ -0,0 +1,515 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type GiftCardRepository interface {
+	Create(ctx context.Context, card *models.GiftCard) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.GiftCard, error)
+	GetByCode(ctx context.Context, code string) (*models.GiftCard, error)
+	GetAll(ctx context.Context, page, limit int) ([]*models.GiftCard, int, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status models.GiftCardStatus) error
+	AdjustGiftCard(ctx context.Context, id uuid.UUID, amount float64, note *string, createdBy *uuid.UUID) (bool, error)
+	GetGiftCardTransactions(ctx context.Context, id uuid.UUID) ([]models.BalanceTransaction, error)
+	GetStoreCredit(ctx context.Context, userID uuid.UUID) (*models.StoreCreditAccount, error)
+	AdjustStoreCredit(ctx context.Context, userID uuid.UUID, txType models.BalanceTransactionType, amount float64, reference, note *string, createdBy *uuid.UUID) (bool, error)
+	GetStoreCreditTransactions(ctx context.Context, userID uuid.UUID, page, limit int) ([]models.BalanceTransaction, int, error)
+	Redeem(ctx context.Context, reference string, giftCardIDs []uuid.UUID, creditUserID *uuid.UUID, amount float64, now time.Time, checkout *TenderCheckout) (*models.TenderRedemption, error)
+	Release(ctx context.Context, reference string) (float64, error)
+}
+
+// TenderCheckout is a whole checkout that gift cards and store credit may pay for.
+// Its promotions and events are recorded with the redemption that pays it.
+type TenderCheckout struct {
+	Total      float64 // The items less the discount, plus shipping
+	CustomerID *uuid.UUID
+	Promotions []models.AppliedPromotion
+	Events     []*models.DomainEvent
+}
+
+type giftCardRepository struct {
+	db *sql.DB
+}
+
+func NewGiftCardRepository(db *sql.DB) GiftCardRepository {
+	return &giftCardRepository{db: db}
+}
+
+const giftCardColumns = `id, code, initial_balance, balance, status, expires_at, recipient_email,
+	message, issued_by, created_at, updated_at`
+
+const balanceTransactionColumns = `id, gift_card_id, user_id, type, amount, balance_after, reference,
+	note, created_by, created_at`
+
+// Create stores a new gift card with its full balance and records the issue in its ledger
+func (r *giftCardRepository) Create(ctx context.Context, card *models.GiftCard) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if card.ID == uuid.Nil {
+		card.ID = uuid.New()
+	}
+	card.Balance = card.InitialBalance
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO gift_cards (id, code, initial_balance, balance, status, expires_at, recipient_email, message, issued_by)
+		VALUES ($1, $2, $3, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at`,
+		card.ID, card.Code, card.InitialBalance, card.Status, card.ExpiresAt, card.RecipientEmail,
+		card.Message, card.IssuedBy,
+	).Scan(&card.CreatedAt, &card.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = insertBalanceTransaction(ctx, tx, &card.ID, nil, models.BalanceIssue, card.InitialBalance, card.Balance, nil, nil, card.IssuedBy)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *giftCardRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.GiftCard, error) {
+	query := fmt.Sprintf("SELECT %s FROM gift_cards WHERE id = $1", giftCardColumns)
+	return r.getOne(ctx, query, id)
+}
+
+// GetByCode looks up a gift card, ignoring case and dashes
+func (r *giftCardRepository) GetByCode(ctx context.Context, code string) (*models.GiftCard, error) {
+	query := fmt.Sprintf("SELECT %s FROM gift_cards WHERE REPLACE(UPPER(code), '-', '') = REPLACE(UPPER($1), '-', '')", giftCardColumns)
+	return r.getOne(ctx, query, code)
+}
+
+func (r *giftCardRepository) GetAll(ctx context.Context, page, limit int) ([]*models.GiftCard, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM gift_cards").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM gift_cards
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2`, giftCardColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	cards := []*models.GiftCard{}
+	for rows.Next() {
+		card, err := scanGiftCard(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, total, rows.Err()
+}
+
+func (r *giftCardRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status models.GiftCardStatus) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE gift_cards SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1", id, status)
+	return err
+}
+
+// AdjustGiftCard adds a signed amount to a gift card's balance. It returns false
+// without changing anything if the balance would become negative.
+func (r *giftCardRepository) AdjustGiftCard(ctx context.Context, id uuid.UUID, amount float64, note *string, createdBy *uuid.UUID) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var balance float64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE gift_cards SET balance = balance + $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND balance + $2 >= 0
+		RETURNING balance`, id, amount,
+	).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := insertBalanceTransaction(ctx, tx, &id, nil, models.BalanceAdjust, amount, balance, nil, note, createdBy); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *giftCardRepository) GetGiftCardTransactions(ctx context.Context, id uuid.UUID) ([]models.BalanceTransaction, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM balance_transactions
+		WHERE gift_card_id = $1
+		ORDER BY created_at DESC`, balanceTransactionColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBalanceTransactions(rows)
+}
+
+// GetStoreCredit returns a user's store credit account, with a zero balance if they have never had credit
+func (r *giftCardRepository) GetStoreCredit(ctx context.Context, userID uuid.UUID) (*models.StoreCreditAccount, error) {
+	account := &models.StoreCreditAccount{UserID: userID}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT balance, updated_at FROM store_credit_accounts WHERE user_id = $1", userID,
+	).Scan(&account.Balance, &account.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return account, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// AdjustStoreCredit adds a signed amount to a user's store credit, opening the
+// account if needed. It returns false without changing anything if the balance
+// would become negative or the user does not exist.
+func (r *giftCardRepository) AdjustStoreCredit(ctx context.Context, userID uuid.UUID, txType models.BalanceTransactionType, amount float64, reference, note *string, createdBy *uuid.UUID) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO store_credit_accounts (user_id)
+		SELECT id FROM users WHERE id = $1
+		ON CONFLICT (user_id) DO NOTHING`, userID)
+	if err != nil {
+		return false, err
+	}
+
+	var balance float64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE store_credit_accounts SET balance = balance + $2, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND balance + $2 >= 0
+		RETURNING balance`, userID, amount,
+	).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := insertBalanceTransaction(ctx, tx, nil, &userID, txType, amount, balance, reference, note, createdBy); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *giftCardRepository) GetStoreCreditTransactions(ctx context.Context, userID uuid.UUID, page, limit int) ([]models.BalanceTransaction, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM balance_transactions WHERE user_id = $1", userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM balance_transactions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`, balanceTransactionColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	transactions, err := scanBalanceTransactions(rows)
+	return transactions, total, err
+}
+
+// Redeem takes up to amount from the gift cards, in the given order, and then
+// from the user's store credit. Every balance is locked before it is read, so
+// concurrent checkouts cannot spend the same money twice; a card that has been
+// emptied, disabled or has expired since it was checked contributes nothing.
+//
+// If checkout is given and the balances cover its total, the total is taken
+// instead and the checkout's promotions and events are recorded in the same
+// transaction. When a promotion's usage limit has been reached since the cart
+// was quoted, nothing is redeemed and Redeem returns nil.
+func (r *giftCardRepository) Redeem(ctx context.Context, reference string, giftCardIDs []uuid.UUID, creditUserID *uuid.UUID, amount float64, now time.Time, checkout *TenderCheckout) (*models.TenderRedemption, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock in ID order so checkouts sharing cards cannot deadlock
+	cards := make(map[uuid.UUID]*models.GiftCard)
+	if len(giftCardIDs) > 0 {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+			SELECT %s FROM gift_cards
+			WHERE id = ANY($1::uuid[])
+			ORDER BY id
+			FOR UPDATE`, giftCardColumns), pq.Array(uuidStrings(giftCardIDs)))
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			card, err := scanGiftCard(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			cards[card.ID] = card
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var available float64
+	var spendable []*models.GiftCard
+	for _, id := range giftCardIDs {
+		card, ok := cards[id]
+		if !ok || card.Status != models.GiftCardStatusActive || card.Balance <= 0 {
+			continue
+		}
+		if card.ExpiresAt != nil && !card.ExpiresAt.After(now) {
+			continue
+		}
+		spendable = append(spendable, card)
+		available += card.Balance
+		delete(cards, id) // A card listed twice is only spent once
+	}
+
+	var credit float64
+	if creditUserID != nil {
+		err := tx.QueryRowContext(ctx,
+			"SELECT balance FROM store_credit_accounts WHERE user_id = $1 FOR UPDATE", *creditUserID,
+		).Scan(&credit)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		available += credit
+	}
+
+	paysCheckout := checkout != nil && available >= checkout.Total-0.005
+	if paysCheckout {
+		amount = checkout.Total
+	}
+
+	redemption := &models.TenderRedemption{Reference: reference, Applied: []models.AppliedTender{}}
+	remaining := amount
+
+	for _, card := range spendable {
+		if remaining <= 0 {
+			break
+		}
+
+		debit := minAmount(card.Balance, remaining)
+		var balance float64
+		err := tx.QueryRowContext(ctx, `
+			UPDATE gift_cards SET balance = balance - $2, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1
+			RETURNING balance`, card.ID, debit,
+		).Scan(&balance)
+		if err != nil {
+			return nil, err
+		}
+		if err := insertBalanceTransaction(ctx, tx, &card.ID, nil, models.BalanceRedeem, -debit, balance, &reference, nil, nil); err != nil {
+			return nil, err
+		}
+
+		cardID := card.ID
+		redemption.Applied = append(redemption.Applied, models.AppliedTender{
+			Type:       models.TenderGiftCard,
+			GiftCardID: &cardID,
+			Code:       card.Code,
+			Amount:     debit,
+		})
+		redemption.Total = roundCents(redemption.Total + debit)
+		remaining = roundCents(remaining - debit)
+	}
+
+	if credit > 0 && remaining > 0 {
+		debit := minAmount(credit, remaining)
+		var balance float64
+		err := tx.QueryRowContext(ctx, `
+			UPDATE store_credit_accounts SET balance = balance - $2, updated_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1
+			RETURNING balance`, *creditUserID, debit,
+		).Scan(&balance)
+		if err != nil {
+			return nil, err
+		}
+		if err := insertBalanceTransaction(ctx, tx, nil, creditUserID, models.BalanceRedeem, -debit, balance, &reference, nil, nil); err != nil {
+			return nil, err
+		}
+
+		redemption.Applied = append(redemption.Applied, models.AppliedTender{
+			Type:   models.TenderStoreCredit,
+			Amount: debit,
+		})
+		redemption.Total = roundCents(redemption.Total + debit)
+	}
+
+	if paysCheckout {
+		redeemed, err := redeemPromotions(ctx, tx, reference, checkout.CustomerID, checkout.Promotions)
+		if err != nil || !redeemed {
+			return nil, err
+		}
+		if err := insertDomainEvents(ctx, tx, checkout.Events); err != nil {
+			return nil, err
+		}
+	}
+
+	return redemption, tx.Commit()
+}
+
+// Release returns everything redeemed under reference to the balances it came
+// from and returns the amount released. Releasing a reference again has no effect.
+func (r *giftCardRepository) Release(ctx context.Context, reference string) (float64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Locking the redemptions makes a concurrent release of the same reference wait and then see ours
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM balance_transactions
+		WHERE reference = $1 AND type = 'redeem'
+		ORDER BY id
+		FOR UPDATE`, balanceTransactionColumns), reference)
+	if err != nil {
+		return 0, err
+	}
+	redemptions, err := scanBalanceTransactions(rows)
+	rows.Close()
+	if err != nil || len(redemptions) == 0 {
+		return 0, err
+	}
+
+	var released bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM balance_transactions WHERE reference = $1 AND type = 'release')", reference,
+	).Scan(&released)
+	if err != nil || released {
+		return 0, err
+	}
+
+	var total float64
+	for _, redemption := range redemptions {
+		credit := -redemption.Amount
+		var balance float64
+		if redemption.GiftCardID != nil {
+			err = tx.QueryRowContext(ctx, `
+				UPDATE gift_cards SET balance = balance + $2, updated_at = CURRENT_TIMESTAMP
+				WHERE id = $1
+				RETURNING balance`, *redemption.GiftCardID, credit,
+			).Scan(&balance)
+		} else {
+			err = tx.QueryRowContext(ctx, `
+				UPDATE store_credit_accounts SET balance = balance + $2, updated_at = CURRENT_TIMESTAMP
+				WHERE user_id = $1
+				RETURNING balance`, *redemption.UserID, credit,
+			).Scan(&balance)
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		err = insertBalanceTransaction(ctx, tx, redemption.GiftCardID, redemption.UserID, models.BalanceRelease, credit, balance, &reference, nil, nil)
+		if err != nil {
+			return 0, err
+		}
+		total = roundCents(total + credit)
+	}
+
+	return total, tx.Commit()
+}
+
+func (r *giftCardRepository) getOne(ctx context.Context, query string, arg interface{}) (*models.GiftCard, error) {
+	card, err := scanGiftCard(r.db.QueryRowContext(ctx, query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return card, nil
+}
+
+func scanGiftCard(row interface{ Scan(...interface{}) error }) (*models.GiftCard, error) {
+	card := &models.GiftCard{}
+	err := row.Scan(
+		&card.ID, &card.Code, &card.InitialBalance, &card.Balance, &card.Status, &card.ExpiresAt,
+		&card.RecipientEmail, &card.Message, &card.IssuedBy, &card.CreatedAt, &card.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+func scanBalanceTransactions(rows *sql.Rows) ([]models.BalanceTransaction, error) {
+	transactions := []models.BalanceTransaction{}
+	for rows.Next() {
+		var t models.BalanceTransaction
+		err := rows.Scan(
+			&t.ID, &t.GiftCardID, &t.UserID, &t.Type, &t.Amount, &t.BalanceAfter, &t.Reference,
+			&t.Note, &t.CreatedBy, &t.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
+
+func insertBalanceTransaction(ctx context.Context, tx *sql.Tx, giftCardID, userID *uuid.UUID, txType models.BalanceTransactionType, amount, balanceAfter float64, reference, note *string, createdBy *uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO balance_transactions (id, gift_card_id, user_id, type, amount, balance_after, reference, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		uuid.New(), giftCardID, userID, txType, amount, balanceAfter, reference, note, createdBy,
+	)
+	return err
+}
+
+// roundCents keeps running totals of DECIMAL(10,2) amounts free of float drift
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func minAmount(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type JobRepository interface {
+	Enqueue(ctx context.Context, job *models.Job) (bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	GetAll(ctx context.Context, status *models.JobStatus, kind *string, page, limit int) ([]*models.Job, int, error)
+	GetStats(ctx context.Context) (*models.JobStats, error)
+	Claim(ctx context.Context, kinds []string, now, lockedUntil time.Time, limit int) ([]*models.Job, error)
+	Complete(ctx context.Context, id uuid.UUID, attempt int, finishedAt time.Time) error
+	Fail(ctx context.Context, id uuid.UUID, attempt int, lastError string, retryAt *time.Time) error
+	Retry(ctx context.Context, id uuid.UUID) (bool, error)
+	DeleteSucceeded(ctx context.Context, before time.Time) (int64, error)
+}
+
+type jobRepository struct {
+	db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) JobRepository {
+	return &jobRepository{db: db}
+}
+
+const jobColumns = `id, kind, payload, unique_key, status, attempts, max_attempts, run_at, locked_until,
+	last_error, finished_at, created_at, updated_at`
+
+// Enqueue stores a pending job. It returns false without storing anything if a
+// pending or running job with the same unique key exists.
+func (r *jobRepository) Enqueue(ctx context.Context, job *models.Job) (bool, error) {
+	return insertJob(ctx, r.db, job)
+}
+
+// insertJob stores a pending job using either the database or a transaction, so
+// a job can be queued atomically with the change that calls for it
+func insertJob(ctx context.Context, exec interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}, job *models.Job) (bool, error) {
+	if job.ID == uuid.Nil {
+		job.ID = uuid.New()
+	}
+	job.Status = models.JobStatusPending
+
+	err := exec.QueryRowContext(ctx, `
+		INSERT INTO jobs (id, kind, payload, unique_key, max_attempts, run_at)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6, CURRENT_TIMESTAMP))
+		ON CONFLICT (unique_key) WHERE unique_key IS NOT NULL AND status IN ('pending', 'running') DO NOTHING
+		RETURNING run_at, created_at, updated_at`,
+		job.ID, job.Kind, []byte(job.Payload), job.UniqueKey, job.MaxAttempts, nullTime(job.RunAt),
+	).Scan(&job.RunAt, &job.CreatedAt, &job.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *jobRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	query := fmt.Sprintf("SELECT %s FROM jobs WHERE id = $1", jobColumns)
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetAll lists jobs newest first, optionally only those with a status or kind
+func (r *jobRepository) GetAll(ctx context.Context, status *models.JobStatus, kind *string, page, limit int) ([]*models.Job, int, error) {
+	where := "WHERE ($1::text IS NULL OR status = $1) AND ($2::text IS NULL OR kind = $2)"
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM jobs "+where, status, kind).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM jobs
+		%s
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4`, jobColumns, where)
+
+	rows, err := r.db.QueryContext(ctx, query, status, kind, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	jobs := []*models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, total, rows.Err()
+}
+
+func (r *jobRepository) GetStats(ctx context.Context) (*models.JobStats, error) {
+	stats := &models.JobStats{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'pending'),
+			COUNT(*) FILTER (WHERE status = 'running'),
+			COUNT(*) FILTER (WHERE status = 'succeeded'),
+			COUNT(*) FILTER (WHERE status = 'dead')
+		FROM jobs`,
+	).Scan(&stats.Pending, &stats.Running, &stats.Succeeded, &stats.Dead)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Claim locks due jobs of the given kinds until lockedUntil and marks them running.
+// Running jobs whose lock expired, because their worker died or timed out, are
+// claimed again. Jobs locked by another claim in progress are skipped.
+func (r *jobRepository) Claim(ctx context.Context, kinds []string, now, lockedUntil time.Time, limit int) ([]*models.Job, error) {
+	query := fmt.Sprintf(`
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, locked_until = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE kind = ANY($3)
+				AND ((status = 'pending' AND run_at <= $1) OR (status = 'running' AND locked_until <= $1))
+			ORDER BY run_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %s`, jobColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, now, lockedUntil, pq.Array(kinds), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*models.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// Complete marks a job succeeded. attempt guards against finishing a job that
+// another worker took over after this one's lock expired.
+func (r *jobRepository) Complete(ctx context.Context, id uuid.UUID, attempt int, finishedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = 'succeeded', locked_until = NULL, finished_at = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND attempts = $2 AND status = 'running'`, id, attempt, finishedAt)
+	return err
+}
+
+// Fail records a failed attempt. The job runs again at retryAt, or is moved to
+// the dead letters when it is nil.
+func (r *jobRepository) Fail(ctx context.Context, id uuid.UUID, attempt int, lastError string, retryAt *time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = CASE WHEN $4::timestamp IS NULL THEN 'dead' ELSE 'pending' END,
+			run_at = COALESCE($4, run_at), last_error = $3, locked_until = NULL,
+			finished_at = CASE WHEN $4::timestamp IS NULL THEN CURRENT_TIMESTAMP END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND attempts = $2 AND status = 'running'`, id, attempt, lastError, retryAt)
+	return err
+}
+
+// Retry moves a dead job back to the queue with fresh attempts. It returns false
+// if the job is not dead or another job with its unique key is queued.
+func (r *jobRepository) Retry(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE jobs j
+		SET status = 'pending', attempts = 0, run_at = CURRENT_TIMESTAMP, finished_at = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE j.id = $1 AND j.status = 'dead'
+			AND (j.unique_key IS NULL OR NOT EXISTS (
+				SELECT 1 FROM jobs o
+				WHERE o.unique_key = j.unique_key AND o.status IN ('pending', 'running')
+			))`, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// DeleteSucceeded removes succeeded jobs finished before the given time
+func (r *jobRepository) DeleteSucceeded(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM jobs WHERE status = 'succeeded' AND finished_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanJob(row interface{ Scan(...interface{}) error }) (*models.Job, error) {
+	job := &models.Job{}
+	var payload []byte
+	err := row.Scan(
+		&job.ID, &job.Kind, &payload, &job.UniqueKey, &job.Status, &job.Attempts, &job.MaxAttempts,
+		&job.RunAt, &job.LockedUntil, &job.LastError, &job.FinishedAt, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = payload
+	return job, nil
+}
+
+// nullTime passes a zero time as NULL
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+)
+
+func enqueueTestJob(t *testing.T, repo JobRepository, kind string, runAt time.Time, uniqueKey string) *models.Job {
+	t.Helper()
+	job := &models.Job{Kind: kind, Payload: []byte(`{}`), RunAt: runAt, MaxAttempts: 3}
+	if uniqueKey != "" {
+		job.UniqueKey = &uniqueKey
+	}
+	enqueued, err := repo.Enqueue(context.Background(), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !enqueued {
+		return nil
+	}
+	return job
+}
+
+func TestJobEnqueueSkipsQueuedUniqueKey(t *testing.T) {
+	ctx := context.Background()
+	repo := NewJobRepository(testDB(t, "jobs"))
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	first := enqueueTestJob(t, repo, "test", now, "key")
+	if first == nil {
+		t.Fatal("first job not enqueued")
+	}
+	if enqueueTestJob(t, repo, "test", now, "key") != nil {
+		t.Error("pending duplicate enqueued")
+	}
+
+	// The key stays taken while the job runs, and is free once it finished
+	if _, err := repo.Claim(ctx, []string{"test"}, now, now.Add(time.Minute), 10); err != nil {
+		t.Fatal(err)
+	}
+	if enqueueTestJob(t, repo, "test", now, "key") != nil {
+		t.Error("running duplicate enqueued")
+	}
+	if err := repo.Complete(ctx, first.ID, 1, now); err != nil {
+		t.Fatal(err)
+	}
+	if enqueueTestJob(t, repo, "test", now, "key") == nil {
+		t.Error("key still taken after the job succeeded")
+	}
+}
+
+func TestJobClaimTakesDueJobsOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := NewJobRepository(testDB(t, "jobs"))
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	due := enqueueTestJob(t, repo, "test", now.Add(-time.Minute), "")
+	enqueueTestJob(t, repo, "test", now.Add(time.Minute), "")
+	enqueueTestJob(t, repo, "other", now.Add(-time.Minute), "")
+
+	jobs, err := repo.Claim(ctx, []string{"test"}, now, now.Add(time.Minute), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != due.ID || jobs[0].Status != models.JobStatusRunning || jobs[0].Attempts != 1 {
+		t.Fatalf("claimed %+v", jobs)
+	}
+	if again, err := repo.Claim(ctx, []string{"test"}, now, now.Add(time.Minute), 10); err != nil || len(again) != 0 {
+		t.Errorf("claimed a locked job again: %+v, %v", again, err)
+	}
+
+	// Once the lock expires the job is taken over, and the first worker can no
+	// longer record its outcome
+	later := now.Add(2 * time.Minute)
+	jobs, err = repo.Claim(ctx, []string{"test"}, later, later.Add(time.Minute), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != due.ID || jobs[0].Attempts != 2 {
+		t.Fatalf("claimed after lock expiry %+v", jobs)
+	}
+	if err := repo.Complete(ctx, due.ID, 1, later); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := repo.GetByID(ctx, due.ID); job.Status != models.JobStatusRunning {
+		t.Errorf("stale attempt completed the job: %s", job.Status)
+	}
+	if err := repo.Complete(ctx, due.ID, 2, later); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := repo.GetByID(ctx, due.ID); job.Status != models.JobStatusSucceeded || job.LockedUntil != nil {
+		t.Errorf("completed job %+v", job)
+	}
+}
+
+func TestJobClaimsDoNotOverlap(t *testing.T) {
+	ctx := context.Background()
+	repo := NewJobRepository(testDB(t, "jobs"))
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		enqueueTestJob(t, repo, "test", now.Add(-time.Minute), "")
+	}
+
+	var mu sync.Mutex
+	claimed := make(map[string]int)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				jobs, err := repo.Claim(ctx, []string{"test"}, now, now.Add(time.Minute), 3)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if len(jobs) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, job := range jobs {
+					claimed[job.ID.String()]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimed) != 20 {
+		t.Errorf("claimed %d of 20 jobs", len(claimed))
+	}
+	for id, n := range claimed {
+		if n != 1 {
+			t.Errorf("job %s claimed %d times", id, n)
+		}
+	}
+}
+
+func TestJobFailRetriesThenDies(t *testing.T) {
+	ctx := context.Background()
+	repo := NewJobRepository(testDB(t, "jobs"))
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	job := enqueueTestJob(t, repo, "test", now, "key")
+
+	if _, err := repo.Claim(ctx, []string{"test"}, now, now.Add(time.Minute), 1); err != nil {
+		t.Fatal(err)
+	}
+	retryAt := now.Add(10 * time.Second)
+	if err := repo.Fail(ctx, job.ID, 1, "carrier unavailable", &retryAt); err != nil {
+		t.Fatal(err)
+	}
+
+	// Not claimed before its backoff has passed
+	if jobs, _ := repo.Claim(ctx, []string{"test"}, now.Add(5*time.Second), now.Add(time.Minute), 1); len(jobs) != 0 {
+		t.Errorf("claimed during backoff: %+v", jobs)
+	}
+	jobs, err := repo.Claim(ctx, []string{"test"}, retryAt, retryAt.Add(time.Minute), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Attempts != 2 || jobs[0].LastError == nil || *jobs[0].LastError != "carrier unavailable" {
+		t.Fatalf("claimed after backoff %+v", jobs)
+	}
+
+	if err := repo.Fail(ctx, job.ID, 2, "bad payload", nil); err != nil {
+		t.Fatal(err)
+	}
+	dead, _ := repo.GetByID(ctx, job.ID)
+	if dead.Status != models.JobStatusDead || dead.FinishedAt == nil {
+		t.Errorf("failed job %+v", dead)
+	}
+
+	// A dead job can be retried unless its key was queued again meanwhile
+	duplicate := enqueueTestJob(t, repo, "test", now, "key")
+	if retried, err := repo.Retry(ctx, job.ID); err != nil || retried {
+		t.Errorf("retried with a queued duplicate: %v, %v", retried, err)
+	}
+	if _, err := repo.Claim(ctx, []string{"test"}, now, now.Add(time.Minute), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Complete(ctx, duplicate.ID, 1, now); err != nil {
+		t.Fatal(err)
+	}
+	if retried, err := repo.Retry(ctx, job.ID); err != nil || !retried {
+		t.Errorf("Retry = %v, %v", retried, err)
+	}
+	if retried, _ := repo.GetByID(ctx, job.ID); retried.Status != models.JobStatusPending || retried.Attempts != 0 {
+		t.Errorf("retried job %+v", retried)
+	}
+}
@@ -0,0 +1,606 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type LedgerRepository interface {
+	GetCommissionRates(ctx context.Context) ([]models.CommissionRate, error)
+	SaveCommissionRate(ctx context.Context, rate *models.CommissionRate) error
+	DeleteCommissionRate(ctx context.Context, id uuid.UUID) error
+	GetOrderItems(ctx context.Context, orderID uuid.UUID) ([]LedgerOrderItem, error)
+	GetOrderItem(ctx context.Context, id uuid.UUID) (*LedgerOrderItem, error)
+	GetItemEntries(ctx context.Context, orderItemID uuid.UUID) ([]models.LedgerEntry, error)
+	AddEntries(ctx context.Context, entries []*models.LedgerEntry) (int, error)
+	AddRefund(ctx context.Context, orderItemID uuid.UUID, entries []*models.LedgerEntry) (bool, error)
+	GetBalance(ctx context.Context, vendorID uuid.UUID) (*models.VendorBalance, error)
+	GetBalanceAt(ctx context.Context, vendorID uuid.UUID, at time.Time) (float64, error)
+	GetEntries(ctx context.Context, vendorID uuid.UUID, from, to time.Time) ([]models.LedgerEntry, error)
+	CreatePayoutBatch(ctx context.Context, periodEnd time.Time) (*models.PayoutBatch, error)
+	GetPayoutBatches(ctx context.Context, page, limit int) ([]*models.PayoutBatch, int, error)
+	GetPayoutBatch(ctx context.Context, id uuid.UUID) (*models.PayoutBatch, error)
+	ClaimPayoutBatch(ctx context.Context, id uuid.UUID, now, lockedUntil time.Time) (bool, error)
+	MarkPayoutPaid(ctx context.Context, id uuid.UUID, transferID string) error
+	MarkPayoutFailed(ctx context.Context, id uuid.UUID, reason string) error
+	FinishPayoutBatch(ctx context.Context, id uuid.UUID) error
+}
+
+// LedgerOrderItem is an order item with the category its product belongs to,
+// which is needed to pick the commission rate
+type LedgerOrderItem struct {
+	models.OrderItem
+	CategoryID *uuid.UUID
+}
+
+type ledgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) LedgerRepository {
+	return &ledgerRepository{db: db}
+}
+
+const ledgerEntryColumns = `id, vendor_id, order_id, order_item_id, payout_id, type, amount, currency,
+	commission_rate, description, created_at`
+
+const orderItemColumns = `oi.id, oi.order_id, oi.product_id, oi.vendor_id, oi.name, oi.price, oi.quantity,
+	oi.total, oi.created_at, p.category_id`
+
+func (r *ledgerRepository) GetCommissionRates(ctx context.Context) ([]models.CommissionRate, error) {
+	query := `
+		SELECT id, scope, category_id, vendor_id, rate, created_at, updated_at
+		FROM commission_rates
+		ORDER BY CASE scope WHEN 'global' THEN 0 WHEN 'category' THEN 1 ELSE 2 END, created_at`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []models.CommissionRate{}
+	for rows.Next() {
+		var rate models.CommissionRate
+		err := rows.Scan(
+			&rate.ID, &rate.Scope, &rate.CategoryID, &rate.VendorID,
+			&rate.Rate, &rate.CreatedAt, &rate.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// SaveCommissionRate creates the rate for its scope and target, or replaces the existing one
+func (r *ledgerRepository) SaveCommissionRate(ctx context.Context, rate *models.CommissionRate) error {
+	var conflict string
+	switch rate.Scope {
+	case models.CommissionScopeGlobal:
+		conflict = "(scope) WHERE scope = 'global'"
+	case models.CommissionScopeCategory:
+		conflict = "(category_id) WHERE scope = 'category'"
+	case models.CommissionScopeVendor:
+		conflict = "(vendor_id) WHERE scope = 'vendor'"
+	default:
+		return fmt.Errorf("unknown commission scope %q", rate.Scope)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO commission_rates (id, scope, category_id, vendor_id, rate)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT %s DO UPDATE SET rate = EXCLUDED.rate, updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at`, conflict)
+
+	return r.db.QueryRowContext(ctx, query,
+		uuid.New(), rate.Scope, rate.CategoryID, rate.VendorID, rate.Rate,
+	).Scan(&rate.ID, &rate.CreatedAt, &rate.UpdatedAt)
+}
+
+func (r *ledgerRepository) DeleteCommissionRate(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM commission_rates WHERE id = $1 AND scope <> 'global'", id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *ledgerRepository) GetOrderItems(ctx context.Context, orderID uuid.UUID) ([]LedgerOrderItem, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM order_items oi
+		LEFT JOIN products p ON p.id = oi.product_id
+		WHERE oi.order_id = $1
+		ORDER BY oi.created_at`, orderItemColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []LedgerOrderItem{}
+	for rows.Next() {
+		item, err := scanLedgerOrderItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+
+	return items, rows.Err()
+}
+
+func (r *ledgerRepository) GetOrderItem(ctx context.Context, id uuid.UUID) (*LedgerOrderItem, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM order_items oi
+		LEFT JOIN products p ON p.id = oi.product_id
+		WHERE oi.id = $1`, orderItemColumns)
+
+	item, err := scanLedgerOrderItem(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (r *ledgerRepository) GetItemEntries(ctx context.Context, orderItemID uuid.UUID) ([]models.LedgerEntry, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM vendor_ledger_entries
+		WHERE order_item_id = $1
+		ORDER BY created_at`, ledgerEntryColumns)
+
+	return r.queryEntries(ctx, query, orderItemID)
+}
+
+// AddEntries inserts ledger entries in one transaction. Sale and commission
+// entries already recorded for an order item are skipped, so recording an
+// order twice is harmless. It returns the number of entries inserted.
+func (r *ledgerRepository) AddEntries(ctx context.Context, entries []*models.LedgerEntry) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	inserted, err := insertLedgerEntries(ctx, tx, entries)
+	if err != nil {
+		return 0, err
+	}
+	return inserted, tx.Commit()
+}
+
+// AddRefund records the entries of a refund of an order item in one transaction.
+// The item's sale entry is locked first, so concurrent refunds of the item are
+// checked one after the other; it returns false without recording anything if
+// the refunds would add up to more than the sale.
+func (r *ledgerRepository) AddRefund(ctx context.Context, orderItemID uuid.UUID, entries []*models.LedgerEntry) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var sale float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT amount FROM vendor_ledger_entries
+		WHERE order_item_id = $1 AND type = 'sale'
+		FOR UPDATE`, orderItemID,
+	).Scan(&sale)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var refunded float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(-SUM(amount), 0) FROM vendor_ledger_entries
+		WHERE order_item_id = $1 AND type = 'refund'`, orderItemID,
+	).Scan(&refunded)
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		if entry.Type == models.LedgerEntryRefund {
+			refunded -= entry.Amount
+		}
+	}
+	if math.Round(refunded*100) > math.Round(sale*100) {
+		return false, nil
+	}
+
+	if _, err := insertLedgerEntries(ctx, tx, entries); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// insertLedgerEntries inserts entries within tx, skipping sale and commission
+// entries already recorded for their order item
+func insertLedgerEntries(ctx context.Context, tx *sql.Tx, entries []*models.LedgerEntry) (int, error) {
+	query := `
+		INSERT INTO vendor_ledger_entries (id, vendor_id, order_id, order_item_id, type, amount, currency,
+			commission_rate, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (order_item_id, type) WHERE type IN ('sale', 'commission') DO NOTHING
+		RETURNING created_at`
+
+	inserted := 0
+	for _, entry := range entries {
+		if entry.ID == uuid.Nil {
+			entry.ID = uuid.New()
+		}
+
+		err := tx.QueryRowContext(ctx, query,
+			entry.ID, entry.VendorID, entry.OrderID, entry.OrderItemID, entry.Type, entry.Amount,
+			entry.Currency, entry.CommissionRate, entry.Description,
+		).Scan(&entry.CreatedAt)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		inserted++
+	}
+	return inserted, nil
+}
+
+// GetBalance sums the vendor's ledger. Entries of paid payouts cancel out
+// against their payout entry, so the balance is what is still owed.
+func (r *ledgerRepository) GetBalance(ctx context.Context, vendorID uuid.UUID) (*models.VendorBalance, error) {
+	query := `
+		SELECT
+			COALESCE(SUM(amount), 0),
+			COALESCE(SUM(amount) FILTER (WHERE payout_id IS NULL), 0),
+			COALESCE(-SUM(amount) FILTER (WHERE type = 'payout'), 0),
+			(SELECT COALESCE(SUM(amount), 0) FROM vendor_payouts WHERE vendor_id = $1 AND status = 'pending')
+		FROM vendor_ledger_entries
+		WHERE vendor_id = $1`
+
+	balance := &models.VendorBalance{VendorID: vendorID}
+	err := r.db.QueryRowContext(ctx, query, vendorID).Scan(
+		&balance.Balance, &balance.Available, &balance.PaidOut, &balance.InPayout,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return balance, nil
+}
+
+// GetBalanceAt returns the vendor's balance from entries created before the given time
+func (r *ledgerRepository) GetBalanceAt(ctx context.Context, vendorID uuid.UUID, at time.Time) (float64, error) {
+	var balance float64
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(amount), 0) FROM vendor_ledger_entries WHERE vendor_id = $1 AND created_at < $2",
+		vendorID, at,
+	).Scan(&balance)
+
+	return balance, err
+}
+
+// GetEntries returns the vendor's entries created in [from, to), oldest first
+func (r *ledgerRepository) GetEntries(ctx context.Context, vendorID uuid.UUID, from, to time.Time) ([]models.LedgerEntry, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM vendor_ledger_entries
+		WHERE vendor_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at, id`, ledgerEntryColumns)
+
+	return r.queryEntries(ctx, query, vendorID, from, to)
+}
+
+// CreatePayoutBatch creates one pending payout per approved vendor whose unpaid
+// entries before periodEnd add up to a positive amount, and attaches those
+// entries to the payout. It returns nil if no vendor has anything to be paid.
+func (r *ledgerRepository) CreatePayoutBatch(ctx context.Context, periodEnd time.Time) (*models.PayoutBatch, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Serialize batch creation so no entry can end up in two payouts
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('payout_batches'))"); err != nil {
+		return nil, err
+	}
+
+	batch := &models.PayoutBatch{ID: uuid.New(), PeriodEnd: periodEnd}
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO payout_batches (id, period_end) VALUES ($1, $2) RETURNING status, created_at",
+		batch.ID, periodEnd,
+	).Scan(&batch.Status, &batch.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO vendor_payouts (id, batch_id, vendor_id, amount, currency, period_start, period_end)
+		SELECT gen_random_uuid(), $1, e.vendor_id, SUM(e.amount), e.currency, MIN(e.created_at), $2
+		FROM vendor_ledger_entries e
+		JOIN vendors v ON v.id = e.vendor_id AND v.status = 'approved'
+		WHERE e.payout_id IS NULL AND e.created_at < $2
+		GROUP BY e.vendor_id, e.currency
+		HAVING SUM(e.amount) > 0`,
+		batch.ID, periodEnd,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE vendor_ledger_entries e SET payout_id = p.id
+		FROM vendor_payouts p
+		WHERE p.batch_id = $1 AND e.vendor_id = p.vendor_id AND e.currency = p.currency
+			AND e.payout_id IS NULL AND e.created_at < $2`,
+		batch.ID, periodEnd,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE payout_batches SET
+			total = (SELECT COALESCE(SUM(amount), 0) FROM vendor_payouts WHERE batch_id = $1),
+			payout_count = (SELECT COUNT(*) FROM vendor_payouts WHERE batch_id = $1)
+		WHERE id = $1
+		RETURNING total, payout_count`,
+		batch.ID,
+	).Scan(&batch.Total, &batch.PayoutCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if batch.PayoutCount == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.GetPayoutBatch(ctx, batch.ID)
+}
+
+func (r *ledgerRepository) GetPayoutBatches(ctx context.Context, page, limit int) ([]*models.PayoutBatch, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM payout_batches").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, period_end, status, total, payout_count, created_at, completed_at
+		FROM payout_batches
+		ORDER BY created_at DESC
+		LIMIT %d OFFSET %d`, limit, (page-1)*limit)
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	batches := []*models.PayoutBatch{}
+	for rows.Next() {
+		batch := &models.PayoutBatch{}
+		err := rows.Scan(
+			&batch.ID, &batch.PeriodEnd, &batch.Status, &batch.Total,
+			&batch.PayoutCount, &batch.CreatedAt, &batch.CompletedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches, total, rows.Err()
+}
+
+// GetPayoutBatch returns a batch with its payouts, or nil if it does not exist
+func (r *ledgerRepository) GetPayoutBatch(ctx context.Context, id uuid.UUID) (*models.PayoutBatch, error) {
+	batch := &models.PayoutBatch{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, period_end, status, total, payout_count, created_at, completed_at
+		FROM payout_batches WHERE id = $1`, id,
+	).Scan(
+		&batch.ID, &batch.PeriodEnd, &batch.Status, &batch.Total,
+		&batch.PayoutCount, &batch.CreatedAt, &batch.CompletedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, batch_id, vendor_id, amount, currency, status, transfer_id, failure_reason,
+			period_start, period_end, created_at, paid_at
+		FROM vendor_payouts
+		WHERE batch_id = $1
+		ORDER BY amount DESC`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batch.Payouts = []models.VendorPayout{}
+	for rows.Next() {
+		var p models.VendorPayout
+		err := rows.Scan(
+			&p.ID, &p.BatchID, &p.VendorID, &p.Amount, &p.Currency, &p.Status, &p.TransferID,
+			&p.FailureReason, &p.PeriodStart, &p.PeriodEnd, &p.CreatedAt, &p.PaidAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		batch.Payouts = append(batch.Payouts, p)
+	}
+
+	return batch, rows.Err()
+}
+
+// ClaimPayoutBatch moves a pending batch to processing until lockedUntil. A
+// batch whose claim expired, because its execution crashed, is claimed again.
+// It returns false if the batch is not pending or is claimed by another execution.
+func (r *ledgerRepository) ClaimPayoutBatch(ctx context.Context, id uuid.UUID, now, lockedUntil time.Time) (bool, error) {
+	var claimed uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE payout_batches SET status = 'processing', locked_until = $3
+		WHERE id = $1 AND (status = 'pending' OR (status = 'processing' AND locked_until <= $2))
+		RETURNING id`,
+		id, now, lockedUntil,
+	).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MarkPayoutPaid records a successful transfer and debits the vendor's ledger by the payout amount
+func (r *ledgerRepository) MarkPayoutPaid(ctx context.Context, id uuid.UUID, transferID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var vendorID uuid.UUID
+	var amount float64
+	var currency string
+	err = tx.QueryRowContext(ctx, `
+		UPDATE vendor_payouts SET status = 'paid', transfer_id = $2, failure_reason = NULL, paid_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending'
+		RETURNING vendor_id, amount, currency`,
+		id, transferID,
+	).Scan(&vendorID, &amount, &currency)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO vendor_ledger_entries (id, vendor_id, payout_id, type, amount, currency, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		uuid.New(), vendorID, id, models.LedgerEntryPayout, -amount, currency, "Payout "+transferID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MarkPayoutFailed records a failed transfer and releases the payout's entries
+// so they are picked up by the next batch
+func (r *ledgerRepository) MarkPayoutFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE vendor_payouts SET status = 'failed', failure_reason = $2 WHERE id = $1 AND status = 'pending'",
+		id, reason,
+	)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE vendor_ledger_entries SET payout_id = NULL WHERE payout_id = $1", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FinishPayoutBatch ends an execution of the batch. It is completed once none
+// of its payouts are pending, and otherwise handed back as pending so the
+// remaining payouts can be retried.
+func (r *ledgerRepository) FinishPayoutBatch(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE payout_batches SET
+			status = CASE
+				WHEN EXISTS (SELECT 1 FROM vendor_payouts WHERE batch_id = $1 AND status = 'pending') THEN 'pending'
+				WHEN EXISTS (SELECT 1 FROM vendor_payouts WHERE batch_id = $1 AND status = 'failed') THEN 'partially_failed'
+				ELSE 'completed' END,
+			completed_at = CASE
+				WHEN EXISTS (SELECT 1 FROM vendor_payouts WHERE batch_id = $1 AND status = 'pending') THEN NULL
+				ELSE CURRENT_TIMESTAMP END,
+			locked_until = NULL
+		WHERE id = $1 AND status = 'processing'`,
+		id,
+	)
+	return err
+}
+
+func (r *ledgerRepository) queryEntries(ctx context.Context, query string, args ...interface{}) ([]models.LedgerEntry, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.LedgerEntry{}
+	for rows.Next() {
+		var e models.LedgerEntry
+		err := rows.Scan(
+			&e.ID, &e.VendorID, &e.OrderID, &e.OrderItemID, &e.PayoutID, &e.Type, &e.Amount,
+			&e.Currency, &e.CommissionRate, &e.Description, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+func scanLedgerOrderItem(row interface{ Scan(...interface{}) error }) (*LedgerOrderItem, error) {
+	item := &LedgerOrderItem{}
+	err := row.Scan(
+		&item.ID, &item.OrderID, &item.ProductID, &item.VendorID, &item.Name, &item.Price,
+		&item.Quantity, &item.Total, &item.CreatedAt, &item.CategoryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
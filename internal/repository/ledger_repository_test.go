@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestClaimPayoutBatchOnce(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t, "payout_batches")
+	repo := NewLedgerRepository(db)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	id := uuid.New()
+	if _, err := db.Exec("INSERT INTO payout_batches (id, period_end) VALUES ($1, $2)", id, now); err != nil {
+		t.Fatal(err)
+	}
+
+	var claims atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimed, err := repo.ClaimPayoutBatch(ctx, id, now, now.Add(time.Minute))
+			if err != nil {
+				t.Error(err)
+			}
+			if claimed {
+				claims.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if claims.Load() != 1 {
+		t.Fatalf("batch claimed %d times", claims.Load())
+	}
+
+	// A claim left by a crashed execution expires
+	later := now.Add(2 * time.Minute)
+	if claimed, err := repo.ClaimPayoutBatch(ctx, id, later, later.Add(time.Minute)); err != nil || !claimed {
+		t.Fatalf("expired claim: %v, %v", claimed, err)
+	}
+
+	if err := repo.FinishPayoutBatch(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	batch, err := repo.GetPayoutBatch(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Status != models.PayoutBatchCompleted {
+		t.Errorf("finished batch is %s", batch.Status)
+	}
+	if claimed, err := repo.ClaimPayoutBatch(ctx, id, later, later.Add(time.Minute)); err != nil || claimed {
+		t.Errorf("completed batch claimed: %v, %v", claimed, err)
+	}
+}
@@ -6,30 +6,6 @@ import (
 
 // Placeholder repository interfaces and implementations
 
-type UserRepository interface {
-	// TODO: Implement user repository methods
-}
-
-type userRepository struct {
-	db *sql.DB
-}
-
-func NewUserRepository(db *sql.DB) UserRepository {
-	return &userRepository{db: db}
-}
-
-type VendorRepository interface {
-	// TODO: Implement vendor repository methods
-}
-
-type vendorRepository struct {
-	db *sql.DB
-}
-
-func NewVendorRepository(db *sql.DB) VendorRepository {
-	return &vendorRepository{db: db}
-}
-
 type OrderRepository interface {
 	// TODO: Implement order repository methods
 }
@@ -52,28 +28,4 @@ type cartRepository struct {
 
 func NewCartRepository(db *sql.DB) CartRepository {
 	return &cartRepository{db: db}
-}
-
-type CategoryRepository interface {
-	// TODO: Implement category repository methods
-}
-
-type categoryRepository struct {
-	db *sql.DB
-}
-
-func NewCategoryRepository(db *sql.DB) CategoryRepository {
-	return &categoryRepository{db: db}
-}
-
-type ReviewRepository interface {
-	// TODO: Implement review repository methods
-}
-
-type reviewRepository struct {
-	db *sql.DB
-}
-
-func NewReviewRepository(db *sql.DB) ReviewRepository {
-	return &reviewRepository{db: db}
 }
\ No newline at end of file
@@ -0,0 +1,345 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type PricingRepository interface {
+	CreateSale(ctx context.Context, sale *models.SalePrice) error
+	GetSale(ctx context.Context, id uuid.UUID) (*models.SalePrice, error)
+	GetSales(ctx context.Context, productID uuid.UUID) ([]*models.SalePrice, error)
+	GetActiveSale(ctx context.Context, productID uuid.UUID) (*models.SalePrice, error)
+	HasOverlappingSale(ctx context.Context, productID uuid.UUID, startsAt, endsAt time.Time) (bool, error)
+	CancelSale(ctx context.Context, id uuid.UUID) (bool, error)
+	ApplyDueSales(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+	GetPriceHistory(ctx context.Context, productID uuid.UUID, limit int) ([]models.PriceHistoryEntry, error)
+	GetLowestPrices(ctx context.Context, productIDs []uuid.UUID, days int) (map[uuid.UUID]float64, error)
+}
+
+type pricingRepository struct {
+	db *sql.DB
+}
+
+func NewPricingRepository(db *sql.DB) PricingRepository {
+	return &pricingRepository{db: db}
+}
+
+const saleColumns = `id, product_id, sale_price, starts_at, ends_at, status, original_price,
+	original_compare_price, created_by, created_at, updated_at`
+
+func (r *pricingRepository) CreateSale(ctx context.Context, sale *models.SalePrice) error {
+	query := `
+		INSERT INTO sale_prices (id, product_id, sale_price, starts_at, ends_at, status, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at`
+
+	if sale.ID == uuid.Nil {
+		sale.ID = uuid.New()
+	}
+	if sale.Status == "" {
+		sale.Status = models.SaleStatusScheduled
+	}
+
+	return r.db.QueryRowContext(ctx, query,
+		sale.ID, sale.ProductID, sale.SalePrice, sale.StartsAt, sale.EndsAt, sale.Status, sale.CreatedBy,
+	).Scan(&sale.CreatedAt, &sale.UpdatedAt)
+}
+
+func (r *pricingRepository) GetSale(ctx context.Context, id uuid.UUID) (*models.SalePrice, error) {
+	query := fmt.Sprintf("SELECT %s FROM sale_prices WHERE id = $1", saleColumns)
+	return r.getOne(ctx, query, id)
+}
+
+// GetSales lists a product's sales, latest start first
+func (r *pricingRepository) GetSales(ctx context.Context, productID uuid.UUID) ([]*models.SalePrice, error) {
+	query := fmt.Sprintf("SELECT %s FROM sale_prices WHERE product_id = $1 ORDER BY starts_at DESC", saleColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sales := []*models.SalePrice{}
+	for rows.Next() {
+		sale, err := scanSale(rows)
+		if err != nil {
+			return nil, err
+		}
+		sales = append(sales, sale)
+	}
+
+	return sales, rows.Err()
+}
+
+func (r *pricingRepository) GetActiveSale(ctx context.Context, productID uuid.UUID) (*models.SalePrice, error) {
+	query := fmt.Sprintf("SELECT %s FROM sale_prices WHERE product_id = $1 AND status = 'active'", saleColumns)
+	return r.getOne(ctx, query, productID)
+}
+
+// HasOverlappingSale reports whether a scheduled or active sale of the product overlaps the period
+func (r *pricingRepository) HasOverlappingSale(ctx context.Context, productID uuid.UUID, startsAt, endsAt time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM sale_prices
+			WHERE product_id = $1 AND status IN ('scheduled', 'active')
+				AND starts_at < $3 AND ends_at > $2
+		)`
+
+	var exists bool
+	err := r.db.QueryRowContext(ctx, query, productID, startsAt, endsAt).Scan(&exists)
+	return exists, err
+}
+
+// CancelSale cancels a scheduled or active sale, restoring the product's prices
+// if it was active. It returns false if the sale has already ended.
+func (r *pricingRepository) CancelSale(ctx context.Context, id uuid.UUID) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var status models.SaleStatus
+	if err := tx.QueryRowContext(ctx, "SELECT status FROM sale_prices WHERE id = $1 FOR UPDATE", id).Scan(&status); err != nil {
+		return false, err
+	}
+	if status != models.SaleStatusScheduled && status != models.SaleStatusActive {
+		return false, nil
+	}
+
+	if status == models.SaleStatusActive {
+		if err := setPriceChangeReason(ctx, tx, models.PriceChangeSaleEnd); err != nil {
+			return false, err
+		}
+		restored, err := queryIDs(ctx, tx, `
+			UPDATE products p SET price = s.original_price, compare_price = s.original_compare_price,
+				updated_at = CURRENT_TIMESTAMP
+			FROM sale_prices s
+			WHERE s.id = $1 AND p.id = s.product_id
+			RETURNING p.id`, id)
+		if err != nil {
+			return false, err
+		}
+		if err := insertProductUpdatedEvents(ctx, tx, restored); err != nil {
+			return false, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE sale_prices SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// ApplyDueSales ends active sales whose period is over and starts scheduled sales
+// whose period has begun, switching the product prices. Sales locked by another
+// instance are skipped. It returns the IDs of products whose price changed, each
+// recorded as a product.updated event.
+func (r *pricingRepository) ApplyDueSales(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var changed []uuid.UUID
+
+	// Sales end first so a sale starting as another ends does not clash with it
+	if err := setPriceChangeReason(ctx, tx, models.PriceChangeSaleEnd); err != nil {
+		return nil, err
+	}
+	ended, err := queryIDs(ctx, tx, `
+		WITH due AS (
+			SELECT id FROM sale_prices
+			WHERE status = 'active' AND ends_at <= $1
+			FOR UPDATE SKIP LOCKED
+		), ended AS (
+			UPDATE sale_prices s SET status = 'ended', updated_at = CURRENT_TIMESTAMP
+			FROM due WHERE s.id = due.id
+			RETURNING s.product_id, s.original_price, s.original_compare_price
+		)
+		UPDATE products p SET price = ended.original_price, compare_price = ended.original_compare_price,
+			updated_at = CURRENT_TIMESTAMP
+		FROM ended WHERE p.id = ended.product_id
+		RETURNING p.id`, now)
+	if err != nil {
+		return nil, err
+	}
+	changed = append(changed, ended...)
+
+	// Sales whose whole period passed before they could start never change the price
+	_, err = tx.ExecContext(ctx, `
+		UPDATE sale_prices SET status = 'ended', updated_at = CURRENT_TIMESTAMP
+		WHERE status = 'scheduled' AND ends_at <= $1`, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// The regular price becomes the compare price for the length of the sale
+	if err := setPriceChangeReason(ctx, tx, models.PriceChangeSaleStart); err != nil {
+		return nil, err
+	}
+	started, err := queryIDs(ctx, tx, `
+		WITH due AS (
+			SELECT id FROM sale_prices
+			WHERE status = 'scheduled' AND starts_at <= $1 AND ends_at > $1
+			FOR UPDATE SKIP LOCKED
+		), started AS (
+			UPDATE sale_prices s SET status = 'active', original_price = p.price,
+				original_compare_price = p.compare_price, updated_at = CURRENT_TIMESTAMP
+			FROM due, products p
+			WHERE s.id = due.id AND p.id = s.product_id
+			RETURNING s.product_id, s.sale_price
+		)
+		UPDATE products p SET compare_price = p.price, price = started.sale_price,
+			updated_at = CURRENT_TIMESTAMP
+		FROM started WHERE p.id = started.product_id
+		RETURNING p.id`, now)
+	if err != nil {
+		return nil, err
+	}
+	changed = append(changed, started...)
+
+	if err := insertProductUpdatedEvents(ctx, tx, changed); err != nil {
+		return nil, err
+	}
+	return changed, tx.Commit()
+}
+
+// GetPriceHistory returns a product's price changes, newest first
+func (r *pricingRepository) GetPriceHistory(ctx context.Context, productID uuid.UUID, limit int) ([]models.PriceHistoryEntry, error) {
+	query := `
+		SELECT id, product_id, price, compare_price, reason, changed_at
+		FROM price_history
+		WHERE product_id = $1
+		ORDER BY changed_at DESC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, productID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.PriceHistoryEntry{}
+	for rows.Next() {
+		var entry models.PriceHistoryEntry
+		err := rows.Scan(&entry.ID, &entry.ProductID, &entry.Price, &entry.ComparePrice, &entry.Reason, &entry.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetLowestPrices returns, per product, the lowest price that applied during the
+// given number of days before the current price took effect. Products without
+// earlier prices in that window are left out.
+func (r *pricingRepository) GetLowestPrices(ctx context.Context, productIDs []uuid.UUID, days int) (map[uuid.UUID]float64, error) {
+	lowest := make(map[uuid.UUID]float64)
+	if len(productIDs) == 0 {
+		return lowest, nil
+	}
+
+	// Each history row applies from changed_at until the next row. The current price
+	// took effect at the last row whose price differs from the row before it.
+	query := `
+		WITH h AS (
+			SELECT product_id, price, changed_at,
+				LAG(price) OVER w AS prev_price,
+				LEAD(changed_at) OVER w AS next_changed_at
+			FROM price_history
+			WHERE product_id = ANY($1::uuid[])
+			WINDOW w AS (PARTITION BY product_id ORDER BY changed_at)
+		), current_price AS (
+			SELECT product_id, MAX(changed_at) AS since
+			FROM h
+			WHERE prev_price IS NULL OR prev_price <> price
+			GROUP BY product_id
+		)
+		SELECT h.product_id, MIN(h.price)
+		FROM h
+		JOIN current_price c ON c.product_id = h.product_id
+		WHERE h.changed_at < c.since AND h.next_changed_at > c.since - make_interval(days => $2)
+		GROUP BY h.product_id`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(uuidStrings(productIDs)), days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID uuid.UUID
+		var price float64
+		if err := rows.Scan(&productID, &price); err != nil {
+			return nil, err
+		}
+		lowest[productID] = price
+	}
+
+	return lowest, rows.Err()
+}
+
+func (r *pricingRepository) getOne(ctx context.Context, query string, arg interface{}) (*models.SalePrice, error) {
+	sale, err := scanSale(r.db.QueryRowContext(ctx, query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return sale, nil
+}
+
+func scanSale(row interface{ Scan(...interface{}) error }) (*models.SalePrice, error) {
+	sale := &models.SalePrice{}
+	err := row.Scan(
+		&sale.ID, &sale.ProductID, &sale.SalePrice, &sale.StartsAt, &sale.EndsAt, &sale.Status,
+		&sale.OriginalPrice, &sale.OriginalComparePrice, &sale.CreatedBy, &sale.CreatedAt, &sale.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sale, nil
+}
+
+// setPriceChangeReason labels the price_history rows the trigger writes for the rest of the transaction
+func setPriceChangeReason(ctx context.Context, tx *sql.Tx, reason models.PriceChangeReason) error {
+	_, err := tx.ExecContext(ctx, "SELECT set_config('smrtmart.price_change_reason', $1, true)", string(reason))
+	return err
+}
+
+// queryIDs runs a query returning a single UUID column
+func queryIDs(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
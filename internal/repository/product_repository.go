@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -13,20 +15,25 @@ import (
 )
 
 type ProductRepository interface {
-	Create(product *models.Product) error
-	GetByID(id uuid.UUID) (*models.Product, error)
-	GetByNumericID(numericID int) (*models.Product, error)
-	GetAll(filters ProductFilters) ([]*models.Product, int, error)
-	Update(product *models.Product) error
-	Delete(id uuid.UUID) error
-	GetByVendor(vendorID uuid.UUID, filters ProductFilters) ([]*models.Product, int, error)
-	Search(query string, filters ProductFilters) ([]*models.Product, int, error)
-	GetFeatured(limit int) ([]*models.Product, error)
-	UpdateStock(id uuid.UUID, stock int) error
+	Create(ctx context.Context, product *models.Product, events ...*models.DomainEvent) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Product, error)
+	GetByNumericID(ctx context.Context, numericID int) (*models.Product, error)
+	GetAll(ctx context.Context, filters ProductFilters) ([]*models.Product, int, error)
+	Update(ctx context.Context, product *models.Product, events ...*models.DomainEvent) error
+	Delete(ctx context.Context, id uuid.UUID, events ...*models.DomainEvent) error
+	GetByVendor(ctx context.Context, vendorID uuid.UUID, filters ProductFilters) ([]*models.Product, int, error)
+	Search(ctx context.Context, query string, filters ProductFilters) ([]*models.Product, int, error)
+	SearchFacets(ctx context.Context, query string, filters ProductFilters) (map[string][]models.FacetCount, error)
+	GetFeatured(ctx context.Context, limit int) ([]*models.Product, error)
+	UpdateStock(ctx context.Context, id uuid.UUID, stock int, events ...*models.DomainEvent) error
+	SetSearchLanguage(ctx context.Context, language string, refresh *models.Job) (bool, error)
+	SeedSearchLanguage(ctx context.Context, language string, refresh *models.Job) (bool, error)
+	RefreshSearchVectors(ctx context.Context, afterID uuid.UUID, limit int) (uuid.UUID, int, error)
 }
 
 type ProductFilters struct {
-	Category string
+	Category    string
+	CategoryIDs []uuid.UUID // Matches any of these categories, e.g. a category and its descendants
 	Status   string
 	Featured *bool
 	MinPrice *float64
@@ -45,41 +52,54 @@ func NewProductRepository(db *sql.DB) ProductRepository {
 	return &productRepository{db: db}
 }
 
-func (r *productRepository) Create(product *models.Product) error {
+// Create stores a product together with the events describing it
+func (r *productRepository) Create(ctx context.Context, product *models.Product, events ...*models.DomainEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO products (id, vendor_id, name, description, price, compare_price, sku,
-			category, tags, images, stock, status, featured, weight, dimensions, seo)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			category, tags, images, stock, status, featured, weight, dimensions, seo, category_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 		RETURNING numeric_id, created_at, updated_at`
 
 	if product.ID == uuid.Nil {
 		product.ID = uuid.New()
 	}
 
-	err := r.db.QueryRow(query,
+	err = tx.QueryRowContext(ctx, query,
 		product.ID, product.VendorID, product.Name, product.Description,
 		product.Price, product.ComparePrice, product.SKU, product.Category,
 		pq.Array(product.Tags), pq.Array(product.Images), product.Stock,
 		product.Status, product.Featured, product.Weight, product.Dimensions,
-		product.SEO,
+		product.SEO, product.CategoryID,
 	).Scan(&product.NumericID, &product.CreatedAt, &product.UpdatedAt)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (r *productRepository) GetByID(id uuid.UUID) (*models.Product, error) {
+func (r *productRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Product, error) {
 	query := `
 		SELECT id, numeric_id, vendor_id, name, description, price, compare_price, sku,
-			category, tags, images, stock, status, featured, weight, dimensions,
+			category, category_id, tags, images, stock, status, featured, weight, dimensions,
 			seo, created_at, updated_at
 		FROM products WHERE id = $1`
 
 	product := &models.Product{}
 	var dimensionsJSON, seoJSON []byte
 
-	err := r.db.QueryRow(query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&product.ID, &product.NumericID, &product.VendorID, &product.Name, &product.Description,
-		&product.Price, &product.ComparePrice, &product.SKU, &product.Category,
+		&product.Price, &product.ComparePrice, &product.SKU, &product.Category, &product.CategoryID,
 		pq.Array(&product.Tags), pq.Array(&product.Images), &product.Stock,
 		&product.Status, &product.Featured, &product.Weight, &dimensionsJSON,
 		&seoJSON, &product.CreatedAt, &product.UpdatedAt,
@@ -103,19 +123,19 @@ func (r *productRepository) GetByID(id uuid.UUID) (*models.Product, error) {
 	return product, nil
 }
 
-func (r *productRepository) GetByNumericID(numericID int) (*models.Product, error) {
+func (r *productRepository) GetByNumericID(ctx context.Context, numericID int) (*models.Product, error) {
 	query := `
 		SELECT id, numeric_id, vendor_id, name, description, price, compare_price, sku,
-			category, tags, images, stock, status, featured, weight, dimensions,
+			category, category_id, tags, images, stock, status, featured, weight, dimensions,
 			seo, created_at, updated_at
 		FROM products WHERE numeric_id = $1`
 
 	product := &models.Product{}
 	var dimensionsJSON, seoJSON []byte
 
-	err := r.db.QueryRow(query, numericID).Scan(
+	err := r.db.QueryRowContext(ctx, query, numericID).Scan(
 		&product.ID, &product.NumericID, &product.VendorID, &product.Name, &product.Description,
-		&product.Price, &product.ComparePrice, &product.SKU, &product.Category,
+		&product.Price, &product.ComparePrice, &product.SKU, &product.Category, &product.CategoryID,
 		pq.Array(&product.Tags), pq.Array(&product.Images), &product.Stock,
 		&product.Status, &product.Featured, &product.Weight, &dimensionsJSON,
 		&seoJSON, &product.CreatedAt, &product.UpdatedAt,
@@ -139,13 +159,13 @@ func (r *productRepository) GetByNumericID(numericID int) (*models.Product, erro
 	return product, nil
 }
 
-func (r *productRepository) GetAll(filters ProductFilters) ([]*models.Product, int, error) {
+func (r *productRepository) GetAll(ctx context.Context, filters ProductFilters) ([]*models.Product, int, error) {
 	whereClause, args := r.buildWhereClause(filters)
 	
 	// Count query
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM products %s", whereClause)
 	var total int
-	err := r.db.QueryRow(countQuery, args...).Scan(&total)
+	err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -156,11 +176,11 @@ func (r *productRepository) GetAll(filters ProductFilters) ([]*models.Product, i
 	
 	query := fmt.Sprintf(`
 		SELECT id, numeric_id, vendor_id, name, description, price, compare_price, sku,
-			category, tags, images, stock, status, featured, weight, dimensions,
+			category, category_id, tags, images, stock, status, featured, weight, dimensions,
 			seo, created_at, updated_at
 		FROM products %s %s %s`, whereClause, orderClause, limitClause)
 
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -173,7 +193,7 @@ func (r *productRepository) GetAll(filters ProductFilters) ([]*models.Product, i
 		
 		err := rows.Scan(
 			&product.ID, &product.NumericID, &product.VendorID, &product.Name, &product.Description,
-			&product.Price, &product.ComparePrice, &product.SKU, &product.Category,
+			&product.Price, &product.ComparePrice, &product.SKU, &product.Category, &product.CategoryID,
 			pq.Array(&product.Tags), pq.Array(&product.Images), &product.Stock,
 			&product.Status, &product.Featured, &product.Weight, &dimensionsJSON,
 			&seoJSON, &product.CreatedAt, &product.UpdatedAt,
@@ -196,29 +216,49 @@ func (r *productRepository) GetAll(filters ProductFilters) ([]*models.Product, i
 	return products, total, nil
 }
 
-func (r *productRepository) Update(product *models.Product) error {
+// Update stores a product's changes together with the events describing them
+func (r *productRepository) Update(ctx context.Context, product *models.Product, events ...*models.DomainEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE products SET
 			name = $2, description = $3, price = $4, compare_price = $5,
 			sku = $6, category = $7, tags = $8, images = $9, stock = $10,
-			status = $11, featured = $12, weight = $13, dimensions = $14, seo = $15
+			status = $11, featured = $12, weight = $13, dimensions = $14, seo = $15,
+			category_id = $16
 		WHERE id = $1
 		RETURNING updated_at`
 
-	err := r.db.QueryRow(query,
+	err = tx.QueryRowContext(ctx, query,
 		product.ID, product.Name, product.Description, product.Price,
 		product.ComparePrice, product.SKU, product.Category,
 		pq.Array(product.Tags), pq.Array(product.Images), product.Stock,
 		product.Status, product.Featured, product.Weight, product.Dimensions,
-		product.SEO,
+		product.SEO, product.CategoryID,
 	).Scan(&product.UpdatedAt)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (r *productRepository) Delete(id uuid.UUID) error {
+func (r *productRepository) Delete(ctx context.Context, id uuid.UUID, events ...*models.DomainEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := "DELETE FROM products WHERE id = $1"
-	result, err := r.db.Exec(query, id)
+	result, err := tx.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -232,10 +272,52 @@ func (r *productRepository) Delete(id uuid.UUID) error {
 		return sql.ErrNoRows
 	}
 
-	return nil
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertProductUpdatedEvents records product.updated for products changed in
+// bulk by another change, such as a category rename or a sale starting, so
+// followers of product events see them like any other product update
+func insertProductUpdatedEvents(ctx context.Context, tx *sql.Tx, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, vendor_id, name, sku, price, stock, status FROM products WHERE id = ANY($1::uuid[])",
+		pq.Array(uuidStrings(ids)),
+	)
+	if err != nil {
+		return err
+	}
+
+	var events []*models.DomainEvent
+	for rows.Next() {
+		var payload models.ProductEventPayload
+		if err := rows.Scan(&payload.ProductID, &payload.VendorID, &payload.Name, &payload.SKU,
+			&payload.Price, &payload.Stock, &payload.Status); err != nil {
+			rows.Close()
+			return err
+		}
+		event, err := models.NewDomainEvent(models.EventProductUpdated, "product", payload.ProductID.String(), payload)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return insertDomainEvents(ctx, tx, events)
 }
 
-func (r *productRepository) GetByVendor(vendorID uuid.UUID, filters ProductFilters) ([]*models.Product, int, error) {
+func (r *productRepository) GetByVendor(ctx context.Context, vendorID uuid.UUID, filters ProductFilters) ([]*models.Product, int, error) {
 	filters.Category = "" // Reset category filter for vendor-specific queries
 	whereClause, args := r.buildWhereClause(filters)
 	
@@ -251,7 +333,7 @@ func (r *productRepository) GetByVendor(vendorID uuid.UUID, filters ProductFilte
 	// Count query
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM products %s", whereClause)
 	var total int
-	err := r.db.QueryRow(countQuery, args...).Scan(&total)
+	err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -262,11 +344,11 @@ func (r *productRepository) GetByVendor(vendorID uuid.UUID, filters ProductFilte
 	
 	query := fmt.Sprintf(`
 		SELECT id, numeric_id, vendor_id, name, description, price, compare_price, sku,
-			category, tags, images, stock, status, featured, weight, dimensions,
+			category, category_id, tags, images, stock, status, featured, weight, dimensions,
 			seo, created_at, updated_at
 		FROM products %s %s %s`, whereClause, orderClause, limitClause)
 
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -279,7 +361,7 @@ func (r *productRepository) GetByVendor(vendorID uuid.UUID, filters ProductFilte
 		
 		err := rows.Scan(
 			&product.ID, &product.NumericID, &product.VendorID, &product.Name, &product.Description,
-			&product.Price, &product.ComparePrice, &product.SKU, &product.Category,
+			&product.Price, &product.ComparePrice, &product.SKU, &product.Category, &product.CategoryID,
 			pq.Array(&product.Tags), pq.Array(&product.Images), &product.Stock,
 			&product.Status, &product.Featured, &product.Weight, &dimensionsJSON,
 			&seoJSON, &product.CreatedAt, &product.UpdatedAt,
@@ -302,53 +384,32 @@ func (r *productRepository) GetByVendor(vendorID uuid.UUID, filters ProductFilte
 	return products, total, nil
 }
 
-func (r *productRepository) Search(query string, filters ProductFilters) ([]*models.Product, int, error) {
-	whereClause, args := r.buildWhereClause(filters)
-	
-	// Add search condition
-	searchCondition := `(
-		to_tsvector('english', name) @@ plainto_tsquery('english', $%d) OR
-		to_tsvector('english', description) @@ plainto_tsquery('english', $%d) OR
-		name ILIKE $%d OR
-		description ILIKE $%d
-	)`
-	
-	searchArg := len(args) + 1
-	searchCondition = fmt.Sprintf(searchCondition, searchArg, searchArg, searchArg+1, searchArg+1)
-	args = append(args, query, "%"+query+"%")
-	
-	if whereClause == "" {
-		whereClause = "WHERE " + searchCondition
-	} else {
-		whereClause += " AND " + searchCondition
-	}
+func (r *productRepository) Search(ctx context.Context, query string, filters ProductFilters) ([]*models.Product, int, error) {
+	whereClause, relevance, args := r.buildSearchClause(query, filters)
 
 	// Count query
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM products %s", whereClause)
 	var total int
-	err := r.db.QueryRow(countQuery, args...).Scan(&total)
+	err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	// Main query with relevance scoring
-	orderClause := r.buildOrderClause(filters)
-	if orderClause == "" {
-		orderClause = `ORDER BY (
-			ts_rank(to_tsvector('english', name), plainto_tsquery('english', $` + fmt.Sprintf("%d", searchArg) + `)) +
-			ts_rank(to_tsvector('english', description), plainto_tsquery('english', $` + fmt.Sprintf("%d", searchArg) + `))
-		) DESC`
+	// Rank by relevance unless an explicit sort was requested
+	orderClause := "ORDER BY relevance DESC, created_at DESC"
+	if filters.SortBy != "" && filters.SortBy != "relevance" {
+		orderClause = r.buildOrderClause(filters)
 	}
-	
+
 	limitClause := r.buildLimitClause(filters)
-	
+
 	query = fmt.Sprintf(`
 		SELECT id, numeric_id, vendor_id, name, description, price, compare_price, sku,
-			category, tags, images, stock, status, featured, weight, dimensions,
-			seo, created_at, updated_at
-		FROM products %s %s %s`, whereClause, orderClause, limitClause)
+			category, category_id, tags, images, stock, status, featured, weight, dimensions,
+			seo, created_at, updated_at, %s AS relevance
+		FROM products %s %s %s`, relevance, whereClause, orderClause, limitClause)
 
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -358,13 +419,14 @@ func (r *productRepository) Search(query string, filters ProductFilters) ([]*mod
 	for rows.Next() {
 		product := &models.Product{}
 		var dimensionsJSON, seoJSON []byte
-		
+		var relevance float64
+
 		err := rows.Scan(
 			&product.ID, &product.NumericID, &product.VendorID, &product.Name, &product.Description,
-			&product.Price, &product.ComparePrice, &product.SKU, &product.Category,
+			&product.Price, &product.ComparePrice, &product.SKU, &product.Category, &product.CategoryID,
 			pq.Array(&product.Tags), pq.Array(&product.Images), &product.Stock,
 			&product.Status, &product.Featured, &product.Weight, &dimensionsJSON,
-			&seoJSON, &product.CreatedAt, &product.UpdatedAt,
+			&seoJSON, &product.CreatedAt, &product.UpdatedAt, &relevance,
 		)
 		if err != nil {
 			return nil, 0, err
@@ -377,6 +439,7 @@ func (r *productRepository) Search(query string, filters ProductFilters) ([]*mod
 		if seoJSON != nil {
 			json.Unmarshal(seoJSON, &product.SEO)
 		}
+		product.Relevance = &relevance
 
 		products = append(products, product)
 	}
@@ -384,17 +447,172 @@ func (r *productRepository) Search(query string, filters ProductFilters) ([]*mod
 	return products, total, nil
 }
 
-func (r *productRepository) GetFeatured(limit int) ([]*models.Product, error) {
+// SearchFacets counts search matches per category and per price range
+func (r *productRepository) SearchFacets(ctx context.Context, query string, filters ProductFilters) (map[string][]models.FacetCount, error) {
+	whereClause, _, args := r.buildSearchClause(query, filters)
+
+	var priceCase strings.Builder
+	priceCase.WriteString("CASE")
+	for _, pr := range models.PriceFacetRanges {
+		if pr.Max == nil {
+			fmt.Fprintf(&priceCase, " WHEN price >= %g THEN '%s'", pr.Min, pr.Label)
+		} else {
+			fmt.Fprintf(&priceCase, " WHEN price >= %g AND price < %g THEN '%s'", pr.Min, *pr.Max, pr.Label)
+		}
+	}
+	priceCase.WriteString(" END")
+
+	facetQuery := fmt.Sprintf(`
+		SELECT 'category' AS facet, category AS value, COUNT(*) FROM products %s GROUP BY category
+		UNION ALL
+		SELECT 'price', %s, COUNT(*) FROM products %s GROUP BY 2`,
+		whereClause, priceCase.String(), whereClause)
+
+	rows, err := r.db.QueryContext(ctx, facetQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := map[string][]models.FacetCount{}
+	for rows.Next() {
+		var facet string
+		var value sql.NullString
+		var count int
+		if err := rows.Scan(&facet, &value, &count); err != nil {
+			return nil, err
+		}
+		if !value.Valid {
+			continue
+		}
+		facets[facet] = append(facets[facet], models.FacetCount{Value: value.String, Count: count})
+	}
+
+	return facets, rows.Err()
+}
+
+// buildSearchClause extends the filter WHERE clause with the full-text match and
+// returns the matching relevance expression. Matches use the stored search vector,
+// falling back to trigram word similarity on the name so misspellings like
+// "macbok" still match.
+func (r *productRepository) buildSearchClause(query string, filters ProductFilters) (string, string, []interface{}) {
+	whereClause, args := r.buildWhereClause(filters)
+
+	searchArg := len(args) + 1
+	tsQuery := fmt.Sprintf("websearch_to_tsquery(product_search_config(), $%d)", searchArg)
+	searchCondition := fmt.Sprintf("(search_vector @@ %s OR $%d <%% name)", tsQuery, searchArg)
+	relevance := fmt.Sprintf("(ts_rank(search_vector, %s) + 0.5 * word_similarity($%d, name))", tsQuery, searchArg)
+	args = append(args, query)
+
+	if whereClause == "" {
+		whereClause = "WHERE " + searchCondition
+	} else {
+		whereClause += " AND " + searchCondition
+	}
+
+	return whereClause, relevance, args
+}
+
+// SetSearchLanguage switches the text-search configuration used for the stored
+// search vector (e.g. "swedish", "english"). It returns true if it changed, in
+// which case refresh, the job refreshing the vectors, is queued in the same
+// transaction.
+func (r *productRepository) SetSearchLanguage(ctx context.Context, language string, refresh *models.Job) (bool, error) {
+	return r.updateSearchLanguage(ctx, language, false, refresh)
+}
+
+// SeedSearchLanguage sets the search language like SetSearchLanguage, but only
+// if it was never set, so a configured default does not override a later choice
+func (r *productRepository) SeedSearchLanguage(ctx context.Context, language string, refresh *models.Job) (bool, error) {
+	return r.updateSearchLanguage(ctx, language, true, refresh)
+}
+
+func (r *productRepository) updateSearchLanguage(ctx context.Context, language string, onlyUnset bool, refresh *models.Job) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var changed, unset bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT text_search_config <> $1::regconfig, language_set_at IS NULL
+		FROM search_settings
+		FOR UPDATE`,
+		language,
+	).Scan(&changed, &unset)
+	if err != nil {
+		return false, fmt.Errorf("invalid search language %q: %w", language, err)
+	}
+	if onlyUnset && !unset {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE search_settings SET
+			text_search_config = $1::regconfig, language_set_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP`,
+		language,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if changed && refresh != nil {
+		if _, err := insertJob(ctx, tx, refresh); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return changed, nil
+}
+
+// RefreshSearchVectors rebuilds the search vectors of up to limit products with
+// IDs after afterID, in ID order, with the current text-search configuration.
+// It returns the last ID refreshed and how many were.
+func (r *productRepository) RefreshSearchVectors(ctx context.Context, afterID uuid.UUID, limit int) (uuid.UUID, int, error) {
+	// Re-run the search vector trigger for the batch
+	rows, err := r.db.QueryContext(ctx, `
+		WITH batch AS (
+			SELECT id FROM products WHERE id > $1 ORDER BY id LIMIT $2
+		)
+		UPDATE products p SET name = p.name
+		FROM batch
+		WHERE p.id = batch.id
+		RETURNING p.id`, afterID, limit)
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+	defer rows.Close()
+
+	last, count := afterID, 0
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return uuid.Nil, 0, err
+		}
+		// Postgres orders UUIDs by their bytes
+		if bytes.Compare(id[:], last[:]) > 0 {
+			last = id
+		}
+		count++
+	}
+	return last, count, rows.Err()
+}
+
+func (r *productRepository) GetFeatured(ctx context.Context, limit int) ([]*models.Product, error) {
 	query := `
 		SELECT id, numeric_id, vendor_id, name, description, price, compare_price, sku,
-			category, tags, images, stock, status, featured, weight, dimensions,
+			category, category_id, tags, images, stock, status, featured, weight, dimensions,
 			seo, created_at, updated_at
 		FROM products 
 		WHERE featured = true AND status = 'active' AND stock > 0
 		ORDER BY created_at DESC
 		LIMIT $1`
 
-	rows, err := r.db.Query(query, limit)
+	rows, err := r.db.QueryContext(ctx, query, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -407,7 +625,7 @@ func (r *productRepository) GetFeatured(limit int) ([]*models.Product, error) {
 		
 		err := rows.Scan(
 			&product.ID, &product.NumericID, &product.VendorID, &product.Name, &product.Description,
-			&product.Price, &product.ComparePrice, &product.SKU, &product.Category,
+			&product.Price, &product.ComparePrice, &product.SKU, &product.Category, &product.CategoryID,
 			pq.Array(&product.Tags), pq.Array(&product.Images), &product.Stock,
 			&product.Status, &product.Featured, &product.Weight, &dimensionsJSON,
 			&seoJSON, &product.CreatedAt, &product.UpdatedAt,
@@ -430,9 +648,15 @@ func (r *productRepository) GetFeatured(limit int) ([]*models.Product, error) {
 	return products, nil
 }
 
-func (r *productRepository) UpdateStock(id uuid.UUID, stock int) error {
+func (r *productRepository) UpdateStock(ctx context.Context, id uuid.UUID, stock int, events ...*models.DomainEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := "UPDATE products SET stock = $2 WHERE id = $1"
-	result, err := r.db.Exec(query, id, stock)
+	result, err := tx.ExecContext(ctx, query, id, stock)
 	if err != nil {
 		return err
 	}
@@ -446,7 +670,10 @@ func (r *productRepository) UpdateStock(id uuid.UUID, stock int) error {
 		return sql.ErrNoRows
 	}
 
-	return nil
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *productRepository) buildWhereClause(filters ProductFilters) (string, []interface{}) {
@@ -460,6 +687,16 @@ func (r *productRepository) buildWhereClause(filters ProductFilters) (string, []
 		argIndex++
 	}
 
+	if len(filters.CategoryIDs) > 0 {
+		ids := make([]string, len(filters.CategoryIDs))
+		for i, id := range filters.CategoryIDs {
+			ids[i] = id.String()
+		}
+		conditions = append(conditions, fmt.Sprintf("category_id = ANY($%d::uuid[])", argIndex))
+		args = append(args, pq.Array(ids))
+		argIndex++
+	}
+
 	if filters.Status != "" {
 		conditions = append(conditions, fmt.Sprintf("status = $%d", argIndex))
 		args = append(args, filters.Status)
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -11,8 +12,8 @@ import (
 )
 
 type SimpleProductRepository interface {
-	GetAll(filters ProductFilters) ([]*models.Product, int, error)
-	GetFeatured(limit int) ([]*models.Product, error)
+	GetAll(ctx context.Context, filters ProductFilters) ([]*models.Product, int, error)
+	GetFeatured(ctx context.Context, limit int) ([]*models.Product, error)
 }
 
 type simpleProductRepository struct {
@@ -23,13 +24,13 @@ func NewSimpleProductRepository(db *sql.DB) SimpleProductRepository {
 	return &simpleProductRepository{db: db}
 }
 
-func (r *simpleProductRepository) GetAll(filters ProductFilters) ([]*models.Product, int, error) {
+func (r *simpleProductRepository) GetAll(ctx context.Context, filters ProductFilters) ([]*models.Product, int, error) {
 	whereClause, args := r.buildWhereClause(filters)
 	
 	// Count query
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM products %s", whereClause)
 	var total int
-	err := r.db.QueryRow(countQuery, args...).Scan(&total)
+	err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -44,7 +45,7 @@ func (r *simpleProductRepository) GetAll(filters ProductFilters) ([]*models.Prod
 			created_at, updated_at
 		FROM products %s %s %s`, whereClause, orderClause, limitClause)
 
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -69,7 +70,7 @@ func (r *simpleProductRepository) GetAll(filters ProductFilters) ([]*models.Prod
 	return products, total, nil
 }
 
-func (r *simpleProductRepository) GetFeatured(limit int) ([]*models.Product, error) {
+func (r *simpleProductRepository) GetFeatured(ctx context.Context, limit int) ([]*models.Product, error) {
 	query := `
 		SELECT id, vendor_id, name, description, price, compare_price, sku,
 			category, tags, images, stock, status, featured, weight,
@@ -79,7 +80,7 @@ func (r *simpleProductRepository) GetFeatured(limit int) ([]*models.Product, err
 		ORDER BY created_at DESC
 		LIMIT $1`
 
-	rows, err := r.db.Query(query, limit)
+	rows, err := r.db.QueryContext(ctx, query, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/models"
+)
+
+func TestSearchLanguageQueuesRefreshWithChange(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t, "jobs")
+	if _, err := db.Exec("UPDATE search_settings SET text_search_config = 'swedish', language_set_at = NULL"); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewProductRepository(db)
+	refresh := func(language string) *models.Job {
+		return &models.Job{Kind: "search.refresh_vectors", Payload: []byte(`{"language":"` + language + `"}`), MaxAttempts: 3}
+	}
+	queued := func() int {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM jobs").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if _, err := repo.SetSearchLanguage(ctx, "klingon", refresh("klingon")); err == nil {
+		t.Error("unknown text search configuration accepted")
+	}
+	if changed, err := repo.SeedSearchLanguage(ctx, "swedish", refresh("swedish")); err != nil || changed {
+		t.Fatalf("seeding the current language: %v, %v", changed, err)
+	}
+	if queued() != 0 {
+		t.Error("refresh queued for an unchanged language")
+	}
+
+	// The seed marked the language as set, so a later seed leaves it alone
+	if changed, err := repo.SeedSearchLanguage(ctx, "english", refresh("english")); err != nil || changed {
+		t.Errorf("second seed: %v, %v", changed, err)
+	}
+	if changed, err := repo.SetSearchLanguage(ctx, "english", refresh("english")); err != nil || !changed {
+		t.Fatalf("SetSearchLanguage = %v, %v", changed, err)
+	}
+	if queued() != 1 {
+		t.Errorf("%d jobs queued, want the refresh", queued())
+	}
+}
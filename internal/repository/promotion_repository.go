@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type PromotionRepository interface {
+	Create(ctx context.Context, promotion *models.Promotion) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Promotion, error)
+	GetByCode(ctx context.Context, code string) (*models.Promotion, error)
+	GetAll(ctx context.Context, vendorID *uuid.UUID, page, limit int) ([]*models.Promotion, int, error)
+	GetAutomatic(ctx context.Context, now time.Time) ([]*models.Promotion, error)
+	Update(ctx context.Context, promotion *models.Promotion) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	CountCustomerRedemptions(ctx context.Context, promotionID, customerID uuid.UUID) (int, error)
+	Redeem(ctx context.Context, reference string, customerID *uuid.UUID, applied []models.AppliedPromotion, events ...*models.DomainEvent) (bool, error)
+	CancelRedemptions(ctx context.Context, reference string) error
+}
+
+type promotionRepository struct {
+	db *sql.DB
+}
+
+func NewPromotionRepository(db *sql.DB) PromotionRepository {
+	return &promotionRepository{db: db}
+}
+
+const promotionColumns = `id, code, name, description, type, value, max_discount, min_subtotal,
+	buy_quantity, get_quantity, vendor_id, product_ids, category_ids, stackable, usage_limit,
+	per_customer_limit, usage_count, starts_at, ends_at, is_active, created_by, created_at, updated_at`
+
+func (r *promotionRepository) Create(ctx context.Context, promotion *models.Promotion) error {
+	query := `
+		INSERT INTO promotions (id, code, name, description, type, value, max_discount, min_subtotal,
+			buy_quantity, get_quantity, vendor_id, product_ids, category_ids, stackable, usage_limit,
+			per_customer_limit, starts_at, ends_at, is_active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+		RETURNING usage_count, created_at, updated_at`
+
+	if promotion.ID == uuid.Nil {
+		promotion.ID = uuid.New()
+	}
+
+	return r.db.QueryRowContext(ctx, query,
+		promotion.ID, promotion.Code, promotion.Name, promotion.Description, promotion.Type,
+		promotion.Value, promotion.MaxDiscount, promotion.MinSubtotal, promotion.BuyQuantity,
+		promotion.GetQuantity, promotion.VendorID, pq.Array(promotion.ProductIDs),
+		pq.Array(uuidStrings(promotion.CategoryIDs)), promotion.Stackable, promotion.UsageLimit,
+		promotion.PerCustomerLimit, promotion.StartsAt, promotion.EndsAt, promotion.IsActive,
+		promotion.CreatedBy,
+	).Scan(&promotion.UsageCount, &promotion.CreatedAt, &promotion.UpdatedAt)
+}
+
+func (r *promotionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Promotion, error) {
+	query := fmt.Sprintf("SELECT %s FROM promotions WHERE id = $1", promotionColumns)
+	return r.getOne(ctx, query, id)
+}
+
+// GetByCode looks up a coupon, ignoring case
+func (r *promotionRepository) GetByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	query := fmt.Sprintf("SELECT %s FROM promotions WHERE code IS NOT NULL AND UPPER(code) = UPPER($1)", promotionColumns)
+	return r.getOne(ctx, query, code)
+}
+
+// GetAll pages through promotions, newest first, optionally only those of one vendor
+func (r *promotionRepository) GetAll(ctx context.Context, vendorID *uuid.UUID, page, limit int) ([]*models.Promotion, int, error) {
+	whereClause := ""
+	args := []interface{}{}
+	if vendorID != nil {
+		whereClause = "WHERE vendor_id = $1"
+		args = append(args, *vendorID)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM promotions "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM promotions
+		%s
+		ORDER BY created_at DESC
+		LIMIT %d OFFSET %d`, promotionColumns, whereClause, limit, (page-1)*limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	promotions, err := scanPromotions(rows)
+	return promotions, total, err
+}
+
+// GetAutomatic returns the active promotions without a code that are running at the given time
+func (r *promotionRepository) GetAutomatic(ctx context.Context, now time.Time) ([]*models.Promotion, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM promotions
+		WHERE code IS NULL AND is_active
+			AND (starts_at IS NULL OR starts_at <= $1)
+			AND (ends_at IS NULL OR ends_at > $1)
+			AND (usage_limit IS NULL OR usage_count < usage_limit)
+		ORDER BY created_at`, promotionColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanPromotions(rows)
+}
+
+func (r *promotionRepository) Update(ctx context.Context, promotion *models.Promotion) error {
+	query := `
+		UPDATE promotions SET
+			code = $2, name = $3, description = $4, type = $5, value = $6, max_discount = $7,
+			min_subtotal = $8, buy_quantity = $9, get_quantity = $10, product_ids = $11,
+			category_ids = $12, stackable = $13, usage_limit = $14, per_customer_limit = $15,
+			starts_at = $16, ends_at = $17, is_active = $18, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING usage_count, updated_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		promotion.ID, promotion.Code, promotion.Name, promotion.Description, promotion.Type,
+		promotion.Value, promotion.MaxDiscount, promotion.MinSubtotal, promotion.BuyQuantity,
+		promotion.GetQuantity, pq.Array(promotion.ProductIDs), pq.Array(uuidStrings(promotion.CategoryIDs)),
+		promotion.Stackable, promotion.UsageLimit, promotion.PerCustomerLimit, promotion.StartsAt,
+		promotion.EndsAt, promotion.IsActive,
+	).Scan(&promotion.UsageCount, &promotion.UpdatedAt)
+}
+
+func (r *promotionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM promotions WHERE id = $1", id)
+	return err
+}
+
+func (r *promotionRepository) CountCustomerRedemptions(ctx context.Context, promotionID, customerID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM promotion_redemptions WHERE promotion_id = $1 AND customer_id = $2",
+		promotionID, customerID,
+	).Scan(&count)
+	return count, err
+}
+
+// Redeem records the promotions applied to a paid checkout, and the events
+// describing it, in one transaction. The promotion rows are locked and their
+// usage limits checked again, so it returns false without recording anything if
+// any limit has been reached since the cart was quoted. Redeeming the same
+// checkout again has no effect.
+func (r *promotionRepository) Redeem(ctx context.Context, reference string, customerID *uuid.UUID, applied []models.AppliedPromotion, events ...*models.DomainEvent) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	redeemed, err := redeemPromotions(ctx, tx, reference, customerID, applied)
+	if err != nil || !redeemed {
+		return false, err
+	}
+
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// redeemPromotions records the promotions applied to a checkout within tx. It
+// returns false if any usage limit has been reached; the caller must then roll
+// the transaction back.
+func redeemPromotions(ctx context.Context, tx *sql.Tx, reference string, customerID *uuid.UUID, applied []models.AppliedPromotion) (bool, error) {
+	for _, a := range applied {
+		var usageLimit, perCustomerLimit sql.NullInt64
+		var usageCount int
+		err := tx.QueryRowContext(ctx,
+			"SELECT usage_limit, per_customer_limit, usage_count FROM promotions WHERE id = $1 FOR UPDATE",
+			a.PromotionID,
+		).Scan(&usageLimit, &perCustomerLimit, &usageCount)
+		if err != nil {
+			return false, err
+		}
+
+		var exists bool
+		err = tx.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM promotion_redemptions WHERE promotion_id = $1 AND checkout_reference = $2)",
+			a.PromotionID, reference,
+		).Scan(&exists)
+		if err != nil {
+			return false, err
+		}
+		if exists {
+			continue
+		}
+
+		if usageLimit.Valid && int64(usageCount) >= usageLimit.Int64 {
+			return false, nil
+		}
+		if perCustomerLimit.Valid && customerID != nil {
+			var used int64
+			err := tx.QueryRowContext(ctx,
+				"SELECT COUNT(*) FROM promotion_redemptions WHERE promotion_id = $1 AND customer_id = $2",
+				a.PromotionID, *customerID,
+			).Scan(&used)
+			if err != nil {
+				return false, err
+			}
+			if used >= perCustomerLimit.Int64 {
+				return false, nil
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO promotion_redemptions (id, promotion_id, checkout_reference, customer_id, discount)
+			VALUES ($1, $2, $3, $4, $5)`,
+			uuid.New(), a.PromotionID, reference, customerID, a.Discount,
+		)
+		if err != nil {
+			return false, err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE promotions SET usage_count = usage_count + 1 WHERE id = $1", a.PromotionID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// CancelRedemptions releases the promotions redeemed by a refunded or unpaid
+// checkout so they can be used again
+func (r *promotionRepository) CancelRedemptions(ctx context.Context, reference string) error {
+	query := `
+		WITH released AS (
+			DELETE FROM promotion_redemptions WHERE checkout_reference = $1 RETURNING promotion_id
+		)
+		UPDATE promotions SET usage_count = GREATEST(usage_count - 1, 0)
+		WHERE id IN (SELECT promotion_id FROM released)`
+
+	_, err := r.db.ExecContext(ctx, query, reference)
+	return err
+}
+
+func (r *promotionRepository) getOne(ctx context.Context, query string, arg interface{}) (*models.Promotion, error) {
+	promotion, err := scanPromotion(r.db.QueryRowContext(ctx, query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return promotion, nil
+}
+
+func scanPromotions(rows *sql.Rows) ([]*models.Promotion, error) {
+	promotions := []*models.Promotion{}
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+
+	return promotions, rows.Err()
+}
+
+func scanPromotion(row interface{ Scan(...interface{}) error }) (*models.Promotion, error) {
+	promotion := &models.Promotion{}
+	var categoryIDs []string
+	err := row.Scan(
+		&promotion.ID, &promotion.Code, &promotion.Name, &promotion.Description, &promotion.Type,
+		&promotion.Value, &promotion.MaxDiscount, &promotion.MinSubtotal, &promotion.BuyQuantity,
+		&promotion.GetQuantity, &promotion.VendorID, pq.Array(&promotion.ProductIDs),
+		pq.Array(&categoryIDs), &promotion.Stackable, &promotion.UsageLimit,
+		&promotion.PerCustomerLimit, &promotion.UsageCount, &promotion.StartsAt, &promotion.EndsAt,
+		&promotion.IsActive, &promotion.CreatedBy, &promotion.CreatedAt, &promotion.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	promotion.CategoryIDs = make([]uuid.UUID, 0, len(categoryIDs))
+	for _, id := range categoryIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, err
+		}
+		promotion.CategoryIDs = append(promotion.CategoryIDs, parsed)
+	}
+	if promotion.ProductIDs == nil {
+		promotion.ProductIDs = []int64{}
+	}
+
+	return promotion, nil
+}
+
+// uuidStrings converts IDs for binding to a uuid[] column
+func uuidStrings(ids []uuid.UUID) []string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+	return strs
+}
@@ -5,23 +5,47 @@ import (
 )
 
 type Repositories struct {
-	User     UserRepository
-	Vendor   VendorRepository
-	Product  ProductRepository
-	Order    OrderRepository
-	Cart     CartRepository
-	Category CategoryRepository
-	Review   ReviewRepository
+	User      UserRepository
+	Vendor    VendorRepository
+	Product   ProductRepository
+	Order     OrderRepository
+	Cart      CartRepository
+	Category  CategoryRepository
+	Review    ReviewRepository
+	Search    SearchRepository
+	Ledger    LedgerRepository
+	Wishlist  WishlistRepository
+	Promotion PromotionRepository
+	Pricing   PricingRepository
+	GiftCard  GiftCardRepository
+	Shipping  ShippingRepository
+	Shipment  ShipmentRepository
+	Email     EmailRepository
+	Job       JobRepository
+	Event     EventRepository
+	Webhook   WebhookRepository
 }
 
 func NewRepositories(db *sql.DB) *Repositories {
 	return &Repositories{
-		User:     NewUserRepository(db),
-		Vendor:   NewVendorRepository(db),
-		Product:  NewProductRepository(db),
-		Order:    NewOrderRepository(db),
-		Cart:     NewCartRepository(db),
-		Category: NewCategoryRepository(db),
-		Review:   NewReviewRepository(db),
+		User:      NewUserRepository(db),
+		Vendor:    NewVendorRepository(db),
+		Product:   NewProductRepository(db),
+		Order:     NewOrderRepository(db),
+		Cart:      NewCartRepository(db),
+		Category:  NewCategoryRepository(db),
+		Review:    NewReviewRepository(db),
+		Search:    NewSearchRepository(db),
+		Ledger:    NewLedgerRepository(db),
+		Wishlist:  NewWishlistRepository(db),
+		Promotion: NewPromotionRepository(db),
+		Pricing:   NewPricingRepository(db),
+		GiftCard:  NewGiftCardRepository(db),
+		Shipping:  NewShippingRepository(db),
+		Shipment:  NewShipmentRepository(db),
+		Email:     NewEmailRepository(db),
+		Job:       NewJobRepository(db),
+		Event:     NewEventRepository(db),
+		Webhook:   NewWebhookRepository(db),
 	}
-}
\ No newline at end of file
+}
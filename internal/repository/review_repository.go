@@ -0,0 +1,563 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type ReviewRepository interface {
+	Create(ctx context.Context, review *models.Review, entry *models.ReviewModerationEntry) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Review, error)
+	GetByProductAndCustomer(ctx context.Context, productID, customerID uuid.UUID) (*models.Review, error)
+	Update(ctx context.Context, review *models.Review, entry *models.ReviewModerationEntry) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	GetByProduct(ctx context.Context, productID uuid.UUID, sort string, page, limit int) ([]*models.Review, int, error)
+	HasDeliveredOrder(ctx context.Context, customerID, productID uuid.UUID) (bool, error)
+	GetRatingSummaries(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]*models.RatingSummary, error)
+	GetByStatus(ctx context.Context, status string, page, limit int) ([]*models.Review, int, error)
+	UpdateStatus(ctx context.Context, entry *models.ReviewModerationEntry) error
+	GetModerationLog(ctx context.Context, reviewID uuid.UUID) ([]models.ReviewModerationEntry, error)
+	AddHelpfulVote(ctx context.Context, reviewID, userID uuid.UUID) error
+	RemoveHelpfulVote(ctx context.Context, reviewID, userID uuid.UUID) error
+	CreateReport(ctx context.Context, report *models.ReviewReport, holdAt int) (bool, error)
+	GetReports(ctx context.Context, reviewID uuid.UUID) ([]models.ReviewReport, error)
+	SetReply(ctx context.Context, reviewID uuid.UUID, body *string, entry *models.ReviewModerationEntry) error
+}
+
+type reviewRepository struct {
+	db *sql.DB
+}
+
+func NewReviewRepository(db *sql.DB) ReviewRepository {
+	return &reviewRepository{db: db}
+}
+
+const reviewColumns = `r.id, r.product_id, r.customer_id, r.rating, r.title, r.comment, r.is_verified,
+	r.helpful_count, COALESCE(u.first_name || ' ' || LEFT(u.last_name, 1) || '.', ''),
+	r.status, r.moderation_reason, r.report_count, r.vendor_reply, r.vendor_replied_at,
+	r.created_at, r.updated_at`
+
+// Create inserts a review. A moderation entry, such as an automatic hold, is
+// recorded in the same transaction.
+func (r *reviewRepository) Create(ctx context.Context, review *models.Review, entry *models.ReviewModerationEntry) error {
+	query := `
+		INSERT INTO reviews (id, product_id, customer_id, rating, title, comment, is_verified, status, moderation_reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING helpful_count, created_at, updated_at`
+
+	if review.ID == uuid.Nil {
+		review.ID = uuid.New()
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query,
+		review.ID, review.ProductID, review.CustomerID, review.Rating,
+		review.Title, review.Comment, review.IsVerified, review.Status, review.ModerationReason,
+	).Scan(&review.HelpfulCount, &review.CreatedAt, &review.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if entry != nil {
+		if err := insertModerationEntry(ctx, tx, entry); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *reviewRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Review, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM reviews r
+		LEFT JOIN users u ON u.id = r.customer_id
+		WHERE r.id = $1`, reviewColumns)
+
+	return r.getOne(ctx, query, id)
+}
+
+func (r *reviewRepository) GetByProductAndCustomer(ctx context.Context, productID, customerID uuid.UUID) (*models.Review, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM reviews r
+		LEFT JOIN users u ON u.id = r.customer_id
+		WHERE r.product_id = $1 AND r.customer_id = $2`, reviewColumns)
+
+	return r.getOne(ctx, query, productID, customerID)
+}
+
+// Update saves an edited review. A moderation entry, such as a resubmission or
+// an automatic hold, is recorded in the same transaction.
+func (r *reviewRepository) Update(ctx context.Context, review *models.Review, entry *models.ReviewModerationEntry) error {
+	query := `
+		UPDATE reviews SET
+			rating = $2, title = $3, comment = $4, is_verified = $5, status = $6, moderation_reason = $7,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query,
+		review.ID, review.Rating, review.Title, review.Comment, review.IsVerified,
+		review.Status, review.ModerationReason,
+	).Scan(&review.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if entry != nil {
+		if err := insertModerationEntry(ctx, tx, entry); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *reviewRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM reviews WHERE id = $1", id)
+	return err
+}
+
+// GetByProduct pages through a product's published reviews. sort is one of "newest"
+// (default), "helpful", "rating" (highest first) or "rating_asc".
+func (r *reviewRepository) GetByProduct(ctx context.Context, productID uuid.UUID, sort string, page, limit int) ([]*models.Review, int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM reviews WHERE product_id = $1 AND status = $2",
+		productID, models.ReviewStatusPublished,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	orderClause := "ORDER BY r.created_at DESC"
+	switch sort {
+	case "helpful":
+		orderClause = "ORDER BY r.helpful_count DESC, r.created_at DESC"
+	case "rating":
+		orderClause = "ORDER BY r.rating DESC, r.created_at DESC"
+	case "rating_asc":
+		orderClause = "ORDER BY r.rating ASC, r.created_at DESC"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM reviews r
+		LEFT JOIN users u ON u.id = r.customer_id
+		WHERE r.product_id = $1 AND r.status = $2
+		%s
+		LIMIT %d OFFSET %d`, reviewColumns, orderClause, limit, (page-1)*limit)
+
+	rows, err := r.db.QueryContext(ctx, query, productID, models.ReviewStatusPublished)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	reviews := []*models.Review{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, total, rows.Err()
+}
+
+// HasDeliveredOrder reports whether the customer has received an order containing the product
+func (r *reviewRepository) HasDeliveredOrder(ctx context.Context, customerID, productID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM orders o
+			JOIN order_items oi ON oi.order_id = o.id
+			WHERE o.customer_id = $1 AND oi.product_id = $2 AND o.status = 'delivered'
+		)`
+
+	var delivered bool
+	err := r.db.QueryRowContext(ctx, query, customerID, productID).Scan(&delivered)
+	return delivered, err
+}
+
+// GetRatingSummaries returns the rating summary of each product that has published reviews
+func (r *reviewRepository) GetRatingSummaries(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]*models.RatingSummary, error) {
+	summaries := make(map[uuid.UUID]*models.RatingSummary, len(productIDs))
+	if len(productIDs) == 0 {
+		return summaries, nil
+	}
+
+	ids := make([]string, len(productIDs))
+	for i, id := range productIDs {
+		ids[i] = id.String()
+	}
+
+	query := `
+		SELECT product_id, rating, COUNT(*)
+		FROM reviews
+		WHERE product_id = ANY($1::uuid[]) AND status = $2
+		GROUP BY product_id, rating`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids), models.ReviewStatusPublished)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID uuid.UUID
+		var rating, count int
+		if err := rows.Scan(&productID, &rating, &count); err != nil {
+			return nil, err
+		}
+
+		summary, ok := summaries[productID]
+		if !ok {
+			summary = &models.RatingSummary{Histogram: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+			summaries[productID] = summary
+		}
+		summary.Histogram[rating] = count
+		summary.Count += count
+		summary.Average += float64(rating * count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, summary := range summaries {
+		summary.Average = math.Round(summary.Average/float64(summary.Count)*100) / 100
+	}
+
+	return summaries, nil
+}
+
+// GetByStatus pages through reviews for moderation. Pending reviews are listed
+// oldest first so the queue is worked in order; other statuses newest first.
+func (r *reviewRepository) GetByStatus(ctx context.Context, status string, page, limit int) ([]*models.Review, int, error) {
+	whereClause := ""
+	args := []interface{}{}
+	if status != "" {
+		whereClause = "WHERE r.status = $1"
+		args = append(args, status)
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM reviews r " + whereClause
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	orderClause := "ORDER BY r.created_at DESC"
+	if status == string(models.ReviewStatusPending) {
+		orderClause = "ORDER BY r.created_at ASC"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM reviews r
+		LEFT JOIN users u ON u.id = r.customer_id
+		%s
+		%s
+		LIMIT %d OFFSET %d`, reviewColumns, whereClause, orderClause, limit, (page-1)*limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	reviews := []*models.Review{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, total, rows.Err()
+}
+
+// UpdateStatus applies a moderation decision and records it in the audit log.
+// Publishing resets the report count so the review is only held again by new reports.
+func (r *reviewRepository) UpdateStatus(ctx context.Context, entry *models.ReviewModerationEntry) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		"SELECT status FROM reviews WHERE id = $1 FOR UPDATE",
+		entry.ReviewID,
+	).Scan(&entry.FromStatus)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE reviews SET
+			status = $2, moderation_reason = $3,
+			report_count = CASE WHEN $4 THEN 0 ELSE report_count END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		entry.ReviewID, entry.ToStatus, entry.Reason, entry.ToStatus == models.ReviewStatusPublished,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := insertModerationEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetModerationLog returns the moderation actions taken on a review, newest first
+func (r *reviewRepository) GetModerationLog(ctx context.Context, reviewID uuid.UUID) ([]models.ReviewModerationEntry, error) {
+	query := `
+		SELECT id, review_id, action, from_status, to_status, reason, actor_id, created_at
+		FROM review_moderation_log
+		WHERE review_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.ReviewModerationEntry{}
+	for rows.Next() {
+		var entry models.ReviewModerationEntry
+		err := rows.Scan(
+			&entry.ID, &entry.ReviewID, &entry.Action, &entry.FromStatus, &entry.ToStatus,
+			&entry.Reason, &entry.ActorID, &entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+
+	return history, rows.Err()
+}
+
+// AddHelpfulVote records the user's helpful vote. Voting again has no effect.
+func (r *reviewRepository) AddHelpfulVote(ctx context.Context, reviewID, userID uuid.UUID) error {
+	query := `
+		WITH vote AS (
+			INSERT INTO review_votes (review_id, user_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+			RETURNING review_id
+		)
+		UPDATE reviews SET helpful_count = helpful_count + 1
+		WHERE id IN (SELECT review_id FROM vote)`
+
+	_, err := r.db.ExecContext(ctx, query, reviewID, userID)
+	return err
+}
+
+// RemoveHelpfulVote withdraws the user's helpful vote, if any
+func (r *reviewRepository) RemoveHelpfulVote(ctx context.Context, reviewID, userID uuid.UUID) error {
+	query := `
+		WITH vote AS (
+			DELETE FROM review_votes
+			WHERE review_id = $1 AND user_id = $2
+			RETURNING review_id
+		)
+		UPDATE reviews SET helpful_count = GREATEST(helpful_count - 1, 0)
+		WHERE id IN (SELECT review_id FROM vote)`
+
+	_, err := r.db.ExecContext(ctx, query, reviewID, userID)
+	return err
+}
+
+// CreateReport records a report, returning false if the user has already
+// reported the review. A published review reaching holdAt reports is moved
+// back to pending; holdAt <= 0 disables this.
+func (r *reviewRepository) CreateReport(ctx context.Context, report *models.ReviewReport, holdAt int) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if report.ID == uuid.Nil {
+		report.ID = uuid.New()
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO review_reports (id, review_id, reporter_id, reason)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (review_id, reporter_id) DO NOTHING
+		RETURNING created_at`,
+		report.ID, report.ReviewID, report.ReporterID, report.Reason,
+	).Scan(&report.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var count int
+	var status models.ReviewStatus
+	err = tx.QueryRowContext(ctx, `
+		UPDATE reviews SET report_count = report_count + 1
+		WHERE id = $1
+		RETURNING report_count, status`,
+		report.ReviewID,
+	).Scan(&count, &status)
+	if err != nil {
+		return false, err
+	}
+
+	if holdAt > 0 && count >= holdAt && status == models.ReviewStatusPublished {
+		reason := fmt.Sprintf("reported %d times", count)
+		_, err = tx.ExecContext(ctx,
+			"UPDATE reviews SET status = $2, moderation_reason = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+			report.ReviewID, models.ReviewStatusPending, reason,
+		)
+		if err != nil {
+			return false, err
+		}
+
+		err = insertModerationEntry(ctx, tx, &models.ReviewModerationEntry{
+			ReviewID:   report.ReviewID,
+			Action:     models.ReviewActionReportHold,
+			FromStatus: status,
+			ToStatus:   models.ReviewStatusPending,
+			Reason:     &reason,
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// GetReports returns the reports made against a review, newest first
+func (r *reviewRepository) GetReports(ctx context.Context, reviewID uuid.UUID) ([]models.ReviewReport, error) {
+	query := `
+		SELECT id, review_id, reporter_id, reason, created_at
+		FROM review_reports
+		WHERE review_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []models.ReviewReport{}
+	for rows.Next() {
+		var report models.ReviewReport
+		err := rows.Scan(&report.ID, &report.ReviewID, &report.ReporterID, &report.Reason, &report.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, rows.Err()
+}
+
+// SetReply sets or, with a nil body, removes the vendor's reply to a review. A
+// moderation entry, such as an admin removing the reply, is recorded in the same
+// transaction.
+func (r *reviewRepository) SetReply(ctx context.Context, reviewID uuid.UUID, body *string, entry *models.ReviewModerationEntry) error {
+	query := `
+		UPDATE reviews SET
+			vendor_reply = $2::text,
+			vendor_replied_at = CASE WHEN $2::text IS NULL THEN NULL ELSE CURRENT_TIMESTAMP END
+		WHERE id = $1`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, reviewID, body); err != nil {
+		return err
+	}
+
+	if entry != nil {
+		if err := insertModerationEntry(ctx, tx, entry); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insertModerationEntry writes an audit log entry using either the database or a transaction
+func insertModerationEntry(ctx context.Context, exec interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}, entry *models.ReviewModerationEntry) error {
+	entry.ID = uuid.New()
+	return exec.QueryRowContext(ctx, `
+		INSERT INTO review_moderation_log (id, review_id, action, from_status, to_status, reason, actor_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at`,
+		entry.ID, entry.ReviewID, entry.Action, entry.FromStatus, entry.ToStatus, entry.Reason, entry.ActorID,
+	).Scan(&entry.CreatedAt)
+}
+
+func (r *reviewRepository) getOne(ctx context.Context, query string, args ...interface{}) (*models.Review, error) {
+	review, err := scanReview(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func scanReview(row interface{ Scan(...interface{}) error }) (*models.Review, error) {
+	review := &models.Review{}
+	var replyBody sql.NullString
+	var repliedAt sql.NullTime
+	err := row.Scan(
+		&review.ID, &review.ProductID, &review.CustomerID, &review.Rating, &review.Title,
+		&review.Comment, &review.IsVerified, &review.HelpfulCount, &review.ReviewerName,
+		&review.Status, &review.ModerationReason, &review.ReportCount, &replyBody, &repliedAt,
+		&review.CreatedAt, &review.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if replyBody.Valid {
+		review.Reply = &models.ReviewReply{Body: replyBody.String, RepliedAt: repliedAt.Time}
+	}
+
+	return review, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/models"
+)
+
+type SearchRepository interface {
+	Suggest(ctx context.Context, prefix string, limit int) (*models.SearchSuggestions, error)
+	RecordQuery(ctx context.Context, query string, resultCount int) error
+	GetZeroResultQueries(ctx context.Context, limit int) ([]models.SearchQueryStat, error)
+}
+
+type searchRepository struct {
+	db *sql.DB
+}
+
+func NewSearchRepository(db *sql.DB) SearchRepository {
+	return &searchRepository{db: db}
+}
+
+// Suggest returns product names, categories and popular past queries matching
+// the prefix in a single round trip. Products and categories also match on
+// trigram word similarity so small typos still produce suggestions.
+func (r *searchRepository) Suggest(ctx context.Context, prefix string, limit int) (*models.SearchSuggestions, error) {
+	query := `
+		(SELECT 'product' AS kind, numeric_id::text AS ref, name AS label
+		FROM products
+		WHERE status = 'active' AND (name ILIKE $2 OR $1 <% name)
+		ORDER BY (name ILIKE $2) DESC, word_similarity($1, name) DESC, name ASC
+		LIMIT $3)
+		UNION ALL
+		(SELECT 'category', slug, name
+		FROM categories
+		WHERE is_active = true AND (name ILIKE $2 OR $1 <% name)
+		ORDER BY (name ILIKE $2) DESC, word_similarity($1, name) DESC, sort_order ASC
+		LIMIT $3)
+		UNION ALL
+		(SELECT 'query', query, query
+		FROM search_queries
+		WHERE query LIKE $4 AND last_result_count > 0
+		ORDER BY search_count DESC
+		LIMIT $3)`
+
+	likePrefix := escapeLike(prefix) + "%"
+	rows, err := r.db.QueryContext(ctx, query, prefix, likePrefix, limit, strings.ToLower(likePrefix))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := &models.SearchSuggestions{
+		Products:   []models.ProductSuggestion{},
+		Categories: []models.CategorySuggestion{},
+		Queries:    []string{},
+	}
+	for rows.Next() {
+		var kind, ref, label string
+		if err := rows.Scan(&kind, &ref, &label); err != nil {
+			return nil, err
+		}
+
+		switch kind {
+		case "product":
+			id, _ := strconv.Atoi(ref)
+			suggestions.Products = append(suggestions.Products, models.ProductSuggestion{ID: id, Name: label})
+		case "category":
+			suggestions.Categories = append(suggestions.Categories, models.CategorySuggestion{Name: label, Slug: ref})
+		case "query":
+			suggestions.Queries = append(suggestions.Queries, label)
+		}
+	}
+
+	return suggestions, rows.Err()
+}
+
+// RecordQuery logs a normalized search query together with how many results it returned
+func (r *searchRepository) RecordQuery(ctx context.Context, query string, resultCount int) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO search_queries (query, search_count, zero_result_count, last_result_count)
+		VALUES ($1, 1, CASE WHEN $2::int = 0 THEN 1 ELSE 0 END, $2::int)
+		ON CONFLICT (query) DO UPDATE SET
+			search_count = search_queries.search_count + 1,
+			zero_result_count = search_queries.zero_result_count + EXCLUDED.zero_result_count,
+			last_result_count = EXCLUDED.last_result_count,
+			last_searched_at = CURRENT_TIMESTAMP`,
+		query, resultCount)
+	return err
+}
+
+// GetZeroResultQueries lists queries that returned nothing, most frequent first
+func (r *searchRepository) GetZeroResultQueries(ctx context.Context, limit int) ([]models.SearchQueryStat, error) {
+	query := `
+		SELECT query, search_count, zero_result_count, last_result_count, first_searched_at, last_searched_at
+		FROM search_queries
+		WHERE zero_result_count > 0
+		ORDER BY zero_result_count DESC, last_searched_at DESC
+		LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []models.SearchQueryStat{}
+	for rows.Next() {
+		var s models.SearchQueryStat
+		err := rows.Scan(
+			&s.Query,
+			&s.SearchCount,
+			&s.ZeroResultCount,
+			&s.LastResultCount,
+			&s.FirstSearchedAt,
+			&s.LastSearchedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,501 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type ShipmentRepository interface {
+	GetOrder(ctx context.Context, orderID uuid.UUID) (*ShipmentOrder, error)
+	Create(ctx context.Context, shipment *models.Shipment, label *models.ShipmentLabel, events ...*models.DomainEvent) (bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Shipment, error)
+	GetByOrder(ctx context.Context, orderID uuid.UUID, vendorID *uuid.UUID) ([]*models.Shipment, error)
+	GetLabel(ctx context.Context, id uuid.UUID) (*models.ShipmentLabel, error)
+	GetTrackable(ctx context.Context, carriers []string, polledBefore time.Time, limit int) ([]*models.Shipment, error)
+	AddEvents(ctx context.Context, id uuid.UUID, events []models.ShipmentEvent, polledAt *time.Time) (models.OrderStatus, error)
+	Cancel(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// ShipmentOrder is what shipping an order needs to know about it: where it goes
+// and how much of each item is already in a shipment that was not cancelled
+type ShipmentOrder struct {
+	ID              uuid.UUID
+	OrderNumber     string
+	CustomerID      uuid.UUID
+	CustomerName    string
+	CustomerEmail   string
+	Status          models.OrderStatus
+	ShippingAddress models.Address
+	Items           []ShippableItem
+}
+
+type ShippableItem struct {
+	models.OrderItem
+	Shipped int
+}
+
+type shipmentRepository struct {
+	db *sql.DB
+}
+
+func NewShipmentRepository(db *sql.DB) ShipmentRepository {
+	return &shipmentRepository{db: db}
+}
+
+const shipmentColumns = `id, order_id, vendor_id, carrier, service, tracking_number, label_reference,
+	label_data IS NOT NULL, status, shipped_at, delivered_at, created_by, created_at, updated_at`
+
+// shippedQuantity is the quantity of order item oi in shipments that were not cancelled
+const shippedQuantity = `COALESCE((
+	SELECT SUM(si.quantity) FROM shipment_items si
+	JOIN shipments s ON s.id = si.shipment_id
+	WHERE si.order_item_id = oi.id AND s.status <> 'cancelled'), 0)`
+
+func (r *shipmentRepository) GetOrder(ctx context.Context, orderID uuid.UUID) (*ShipmentOrder, error) {
+	query := `
+		SELECT o.id, o.order_number, o.customer_id, TRIM(COALESCE(u.first_name, '') || ' ' || COALESCE(u.last_name, '')),
+			COALESCE(u.email, ''), o.status, o.shipping_address
+		FROM orders o
+		LEFT JOIN users u ON u.id = o.customer_id
+		WHERE o.id = $1`
+
+	order := &ShipmentOrder{}
+	err := r.db.QueryRowContext(ctx, query, orderID).Scan(
+		&order.ID, &order.OrderNumber, &order.CustomerID, &order.CustomerName, &order.CustomerEmail, &order.Status, &order.ShippingAddress,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	itemsQuery := fmt.Sprintf(`
+		SELECT oi.id, oi.order_id, oi.product_id, oi.vendor_id, oi.name, oi.price, oi.quantity, oi.total,
+			oi.created_at, %s
+		FROM order_items oi
+		WHERE oi.order_id = $1
+		ORDER BY oi.created_at`, shippedQuantity)
+
+	rows, err := r.db.QueryContext(ctx, itemsQuery, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item ShippableItem
+		if err := rows.Scan(
+			&item.ID, &item.OrderID, &item.ProductID, &item.VendorID, &item.Name, &item.Price, &item.Quantity,
+			&item.Total, &item.CreatedAt, &item.Shipped,
+		); err != nil {
+			return nil, err
+		}
+		order.Items = append(order.Items, item)
+	}
+
+	return order, rows.Err()
+}
+
+// Create saves a shipment with its items and label. The order row is locked while
+// quantities are checked, so it returns false without saving if another shipment
+// took any of the remaining quantity first.
+func (r *shipmentRepository) Create(ctx context.Context, shipment *models.Shipment, label *models.ShipmentLabel, events ...*models.DomainEvent) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM orders WHERE id = $1 FOR UPDATE", shipment.OrderID); err != nil {
+		return false, err
+	}
+
+	for _, item := range shipment.Items {
+		var available bool
+		query := fmt.Sprintf(`
+			SELECT oi.quantity - %s >= $3
+			FROM order_items oi
+			WHERE oi.id = $1 AND oi.order_id = $2`, shippedQuantity)
+
+		err := tx.QueryRowContext(ctx, query, item.OrderItemID, shipment.OrderID, item.Quantity).Scan(&available)
+		if err == sql.ErrNoRows || (err == nil && !available) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if shipment.ID == uuid.Nil {
+		shipment.ID = uuid.New()
+	}
+
+	var labelData []byte
+	var labelFormat *string
+	if label != nil && len(label.Data) > 0 {
+		labelData = label.Data
+		labelFormat = &label.ContentType
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO shipments (id, order_id, vendor_id, carrier, service, tracking_number, label_reference,
+			label_data, label_format, status, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING shipped_at, created_at, updated_at`,
+		shipment.ID, shipment.OrderID, shipment.VendorID, shipment.Carrier, shipment.Service,
+		shipment.TrackingNumber, shipment.LabelReference, labelData, labelFormat, shipment.Status, shipment.CreatedBy,
+	).Scan(&shipment.ShippedAt, &shipment.CreatedAt, &shipment.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	shipment.HasLabel = labelData != nil
+
+	for _, item := range shipment.Items {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO shipment_items (shipment_id, order_item_id, quantity) VALUES ($1, $2, $3)",
+			shipment.ID, item.OrderItemID, item.Quantity,
+		)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := syncOrderStatus(ctx, tx, shipment.OrderID); err != nil {
+		return false, err
+	}
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *shipmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Shipment, error) {
+	query := fmt.Sprintf("SELECT %s FROM shipments WHERE id = $1", shipmentColumns)
+
+	shipment, err := scanShipment(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.attachDetails(ctx, []*models.Shipment{shipment}); err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// GetByOrder returns an order's shipments, oldest first. vendorID limits them to
+// that vendor's shipments.
+func (r *shipmentRepository) GetByOrder(ctx context.Context, orderID uuid.UUID, vendorID *uuid.UUID) ([]*models.Shipment, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM shipments
+		WHERE order_id = $1 AND ($2::uuid IS NULL OR vendor_id = $2)
+		ORDER BY created_at`, shipmentColumns)
+
+	shipments, err := r.queryShipments(ctx, query, orderID, vendorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.attachDetails(ctx, shipments); err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+func (r *shipmentRepository) GetLabel(ctx context.Context, id uuid.UUID) (*models.ShipmentLabel, error) {
+	label := &models.ShipmentLabel{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT label_data, COALESCE(label_format, 'application/pdf')
+		FROM shipments
+		WHERE id = $1 AND label_data IS NOT NULL`, id).Scan(&label.Data, &label.ContentType)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// GetTrackable returns shipments with the given carriers that are still on their
+// way and were last polled before polledBefore, least recently polled first
+func (r *shipmentRepository) GetTrackable(ctx context.Context, carriers []string, polledBefore time.Time, limit int) ([]*models.Shipment, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM shipments
+		WHERE tracking_number IS NOT NULL
+			AND status NOT IN ('delivered', 'returned', 'cancelled')
+			AND carrier = ANY($1)
+			AND (last_polled_at IS NULL OR last_polled_at < $2)
+		ORDER BY last_polled_at NULLS FIRST
+		LIMIT $3`, shipmentColumns)
+
+	return r.queryShipments(ctx, query, pq.Array(carriers), polledBefore, limit)
+}
+
+// AddEvents records tracking events, skipping ones already recorded, and moves the
+// shipment to the status of its latest event. A delivered shipment also moves the
+// order to delivered once all of its items have been delivered. It returns the
+// order status afterwards, or "" when the shipment was cancelled and left alone.
+func (r *shipmentRepository) AddEvents(ctx context.Context, id uuid.UUID, events []models.ShipmentEvent, polledAt *time.Time) (models.OrderStatus, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var orderID uuid.UUID
+	var status models.ShipmentStatus
+	err = tx.QueryRowContext(ctx, "SELECT order_id, status FROM shipments WHERE id = $1 FOR UPDATE", id).Scan(&orderID, &status)
+	if err != nil {
+		return "", err
+	}
+	if status == models.ShipmentStatusCancelled {
+		return "", nil
+	}
+
+	for _, event := range events {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO shipment_events (id, shipment_id, status, code, description, location, occurred_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (shipment_id, occurred_at, code, description) DO NOTHING`,
+			uuid.New(), id, event.Status, event.Code, event.Description, event.Location, event.OccurredAt,
+		)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		WITH latest AS (
+			SELECT status FROM shipment_events
+			WHERE shipment_id = $1
+			ORDER BY occurred_at DESC, created_at DESC
+			LIMIT 1
+		)
+		UPDATE shipments SET
+			status = COALESCE((SELECT status FROM latest), status),
+			delivered_at = CASE WHEN (SELECT status FROM latest) = 'delivered' THEN (
+				SELECT MAX(occurred_at) FROM shipment_events WHERE shipment_id = $1 AND status = 'delivered'
+			) END,
+			last_polled_at = COALESCE($2, last_polled_at),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, id, polledAt)
+	if err != nil {
+		return "", err
+	}
+
+	orderStatus, err := syncOrderStatus(ctx, tx, orderID)
+	if err != nil {
+		return "", err
+	}
+	return orderStatus, tx.Commit()
+}
+
+// Cancel cancels a shipment that has not been delivered or returned, which makes
+// its items available to ship again. It returns false if it could not be cancelled.
+func (r *shipmentRepository) Cancel(ctx context.Context, id uuid.UUID) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var orderID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		UPDATE shipments SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status NOT IN ('delivered', 'returned', 'cancelled')
+		RETURNING order_id`, id).Scan(&orderID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := syncOrderStatus(ctx, tx, orderID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// syncOrderStatus moves a confirmed, processing or shipped order to shipped once
+// every item is in a shipment and to delivered once every item has been delivered;
+// a partly shipped order is processing. Other statuses are left alone. A change is
+// recorded as an order.status_changed event. It returns the order's status afterwards.
+func syncOrderStatus(ctx context.Context, tx *sql.Tx, orderID uuid.UUID) (models.OrderStatus, error) {
+	var current models.OrderStatus
+	if err := tx.QueryRowContext(ctx, "SELECT status FROM orders WHERE id = $1", orderID).Scan(&current); err != nil {
+		return "", err
+	}
+	switch current {
+	case models.OrderStatusConfirmed, models.OrderStatusProcessing, models.OrderStatusShipped:
+	default:
+		return current, nil
+	}
+
+	var shipped, delivered, started bool
+	err := tx.QueryRowContext(ctx, `
+		WITH progress AS (
+			SELECT oi.quantity,
+				COALESCE(SUM(si.quantity) FILTER (WHERE s.status <> 'cancelled'), 0) AS shipped,
+				COALESCE(SUM(si.quantity) FILTER (WHERE s.status = 'delivered'), 0) AS delivered
+			FROM order_items oi
+			LEFT JOIN shipment_items si ON si.order_item_id = oi.id
+			LEFT JOIN shipments s ON s.id = si.shipment_id
+			WHERE oi.order_id = $1
+			GROUP BY oi.id, oi.quantity
+		)
+		SELECT COALESCE(BOOL_AND(shipped >= quantity), FALSE),
+			COALESCE(BOOL_AND(delivered >= quantity), FALSE),
+			COALESCE(BOOL_OR(shipped > 0), FALSE)
+		FROM progress`, orderID).Scan(&shipped, &delivered, &started)
+	if err != nil {
+		return "", err
+	}
+
+	status := current
+	switch {
+	case delivered:
+		status = models.OrderStatusDelivered
+	case shipped:
+		status = models.OrderStatusShipped
+	case started || current == models.OrderStatusShipped:
+		status = models.OrderStatusProcessing
+	}
+	if status == current {
+		return current, nil
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE orders SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1", orderID, status)
+	if err != nil {
+		return "", err
+	}
+
+	payload := models.OrderStatusEventPayload{OrderID: orderID, From: current, To: status}
+	rows, err := tx.QueryContext(ctx, "SELECT DISTINCT vendor_id FROM order_items WHERE order_id = $1", orderID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var vendorID uuid.UUID
+		if err := rows.Scan(&vendorID); err != nil {
+			return "", err
+		}
+		payload.VendorIDs = append(payload.VendorIDs, vendorID)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	event, err := models.NewDomainEvent(models.EventOrderStatusChanged, "order", orderID.String(), payload)
+	if err != nil {
+		return "", err
+	}
+	return status, insertDomainEvents(ctx, tx, []*models.DomainEvent{event})
+}
+
+func (r *shipmentRepository) queryShipments(ctx context.Context, query string, args ...interface{}) ([]*models.Shipment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shipments := []*models.Shipment{}
+	for rows.Next() {
+		shipment, err := scanShipment(rows)
+		if err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, shipment)
+	}
+	return shipments, rows.Err()
+}
+
+// attachDetails loads the items and events of the shipments
+func (r *shipmentRepository) attachDetails(ctx context.Context, shipments []*models.Shipment) error {
+	if len(shipments) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(shipments))
+	byID := make(map[uuid.UUID]*models.Shipment, len(shipments))
+	for i, shipment := range shipments {
+		ids[i] = shipment.ID
+		shipment.Items = []models.ShipmentItem{}
+		shipment.Events = []models.ShipmentEvent{}
+		byID[shipment.ID] = shipment
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT si.shipment_id, si.order_item_id, oi.product_id, oi.name, si.quantity
+		FROM shipment_items si
+		JOIN order_items oi ON oi.id = si.order_item_id
+		WHERE si.shipment_id = ANY($1::uuid[])
+		ORDER BY oi.created_at`, pq.Array(uuidStrings(ids)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shipmentID uuid.UUID
+		var item models.ShipmentItem
+		if err := rows.Scan(&shipmentID, &item.OrderItemID, &item.ProductID, &item.Name, &item.Quantity); err != nil {
+			return err
+		}
+		byID[shipmentID].Items = append(byID[shipmentID].Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	eventRows, err := r.db.QueryContext(ctx, `
+		SELECT id, shipment_id, status, code, description, location, occurred_at, created_at
+		FROM shipment_events
+		WHERE shipment_id = ANY($1::uuid[])
+		ORDER BY occurred_at, created_at`, pq.Array(uuidStrings(ids)))
+	if err != nil {
+		return err
+	}
+	defer eventRows.Close()
+
+	for eventRows.Next() {
+		var event models.ShipmentEvent
+		if err := eventRows.Scan(
+			&event.ID, &event.ShipmentID, &event.Status, &event.Code, &event.Description, &event.Location,
+			&event.OccurredAt, &event.CreatedAt,
+		); err != nil {
+			return err
+		}
+		byID[event.ShipmentID].Events = append(byID[event.ShipmentID].Events, event)
+	}
+
+	return eventRows.Err()
+}
+
+func scanShipment(row interface{ Scan(...interface{}) error }) (*models.Shipment, error) {
+	shipment := &models.Shipment{}
+	err := row.Scan(
+		&shipment.ID, &shipment.OrderID, &shipment.VendorID, &shipment.Carrier, &shipment.Service,
+		&shipment.TrackingNumber, &shipment.LabelReference, &shipment.HasLabel, &shipment.Status,
+		&shipment.ShippedAt, &shipment.DeliveredAt, &shipment.CreatedBy, &shipment.CreatedAt, &shipment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
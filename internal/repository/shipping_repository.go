@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type ShippingRepository interface {
+	CreateZone(ctx context.Context, zone *models.ShippingZone) error
+	GetZone(ctx context.Context, id uuid.UUID) (*models.ShippingZone, error)
+	GetZones(ctx context.Context, activeOnly bool) ([]*models.ShippingZone, error)
+	UpdateZone(ctx context.Context, zone *models.ShippingZone) error
+	DeleteZone(ctx context.Context, id uuid.UUID) error
+	CreateMethod(ctx context.Context, method *models.ShippingMethod) error
+	GetMethod(ctx context.Context, id uuid.UUID) (*models.ShippingMethod, error)
+	UpdateMethod(ctx context.Context, method *models.ShippingMethod) error
+	DeleteMethod(ctx context.Context, id uuid.UUID) error
+}
+
+type shippingRepository struct {
+	db *sql.DB
+}
+
+func NewShippingRepository(db *sql.DB) ShippingRepository {
+	return &shippingRepository{db: db}
+}
+
+const shippingZoneColumns = `id, name, regions, priority, is_active, created_at, updated_at`
+
+const shippingMethodColumns = `id, zone_id, name, description, base_rate, rate_per_kg, min_weight,
+	max_weight, free_shipping_threshold, min_delivery_days, max_delivery_days, sort_order, is_active,
+	created_at, updated_at`
+
+func (r *shippingRepository) CreateZone(ctx context.Context, zone *models.ShippingZone) error {
+	query := `
+		INSERT INTO shipping_zones (id, name, regions, priority, is_active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, updated_at`
+
+	if zone.ID == uuid.Nil {
+		zone.ID = uuid.New()
+	}
+
+	return r.db.QueryRowContext(ctx, query, zone.ID, zone.Name, zone.Regions, zone.Priority, zone.IsActive).
+		Scan(&zone.CreatedAt, &zone.UpdatedAt)
+}
+
+// GetZone returns a zone with all of its methods
+func (r *shippingRepository) GetZone(ctx context.Context, id uuid.UUID) (*models.ShippingZone, error) {
+	query := fmt.Sprintf("SELECT %s FROM shipping_zones WHERE id = $1", shippingZoneColumns)
+
+	zone, err := scanShippingZone(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.attachMethods(ctx, []*models.ShippingZone{zone}, false); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+// GetZones returns zones in matching order with their methods. With activeOnly,
+// inactive zones and methods are left out.
+func (r *shippingRepository) GetZones(ctx context.Context, activeOnly bool) ([]*models.ShippingZone, error) {
+	whereClause := ""
+	if activeOnly {
+		whereClause = "WHERE is_active"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM shipping_zones
+		%s
+		ORDER BY priority, created_at`, shippingZoneColumns, whereClause)
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []*models.ShippingZone{}
+	for rows.Next() {
+		zone, err := scanShippingZone(rows)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, zone)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.attachMethods(ctx, zones, activeOnly); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+func (r *shippingRepository) UpdateZone(ctx context.Context, zone *models.ShippingZone) error {
+	query := `
+		UPDATE shipping_zones SET name = $2, regions = $3, priority = $4, is_active = $5,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query, zone.ID, zone.Name, zone.Regions, zone.Priority, zone.IsActive).
+		Scan(&zone.CreatedAt, &zone.UpdatedAt)
+}
+
+func (r *shippingRepository) DeleteZone(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM shipping_zones WHERE id = $1", id)
+	return err
+}
+
+func (r *shippingRepository) CreateMethod(ctx context.Context, method *models.ShippingMethod) error {
+	query := `
+		INSERT INTO shipping_methods (id, zone_id, name, description, base_rate, rate_per_kg, min_weight,
+			max_weight, free_shipping_threshold, min_delivery_days, max_delivery_days, sort_order, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING created_at, updated_at`
+
+	if method.ID == uuid.Nil {
+		method.ID = uuid.New()
+	}
+
+	return r.db.QueryRowContext(ctx, query,
+		method.ID, method.ZoneID, method.Name, method.Description, method.BaseRate, method.RatePerKg,
+		method.MinWeight, method.MaxWeight, method.FreeShippingThreshold, method.MinDeliveryDays,
+		method.MaxDeliveryDays, method.SortOrder, method.IsActive,
+	).Scan(&method.CreatedAt, &method.UpdatedAt)
+}
+
+func (r *shippingRepository) GetMethod(ctx context.Context, id uuid.UUID) (*models.ShippingMethod, error) {
+	query := fmt.Sprintf("SELECT %s FROM shipping_methods WHERE id = $1", shippingMethodColumns)
+
+	method, err := scanShippingMethod(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+func (r *shippingRepository) UpdateMethod(ctx context.Context, method *models.ShippingMethod) error {
+	query := `
+		UPDATE shipping_methods SET
+			name = $2, description = $3, base_rate = $4, rate_per_kg = $5, min_weight = $6,
+			max_weight = $7, free_shipping_threshold = $8, min_delivery_days = $9,
+			max_delivery_days = $10, sort_order = $11, is_active = $12, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING zone_id, created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		method.ID, method.Name, method.Description, method.BaseRate, method.RatePerKg, method.MinWeight,
+		method.MaxWeight, method.FreeShippingThreshold, method.MinDeliveryDays, method.MaxDeliveryDays,
+		method.SortOrder, method.IsActive,
+	).Scan(&method.ZoneID, &method.CreatedAt, &method.UpdatedAt)
+}
+
+func (r *shippingRepository) DeleteMethod(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM shipping_methods WHERE id = $1", id)
+	return err
+}
+
+// attachMethods loads the methods of the zones in one query
+func (r *shippingRepository) attachMethods(ctx context.Context, zones []*models.ShippingZone, activeOnly bool) error {
+	if len(zones) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(zones))
+	byID := make(map[uuid.UUID]*models.ShippingZone, len(zones))
+	for i, zone := range zones {
+		ids[i] = zone.ID
+		zone.Methods = []models.ShippingMethod{}
+		byID[zone.ID] = zone
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM shipping_methods
+		WHERE zone_id = ANY($1::uuid[]) AND (is_active OR NOT $2)
+		ORDER BY sort_order, created_at`, shippingMethodColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(uuidStrings(ids)), activeOnly)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		method, err := scanShippingMethod(rows)
+		if err != nil {
+			return err
+		}
+		zone := byID[method.ZoneID]
+		zone.Methods = append(zone.Methods, *method)
+	}
+
+	return rows.Err()
+}
+
+func scanShippingZone(row interface{ Scan(...interface{}) error }) (*models.ShippingZone, error) {
+	zone := &models.ShippingZone{}
+	err := row.Scan(&zone.ID, &zone.Name, &zone.Regions, &zone.Priority, &zone.IsActive, &zone.CreatedAt, &zone.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+func scanShippingMethod(row interface{ Scan(...interface{}) error }) (*models.ShippingMethod, error) {
+	method := &models.ShippingMethod{}
+	err := row.Scan(
+		&method.ID, &method.ZoneID, &method.Name, &method.Description, &method.BaseRate,
+		&method.RatePerKg, &method.MinWeight, &method.MaxWeight, &method.FreeShippingThreshold,
+		&method.MinDeliveryDays, &method.MaxDeliveryDays, &method.SortOrder, &method.IsActive,
+		&method.CreatedAt, &method.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return method, nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User, verifyTokenHash string, verifyExpiresAt time.Time, verifyEmail *models.Job) (bool, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	CreateToken(ctx context.Context, userID uuid.UUID, purpose models.TokenPurpose, tokenHash string, expiresAt time.Time, tokenEmail *models.Job) error
+	VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (bool, error)
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (bool, error)
+}
+
+type userRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+const userColumns = `id, email, password_hash, first_name, last_name, phone, role, status, avatar,
+	created_at, updated_at, last_login_at, email_verified_at`
+
+// Create inserts the user with its email lowercased, together with the token
+// verifying its address and the job emailing it, so an account is never left
+// without a way to verify it. It returns false if the email is already registered.
+func (r *userRepository) Create(ctx context.Context, user *models.User, verifyTokenHash string, verifyExpiresAt time.Time, verifyEmail *models.Job) (bool, error) {
+	query := `
+		INSERT INTO users (id, email, password_hash, first_name, last_name, phone, role, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (email) DO NOTHING
+		RETURNING created_at, updated_at`
+
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	user.Email = strings.ToLower(user.Email)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query,
+		user.ID, user.Email, user.Password, user.FirstName, user.LastName, user.Phone, user.Role, user.Status,
+	).Scan(&user.CreatedAt, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := insertToken(ctx, tx, user.ID, models.TokenPurposeVerifyEmail, verifyTokenHash, verifyExpiresAt); err != nil {
+		return false, err
+	}
+	if _, err := insertJob(ctx, tx, verifyEmail); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE email = $1"
+
+	var user models.User
+	err := r.db.QueryRowContext(ctx, query, strings.ToLower(email)).Scan(
+		&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName, &user.Phone,
+		&user.Role, &user.Status, &user.Avatar, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
+		&user.EmailVerifiedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateToken stores the hash of a token together with the job emailing it to
+// the user. Unused tokens of the same purpose are retired, so only the latest
+// link works.
+func (r *userRepository) CreateToken(ctx context.Context, userID uuid.UUID, purpose models.TokenPurpose, tokenHash string, expiresAt time.Time, tokenEmail *models.Job) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL",
+		userID, purpose,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := insertToken(ctx, tx, userID, purpose, tokenHash, expiresAt); err != nil {
+		return err
+	}
+	if _, err := insertJob(ctx, tx, tokenEmail); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertToken stores the hash of a token emailed to the user
+func insertToken(ctx context.Context, tx *sql.Tx, userID uuid.UUID, purpose models.TokenPurpose, tokenHash string, expiresAt time.Time) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+		userID, purpose, tokenHash, expiresAt,
+	)
+	return err
+}
+
+// VerifyEmail uses a verification token and marks its user's email verified.
+// It returns false if the token is unknown, used or expired.
+func (r *userRepository) VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	userID, err := useToken(ctx, tx, models.TokenPurposeVerifyEmail, tokenHash, now)
+	if err != nil || userID == nil {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE users SET email_verified_at = COALESCE(email_verified_at, $2), updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		*userID, now,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// ResetPassword uses a password reset token and sets its user's password. It
+// returns false if the token is unknown, used or expired.
+func (r *userRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	userID, err := useToken(ctx, tx, models.TokenPurposePasswordReset, tokenHash, now)
+	if err != nil || userID == nil {
+		return false, err
+	}
+
+	// Following the emailed link also proves the address
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users SET password_hash = $2, email_verified_at = COALESCE(email_verified_at, $3),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		*userID, passwordHash, now,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// useToken marks an unused, unexpired token as used and returns its user, or
+// nil if there is no such token
+func useToken(ctx context.Context, tx *sql.Tx, purpose models.TokenPurpose, tokenHash string, now time.Time) (*uuid.UUID, error) {
+	var userID uuid.UUID
+	err := tx.QueryRowContext(ctx, `
+		UPDATE user_tokens SET used_at = $3
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+		RETURNING user_id`,
+		tokenHash, purpose, now,
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &userID, nil
+}
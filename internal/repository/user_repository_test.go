@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+)
+
+func TestUserCreateStoresTokenAndEmailTogether(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t, "users", "user_tokens", "jobs")
+	repo := NewUserRepository(db)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	newUser := func() *models.User {
+		return &models.User{Email: "Asa@Example.com", Password: "hash", FirstName: "Åsa", LastName: "Berg", Role: models.RoleCustomer, Status: models.StatusActive}
+	}
+	newJob := func() *models.Job {
+		return &models.Job{Kind: "email.send", Payload: []byte(`{}`), MaxAttempts: 3}
+	}
+
+	user := newUser()
+	created, err := repo.Create(ctx, user, "hash-1", now.Add(48*time.Hour), newJob())
+	if err != nil || !created {
+		t.Fatalf("Create = %v, %v", created, err)
+	}
+	if verified, err := repo.VerifyEmail(ctx, "hash-1", now); err != nil || !verified {
+		t.Errorf("VerifyEmail = %v, %v", verified, err)
+	}
+
+	// A second registration of the address leaves no token or email behind
+	created, err = repo.Create(ctx, newUser(), "hash-2", now.Add(48*time.Hour), newJob())
+	if err != nil || created {
+		t.Fatalf("duplicate Create = %v, %v", created, err)
+	}
+	var tokens, jobs int
+	if err := db.QueryRowContext(ctx, "SELECT (SELECT COUNT(*) FROM user_tokens), (SELECT COUNT(*) FROM jobs)").Scan(&tokens, &jobs); err != nil {
+		t.Fatal(err)
+	}
+	if tokens != 1 || jobs != 1 {
+		t.Errorf("stored %d tokens and %d jobs, want 1 and 1", tokens, jobs)
+	}
+}
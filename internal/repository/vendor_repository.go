@@ -0,0 +1,415 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type VendorRepository interface {
+	Create(ctx context.Context, vendor *models.Vendor) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Vendor, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*models.Vendor, error)
+	GetAll(ctx context.Context, status string, page, limit int) ([]*models.Vendor, int, error)
+	Update(ctx context.Context, vendor *models.Vendor) error
+	Reapply(ctx context.Context, vendor *models.Vendor) (bool, error)
+	UpdateStatus(ctx context.Context, change *models.VendorStatusChange) ([]uuid.UUID, error)
+	MarkVerified(ctx context.Context, id uuid.UUID) error
+	GetStatusHistory(ctx context.Context, id uuid.UUID) ([]models.VendorStatusChange, error)
+	SetStripeAccount(ctx context.Context, id uuid.UUID, accountID *string) error
+}
+
+type vendorRepository struct {
+	db *sql.DB
+}
+
+func NewVendorRepository(db *sql.DB) VendorRepository {
+	return &vendorRepository{db: db}
+}
+
+const vendorColumns = `id, user_id, business_name, COALESCE(business_type, ''), description, logo, website,
+	address, organization_number, vat_id, bank_proof_url, status, status_reason, verified_at,
+	stripe_account_id, created_at, updated_at`
+
+func (r *vendorRepository) Create(ctx context.Context, vendor *models.Vendor) error {
+	query := `
+		INSERT INTO vendors (id, user_id, business_name, business_type, description, logo, website, address,
+			organization_number, vat_id, bank_proof_url, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING created_at, updated_at`
+
+	if vendor.ID == uuid.Nil {
+		vendor.ID = uuid.New()
+	}
+	if vendor.Documents == nil {
+		vendor.Documents = &models.VendorDocuments{}
+	}
+
+	return r.db.QueryRowContext(ctx, query,
+		vendor.ID, vendor.UserID, vendor.BusinessName, vendor.BusinessType, vendor.Description,
+		vendor.Logo, vendor.Website, vendor.Address, vendor.Documents.OrganizationNumber,
+		vendor.Documents.VATID, vendor.Documents.BankProofURL, vendor.Status,
+	).Scan(&vendor.CreatedAt, &vendor.UpdatedAt)
+}
+
+func (r *vendorRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Vendor, error) {
+	query := fmt.Sprintf("SELECT %s FROM vendors WHERE id = $1", vendorColumns)
+
+	return r.getOne(ctx, query, id)
+}
+
+func (r *vendorRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.Vendor, error) {
+	query := fmt.Sprintf("SELECT %s FROM vendors WHERE user_id = $1", vendorColumns)
+
+	return r.getOne(ctx, query, userID)
+}
+
+// GetAll lists vendors for the review queue, oldest first, optionally filtered by status
+func (r *vendorRepository) GetAll(ctx context.Context, status string, page, limit int) ([]*models.Vendor, int, error) {
+	whereClause := ""
+	args := []interface{}{}
+	if status != "" {
+		whereClause = "WHERE status = $1"
+		args = append(args, status)
+	}
+
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM vendors %s", whereClause)
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM vendors %s ORDER BY created_at ASC LIMIT %d OFFSET %d",
+		vendorColumns, whereClause, limit, (page-1)*limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	vendors := []*models.Vendor{}
+	for rows.Next() {
+		vendor, err := scanVendor(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		vendors = append(vendors, vendor)
+	}
+
+	return vendors, total, rows.Err()
+}
+
+// Update saves the vendor's editable profile fields. Changing any verification
+// document clears verified_at, and an approved vendor goes back to pending
+// review with the change recorded in its status history.
+func (r *vendorRepository) Update(ctx context.Context, vendor *models.Vendor) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if vendor.Documents == nil {
+		vendor.Documents = &models.VendorDocuments{}
+	}
+
+	var documentsChanged bool
+	var status models.VendorStatus
+	err = tx.QueryRowContext(ctx, `
+		SELECT (organization_number, vat_id, bank_proof_url) IS DISTINCT FROM ($2::varchar, $3::varchar, $4::text), status
+		FROM vendors WHERE id = $1 FOR UPDATE`,
+		vendor.ID, vendor.Documents.OrganizationNumber, vendor.Documents.VATID, vendor.Documents.BankProofURL,
+	).Scan(&documentsChanged, &status)
+	if err != nil {
+		return err
+	}
+
+	if err := updateProfile(ctx, tx, vendor); err != nil {
+		return err
+	}
+
+	if documentsChanged && status == models.VendorStatusApproved {
+		change := &models.VendorStatusChange{
+			VendorID:   vendor.ID,
+			FromStatus: status,
+			ToStatus:   models.VendorStatusPending,
+			Reason:     "Verification documents changed",
+		}
+		if err := recordStatusChange(ctx, tx, change); err != nil {
+			return err
+		}
+		vendor.Status = change.ToStatus
+		vendor.StatusReason = &change.Reason
+	}
+
+	return tx.Commit()
+}
+
+// Reapply replaces a rejected vendor's application and moves it back to
+// pending review. It returns false if the vendor is no longer rejected.
+func (r *vendorRepository) Reapply(ctx context.Context, vendor *models.Vendor) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	change := &models.VendorStatusChange{
+		VendorID: vendor.ID,
+		ToStatus: models.VendorStatusPending,
+		Reason:   "Vendor reapplied",
+	}
+	err = tx.QueryRowContext(ctx,
+		"SELECT status FROM vendors WHERE id = $1 FOR UPDATE",
+		vendor.ID,
+	).Scan(&change.FromStatus)
+	if err != nil {
+		return false, err
+	}
+	if change.FromStatus != models.VendorStatusRejected {
+		return false, nil
+	}
+
+	if vendor.Documents == nil {
+		vendor.Documents = &models.VendorDocuments{}
+	}
+	if err := updateProfile(ctx, tx, vendor); err != nil {
+		return false, err
+	}
+	if err := recordStatusChange(ctx, tx, change); err != nil {
+		return false, err
+	}
+	vendor.Status = change.ToStatus
+	vendor.StatusReason = &change.Reason
+
+	return true, tx.Commit()
+}
+
+// updateProfile writes the vendor's profile fields, clearing verified_at when
+// a verification document changes
+func updateProfile(ctx context.Context, tx *sql.Tx, vendor *models.Vendor) error {
+	query := `
+		UPDATE vendors SET
+			business_name = $2, business_type = $3, description = $4, logo = $5,
+			website = $6, address = $7, organization_number = $8, vat_id = $9, bank_proof_url = $10,
+			verified_at = CASE
+				WHEN (organization_number, vat_id, bank_proof_url) IS DISTINCT FROM ($8::varchar, $9::varchar, $10::text)
+				THEN NULL ELSE verified_at END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING verified_at, updated_at`
+
+	return tx.QueryRowContext(ctx, query,
+		vendor.ID, vendor.BusinessName, vendor.BusinessType, vendor.Description,
+		vendor.Logo, vendor.Website, vendor.Address, vendor.Documents.OrganizationNumber,
+		vendor.Documents.VATID, vendor.Documents.BankProofURL,
+	).Scan(&vendor.VerifiedAt, &vendor.UpdatedAt)
+}
+
+// recordStatusChange sets the vendor's status and appends the change to its
+// history. The caller has locked the vendor row and filled in FromStatus.
+func recordStatusChange(ctx context.Context, tx *sql.Tx, change *models.VendorStatusChange) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE vendors SET status = $2, status_reason = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		change.VendorID, change.ToStatus, change.Reason,
+	)
+	if err != nil {
+		return err
+	}
+
+	change.ID = uuid.New()
+	return tx.QueryRowContext(ctx, `
+		INSERT INTO vendor_status_history (id, vendor_id, from_status, to_status, reason, changed_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at`,
+		change.ID, change.VendorID, change.FromStatus, change.ToStatus, change.Reason, change.ChangedBy,
+	).Scan(&change.CreatedAt)
+}
+
+// UpdateStatus applies a review decision and records it in the status history.
+// Approving a vendor promotes its user to the vendor role and reactivates any
+// products hidden by an earlier suspension; suspending a vendor deactivates its
+// active products. It returns the IDs of products whose status changed, each
+// recorded as a product.updated event.
+func (r *vendorRepository) UpdateStatus(ctx context.Context, change *models.VendorStatusChange) ([]uuid.UUID, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	err = tx.QueryRowContext(ctx,
+		"SELECT user_id, status FROM vendors WHERE id = $1 FOR UPDATE",
+		change.VendorID,
+	).Scan(&userID, &change.FromStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordStatusChange(ctx, tx, change); err != nil {
+		return nil, err
+	}
+
+	var productQuery string
+	switch change.ToStatus {
+	case models.VendorStatusApproved:
+		_, err = tx.ExecContext(ctx,
+			"UPDATE users SET role = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND role = $3",
+			userID, models.RoleVendor, models.RoleCustomer,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		productQuery = `
+			WITH restored AS (
+				DELETE FROM vendor_suspended_products WHERE vendor_id = $1 RETURNING product_id
+			)
+			UPDATE products SET status = 'active', updated_at = CURRENT_TIMESTAMP
+			WHERE id IN (SELECT product_id FROM restored) AND status = 'inactive'
+			RETURNING id`
+	case models.VendorStatusSuspended:
+		productQuery = `
+			WITH deactivated AS (
+				UPDATE products SET status = 'inactive', updated_at = CURRENT_TIMESTAMP
+				WHERE vendor_id = $1 AND status = 'active'
+				RETURNING id
+			)
+			INSERT INTO vendor_suspended_products (vendor_id, product_id)
+			SELECT $1, id FROM deactivated
+			ON CONFLICT DO NOTHING
+			RETURNING product_id`
+	}
+
+	changed := []uuid.UUID{}
+	if productQuery != "" {
+		rows, err := tx.QueryContext(ctx, productQuery, change.VendorID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			changed = append(changed, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if err := insertProductUpdatedEvents(ctx, tx, changed); err != nil {
+		return nil, err
+	}
+
+	return changed, tx.Commit()
+}
+
+// MarkVerified records that the vendor's documents have been checked
+func (r *vendorRepository) MarkVerified(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE vendors SET verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// SetStripeAccount sets the connected Stripe account that receives the vendor's payouts
+func (r *vendorRepository) SetStripeAccount(ctx context.Context, id uuid.UUID, accountID *string) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE vendors SET stripe_account_id = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		id, accountID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetStatusHistory returns the vendor's review decisions, newest first
+func (r *vendorRepository) GetStatusHistory(ctx context.Context, id uuid.UUID) ([]models.VendorStatusChange, error) {
+	query := `
+		SELECT id, vendor_id, from_status, to_status, reason, changed_by, created_at
+		FROM vendor_status_history
+		WHERE vendor_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.VendorStatusChange{}
+	for rows.Next() {
+		var change models.VendorStatusChange
+		err := rows.Scan(
+			&change.ID, &change.VendorID, &change.FromStatus, &change.ToStatus,
+			&change.Reason, &change.ChangedBy, &change.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, change)
+	}
+
+	return history, rows.Err()
+}
+
+func (r *vendorRepository) getOne(ctx context.Context, query string, arg interface{}) (*models.Vendor, error) {
+	vendor, err := scanVendor(r.db.QueryRowContext(ctx, query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return vendor, nil
+}
+
+// scanVendor reads a row selected with vendorColumns
+func scanVendor(row interface{ Scan(...interface{}) error }) (*models.Vendor, error) {
+	vendor := &models.Vendor{Documents: &models.VendorDocuments{}}
+	err := row.Scan(
+		&vendor.ID, &vendor.UserID, &vendor.BusinessName, &vendor.BusinessType,
+		&vendor.Description, &vendor.Logo, &vendor.Website, &vendor.Address,
+		&vendor.Documents.OrganizationNumber, &vendor.Documents.VATID, &vendor.Documents.BankProofURL,
+		&vendor.Status, &vendor.StatusReason, &vendor.VerifiedAt, &vendor.StripeAccountID,
+		&vendor.CreatedAt, &vendor.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return vendor, nil
+}
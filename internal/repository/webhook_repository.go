@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, endpoint *models.WebhookEndpoint) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.WebhookEndpoint, error)
+	GetAll(ctx context.Context, vendorID *uuid.UUID, page, limit int) ([]*models.WebhookEndpoint, int, error)
+	GetSubscribed(ctx context.Context, eventType models.EventType, vendorIDs []uuid.UUID) ([]*models.WebhookEndpoint, error)
+	Update(ctx context.Context, endpoint *models.WebhookEndpoint) error
+	UpdateSecret(ctx context.Context, endpoint *models.WebhookEndpoint) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery, disableAfter int) (bool, error)
+	GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, endpointID uuid.UUID, page, limit int) ([]*models.WebhookDelivery, int, error)
+}
+
+type webhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+const webhookEndpointColumns = `id, vendor_id, url, description, secret, event_types, is_active,
+	consecutive_failures, disabled_at, disabled_reason, created_by, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, endpoint_id, event_id, event_type, attempt, redelivery, success,
+	response_status, response_body, error, duration_ms, created_at`
+
+func (r *webhookRepository) Create(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	if endpoint.ID == uuid.Nil {
+		endpoint.ID = uuid.New()
+	}
+
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO webhook_endpoints (id, vendor_id, url, description, secret, event_types, is_active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING consecutive_failures, created_at, updated_at`,
+		endpoint.ID, endpoint.VendorID, endpoint.URL, endpoint.Description, endpoint.Secret,
+		pq.Array(eventTypeStrings(endpoint.EventTypes)), endpoint.IsActive, endpoint.CreatedBy,
+	).Scan(&endpoint.ConsecutiveFailures, &endpoint.CreatedAt, &endpoint.UpdatedAt)
+}
+
+func (r *webhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.WebhookEndpoint, error) {
+	query := fmt.Sprintf("SELECT %s FROM webhook_endpoints WHERE id = $1", webhookEndpointColumns)
+	endpoint, err := scanWebhookEndpoint(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// GetAll pages through endpoints, newest first, optionally only those of one vendor
+func (r *webhookRepository) GetAll(ctx context.Context, vendorID *uuid.UUID, page, limit int) ([]*models.WebhookEndpoint, int, error) {
+	where := "WHERE ($1::uuid IS NULL OR vendor_id = $1)"
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_endpoints "+where, vendorID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM webhook_endpoints
+		%s
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`, webhookEndpointColumns, where)
+
+	endpoints, err := r.queryEndpoints(ctx, query, vendorID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return endpoints, total, nil
+}
+
+// GetSubscribed returns the active endpoints that want an event of the given type
+// about the given vendors: platform endpoints and those of the vendors
+func (r *webhookRepository) GetSubscribed(ctx context.Context, eventType models.EventType, vendorIDs []uuid.UUID) ([]*models.WebhookEndpoint, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM webhook_endpoints
+		WHERE is_active
+			AND (cardinality(event_types) = 0 OR $1 = ANY(event_types))
+			AND (vendor_id IS NULL OR vendor_id = ANY($2::uuid[]))
+		ORDER BY created_at`, webhookEndpointColumns)
+
+	return r.queryEndpoints(ctx, query, eventType, pq.Array(uuidStrings(vendorIDs)))
+}
+
+// Update saves an endpoint's settings. Activating a disabled endpoint clears its
+// failures, so it gets a fresh start.
+func (r *webhookRepository) Update(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	query := fmt.Sprintf(`
+		UPDATE webhook_endpoints
+		SET url = $2, description = $3, event_types = $4,
+			consecutive_failures = CASE WHEN $5 AND NOT is_active THEN 0 ELSE consecutive_failures END,
+			disabled_at = CASE WHEN $5 THEN NULL ELSE disabled_at END,
+			disabled_reason = CASE WHEN $5 THEN NULL ELSE disabled_reason END,
+			is_active = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING %s`, webhookEndpointColumns)
+
+	updated, err := scanWebhookEndpoint(r.db.QueryRowContext(ctx, query,
+		endpoint.ID, endpoint.URL, endpoint.Description, pq.Array(eventTypeStrings(endpoint.EventTypes)), endpoint.IsActive,
+	))
+	if err != nil {
+		return err
+	}
+	*endpoint = *updated
+	return nil
+}
+
+// UpdateSecret replaces the endpoint's signing secret
+func (r *webhookRepository) UpdateSecret(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	query := fmt.Sprintf(`
+		UPDATE webhook_endpoints
+		SET secret = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING %s`, webhookEndpointColumns)
+
+	updated, err := scanWebhookEndpoint(r.db.QueryRowContext(ctx, query, endpoint.ID, endpoint.Secret))
+	if err != nil {
+		return err
+	}
+	*endpoint = *updated
+	return nil
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM webhook_endpoints WHERE id = $1", id)
+	return err
+}
+
+// RecordDelivery logs a delivery attempt and tracks the endpoint's consecutive
+// failures. A success resets them; the failure that reaches disableAfter
+// deactivates the endpoint, in which case it returns true.
+func (r *webhookRepository) RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery, disableAfter int) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if delivery.ID == uuid.Nil {
+		delivery.ID = uuid.New()
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries (id, endpoint_id, event_id, event_type, attempt, redelivery, success,
+			response_status, response_body, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING created_at`,
+		delivery.ID, delivery.EndpointID, delivery.EventID, delivery.EventType, delivery.Attempt, delivery.Redelivery,
+		delivery.Success, delivery.ResponseStatus, delivery.ResponseBody, delivery.Error, delivery.DurationMs,
+	).Scan(&delivery.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	if delivery.Success {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE webhook_endpoints SET consecutive_failures = 0
+			WHERE id = $1 AND consecutive_failures > 0`, delivery.EndpointID)
+		if err != nil {
+			return false, err
+		}
+		return false, tx.Commit()
+	}
+
+	var disabled bool
+	err = tx.QueryRowContext(ctx, `
+		UPDATE webhook_endpoints w
+		SET consecutive_failures = w.consecutive_failures + 1,
+			is_active = w.is_active AND w.consecutive_failures + 1 < $2,
+			disabled_at = CASE WHEN w.is_active AND w.consecutive_failures + 1 >= $2 THEN CURRENT_TIMESTAMP ELSE w.disabled_at END,
+			disabled_reason = CASE WHEN w.is_active AND w.consecutive_failures + 1 >= $2 THEN $3 ELSE w.disabled_reason END,
+			updated_at = CURRENT_TIMESTAMP
+		FROM (SELECT id, is_active FROM webhook_endpoints WHERE id = $1 FOR UPDATE) old
+		WHERE w.id = old.id
+		RETURNING old.is_active AND NOT w.is_active`,
+		delivery.EndpointID, disableAfter, fmt.Sprintf("Disabled after %d consecutive failed deliveries", disableAfter),
+	).Scan(&disabled)
+	if err != nil {
+		return false, err
+	}
+	return disabled, tx.Commit()
+}
+
+func (r *webhookRepository) GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	query := fmt.Sprintf("SELECT %s FROM webhook_deliveries WHERE id = $1", webhookDeliveryColumns)
+	delivery, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// GetDeliveries pages through an endpoint's delivery log, newest first
+func (r *webhookRepository) GetDeliveries(ctx context.Context, endpointID uuid.UUID, page, limit int) ([]*models.WebhookDelivery, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_deliveries WHERE endpoint_id = $1", endpointID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM webhook_deliveries
+		WHERE endpoint_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`, webhookDeliveryColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, endpointID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, total, rows.Err()
+}
+
+func (r *webhookRepository) queryEndpoints(ctx context.Context, query string, args ...interface{}) ([]*models.WebhookEndpoint, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []*models.WebhookEndpoint{}
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+func scanWebhookEndpoint(row interface{ Scan(...interface{}) error }) (*models.WebhookEndpoint, error) {
+	endpoint := &models.WebhookEndpoint{}
+	var eventTypes []string
+	err := row.Scan(
+		&endpoint.ID, &endpoint.VendorID, &endpoint.URL, &endpoint.Description, &endpoint.Secret,
+		pq.Array(&eventTypes), &endpoint.IsActive, &endpoint.ConsecutiveFailures, &endpoint.DisabledAt,
+		&endpoint.DisabledReason, &endpoint.CreatedBy, &endpoint.CreatedAt, &endpoint.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.EventTypes = make([]models.EventType, len(eventTypes))
+	for i, t := range eventTypes {
+		endpoint.EventTypes[i] = models.EventType(t)
+	}
+	return endpoint, nil
+}
+
+func scanWebhookDelivery(row interface{ Scan(...interface{}) error }) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	err := row.Scan(
+		&delivery.ID, &delivery.EndpointID, &delivery.EventID, &delivery.EventType, &delivery.Attempt,
+		&delivery.Redelivery, &delivery.Success, &delivery.ResponseStatus, &delivery.ResponseBody, &delivery.Error,
+		&delivery.DurationMs, &delivery.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func eventTypeStrings(types []models.EventType) []string {
+	strs := make([]string, len(types))
+	for i, t := range types {
+		strs[i] = string(t)
+	}
+	return strs
+}
@@ -0,0 +1,327 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type WishlistRepository interface {
+	Create(ctx context.Context, wishlist *models.Wishlist) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Wishlist, error)
+	GetByShareToken(ctx context.Context, token string) (*models.Wishlist, error)
+	GetByUser(ctx context.Context, userID uuid.UUID) ([]*models.Wishlist, error)
+	GetOrCreateDefault(ctx context.Context, userID uuid.UUID, name string) (*models.Wishlist, error)
+	Update(ctx context.Context, wishlist *models.Wishlist) error
+	SetDefault(ctx context.Context, userID, id uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	GetItems(ctx context.Context, wishlistID uuid.UUID) ([]models.WishlistItem, error)
+	AddItem(ctx context.Context, wishlistID, productID uuid.UUID, price float64) error
+	RemoveItem(ctx context.Context, wishlistID, productID uuid.UUID) (bool, error)
+	MoveToCart(ctx context.Context, userID, wishlistID, productID uuid.UUID, quantity int) (*models.CartItem, error)
+	MoveFromCart(ctx context.Context, userID, wishlistID, productID uuid.UUID, price float64) (bool, error)
+}
+
+type wishlistRepository struct {
+	db *sql.DB
+}
+
+func NewWishlistRepository(db *sql.DB) WishlistRepository {
+	return &wishlistRepository{db: db}
+}
+
+const wishlistColumns = `w.id, w.user_id, w.name, w.is_default, w.share_token,
+	(SELECT COUNT(*) FROM wishlist_items wi WHERE wi.wishlist_id = w.id),
+	w.created_at, w.updated_at`
+
+func (r *wishlistRepository) Create(ctx context.Context, wishlist *models.Wishlist) error {
+	query := `
+		INSERT INTO wishlists (id, user_id, name, is_default)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at, updated_at`
+
+	if wishlist.ID == uuid.Nil {
+		wishlist.ID = uuid.New()
+	}
+
+	return r.db.QueryRowContext(ctx, query,
+		wishlist.ID, wishlist.UserID, wishlist.Name, wishlist.IsDefault,
+	).Scan(&wishlist.CreatedAt, &wishlist.UpdatedAt)
+}
+
+func (r *wishlistRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Wishlist, error) {
+	query := fmt.Sprintf("SELECT %s FROM wishlists w WHERE w.id = $1", wishlistColumns)
+	return r.getOne(ctx, query, id)
+}
+
+func (r *wishlistRepository) GetByShareToken(ctx context.Context, token string) (*models.Wishlist, error) {
+	query := fmt.Sprintf("SELECT %s FROM wishlists w WHERE w.share_token = $1", wishlistColumns)
+	return r.getOne(ctx, query, token)
+}
+
+// GetByUser returns the user's wishlists, default first and then by name
+func (r *wishlistRepository) GetByUser(ctx context.Context, userID uuid.UUID) ([]*models.Wishlist, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM wishlists w
+		WHERE w.user_id = $1
+		ORDER BY w.is_default DESC, LOWER(w.name)`, wishlistColumns)
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wishlists := []*models.Wishlist{}
+	for rows.Next() {
+		wishlist, err := scanWishlist(rows)
+		if err != nil {
+			return nil, err
+		}
+		wishlists = append(wishlists, wishlist)
+	}
+
+	return wishlists, rows.Err()
+}
+
+// GetOrCreateDefault returns the user's default wishlist, creating it with the given name if needed
+func (r *wishlistRepository) GetOrCreateDefault(ctx context.Context, userID uuid.UUID, name string) (*models.Wishlist, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO wishlists (id, user_id, name, is_default)
+		VALUES ($1, $2, $3, TRUE)
+		ON CONFLICT DO NOTHING`,
+		uuid.New(), userID, name,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM wishlists w WHERE w.user_id = $1 AND w.is_default", wishlistColumns)
+	return r.getOne(ctx, query, userID)
+}
+
+// Update saves the wishlist's name and share token
+func (r *wishlistRepository) Update(ctx context.Context, wishlist *models.Wishlist) error {
+	query := `
+		UPDATE wishlists SET name = $2, share_token = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`
+
+	return r.db.QueryRowContext(ctx, query, wishlist.ID, wishlist.Name, wishlist.ShareToken).Scan(&wishlist.UpdatedAt)
+}
+
+// SetDefault makes the wishlist the user's default in place of the current one
+func (r *wishlistRepository) SetDefault(ctx context.Context, userID, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE wishlists SET is_default = FALSE, updated_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND is_default AND id <> $2",
+		userID, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE wishlists SET is_default = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2",
+		id, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *wishlistRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM wishlists WHERE id = $1", id)
+	return err
+}
+
+// GetItems returns the wishlist's items with their products' current price and stock, newest first
+func (r *wishlistRepository) GetItems(ctx context.Context, wishlistID uuid.UUID) ([]models.WishlistItem, error) {
+	query := `
+		SELECT wi.id, wi.wishlist_id, wi.product_id, p.numeric_id, p.name, p.images, p.status,
+			p.price, wi.price_when_added, p.stock, wi.created_at
+		FROM wishlist_items wi
+		JOIN products p ON p.id = wi.product_id
+		WHERE wi.wishlist_id = $1
+		ORDER BY wi.created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, wishlistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.WishlistItem{}
+	for rows.Next() {
+		var item models.WishlistItem
+		var images []string
+		err := rows.Scan(
+			&item.ID, &item.WishlistID, &item.ProductID, &item.ProductNumericID, &item.Name,
+			pq.Array(&images), &item.Status, &item.Price, &item.PriceWhenAdded, &item.Stock, &item.AddedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(images) > 0 {
+			item.Image = &images[0]
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// AddItem saves a product to the wishlist at its current price. Adding it again keeps the original price.
+func (r *wishlistRepository) AddItem(ctx context.Context, wishlistID, productID uuid.UUID, price float64) error {
+	query := `
+		INSERT INTO wishlist_items (id, wishlist_id, product_id, price_when_added)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (wishlist_id, product_id) DO NOTHING`
+
+	_, err := r.db.ExecContext(ctx, query, uuid.New(), wishlistID, productID, price)
+	return err
+}
+
+// RemoveItem removes a product from the wishlist, returning false if it was not there
+func (r *wishlistRepository) RemoveItem(ctx context.Context, wishlistID, productID uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM wishlist_items WHERE wishlist_id = $1 AND product_id = $2",
+		wishlistID, productID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// MoveToCart removes a product from the wishlist and adds the quantity to the
+// user's cart, creating the cart if needed. It returns nil if the product is
+// not in the wishlist.
+func (r *wishlistRepository) MoveToCart(ctx context.Context, userID, wishlistID, productID uuid.UUID, quantity int) (*models.CartItem, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM wishlist_items WHERE wishlist_id = $1 AND product_id = $2",
+		wishlistID, productID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return nil, err
+	}
+
+	cartID, err := customerCartID(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &models.CartItem{ID: uuid.New(), CartID: cartID, ProductID: productID}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO cart_items (id, cart_id, product_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (cart_id, product_id) DO UPDATE SET
+			quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP
+		RETURNING id, quantity, created_at, updated_at`,
+		item.ID, cartID, productID, quantity,
+	).Scan(&item.ID, &item.Quantity, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, tx.Commit()
+}
+
+// MoveFromCart removes a product from the user's cart and saves it to the
+// wishlist, returning false if it was not in the cart
+func (r *wishlistRepository) MoveFromCart(ctx context.Context, userID, wishlistID, productID uuid.UUID, price float64) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM cart_items ci
+		USING carts c
+		WHERE ci.cart_id = c.id AND c.customer_id = $1 AND ci.product_id = $2`,
+		userID, productID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO wishlist_items (id, wishlist_id, product_id, price_when_added)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (wishlist_id, product_id) DO NOTHING`,
+		uuid.New(), wishlistID, productID, price,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// customerCartID returns the ID of the customer's cart, creating one if they have none
+func customerCartID(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (uuid.UUID, error) {
+	var cartID uuid.UUID
+	err := tx.QueryRowContext(ctx,
+		"SELECT id FROM carts WHERE customer_id = $1 ORDER BY created_at LIMIT 1 FOR UPDATE",
+		userID,
+	).Scan(&cartID)
+	if err == sql.ErrNoRows {
+		cartID = uuid.New()
+		_, err = tx.ExecContext(ctx, "INSERT INTO carts (id, customer_id) VALUES ($1, $2)", cartID, userID)
+	}
+
+	return cartID, err
+}
+
+func (r *wishlistRepository) getOne(ctx context.Context, query string, arg interface{}) (*models.Wishlist, error) {
+	wishlist, err := scanWishlist(r.db.QueryRowContext(ctx, query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return wishlist, nil
+}
+
+func scanWishlist(row interface{ Scan(...interface{}) error }) (*models.Wishlist, error) {
+	wishlist := &models.Wishlist{}
+	err := row.Scan(
+		&wishlist.ID, &wishlist.UserID, &wishlist.Name, &wishlist.IsDefault, &wishlist.ShareToken,
+		&wishlist.ItemCount, &wishlist.CreatedAt, &wishlist.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return wishlist, nil
+}
@@ -0,0 +1,47 @@
+package search
+
+import (
+	"context"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Index is the SearchIndex used by the product service. Implementations are
+// kept in sync as products are created, updated and deleted, and can be rebuilt
+// from the products table.
+type Index interface {
+	Index(product *models.Product) error
+	Delete(id uuid.UUID) error
+	Query(ctx context.Context, q Query) (*Result, error)
+	Rebuild(products []*models.Product) error
+}
+
+// Query describes a full-text search with filters and requested facets
+type Query struct {
+	Text    string
+	Filters repository.ProductFilters
+	Facets  []string // supported: "category", "price"
+}
+
+// Result holds one page of matches ordered by relevance unless a sort was requested
+type Result struct {
+	Products []*models.Product
+	Total    int
+	Facets   map[string][]models.FacetCount
+}
+
+const (
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
+// New returns the index for the configured backend, defaulting to Postgres
+func New(backend string, repo repository.ProductRepository) Index {
+	if backend == BackendMemory {
+		return NewMemoryIndex()
+	}
+	return NewPostgresIndex(repo)
+}
@@ -0,0 +1,358 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Field weights mirror the Postgres search vector: name A, tags/SKU B, description C
+const (
+	nameWeight        = 3.0
+	tagWeight         = 2.0
+	descriptionWeight = 1.0
+
+	// fuzzyPenalty scales the score of terms matched within one edit
+	fuzzyPenalty = 0.5
+)
+
+// MemoryIndex is an embedded in-process inverted index over products. It needs
+// no database, which makes it suitable for tests and for running search
+// separately from Postgres. Each process holds its own copy, which the product
+// service keeps current by following product events.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[uuid.UUID]*models.Product
+	postings map[string]map[uuid.UUID]float64 // term -> product -> weighted term frequency
+	terms    map[uuid.UUID][]string           // product -> indexed terms, for removal
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     make(map[uuid.UUID]*models.Product),
+		postings: make(map[string]map[uuid.UUID]float64),
+		terms:    make(map[uuid.UUID][]string),
+	}
+}
+
+func (i *MemoryIndex) Index(product *models.Product) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.index(product)
+	return nil
+}
+
+func (i *MemoryIndex) Delete(id uuid.UUID) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(id)
+	return nil
+}
+
+func (i *MemoryIndex) Rebuild(products []*models.Product) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.docs = make(map[uuid.UUID]*models.Product, len(products))
+	i.postings = make(map[string]map[uuid.UUID]float64)
+	i.terms = make(map[uuid.UUID][]string, len(products))
+	for _, product := range products {
+		i.index(product)
+	}
+	return nil
+}
+
+func (i *MemoryIndex) Query(_ context.Context, q Query) (*Result, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	scores := i.match(tokenize(q.Text))
+
+	var matches []*models.Product
+	for id, score := range scores {
+		doc := i.docs[id]
+		if !matchesFilters(doc, q.Filters) {
+			continue
+		}
+		product := *doc
+		relevance := score
+		product.Relevance = &relevance
+		matches = append(matches, &product)
+	}
+
+	sortProducts(matches, q.Filters)
+
+	result := &Result{Products: paginate(matches, q.Filters), Total: len(matches)}
+	if len(q.Facets) > 0 {
+		result.Facets = facetCounts(matches, q.Facets)
+	}
+
+	return result, nil
+}
+
+func (i *MemoryIndex) index(product *models.Product) {
+	i.remove(product.ID)
+
+	weights := make(map[string]float64)
+	addTerms := func(text string, weight float64) {
+		for _, term := range tokenize(text) {
+			weights[term] += weight
+		}
+	}
+
+	addTerms(product.Name, nameWeight)
+	addTerms(strings.Join(product.Tags, " "), tagWeight)
+	if product.SKU != nil {
+		addTerms(*product.SKU, tagWeight)
+	}
+	addTerms(product.Description, descriptionWeight)
+
+	terms := make([]string, 0, len(weights))
+	for term, weight := range weights {
+		if i.postings[term] == nil {
+			i.postings[term] = make(map[uuid.UUID]float64)
+		}
+		i.postings[term][product.ID] = weight
+		terms = append(terms, term)
+	}
+
+	doc := *product
+	doc.Relevance = nil
+	i.docs[product.ID] = &doc
+	i.terms[product.ID] = terms
+}
+
+func (i *MemoryIndex) remove(id uuid.UUID) {
+	for _, term := range i.terms[id] {
+		delete(i.postings[term], id)
+		if len(i.postings[term]) == 0 {
+			delete(i.postings, term)
+		}
+	}
+	delete(i.terms, id)
+	delete(i.docs, id)
+}
+
+// match scores products containing every query term. A term with no exact
+// postings falls back to indexed terms within one edit (or, for the last
+// term, terms it is a prefix of) so typos and partial words still match.
+func (i *MemoryIndex) match(queryTerms []string) map[uuid.UUID]float64 {
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	total := float64(len(i.docs))
+	var scores map[uuid.UUID]float64
+
+	for n, queryTerm := range queryTerms {
+		expansions := map[string]float64{}
+		if _, ok := i.postings[queryTerm]; ok {
+			expansions[queryTerm] = 1
+		} else {
+			for term := range i.postings {
+				switch {
+				case n == len(queryTerms)-1 && strings.HasPrefix(term, queryTerm):
+					expansions[term] = fuzzyPenalty
+				case len([]rune(queryTerm)) >= 4 && withinOneEdit(queryTerm, term):
+					expansions[term] = fuzzyPenalty
+				}
+			}
+		}
+
+		termScores := map[uuid.UUID]float64{}
+		for term, factor := range expansions {
+			docs := i.postings[term]
+			idf := math.Log(1 + total/float64(len(docs)))
+			for id, weight := range docs {
+				if s := weight * idf * factor; s > termScores[id] {
+					termScores[id] = s
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for id := range scores {
+			if s, ok := termScores[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	return scores
+}
+
+// tokenize lowercases text and splits it on anything that is not a letter or digit
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// withinOneEdit reports whether a and b differ by at most one insertion, deletion or substitution
+func withinOneEdit(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) > len(rb) {
+		ra, rb = rb, ra
+	}
+	if len(rb)-len(ra) > 1 {
+		return false
+	}
+
+	x, y, edits := 0, 0, 0
+	for x < len(ra) && y < len(rb) {
+		if ra[x] == rb[y] {
+			x++
+			y++
+			continue
+		}
+		edits++
+		if edits > 1 {
+			return false
+		}
+		if len(ra) == len(rb) {
+			x++
+		}
+		y++
+	}
+
+	// Any unmatched tail counts as further edits
+	edits += len(ra) - x + len(rb) - y
+	return edits <= 1
+}
+
+func matchesFilters(p *models.Product, f repository.ProductFilters) bool {
+	if f.Category != "" && p.Category != f.Category {
+		return false
+	}
+	if len(f.CategoryIDs) > 0 && !containsID(f.CategoryIDs, p.CategoryID) {
+		return false
+	}
+	if f.Status != "" && string(p.Status) != f.Status {
+		return false
+	}
+	if f.Featured != nil && p.Featured != *f.Featured {
+		return false
+	}
+	if f.MinPrice != nil && p.Price < *f.MinPrice {
+		return false
+	}
+	if f.MaxPrice != nil && p.Price > *f.MaxPrice {
+		return false
+	}
+	return true
+}
+
+func containsID(ids []uuid.UUID, id *uuid.UUID) bool {
+	if id == nil {
+		return false
+	}
+	for _, candidate := range ids {
+		if candidate == *id {
+			return true
+		}
+	}
+	return false
+}
+
+func sortProducts(products []*models.Product, f repository.ProductFilters) {
+	desc := f.SortDir == "desc"
+	less := func(a, b *models.Product) bool {
+		switch f.SortBy {
+		case "name":
+			if desc {
+				return a.Name > b.Name
+			}
+			return a.Name < b.Name
+		case "price":
+			if desc {
+				return a.Price > b.Price
+			}
+			return a.Price < b.Price
+		case "created_at":
+			if desc {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+			return a.CreatedAt.Before(b.CreatedAt)
+		case "updated_at":
+			if desc {
+				return a.UpdatedAt.After(b.UpdatedAt)
+			}
+			return a.UpdatedAt.Before(b.UpdatedAt)
+		default:
+			if *a.Relevance != *b.Relevance {
+				return *a.Relevance > *b.Relevance
+			}
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+	}
+
+	sort.SliceStable(products, func(x, y int) bool {
+		return less(products[x], products[y])
+	})
+}
+
+func paginate(products []*models.Product, f repository.ProductFilters) []*models.Product {
+	limit, page := f.Limit, f.Page
+	if limit <= 0 {
+		limit = 20
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	start := (page - 1) * limit
+	if start >= len(products) {
+		return []*models.Product{}
+	}
+	end := start + limit
+	if end > len(products) {
+		end = len(products)
+	}
+	return products[start:end]
+}
+
+func facetCounts(products []*models.Product, names []string) map[string][]models.FacetCount {
+	facets := map[string][]models.FacetCount{}
+	for _, name := range names {
+		counts := map[string]int{}
+		for _, p := range products {
+			switch name {
+			case "category":
+				counts[p.Category]++
+			case "price":
+				counts[models.PriceRangeLabel(p.Price)]++
+			}
+		}
+		if len(counts) == 0 {
+			continue
+		}
+
+		values := make([]models.FacetCount, 0, len(counts))
+		for value, count := range counts {
+			values = append(values, models.FacetCount{Value: value, Count: count})
+		}
+		sort.Slice(values, func(a, b int) bool {
+			if values[a].Count != values[b].Count {
+				return values[a].Count > values[b].Count
+			}
+			return values[a].Value < values[b].Value
+		})
+		facets[name] = values
+	}
+	return facets
+}
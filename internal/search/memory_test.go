@@ -0,0 +1,150 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+func newProduct(name, category string, price float64, tags ...string) *models.Product {
+	return &models.Product{
+		ID:       uuid.New(),
+		Name:     name,
+		Category: category,
+		Price:    price,
+		Tags:     tags,
+		Status:   models.ProductStatusActive,
+	}
+}
+
+func names(products []*models.Product) []string {
+	result := make([]string, len(products))
+	for i, p := range products {
+		result[i] = p.Name
+	}
+	return result
+}
+
+func TestMemoryIndexRanksNameMatchesFirst(t *testing.T) {
+	index := NewMemoryIndex()
+	tagged := newProduct("Carry case", "bags", 30, "laptop")
+	named := newProduct("Laptop stand", "accessories", 40)
+	described := newProduct("Desk lamp", "lighting", 25)
+	described.Description = "Lights a laptop keyboard"
+	index.Rebuild([]*models.Product{described, tagged, named, newProduct("Phone", "phones", 500)})
+
+	result, err := index.Query(context.Background(), Query{Text: "laptop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := names(result.Products)
+	want := []string{"Laptop stand", "Carry case", "Desk lamp"}
+	if result.Total != 3 || len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("results = %v (total %d), want %v", got, result.Total, want)
+	}
+	for _, p := range result.Products {
+		if p.Relevance == nil || *p.Relevance <= 0 {
+			t.Errorf("%s has relevance %v", p.Name, p.Relevance)
+		}
+	}
+}
+
+func TestMemoryIndexMatchesTyposAndPrefixes(t *testing.T) {
+	index := NewMemoryIndex()
+	index.Rebuild([]*models.Product{
+		newProduct("Mechanical keyboard", "accessories", 120),
+		newProduct("Wireless mouse", "accessories", 40),
+	})
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"keyboad", "Mechanical keyboard"},
+		{"keybpard", "Mechanical keyboard"},
+		{"wireless mou", "Wireless mouse"},
+	}
+	for _, tt := range tests {
+		result, err := index.Query(context.Background(), Query{Text: tt.text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(result.Products); len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%q matched %v, want %s", tt.text, got, tt.want)
+		}
+	}
+
+	result, _ := index.Query(context.Background(), Query{Text: "mou"})
+	if len(result.Products) != 1 {
+		t.Errorf("prefix of the last term matched %v", names(result.Products))
+	}
+	result, _ = index.Query(context.Background(), Query{Text: "mou wireless"})
+	if len(result.Products) != 0 {
+		t.Errorf("prefix of an earlier term matched %v", names(result.Products))
+	}
+}
+
+func TestMemoryIndexFiltersAndFacets(t *testing.T) {
+	index := NewMemoryIndex()
+	index.Rebuild([]*models.Product{
+		newProduct("Gaming laptop", "laptops", 1500),
+		newProduct("Office laptop", "laptops", 600),
+		newProduct("Laptop sleeve", "bags", 30),
+	})
+
+	maxPrice := 1000.0
+	result, err := index.Query(context.Background(), Query{
+		Text:    "laptop",
+		Filters: repository.ProductFilters{MaxPrice: &maxPrice, SortBy: "price", SortDir: "asc"},
+		Facets:  []string{"category", "price"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(result.Products); len(got) != 2 || got[0] != "Laptop sleeve" || got[1] != "Office laptop" {
+		t.Fatalf("results = %v", got)
+	}
+	categories := result.Facets["category"]
+	if len(categories) != 2 || categories[0].Count != 1 || categories[0].Value != "bags" {
+		t.Errorf("category facets = %+v", categories)
+	}
+	if len(result.Facets["price"]) == 0 {
+		t.Error("no price facets")
+	}
+
+	result, _ = index.Query(context.Background(), Query{
+		Text:    "laptop",
+		Filters: repository.ProductFilters{Category: "laptops", Limit: 1, Page: 2, SortBy: "price", SortDir: "desc"},
+	})
+	if got := names(result.Products); result.Total != 2 || len(got) != 1 || got[0] != "Office laptop" {
+		t.Errorf("second page = %v of %d", got, result.Total)
+	}
+}
+
+func TestMemoryIndexReindexDropsStaleTerms(t *testing.T) {
+	index := NewMemoryIndex()
+	product := newProduct("Laptop stand", "accessories", 40)
+	index.Index(product)
+
+	renamed := *product
+	renamed.Name = "Monitor stand"
+	renamed.Category = "desk"
+	index.Index(&renamed)
+
+	if result, _ := index.Query(context.Background(), Query{Text: "laptop"}); result.Total != 0 {
+		t.Errorf("old name still matches %v", names(result.Products))
+	}
+	result, _ := index.Query(context.Background(), Query{Text: "monitor", Filters: repository.ProductFilters{Category: "desk"}})
+	if result.Total != 1 {
+		t.Fatalf("renamed product matched %d times", result.Total)
+	}
+
+	index.Delete(product.ID)
+	if result, _ := index.Query(context.Background(), Query{Text: "stand"}); result.Total != 0 {
+		t.Errorf("deleted product still matches %v", names(result.Products))
+	}
+}
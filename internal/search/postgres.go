@@ -0,0 +1,58 @@
+package search
+
+import (
+	"context"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// postgresIndex searches the products table directly. The search vector is
+// maintained by a database trigger, so indexing and deletion are no-ops.
+type postgresIndex struct {
+	repo repository.ProductRepository
+}
+
+func NewPostgresIndex(repo repository.ProductRepository) Index {
+	return &postgresIndex{repo: repo}
+}
+
+func (i *postgresIndex) Index(product *models.Product) error {
+	return nil
+}
+
+func (i *postgresIndex) Delete(id uuid.UUID) error {
+	return nil
+}
+
+func (i *postgresIndex) Rebuild(products []*models.Product) error {
+	return nil
+}
+
+func (i *postgresIndex) Query(ctx context.Context, q Query) (*Result, error) {
+	products, total, err := i.repo.Search(ctx, q.Text, q.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Products: products, Total: total}
+	if len(q.Facets) == 0 {
+		return result, nil
+	}
+
+	facets, err := i.repo.SearchFacets(ctx, q.Text, q.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Facets = map[string][]models.FacetCount{}
+	for _, name := range q.Facets {
+		if counts, ok := facets[name]; ok {
+			result.Facets[name] = counts
+		}
+	}
+
+	return result, nil
+}
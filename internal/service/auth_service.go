@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/email"
+	"smrtmart-go-postgresql/internal/jwt"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores everything after 72 bytes
+	maxPasswordLength = 72
+
+	verifyEmailValidity   = 48 * time.Hour
+	passwordResetValidity = time.Hour
+)
+
+type AuthService interface {
+	Register(ctx context.Context, req RegisterRequest, lang string) (*models.User, error)
+	Login(ctx context.Context, emailAddress, password string) (*LoginResult, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ForgotPassword(ctx context.Context, emailAddress, lang string) error
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+type RegisterRequest struct {
+	Email     string  `json:"email" binding:"required"`
+	Password  string  `json:"password" binding:"required"`
+	FirstName string  `json:"first_name" binding:"required"`
+	LastName  string  `json:"last_name" binding:"required"`
+	Phone     *string `json:"phone"`
+}
+
+// LoginResult is the access token issued on login
+type LoginResult struct {
+	AccessToken string       `json:"access_token"`
+	TokenType   string       `json:"token_type"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	User        *models.User `json:"user"`
+}
+
+type authService struct {
+	userRepo  repository.UserRepository
+	emails    EmailService
+	jwtConfig config.JWTConfig
+	appURL    string
+	now       func() time.Time
+}
+
+func NewAuthService(userRepo repository.UserRepository, emails EmailService, jwtConfig config.JWTConfig, appURL string) AuthService {
+	return &authService{
+		userRepo:  userRepo,
+		emails:    emails,
+		jwtConfig: jwtConfig,
+		appURL:    strings.TrimRight(appURL, "/"),
+		now:       time.Now,
+	}
+}
+
+// Register creates a customer account and emails the link that verifies its address
+func (s *authService) Register(ctx context.Context, req RegisterRequest, lang string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Register")
+	defer func() { tracing.End(span, err) }()
+
+	address, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	if err != nil || address.Address != strings.TrimSpace(req.Email) {
+		return nil, errors.New("invalid email address")
+	}
+	if err := validPassword(req.Password); err != nil {
+		return nil, err
+	}
+	firstName, lastName := strings.TrimSpace(req.FirstName), strings.TrimSpace(req.LastName)
+	if firstName == "" || lastName == "" {
+		return nil, errors.New("name is required")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Email:     strings.ToLower(address.Address),
+		Password:  string(hash),
+		FirstName: firstName,
+		LastName:  lastName,
+		Phone:     req.Phone,
+		Role:      models.RoleCustomer,
+		Status:    models.StatusActive,
+	}
+
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	verifyEmail, err := s.emails.NewVerifyEmail(user.Email, lang, email.VerifyEmailData{
+		Name:       user.FirstName,
+		Link:       s.appURL + "/verify-email?token=" + token,
+		ValidHours: int(verifyEmailValidity / time.Hour),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.userRepo.Create(ctx, user, tokenHash, s.now().Add(verifyEmailValidity), verifyEmail)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, errors.New("email already registered")
+	}
+
+	return user, nil
+}
+
+// Login checks a user's password and issues an access token carrying their role
+func (s *authService) Login(ctx context.Context, emailAddress, password string) (_ *LoginResult, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(emailAddress))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare against a hash either way, so unknown addresses take as long to refuse
+	hash := unknownUserHash()
+	if user != nil {
+		hash = []byte(user.Password)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || user == nil {
+		return nil, errors.New("invalid email or password")
+	}
+	if user.Status != models.StatusActive {
+		return nil, errors.New("account is not active")
+	}
+
+	now := s.now()
+	expiresAt := now.Add(s.jwtConfig.AccessTTL)
+	token, err := jwt.Sign(s.jwtConfig.Secret, jwt.Claims{
+		UserID:    user.ID,
+		Role:      string(user.Role),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{AccessToken: token, TokenType: "Bearer", ExpiresAt: expiresAt, User: user}, nil
+}
+
+// VerifyEmail confirms the address that a verification link was sent to
+func (s *authService) VerifyEmail(ctx context.Context, token string) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.VerifyEmail")
+	defer func() { tracing.End(span, err) }()
+
+	verified, err := s.userRepo.VerifyEmail(ctx, hashToken(token), s.now())
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return errors.New("invalid or expired token")
+	}
+	return nil
+}
+
+// ForgotPassword emails a password reset link if the address belongs to an
+// active account. It reports success either way, so it does not reveal which
+// addresses are registered.
+func (s *authService) ForgotPassword(ctx context.Context, emailAddress, lang string) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ForgotPassword")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(emailAddress))
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Status != models.StatusActive {
+		return nil
+	}
+
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return err
+	}
+	resetEmail, err := s.emails.NewPasswordReset(user.Email, lang, email.PasswordResetData{
+		Name:       user.FirstName,
+		Link:       s.appURL + "/reset-password?token=" + token,
+		ValidHours: int(passwordResetValidity / time.Hour),
+	})
+	if err != nil {
+		return err
+	}
+	return s.userRepo.CreateToken(ctx, user.ID, models.TokenPurposePasswordReset, tokenHash, s.now().Add(passwordResetValidity), resetEmail)
+}
+
+// ResetPassword sets a new password with the token from a password reset link
+func (s *authService) ResetPassword(ctx context.Context, token, password string) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ResetPassword")
+	defer func() { tracing.End(span, err) }()
+
+	if err := validPassword(password); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	reset, err := s.userRepo.ResetPassword(ctx, hashToken(token), string(hash), s.now())
+	if err != nil {
+		return err
+	}
+	if !reset {
+		return errors.New("invalid or expired token")
+	}
+	return nil
+}
+
+// newToken returns a new token to email and the hash of it to store
+func newToken() (token, tokenHash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+// hashToken is what is stored of an emailed token, so a database leak does not
+// give away working links
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	unknownUserHashOnce sync.Once
+	unknownUserHashed   []byte
+)
+
+// unknownUserHash is a bcrypt hash no password matches, compared against when
+// the address is unknown
+func unknownUserHash() []byte {
+	unknownUserHashOnce.Do(func() {
+		unknownUserHashed, _ = bcrypt.GenerateFromPassword([]byte(uuid.NewString()), bcrypt.DefaultCost)
+	})
+	return unknownUserHashed
+}
+
+func validPassword(password string) error {
+	if len(password) < minPasswordLength {
+		return errors.New("password too short")
+	}
+	if len(password) > maxPasswordLength {
+		return errors.New("password too long")
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/email"
+	"smrtmart-go-postgresql/internal/jwt"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dedupeEmailRepository keeps queued messages, honouring dedupe keys
+type dedupeEmailRepository struct {
+	repository.EmailRepository
+	keys   map[string]bool
+	queued []emailJob
+}
+
+func (r *dedupeEmailRepository) Enqueue(_ context.Context, job *models.Job, dedupeKey *string) (bool, error) {
+	if dedupeKey != nil {
+		if r.keys[*dedupeKey] {
+			return false, nil
+		}
+		r.keys[*dedupeKey] = true
+	}
+	var payload emailJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return false, err
+	}
+	r.queued = append(r.queued, payload)
+	return true, nil
+}
+
+func newDedupeEmailService() (EmailService, *dedupeEmailRepository) {
+	repo := &dedupeEmailRepository{keys: make(map[string]bool)}
+	cfg := config.EmailConfig{AppURL: "https://shop.test", DefaultLanguage: "en", MaxAttempts: 5}
+	jobs := NewJobService(nil, config.JobsConfig{MaxAttempts: 3})
+	return NewEmailService(repo, jobs, email.MustNewRenderer(cfg.AppURL), email.NewFakeSender(), cfg), repo
+}
+
+type userToken struct {
+	userID    uuid.UUID
+	purpose   models.TokenPurpose
+	expiresAt time.Time
+	used      bool
+}
+
+// memoryUserRepository keeps users, their tokens by hash and the emails
+// queued with them
+type memoryUserRepository struct {
+	repository.UserRepository
+	users  map[string]*models.User
+	tokens map[string]*userToken
+	emails []emailJob
+}
+
+func newMemoryUserRepository() *memoryUserRepository {
+	return &memoryUserRepository{users: make(map[string]*models.User), tokens: make(map[string]*userToken)}
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User, verifyTokenHash string, verifyExpiresAt time.Time, verifyEmail *models.Job) (bool, error) {
+	user.Email = strings.ToLower(user.Email)
+	if _, ok := r.users[user.Email]; ok {
+		return false, nil
+	}
+	user.ID = uuid.New()
+	r.users[user.Email] = user
+	return true, r.CreateToken(ctx, user.ID, models.TokenPurposeVerifyEmail, verifyTokenHash, verifyExpiresAt, verifyEmail)
+}
+
+func (r *memoryUserRepository) GetByEmail(_ context.Context, emailAddress string) (*models.User, error) {
+	return r.users[strings.ToLower(emailAddress)], nil
+}
+
+func (r *memoryUserRepository) CreateToken(_ context.Context, userID uuid.UUID, purpose models.TokenPurpose, tokenHash string, expiresAt time.Time, tokenEmail *models.Job) error {
+	var payload emailJob
+	if err := json.Unmarshal(tokenEmail.Payload, &payload); err != nil {
+		return err
+	}
+	for _, token := range r.tokens {
+		if token.userID == userID && token.purpose == purpose {
+			token.used = true
+		}
+	}
+	r.tokens[tokenHash] = &userToken{userID: userID, purpose: purpose, expiresAt: expiresAt}
+	r.emails = append(r.emails, payload)
+	return nil
+}
+
+func (r *memoryUserRepository) use(tokenHash string, purpose models.TokenPurpose, now time.Time) *models.User {
+	token, ok := r.tokens[tokenHash]
+	if !ok || token.used || token.purpose != purpose || !token.expiresAt.After(now) {
+		return nil
+	}
+	token.used = true
+	for _, user := range r.users {
+		if user.ID == token.userID {
+			return user
+		}
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) VerifyEmail(_ context.Context, tokenHash string, now time.Time) (bool, error) {
+	user := r.use(tokenHash, models.TokenPurposeVerifyEmail, now)
+	if user == nil {
+		return false, nil
+	}
+	user.EmailVerifiedAt = &now
+	return true, nil
+}
+
+func (r *memoryUserRepository) ResetPassword(_ context.Context, tokenHash, passwordHash string, now time.Time) (bool, error) {
+	user := r.use(tokenHash, models.TokenPurposePasswordReset, now)
+	if user == nil {
+		return false, nil
+	}
+	user.Password = passwordHash
+	return true, nil
+}
+
+var linkToken = regexp.MustCompile(`\?token=([0-9a-f]{64})`)
+
+// emailedToken returns the token in the link of a queued message
+func emailedToken(t *testing.T, e emailJob) string {
+	t.Helper()
+	match := linkToken.FindStringSubmatch(e.Text)
+	if match == nil {
+		t.Fatalf("no token link in %q", e.Text)
+	}
+	return match[1]
+}
+
+func TestRegisterQueuesVerificationEmail(t *testing.T) {
+	ctx := context.Background()
+	users := newMemoryUserRepository()
+	emails, _ := newDedupeEmailService()
+	auth := NewAuthService(users, emails, config.JWTConfig{}, "https://shop.test/").(*authService)
+
+	user, err := auth.Register(ctx, RegisterRequest{
+		Email: "Asa@Example.com", Password: "correct horse", FirstName: " Åsa ", LastName: "Berg",
+	}, "sv-SE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "asa@example.com" || user.Role != models.RoleCustomer || user.EmailVerifiedAt != nil {
+		t.Errorf("registered %+v", user)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("correct horse")) != nil {
+		t.Error("password is not stored as its bcrypt hash")
+	}
+	if _, err := auth.Register(ctx, RegisterRequest{
+		Email: "asa@example.com", Password: "another one", FirstName: "Åsa", LastName: "Berg",
+	}, ""); err == nil || err.Error() != "email already registered" {
+		t.Errorf("second registration: %v", err)
+	}
+
+	// The email is queued with the account, not afterwards
+	if len(users.emails) != 1 {
+		t.Fatalf("queued %d emails, want 1", len(users.emails))
+	}
+	sent := users.emails[0]
+	if sent.Kind != string(email.KindVerifyEmail) || sent.To != "asa@example.com" || sent.Language != "sv" {
+		t.Errorf("queued %s to %s in %s", sent.Kind, sent.To, sent.Language)
+	}
+	if !strings.Contains(sent.Text, "https://shop.test/verify-email?token=") || !strings.Contains(sent.Text, "Åsa") {
+		t.Errorf("verification email text: %q", sent.Text)
+	}
+
+	token := emailedToken(t, sent)
+	if _, stored := users.tokens[token]; stored {
+		t.Error("the token is stored in plain text")
+	}
+	if err := auth.VerifyEmail(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Error("email not marked verified")
+	}
+	if err := auth.VerifyEmail(ctx, token); err == nil || err.Error() != "invalid or expired token" {
+		t.Errorf("reused token: %v", err)
+	}
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	ctx := context.Background()
+	users := newMemoryUserRepository()
+	emails, _ := newDedupeEmailService()
+	auth := NewAuthService(users, emails, config.JWTConfig{}, "https://shop.test").(*authService)
+	users.users["asa@example.com"] = &models.User{ID: uuid.New(), Email: "asa@example.com", FirstName: "Åsa", Status: models.StatusActive}
+
+	// Unknown addresses look the same to the caller but get no email
+	if err := auth.ForgotPassword(ctx, "nobody@example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(users.emails) != 0 {
+		t.Fatalf("queued %d emails for an unknown address", len(users.emails))
+	}
+
+	if err := auth.ForgotPassword(ctx, "ASA@example.com ", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.ForgotPassword(ctx, "asa@example.com", "en"); err != nil {
+		t.Fatal(err)
+	}
+	if len(users.emails) != 2 || users.emails[1].Kind != string(email.KindPasswordReset) {
+		t.Fatalf("queued %+v", users.emails)
+	}
+	first, latest := emailedToken(t, users.emails[0]), emailedToken(t, users.emails[1])
+	if !strings.Contains(users.emails[1].Text, "https://shop.test/reset-password?token=") {
+		t.Errorf("reset email text: %q", users.emails[1].Text)
+	}
+
+	if err := auth.ResetPassword(ctx, latest, "short"); err == nil || err.Error() != "password too short" {
+		t.Errorf("short password: %v", err)
+	}
+	if err := auth.ResetPassword(ctx, first, "a new password"); err == nil || err.Error() != "invalid or expired token" {
+		t.Errorf("superseded token: %v", err)
+	}
+
+	// The link expires after an hour
+	auth.now = func() time.Time { return time.Now().Add(passwordResetValidity + time.Minute) }
+	if err := auth.ResetPassword(ctx, latest, "a new password"); err == nil {
+		t.Error("expired token accepted")
+	}
+	auth.now = time.Now
+	if err := auth.ResetPassword(ctx, latest, "a new password"); err != nil {
+		t.Fatal(err)
+	}
+	user := users.users["asa@example.com"]
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("a new password")) != nil {
+		t.Error("password not changed")
+	}
+}
+
+func TestLoginIssuesTokenWithRole(t *testing.T) {
+	ctx := context.Background()
+	users := newMemoryUserRepository()
+	emails, _ := newDedupeEmailService()
+	cfg := config.JWTConfig{Secret: "test-secret", AccessTTL: time.Hour}
+	auth := NewAuthService(users, emails, cfg, "https://shop.test")
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := &models.User{ID: uuid.New(), Email: "admin@example.com", Password: string(hash), Role: models.RoleAdmin, Status: models.StatusActive}
+	users.users[admin.Email] = admin
+	users.users["gone@example.com"] = &models.User{ID: uuid.New(), Email: "gone@example.com", Password: string(hash), Role: models.RoleCustomer, Status: models.StatusSuspended}
+
+	result, err := auth.Login(ctx, " admin@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := jwt.Parse(cfg.Secret, result.AccessToken, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != admin.ID || claims.Role != string(models.RoleAdmin) || result.TokenType != "Bearer" {
+		t.Errorf("claims %+v, token type %q", claims, result.TokenType)
+	}
+	if time.Until(result.ExpiresAt) > time.Hour || time.Until(result.ExpiresAt) < 59*time.Minute {
+		t.Errorf("expires at %v", result.ExpiresAt)
+	}
+
+	tests := []struct {
+		email, password, want string
+	}{
+		{"admin@example.com", "wrong horse", "invalid email or password"},
+		{"nobody@example.com", "correct horse", "invalid email or password"},
+		{"gone@example.com", "correct horse", "account is not active"},
+	}
+	for _, tt := range tests {
+		if _, err := auth.Login(ctx, tt.email, tt.password); err == nil || err.Error() != tt.want {
+			t.Errorf("Login(%s, %s) = %v, want %q", tt.email, tt.password, err, tt.want)
+		}
+	}
+}
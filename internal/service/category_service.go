@@ -1,27 +1,323 @@
-package service
-
-import (
-	"smrtmart-go-postgresql/internal/models"
-	"smrtmart-go-postgresql/internal/repository"
-)
-
-type CategoryService interface {
-	GetAll() ([]models.Category, error)
-	GetByID(id string) (*models.Category, error)
-}
-
-type categoryService struct {
-	repo repository.CategoryRepository
-}
-
-func NewCategoryService(repo repository.CategoryRepository) CategoryService {
-	return &categoryService{repo: repo}
-}
-
-func (s *categoryService) GetAll() ([]models.Category, error) {
-	return s.repo.GetAll()
-}
-
-func (s *categoryService) GetByID(id string) (*models.Category, error) {
-	return s.repo.GetByID(id)
-}
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+type CategoryService interface {
+	GetAll(ctx context.Context) ([]models.Category, error)
+	GetByID(ctx context.Context, id string) (*models.Category, error)
+	GetTree(ctx context.Context) ([]*models.Category, error)
+	GetBreadcrumbs(ctx context.Context, id string) ([]models.Category, error)
+	Create(ctx context.Context, category *models.Category) error
+	Update(ctx context.Context, category *models.Category, isActive *bool) error
+	Reorder(ctx context.Context, orders []models.CategorySortOrder) error
+	Delete(ctx context.Context, id string, reassignTo string) error
+}
+
+type categoryService struct {
+	repo repository.CategoryRepository
+}
+
+func NewCategoryService(repo repository.CategoryRepository) CategoryService {
+	return &categoryService{repo: repo}
+}
+
+func (s *categoryService) GetAll(ctx context.Context) (_ []models.Category, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.GetAll")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.GetAll(ctx)
+}
+
+// GetByID looks a category up by UUID, falling back to its slug
+func (s *categoryService) GetByID(ctx context.Context, id string) (_ *models.Category, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.GetByID")
+	defer func() { tracing.End(span, err) }()
+
+	category, err := findCategory(ctx, s.repo, id)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, errors.New("category not found")
+	}
+
+	return category, nil
+}
+
+// GetTree returns the active categories nested under their parents, ordered by sort order
+func (s *categoryService) GetTree(ctx context.Context) (_ []*models.Category, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.GetTree")
+	defer func() { tracing.End(span, err) }()
+
+	categories, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uuid.UUID]*models.Category, len(categories))
+	for i := range categories {
+		nodes[categories[i].ID] = &categories[i]
+	}
+
+	// GetAll is already sorted, so appending keeps siblings in order
+	roots := []*models.Category{}
+	for i := range categories {
+		node := &categories[i]
+		if node.ParentID != nil {
+			if parent, ok := nodes[*node.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		// Categories whose parent is missing or inactive are shown at the top level
+		roots = append(roots, node)
+	}
+
+	return roots, nil
+}
+
+// GetBreadcrumbs returns the path from the root category down to the given category
+func (s *categoryService) GetBreadcrumbs(ctx context.Context, id string) (_ []models.Category, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.GetBreadcrumbs")
+	defer func() { tracing.End(span, err) }()
+
+	category, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPath(ctx, category.ID.String())
+}
+
+func (s *categoryService) Create(ctx context.Context, category *models.Category) (err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.Create")
+	defer func() { tracing.End(span, err) }()
+
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return errors.New("category name is required")
+	}
+
+	if category.ParentID != nil {
+		parent, err := s.repo.FindByID(ctx, category.ParentID.String())
+		if err != nil {
+			return err
+		}
+		if parent == nil {
+			return errors.New("parent category not found")
+		}
+	}
+
+	slug, err := s.uniqueSlug(ctx, category.Slug, category.Name, nil)
+	if err != nil {
+		return err
+	}
+	category.Slug = slug
+
+	return s.repo.Create(ctx, category)
+}
+
+// Update saves a category. A nil isActive keeps whether it is active; moving it
+// under one of its own subcategories is refused.
+func (s *categoryService) Update(ctx context.Context, category *models.Category, isActive *bool) (err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.Update")
+	defer func() { tracing.End(span, err) }()
+
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return errors.New("category name is required")
+	}
+
+	existing, err := s.repo.FindByID(ctx, category.ID.String())
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("category not found")
+	}
+	if category.ParentID != nil && *category.ParentID == category.ID {
+		return errors.New("category cannot be its own parent")
+	}
+
+	// Keep the current slug unless a new one is given explicitly
+	requested := category.Slug
+	if requested == "" {
+		requested = existing.Slug
+	}
+	slug, err := s.uniqueSlug(ctx, requested, category.Name, &category.ID)
+	if err != nil {
+		return err
+	}
+	category.Slug = slug
+
+	err = s.repo.Update(ctx, category, isActive, func(parentPath []models.Category) error {
+		return checkParent(category.ID, parentPath)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("category not found")
+	}
+	return err
+}
+
+func (s *categoryService) Reorder(ctx context.Context, orders []models.CategorySortOrder) (err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.Reorder")
+	defer func() { tracing.End(span, err) }()
+
+	if len(orders) == 0 {
+		return errors.New("at least one category is required")
+	}
+
+	seen := make(map[uuid.UUID]bool, len(orders))
+	for _, order := range orders {
+		if order.ID == uuid.Nil {
+			return errors.New("invalid category ID")
+		}
+		if seen[order.ID] {
+			return errors.New("duplicate category in reorder request")
+		}
+		seen[order.ID] = true
+	}
+
+	if err := s.repo.Reorder(ctx, orders); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("category not found")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes a category. Categories that still have products or subcategories
+// are refused unless reassignTo names a category to move them to.
+func (s *categoryService) Delete(ctx context.Context, id string, reassignTo string) (err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	existing, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("category not found")
+	}
+
+	if reassignTo == "" {
+		products, children, err := s.repo.CountUsage(ctx, existing.ID.String())
+		if err != nil {
+			return err
+		}
+		if products > 0 || children > 0 {
+			return fmt.Errorf("category is in use by %d products and %d subcategories", products, children)
+		}
+		return s.repo.Delete(ctx, existing.ID.String())
+	}
+
+	target, err := s.repo.FindByID(ctx, reassignTo)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return errors.New("target category not found")
+	}
+	if target.ID == existing.ID {
+		return errors.New("cannot reassign a category to itself")
+	}
+
+	// The target must not sit below the deleted category, or its children would form a cycle
+	return s.repo.DeleteAndReassign(ctx, existing.ID.String(), target, func(targetPath []models.Category) error {
+		if err := checkParent(existing.ID, targetPath); err != nil {
+			return errors.New("cannot reassign to a subcategory of the deleted category")
+		}
+		return nil
+	})
+}
+
+// checkParent ensures making the last category of parentPath the parent of id
+// would not create a cycle
+func checkParent(id uuid.UUID, parentPath []models.Category) error {
+	if len(parentPath) == 0 {
+		return errors.New("parent category not found")
+	}
+
+	for _, ancestor := range parentPath {
+		if ancestor.ID == id {
+			return errors.New("category cannot be moved under one of its subcategories")
+		}
+	}
+
+	return nil
+}
+
+// uniqueSlug slugifies the requested slug (or the name if none is given) and
+// appends a numeric suffix until it no longer clashes with another category
+func (s *categoryService) uniqueSlug(ctx context.Context, requested, name string, excludeID *uuid.UUID) (string, error) {
+	base := slugify(requested)
+	if base == "" {
+		base = slugify(name)
+	}
+	if base == "" {
+		return "", errors.New("category slug could not be generated from name")
+	}
+
+	slug := base
+	for n := 2; ; n++ {
+		exists, err := s.repo.SlugExists(ctx, slug, excludeID)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// slugify lowercases text, transliterates Nordic and accented letters and joins words with dashes
+func slugify(text string) string {
+	replacer := strings.NewReplacer(
+		"å", "a", "ä", "a", "ö", "o", "ø", "o", "æ", "ae",
+		"é", "e", "è", "e", "ü", "u", "ß", "ss", "&", " and ",
+	)
+	text = replacer.Replace(strings.ToLower(text))
+
+	var b strings.Builder
+	dash := false
+	for _, r := range text {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// findCategory resolves a category by UUID or slug, returning nil if neither matches
+func findCategory(ctx context.Context, repo repository.CategoryRepository, idOrSlug string) (*models.Category, error) {
+	if _, err := uuid.Parse(idOrSlug); err == nil {
+		category, err := repo.GetByID(ctx, idOrSlug)
+		if err != nil || category != nil {
+			return category, err
+		}
+	}
+
+	return repo.GetBySlug(ctx, idOrSlug)
+}
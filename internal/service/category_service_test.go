@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// treeCategoryRepository keeps categories by ID and walks parents for paths
+type treeCategoryRepository struct {
+	repository.CategoryRepository
+	categories map[uuid.UUID]*models.Category
+}
+
+func (r *treeCategoryRepository) FindByID(_ context.Context, id string) (*models.Category, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil, nil
+	}
+	category, ok := r.categories[parsed]
+	if !ok {
+		return nil, nil
+	}
+	copied := *category
+	return &copied, nil
+}
+
+func (r *treeCategoryRepository) SlugExists(_ context.Context, slug string, excludeID *uuid.UUID) (bool, error) {
+	for _, category := range r.categories {
+		if category.Slug == slug && (excludeID == nil || category.ID != *excludeID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *treeCategoryRepository) path(id uuid.UUID) []models.Category {
+	var path []models.Category
+	for next := &id; next != nil; {
+		category, ok := r.categories[*next]
+		if !ok {
+			break
+		}
+		path = append([]models.Category{*category}, path...)
+		next = category.ParentID
+	}
+	return path
+}
+
+func (r *treeCategoryRepository) Update(_ context.Context, category *models.Category, isActive *bool, checkParent func([]models.Category) error) error {
+	stored, ok := r.categories[category.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if category.ParentID != nil {
+		if err := checkParent(r.path(*category.ParentID)); err != nil {
+			return err
+		}
+	}
+	category.IsActive = stored.IsActive
+	if isActive != nil {
+		category.IsActive = *isActive
+	}
+	copied := *category
+	r.categories[category.ID] = &copied
+	return nil
+}
+
+func TestUpdateCategory(t *testing.T) {
+	ctx := context.Background()
+	root := &models.Category{ID: uuid.New(), Name: "Root", Slug: "root", IsActive: true}
+	child := &models.Category{ID: uuid.New(), Name: "Child", Slug: "child", ParentID: &root.ID, IsActive: true}
+	hidden := &models.Category{ID: uuid.New(), Name: "Hidden", Slug: "hidden"}
+	repo := &treeCategoryRepository{categories: map[uuid.UUID]*models.Category{root.ID: root, child.ID: child, hidden.ID: hidden}}
+	categories := NewCategoryService(repo)
+	missing := uuid.New()
+
+	tests := []struct {
+		name     string
+		update   models.Category
+		isActive *bool
+		want     string
+	}{
+		{"blank name", models.Category{ID: child.ID, Name: " "}, nil, "category name is required"},
+		{"unknown category", models.Category{ID: missing, Name: "Gone"}, nil, "category not found"},
+		{"own parent", models.Category{ID: root.ID, Name: "Root", ParentID: &root.ID}, nil, "category cannot be its own parent"},
+		{"under own subcategory", models.Category{ID: root.ID, Name: "Root", ParentID: &child.ID}, nil, "category cannot be moved under one of its subcategories"},
+		{"unknown parent", models.Category{ID: child.ID, Name: "Child", ParentID: &missing}, nil, "parent category not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := tt.update
+			if err := categories.Update(ctx, &update, tt.isActive); err == nil || err.Error() != tt.want {
+				t.Errorf("Update = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// Omitting is_active keeps a hidden category hidden
+	update := &models.Category{ID: hidden.ID, Name: "Still hidden", ParentID: &root.ID}
+	if err := categories.Update(ctx, update, nil); err != nil {
+		t.Fatal(err)
+	}
+	if update.IsActive || repo.categories[hidden.ID].IsActive || update.Slug != "hidden" {
+		t.Errorf("updated %+v", update)
+	}
+	active := true
+	if err := categories.Update(ctx, &models.Category{ID: hidden.ID, Name: "Shown"}, &active); err != nil {
+		t.Fatal(err)
+	}
+	if !repo.categories[hidden.ID].IsActive {
+		t.Error("is_active not applied")
+	}
+}
+
+func (r *treeCategoryRepository) GetByID(ctx context.Context, id string) (*models.Category, error) {
+	return r.FindByID(ctx, id)
+}
+
+// GetAll returns the categories sorted by name, standing in for the sort order
+func (r *treeCategoryRepository) GetAll(context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	for _, category := range r.categories {
+		categories = append(categories, *category)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories, nil
+}
+
+func (r *treeCategoryRepository) GetBySlug(_ context.Context, slug string) (*models.Category, error) {
+	for _, category := range r.categories {
+		if category.Slug == slug {
+			copied := *category
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *treeCategoryRepository) Create(_ context.Context, category *models.Category) error {
+	category.ID = uuid.New()
+	copied := *category
+	r.categories[category.ID] = &copied
+	return nil
+}
+
+func (r *treeCategoryRepository) CountUsage(_ context.Context, id string) (int, int, error) {
+	var children int
+	for _, category := range r.categories {
+		if category.ParentID != nil && category.ParentID.String() == id {
+			children++
+		}
+	}
+	return 0, children, nil
+}
+
+func TestCreateCategory(t *testing.T) {
+	ctx := context.Background()
+	shoes := &models.Category{ID: uuid.New(), Name: "Shoes", Slug: "shoes", IsActive: true}
+	repo := &treeCategoryRepository{categories: map[uuid.UUID]*models.Category{shoes.ID: shoes}}
+	categories := NewCategoryService(repo)
+	missing := uuid.New()
+
+	tests := []struct {
+		name     string
+		category models.Category
+		wantSlug string
+		wantErr  string
+	}{
+		{"blank name", models.Category{Name: "  "}, "", "category name is required"},
+		{"unknown parent", models.Category{Name: "Boots", ParentID: &missing}, "", "parent category not found"},
+		{"no usable slug", models.Category{Name: "???"}, "", "category slug could not be generated from name"},
+		{"slug from name", models.Category{Name: "Kök & Bad", ParentID: &shoes.ID}, "kok-and-bad", ""},
+		{"taken slug gets a suffix", models.Category{Name: "Shoes"}, "shoes-2", ""},
+		{"explicit slug", models.Category{Name: "Sneakers", Slug: "Street Shoes"}, "street-shoes", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			category := tt.category
+			err := categories.Create(ctx, &category)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Create = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if category.Slug != tt.wantSlug {
+				t.Errorf("slug = %q, want %q", category.Slug, tt.wantSlug)
+			}
+		})
+	}
+}
+
+func TestCategoryLookupAndDelete(t *testing.T) {
+	ctx := context.Background()
+	root := &models.Category{ID: uuid.New(), Name: "Root", Slug: "root"}
+	child := &models.Category{ID: uuid.New(), Name: "Child", Slug: "child", ParentID: &root.ID}
+	other := &models.Category{ID: uuid.New(), Name: "Other", Slug: "other"}
+	repo := &treeCategoryRepository{categories: map[uuid.UUID]*models.Category{root.ID: root, child.ID: child, other.ID: other}}
+	categories := NewCategoryService(repo)
+
+	// A category whose parent is missing is shown at the top level
+	orphan := &models.Category{ID: uuid.New(), Name: "Orphan", Slug: "orphan", ParentID: new(uuid.UUID)}
+	repo.categories[orphan.ID] = orphan
+	tree, err := categories.GetTree(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, node := range tree {
+		names = append(names, node.Name)
+	}
+	if strings.Join(names, ",") != "Orphan,Other,Root" || len(tree[2].Children) != 1 || tree[2].Children[0].ID != child.ID {
+		t.Errorf("tree roots %v, root children %+v", names, tree[2].Children)
+	}
+	delete(repo.categories, orphan.ID)
+
+	if found, err := categories.GetByID(ctx, "child"); err != nil || found.ID != child.ID {
+		t.Errorf("GetByID by slug = %+v, %v", found, err)
+	}
+	if _, err := categories.GetByID(ctx, uuid.NewString()); err == nil || err.Error() != "category not found" {
+		t.Errorf("GetByID unknown = %v", err)
+	}
+
+	deletes := []struct {
+		name       string
+		id         string
+		reassignTo string
+		want       string
+	}{
+		{"unknown category", uuid.NewString(), "", "category not found"},
+		{"in use", root.ID.String(), "", "category is in use by 0 products and 1 subcategories"},
+		{"unknown target", root.ID.String(), uuid.NewString(), "target category not found"},
+		{"reassign to itself", root.ID.String(), root.ID.String(), "cannot reassign a category to itself"},
+	}
+	for _, tt := range deletes {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := categories.Delete(ctx, tt.id, tt.reassignTo); err == nil || err.Error() != tt.want {
+				t.Errorf("Delete = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	reorders := []struct {
+		name   string
+		orders []models.CategorySortOrder
+		want   string
+	}{
+		{"empty", nil, "at least one category is required"},
+		{"nil ID", []models.CategorySortOrder{{ID: uuid.Nil}}, "invalid category ID"},
+		{"duplicate", []models.CategorySortOrder{{ID: root.ID}, {ID: root.ID, SortOrder: 1}}, "duplicate category in reorder request"},
+	}
+	for _, tt := range reorders {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := categories.Reorder(ctx, tt.orders); err == nil || err.Error() != tt.want {
+				t.Errorf("Reorder = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckParent(t *testing.T) {
+	root := models.Category{ID: uuid.New()}
+	child := models.Category{ID: uuid.New(), ParentID: &root.ID}
+
+	if err := checkParent(child.ID, nil); err == nil || err.Error() != "parent category not found" {
+		t.Errorf("empty path: %v", err)
+	}
+	if err := checkParent(root.ID, []models.Category{root, child}); err == nil {
+		t.Error("moving root under its child was allowed")
+	}
+	if err := checkParent(uuid.New(), []models.Category{root, child}); err != nil {
+		t.Errorf("unrelated move: %v", err)
+	}
+}
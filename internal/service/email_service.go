@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/email"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+// emailJobKind is the job kind sending one rendered message
+const emailJobKind = "email.send"
+
+type EmailService interface {
+	NewVerifyEmail(to, lang string, data email.VerifyEmailData) (*models.Job, error)
+	NewPasswordReset(to, lang string, data email.PasswordResetData) (*models.Job, error)
+	QueueOrderConfirmation(ctx context.Context, to, lang, reference string, data email.OrderConfirmationData) error
+	QueueShippingNotification(ctx context.Context, to, lang string, shipmentID uuid.UUID, data email.ShippingNotificationData) error
+	QueueRefundIssued(ctx context.Context, to, lang, reference string, data email.RefundIssuedData) error
+	QueueReviewRequest(ctx context.Context, to, lang string, orderID uuid.UUID, data email.ReviewRequestData) error
+}
+
+// emailJob is the payload of an email.send job
+type emailJob struct {
+	Kind     string `json:"kind"`
+	To       string `json:"to"`
+	Language string `json:"language"`
+	Subject  string `json:"subject"`
+	Text     string `json:"text"`
+	HTML     string `json:"html"`
+}
+
+type emailService struct {
+	repo     repository.EmailRepository
+	jobs     JobService
+	renderer *email.Renderer
+	sender   email.Sender
+	cfg      config.EmailConfig
+}
+
+// NewEmailService creates the email service and registers the job sending its
+// messages, so it must be created before the job workers start
+func NewEmailService(repo repository.EmailRepository, jobs JobService, renderer *email.Renderer, sender email.Sender, cfg config.EmailConfig) EmailService {
+	s := &emailService{
+		repo:     repo,
+		jobs:     jobs,
+		renderer: renderer,
+		sender:   sender,
+		cfg:      cfg,
+	}
+	jobs.Register(emailJobKind, s.send)
+	return s
+}
+
+// NewVerifyEmail builds the job sending the link that confirms a new account's
+// email address, for the account to be stored in the same transaction
+func (s *emailService) NewVerifyEmail(to, lang string, data email.VerifyEmailData) (*models.Job, error) {
+	return s.newJob(email.KindVerifyEmail, to, lang, data)
+}
+
+// NewPasswordReset builds the job sending a password reset link, for the token
+// to be stored in the same transaction
+func (s *emailService) NewPasswordReset(to, lang string, data email.PasswordResetData) (*models.Job, error) {
+	return s.newJob(email.KindPasswordReset, to, lang, data)
+}
+
+// QueueOrderConfirmation queues the confirmation of a paid order, once per reference
+func (s *emailService) QueueOrderConfirmation(ctx context.Context, to, lang, reference string, data email.OrderConfirmationData) (err error) {
+	ctx, span := tracing.Start(ctx, "EmailService.QueueOrderConfirmation")
+	defer func() { tracing.End(span, err) }()
+
+	return s.queue(ctx, email.KindOrderConfirmation, to, lang, reference, data)
+}
+
+func (s *emailService) QueueShippingNotification(ctx context.Context, to, lang string, shipmentID uuid.UUID, data email.ShippingNotificationData) (err error) {
+	ctx, span := tracing.Start(ctx, "EmailService.QueueShippingNotification")
+	defer func() { tracing.End(span, err) }()
+
+	return s.queue(ctx, email.KindShippingNotification, to, lang, shipmentID.String(), data)
+}
+
+// QueueRefundIssued queues the notice of a refund, once per refund reference
+func (s *emailService) QueueRefundIssued(ctx context.Context, to, lang, reference string, data email.RefundIssuedData) (err error) {
+	ctx, span := tracing.Start(ctx, "EmailService.QueueRefundIssued")
+	defer func() { tracing.End(span, err) }()
+
+	return s.queue(ctx, email.KindRefundIssued, to, lang, reference, data)
+}
+
+// QueueReviewRequest asks the customer to review the products of a delivered order, once per order
+func (s *emailService) QueueReviewRequest(ctx context.Context, to, lang string, orderID uuid.UUID, data email.ReviewRequestData) (err error) {
+	ctx, span := tracing.Start(ctx, "EmailService.QueueReviewRequest")
+	defer func() { tracing.End(span, err) }()
+
+	return s.queue(ctx, email.KindReviewRequest, to, lang, orderID.String(), data)
+}
+
+// queue queues the job sending a message. A message of the same kind and
+// reference is only queued once.
+func (s *emailService) queue(ctx context.Context, kind email.Kind, to, lang, reference string, data interface{}) error {
+	job, err := s.newJob(kind, to, lang, data)
+	if err != nil {
+		return err
+	}
+
+	key := string(kind) + ":" + reference
+	if _, err := s.repo.Enqueue(ctx, job, &key); err != nil {
+		return err
+	}
+	return nil
+}
+
+// newJob renders a message into the job that sends it. Failed sends are
+// retried with the job queue's backoff until MaxAttempts.
+func (s *emailService) newJob(kind email.Kind, to, lang string, data interface{}) (*models.Job, error) {
+	to = strings.TrimSpace(to)
+	if _, err := mail.ParseAddress(to); err != nil {
+		return nil, errors.New("invalid email address")
+	}
+	if lang == "" {
+		lang = s.cfg.DefaultLanguage
+	}
+	lang = email.Language(lang)
+
+	msg, err := s.renderer.Render(kind, lang, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.jobs.NewJob(emailJobKind, emailJob{
+		Kind:     string(kind),
+		To:       to,
+		Language: lang,
+		Subject:  msg.Subject,
+		Text:     msg.Text,
+		HTML:     msg.HTML,
+	}, JobOptions{MaxAttempts: s.cfg.MaxAttempts})
+}
+
+// send sends the message of an email.send job
+func (s *emailService) send(ctx context.Context, job *models.Job) error {
+	var payload emailJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return PermanentJobError(fmt.Errorf("invalid email job payload: %w", err))
+	}
+
+	return s.sender.Send(ctx, email.Message{
+		To:      payload.To,
+		Subject: payload.Subject,
+		Text:    payload.Text,
+		HTML:    payload.HTML,
+	})
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/email"
+
+	"github.com/google/uuid"
+)
+
+func TestEmailJobSendsRenderedMessage(t *testing.T) {
+	ctx := context.Background()
+	sender := email.NewFakeSender()
+	jobs := NewJobService(nil, config.JobsConfig{MaxAttempts: 3}).(*jobService)
+	cfg := config.EmailConfig{AppURL: "https://shop.test", DefaultLanguage: "en", MaxAttempts: 5}
+	emails := NewEmailService(&dedupeEmailRepository{keys: make(map[string]bool)}, jobs, email.MustNewRenderer(cfg.AppURL), sender, cfg)
+
+	job, err := emails.NewVerifyEmail(" asa@example.com", "", email.VerifyEmailData{Name: "Åsa", Link: "https://shop.test/verify-email?token=abc", ValidHours: 48})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Kind != emailJobKind || job.MaxAttempts != 5 {
+		t.Errorf("built %+v", job)
+	}
+	if _, err := emails.NewVerifyEmail("not an address", "", email.VerifyEmailData{}); err == nil {
+		t.Error("invalid address accepted")
+	}
+
+	send := jobs.handlers[emailJobKind]
+	if send == nil {
+		t.Fatal("no handler registered for email jobs")
+	}
+	if err := send(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.Sent) != 1 || sender.Sent[0].To != "asa@example.com" || sender.Sent[0].Subject == "" || sender.Sent[0].HTML == "" {
+		t.Fatalf("sent %+v", sender.Sent)
+	}
+
+	// A failed send is returned for the job queue to retry
+	sender.Err = errors.New("connection refused")
+	if err := send(ctx, job); err == nil {
+		t.Error("failed send reported as sent")
+	}
+	job.Payload = []byte(`"garbage"`)
+	var permanent *permanentJobError
+	if err := send(ctx, job); !errors.As(err, &permanent) {
+		t.Errorf("invalid payload: %v", err)
+	}
+}
+
+func TestQueuedEmailsAreSentOncePerReference(t *testing.T) {
+	ctx := context.Background()
+	emails, queued := newDedupeEmailService()
+	orderID := uuid.New()
+
+	for i := 0; i < 2; i++ {
+		if err := emails.QueueReviewRequest(ctx, "asa@example.com", "sv", orderID, email.ReviewRequestData{Name: "Åsa"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := emails.QueueReviewRequest(ctx, "asa@example.com", "sv", uuid.New(), email.ReviewRequestData{Name: "Åsa"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(queued.queued) != 2 {
+		t.Fatalf("queued %d emails, want 2", len(queued.queued))
+	}
+	if sent := queued.queued[0]; sent.Kind != string(email.KindReviewRequest) || sent.Language != "sv" {
+		t.Errorf("queued %+v", sent)
+	}
+}
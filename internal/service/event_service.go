@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"smrtmart-go-postgresql/internal/eventsink"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+// eventBatchSize is how many events are fanned out per dispatch run
+const eventBatchSize = 100
+
+// EventHandler reacts to a domain event. It runs as a background job, so a
+// returned error retries it, and it may see the same event more than once.
+type EventHandler func(ctx context.Context, event *models.DomainEvent) error
+
+type EventService interface {
+	Subscribe(name string, types []models.EventType, handler EventHandler)
+	Publish(ctx context.Context, events ...*models.DomainEvent) error
+	GetEvents(ctx context.Context, eventType *models.EventType, aggregateType, aggregateID *string, page, limit int) (*models.PaginatedResponse, error)
+	GetEvent(ctx context.Context, id uuid.UUID) (*models.DomainEvent, error)
+	DispatchPending(ctx context.Context) (int, error)
+	RunDispatcher(ctx context.Context, interval time.Duration)
+}
+
+type eventSubscription struct {
+	name  string
+	types map[models.EventType]bool // Empty for every type
+}
+
+type eventService struct {
+	repo repository.EventRepository
+	jobs JobService
+
+	mu            sync.RWMutex
+	subscriptions []eventSubscription
+}
+
+// NewEventService creates the event service and subscribes the external sinks to every event
+func NewEventService(repo repository.EventRepository, jobs JobService, sinks map[string]eventsink.Sink) EventService {
+	s := &eventService{repo: repo, jobs: jobs}
+	for name, sink := range sinks {
+		s.Subscribe("sink."+name, nil, sink.Publish)
+	}
+	return s
+}
+
+// Subscribe delivers events of the given types, or of every type when none are
+// given, to handler. Each subscriber gets its own job per event, so a failing
+// subscriber is retried without holding up the others. Subscribers must be
+// registered before the job workers start.
+func (s *eventService) Subscribe(name string, types []models.EventType, handler EventHandler) {
+	sub := eventSubscription{name: name, types: make(map[models.EventType]bool)}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+
+	s.mu.Lock()
+	s.subscriptions = append(s.subscriptions, sub)
+	s.mu.Unlock()
+
+	s.jobs.Register(eventJobKind(name), func(ctx context.Context, job *models.Job) error {
+		var event models.DomainEvent
+		if err := json.Unmarshal(job.Payload, &event); err != nil {
+			return PermanentJobError(fmt.Errorf("invalid event payload: %w", err))
+		}
+		return handler(ctx, &event)
+	})
+}
+
+// Publish stores events that do not accompany a change in the database. Events
+// of a database change are stored by the repository in its transaction instead.
+func (s *eventService) Publish(ctx context.Context, events ...*models.DomainEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "EventService.Publish")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.Append(ctx, events...)
+}
+
+func (s *eventService) GetEvents(ctx context.Context, eventType *models.EventType, aggregateType, aggregateID *string, page, limit int) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetEvents")
+	defer func() { tracing.End(span, err) }()
+
+	events, total, err := s.repo.GetAll(ctx, eventType, aggregateType, aggregateID, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedResponse{
+		Data: events,
+		Pagination: models.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	}, nil
+}
+
+func (s *eventService) GetEvent(ctx context.Context, id uuid.UUID) (_ *models.DomainEvent, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetEvent")
+	defer func() { tracing.End(span, err) }()
+
+	event, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, errors.New("event not found")
+	}
+	return event, nil
+}
+
+// DispatchPending fans new events out to their subscribers as jobs, in the order
+// they were stored. An event is marked dispatched once all its jobs are queued;
+// if that fails it is fanned out again, and the jobs' unique keys keep queued
+// deliveries from doubling up. It returns the number of events dispatched.
+func (s *eventService) DispatchPending(ctx context.Context) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.DispatchPending")
+	defer func() { tracing.End(span, err) }()
+
+	s.mu.RLock()
+	subscriptions := s.subscriptions
+	s.mu.RUnlock()
+
+	return s.repo.Dispatch(ctx, eventBatchSize, func(event *models.DomainEvent) error {
+		for _, sub := range subscriptions {
+			if len(sub.types) > 0 && !sub.types[event.Type] {
+				continue
+			}
+
+			_, err := s.jobs.Enqueue(ctx, eventJobKind(sub.name), event, JobOptions{
+				UniqueKey: fmt.Sprintf("%s:%s", eventJobKind(sub.name), event.ID),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to queue event %s for %s: %w", event.ID, sub.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// RunDispatcher fans out new events every interval until ctx is cancelled
+func (s *eventService) RunDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches suggest more events are waiting
+		for {
+			dispatched, err := s.DispatchPending(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to dispatch events", "error", err)
+			}
+			if err != nil || dispatched < eventBatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// eventJobKind is the job kind delivering events to a subscriber
+func eventJobKind(subscriber string) string {
+	return "event:" + subscriber
+}
@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/metrics"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxGiftCardBalance = 10000 // Largest balance a single gift card can be issued with
+	maxTenderCodes     = 5     // Gift cards that can be combined in one checkout
+)
+
+// giftCardAlphabet leaves out characters that are easily misread, such as 0/O and 1/I
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+var giftCardCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9-]{4,30}[A-Z0-9]$`)
+
+type GiftCardService interface {
+	IssueGiftCard(ctx context.Context, card *models.GiftCard) error
+	GetGiftCards(ctx context.Context, page, limit int) (*models.PaginatedResponse, error)
+	GetGiftCard(ctx context.Context, id uuid.UUID) (*models.GiftCardDetail, error)
+	SetGiftCardStatus(ctx context.Context, id uuid.UUID, status models.GiftCardStatus) (*models.GiftCard, error)
+	AdjustGiftCard(ctx context.Context, id uuid.UUID, amount float64, note *string, adminID *uuid.UUID) (*models.GiftCard, error)
+	CheckBalance(ctx context.Context, code string) (*models.GiftCardBalance, error)
+	GetStoreCredit(ctx context.Context, userID uuid.UUID, page, limit int) (*models.StoreCreditAccount, error)
+	AdjustStoreCredit(ctx context.Context, userID uuid.UUID, amount float64, reference, note *string, adminID *uuid.UUID) (*models.StoreCreditAccount, error)
+	RedeemTenders(ctx context.Context, customerID *uuid.UUID, codes []string, useStoreCredit bool, amount float64, checkout *TenderCheckout) (*models.TenderRedemption, error)
+	ReleaseTenders(ctx context.Context, reference string) error
+}
+
+// TenderCheckout is a whole checkout that RedeemTenders pays for when the gift
+// cards and store credit cover its total. The checkout is then recorded as
+// completed, and its promotions redeemed, together with the redemption.
+type TenderCheckout struct {
+	Total      float64 // The items less the discount, plus shipping
+	Promotions []models.AppliedPromotion
+	Completed  models.CheckoutEventPayload // Stored as checkout.completed under the redemption reference
+}
+
+type giftCardService struct {
+	repo repository.GiftCardRepository
+}
+
+func NewGiftCardService(repo repository.GiftCardRepository) GiftCardService {
+	return &giftCardService{repo: repo}
+}
+
+// IssueGiftCard creates a gift card, generating a code unless one is given
+func (s *giftCardService) IssueGiftCard(ctx context.Context, card *models.GiftCard) (err error) {
+	ctx, span := tracing.Start(ctx, "GiftCardService.IssueGiftCard")
+	defer func() { tracing.End(span, err) }()
+
+	card.InitialBalance = roundMoney(card.InitialBalance)
+	if card.InitialBalance <= 0 {
+		return errors.New("initial_balance must be greater than 0")
+	}
+	if card.InitialBalance > maxGiftCardBalance {
+		return errors.New("initial_balance must be at most 10000")
+	}
+	if card.ExpiresAt != nil && !card.ExpiresAt.After(time.Now()) {
+		return errors.New("expires_at must be in the future")
+	}
+	card.RecipientEmail = trimOptional(card.RecipientEmail)
+	card.Message = trimOptional(card.Message)
+
+	if card.Code == "" {
+		code, err := newGiftCardCode()
+		if err != nil {
+			return err
+		}
+		card.Code = code
+	} else {
+		card.Code = strings.ToUpper(strings.TrimSpace(card.Code))
+		if !giftCardCodePattern.MatchString(card.Code) {
+			return errors.New("code must be 6-32 letters, digits or dashes")
+		}
+		existing, err := s.repo.GetByCode(ctx, card.Code)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return errors.New("code already in use")
+		}
+	}
+
+	card.ID = uuid.Nil
+	card.Status = models.GiftCardStatusActive
+	return s.repo.Create(ctx, card)
+}
+
+func (s *giftCardService) GetGiftCards(ctx context.Context, page, limit int) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "GiftCardService.GetGiftCards")
+	defer func() { tracing.End(span, err) }()
+
+	cards, total, err := s.repo.GetAll(ctx, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedResponse{
+		Data: cards,
+		Pagination: models.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	}, nil
+}
+
+func (s *giftCardService) GetGiftCard(ctx context.Context, id uuid.UUID) (_ *models.GiftCardDetail, err error) {
+	ctx, span := tracing.Start(ctx, "GiftCardService.GetGiftCard")
+	defer func() { tracing.End(span, err) }()
+
+	card, err := s.getGiftCard(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.repo.GetGiftCardTransactions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.GiftCardDetail{GiftCard: card, Transactions: transactions}, nil
+}
+
+func (s *giftCardService) SetGiftCardStatus(ctx context.Context, id uuid.UUID, status models.GiftCardStatus) (_ *models.GiftCard, err error) {
+	ctx, span := tracing.Start(ctx, "GiftCardService.SetGiftCardStatus")
+	defer func() { tracing.End(span, err) }()
+
+	if status != models.GiftCardStatusActive && status != models.GiftCardStatusDisabled {
+		return nil, errors.New("status must be active or disabled")
+	}
+	if _, err := s.getGiftCard(ctx, id); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateStatus(ctx, id, status); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// AdjustGiftCard corrects a gift card's balance by a signed amount
+func (s *giftCardService) AdjustGiftCard(ctx context.Context, id uuid.UUID, amount float64, note *string, adminID *uuid.UUID) (_ *models.GiftCard, err error) {
+	ctx, span := tracing.Start(ctx, "GiftCardService.AdjustGiftCard")
+	defer func() { tracing.End(span, err) }()
+
+	amount = roundMoney(amount)
+	if amount == 0 {
+		return nil, errors.New("amount must not be zero")
+	}
+	note = trimOptional(note)
+	if note == nil {
+		return nil, errors.New("note is required")
+	}
+	if _, err := s.getGiftCard(ctx, id); err != nil {
+		return nil, err
+	}
+
+	adjusted, err := s.repo.AdjustGiftCard(ctx, id, amount, note, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if !adjusted {
+		return nil, errors.New("adjustment would make the balance negative")
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// CheckBalance looks up a gift card by code. Disabled cards are reported as not
+// found so their codes cannot be probed.
+func (s *giftCardService) CheckBalance(ctx context.Context, code string) (_ *models.GiftCardBalance, err error) {
+	ctx, span := tracing.Start(ctx, "GiftCardService.CheckBalance")
+	defer func() { tracing.End(span, err) }()
+
+	card, err := s.repo.GetByCode(ctx, strings.TrimSpace(code))
+	if err != nil {
+		return nil, err
+	}
+	if card == nil || card.Status != models.GiftCardStatusActive {
+		return nil, errors.New("gift card not found")
+	}
+
+	expired := card.ExpiresAt != nil && !card.ExpiresAt.After(time.Now())
+	return &models.GiftCardBalance{
+		Code:      maskGiftCardCode(card.Code),
+		Balance:   card.Balance,
+		ExpiresAt: card.ExpiresAt,
+		Usable:    !expired && card.Balance > 0,
+	}, nil
+}
+
+// GetStoreCredit returns a user's store credit balance with a page of its transactions
+func (s *giftCardService) GetStoreCredit(ctx context.Context, userID uuid.UUID, page, limit int) (_ *models.StoreCreditAccount, err error) {
+	ctx, span := tracing.Start(ctx, "GiftCardService.GetStoreCredit")
+	defer func() { tracing.End(span, err) }()
+
+	account, err := s.repo.GetStoreCredit(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, total, err := s.repo.GetStoreCreditTransactions(ctx, userID, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	account.Transactions = &models.PaginatedResponse{
+		Data: transactions,
+		Pagination: models.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	}
+	return account, nil
+}
+
+// AdjustStoreCredit adds store credit, e.g. for a return, or corrects it with a negative amount
+func (s *giftCardService) AdjustStoreCredit(ctx context.Context, userID uuid.UUID, amount float64, reference, note *string, adminID *uuid.UUID) (_ *models.StoreCreditAccount, err error) {
+	ctx, span := tracing.Start(ctx, "GiftCardService.AdjustStoreCredit")
+	defer func() { tracing.End(span, err) }()
+
+	amount = roundMoney(amount)
+	if amount == 0 {
+		return nil, errors.New("amount must not be zero")
+	}
+
+	txType := models.BalanceCredit
+	if amount < 0 {
+		txType = models.BalanceAdjust
+	}
+
+	adjusted, err := s.repo.AdjustStoreCredit(ctx, userID, txType, amount, trimOptional(reference), trimOptional(note), adminID)
+	if err != nil {
+		return nil, err
+	}
+	if !adjusted {
+		// Adding credit only fails when there is no such user to open an account for
+		if amount > 0 {
+			return nil, errors.New("user not found")
+		}
+		return nil, errors.New("adjustment would make the balance negative")
+	}
+
+	return s.repo.GetStoreCredit(ctx, userID)
+}
+
+// RedeemTenders spends up to amount from the gift cards and then the customer's
+// store credit. The redemption reference must be released if the checkout is
+// abandoned. If the balances cover the whole checkout they pay for all of it
+// instead; it fails with errPromotionLimitReached if one of the checkout's
+// promotions ran out since the cart was quoted.
+func (s *giftCardService) RedeemTenders(ctx context.Context, customerID *uuid.UUID, codes []string, useStoreCredit bool, amount float64, checkout *TenderCheckout) (_ *models.TenderRedemption, err error) {
+	ctx, span := tracing.Start(ctx, "GiftCardService.RedeemTenders")
+	defer func() { tracing.End(span, err) }()
+
+	if useStoreCredit && customerID == nil {
+		return nil, errors.New("sign in to use store credit")
+	}
+	if len(codes) > maxTenderCodes {
+		return nil, errors.New("gift card limit is 5 per order")
+	}
+
+	now := time.Now()
+	var cardIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+
+		card, err := s.repo.GetByCode(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case card == nil || card.Status != models.GiftCardStatusActive:
+			return nil, errors.New("gift card " + strings.ToUpper(code) + " not found")
+		case card.ExpiresAt != nil && !card.ExpiresAt.After(now):
+			return nil, errors.New("gift card " + maskGiftCardCode(card.Code) + " has expired")
+		case card.Balance <= 0:
+			return nil, errors.New("gift card " + maskGiftCardCode(card.Code) + " has no balance left")
+		}
+
+		if !seen[card.ID] {
+			seen[card.ID] = true
+			cardIDs = append(cardIDs, card.ID)
+		}
+	}
+
+	var creditUserID *uuid.UUID
+	if useStoreCredit {
+		creditUserID = customerID
+	}
+
+	reference := "chk_" + uuid.New().String()
+	var paid *repository.TenderCheckout
+	if checkout != nil {
+		if paid, err = tenderCheckout(reference, customerID, checkout); err != nil {
+			return nil, err
+		}
+	}
+
+	redemption, err := s.repo.Redeem(ctx, reference, cardIDs, creditUserID, roundMoney(amount), now, paid)
+	if err != nil {
+		return nil, err
+	}
+	if redemption == nil {
+		return nil, errPromotionLimitReached
+	}
+	if paid != nil && paid.Events[0].Seq != 0 {
+		metrics.OrderPaid(checkout.Completed.Currency, checkout.Completed.AmountTotal)
+	}
+
+	for i := range redemption.Applied {
+		if redemption.Applied[i].Code != "" {
+			redemption.Applied[i].Code = maskGiftCardCode(redemption.Applied[i].Code)
+		}
+	}
+	return redemption, nil
+}
+
+// ReleaseTenders returns the balances redeemed for an abandoned checkout
+func (s *giftCardService) ReleaseTenders(ctx context.Context, reference string) (err error) {
+	if reference == "" {
+		return nil
+	}
+
+	ctx, span := tracing.Start(ctx, "GiftCardService.ReleaseTenders")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.repo.Release(ctx, reference)
+	return err
+}
+
+// tenderCheckout describes a checkout paid under reference to the repository
+func tenderCheckout(reference string, customerID *uuid.UUID, checkout *TenderCheckout) (*repository.TenderCheckout, error) {
+	payload := checkout.Completed
+	payload.SessionID = reference
+	events, err := checkoutCompletedEvents(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repository.TenderCheckout{
+		Total:      roundMoney(checkout.Total),
+		CustomerID: customerID,
+		Promotions: checkout.Promotions,
+		Events:     events,
+	}, nil
+}
+
+func (s *giftCardService) getGiftCard(ctx context.Context, id uuid.UUID) (*models.GiftCard, error) {
+	card, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if card == nil {
+		return nil, errors.New("gift card not found")
+	}
+	return card, nil
+}
+
+// newGiftCardCode returns a random code such as "ABCD-EFGH-JKLM-NPQR"
+func newGiftCardCode() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	var code strings.Builder
+	for i, v := range b {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+		// The alphabet has 32 characters, so every byte maps to one without bias
+		code.WriteByte(giftCardAlphabet[int(v)%len(giftCardAlphabet)])
+	}
+	return code.String(), nil
+}
+
+// maskGiftCardCode hides all but the last four characters of a code
+func maskGiftCardCode(code string) string {
+	plain := strings.ReplaceAll(code, "-", "")
+	if len(plain) <= 4 {
+		return plain
+	}
+	return "****-" + plain[len(plain)-4:]
+}
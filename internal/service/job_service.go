@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// jobRetryBase and jobRetryMax bound the exponential backoff between attempts
+	jobRetryBase = 10 * time.Second
+	jobRetryMax  = time.Hour
+	// jobLockMargin keeps a job locked a while past its timeout, so a handler that
+	// stops at the deadline can record its result before anyone takes it over
+	jobLockMargin = time.Minute
+	// jobRecordTimeout bounds saving a job's outcome once its handler returned
+	jobRecordTimeout = 10 * time.Second
+	// jobRetention is how long succeeded jobs are kept
+	jobRetention = 7 * 24 * time.Hour
+	// jobCleanupInterval is how often succeeded jobs past retention are deleted
+	jobCleanupInterval = time.Hour
+)
+
+// JobHandler runs one job. A returned error retries the job with backoff until it
+// is out of attempts; wrap it in PermanentJobError to give up on the job at once.
+// ctx is cancelled when the job times out.
+type JobHandler func(ctx context.Context, job *models.Job) error
+
+// JobOptions controls how a job is queued
+type JobOptions struct {
+	RunAt       time.Time // When to run the job; zero runs it as soon as possible
+	UniqueKey   string    // Skips enqueueing while a pending or running job has the same key
+	MaxAttempts int       // Zero uses the configured default
+}
+
+type permanentJobError struct {
+	err error
+}
+
+func (e *permanentJobError) Error() string { return e.err.Error() }
+func (e *permanentJobError) Unwrap() error { return e.err }
+
+// PermanentJobError marks a job failure that retrying cannot fix, such as a
+// malformed payload
+func PermanentJobError(err error) error {
+	return &permanentJobError{err: err}
+}
+
+type JobService interface {
+	Register(kind string, handler JobHandler)
+	NewJob(kind string, payload interface{}, opts JobOptions) (*models.Job, error)
+	Enqueue(ctx context.Context, kind string, payload interface{}, opts JobOptions) (*models.Job, error)
+	GetJobs(ctx context.Context, status *models.JobStatus, kind *string, page, limit int) (*models.PaginatedResponse, error)
+	GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	GetStats(ctx context.Context) (*models.JobStats, error)
+	RetryJob(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	Run(ctx context.Context, workers int)
+}
+
+type jobService struct {
+	repo repository.JobRepository
+	cfg  config.JobsConfig
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+}
+
+func NewJobService(repo repository.JobRepository, cfg config.JobsConfig) JobService {
+	return &jobService{
+		repo:     repo,
+		cfg:      cfg,
+		handlers: make(map[string]JobHandler),
+	}
+}
+
+// Register sets the handler of a job kind. Workers only claim jobs of registered kinds.
+func (s *jobService) Register(kind string, handler JobHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[kind] = handler
+}
+
+// NewJob builds a job with a JSON payload without queueing it, for a repository
+// to store in the same transaction as the change that calls for it
+func (s *jobService) NewJob(kind string, payload interface{}, opts JobOptions) (*models.Job, error) {
+	if strings.TrimSpace(kind) == "" {
+		return nil, errors.New("job kind is required")
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s job payload: %w", kind, err)
+	}
+
+	job := &models.Job{
+		Kind:        kind,
+		Payload:     data,
+		RunAt:       opts.RunAt,
+		MaxAttempts: opts.MaxAttempts,
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = s.cfg.MaxAttempts
+	}
+	if opts.UniqueKey != "" {
+		key := opts.UniqueKey
+		job.UniqueKey = &key
+	}
+	return job, nil
+}
+
+// Enqueue queues a job with a JSON payload. It returns nil without queueing
+// anything if a job with the same unique key is pending or running.
+func (s *jobService) Enqueue(ctx context.Context, kind string, payload interface{}, opts JobOptions) (_ *models.Job, err error) {
+	ctx, span := tracing.Start(ctx, "JobService.Enqueue")
+	defer func() { tracing.End(span, err) }()
+
+	job, err := s.NewJob(kind, payload, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	enqueued, err := s.repo.Enqueue(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if !enqueued {
+		return nil, nil
+	}
+	return job, nil
+}
+
+func (s *jobService) GetJobs(ctx context.Context, status *models.JobStatus, kind *string, page, limit int) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "JobService.GetJobs")
+	defer func() { tracing.End(span, err) }()
+
+	if status != nil {
+		switch *status {
+		case models.JobStatusPending, models.JobStatusRunning, models.JobStatusSucceeded, models.JobStatusDead:
+		default:
+			return nil, errors.New("status must be pending, running, succeeded or dead")
+		}
+	}
+
+	jobs, total, err := s.repo.GetAll(ctx, status, kind, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedResponse{
+		Data: jobs,
+		Pagination: models.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	}, nil
+}
+
+func (s *jobService) GetJob(ctx context.Context, id uuid.UUID) (_ *models.Job, err error) {
+	ctx, span := tracing.Start(ctx, "JobService.GetJob")
+	defer func() { tracing.End(span, err) }()
+
+	job, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, errors.New("job not found")
+	}
+	return job, nil
+}
+
+func (s *jobService) GetStats(ctx context.Context) (_ *models.JobStats, err error) {
+	ctx, span := tracing.Start(ctx, "JobService.GetStats")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.GetStats(ctx)
+}
+
+// RetryJob moves a dead job back to the queue with fresh attempts
+func (s *jobService) RetryJob(ctx context.Context, id uuid.UUID) (_ *models.Job, err error) {
+	ctx, span := tracing.Start(ctx, "JobService.RetryJob")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.GetJob(ctx, id); err != nil {
+		return nil, err
+	}
+
+	retried, err := s.repo.Retry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !retried {
+		return nil, errors.New("only dead jobs without a queued duplicate can be retried")
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Run works through due jobs with up to workers running at once, until ctx is
+// cancelled. It then stops claiming jobs and returns once the running ones have
+// finished, each within the configured timeout.
+func (s *jobService) Run(ctx context.Context, workers int) {
+	if workers <= 0 {
+		return
+	}
+
+	kinds := s.kinds()
+	if len(kinds) == 0 {
+		slog.WarnContext(ctx, "No job handlers registered, job workers not started")
+		return
+	}
+	slog.InfoContext(ctx, "Starting job workers", "workers", workers, "kinds", kinds)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// A token in free is an idle worker
+	free := make(chan struct{}, workers)
+	for i := 0; i < workers; i++ {
+		free <- struct{}{}
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	lastCleanup := time.Time{}
+
+	for {
+		if time.Since(lastCleanup) >= jobCleanupInterval {
+			lastCleanup = time.Now()
+			if deleted, err := s.repo.DeleteSucceeded(ctx, time.Now().Add(-jobRetention)); err != nil {
+				slog.ErrorContext(ctx, "Failed to delete old jobs", "error", err)
+			} else if deleted > 0 {
+				slog.InfoContext(ctx, "Deleted succeeded jobs", "jobs", deleted)
+			}
+		}
+
+		idle := len(free)
+		var jobs []*models.Job
+		if idle > 0 {
+			now := time.Now()
+			var err error
+			jobs, err = s.repo.Claim(ctx, kinds, now, now.Add(s.cfg.Timeout+jobLockMargin), idle)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to claim jobs", "error", err)
+			}
+		}
+
+		for _, job := range jobs {
+			<-free
+			wg.Add(1)
+			go func(job *models.Job) {
+				defer wg.Done()
+				defer func() { free <- struct{}{} }()
+				s.runJob(ctx, job)
+			}(job)
+		}
+
+		// A full batch suggests more jobs are due, so claim again right away
+		if idle > 0 && len(jobs) == idle {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runJob runs a claimed job and records the outcome
+func (s *jobService) runJob(ctx context.Context, job *models.Job) {
+	s.mu.RLock()
+	handler := s.handlers[job.Kind]
+	s.mu.RUnlock()
+
+	ctx, span := tracing.Tracer().Start(ctx, "job "+job.Kind,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("job.id", job.ID.String()),
+			attribute.String("job.kind", job.Kind),
+			attribute.Int("job.attempt", job.Attempts),
+		),
+	)
+
+	// Running jobs finish on shutdown; only the timeout cuts them short
+	handlerCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.cfg.Timeout)
+	err := safeRunJob(handlerCtx, handler, job)
+	cancel()
+	tracing.End(span, err)
+
+	// The outcome is recorded even when the handler ran out of time or the
+	// worker is shutting down
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobRecordTimeout)
+	defer cancel()
+
+	if err == nil {
+		if err := s.repo.Complete(recordCtx, job.ID, job.Attempts, time.Now()); err != nil {
+			slog.ErrorContext(ctx, "Failed to complete job", "kind", job.Kind, "job_id", job.ID, "error", err)
+		}
+		return
+	}
+
+	var permanent *permanentJobError
+	var retryAt *time.Time
+	if !errors.As(err, &permanent) && job.Attempts < job.MaxAttempts {
+		at := time.Now().Add(jobBackoff(job.Attempts))
+		retryAt = &at
+		slog.WarnContext(ctx, "Job failed, retrying", "kind", job.Kind, "job_id", job.ID,
+			"attempt", job.Attempts, "max_attempts", job.MaxAttempts, "retry_at", at, "error", err)
+	} else {
+		slog.ErrorContext(ctx, "Job failed, moved to dead letters", "kind", job.Kind, "job_id", job.ID, "attempts", job.Attempts, "error", err)
+	}
+
+	if err := s.repo.Fail(recordCtx, job.ID, job.Attempts, err.Error(), retryAt); err != nil {
+		slog.ErrorContext(ctx, "Failed to record job failure", "kind", job.Kind, "job_id", job.ID, "error", err)
+	}
+}
+
+func (s *jobService) kinds() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	kinds := make([]string, 0, len(s.handlers))
+	for kind := range s.handlers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// safeRunJob runs a handler, turning a panic into an error
+func safeRunJob(ctx context.Context, handler JobHandler, job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// jobBackoff is the wait after the given number of failed attempts, with up to
+// a tenth added at random so jobs that failed together do not retry together
+func jobBackoff(attempts int) time.Duration {
+	wait := jobRetryBase
+	for i := 1; i < attempts && wait < jobRetryMax; i++ {
+		wait *= 2
+	}
+	if wait > jobRetryMax {
+		wait = jobRetryMax
+	}
+	return wait + time.Duration(rand.Int63n(int64(wait/10)+1))
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// jobOutcome is a Complete or Fail call, with whether its context was still live
+type jobOutcome struct {
+	id        uuid.UUID
+	attempt   int
+	succeeded bool
+	lastError string
+	retryAt   *time.Time
+	ctxErr    error
+}
+
+// outcomeJobRepository records job outcomes and answers enqueues like the
+// unique key index does
+type outcomeJobRepository struct {
+	repository.JobRepository
+
+	mu       sync.Mutex
+	queued   []*models.Job
+	outcomes []jobOutcome
+}
+
+func (r *outcomeJobRepository) Enqueue(_ context.Context, job *models.Job) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, q := range r.queued {
+		if job.UniqueKey != nil && q.UniqueKey != nil && *q.UniqueKey == *job.UniqueKey {
+			return false, nil
+		}
+	}
+	job.ID = uuid.New()
+	r.queued = append(r.queued, job)
+	return true, nil
+}
+
+func (r *outcomeJobRepository) Complete(ctx context.Context, id uuid.UUID, attempt int, _ time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outcomes = append(r.outcomes, jobOutcome{id: id, attempt: attempt, succeeded: true, ctxErr: ctx.Err()})
+	return nil
+}
+
+func (r *outcomeJobRepository) Fail(ctx context.Context, id uuid.UUID, attempt int, lastError string, retryAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outcomes = append(r.outcomes, jobOutcome{id: id, attempt: attempt, lastError: lastError, retryAt: retryAt, ctxErr: ctx.Err()})
+	return nil
+}
+
+func newOutcomeJobService(timeout time.Duration) (*jobService, *outcomeJobRepository) {
+	repo := &outcomeJobRepository{}
+	return NewJobService(repo, config.JobsConfig{Timeout: timeout, MaxAttempts: 3}).(*jobService), repo
+}
+
+func TestEnqueueSkipsQueuedUniqueKey(t *testing.T) {
+	ctx := context.Background()
+	jobs, repo := newOutcomeJobService(time.Minute)
+
+	first, err := jobs.Enqueue(ctx, "search.refresh_vectors", map[string]string{"language": "swedish"}, JobOptions{UniqueKey: "search:swedish"})
+	if err != nil || first == nil {
+		t.Fatalf("first enqueue = %v, %v", first, err)
+	}
+	if first.MaxAttempts != 3 || string(first.Payload) != `{"language":"swedish"}` {
+		t.Errorf("queued %+v", first)
+	}
+	duplicate, err := jobs.Enqueue(ctx, "search.refresh_vectors", nil, JobOptions{UniqueKey: "search:swedish"})
+	if err != nil || duplicate != nil {
+		t.Errorf("duplicate enqueue = %v, %v", duplicate, err)
+	}
+	if _, err := jobs.Enqueue(ctx, "other", nil, JobOptions{MaxAttempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.queued) != 2 || repo.queued[1].MaxAttempts != 1 || repo.queued[1].UniqueKey != nil {
+		t.Errorf("queued %+v", repo.queued)
+	}
+	if _, err := jobs.Enqueue(ctx, " ", nil, JobOptions{}); err == nil {
+		t.Error("blank kind accepted")
+	}
+}
+
+func TestRunJobRecordsOutcome(t *testing.T) {
+	tests := []struct {
+		name      string
+		attempts  int
+		handler   JobHandler
+		succeeded bool
+		retry     bool
+	}{
+		{"success", 1, func(context.Context, *models.Job) error { return nil }, true, false},
+		{"failure retries", 1, func(context.Context, *models.Job) error { return errors.New("carrier unavailable") }, false, true},
+		{"last attempt", 3, func(context.Context, *models.Job) error { return errors.New("carrier unavailable") }, false, false},
+		{"permanent failure", 1, func(context.Context, *models.Job) error { return PermanentJobError(errors.New("bad payload")) }, false, false},
+		{"panic retries", 1, func(context.Context, *models.Job) error { panic("nil map") }, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, repo := newOutcomeJobService(time.Minute)
+			jobs.Register("test", tt.handler)
+			job := &models.Job{ID: uuid.New(), Kind: "test", Attempts: tt.attempts, MaxAttempts: 3}
+
+			before := time.Now()
+			jobs.runJob(context.Background(), job)
+
+			if len(repo.outcomes) != 1 {
+				t.Fatalf("recorded %d outcomes", len(repo.outcomes))
+			}
+			got := repo.outcomes[0]
+			if got.id != job.ID || got.attempt != tt.attempts || got.succeeded != tt.succeeded || (got.retryAt != nil) != tt.retry {
+				t.Errorf("recorded %+v", got)
+			}
+			if got.retryAt != nil && got.retryAt.Sub(before) < jobRetryBase {
+				t.Errorf("retry at %v, less than %v away", got.retryAt, jobRetryBase)
+			}
+		})
+	}
+}
+
+func TestRunJobRecordsTimeoutWithLiveContext(t *testing.T) {
+	jobs, repo := newOutcomeJobService(20 * time.Millisecond)
+	jobs.Register("slow", func(ctx context.Context, _ *models.Job) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	jobs.runJob(context.Background(), &models.Job{ID: uuid.New(), Kind: "slow", Attempts: 1, MaxAttempts: 3})
+
+	if len(repo.outcomes) != 1 {
+		t.Fatalf("recorded %d outcomes", len(repo.outcomes))
+	}
+	got := repo.outcomes[0]
+	if got.ctxErr != nil {
+		t.Errorf("failure recorded with a done context: %v", got.ctxErr)
+	}
+	if got.lastError != context.DeadlineExceeded.Error() || got.retryAt == nil {
+		t.Errorf("recorded %+v", got)
+	}
+}
+
+func TestRunJobFinishesOnShutdown(t *testing.T) {
+	jobs, repo := newOutcomeJobService(time.Minute)
+	started := make(chan struct{})
+	jobs.Register("test", func(ctx context.Context, _ *models.Job) error {
+		close(started)
+		time.Sleep(20 * time.Millisecond)
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		jobs.runJob(ctx, &models.Job{ID: uuid.New(), Kind: "test", Attempts: 1, MaxAttempts: 3})
+	}()
+	<-started
+	cancel()
+	<-done
+
+	if len(repo.outcomes) != 1 || !repo.outcomes[0].succeeded || repo.outcomes[0].ctxErr != nil {
+		t.Errorf("recorded %+v", repo.outcomes)
+	}
+}
+
+func TestJobBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		min      time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{12, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if wait := jobBackoff(tt.attempts); wait < tt.min || wait > tt.min+tt.min/10 {
+				t.Errorf("jobBackoff(%d) = %v, want between %v and %v", tt.attempts, wait, tt.min, tt.min+tt.min/10)
+			}
+		}
+	}
+}
@@ -0,0 +1,491 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/payout"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+// payoutBatchLock is how long an execution holds a payout batch before another
+// may take it over, in case the first one crashed
+const payoutBatchLock = 30 * time.Minute
+
+type LedgerService interface {
+	GetCommissionRates(ctx context.Context) ([]models.CommissionRate, error)
+	SetCommissionRate(ctx context.Context, rate *models.CommissionRate) error
+	DeleteCommissionRate(ctx context.Context, id uuid.UUID) error
+	RecordOrder(ctx context.Context, orderID uuid.UUID) (int, error)
+	RecordRefund(ctx context.Context, orderItemID uuid.UUID, amount float64) ([]*models.LedgerEntry, error)
+	GetBalance(ctx context.Context, vendorID uuid.UUID) (*models.VendorBalance, error)
+	GetStatement(ctx context.Context, vendorID uuid.UUID, from, to time.Time) (*models.VendorStatement, error)
+	CreatePayoutBatch(ctx context.Context, periodEnd time.Time) (*models.PayoutBatch, error)
+	GetPayoutBatches(ctx context.Context, page, limit int) (*models.PaginatedResponse, error)
+	GetPayoutBatch(ctx context.Context, id uuid.UUID) (*models.PayoutBatch, error)
+	ExecutePayoutBatch(ctx context.Context, id uuid.UUID) (*models.PayoutBatch, error)
+}
+
+type ledgerService struct {
+	repo         repository.LedgerRepository
+	vendorRepo   repository.VendorRepository
+	categoryRepo repository.CategoryRepository
+	executor     payout.Executor
+	currency     string
+}
+
+func NewLedgerService(repo repository.LedgerRepository, vendorRepo repository.VendorRepository, categoryRepo repository.CategoryRepository, executor payout.Executor, currency string) LedgerService {
+	return &ledgerService{
+		repo:         repo,
+		vendorRepo:   vendorRepo,
+		categoryRepo: categoryRepo,
+		executor:     executor,
+		currency:     currency,
+	}
+}
+
+func (s *ledgerService) GetCommissionRates(ctx context.Context) (_ []models.CommissionRate, err error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.GetCommissionRates")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.GetCommissionRates(ctx)
+}
+
+// SetCommissionRate creates or replaces the rate for a scope. Category and
+// vendor rates must name their category or vendor; the global rate names neither.
+func (s *ledgerService) SetCommissionRate(ctx context.Context, rate *models.CommissionRate) (err error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.SetCommissionRate")
+	defer func() { tracing.End(span, err) }()
+
+	if rate.Rate < 0 || rate.Rate > 1 {
+		return errors.New("commission rate must be between 0 and 1")
+	}
+
+	switch rate.Scope {
+	case models.CommissionScopeGlobal:
+		rate.CategoryID, rate.VendorID = nil, nil
+	case models.CommissionScopeCategory:
+		if rate.CategoryID == nil {
+			return errors.New("category_id is required for a category commission")
+		}
+		category, err := s.categoryRepo.FindByID(ctx, rate.CategoryID.String())
+		if err != nil {
+			return err
+		}
+		if category == nil {
+			return errors.New("category not found")
+		}
+		rate.VendorID = nil
+	case models.CommissionScopeVendor:
+		if rate.VendorID == nil {
+			return errors.New("vendor_id is required for a vendor commission")
+		}
+		vendor, err := s.vendorRepo.GetByID(ctx, *rate.VendorID)
+		if err != nil {
+			return err
+		}
+		if vendor == nil {
+			return errors.New("vendor not found")
+		}
+		rate.CategoryID = nil
+	default:
+		return errors.New("invalid commission scope")
+	}
+
+	return s.repo.SaveCommissionRate(ctx, rate)
+}
+
+// DeleteCommissionRate removes a category or vendor override. The global rate can only be changed.
+func (s *ledgerService) DeleteCommissionRate(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.DeleteCommissionRate")
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.repo.DeleteCommissionRate(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("commission rate not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// RecordOrder credits each vendor with its order items and debits the
+// commission. Items already recorded are skipped. It returns the number of
+// ledger entries added.
+func (s *ledgerService) RecordOrder(ctx context.Context, orderID uuid.UUID) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.RecordOrder")
+	defer func() { tracing.End(span, err) }()
+
+	items, err := s.repo.GetOrderItems(ctx, orderID)
+	if err != nil {
+		return 0, err
+	}
+	if len(items) == 0 {
+		return 0, errors.New("order not found")
+	}
+
+	rates, err := s.repo.GetCommissionRates(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	paths := map[uuid.UUID][]models.Category{}
+	var entries []*models.LedgerEntry
+	for i := range items {
+		item := items[i]
+
+		var path []models.Category
+		if item.CategoryID != nil {
+			var ok bool
+			if path, ok = paths[*item.CategoryID]; !ok {
+				if path, err = s.categoryRepo.GetPath(ctx, item.CategoryID.String()); err != nil {
+					return 0, err
+				}
+				paths[*item.CategoryID] = path
+			}
+		}
+
+		rate := commissionRate(rates, item.VendorID, path)
+		sale := roundMoney(item.Total)
+		description := fmt.Sprintf("%d x %s", item.Quantity, item.Name)
+
+		entries = append(entries,
+			&models.LedgerEntry{
+				VendorID:    item.VendorID,
+				OrderID:     &item.OrderID,
+				OrderItemID: &item.ID,
+				Type:        models.LedgerEntrySale,
+				Amount:      sale,
+				Currency:    s.currency,
+				Description: &description,
+			},
+			&models.LedgerEntry{
+				VendorID:       item.VendorID,
+				OrderID:        &item.OrderID,
+				OrderItemID:    &item.ID,
+				Type:           models.LedgerEntryCommission,
+				Amount:         -roundMoney(sale * rate),
+				Currency:       s.currency,
+				CommissionRate: &rate,
+			},
+		)
+	}
+
+	return s.repo.AddEntries(ctx, entries)
+}
+
+// RecordRefund debits the vendor for a (partial) refund of an order item and
+// returns the commission charged on the refunded amount
+func (s *ledgerService) RecordRefund(ctx context.Context, orderItemID uuid.UUID, amount float64) (_ []*models.LedgerEntry, err error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.RecordRefund")
+	defer func() { tracing.End(span, err) }()
+
+	amount = roundMoney(amount)
+	if amount <= 0 {
+		return nil, errors.New("refund amount must be positive")
+	}
+
+	item, err := s.repo.GetOrderItem(ctx, orderItemID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, errors.New("order item not found")
+	}
+
+	existing, err := s.repo.GetItemEntries(ctx, orderItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	var sale, refunded, rate float64
+	recorded := false
+	for _, entry := range existing {
+		switch entry.Type {
+		case models.LedgerEntrySale:
+			sale = entry.Amount
+			recorded = true
+		case models.LedgerEntryCommission:
+			if entry.CommissionRate != nil {
+				rate = *entry.CommissionRate
+			}
+		case models.LedgerEntryRefund:
+			refunded -= entry.Amount
+		}
+	}
+	if !recorded {
+		return nil, errors.New("order item has not been recorded in the ledger")
+	}
+	if roundMoney(refunded+amount) > sale {
+		return nil, fmt.Errorf("refund exceeds the remaining item total of %.2f", sale-refunded)
+	}
+
+	description := fmt.Sprintf("Refund of %s", item.Name)
+	entries := []*models.LedgerEntry{
+		{
+			VendorID:    item.VendorID,
+			OrderID:     &item.OrderID,
+			OrderItemID: &item.ID,
+			Type:        models.LedgerEntryRefund,
+			Amount:      -amount,
+			Currency:    s.currency,
+			Description: &description,
+		},
+		{
+			VendorID:       item.VendorID,
+			OrderID:        &item.OrderID,
+			OrderItemID:    &item.ID,
+			Type:           models.LedgerEntryCommissionRefund,
+			Amount:         roundMoney(amount * rate),
+			Currency:       s.currency,
+			CommissionRate: &rate,
+		},
+	}
+
+	// Checked again with the item locked, in case another refund was recorded meanwhile
+	added, err := s.repo.AddRefund(ctx, orderItemID, entries)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, errors.New("refund exceeds the remaining item total")
+	}
+
+	return entries, nil
+}
+
+func (s *ledgerService) GetBalance(ctx context.Context, vendorID uuid.UUID) (_ *models.VendorBalance, err error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.GetBalance")
+	defer func() { tracing.End(span, err) }()
+
+	balance, err := s.repo.GetBalance(ctx, vendorID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance.Currency = s.currency
+	return balance, nil
+}
+
+// GetStatement returns the vendor's ledger entries created in [from, to)
+func (s *ledgerService) GetStatement(ctx context.Context, vendorID uuid.UUID, from, to time.Time) (_ *models.VendorStatement, err error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.GetStatement")
+	defer func() { tracing.End(span, err) }()
+
+	if !from.Before(to) {
+		return nil, errors.New("statement start must be before its end")
+	}
+
+	opening, err := s.repo.GetBalanceAt(ctx, vendorID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.GetEntries(ctx, vendorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	closing := opening
+	for _, entry := range entries {
+		closing += entry.Amount
+	}
+
+	return &models.VendorStatement{
+		VendorID:       vendorID,
+		From:           from,
+		To:             to,
+		OpeningBalance: roundMoney(opening),
+		ClosingBalance: roundMoney(closing),
+		Entries:        entries,
+	}, nil
+}
+
+// CreatePayoutBatch rolls up every approved vendor's unpaid balance up to periodEnd
+func (s *ledgerService) CreatePayoutBatch(ctx context.Context, periodEnd time.Time) (_ *models.PayoutBatch, err error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.CreatePayoutBatch")
+	defer func() { tracing.End(span, err) }()
+
+	if periodEnd.After(time.Now()) {
+		return nil, errors.New("payout period cannot end in the future")
+	}
+
+	batch, err := s.repo.CreatePayoutBatch(ctx, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, errors.New("no vendor balances to pay out")
+	}
+
+	return batch, nil
+}
+
+func (s *ledgerService) GetPayoutBatches(ctx context.Context, page, limit int) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.GetPayoutBatches")
+	defer func() { tracing.End(span, err) }()
+
+	// Set default pagination
+	if limit <= 0 {
+		limit = 20
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	batches, total, err := s.repo.GetPayoutBatches(ctx, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &models.PaginatedResponse{
+		Data: batches,
+		Pagination: models.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+func (s *ledgerService) GetPayoutBatch(ctx context.Context, id uuid.UUID) (_ *models.PayoutBatch, err error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.GetPayoutBatch")
+	defer func() { tracing.End(span, err) }()
+
+	batch, err := s.repo.GetPayoutBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, errors.New("payout batch not found")
+	}
+
+	return batch, nil
+}
+
+// ExecutePayoutBatch transfers each pending payout in the batch. Payouts the
+// provider rejects are failed and release their entries to the next batch.
+// Payouts that fail otherwise stay pending, and running it again retries them
+// under the same payout ID, so a transfer that went through is not repeated.
+func (s *ledgerService) ExecutePayoutBatch(ctx context.Context, id uuid.UUID) (_ *models.PayoutBatch, err error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.ExecutePayoutBatch")
+	defer func() { tracing.End(span, err) }()
+
+	batch, err := s.GetPayoutBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	claimed, err := s.repo.ClaimPayoutBatch(ctx, id, now, now.Add(payoutBatchLock))
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		if batch.Status == models.PayoutBatchProcessing {
+			return nil, errors.New("payout batch is already being executed")
+		}
+		return nil, errors.New("payout batch has already been executed")
+	}
+
+	payErr := s.transferPayouts(ctx, batch)
+	// Finish even after an error, so the batch can be executed again
+	if err := s.repo.FinishPayoutBatch(context.WithoutCancel(ctx), id); err != nil {
+		return nil, err
+	}
+	if payErr != nil {
+		return nil, payErr
+	}
+
+	return s.GetPayoutBatch(ctx, id)
+}
+
+// transferPayouts makes the transfers of a claimed batch's pending payouts
+func (s *ledgerService) transferPayouts(ctx context.Context, batch *models.PayoutBatch) error {
+	for _, p := range batch.Payouts {
+		if p.Status != models.PayoutStatusPending {
+			continue
+		}
+
+		vendor, err := s.vendorRepo.GetByID(ctx, p.VendorID)
+		if err != nil {
+			return err
+		}
+
+		destination := ""
+		if vendor != nil && vendor.StripeAccountID != nil {
+			destination = *vendor.StripeAccountID
+		}
+
+		transferID, err := s.executor.Transfer(ctx, payout.Transfer{
+			PayoutID:    p.ID,
+			BatchID:     batch.ID,
+			Destination: destination,
+			Amount:      p.Amount,
+			Currency:    p.Currency,
+		})
+		if errors.Is(err, payout.ErrRejected) {
+			slog.WarnContext(ctx, "Payout rejected", "payout_id", p.ID, "vendor_id", p.VendorID, "error", err)
+			if err := s.repo.MarkPayoutFailed(ctx, p.ID, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Payout failed, left pending for retry", "payout_id", p.ID, "vendor_id", p.VendorID, "error", err)
+			continue
+		}
+
+		if err := s.repo.MarkPayoutPaid(ctx, p.ID, transferID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commissionRate picks the most specific rate: the vendor's own rate, then the
+// nearest category in the product's category path, then the global rate
+func commissionRate(rates []models.CommissionRate, vendorID uuid.UUID, path []models.Category) float64 {
+	var global float64
+	categoryRates := map[uuid.UUID]float64{}
+	for _, r := range rates {
+		switch r.Scope {
+		case models.CommissionScopeVendor:
+			if r.VendorID != nil && *r.VendorID == vendorID {
+				return r.Rate
+			}
+		case models.CommissionScopeCategory:
+			if r.CategoryID != nil {
+				categoryRates[*r.CategoryID] = r.Rate
+			}
+		case models.CommissionScopeGlobal:
+			global = r.Rate
+		}
+	}
+
+	// The path runs from the root down, so walk it backwards from the product's own category
+	for i := len(path) - 1; i >= 0; i-- {
+		if rate, ok := categoryRates[path[i].ID]; ok {
+			return rate
+		}
+	}
+
+	return global
+}
+
+// roundMoney rounds an amount to whole cents (öre)
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
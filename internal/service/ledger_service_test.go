@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/payout"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// memoryLedgerRepository keeps one payout batch and the entries of one order item
+type memoryLedgerRepository struct {
+	repository.LedgerRepository
+	batch   *models.PayoutBatch
+	item    *repository.LedgerOrderItem
+	entries []models.LedgerEntry
+	refunds int  // AddRefund calls that recorded entries
+	full    bool // AddRefund finds the item already fully refunded
+}
+
+func (r *memoryLedgerRepository) GetPayoutBatch(_ context.Context, id uuid.UUID) (*models.PayoutBatch, error) {
+	if r.batch == nil || r.batch.ID != id {
+		return nil, nil
+	}
+	batch := *r.batch
+	batch.Payouts = append([]models.VendorPayout(nil), r.batch.Payouts...)
+	return &batch, nil
+}
+
+func (r *memoryLedgerRepository) payout(id uuid.UUID) *models.VendorPayout {
+	for i := range r.batch.Payouts {
+		if r.batch.Payouts[i].ID == id {
+			return &r.batch.Payouts[i]
+		}
+	}
+	return nil
+}
+
+func (r *memoryLedgerRepository) ClaimPayoutBatch(_ context.Context, id uuid.UUID, _, _ time.Time) (bool, error) {
+	if r.batch.Status != models.PayoutBatchPending {
+		return false, nil
+	}
+	r.batch.Status = models.PayoutBatchProcessing
+	return true, nil
+}
+
+func (r *memoryLedgerRepository) MarkPayoutPaid(_ context.Context, id uuid.UUID, transferID string) error {
+	p := r.payout(id)
+	p.Status = models.PayoutStatusPaid
+	p.TransferID = &transferID
+	return nil
+}
+
+func (r *memoryLedgerRepository) MarkPayoutFailed(_ context.Context, id uuid.UUID, reason string) error {
+	p := r.payout(id)
+	p.Status = models.PayoutStatusFailed
+	p.FailureReason = &reason
+	return nil
+}
+
+func (r *memoryLedgerRepository) FinishPayoutBatch(context.Context, uuid.UUID) error {
+	r.batch.Status = models.PayoutBatchCompleted
+	for _, p := range r.batch.Payouts {
+		switch p.Status {
+		case models.PayoutStatusPending:
+			r.batch.Status = models.PayoutBatchPending
+			return nil
+		case models.PayoutStatusFailed:
+			r.batch.Status = models.PayoutBatchPartiallyFailed
+		}
+	}
+	return nil
+}
+
+func (r *memoryLedgerRepository) GetOrderItem(context.Context, uuid.UUID) (*repository.LedgerOrderItem, error) {
+	return r.item, nil
+}
+
+func (r *memoryLedgerRepository) GetItemEntries(context.Context, uuid.UUID) ([]models.LedgerEntry, error) {
+	return r.entries, nil
+}
+
+func (r *memoryLedgerRepository) AddRefund(_ context.Context, _ uuid.UUID, entries []*models.LedgerEntry) (bool, error) {
+	if r.full {
+		return false, nil
+	}
+	r.refunds++
+	return true, nil
+}
+
+// memoryVendorRepository serves vendors by ID
+type memoryVendorRepository struct {
+	repository.VendorRepository
+	vendors map[uuid.UUID]*models.Vendor
+}
+
+func (r *memoryVendorRepository) GetByID(_ context.Context, id uuid.UUID) (*models.Vendor, error) {
+	return r.vendors[id], nil
+}
+
+func newPayoutBatch(vendorIDs ...uuid.UUID) *models.PayoutBatch {
+	batch := &models.PayoutBatch{ID: uuid.New(), Status: models.PayoutBatchPending}
+	for i, vendorID := range vendorIDs {
+		batch.Payouts = append(batch.Payouts, models.VendorPayout{
+			ID:       uuid.New(),
+			BatchID:  batch.ID,
+			VendorID: vendorID,
+			Amount:   float64(100 * (i + 1)),
+			Currency: "usd",
+			Status:   models.PayoutStatusPending,
+		})
+	}
+	return batch
+}
+
+func TestExecutePayoutBatchTransfersEachPayoutOnce(t *testing.T) {
+	connected, unconnected := uuid.New(), uuid.New()
+	account := "acct_123"
+	repo := &memoryLedgerRepository{batch: newPayoutBatch(connected, unconnected)}
+	vendors := &memoryVendorRepository{vendors: map[uuid.UUID]*models.Vendor{
+		connected:   {ID: connected, StripeAccountID: &account},
+		unconnected: {ID: unconnected},
+	}}
+	executor := payout.NewFakeExecutor()
+	ledger := NewLedgerService(repo, vendors, nil, executor, "usd")
+
+	batch, err := ledger.ExecutePayoutBatch(context.Background(), repo.batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Status != models.PayoutBatchCompleted {
+		t.Errorf("batch status = %s, want completed", batch.Status)
+	}
+	if len(executor.Transfers) != 2 {
+		t.Fatalf("made %d transfers, want 2", len(executor.Transfers))
+	}
+	first := executor.Transfers[0]
+	if first.PayoutID != batch.Payouts[0].ID || first.Destination != account || first.Amount != 100 || first.Currency != "usd" {
+		t.Errorf("first transfer = %+v", first)
+	}
+	if executor.Transfers[1].Destination != "" {
+		t.Errorf("vendor without a payout account got destination %q", executor.Transfers[1].Destination)
+	}
+	for _, p := range batch.Payouts {
+		if p.Status != models.PayoutStatusPaid || p.TransferID == nil || *p.TransferID != "fake_tr_"+p.ID.String() {
+			t.Errorf("payout %s: status %s, transfer %v", p.ID, p.Status, p.TransferID)
+		}
+	}
+
+	if _, err := ledger.ExecutePayoutBatch(context.Background(), repo.batch.ID); err == nil || err.Error() != "payout batch has already been executed" {
+		t.Errorf("second execution: err = %v", err)
+	}
+	if len(executor.Transfers) != 2 {
+		t.Errorf("second execution made %d more transfers", len(executor.Transfers)-2)
+	}
+}
+
+func TestExecutePayoutBatchRecordsFailedTransfers(t *testing.T) {
+	vendorID := uuid.New()
+	repo := &memoryLedgerRepository{batch: newPayoutBatch(vendorID)}
+	vendors := &memoryVendorRepository{vendors: map[uuid.UUID]*models.Vendor{vendorID: {ID: vendorID}}}
+	executor := payout.NewFakeExecutor()
+	executor.Err = fmt.Errorf("%w: no destination account", payout.ErrRejected)
+	ledger := NewLedgerService(repo, vendors, nil, executor, "usd")
+
+	batch, err := ledger.ExecutePayoutBatch(context.Background(), repo.batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Status != models.PayoutBatchPartiallyFailed {
+		t.Errorf("batch status = %s, want partially_failed", batch.Status)
+	}
+	p := batch.Payouts[0]
+	if p.Status != models.PayoutStatusFailed || p.FailureReason == nil || *p.FailureReason != "transfer rejected: no destination account" {
+		t.Errorf("payout: status %s, reason %v", p.Status, p.FailureReason)
+	}
+}
+
+func TestExecutePayoutBatchRetriesTemporaryFailures(t *testing.T) {
+	vendorID := uuid.New()
+	account := "acct_123"
+	repo := &memoryLedgerRepository{batch: newPayoutBatch(vendorID)}
+	vendors := &memoryVendorRepository{vendors: map[uuid.UUID]*models.Vendor{vendorID: {ID: vendorID, StripeAccountID: &account}}}
+	executor := payout.NewFakeExecutor()
+	executor.Err = errors.New("failed to create transfer: connection reset")
+	ledger := NewLedgerService(repo, vendors, nil, executor, "usd")
+
+	batch, err := ledger.ExecutePayoutBatch(context.Background(), repo.batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Status != models.PayoutBatchPending || batch.Payouts[0].Status != models.PayoutStatusPending {
+		t.Fatalf("after a temporary failure: batch %s, payout %s", batch.Status, batch.Payouts[0].Status)
+	}
+
+	executor.Err = nil
+	batch, err = ledger.ExecutePayoutBatch(context.Background(), repo.batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Status != models.PayoutBatchCompleted || batch.Payouts[0].Status != models.PayoutStatusPaid {
+		t.Errorf("after retrying: batch %s, payout %s", batch.Status, batch.Payouts[0].Status)
+	}
+	if len(executor.Transfers) != 1 || executor.Transfers[0].PayoutID != batch.Payouts[0].ID {
+		t.Errorf("transfers %+v, want one for payout %s", executor.Transfers, batch.Payouts[0].ID)
+	}
+}
+
+func TestExecutePayoutBatchRefusesClaimedBatch(t *testing.T) {
+	vendorID := uuid.New()
+	repo := &memoryLedgerRepository{batch: newPayoutBatch(vendorID)}
+	repo.batch.Status = models.PayoutBatchProcessing
+	executor := payout.NewFakeExecutor()
+	ledger := NewLedgerService(repo, &memoryVendorRepository{}, nil, executor, "usd")
+
+	if _, err := ledger.ExecutePayoutBatch(context.Background(), repo.batch.ID); err == nil || err.Error() != "payout batch is already being executed" {
+		t.Errorf("err = %v", err)
+	}
+	if len(executor.Transfers) != 0 {
+		t.Errorf("made %d transfers for a claimed batch", len(executor.Transfers))
+	}
+}
+
+func TestRecordRefundRejectsRefundsBeyondTheSale(t *testing.T) {
+	itemID := uuid.New()
+	rate := 0.1
+	repo := &memoryLedgerRepository{
+		item: &repository.LedgerOrderItem{OrderItem: models.OrderItem{ID: itemID, Name: "Laptop"}},
+		entries: []models.LedgerEntry{
+			{Type: models.LedgerEntrySale, Amount: 100},
+			{Type: models.LedgerEntryCommission, Amount: -10, CommissionRate: &rate},
+			{Type: models.LedgerEntryRefund, Amount: -60},
+		},
+	}
+	ledger := NewLedgerService(repo, nil, nil, nil, "usd")
+
+	if _, err := ledger.RecordRefund(context.Background(), itemID, 50); err == nil {
+		t.Error("refund beyond the remaining 40 was recorded")
+	}
+
+	entries, err := ledger.RecordRefund(context.Background(), itemID, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.refunds != 1 || len(entries) != 2 || entries[0].Amount != -40 || entries[1].Amount != 4 {
+		t.Errorf("recorded %d refunds with entries %+v", repo.refunds, entries)
+	}
+
+	// Another refund was recorded between the check and the locked insert
+	repo.full = true
+	if _, err := ledger.RecordRefund(context.Background(), itemID, 40); err == nil || err.Error() != "refund exceeds the remaining item total" {
+		t.Errorf("concurrent refund: err = %v", err)
+	}
+}
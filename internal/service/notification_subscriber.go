@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/email"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// notificationSubscriber queues customer email in reaction to domain events. The
+// email service deduplicates each message, so repeated events send it once.
+type notificationSubscriber struct {
+	emails       EmailService
+	shipmentRepo repository.ShipmentRepository
+	appURL       string
+}
+
+func subscribeNotifications(events EventService, emails EmailService, shipmentRepo repository.ShipmentRepository, appURL string) {
+	n := &notificationSubscriber{emails: emails, shipmentRepo: shipmentRepo, appURL: appURL}
+	events.Subscribe("notifications.order_confirmation", []models.EventType{models.EventCheckoutCompleted}, n.orderConfirmation)
+	events.Subscribe("notifications.shipping", []models.EventType{models.EventShipmentCreated}, n.shippingNotification)
+	events.Subscribe("notifications.refund", []models.EventType{models.EventCheckoutRefunded}, n.refundIssued)
+	events.Subscribe("notifications.review_request", []models.EventType{models.EventOrderStatusChanged}, n.reviewRequest)
+}
+
+// orderConfirmation confirms a paid checkout session to the customer
+func (n *notificationSubscriber) orderConfirmation(ctx context.Context, event *models.DomainEvent) error {
+	var payload models.CheckoutEventPayload
+	if err := decodeEventPayload(event, &payload); err != nil {
+		return err
+	}
+	if payload.CustomerEmail == "" {
+		return nil
+	}
+
+	data := email.OrderConfirmationData{
+		Name:        payload.CustomerName,
+		OrderNumber: sessionOrderNumber(payload.SessionID),
+		Shipping:    payload.Shipping,
+		Discount:    payload.Discount,
+		Total:       payload.AmountTotal,
+		Currency:    payload.Currency,
+	}
+
+	// "auto" and unknown locales fall back to the default language
+	lang := payload.Locale
+	if lang == "auto" {
+		lang = ""
+	}
+	return n.emails.QueueOrderConfirmation(ctx, payload.CustomerEmail, lang, payload.SessionID, data)
+}
+
+// refundIssued tells the customer about each refund of a checkout
+func (n *notificationSubscriber) refundIssued(ctx context.Context, event *models.DomainEvent) error {
+	var payload models.CheckoutRefundEventPayload
+	if err := decodeEventPayload(event, &payload); err != nil {
+		return err
+	}
+	if payload.CustomerEmail == "" {
+		return nil
+	}
+
+	data := email.RefundIssuedData{
+		Name:        payload.CustomerName,
+		OrderNumber: sessionOrderNumber(payload.SessionID),
+		Amount:      payload.Amount,
+		Currency:    payload.Currency,
+	}
+	// The event ID is derived from the Stripe event, so each refund is emailed once
+	return n.emails.QueueRefundIssued(ctx, payload.CustomerEmail, "", event.ID.String(), data)
+}
+
+// shippingNotification tells the customer what is on its way
+func (n *notificationSubscriber) shippingNotification(ctx context.Context, event *models.DomainEvent) error {
+	var payload models.ShipmentEventPayload
+	if err := decodeEventPayload(event, &payload); err != nil {
+		return err
+	}
+
+	shipment, err := n.shipmentRepo.GetByID(ctx, payload.ShipmentID)
+	if err != nil {
+		return err
+	}
+	order, err := n.shipmentRepo.GetOrder(ctx, payload.OrderID)
+	if err != nil {
+		return err
+	}
+	if shipment == nil || order == nil || order.CustomerEmail == "" {
+		return nil
+	}
+
+	data := email.ShippingNotificationData{
+		Name:        order.CustomerName,
+		OrderNumber: order.OrderNumber,
+		Carrier:     shipment.Carrier,
+		TrackingURL: fmt.Sprintf("%s/orders/%s", n.appURL, order.ID),
+	}
+	if shipment.TrackingNumber != nil {
+		data.TrackingNumber = *shipment.TrackingNumber
+	}
+	for _, item := range shipment.Items {
+		data.Items = append(data.Items, email.LineItem{Name: item.Name, Quantity: item.Quantity})
+	}
+
+	return n.emails.QueueShippingNotification(ctx, order.CustomerEmail, "", shipment.ID, data)
+}
+
+// reviewRequest asks the customer to review the products of a delivered order
+func (n *notificationSubscriber) reviewRequest(ctx context.Context, event *models.DomainEvent) error {
+	var payload models.OrderStatusEventPayload
+	if err := decodeEventPayload(event, &payload); err != nil {
+		return err
+	}
+	if payload.To != models.OrderStatusDelivered {
+		return nil
+	}
+
+	order, err := n.shipmentRepo.GetOrder(ctx, payload.OrderID)
+	if err != nil {
+		return err
+	}
+	if order == nil || order.CustomerEmail == "" {
+		return nil
+	}
+
+	data := email.ReviewRequestData{Name: order.CustomerName, OrderNumber: order.OrderNumber}
+	seen := make(map[uuid.UUID]bool)
+	for _, item := range order.Items {
+		if seen[item.ProductID] {
+			continue
+		}
+		seen[item.ProductID] = true
+
+		data.Products = append(data.Products, email.ReviewProduct{
+			Name: item.Name,
+			URL:  fmt.Sprintf("%s/products/%s#reviews", n.appURL, item.ProductID),
+		})
+	}
+	if len(data.Products) == 0 {
+		return nil
+	}
+
+	return n.emails.QueueReviewRequest(ctx, order.CustomerEmail, "", order.ID, data)
+}
+
+// decodeEventPayload decodes an event's payload, failing the job permanently if it is malformed
+func decodeEventPayload(event *models.DomainEvent, payload interface{}) error {
+	if err := json.Unmarshal(event.Payload, payload); err != nil {
+		return PermanentJobError(fmt.Errorf("invalid %s payload: %w", event.Type, err))
+	}
+	return nil
+}
+
+// sessionOrderNumber is the number a checkout session is shown to the customer by,
+// until orders are created from sessions
+func sessionOrderNumber(sessionID string) string {
+	if len(sessionID) > 8 {
+		sessionID = sessionID[len(sessionID)-8:]
+	}
+	return strings.ToUpper(sessionID)
+}
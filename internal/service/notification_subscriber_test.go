@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/email"
+	"smrtmart-go-postgresql/internal/models"
+
+	"github.com/stripe/stripe-go/v76"
+)
+
+// subscribingEventService collects the handlers subscribers register
+type subscribingEventService struct {
+	EventService
+	handlers map[models.EventType][]EventHandler
+}
+
+func (s *subscribingEventService) Subscribe(_ string, types []models.EventType, handler EventHandler) {
+	for _, eventType := range types {
+		s.handlers[eventType] = append(s.handlers[eventType], handler)
+	}
+}
+
+func (s *subscribingEventService) deliver(t *testing.T, event *models.DomainEvent) {
+	t.Helper()
+	for _, handler := range s.handlers[event.Type] {
+		if err := handler(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRefundsQueueRefundIssuedEmails(t *testing.T) {
+	events := &subscribingEventService{handlers: make(map[models.EventType][]EventHandler)}
+	emails, queued := newDedupeEmailService()
+	subscribeNotifications(events, emails, nil, "https://shop.test")
+
+	charge := &stripe.Charge{
+		ID:             "ch_1",
+		Currency:       stripe.CurrencySEK,
+		AmountRefunded: 19900,
+		BillingDetails: &stripe.ChargeBillingDetails{Email: "asa@example.com", Name: "Åsa Berg"},
+	}
+	refund, err := checkoutRefundedEvent("evt_1", charge, "cs_test_a1b2c3d4e5f6", 199)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events.deliver(t, refund)
+
+	// A repeated Stripe delivery is emailed once
+	again, err := checkoutRefundedEvent("evt_1", charge, "cs_test_a1b2c3d4e5f6", 199)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events.deliver(t, again)
+
+	if len(queued.queued) != 1 {
+		t.Fatalf("queued %d emails, want 1", len(queued.queued))
+	}
+	sent := queued.queued[0]
+	if sent.Kind != string(email.KindRefundIssued) || sent.To != "asa@example.com" {
+		t.Errorf("queued %s to %s", sent.Kind, sent.To)
+	}
+	orderNumber := sessionOrderNumber("cs_test_a1b2c3d4e5f6")
+	if !strings.Contains(sent.Subject, orderNumber) || !strings.Contains(sent.Text, "Åsa Berg") {
+		t.Errorf("refund email %q: %q", sent.Subject, sent.Text)
+	}
+
+	// A second, partial refund of the same charge gets its own email
+	charge.AmountRefunded = 29900
+	partial, err := checkoutRefundedEvent("evt_2", charge, "cs_test_a1b2c3d4e5f6", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events.deliver(t, partial)
+	if len(queued.queued) != 2 {
+		t.Errorf("queued %d emails after a second refund, want 2", len(queued.queued))
+	}
+
+	// Without an address there is no one to tell
+	charge.BillingDetails = nil
+	anonymous, err := checkoutRefundedEvent("evt_3", charge, "cs_test_a1b2c3d4e5f6", 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events.deliver(t, anonymous)
+	if len(queued.queued) != 2 {
+		t.Errorf("queued an email without a recipient")
+	}
+}
@@ -1,21 +1,37 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/metrics"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
 
+	"github.com/google/uuid"
 	"github.com/stripe/stripe-go/v76"
 	"github.com/stripe/stripe-go/v76/checkout/session"
+	"github.com/stripe/stripe-go/v76/coupon"
+	"github.com/stripe/stripe-go/v76/refund"
 	"github.com/stripe/stripe-go/v76/webhook"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type PaymentService interface {
-	CreateCheckoutSession(items []CheckoutItem, customerEmail string, successURL, cancelURL string) (*stripe.CheckoutSession, error)
-	CreateCheckoutSessionWithFullInfo(items []CheckoutItem, customerInfo CustomerInfo, shippingAddress Address, billingAddress *Address, successURL, cancelURL string) (*stripe.CheckoutSession, error)
-	HandleWebhook(payload []byte, signature string) error
+	PriceItems(ctx context.Context, lines []CartLine) ([]CheckoutItem, error)
+	CreateCheckoutSession(ctx context.Context, items []CheckoutItem, customerEmail string, shipping *CheckoutShipping, discount *CheckoutDiscount, successURL, cancelURL string) (*stripe.CheckoutSession, error)
+	CreateCheckoutSessionWithFullInfo(ctx context.Context, items []CheckoutItem, customerInfo CustomerInfo, shippingAddress Address, billingAddress *Address, shipping *CheckoutShipping, discount *CheckoutDiscount, successURL, cancelURL string) (*stripe.CheckoutSession, error)
+	HandleWebhook(ctx context.Context, payload []byte, signature string) error
 }
 
 type CustomerInfo struct {
@@ -47,17 +63,90 @@ type CheckoutItem struct {
 	Images      []string `json:"images"`
 }
 
+// checkoutEventNamespace derives checkout event IDs from Stripe session and event IDs
+var checkoutEventNamespace = uuid.MustParse("6f1c2a4e-8d3b-4f5a-9c7e-2b1d0e3f4a5b")
+
+// maxStripeShippingOptions is the most shipping options a Stripe checkout session accepts
+const maxStripeShippingOptions = 5
+
+// CheckoutShipping is the shipping offered in a checkout session. Without it the
+// session falls back to the built-in countries and options.
+type CheckoutShipping struct {
+	AllowedCountries []string
+	Options          []models.ShippingOption // Priced for the cart and address; nil to leave the options alone
+}
+
+// CheckoutDiscount is the promotion discount and gift card or store credit tender
+// applied to a checkout session. Stripe charges what remains.
+type CheckoutDiscount struct {
+	Amount       float64
+	FreeShipping bool
+	Quote        *models.PromotionQuote   // Recorded in the session metadata and redeemed once the session is paid
+	CustomerID   *uuid.UUID               // Checked against per-customer promotion limits; nil for guests
+	Tenders      *models.TenderRedemption // Released again if the session expires unpaid
+}
+
 type paymentService struct {
 	stripeConfig config.StripeConfig
+	productRepo  repository.ProductRepository
+	giftCards    GiftCardService
+	promotions   PromotionService
+	events       EventService
 }
 
-func NewPaymentService(stripeConfig config.StripeConfig) PaymentService {
-	// Initialize Stripe
+func NewPaymentService(stripeConfig config.StripeConfig, productRepo repository.ProductRepository, giftCards GiftCardService, promotions PromotionService, events EventService) PaymentService {
+	// Initialize Stripe. Calls made with a request's context are traced as part
+	// of the request.
 	stripe.Key = stripeConfig.SecretKey
-	return &paymentService{stripeConfig: stripeConfig}
+	stripe.SetBackend(stripe.APIBackend, stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{
+		HTTPClient: &http.Client{
+			Timeout:   80 * time.Second, // Stripe's default
+			Transport: tracing.Transport("stripe", nil),
+		},
+	}))
+	return &paymentService{stripeConfig: stripeConfig, productRepo: productRepo, giftCards: giftCards, promotions: promotions, events: events}
+}
+
+// PriceItems prices the cart at the products' current prices. Only the product
+// and quantity of each line come from the client.
+func (s *paymentService) PriceItems(ctx context.Context, lines []CartLine) (_ []CheckoutItem, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.PriceItems")
+	defer func() { tracing.End(span, err) }()
+
+	if len(lines) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+
+	items := make([]CheckoutItem, len(lines))
+	for i, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than zero")
+		}
+
+		product, err := findProduct(ctx, s.productRepo, line.ProductRef)
+		if err != nil {
+			return nil, err
+		}
+		if product == nil || product.Status != models.ProductStatusActive {
+			return nil, fmt.Errorf("product %s not found", line.ProductRef)
+		}
+
+		items[i] = CheckoutItem{
+			ProductID:   line.ProductRef,
+			Name:        product.Name,
+			Description: product.Description,
+			Price:       product.Price,
+			Quantity:    line.Quantity,
+			Images:      product.Images,
+		}
+	}
+	return items, nil
 }
 
-func (s *paymentService) CreateCheckoutSession(items []CheckoutItem, customerEmail string, successURL, cancelURL string) (*stripe.CheckoutSession, error) {
+func (s *paymentService) CreateCheckoutSession(ctx context.Context, items []CheckoutItem, customerEmail string, shipping *CheckoutShipping, discount *CheckoutDiscount, successURL, cancelURL string) (_ *stripe.CheckoutSession, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.CreateCheckoutSession")
+	defer func() { tracing.End(span, err) }()
+
 	var lineItems []*stripe.CheckoutSessionLineItemParams
 
 	for _, item := range items {
@@ -116,15 +205,25 @@ func (s *paymentService) CreateCheckoutSession(items []CheckoutItem, customerEma
 		},
 	}
 
+	applyCheckoutShipping(params, shipping)
+	if err := applyCheckoutDiscount(ctx, params, discount); err != nil {
+		return nil, err
+	}
+
+	params.Context = ctx
 	sess, err := session.New(params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create checkout session: %w", err)
 	}
+	metrics.CheckoutsStarted.Inc()
 
 	return sess, nil
 }
 
-func (s *paymentService) CreateCheckoutSessionWithFullInfo(items []CheckoutItem, customerInfo CustomerInfo, shippingAddress Address, billingAddress *Address, successURL, cancelURL string) (*stripe.CheckoutSession, error) {
+func (s *paymentService) CreateCheckoutSessionWithFullInfo(ctx context.Context, items []CheckoutItem, customerInfo CustomerInfo, shippingAddress Address, billingAddress *Address, shipping *CheckoutShipping, discount *CheckoutDiscount, successURL, cancelURL string) (_ *stripe.CheckoutSession, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.CreateCheckoutSessionWithFullInfo")
+	defer func() { tracing.End(span, err) }()
+
 	var lineItems []*stripe.CheckoutSessionLineItemParams
 
 	for _, item := range items {
@@ -225,19 +324,148 @@ func (s *paymentService) CreateCheckoutSessionWithFullInfo(items []CheckoutItem,
 		params.Metadata["billing_country"] = billingAddress.Country
 	}
 
+	applyCheckoutShipping(params, shipping)
+	if err := applyCheckoutDiscount(ctx, params, discount); err != nil {
+		return nil, err
+	}
+
+	params.Context = ctx
 	sess, err := session.New(params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create checkout session: %w", err)
 	}
+	metrics.CheckoutsStarted.Inc()
 
 	return sess, nil
 }
 
-func (s *paymentService) HandleWebhook(payload []byte, signature string) error {
+// applyCheckoutShipping replaces the session's countries and shipping options with calculated ones
+func applyCheckoutShipping(params *stripe.CheckoutSessionParams, shipping *CheckoutShipping) {
+	if shipping == nil {
+		return
+	}
+
+	if len(shipping.AllowedCountries) > 0 {
+		params.ShippingAddressCollection.AllowedCountries = stripe.StringSlice(shipping.AllowedCountries)
+	}
+
+	if shipping.Options == nil {
+		return
+	}
+	params.ShippingOptions = nil
+	for i, option := range shipping.Options {
+		if i == maxStripeShippingOptions {
+			break
+		}
+
+		rate := &stripe.CheckoutSessionShippingOptionShippingRateDataParams{
+			Type: stripe.String("fixed_amount"),
+			FixedAmount: &stripe.CheckoutSessionShippingOptionShippingRateDataFixedAmountParams{
+				Amount:   stripe.Int64(int64(math.Round(option.Rate * 100))),
+				Currency: stripe.String("usd"),
+			},
+			DisplayName: stripe.String(option.Name),
+			Metadata:    map[string]string{"shipping_method_id": option.MethodID.String()},
+		}
+		if option.MinDeliveryDays != nil || option.MaxDeliveryDays != nil {
+			rate.DeliveryEstimate = &stripe.CheckoutSessionShippingOptionShippingRateDataDeliveryEstimateParams{}
+			if option.MinDeliveryDays != nil {
+				rate.DeliveryEstimate.Minimum = &stripe.CheckoutSessionShippingOptionShippingRateDataDeliveryEstimateMinimumParams{
+					Unit:  stripe.String("business_day"),
+					Value: stripe.Int64(int64(*option.MinDeliveryDays)),
+				}
+			}
+			if option.MaxDeliveryDays != nil {
+				rate.DeliveryEstimate.Maximum = &stripe.CheckoutSessionShippingOptionShippingRateDataDeliveryEstimateMaximumParams{
+					Unit:  stripe.String("business_day"),
+					Value: stripe.Int64(int64(*option.MaxDeliveryDays)),
+				}
+			}
+		}
+
+		params.ShippingOptions = append(params.ShippingOptions, &stripe.CheckoutSessionShippingOptionParams{
+			ShippingRateData: rate,
+		})
+	}
+}
+
+// applyCheckoutDiscount adds the discount and tender to the session as a single-use
+// Stripe coupon and, for free shipping, makes every shipping option free
+func applyCheckoutDiscount(ctx context.Context, params *stripe.CheckoutSessionParams, discount *CheckoutDiscount) error {
+	if discount == nil {
+		return nil
+	}
+
+	if discount.Quote != nil && len(discount.Quote.Applied) > 0 {
+		ids := make([]string, len(discount.Quote.Applied))
+		discounts := make([]string, len(discount.Quote.Applied))
+		for i, applied := range discount.Quote.Applied {
+			ids[i] = applied.PromotionID.String()
+			discounts[i] = strconv.FormatFloat(applied.Discount, 'f', 2, 64)
+		}
+		params.Metadata["promotion_ids"] = strings.Join(ids, ",")
+		params.Metadata["promotion_discounts"] = strings.Join(discounts, ",")
+		if discount.CustomerID != nil {
+			params.Metadata["customer_id"] = discount.CustomerID.String()
+		}
+	}
+
+	if discount.FreeShipping {
+		params.Metadata["free_shipping"] = "true"
+		for _, option := range params.ShippingOptions {
+			if option.ShippingRateData != nil && option.ShippingRateData.FixedAmount != nil {
+				option.ShippingRateData.FixedAmount.Amount = stripe.Int64(0)
+			}
+		}
+	}
+
+	name := "Promotion discount"
+	total := discount.Amount
+	if discount.Tenders != nil && discount.Tenders.Total > 0 {
+		params.Metadata["tender_reference"] = discount.Tenders.Reference
+		params.Metadata["tender_total"] = strconv.FormatFloat(discount.Tenders.Total, 'f', 2, 64)
+		total += discount.Tenders.Total
+		if discount.Amount > 0 {
+			name = "Promotion discount, gift card and store credit"
+		} else {
+			name = "Gift card and store credit"
+		}
+	}
+
+	amountOff := int64(math.Round(total * 100))
+	if amountOff <= 0 {
+		return nil
+	}
+
+	couponParams := &stripe.CouponParams{
+		AmountOff:      stripe.Int64(amountOff),
+		Currency:       stripe.String("usd"),
+		Duration:       stripe.String(string(stripe.CouponDurationOnce)),
+		MaxRedemptions: stripe.Int64(1),
+		Name:           stripe.String(name),
+	}
+	couponParams.Context = ctx
+	c, err := coupon.New(couponParams)
+	if err != nil {
+		return fmt.Errorf("failed to create checkout discount: %w", err)
+	}
+
+	params.Discounts = []*stripe.CheckoutSessionDiscountParams{
+		{Coupon: stripe.String(c.ID)},
+	}
+	params.Metadata["discount"] = strconv.FormatFloat(discount.Amount, 'f', 2, 64)
+	return nil
+}
+
+func (s *paymentService) HandleWebhook(ctx context.Context, payload []byte, signature string) (err error) {
+	ctx, span := tracing.Start(ctx, "PaymentService.HandleWebhook")
+	defer func() { tracing.End(span, err) }()
+
 	event, err := webhook.ConstructEvent(payload, signature, s.stripeConfig.WebhookSecret)
 	if err != nil {
 		return fmt.Errorf("failed to verify webhook signature: %w", err)
 	}
+	span.SetAttributes(attribute.String("stripe.event_type", string(event.Type)))
 
 	switch event.Type {
 	case "checkout.session.completed":
@@ -246,8 +474,77 @@ func (s *paymentService) HandleWebhook(payload []byte, signature string) error {
 			return fmt.Errorf("failed to unmarshal session: %w", err)
 		}
 		
-		log.Printf("Payment successful for session: %s", session.ID)
-		// TODO: Create order in database, update inventory, send confirmation email
+		slog.InfoContext(ctx, "Payment successful", "session_id", session.ID)
+		// TODO: Create order in database, update inventory
+
+		// Subscribers such as the order confirmation react to the event. If it
+		// cannot be stored Stripe retries the webhook.
+		recorded, err := s.completeCheckout(ctx, &session)
+		if errors.Is(err, errPromotionLimitReached) {
+			return s.refundCheckout(ctx, &session, err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to record completed session %s: %w", session.ID, err)
+		}
+		// A repeated delivery of the same session is not counted again
+		if recorded {
+			metrics.OrderPaid(string(session.Currency), float64(session.AmountTotal)/100)
+		}
+
+	case "checkout.session.expired", "checkout.session.async_payment_failed":
+		var session stripe.CheckoutSession
+		if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
+			return fmt.Errorf("failed to unmarshal session: %w", err)
+		}
+
+		// Gift card and store credit balances were taken when the session was
+		// created, and promotions when a delayed payment completed it
+		if err := s.giftCards.ReleaseTenders(ctx, session.Metadata["tender_reference"]); err != nil {
+			return fmt.Errorf("failed to release tenders for session %s: %w", session.ID, err)
+		}
+		if err := s.promotions.CancelRedemptions(ctx, session.ID); err != nil {
+			return fmt.Errorf("failed to cancel promotions of session %s: %w", session.ID, err)
+		}
+		slog.InfoContext(ctx, "Checkout session was not paid", "session_id", session.ID, "event_type", event.Type)
+
+	case "charge.refunded":
+		var charge stripe.Charge
+		if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
+			return fmt.Errorf("failed to unmarshal charge: %w", err)
+		}
+
+		if charge.PaymentIntent == nil {
+			break
+		}
+		sessionID, err := s.checkoutSessionID(ctx, charge.PaymentIntent.ID)
+		if err != nil {
+			return err
+		}
+		if sessionID == "" {
+			slog.DebugContext(ctx, "Ignoring refund of a charge without a checkout session", "charge_id", charge.ID)
+			break
+		}
+
+		amount := refundedAmount(event, &charge)
+		refunded, err := checkoutRefundedEvent(event.ID, &charge, sessionID, amount)
+		if err != nil {
+			return err
+		}
+		if err := s.events.Publish(ctx, refunded); err != nil {
+			return fmt.Errorf("failed to record refund of session %s: %w", sessionID, err)
+		}
+		// A repeated delivery of the same refund is not counted again
+		if refunded.Seq != 0 {
+			metrics.Refunded(string(charge.Currency), amount)
+		}
+
+		// A partly refunded order is still fulfilled and keeps its promotions
+		if charge.Refunded {
+			if err := s.promotions.CancelRedemptions(ctx, sessionID); err != nil {
+				return fmt.Errorf("failed to cancel promotions of session %s: %w", sessionID, err)
+			}
+		}
+		slog.InfoContext(ctx, "Charge refunded", "charge_id", charge.ID, "session_id", sessionID, "fully_refunded", charge.Refunded)
 		
 	case "payment_intent.succeeded":
 		var paymentIntent stripe.PaymentIntent
@@ -255,7 +552,7 @@ func (s *paymentService) HandleWebhook(payload []byte, signature string) error {
 			return fmt.Errorf("failed to unmarshal payment intent: %w", err)
 		}
 		
-		log.Printf("Payment intent succeeded: %s", paymentIntent.ID)
+		slog.InfoContext(ctx, "Payment intent succeeded", "payment_intent_id", paymentIntent.ID)
 		
 	case "payment_intent.payment_failed":
 		var paymentIntent stripe.PaymentIntent
@@ -263,11 +560,192 @@ func (s *paymentService) HandleWebhook(payload []byte, signature string) error {
 			return fmt.Errorf("failed to unmarshal payment intent: %w", err)
 		}
 		
-		log.Printf("Payment failed: %s", paymentIntent.ID)
+		slog.WarnContext(ctx, "Payment failed", "payment_intent_id", paymentIntent.ID)
 		
 	default:
-		log.Printf("Unhandled event type: %s", event.Type)
+		slog.DebugContext(ctx, "Unhandled Stripe event", "event_type", event.Type)
+	}
+
+	return nil
+}
+
+// completeCheckout redeems the session's promotions and records the session's
+// checkout.completed, order.created and order.paid events in one transaction. It
+// reports whether the events are new, i.e. this is not a repeated delivery, and fails with
+// errPromotionLimitReached if a promotion ran out while the customer was paying.
+func (s *paymentService) completeCheckout(ctx context.Context, session *stripe.CheckoutSession) (bool, error) {
+	events, err := checkoutCompletedEvents(checkoutCompletedPayload(session))
+	if err != nil {
+		return false, err
+	}
+
+	applied, customerID, err := checkoutPromotions(session.Metadata)
+	if err != nil {
+		return false, err
+	}
+	if len(applied) == 0 {
+		err = s.events.Publish(ctx, events...)
+	} else {
+		err = s.promotions.Redeem(ctx, session.ID, customerID, applied, events...)
 	}
+	return events[0].Seq != 0, err
+}
+
+// refundCheckout refunds a paid session that cannot be fulfilled and gives back
+// the gift card and store credit it used
+func (s *paymentService) refundCheckout(ctx context.Context, session *stripe.CheckoutSession, reason error) error {
+	slog.WarnContext(ctx, "Refunding checkout session", "session_id", session.ID, "reason", reason)
 
+	if err := s.giftCards.ReleaseTenders(ctx, session.Metadata["tender_reference"]); err != nil {
+		return fmt.Errorf("failed to release tenders for session %s: %w", session.ID, err)
+	}
+	if session.PaymentIntent == nil || session.AmountTotal == 0 {
+		return nil
+	}
+
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(session.PaymentIntent.ID),
+		Reason:        stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+	}
+	params.AddMetadata("session_id", session.ID)
+	params.AddMetadata("reason", reason.Error())
+	params.Context = ctx
+	// Stripe retries the webhook until it succeeds; the key refunds the session once
+	params.SetIdempotencyKey("checkout-refund-" + session.ID)
+	if _, err := refund.New(params); err != nil {
+		return fmt.Errorf("failed to refund session %s: %w", session.ID, err)
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+// checkoutSessionID looks up the checkout session a payment intent was created for
+func (s *paymentService) checkoutSessionID(ctx context.Context, paymentIntentID string) (string, error) {
+	params := &stripe.CheckoutSessionListParams{PaymentIntent: stripe.String(paymentIntentID)}
+	params.Context = ctx
+	params.Limit = stripe.Int64(1)
+
+	iter := session.List(params)
+	if iter.Next() {
+		return iter.CheckoutSession().ID, nil
+	}
+	if err := iter.Err(); err != nil {
+		return "", fmt.Errorf("failed to look up checkout session of payment intent %s: %w", paymentIntentID, err)
+	}
+	return "", nil
+}
+
+// checkoutPromotions reads the promotions recorded in a session's metadata
+func checkoutPromotions(metadata map[string]string) ([]models.AppliedPromotion, *uuid.UUID, error) {
+	if metadata["promotion_ids"] == "" {
+		return nil, nil, nil
+	}
+
+	ids := strings.Split(metadata["promotion_ids"], ",")
+	discounts := strings.Split(metadata["promotion_discounts"], ",")
+	applied := make([]models.AppliedPromotion, len(ids))
+	for i, id := range ids {
+		promotionID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid promotion ID %q: %w", id, err)
+		}
+		applied[i].PromotionID = promotionID
+		if i < len(discounts) {
+			applied[i].Discount, _ = strconv.ParseFloat(discounts[i], 64)
+		}
+	}
+
+	var customerID *uuid.UUID
+	if id, err := uuid.Parse(metadata["customer_id"]); err == nil {
+		customerID = &id
+	}
+	return applied, customerID, nil
+}
+
+// checkoutCompletedPayload describes a paid checkout session
+func checkoutCompletedPayload(session *stripe.CheckoutSession) models.CheckoutEventPayload {
+	payload := models.CheckoutEventPayload{
+		SessionID:   session.ID,
+		Locale:      string(session.Locale),
+		Currency:    string(session.Currency),
+		AmountTotal: float64(session.AmountTotal) / 100,
+	}
+	if session.CustomerDetails != nil {
+		payload.CustomerEmail = session.CustomerDetails.Email
+		payload.CustomerName = session.CustomerDetails.Name
+	}
+	if session.TotalDetails != nil {
+		payload.Shipping = float64(session.TotalDetails.AmountShipping) / 100
+		payload.Discount = float64(session.TotalDetails.AmountDiscount) / 100
+	}
+	return payload
+}
+
+// checkoutCompletedEvents are the events of a paid checkout: checkout.completed,
+// followed by order.created and order.paid for the order it becomes. They are
+// stored in the transaction that records the payment.
+func checkoutCompletedEvents(payload models.CheckoutEventPayload) ([]*models.DomainEvent, error) {
+	completed, err := models.NewDomainEvent(models.EventCheckoutCompleted, "checkout_session", payload.SessionID, payload)
+	if err != nil {
+		return nil, err
+	}
+	order := models.OrderEventPayload{
+		SessionID:     payload.SessionID,
+		OrderNumber:   sessionOrderNumber(payload.SessionID),
+		CustomerEmail: payload.CustomerEmail,
+		Currency:      payload.Currency,
+		AmountTotal:   payload.AmountTotal,
+	}
+	created, err := models.NewDomainEvent(models.EventOrderCreated, "order", payload.SessionID, order)
+	if err != nil {
+		return nil, err
+	}
+	paid, err := models.NewDomainEvent(models.EventOrderPaid, "order", payload.SessionID, order)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stripe delivers webhooks at least once; the event IDs are derived from the
+	// session so a repeated delivery is not stored twice
+	completed.ID = uuid.NewSHA1(checkoutEventNamespace, []byte(payload.SessionID))
+	created.ID = uuid.NewSHA1(checkoutEventNamespace, []byte(string(models.EventOrderCreated)+":"+payload.SessionID))
+	paid.ID = uuid.NewSHA1(checkoutEventNamespace, []byte(string(models.EventOrderPaid)+":"+payload.SessionID))
+	return []*models.DomainEvent{completed, created, paid}, nil
+}
+
+// refundedAmount is what a charge.refunded event refunded: how much the charge's
+// refunded amount grew by
+func refundedAmount(event stripe.Event, charge *stripe.Charge) float64 {
+	var previous int64
+	if value, ok := event.Data.PreviousAttributes["amount_refunded"].(float64); ok {
+		previous = int64(value)
+	}
+	return float64(charge.AmountRefunded-previous) / 100
+}
+
+// checkoutRefundedEvent describes a refund of a checkout session's charge as a
+// checkout.refunded event
+func checkoutRefundedEvent(stripeEventID string, charge *stripe.Charge, sessionID string, amount float64) (*models.DomainEvent, error) {
+	payload := models.CheckoutRefundEventPayload{
+		SessionID:      sessionID,
+		ChargeID:       charge.ID,
+		Currency:       string(charge.Currency),
+		Amount:         amount,
+		AmountRefunded: float64(charge.AmountRefunded) / 100,
+		FullyRefunded:  charge.Refunded,
+	}
+	if charge.BillingDetails != nil {
+		payload.CustomerEmail = charge.BillingDetails.Email
+		payload.CustomerName = charge.BillingDetails.Name
+	}
+	if payload.CustomerEmail == "" {
+		payload.CustomerEmail = charge.ReceiptEmail
+	}
+
+	event, err := models.NewDomainEvent(models.EventCheckoutRefunded, "checkout_session", sessionID, payload)
+	if err != nil {
+		return nil, err
+	}
+	// Derived from the Stripe event, so a repeated delivery is recorded once
+	event.ID = uuid.NewSHA1(checkoutEventNamespace, []byte(stripeEventID))
+	return event, nil
+}
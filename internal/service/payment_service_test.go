@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/metrics"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+func TestCheckoutPromotionsReadsSessionMetadata(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+	customer := uuid.New()
+
+	applied, customerID, err := checkoutPromotions(map[string]string{
+		"promotion_ids":       first.String() + "," + second.String(),
+		"promotion_discounts": "12.50,3.00",
+		"customer_id":         customer.String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[0].PromotionID != first || applied[1].PromotionID != second {
+		t.Fatalf("applied = %+v", applied)
+	}
+	if applied[0].Discount != 12.5 || applied[1].Discount != 3 {
+		t.Errorf("discounts = %v, %v", applied[0].Discount, applied[1].Discount)
+	}
+	if customerID == nil || *customerID != customer {
+		t.Errorf("customer = %v, want %s", customerID, customer)
+	}
+
+	applied, customerID, err = checkoutPromotions(map[string]string{"source": "smrtmart_website"})
+	if err != nil || applied != nil || customerID != nil {
+		t.Errorf("session without promotions gave %v, %v, %v", applied, customerID, err)
+	}
+
+	if _, _, err := checkoutPromotions(map[string]string{"promotion_ids": "not-a-uuid"}); err == nil {
+		t.Error("expected an error for a malformed promotion ID")
+	}
+}
+
+// stubProductRepository serves products by numeric ID
+type stubProductRepository struct {
+	repository.ProductRepository
+	products map[int]*models.Product
+}
+
+func (r *stubProductRepository) GetByNumericID(_ context.Context, id int) (*models.Product, error) {
+	return r.products[id], nil
+}
+
+func TestPriceItemsChargesCurrentProductPrices(t *testing.T) {
+	products := &stubProductRepository{products: map[int]*models.Product{
+		7: {NumericID: 7, Name: "Laptop", Price: 999, Status: models.ProductStatusActive, Images: []string{"laptop.jpg"}},
+		8: {NumericID: 8, Name: "Old phone", Price: 199, Status: models.ProductStatusArchived},
+	}}
+	payments := &paymentService{productRepo: products}
+
+	items, err := payments.PriceItems(context.Background(), []CartLine{{ProductRef: "7", Quantity: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Price != 999 || items[0].Quantity != 2 || items[0].Name != "Laptop" {
+		t.Fatalf("items = %+v", items)
+	}
+
+	if _, err := payments.PriceItems(context.Background(), []CartLine{{ProductRef: "8", Quantity: 1}}); err == nil || err.Error() != "product 8 not found" {
+		t.Errorf("archived product: err = %v", err)
+	}
+	if _, err := payments.PriceItems(context.Background(), []CartLine{{ProductRef: "7", Quantity: 0}}); err == nil {
+		t.Error("zero quantity was priced")
+	}
+}
+
+// memoryEventService stores events by ID like the event repository: a repeated
+// event is skipped and keeps a zero Seq
+type memoryEventService struct {
+	EventService
+	events []*models.DomainEvent
+}
+
+func (s *memoryEventService) Publish(_ context.Context, events ...*models.DomainEvent) error {
+	for _, event := range events {
+		if s.stored(event.ID) {
+			continue
+		}
+		s.events = append(s.events, event)
+		event.Seq = int64(len(s.events))
+	}
+	return nil
+}
+
+func (s *memoryEventService) stored(id uuid.UUID) bool {
+	for _, event := range s.events {
+		if event.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// cancelCountingPromotions counts cancelled redemptions
+type cancelCountingPromotions struct {
+	PromotionService
+	cancelled []string
+}
+
+func (p *cancelCountingPromotions) CancelRedemptions(_ context.Context, reference string) error {
+	p.cancelled = append(p.cancelled, reference)
+	return nil
+}
+
+const testWebhookSecret = "whsec_test"
+
+// deliverWebhook signs a Stripe event and hands it to the service
+func deliverWebhook(t *testing.T, payments PaymentService, event string) {
+	t.Helper()
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: []byte(event), Secret: testWebhookSecret})
+	if err := payments.HandleWebhook(context.Background(), signed.Payload, signed.Header); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookCountsRedeliveredCheckoutOnce(t *testing.T) {
+	events := &memoryEventService{}
+	payments := &paymentService{
+		stripeConfig: config.StripeConfig{WebhookSecret: testWebhookSecret},
+		events:       events,
+	}
+
+	paid := testutil.ToFloat64(metrics.OrdersPaid.WithLabelValues("usd"))
+	revenue := testutil.ToFloat64(metrics.Revenue.WithLabelValues("usd"))
+	completed := fmt.Sprintf(`{"id": "evt_1", "object": "event", "api_version": %q, "type": "checkout.session.completed",
+		"data": {"object": {"id": "cs_1", "object": "checkout.session", "currency": "usd", "amount_total": 4999}}}`, stripe.APIVersion)
+	deliverWebhook(t, payments, completed)
+	deliverWebhook(t, payments, completed)
+
+	// The order is created and paid with the checkout, in the same write
+	var types []models.EventType
+	for _, event := range events.events {
+		types = append(types, event.Type)
+	}
+	want := []models.EventType{models.EventCheckoutCompleted, models.EventOrderCreated, models.EventOrderPaid}
+	if !reflect.DeepEqual(types, want) {
+		t.Fatalf("stored events %v, want %v", types, want)
+	}
+	var order models.OrderEventPayload
+	if err := json.Unmarshal(events.events[2].Payload, &order); err != nil {
+		t.Fatal(err)
+	}
+	if order.SessionID != "cs_1" || order.OrderNumber != "CS_1" || order.AmountTotal != 49.99 || events.events[2].AggregateType != "order" {
+		t.Errorf("order.paid %+v", order)
+	}
+	if got := testutil.ToFloat64(metrics.OrdersPaid.WithLabelValues("usd")) - paid; got != 1 {
+		t.Errorf("orders paid grew by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.Revenue.WithLabelValues("usd")) - revenue; got != 49.99 {
+		t.Errorf("revenue grew by %v, want 49.99", got)
+	}
+}
+
+func TestWebhookRecordsEachRefundOnce(t *testing.T) {
+	// Stripe answers the lookup of the charge's checkout session
+	stripeAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/checkout/sessions" || r.URL.Query().Get("payment_intent") != "pi_1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"object": "list", "data": [{"id": "cs_1", "object": "checkout.session"}], "has_more": false}`))
+	}))
+	defer stripeAPI.Close()
+	previous := stripe.GetBackend(stripe.APIBackend)
+	stripe.SetBackend(stripe.APIBackend, stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{URL: stripe.String(stripeAPI.URL)}))
+	defer stripe.SetBackend(stripe.APIBackend, previous)
+
+	events := &memoryEventService{}
+	promotions := &cancelCountingPromotions{}
+	payments := &paymentService{
+		stripeConfig: config.StripeConfig{WebhookSecret: testWebhookSecret},
+		promotions:   promotions,
+		events:       events,
+	}
+
+	refunds := testutil.ToFloat64(metrics.Refunds.WithLabelValues("usd"))
+	refunded := testutil.ToFloat64(metrics.RefundedAmount.WithLabelValues("usd"))
+	refund := func(eventID string, previous, total int64, full bool) string {
+		return fmt.Sprintf(`{"id": %q, "object": "event", "api_version": %q, "type": "charge.refunded",
+			"data": {"object": {"id": "ch_1", "object": "charge", "currency": "usd", "payment_intent": "pi_1",
+				"amount": 5000, "amount_refunded": %d, "refunded": %t, "receipt_email": "ada@example.com"},
+			"previous_attributes": {"amount_refunded": %d}}}`, eventID, stripe.APIVersion, total, full, previous)
+	}
+	deliverWebhook(t, payments, refund("evt_1", 0, 1500, false))
+	deliverWebhook(t, payments, refund("evt_1", 0, 1500, false)) // Redelivered
+	if len(promotions.cancelled) != 0 {
+		t.Error("a partial refund cancelled the promotions")
+	}
+	deliverWebhook(t, payments, refund("evt_2", 1500, 5000, true))
+
+	if len(events.events) != 2 {
+		t.Fatalf("stored %d events, want 2", len(events.events))
+	}
+	var payload models.CheckoutRefundEventPayload
+	if err := json.Unmarshal(events.events[1].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.SessionID != "cs_1" || payload.Amount != 35 || payload.AmountRefunded != 50 || !payload.FullyRefunded || payload.CustomerEmail != "ada@example.com" {
+		t.Errorf("second refund payload = %+v", payload)
+	}
+	if got := testutil.ToFloat64(metrics.Refunds.WithLabelValues("usd")) - refunds; got != 2 {
+		t.Errorf("refunds grew by %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(metrics.RefundedAmount.WithLabelValues("usd")) - refunded; got != 50 {
+		t.Errorf("refunded amount grew by %v, want 50", got)
+	}
+	if len(promotions.cancelled) != 1 || promotions.cancelled[0] != "cs_1" {
+		t.Errorf("cancelled promotions of %v, want cs_1 once", promotions.cancelled)
+	}
+}
@@ -19,18 +19,6 @@ func NewUserService(repo repository.UserRepository) UserService {
 	return &userService{repo: repo}
 }
 
-type VendorService interface {
-	// TODO: Implement vendor service methods
-}
-
-type vendorService struct {
-	repo repository.VendorRepository
-}
-
-func NewVendorService(repo repository.VendorRepository) VendorService {
-	return &vendorService{repo: repo}
-}
-
 type OrderService interface {
 	// TODO: Implement order service methods
 }
@@ -63,48 +51,8 @@ func NewCartService(cartRepo repository.CartRepository, productRepo repository.P
 	}
 }
 
-type CategoryService interface {
-	// TODO: Implement category service methods
-}
-
-type categoryService struct {
-	repo repository.CategoryRepository
-}
-
-func NewCategoryService(repo repository.CategoryRepository) CategoryService {
-	return &categoryService{repo: repo}
-}
-
-type ReviewService interface {
-	// TODO: Implement review service methods
-}
-
-type reviewService struct {
-	repo repository.ReviewRepository
-}
-
-func NewReviewService(repo repository.ReviewRepository) ReviewService {
-	return &reviewService{repo: repo}
-}
-
 // PaymentService is now implemented in payment_service.go
 
-type AuthService interface {
-	// TODO: Implement auth service methods
-}
-
-type authService struct {
-	userRepo  repository.UserRepository
-	jwtConfig config.JWTConfig
-}
-
-func NewAuthService(userRepo repository.UserRepository, jwtConfig config.JWTConfig) AuthService {
-	return &authService{
-		userRepo:  userRepo,
-		jwtConfig: jwtConfig,
-	}
-}
-
 type UploadService interface {
 	// TODO: Implement upload service methods
 }
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/search"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+// omnibusWindowDays is how far back the lowest prior price of a discounted
+// product is looked up, as required by the EU Omnibus Directive
+const omnibusWindowDays = 30
+
+type PricingService interface {
+	GetSales(ctx context.Context, vendorID *uuid.UUID, productID uuid.UUID) ([]*models.SalePrice, error)
+	ScheduleSale(ctx context.Context, vendorID *uuid.UUID, sale *models.SalePrice) error
+	CancelSale(ctx context.Context, vendorID *uuid.UUID, productID, saleID uuid.UUID) error
+	GetPriceHistory(ctx context.Context, vendorID *uuid.UUID, productID uuid.UUID, limit int) ([]models.PriceHistoryEntry, error)
+	ApplyDueSales(ctx context.Context) (int, error)
+	RunSaleScheduler(ctx context.Context, interval time.Duration)
+}
+
+type pricingService struct {
+	repo        repository.PricingRepository
+	productRepo repository.ProductRepository
+	searchIndex search.Index
+}
+
+func NewPricingService(repo repository.PricingRepository, productRepo repository.ProductRepository, searchIndex search.Index) PricingService {
+	return &pricingService{
+		repo:        repo,
+		productRepo: productRepo,
+		searchIndex: searchIndex,
+	}
+}
+
+// GetSales lists a product's sales. vendorID restricts access to that vendor's products; nil is an admin.
+func (s *pricingService) GetSales(ctx context.Context, vendorID *uuid.UUID, productID uuid.UUID) (_ []*models.SalePrice, err error) {
+	ctx, span := tracing.Start(ctx, "PricingService.GetSales")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.ownProduct(ctx, vendorID, productID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetSales(ctx, productID)
+}
+
+// ScheduleSale creates a sale for sale.ProductID. A sale whose start has already
+// passed, or that has no start, takes effect immediately.
+func (s *pricingService) ScheduleSale(ctx context.Context, vendorID *uuid.UUID, sale *models.SalePrice) (err error) {
+	ctx, span := tracing.Start(ctx, "PricingService.ScheduleSale")
+	defer func() { tracing.End(span, err) }()
+
+	product, err := s.ownProduct(ctx, vendorID, sale.ProductID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if sale.StartsAt.IsZero() {
+		sale.StartsAt = now
+	}
+	if sale.SalePrice <= 0 {
+		return errors.New("sale_price must be greater than 0")
+	}
+	if sale.EndsAt.IsZero() {
+		return errors.New("ends_at is required")
+	}
+	if !sale.EndsAt.After(sale.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if !sale.EndsAt.After(now) {
+		return errors.New("ends_at must be in the future")
+	}
+
+	// While another sale is running the product price is that sale's price
+	regularPrice := product.Price
+	active, err := s.repo.GetActiveSale(ctx, product.ID)
+	if err != nil {
+		return err
+	}
+	if active != nil && active.OriginalPrice != nil {
+		regularPrice = *active.OriginalPrice
+	}
+	sale.SalePrice = roundMoney(sale.SalePrice)
+	if sale.SalePrice >= regularPrice {
+		return errors.New("sale_price must be below the regular price")
+	}
+
+	overlaps, err := s.repo.HasOverlappingSale(ctx, product.ID, sale.StartsAt, sale.EndsAt)
+	if err != nil {
+		return err
+	}
+	if overlaps {
+		return errors.New("sale overlaps another scheduled sale")
+	}
+
+	sale.ID = uuid.Nil
+	sale.Status = models.SaleStatusScheduled
+	if err := s.repo.CreateSale(ctx, sale); err != nil {
+		return err
+	}
+
+	if sale.StartsAt.After(now) {
+		return nil
+	}
+	if _, err := s.ApplyDueSales(ctx); err != nil {
+		return err
+	}
+
+	started, err := s.repo.GetSale(ctx, sale.ID)
+	if err != nil {
+		return err
+	}
+	if started != nil {
+		*sale = *started
+	}
+	return nil
+}
+
+// CancelSale cancels a scheduled sale, or ends an active one early and restores the regular price
+func (s *pricingService) CancelSale(ctx context.Context, vendorID *uuid.UUID, productID, saleID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "PricingService.CancelSale")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.ownProduct(ctx, vendorID, productID); err != nil {
+		return err
+	}
+
+	sale, err := s.repo.GetSale(ctx, saleID)
+	if err != nil {
+		return err
+	}
+	if sale == nil || sale.ProductID != productID {
+		return errors.New("sale not found")
+	}
+
+	cancelled, err := s.repo.CancelSale(ctx, saleID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return errors.New("sale has already ended")
+	}
+
+	if sale.Status == models.SaleStatusActive {
+		s.reindex(ctx, []uuid.UUID{productID})
+	}
+	return nil
+}
+
+// GetPriceHistory returns a product's price changes, newest first
+func (s *pricingService) GetPriceHistory(ctx context.Context, vendorID *uuid.UUID, productID uuid.UUID, limit int) (_ []models.PriceHistoryEntry, err error) {
+	ctx, span := tracing.Start(ctx, "PricingService.GetPriceHistory")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.ownProduct(ctx, vendorID, productID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	return s.repo.GetPriceHistory(ctx, productID, limit)
+}
+
+// ApplyDueSales starts and ends sales whose time has come and returns the number of products repriced
+func (s *pricingService) ApplyDueSales(ctx context.Context) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "PricingService.ApplyDueSales")
+	defer func() { tracing.End(span, err) }()
+
+	changed, err := s.repo.ApplyDueSales(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	s.reindex(ctx, changed)
+	return len(changed), nil
+}
+
+// RunSaleScheduler applies due sales every interval until ctx is cancelled
+func (s *pricingService) RunSaleScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := s.ApplyDueSales(ctx); err != nil {
+			slog.ErrorContext(ctx, "Failed to apply scheduled sale prices", "error", err)
+		} else if count > 0 {
+			slog.InfoContext(ctx, "Applied scheduled sale prices", "products", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ownProduct loads a product, treating another vendor's product as not found
+func (s *pricingService) ownProduct(ctx context.Context, vendorID *uuid.UUID, productID uuid.UUID) (*models.Product, error) {
+	if productID == uuid.Nil {
+		return nil, errors.New("invalid product ID")
+	}
+
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil || (vendorID != nil && product.VendorID != *vendorID) {
+		return nil, errors.New("product not found")
+	}
+
+	return product, nil
+}
+
+// reindex refreshes repriced products in the search index. The products table is
+// the source of truth, so failures are logged rather than returned.
+func (s *pricingService) reindex(ctx context.Context, productIDs []uuid.UUID) {
+	for _, id := range productIDs {
+		product, err := s.productRepo.GetByID(ctx, id)
+		if err != nil || product == nil {
+			slog.ErrorContext(ctx, "Failed to load repriced product", "product_id", id, "error", err)
+			continue
+		}
+		if err := s.searchIndex.Index(product); err != nil {
+			slog.ErrorContext(ctx, "Failed to index product", "product_id", id, "error", err)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/search"
+
+	"github.com/google/uuid"
+)
+
+// schedulingPricingRepository keeps sales in memory and reprices the products
+// they belong to the way the sale_prices queries do
+type schedulingPricingRepository struct {
+	repository.PricingRepository
+	products map[uuid.UUID]*models.Product
+	sales    []*models.SalePrice
+}
+
+func (r *schedulingPricingRepository) CreateSale(_ context.Context, sale *models.SalePrice) error {
+	sale.ID = uuid.New()
+	copied := *sale
+	r.sales = append(r.sales, &copied)
+	return nil
+}
+
+func (r *schedulingPricingRepository) GetSale(_ context.Context, id uuid.UUID) (*models.SalePrice, error) {
+	for _, sale := range r.sales {
+		if sale.ID == id {
+			copied := *sale
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *schedulingPricingRepository) GetActiveSale(_ context.Context, productID uuid.UUID) (*models.SalePrice, error) {
+	for _, sale := range r.sales {
+		if sale.ProductID == productID && sale.Status == models.SaleStatusActive {
+			copied := *sale
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *schedulingPricingRepository) HasOverlappingSale(_ context.Context, productID uuid.UUID, startsAt, endsAt time.Time) (bool, error) {
+	for _, sale := range r.sales {
+		open := sale.Status == models.SaleStatusScheduled || sale.Status == models.SaleStatusActive
+		if open && sale.ProductID == productID && sale.StartsAt.Before(endsAt) && startsAt.Before(sale.EndsAt) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *schedulingPricingRepository) CancelSale(_ context.Context, id uuid.UUID) (bool, error) {
+	for _, sale := range r.sales {
+		if sale.ID != id {
+			continue
+		}
+		switch sale.Status {
+		case models.SaleStatusActive:
+			r.end(sale)
+		case models.SaleStatusScheduled:
+		default:
+			return false, nil
+		}
+		sale.Status = models.SaleStatusCancelled
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *schedulingPricingRepository) ApplyDueSales(_ context.Context, now time.Time) ([]uuid.UUID, error) {
+	var changed []uuid.UUID
+	for _, sale := range r.sales {
+		if sale.Status == models.SaleStatusActive && !sale.EndsAt.After(now) {
+			r.end(sale)
+			sale.Status = models.SaleStatusEnded
+			changed = append(changed, sale.ProductID)
+		}
+	}
+	for _, sale := range r.sales {
+		if sale.Status == models.SaleStatusScheduled && !sale.EndsAt.After(now) {
+			sale.Status = models.SaleStatusEnded
+		}
+	}
+	for _, sale := range r.sales {
+		if sale.Status == models.SaleStatusScheduled && !sale.StartsAt.After(now) {
+			product := r.products[sale.ProductID]
+			price, comparePrice := product.Price, product.ComparePrice
+			sale.OriginalPrice, sale.OriginalComparePrice = &price, comparePrice
+			product.ComparePrice = &price
+			product.Price = sale.SalePrice
+			sale.Status = models.SaleStatusActive
+			changed = append(changed, sale.ProductID)
+		}
+	}
+	return changed, nil
+}
+
+// end restores the prices the sale replaced
+func (r *schedulingPricingRepository) end(sale *models.SalePrice) {
+	product := r.products[sale.ProductID]
+	product.Price = *sale.OriginalPrice
+	product.ComparePrice = sale.OriginalComparePrice
+}
+
+// recordingIndex records the products indexed
+type recordingIndex struct {
+	*search.MemoryIndex
+	indexed []*models.Product
+}
+
+func (i *recordingIndex) Index(product *models.Product) error {
+	i.indexed = append(i.indexed, product)
+	return nil
+}
+
+func TestScheduleSaleValidation(t *testing.T) {
+	ctx := context.Background()
+	vendorID := uuid.New()
+	product := &models.Product{ID: uuid.New(), VendorID: vendorID, Price: 100, Status: models.ProductStatusActive}
+	products := map[uuid.UUID]*models.Product{product.ID: product}
+	repo := &schedulingPricingRepository{products: products}
+	pricing := NewPricingService(repo, &memoryProductRepository{products: products}, &recordingIndex{MemoryIndex: search.NewMemoryIndex()})
+
+	now := time.Now()
+	scheduled := &models.SalePrice{ProductID: product.ID, SalePrice: 80, StartsAt: now.Add(24 * time.Hour), EndsAt: now.Add(48 * time.Hour)}
+	if err := pricing.ScheduleSale(ctx, &vendorID, scheduled); err != nil {
+		t.Fatal(err)
+	}
+
+	otherVendor := uuid.New()
+	tests := []struct {
+		name     string
+		vendorID *uuid.UUID
+		sale     models.SalePrice
+		want     string
+	}{
+		{"no product", nil, models.SalePrice{SalePrice: 80, EndsAt: now.Add(time.Hour)}, "invalid product ID"},
+		{"another vendor's product", &otherVendor, models.SalePrice{ProductID: product.ID, SalePrice: 80, EndsAt: now.Add(time.Hour)}, "product not found"},
+		{"free", &vendorID, models.SalePrice{ProductID: product.ID, EndsAt: now.Add(time.Hour)}, "sale_price must be greater than 0"},
+		{"no end", &vendorID, models.SalePrice{ProductID: product.ID, SalePrice: 80}, "ends_at is required"},
+		{"ends before it starts", &vendorID, models.SalePrice{ProductID: product.ID, SalePrice: 80, StartsAt: now.Add(2 * time.Hour), EndsAt: now.Add(time.Hour)}, "ends_at must be after starts_at"},
+		{"already over", &vendorID, models.SalePrice{ProductID: product.ID, SalePrice: 80, StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)}, "ends_at must be in the future"},
+		{"not a discount", &vendorID, models.SalePrice{ProductID: product.ID, SalePrice: 100, EndsAt: now.Add(time.Hour)}, "sale_price must be below the regular price"},
+		{"overlapping", nil, models.SalePrice{ProductID: product.ID, SalePrice: 70, StartsAt: now.Add(36 * time.Hour), EndsAt: now.Add(72 * time.Hour)}, "sale overlaps another scheduled sale"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sale := tt.sale
+			if err := pricing.ScheduleSale(ctx, tt.vendorID, &sale); err == nil || err.Error() != tt.want {
+				t.Errorf("ScheduleSale = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if len(repo.sales) != 1 || product.Price != 100 {
+		t.Errorf("refused sales changed %d sales, price %v", len(repo.sales), product.Price)
+	}
+
+	if err := pricing.CancelSale(ctx, &vendorID, product.ID, uuid.New()); err == nil || err.Error() != "sale not found" {
+		t.Errorf("cancelling an unknown sale = %v", err)
+	}
+	if err := pricing.CancelSale(ctx, &vendorID, product.ID, scheduled.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := pricing.CancelSale(ctx, &vendorID, product.ID, scheduled.ID); err == nil || err.Error() != "sale has already ended" {
+		t.Errorf("cancelling twice = %v", err)
+	}
+}
+
+func TestSaleSchedulerStartsAndEndsSales(t *testing.T) {
+	ctx := context.Background()
+	comparePrice := 150.0
+	product := &models.Product{ID: uuid.New(), Price: 100, ComparePrice: &comparePrice, Status: models.ProductStatusActive}
+	products := map[uuid.UUID]*models.Product{product.ID: product}
+	repo := &schedulingPricingRepository{products: products}
+	index := &recordingIndex{MemoryIndex: search.NewMemoryIndex()}
+	pricing := NewPricingService(repo, &memoryProductRepository{products: products}, index)
+
+	// A sale without a start begins at once, showing the regular price as the compare price
+	now := time.Now()
+	sale := &models.SalePrice{ProductID: product.ID, SalePrice: 79.999, EndsAt: now.Add(time.Hour)}
+	if err := pricing.ScheduleSale(ctx, nil, sale); err != nil {
+		t.Fatal(err)
+	}
+	if sale.Status != models.SaleStatusActive || product.Price != 80 || *product.ComparePrice != 100 {
+		t.Fatalf("started %s sale, product at %v compare %v", sale.Status, product.Price, *product.ComparePrice)
+	}
+	if len(index.indexed) != 1 || index.indexed[0].Price != 80 {
+		t.Errorf("indexed %+v after start", index.indexed)
+	}
+
+	// While it runs the regular price is the one the sale replaced
+	next := &models.SalePrice{ProductID: product.ID, SalePrice: 90, StartsAt: now.Add(2 * time.Hour), EndsAt: now.Add(3 * time.Hour)}
+	if err := pricing.ScheduleSale(ctx, nil, next); err != nil {
+		t.Errorf("sale below the regular price refused during another sale: %v", err)
+	}
+
+	// Nothing is due yet
+	if count, err := pricing.ApplyDueSales(ctx); err != nil || count != 0 {
+		t.Fatalf("ApplyDueSales = %d, %v", count, err)
+	}
+
+	// Once the sale is over both prices are restored
+	repo.sales[0].EndsAt = now.Add(-time.Second)
+	if count, err := pricing.ApplyDueSales(ctx); err != nil || count != 1 {
+		t.Fatalf("ApplyDueSales = %d, %v", count, err)
+	}
+	if repo.sales[0].Status != models.SaleStatusEnded || product.Price != 100 || *product.ComparePrice != 150 {
+		t.Errorf("ended %s sale, product at %v compare %v", repo.sales[0].Status, product.Price, *product.ComparePrice)
+	}
+	if last := index.indexed[len(index.indexed)-1]; last.Price != 100 {
+		t.Errorf("indexed price %v after end", last.Price)
+	}
+
+	// A sale whose whole period passed unnoticed never touches the price
+	repo.sales[1].StartsAt, repo.sales[1].EndsAt = now.Add(-2*time.Hour), now.Add(-time.Hour)
+	if count, err := pricing.ApplyDueSales(ctx); err != nil || count != 0 {
+		t.Fatalf("ApplyDueSales = %d, %v", count, err)
+	}
+	if repo.sales[1].Status != models.SaleStatusEnded || product.Price != 100 {
+		t.Errorf("missed %s sale, product at %v", repo.sales[1].Status, product.Price)
+	}
+}
@@ -1,42 +1,126 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
-
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/metrics"
 	"smrtmart-go-postgresql/internal/models"
 	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/search"
+	"smrtmart-go-postgresql/internal/tracing"
 
 	"github.com/google/uuid"
 )
 
 type ProductService interface {
-	CreateProduct(product *models.Product) error
-	GetProduct(id uuid.UUID) (*models.Product, error)
-	GetProductByNumericID(numericID int) (*models.Product, error)
-	GetProducts(filters repository.ProductFilters) (*models.PaginatedResponse, error)
-	UpdateProduct(product *models.Product) error
-	DeleteProduct(id uuid.UUID) error
-	GetVendorProducts(vendorID uuid.UUID, filters repository.ProductFilters) (*models.PaginatedResponse, error)
-	SearchProducts(query string, filters repository.ProductFilters) (*models.PaginatedResponse, error)
-	GetFeaturedProducts(limit int) ([]*models.Product, error)
-	UpdateProductStock(id uuid.UUID, stock int) error
+	CreateProduct(ctx context.Context, product *models.Product) error
+	GetProduct(ctx context.Context, id uuid.UUID) (*models.Product, error)
+	GetProductByNumericID(ctx context.Context, numericID int) (*models.Product, error)
+	GetProducts(ctx context.Context, filters repository.ProductFilters) (*models.PaginatedResponse, error)
+	UpdateProduct(ctx context.Context, product *models.Product) error
+	DeleteProduct(ctx context.Context, id uuid.UUID) error
+	GetVendorProducts(ctx context.Context, vendorID uuid.UUID, filters repository.ProductFilters) (*models.PaginatedResponse, error)
+	GetCategoryProducts(ctx context.Context, categoryID string, filters repository.ProductFilters) (*models.PaginatedResponse, error)
+	SearchProducts(ctx context.Context, query string, filters repository.ProductFilters, facets []string) (*models.SearchResponse, error)
+	GetFeaturedProducts(ctx context.Context, limit int) ([]*models.Product, error)
+	UpdateProductStock(ctx context.Context, id uuid.UUID, stock int) error
+	SuggestProducts(ctx context.Context, query string, limit int) (*models.SearchSuggestions, error)
+	GetZeroResultSearches(ctx context.Context, limit int) ([]models.SearchQueryStat, error)
+	RebuildSearchIndex(ctx context.Context) (int, error)
+	SetSearchLanguage(ctx context.Context, language string) (bool, error)
+	SeedSearchLanguage(ctx context.Context, language string) (bool, error)
+	SyncSearchIndex(ctx context.Context) (int, error)
+	RunSearchIndexSync(ctx context.Context, interval time.Duration)
+	RunQueryRecorder(ctx context.Context)
+}
+
+const (
+	// searchSyncBatchSize is how many events the search index applies per sync
+	searchSyncBatchSize = 500
+
+	// searchSyncSettle is how long a gap in event seqs is waited on before it is
+	// taken to be a rolled back transaction rather than one still committing
+	searchSyncSettle = time.Minute
+
+	// searchQueryBuffer is how many searches can wait to be recorded
+	searchQueryBuffer = 1000
+
+	// searchVectorsJobKind is the job kind refreshing a batch of stored search
+	// vectors after the search language changed
+	searchVectorsJobKind   = "search.refresh_vectors"
+	searchVectorsBatchSize = 500
+)
+
+// searchVectorsJob is the payload of a search.refresh_vectors job
+type searchVectorsJob struct {
+	Language string    `json:"language"`
+	AfterID  uuid.UUID `json:"after_id"` // The batch starts after this product
+}
+
+// searchQuery is a search waiting to be recorded in the query log
+type searchQuery struct {
+	text  string
+	total int
 }
 
 type productService struct {
-	repo repository.ProductRepository
+	repo         repository.ProductRepository
+	categoryRepo repository.CategoryRepository
+	searchRepo   repository.SearchRepository
+	reviewRepo   repository.ReviewRepository
+	pricingRepo  repository.PricingRepository
+	searchIndex  search.Index
+	eventRepo    repository.EventRepository
+	jobs         JobService
+	searchConfig config.SearchConfig
+	lowStock     int // Stock at or below which stock.low is emitted
+	queries      chan searchQuery
+
+	syncMu    sync.Mutex
+	syncedSeq int64               // Events up to here are applied to the search index
+	syncGaps  map[int64]time.Time // Seqs skipped over, with when they were first missed
 }
 
-func NewProductService(repo repository.ProductRepository) ProductService {
-	return &productService{repo: repo}
+func NewProductService(repo repository.ProductRepository, categoryRepo repository.CategoryRepository, searchRepo repository.SearchRepository, reviewRepo repository.ReviewRepository, pricingRepo repository.PricingRepository, eventRepo repository.EventRepository, jobs JobService, searchIndex search.Index, searchConfig config.SearchConfig, lowStockThreshold int) ProductService {
+	s := &productService{
+		repo:         repo,
+		categoryRepo: categoryRepo,
+		searchRepo:   searchRepo,
+		reviewRepo:   reviewRepo,
+		pricingRepo:  pricingRepo,
+		eventRepo:    eventRepo,
+		jobs:         jobs,
+		searchIndex:  searchIndex,
+		searchConfig: searchConfig,
+		lowStock:     lowStockThreshold,
+		queries:      make(chan searchQuery, searchQueryBuffer),
+		syncGaps:     make(map[int64]time.Time),
+	}
+	jobs.Register(searchVectorsJobKind, s.refreshSearchVectors)
+	return s
 }
 
-func (s *productService) CreateProduct(product *models.Product) error {
+func (s *productService) CreateProduct(ctx context.Context, product *models.Product) (err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.CreateProduct")
+	defer func() { tracing.End(span, err) }()
+
 	if product.Name == "" {
 		return errors.New("product name is required")
 	}
 	if product.Price <= 0 {
 		return errors.New("product price must be greater than 0")
 	}
+	if err := s.resolveCategory(ctx, product); err != nil {
+		return err
+	}
 	if product.Category == "" {
 		return errors.New("product category is required")
 	}
@@ -54,15 +138,30 @@ func (s *productService) CreateProduct(product *models.Product) error {
 		product.Images = []string{}
 	}
 
-	return s.repo.Create(product)
+	if product.ID == uuid.Nil {
+		product.ID = uuid.New()
+	}
+	events, err := s.productEvents(models.EventProductCreated, product, nil)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, product, events...); err != nil {
+		return err
+	}
+
+	s.indexProduct(ctx, product)
+	return nil
 }
 
-func (s *productService) GetProduct(id uuid.UUID) (*models.Product, error) {
+func (s *productService) GetProduct(ctx context.Context, id uuid.UUID) (_ *models.Product, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetProduct")
+	defer func() { tracing.End(span, err) }()
+
 	if id == uuid.Nil {
 		return nil, errors.New("invalid product ID")
 	}
 
-	product, err := s.repo.GetByID(id)
+	product, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -70,15 +169,20 @@ func (s *productService) GetProduct(id uuid.UUID) (*models.Product, error) {
 		return nil, errors.New("product not found")
 	}
 
+	s.attachRatings(ctx, []*models.Product{product})
+	s.attachLowestPrices(ctx, []*models.Product{product})
 	return product, nil
 }
 
-func (s *productService) GetProductByNumericID(numericID int) (*models.Product, error) {
+func (s *productService) GetProductByNumericID(ctx context.Context, numericID int) (_ *models.Product, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetProductByNumericID")
+	defer func() { tracing.End(span, err) }()
+
 	if numericID < 1 || numericID > 50 {
 		return nil, errors.New("invalid numeric product ID")
 	}
 
-	product, err := s.repo.GetByNumericID(numericID)
+	product, err := s.repo.GetByNumericID(ctx, numericID)
 	if err != nil {
 		return nil, err
 	}
@@ -86,10 +190,15 @@ func (s *productService) GetProductByNumericID(numericID int) (*models.Product,
 		return nil, errors.New("product not found")
 	}
 
+	s.attachRatings(ctx, []*models.Product{product})
+	s.attachLowestPrices(ctx, []*models.Product{product})
 	return product, nil
 }
 
-func (s *productService) GetProducts(filters repository.ProductFilters) (*models.PaginatedResponse, error) {
+func (s *productService) GetProducts(ctx context.Context, filters repository.ProductFilters) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetProducts")
+	defer func() { tracing.End(span, err) }()
+
 	// Set default pagination
 	if filters.Limit <= 0 {
 		filters.Limit = 20
@@ -98,10 +207,12 @@ func (s *productService) GetProducts(filters repository.ProductFilters) (*models
 		filters.Page = 1
 	}
 
-	products, total, err := s.repo.GetAll(filters)
+	products, total, err := s.repo.GetAll(ctx, filters)
 	if err != nil {
 		return nil, err
 	}
+	s.attachRatings(ctx, products)
+	s.attachLowestPrices(ctx, products)
 
 	totalPages := (total + filters.Limit - 1) / filters.Limit
 
@@ -116,7 +227,10 @@ func (s *productService) GetProducts(filters repository.ProductFilters) (*models
 	}, nil
 }
 
-func (s *productService) UpdateProduct(product *models.Product) error {
+func (s *productService) UpdateProduct(ctx context.Context, product *models.Product) (err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.UpdateProduct")
+	defer func() { tracing.End(span, err) }()
+
 	if product.ID == uuid.Nil {
 		return errors.New("invalid product ID")
 	}
@@ -126,12 +240,15 @@ func (s *productService) UpdateProduct(product *models.Product) error {
 	if product.Price <= 0 {
 		return errors.New("product price must be greater than 0")
 	}
+	if err := s.resolveCategory(ctx, product); err != nil {
+		return err
+	}
 	if product.Category == "" {
 		return errors.New("product category is required")
 	}
 
 	// Check if product exists
-	existing, err := s.repo.GetByID(product.ID)
+	existing, err := s.repo.GetByID(ctx, product.ID)
 	if err != nil {
 		return err
 	}
@@ -139,16 +256,44 @@ func (s *productService) UpdateProduct(product *models.Product) error {
 		return errors.New("product not found")
 	}
 
-	return s.repo.Update(product)
+	// A running sale owns the prices until it ends or is cancelled
+	if roundMoney(product.Price) != roundMoney(existing.Price) || !sameOptionalPrice(product.ComparePrice, existing.ComparePrice) {
+		active, err := s.pricingRepo.GetActiveSale(ctx, product.ID)
+		if err != nil {
+			return err
+		}
+		if active != nil {
+			return errors.New("product has an active sale; cancel it before changing the price")
+		}
+	}
+
+	// Fields not part of the update are carried over so the events and the indexed copy are complete
+	product.NumericID = existing.NumericID
+	product.VendorID = existing.VendorID
+	product.CreatedAt = existing.CreatedAt
+
+	events, err := s.productEvents(models.EventProductUpdated, product, &existing.Stock)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, product, events...); err != nil {
+		return err
+	}
+
+	s.indexProduct(ctx, product)
+	return nil
 }
 
-func (s *productService) DeleteProduct(id uuid.UUID) error {
+func (s *productService) DeleteProduct(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.DeleteProduct")
+	defer func() { tracing.End(span, err) }()
+
 	if id == uuid.Nil {
 		return errors.New("invalid product ID")
 	}
 
 	// Check if product exists
-	existing, err := s.repo.GetByID(id)
+	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -156,10 +301,24 @@ func (s *productService) DeleteProduct(id uuid.UUID) error {
 		return errors.New("product not found")
 	}
 
-	return s.repo.Delete(id)
+	events, err := s.productEvents(models.EventProductDeleted, existing, nil)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id, events...); err != nil {
+		return err
+	}
+
+	if err := s.searchIndex.Delete(id); err != nil {
+		slog.ErrorContext(ctx, "Failed to remove product from search index", "product_id", id, "error", err)
+	}
+	return nil
 }
 
-func (s *productService) GetVendorProducts(vendorID uuid.UUID, filters repository.ProductFilters) (*models.PaginatedResponse, error) {
+func (s *productService) GetVendorProducts(ctx context.Context, vendorID uuid.UUID, filters repository.ProductFilters) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetVendorProducts")
+	defer func() { tracing.End(span, err) }()
+
 	if vendorID == uuid.Nil {
 		return nil, errors.New("invalid vendor ID")
 	}
@@ -172,10 +331,12 @@ func (s *productService) GetVendorProducts(vendorID uuid.UUID, filters repositor
 		filters.Page = 1
 	}
 
-	products, total, err := s.repo.GetByVendor(vendorID, filters)
+	products, total, err := s.repo.GetByVendor(ctx, vendorID, filters)
 	if err != nil {
 		return nil, err
 	}
+	s.attachRatings(ctx, products)
+	s.attachLowestPrices(ctx, products)
 
 	totalPages := (total + filters.Limit - 1) / filters.Limit
 
@@ -190,9 +351,39 @@ func (s *productService) GetVendorProducts(vendorID uuid.UUID, filters repositor
 	}, nil
 }
 
-func (s *productService) SearchProducts(query string, filters repository.ProductFilters) (*models.PaginatedResponse, error) {
+// GetCategoryProducts lists products in a category (by UUID or slug) and all of its descendants
+func (s *productService) GetCategoryProducts(ctx context.Context, categoryID string, filters repository.ProductFilters) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetCategoryProducts")
+	defer func() { tracing.End(span, err) }()
+
+	category, err := findCategory(ctx, s.categoryRepo, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, errors.New("category not found")
+	}
+
+	ids, err := s.categoryRepo.GetDescendantIDs(ctx, category.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	filters.Category = ""
+	filters.CategoryIDs = ids
+	return s.GetProducts(ctx, filters)
+}
+
+func (s *productService) SearchProducts(ctx context.Context, query string, filters repository.ProductFilters, facets []string) (_ *models.SearchResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.SearchProducts")
+	defer func() { tracing.End(span, err) }()
+
 	if query == "" {
-		return s.GetProducts(filters)
+		result, err := s.GetProducts(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+		return &models.SearchResponse{Data: result.Data, Pagination: result.Pagination}, nil
 	}
 
 	// Set default pagination
@@ -203,25 +394,46 @@ func (s *productService) SearchProducts(query string, filters repository.Product
 		filters.Page = 1
 	}
 
-	products, total, err := s.repo.Search(query, filters)
+	result, err := s.searchIndex.Query(ctx, search.Query{
+		Text:    query,
+		Filters: filters,
+		Facets:  facets,
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.attachRatings(ctx, result.Products)
+	s.attachLowestPrices(ctx, result.Products)
 
-	totalPages := (total + filters.Limit - 1) / filters.Limit
+	// Log the first page of each search so suggestions and zero-result reports
+	// stay current. The log is best effort: when the recorder falls behind,
+	// searches are dropped rather than held up.
+	if filters.Page == 1 {
+		select {
+		case s.queries <- searchQuery{text: normalizeQuery(query), total: result.Total}:
+		default:
+			metrics.SearchQueriesDropped.Inc()
+		}
+	}
 
-	return &models.PaginatedResponse{
-		Data: products,
+	totalPages := (result.Total + filters.Limit - 1) / filters.Limit
+
+	return &models.SearchResponse{
+		Data: result.Products,
 		Pagination: models.Pagination{
 			Page:       filters.Page,
 			Limit:      filters.Limit,
-			Total:      total,
+			Total:      result.Total,
 			TotalPages: totalPages,
 		},
+		Facets: result.Facets,
 	}, nil
 }
 
-func (s *productService) GetFeaturedProducts(limit int) ([]*models.Product, error) {
+func (s *productService) GetFeaturedProducts(ctx context.Context, limit int) (_ []*models.Product, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetFeaturedProducts")
+	defer func() { tracing.End(span, err) }()
+
 	if limit <= 0 {
 		limit = 10
 	}
@@ -229,10 +441,20 @@ func (s *productService) GetFeaturedProducts(limit int) ([]*models.Product, erro
 		limit = 50
 	}
 
-	return s.repo.GetFeatured(limit)
+	products, err := s.repo.GetFeatured(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	s.attachRatings(ctx, products)
+	s.attachLowestPrices(ctx, products)
+	return products, nil
 }
 
-func (s *productService) UpdateProductStock(id uuid.UUID, stock int) error {
+func (s *productService) UpdateProductStock(ctx context.Context, id uuid.UUID, stock int) (err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.UpdateProductStock")
+	defer func() { tracing.End(span, err) }()
+
 	if id == uuid.Nil {
 		return errors.New("invalid product ID")
 	}
@@ -241,7 +463,7 @@ func (s *productService) UpdateProductStock(id uuid.UUID, stock int) error {
 	}
 
 	// Check if product exists
-	existing, err := s.repo.GetByID(id)
+	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -249,5 +471,458 @@ func (s *productService) UpdateProductStock(id uuid.UUID, stock int) error {
 		return errors.New("product not found")
 	}
 
-	return s.repo.UpdateStock(id, stock)
-}
\ No newline at end of file
+	previous := existing.Stock
+	existing.Stock = stock
+	events, err := s.stockEvents(existing, previous)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdateStock(ctx, id, stock, events...); err != nil {
+		return err
+	}
+
+	s.indexProduct(ctx, existing)
+	return nil
+}
+
+func (s *productService) SuggestProducts(ctx context.Context, query string, limit int) (_ *models.SearchSuggestions, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.SuggestProducts")
+	defer func() { tracing.End(span, err) }()
+
+	query = normalizeQuery(query)
+	if len([]rune(query)) < 2 {
+		return &models.SearchSuggestions{
+			Products:   []models.ProductSuggestion{},
+			Categories: []models.CategorySuggestion{},
+			Queries:    []string{},
+		}, nil
+	}
+
+	if limit <= 0 {
+		limit = 5
+	}
+	if limit > 10 {
+		limit = 10
+	}
+
+	if s.searchConfig.SuggestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.searchConfig.SuggestTimeout)
+		defer cancel()
+	}
+
+	return s.searchRepo.Suggest(ctx, query, limit)
+}
+
+func (s *productService) GetZeroResultSearches(ctx context.Context, limit int) (_ []models.SearchQueryStat, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetZeroResultSearches")
+	defer func() { tracing.End(span, err) }()
+
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	return s.searchRepo.GetZeroResultQueries(ctx, limit)
+}
+
+// RebuildSearchIndex reloads every product from the products table into the
+// search index. Syncing then resumes from events that might have committed
+// after the products were read.
+func (s *productService) RebuildSearchIndex(ctx context.Context) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.RebuildSearchIndex")
+	defer func() { tracing.End(span, err) }()
+
+	const pageSize = 500
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	seq, err := s.eventRepo.SeqBefore(ctx, time.Now().Add(-searchSyncSettle))
+	if err != nil {
+		return 0, err
+	}
+
+	var products []*models.Product
+	for page := 1; ; page++ {
+		batch, _, err := s.repo.GetAll(ctx, repository.ProductFilters{Page: page, Limit: pageSize, SortBy: "created_at"})
+		if err != nil {
+			return 0, err
+		}
+		products = append(products, batch...)
+		if len(batch) < pageSize {
+			break
+		}
+	}
+
+	if err := s.searchIndex.Rebuild(products); err != nil {
+		return 0, err
+	}
+	s.syncedSeq = seq
+	s.syncGaps = make(map[int64]time.Time)
+
+	return len(products), nil
+}
+
+// SetSearchLanguage switches the text-search configuration of the stored search
+// vectors, e.g. to "english". It returns true if the language changed, in which
+// case the vectors are refreshed in batches by background jobs; searches keep
+// working meanwhile, with products not yet refreshed stemmed the old way.
+func (s *productService) SetSearchLanguage(ctx context.Context, language string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.SetSearchLanguage")
+	defer func() { tracing.End(span, err) }()
+
+	language, refresh, err := s.searchLanguageChange(language)
+	if err != nil {
+		return false, err
+	}
+	return s.repo.SetSearchLanguage(ctx, language, refresh)
+}
+
+// SeedSearchLanguage sets the search language like SetSearchLanguage, unless
+// one was set before, by an admin or an earlier seed
+func (s *productService) SeedSearchLanguage(ctx context.Context, language string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.SeedSearchLanguage")
+	defer func() { tracing.End(span, err) }()
+
+	language, refresh, err := s.searchLanguageChange(language)
+	if err != nil {
+		return false, err
+	}
+	return s.repo.SeedSearchLanguage(ctx, language, refresh)
+}
+
+// searchLanguageChange normalises a search language and builds the first job
+// refreshing the search vectors, to be queued if the language changes
+func (s *productService) searchLanguageChange(language string) (string, *models.Job, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		return "", nil, errors.New("search language is required")
+	}
+
+	payload := searchVectorsJob{Language: language}
+	refresh, err := s.jobs.NewJob(searchVectorsJobKind, payload, searchVectorsJobOptions(payload))
+	if err != nil {
+		return "", nil, err
+	}
+	return language, refresh, nil
+}
+
+func searchVectorsJobOptions(payload searchVectorsJob) JobOptions {
+	return JobOptions{
+		UniqueKey: fmt.Sprintf("%s:%s:%s", searchVectorsJobKind, payload.Language, payload.AfterID),
+	}
+}
+
+// refreshSearchVectors refreshes one batch of search vectors and queues the
+// next. Refreshing a batch again is harmless, so a retried job just repeats it.
+func (s *productService) refreshSearchVectors(ctx context.Context, job *models.Job) error {
+	var payload searchVectorsJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return PermanentJobError(fmt.Errorf("invalid search vectors job payload: %w", err))
+	}
+
+	last, count, err := s.repo.RefreshSearchVectors(ctx, payload.AfterID, searchVectorsBatchSize)
+	if err != nil {
+		return err
+	}
+	if count < searchVectorsBatchSize {
+		slog.InfoContext(ctx, "Refreshed search vectors", "language", payload.Language)
+		return nil
+	}
+
+	payload.AfterID = last
+	_, err = s.jobs.Enqueue(ctx, searchVectorsJobKind, payload, searchVectorsJobOptions(payload))
+	return err
+}
+
+// SyncSearchIndex applies product events stored since the last sync to the
+// search index, so an index held in memory sees changes made by other
+// processes. Products are reloaded rather than taken from the event, which
+// makes applying an event again, or out of order, harmless. It returns the
+// number of events read.
+func (s *productService) SyncSearchIndex(ctx context.Context) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.SyncSearchIndex")
+	defer func() { tracing.End(span, err) }()
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	now := time.Now()
+	missing := make([]int64, 0, len(s.syncGaps))
+	for seq, since := range s.syncGaps {
+		if now.Sub(since) > searchSyncSettle {
+			delete(s.syncGaps, seq)
+			continue
+		}
+		missing = append(missing, seq)
+	}
+
+	events, err := s.eventRepo.GetSince(ctx, s.syncedSeq, missing, searchSyncBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if err := s.indexEvent(ctx, event); err != nil {
+			return 0, fmt.Errorf("failed to index event %s: %w", event.ID, err)
+		}
+		if event.Seq <= s.syncedSeq {
+			delete(s.syncGaps, event.Seq)
+			continue
+		}
+		for seq := max(s.syncedSeq+1, event.Seq-searchSyncBatchSize); seq < event.Seq; seq++ {
+			s.syncGaps[seq] = now
+		}
+		s.syncedSeq = event.Seq
+	}
+
+	return len(events), nil
+}
+
+// RunSearchIndexSync applies new product events to the search index every
+// interval until ctx is cancelled
+func (s *productService) RunSearchIndexSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches suggest more events are waiting
+		for {
+			synced, err := s.SyncSearchIndex(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to sync search index", "error", err)
+			}
+			if err != nil || synced < searchSyncBatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunQueryRecorder writes searches to the query log until ctx is cancelled.
+// Searches still buffered then are not recorded.
+func (s *productService) RunQueryRecorder(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case q := <-s.queries:
+			if err := s.searchRepo.RecordQuery(ctx, q.text, q.total); err != nil {
+				slog.ErrorContext(ctx, "Failed to record search query", "error", err)
+			}
+		}
+	}
+}
+
+// indexEvent reindexes the product a product or stock event is about
+func (s *productService) indexEvent(ctx context.Context, event *models.DomainEvent) error {
+	switch event.Type {
+	case models.EventProductCreated, models.EventProductUpdated, models.EventProductDeleted, models.EventStockChanged:
+	default:
+		return nil
+	}
+
+	var payload models.ProductEventPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		// Failing here would hold the sync at this event for good
+		slog.ErrorContext(ctx, "Skipping unreadable product event", "event_id", event.ID, "error", err)
+		return nil
+	}
+	if event.Type == models.EventProductDeleted {
+		return s.searchIndex.Delete(payload.ProductID)
+	}
+
+	product, err := s.repo.GetByID(ctx, payload.ProductID)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return s.searchIndex.Delete(payload.ProductID)
+	}
+	return s.searchIndex.Index(product)
+}
+
+// resolveCategory links the product to a row in the categories table. A given
+// CategoryID wins and sets the readable category label; otherwise the label is
+// matched against category slugs so existing clients keep working.
+func (s *productService) resolveCategory(ctx context.Context, product *models.Product) error {
+	if product.CategoryID != nil {
+		category, err := s.categoryRepo.GetByID(ctx, product.CategoryID.String())
+		if err != nil {
+			return err
+		}
+		if category == nil {
+			return errors.New("category not found")
+		}
+		product.Category = category.Slug
+		return nil
+	}
+
+	if product.Category == "" {
+		return nil
+	}
+
+	category, err := findCategory(ctx, s.categoryRepo, product.Category)
+	if err != nil {
+		return err
+	}
+	if category != nil {
+		product.CategoryID = &category.ID
+	}
+	return nil
+}
+
+// indexProduct keeps the search index in sync after a write. The products table is
+// the source of truth, so a failure here is logged rather than failing the request.
+// attachRatings sets each product's review summary. Ratings are not essential
+// to a product listing, so failures are logged rather than returned.
+func (s *productService) attachRatings(ctx context.Context, products []*models.Product) {
+	if len(products) == 0 {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+
+	summaries, err := s.reviewRepo.GetRatingSummaries(ctx, ids)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load product ratings", "error", err)
+		return
+	}
+
+	for _, product := range products {
+		if summary, ok := summaries[product.ID]; ok {
+			product.Rating = summary
+		} else {
+			product.Rating = &models.RatingSummary{Histogram: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+		}
+	}
+}
+
+// attachLowestPrices sets the Omnibus lowest prior price on discounted products,
+// falling back to the current price when there is no earlier price on record.
+// Like ratings, failures are logged rather than returned.
+func (s *productService) attachLowestPrices(ctx context.Context, products []*models.Product) {
+	var ids []uuid.UUID
+	for _, product := range products {
+		if isDiscounted(product) {
+			ids = append(ids, product.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	lowest, err := s.pricingRepo.GetLowestPrices(ctx, ids, omnibusWindowDays)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load lowest prior prices", "error", err)
+		return
+	}
+
+	for _, product := range products {
+		if !isDiscounted(product) {
+			continue
+		}
+		price := product.Price
+		if prior, ok := lowest[product.ID]; ok {
+			price = prior
+		}
+		product.LowestPrice30Days = &price
+	}
+}
+
+// isDiscounted reports whether the product is shown with a higher compare price
+func isDiscounted(product *models.Product) bool {
+	return product.ComparePrice != nil && *product.ComparePrice > product.Price
+}
+
+func sameOptionalPrice(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return roundMoney(*a) == roundMoney(*b)
+}
+
+// productEvents describes a product change. When previousStock is given, stock
+// events are added if the stock changed.
+func (s *productService) productEvents(eventType models.EventType, product *models.Product, previousStock *int) ([]*models.DomainEvent, error) {
+	event, err := models.NewDomainEvent(eventType, "product", product.ID.String(), models.ProductEventPayload{
+		ProductID: product.ID,
+		VendorID:  product.VendorID,
+		Name:      product.Name,
+		SKU:       product.SKU,
+		Price:     product.Price,
+		Stock:     product.Stock,
+		Status:    product.Status,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := []*models.DomainEvent{event}
+	if previousStock != nil {
+		stock, err := s.stockEvents(product, *previousStock)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, stock...)
+	}
+	return events, nil
+}
+
+// stockEvents describes a stock change, adding stock.low when it falls to or
+// below the threshold
+func (s *productService) stockEvents(product *models.Product, previous int) ([]*models.DomainEvent, error) {
+	if product.Stock == previous {
+		return nil, nil
+	}
+
+	payload := models.StockEventPayload{
+		ProductID:     product.ID,
+		VendorID:      product.VendorID,
+		SKU:           product.SKU,
+		PreviousStock: previous,
+		Stock:         product.Stock,
+	}
+	changed, err := models.NewDomainEvent(models.EventStockChanged, "product", product.ID.String(), payload)
+	if err != nil {
+		return nil, err
+	}
+	events := []*models.DomainEvent{changed}
+
+	if product.Stock <= s.lowStock && previous > s.lowStock {
+		payload.Threshold = s.lowStock
+		low, err := models.NewDomainEvent(models.EventStockLow, "product", product.ID.String(), payload)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, low)
+	}
+	return events, nil
+}
+
+func (s *productService) indexProduct(ctx context.Context, product *models.Product) {
+	if err := s.searchIndex.Index(product); err != nil {
+		slog.ErrorContext(ctx, "Failed to index product", "product_id", product.ID, "error", err)
+	}
+}
+
+// normalizeQuery trims, lowercases and collapses whitespace so equivalent searches are logged together
+func normalizeQuery(query string) string {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	if runes := []rune(query); len(runes) > 255 {
+		query = string(runes[:255])
+	}
+	return query
+}
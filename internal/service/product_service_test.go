@@ -0,0 +1,337 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/metrics"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/search"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// memoryProductRepository serves products by ID from a map shared with the test
+type memoryProductRepository struct {
+	repository.ProductRepository
+	products map[uuid.UUID]*models.Product
+}
+
+func (r *memoryProductRepository) GetByID(_ context.Context, id uuid.UUID) (*models.Product, error) {
+	product, ok := r.products[id]
+	if !ok {
+		return nil, nil
+	}
+	p := *product
+	return &p, nil
+}
+
+func (r *memoryProductRepository) GetAll(context.Context, repository.ProductFilters) ([]*models.Product, int, error) {
+	var products []*models.Product
+	for _, product := range r.products {
+		p := *product
+		products = append(products, &p)
+	}
+	return products, len(products), nil
+}
+
+// seqEventRepository holds the committed events of the events table
+type seqEventRepository struct {
+	repository.EventRepository
+	events []*models.DomainEvent
+}
+
+func (r *seqEventRepository) commit(t *testing.T, seq int64, eventType models.EventType, product *models.Product) {
+	t.Helper()
+	event, err := models.NewDomainEvent(eventType, "product", product.ID.String(), models.ProductEventPayload{ProductID: product.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.Seq = seq
+	r.events = append(r.events, event)
+	sort.Slice(r.events, func(i, j int) bool { return r.events[i].Seq < r.events[j].Seq })
+}
+
+func (r *seqEventRepository) GetSince(_ context.Context, afterSeq int64, missing []int64, limit int) ([]*models.DomainEvent, error) {
+	var events []*models.DomainEvent
+	for _, event := range r.events {
+		wanted := event.Seq > afterSeq
+		for _, seq := range missing {
+			wanted = wanted || event.Seq == seq
+		}
+		if wanted && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *seqEventRepository) SeqBefore(_ context.Context, before time.Time) (int64, error) {
+	var seq int64
+	for _, event := range r.events {
+		if event.OccurredAt.Before(before) {
+			seq = event.Seq
+		}
+	}
+	return seq, nil
+}
+
+func TestSyncSearchIndexAppliesOtherProcessesChanges(t *testing.T) {
+	ctx := context.Background()
+	laptop := &models.Product{ID: uuid.New(), Name: "Gaming laptop", Category: "laptops", Status: models.ProductStatusActive}
+	sleeve := &models.Product{ID: uuid.New(), Name: "Laptop sleeve", Category: "bags", Status: models.ProductStatusActive}
+	products := &memoryProductRepository{products: map[uuid.UUID]*models.Product{laptop.ID: laptop, sleeve.ID: sleeve}}
+	events := &seqEventRepository{}
+	index := search.NewMemoryIndex()
+	service := NewProductService(products, nil, nil, nil, nil, events, newRegisteringJobService(), index, config.SearchConfig{}, 5).(*productService)
+
+	if count, err := service.RebuildSearchIndex(ctx); err != nil || count != 2 {
+		t.Fatalf("rebuilt %d products, err %v", count, err)
+	}
+
+	// Another process renames the laptops category; the sleeve's deletion took
+	// seq 1 but has not committed yet
+	laptop.Category = "notebooks"
+	events.commit(t, 2, models.EventProductUpdated, laptop)
+	if _, err := service.SyncSearchIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertCategoryMatches(t, index, "notebooks", 1)
+	assertCategoryMatches(t, index, "laptops", 0)
+	assertCategoryMatches(t, index, "bags", 1)
+
+	delete(products.products, sleeve.ID)
+	events.commit(t, 1, models.EventProductDeleted, sleeve)
+	if _, err := service.SyncSearchIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertCategoryMatches(t, index, "bags", 0)
+	if len(service.syncGaps) != 0 {
+		t.Errorf("filled gap still tracked: %v", service.syncGaps)
+	}
+
+	// Applying every event again changes nothing
+	service.syncedSeq = 0
+	if synced, err := service.SyncSearchIndex(ctx); err != nil || synced != 2 {
+		t.Fatalf("resynced %d events, err %v", synced, err)
+	}
+	assertCategoryMatches(t, index, "notebooks", 1)
+	assertCategoryMatches(t, index, "bags", 0)
+}
+
+func TestSyncSearchIndexGivesUpOnRolledBackSeqs(t *testing.T) {
+	ctx := context.Background()
+	product := &models.Product{ID: uuid.New(), Name: "Desk lamp", Category: "lighting"}
+	events := &seqEventRepository{}
+	service := NewProductService(&memoryProductRepository{products: map[uuid.UUID]*models.Product{product.ID: product}},
+		nil, nil, nil, nil, events, newRegisteringJobService(), search.NewMemoryIndex(), config.SearchConfig{}, 5).(*productService)
+
+	events.commit(t, 3, models.EventProductUpdated, product)
+	if _, err := service.SyncSearchIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if service.syncedSeq != 3 || len(service.syncGaps) != 2 {
+		t.Fatalf("synced to %d with gaps %v", service.syncedSeq, service.syncGaps)
+	}
+
+	for seq := range service.syncGaps {
+		service.syncGaps[seq] = time.Now().Add(-2 * searchSyncSettle)
+	}
+	if _, err := service.SyncSearchIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(service.syncGaps) != 0 {
+		t.Errorf("expired gaps still tracked: %v", service.syncGaps)
+	}
+}
+
+func assertCategoryMatches(t *testing.T, index search.Index, category string, want int) {
+	t.Helper()
+	result, err := index.Query(context.Background(), search.Query{
+		Text:    "laptop",
+		Filters: repository.ProductFilters{Category: category},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != want {
+		t.Errorf("%d products in %s match, want %d", result.Total, category, want)
+	}
+}
+
+// recordingSearchRepository collects recorded queries
+type recordingSearchRepository struct {
+	repository.SearchRepository
+	recorded chan string
+}
+
+func (r *recordingSearchRepository) RecordQuery(_ context.Context, query string, resultCount int) error {
+	r.recorded <- query
+	return nil
+}
+
+func TestSearchQueriesAreRecordedInTheBackground(t *testing.T) {
+	searches := &recordingSearchRepository{recorded: make(chan string, searchQueryBuffer)}
+	service := NewProductService(nil, nil, searches, nil, nil, nil, newRegisteringJobService(), search.NewMemoryIndex(), config.SearchConfig{}, 5)
+	ctx := context.Background()
+
+	// Nothing drains the buffer yet, so the search after a full buffer is dropped
+	dropped := testutil.ToFloat64(metrics.SearchQueriesDropped)
+	for i := 0; i <= searchQueryBuffer; i++ {
+		if _, err := service.SearchProducts(ctx, "  Gaming  LAPTOP ", repository.ProductFilters{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := service.SearchProducts(ctx, "laptop", repository.ProductFilters{Page: 2}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(metrics.SearchQueriesDropped) - dropped; got != 1 {
+		t.Errorf("dropped %v searches, want 1", got)
+	}
+
+	recorderCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		service.RunQueryRecorder(recorderCtx)
+		close(done)
+	}()
+	for i := 0; i < searchQueryBuffer; i++ {
+		select {
+		case query := <-searches.recorded:
+			if query != "gaming laptop" {
+				t.Fatalf("recorded %q", query)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("recorded %d of %d buffered searches", i, searchQueryBuffer)
+		}
+	}
+	stop()
+	<-done
+	if len(searches.recorded) != 0 {
+		t.Errorf("later pages were recorded too")
+	}
+}
+
+// vectorProductRepository holds the search language, queueing the refresh job
+// when it changes, and refreshes vectors of products with the IDs it was given
+type vectorProductRepository struct {
+	repository.ProductRepository
+	jobs      *registeringJobService
+	language  string
+	set       bool        // The language was set or seeded
+	ids       []uuid.UUID // In ID order
+	refreshed map[uuid.UUID]int
+}
+
+func (r *vectorProductRepository) SetSearchLanguage(_ context.Context, language string, refresh *models.Job) (bool, error) {
+	changed := r.language != language
+	r.language, r.set = language, true
+	if changed {
+		r.jobs.queue(refresh)
+	}
+	return changed, nil
+}
+
+func (r *vectorProductRepository) SeedSearchLanguage(ctx context.Context, language string, refresh *models.Job) (bool, error) {
+	if r.set {
+		return false, nil
+	}
+	return r.SetSearchLanguage(ctx, language, refresh)
+}
+
+func (r *vectorProductRepository) RefreshSearchVectors(_ context.Context, afterID uuid.UUID, limit int) (uuid.UUID, int, error) {
+	last, count := afterID, 0
+	for _, id := range r.ids {
+		if bytes.Compare(id[:], afterID[:]) > 0 && count < limit {
+			r.refreshed[id]++
+			last = id
+			count++
+		}
+	}
+	return last, count, nil
+}
+
+func TestSetSearchLanguageRefreshesVectorsInBatchJobs(t *testing.T) {
+	ctx := context.Background()
+	jobs := newRegisteringJobService()
+	products := &vectorProductRepository{jobs: jobs, language: "swedish", refreshed: make(map[uuid.UUID]int)}
+	for i := 0; i < 2*searchVectorsBatchSize+1; i++ {
+		products.ids = append(products.ids, uuid.New())
+	}
+	sort.Slice(products.ids, func(i, j int) bool { return bytes.Compare(products.ids[i][:], products.ids[j][:]) < 0 })
+	service := NewProductService(products, nil, nil, nil, nil, nil, jobs, search.NewMemoryIndex(), config.SearchConfig{}, 5)
+
+	if changed, err := service.SetSearchLanguage(ctx, "swedish"); err != nil || changed {
+		t.Fatalf("unchanged language: changed %v, err %v", changed, err)
+	}
+	if len(jobs.queued) != 0 {
+		t.Fatalf("queued %d jobs for an unchanged language", len(jobs.queued))
+	}
+
+	if changed, err := service.SetSearchLanguage(ctx, " English "); err != nil || !changed {
+		t.Fatalf("changed %v, err %v", changed, err)
+	}
+	if products.language != "english" {
+		t.Errorf("language set to %q", products.language)
+	}
+
+	// Each job refreshes one batch and queues the next
+	for i := 0; i < len(jobs.queued); i++ {
+		job := jobs.queued[i]
+		if err := jobs.handlers[job.Kind](ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		// A retried batch does not queue its successor twice
+		if i == 0 {
+			if err := jobs.handlers[job.Kind](ctx, job); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if len(jobs.queued) != 3 {
+		t.Errorf("ran %d jobs, want 3", len(jobs.queued))
+	}
+	for i, id := range products.ids {
+		want := 1
+		if i < searchVectorsBatchSize {
+			want = 2
+		}
+		if products.refreshed[id] != want {
+			t.Fatalf("product %d refreshed %d times, want %d", i, products.refreshed[id], want)
+		}
+	}
+}
+
+func TestSeedSearchLanguageOnlyWhenUnset(t *testing.T) {
+	ctx := context.Background()
+	jobs := newRegisteringJobService()
+	products := &vectorProductRepository{jobs: jobs, language: "swedish", refreshed: make(map[uuid.UUID]int)}
+	service := NewProductService(products, nil, nil, nil, nil, nil, jobs, search.NewMemoryIndex(), config.SearchConfig{}, 5)
+
+	if changed, err := service.SeedSearchLanguage(ctx, "english"); err != nil || !changed {
+		t.Fatalf("first seed: changed %v, err %v", changed, err)
+	}
+	if len(jobs.queued) != 1 || jobs.queued[0].Kind != searchVectorsJobKind {
+		t.Fatalf("queued %+v, want one refresh job", jobs.queued)
+	}
+
+	// An admin's choice is kept when the configured language seeds it again
+	if _, err := service.SetSearchLanguage(ctx, "danish"); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := service.SeedSearchLanguage(ctx, "english"); err != nil || changed {
+		t.Errorf("seed after an admin set the language: changed %v, err %v", changed, err)
+	}
+	if products.language != "danish" {
+		t.Errorf("language = %q, want the admin's danish", products.language)
+	}
+	if _, err := service.SeedSearchLanguage(ctx, " "); err == nil {
+		t.Error("blank language seeded")
+	}
+}
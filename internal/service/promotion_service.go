@@ -0,0 +1,592 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+const maxPromotionCodeLength = 50
+
+// CartLine is a product and quantity in a cart being priced
+type CartLine struct {
+	ProductRef string // Numeric product ID or UUID
+	Quantity   int
+}
+
+type PromotionService interface {
+	GetPromotions(ctx context.Context, vendorID *uuid.UUID, page, limit int) (*models.PaginatedResponse, error)
+	GetPromotion(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (*models.Promotion, error)
+	CreatePromotion(ctx context.Context, vendorID *uuid.UUID, promotion *models.Promotion) error
+	UpdatePromotion(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID, update *models.Promotion) (*models.Promotion, error)
+	DeletePromotion(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) error
+	Quote(ctx context.Context, customerID *uuid.UUID, lines []CartLine, codes []string) (*models.PromotionQuote, error)
+	Redeem(ctx context.Context, reference string, customerID *uuid.UUID, applied []models.AppliedPromotion, events ...*models.DomainEvent) error
+	CancelRedemptions(ctx context.Context, reference string) error
+}
+
+// errPromotionLimitReached is returned by Redeem when a promotion ran out after the cart was quoted
+var errPromotionLimitReached = errors.New("coupon usage limit reached")
+
+type promotionService struct {
+	repo         repository.PromotionRepository
+	productRepo  repository.ProductRepository
+	categoryRepo repository.CategoryRepository
+}
+
+func NewPromotionService(repo repository.PromotionRepository, productRepo repository.ProductRepository, categoryRepo repository.CategoryRepository) PromotionService {
+	return &promotionService{
+		repo:         repo,
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+	}
+}
+
+// GetPromotions pages through promotions. A vendor only sees its own; admins (nil vendorID) see all.
+func (s *promotionService) GetPromotions(ctx context.Context, vendorID *uuid.UUID, page, limit int) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "PromotionService.GetPromotions")
+	defer func() { tracing.End(span, err) }()
+
+	// Set default pagination
+	if limit <= 0 {
+		limit = 20
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	promotions, total, err := s.repo.GetAll(ctx, vendorID, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &models.PaginatedResponse{
+		Data: promotions,
+		Pagination: models.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+func (s *promotionService) GetPromotion(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (_ *models.Promotion, err error) {
+	ctx, span := tracing.Start(ctx, "PromotionService.GetPromotion")
+	defer func() { tracing.End(span, err) }()
+
+	return s.ownPromotion(ctx, vendorID, id)
+}
+
+// CreatePromotion adds a promotion. Promotions created by a vendor only apply to its own products.
+func (s *promotionService) CreatePromotion(ctx context.Context, vendorID *uuid.UUID, promotion *models.Promotion) (err error) {
+	ctx, span := tracing.Start(ctx, "PromotionService.CreatePromotion")
+	defer func() { tracing.End(span, err) }()
+
+	if vendorID != nil {
+		promotion.VendorID = vendorID
+	}
+
+	if err := s.validatePromotion(ctx, promotion, uuid.Nil); err != nil {
+		return err
+	}
+
+	promotion.ID = uuid.Nil
+	promotion.UsageCount = 0
+	return s.repo.Create(ctx, promotion)
+}
+
+// UpdatePromotion replaces a promotion's settings. The owning vendor and usage count cannot be changed.
+func (s *promotionService) UpdatePromotion(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID, update *models.Promotion) (_ *models.Promotion, err error) {
+	ctx, span := tracing.Start(ctx, "PromotionService.UpdatePromotion")
+	defer func() { tracing.End(span, err) }()
+
+	promotion, err := s.ownPromotion(ctx, vendorID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	update.ID = promotion.ID
+	update.VendorID = promotion.VendorID
+	update.CreatedBy = promotion.CreatedBy
+	update.CreatedAt = promotion.CreatedAt
+	if err := s.validatePromotion(ctx, update, promotion.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, update); err != nil {
+		return nil, err
+	}
+
+	return update, nil
+}
+
+// DeletePromotion removes a promotion that has never been redeemed. Redeemed
+// promotions are kept for the order history and should be deactivated instead.
+func (s *promotionService) DeletePromotion(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "PromotionService.DeletePromotion")
+	defer func() { tracing.End(span, err) }()
+
+	promotion, err := s.ownPromotion(ctx, vendorID, id)
+	if err != nil {
+		return err
+	}
+	if promotion.UsageCount > 0 {
+		return errors.New("promotion has been redeemed; deactivate it instead")
+	}
+
+	return s.repo.Delete(ctx, id)
+}
+
+// pricedLine is a cart line with its product and category path resolved
+type pricedLine struct {
+	product  *models.Product
+	path     []models.Category
+	quantity int
+	total    float64
+}
+
+// Quote applies the given coupon codes and any automatic promotions to a cart
+// using current product prices. Invalid coupons are reported as errors starting
+// with "coupon"; automatic promotions that do not apply are skipped. The
+// customer is needed to check per-customer limits and is nil for guests.
+func (s *promotionService) Quote(ctx context.Context, customerID *uuid.UUID, lines []CartLine, codes []string) (_ *models.PromotionQuote, err error) {
+	ctx, span := tracing.Start(ctx, "PromotionService.Quote")
+	defer func() { tracing.End(span, err) }()
+
+	priced, subtotal, err := s.priceLines(ctx, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var coupons []*models.Promotion
+	seen := map[uuid.UUID]bool{}
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+
+		promotion, err := s.repo.GetByCode(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		if promotion == nil {
+			return nil, fmt.Errorf("coupon %s not found", code)
+		}
+		if seen[promotion.ID] {
+			continue
+		}
+		seen[promotion.ID] = true
+
+		if reason, err := s.ineligibleReason(ctx, promotion, customerID, priced, subtotal, now); err != nil {
+			return nil, err
+		} else if reason != "" {
+			return nil, fmt.Errorf("coupon %s %s", code, reason)
+		}
+		coupons = append(coupons, promotion)
+	}
+
+	if len(coupons) > 1 {
+		for _, coupon := range coupons {
+			if !coupon.Stackable {
+				return nil, fmt.Errorf("coupon %s cannot be combined with other promotions", *coupon.Code)
+			}
+		}
+	}
+
+	automatic, err := s.repo.GetAutomatic(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var eligible []*models.Promotion
+	for _, promotion := range automatic {
+		reason, err := s.ineligibleReason(ctx, promotion, customerID, priced, subtotal, now)
+		if err != nil {
+			return nil, err
+		}
+		if reason == "" {
+			eligible = append(eligible, promotion)
+		}
+	}
+
+	var applied []*models.Promotion
+	switch {
+	case len(coupons) == 1 && !coupons[0].Stackable:
+		applied = coupons
+	case len(coupons) > 0:
+		// Stackable coupons combine with the stackable automatic promotions
+		applied = append(coupons, stackable(eligible)...)
+	default:
+		applied = bestAutomatic(eligible, priced, subtotal)
+	}
+
+	return buildQuote(applied, priced, subtotal), nil
+}
+
+// Redeem records the promotions applied to a paid checkout together with the
+// events describing it. It fails with errPromotionLimitReached, recording
+// nothing, if a usage limit was reached after the cart was quoted; the checkout
+// must then not be fulfilled.
+func (s *promotionService) Redeem(ctx context.Context, reference string, customerID *uuid.UUID, applied []models.AppliedPromotion, events ...*models.DomainEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "PromotionService.Redeem")
+	defer func() { tracing.End(span, err) }()
+
+	redeemed, err := s.repo.Redeem(ctx, reference, customerID, applied, events...)
+	if err != nil {
+		return err
+	}
+	if !redeemed {
+		return errPromotionLimitReached
+	}
+
+	return nil
+}
+
+// CancelRedemptions gives back the promotions of a refunded or unpaid checkout
+func (s *promotionService) CancelRedemptions(ctx context.Context, reference string) (err error) {
+	ctx, span := tracing.Start(ctx, "PromotionService.CancelRedemptions")
+	defer func() { tracing.End(span, err) }()
+
+	if reference == "" {
+		return nil
+	}
+	return s.repo.CancelRedemptions(ctx, reference)
+}
+
+// priceLines resolves each line's product at its current price, merging lines for the same product
+func (s *promotionService) priceLines(ctx context.Context, lines []CartLine) ([]pricedLine, float64, error) {
+	if len(lines) == 0 {
+		return nil, 0, errors.New("cart is empty")
+	}
+
+	var priced []pricedLine
+	index := map[uuid.UUID]int{}
+	paths := map[uuid.UUID][]models.Category{}
+	var subtotal float64
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, 0, errors.New("quantity must be greater than zero")
+		}
+
+		product, err := findProduct(ctx, s.productRepo, line.ProductRef)
+		if err != nil {
+			return nil, 0, err
+		}
+		if product == nil || product.Status != models.ProductStatusActive {
+			return nil, 0, fmt.Errorf("product %s not found", line.ProductRef)
+		}
+
+		total := product.Price * float64(line.Quantity)
+		subtotal += total
+		if i, ok := index[product.ID]; ok {
+			priced[i].quantity += line.Quantity
+			priced[i].total += total
+			continue
+		}
+
+		var path []models.Category
+		if product.CategoryID != nil {
+			var ok bool
+			if path, ok = paths[*product.CategoryID]; !ok {
+				if path, err = s.categoryRepo.GetPath(ctx, product.CategoryID.String()); err != nil {
+					return nil, 0, err
+				}
+				paths[*product.CategoryID] = path
+			}
+		}
+
+		index[product.ID] = len(priced)
+		priced = append(priced, pricedLine{product: product, path: path, quantity: line.Quantity, total: total})
+	}
+
+	return priced, roundMoney(subtotal), nil
+}
+
+// ineligibleReason explains why the promotion cannot be used on the cart, or returns an empty string if it can
+func (s *promotionService) ineligibleReason(ctx context.Context, p *models.Promotion, customerID *uuid.UUID, lines []pricedLine, subtotal float64, now time.Time) (string, error) {
+	switch {
+	case !p.IsActive:
+		return "is not active", nil
+	case p.StartsAt != nil && now.Before(*p.StartsAt):
+		return "is not valid yet", nil
+	case p.EndsAt != nil && !now.Before(*p.EndsAt):
+		return "has expired", nil
+	case p.UsageLimit != nil && p.UsageCount >= *p.UsageLimit:
+		return "has reached its usage limit", nil
+	case p.MinSubtotal != nil && subtotal < *p.MinSubtotal:
+		return fmt.Sprintf("requires a minimum order of %.2f", *p.MinSubtotal), nil
+	}
+
+	if p.PerCustomerLimit != nil {
+		if customerID == nil {
+			return "requires you to sign in", nil
+		}
+
+		used, err := s.repo.CountCustomerRedemptions(ctx, p.ID, *customerID)
+		if err != nil {
+			return "", err
+		}
+		if used >= *p.PerCustomerLimit {
+			return "has already been used", nil
+		}
+	}
+
+	if discount, freeShipping := evaluatePromotion(p, lines); discount <= 0 && !freeShipping {
+		return "does not apply to any items in your cart", nil
+	}
+
+	return "", nil
+}
+
+// evaluatePromotion returns the discount the promotion gives on the cart and whether it gives free shipping
+func evaluatePromotion(p *models.Promotion, lines []pricedLine) (float64, bool) {
+	var eligible []pricedLine
+	var eligibleTotal float64
+	for _, line := range lines {
+		if promotionApplies(p, line) {
+			eligible = append(eligible, line)
+			eligibleTotal += line.total
+		}
+	}
+	if len(eligible) == 0 {
+		return 0, false
+	}
+
+	var discount float64
+	switch p.Type {
+	case models.PromotionPercentage:
+		discount = eligibleTotal * p.Value / 100
+	case models.PromotionFixedAmount:
+		discount = p.Value
+	case models.PromotionFreeShipping:
+		return 0, true
+	case models.PromotionBuyXGetY:
+		discount = buyXGetYDiscount(p, eligible)
+	}
+
+	if p.MaxDiscount != nil {
+		discount = math.Min(discount, *p.MaxDiscount)
+	}
+	return roundMoney(math.Min(discount, eligibleTotal)), false
+}
+
+// promotionApplies reports whether the promotion's vendor, product and category scope covers the line.
+// A promotion limited to both products and categories applies to items matching either.
+func promotionApplies(p *models.Promotion, line pricedLine) bool {
+	if p.VendorID != nil && *p.VendorID != line.product.VendorID {
+		return false
+	}
+	if len(p.ProductIDs) == 0 && len(p.CategoryIDs) == 0 {
+		return true
+	}
+
+	for _, id := range p.ProductIDs {
+		if id == int64(line.product.NumericID) {
+			return true
+		}
+	}
+	for _, id := range p.CategoryIDs {
+		for _, category := range line.path {
+			if category.ID == id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// buyXGetYDiscount discounts the cheapest GetQuantity units of every BuyQuantity+GetQuantity eligible units
+func buyXGetYDiscount(p *models.Promotion, lines []pricedLine) float64 {
+	if p.BuyQuantity == nil || p.GetQuantity == nil {
+		return 0
+	}
+
+	var prices []float64
+	for _, line := range lines {
+		for i := 0; i < line.quantity; i++ {
+			prices = append(prices, line.product.Price)
+		}
+	}
+	sort.Float64s(prices)
+
+	free := len(prices) / (*p.BuyQuantity + *p.GetQuantity) * *p.GetQuantity
+	var discount float64
+	for _, price := range prices[:free] {
+		discount += price * p.Value / 100
+	}
+	return discount
+}
+
+func stackable(promotions []*models.Promotion) []*models.Promotion {
+	var result []*models.Promotion
+	for _, p := range promotions {
+		if p.Stackable {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// bestAutomatic picks the automatic promotions giving the biggest discount:
+// either all the stackable ones together or a single non-stackable one
+func bestAutomatic(promotions []*models.Promotion, lines []pricedLine, subtotal float64) []*models.Promotion {
+	best := stackable(promotions)
+	bestQuote := buildQuote(best, lines, subtotal)
+	for _, p := range promotions {
+		if p.Stackable {
+			continue
+		}
+
+		quote := buildQuote([]*models.Promotion{p}, lines, subtotal)
+		if quote.Discount > bestQuote.Discount ||
+			(quote.Discount == bestQuote.Discount && quote.FreeShipping && !bestQuote.FreeShipping) {
+			best, bestQuote = []*models.Promotion{p}, quote
+		}
+	}
+	return best
+}
+
+// buildQuote applies the promotions in order, never discounting more than the subtotal
+func buildQuote(promotions []*models.Promotion, lines []pricedLine, subtotal float64) *models.PromotionQuote {
+	quote := &models.PromotionQuote{
+		Subtotal: subtotal,
+		Applied:  []models.AppliedPromotion{},
+	}
+
+	remaining := subtotal
+	for _, p := range promotions {
+		discount, freeShipping := evaluatePromotion(p, lines)
+		discount = roundMoney(math.Min(discount, remaining))
+		remaining -= discount
+
+		quote.Discount += discount
+		quote.FreeShipping = quote.FreeShipping || freeShipping
+		quote.Applied = append(quote.Applied, models.AppliedPromotion{
+			PromotionID:  p.ID,
+			Code:         p.Code,
+			Name:         p.Name,
+			Type:         p.Type,
+			Discount:     discount,
+			FreeShipping: freeShipping,
+		})
+	}
+
+	quote.Discount = roundMoney(quote.Discount)
+	quote.Total = roundMoney(subtotal - quote.Discount)
+	return quote
+}
+
+// ownPromotion loads a promotion, treating other vendors' promotions as not found
+func (s *promotionService) ownPromotion(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (*models.Promotion, error) {
+	promotion, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if promotion == nil || (vendorID != nil && (promotion.VendorID == nil || *promotion.VendorID != *vendorID)) {
+		return nil, errors.New("promotion not found")
+	}
+
+	return promotion, nil
+}
+
+func (s *promotionService) validatePromotion(ctx context.Context, p *models.Promotion, id uuid.UUID) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	p.Description = trimOptional(p.Description)
+
+	if p.Code = trimOptional(p.Code); p.Code != nil {
+		code := strings.ToUpper(*p.Code)
+		if len(code) > maxPromotionCodeLength || strings.ContainsAny(code, " \t\n") {
+			return errors.New("code must be at most 50 characters without spaces")
+		}
+		p.Code = &code
+
+		existing, err := s.repo.GetByCode(ctx, code)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.ID != id {
+			return errors.New("code already in use")
+		}
+	}
+
+	switch p.Type {
+	case models.PromotionPercentage:
+		if p.Value <= 0 || p.Value > 100 {
+			return errors.New("percentage must be greater than 0 and at most 100")
+		}
+	case models.PromotionFixedAmount:
+		if p.Value <= 0 {
+			return errors.New("amount must be greater than 0")
+		}
+	case models.PromotionFreeShipping:
+		p.Value = 0
+	case models.PromotionBuyXGetY:
+		if p.BuyQuantity == nil || *p.BuyQuantity <= 0 || p.GetQuantity == nil || *p.GetQuantity <= 0 {
+			return errors.New("buy_quantity and get_quantity are required for buy_x_get_y")
+		}
+		if p.Value == 0 {
+			p.Value = 100
+		}
+		if p.Value < 0 || p.Value > 100 {
+			return errors.New("percentage must be greater than 0 and at most 100")
+		}
+	default:
+		return errors.New("type must be percentage, fixed_amount, free_shipping or buy_x_get_y")
+	}
+	if p.Type != models.PromotionBuyXGetY {
+		p.BuyQuantity, p.GetQuantity = nil, nil
+	}
+
+	if p.MaxDiscount != nil && *p.MaxDiscount <= 0 {
+		return errors.New("max_discount must be greater than 0")
+	}
+	if p.MinSubtotal != nil && *p.MinSubtotal < 0 {
+		return errors.New("min_subtotal cannot be negative")
+	}
+	if p.UsageLimit != nil && *p.UsageLimit <= 0 {
+		return errors.New("usage_limit must be greater than 0")
+	}
+	if p.PerCustomerLimit != nil && *p.PerCustomerLimit <= 0 {
+		return errors.New("per_customer_limit must be greater than 0")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+
+	if p.ProductIDs == nil {
+		p.ProductIDs = []int64{}
+	}
+	if p.CategoryIDs == nil {
+		p.CategoryIDs = []uuid.UUID{}
+	}
+	for _, categoryID := range p.CategoryIDs {
+		category, err := s.categoryRepo.FindByID(ctx, categoryID.String())
+		if err != nil {
+			return err
+		}
+		if category == nil {
+			return errors.New("category not found")
+		}
+	}
+
+	return nil
+}
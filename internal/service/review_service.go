@@ -0,0 +1,625 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxReviewTitleLength   = 255
+	maxReviewCommentLength = 5000
+	maxReviewReplyLength   = 2000
+	maxReportReasonLength  = 1000
+)
+
+// linkPattern matches URLs and bare domains with common top-level domains
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://|\bwww\.|\b[a-z0-9-]+\.(com|net|org|info|biz|io|co|se|ru|xyz)\b`)
+
+type ReviewService interface {
+	CreateReview(ctx context.Context, customerID uuid.UUID, productRef string, review *models.Review) error
+	UpdateReview(ctx context.Context, customerID, id uuid.UUID, update *models.Review) (*models.Review, error)
+	DeleteReview(ctx context.Context, customerID, id uuid.UUID) error
+	GetProductReviews(ctx context.Context, productRef string, sort string, page, limit int) (*models.PaginatedResponse, error)
+	VoteHelpful(ctx context.Context, userID, id uuid.UUID) (*models.Review, error)
+	RemoveHelpfulVote(ctx context.Context, userID, id uuid.UUID) (*models.Review, error)
+	ReportReview(ctx context.Context, userID, id uuid.UUID, reason string) error
+	SetReply(ctx context.Context, vendorID, id uuid.UUID, body string) (*models.Review, error)
+	DeleteReply(ctx context.Context, vendorID, id uuid.UUID) error
+	GetModerationQueue(ctx context.Context, status string, page, limit int) (*models.PaginatedResponse, error)
+	GetReviewModeration(ctx context.Context, id uuid.UUID) (*models.ReviewModeration, error)
+	Moderate(ctx context.Context, id uuid.UUID, status models.ReviewStatus, reason *string, actorID *uuid.UUID) (*models.Review, error)
+	RemoveReply(ctx context.Context, id uuid.UUID, reason string, actorID *uuid.UUID) (*models.Review, error)
+}
+
+type reviewService struct {
+	repo            repository.ReviewRepository
+	productRepo     repository.ProductRepository
+	filter          contentFilter
+	reportThreshold int
+}
+
+func NewReviewService(repo repository.ReviewRepository, productRepo repository.ProductRepository, cfg config.ReviewConfig) ReviewService {
+	return &reviewService{
+		repo:            repo,
+		productRepo:     productRepo,
+		filter:          newContentFilter(cfg),
+		reportThreshold: cfg.ReportThreshold,
+	}
+}
+
+// CreateReview adds the customer's review of a product. Customers can review
+// each product once; the review is marked verified if they have received it.
+// Reviews caught by the content filter are held as pending for moderation.
+func (s *reviewService) CreateReview(ctx context.Context, customerID uuid.UUID, productRef string, review *models.Review) (err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.CreateReview")
+	defer func() { tracing.End(span, err) }()
+
+	if err := validateReview(review); err != nil {
+		return err
+	}
+
+	product, err := findProduct(ctx, s.productRepo, productRef)
+	if err != nil {
+		return err
+	}
+	if product == nil || product.Status != models.ProductStatusActive {
+		return errors.New("product not found")
+	}
+
+	existing, err := s.repo.GetByProductAndCustomer(ctx, product.ID, customerID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.New("product already reviewed")
+	}
+
+	verified, err := s.repo.HasDeliveredOrder(ctx, customerID, product.ID)
+	if err != nil {
+		return err
+	}
+
+	review.ID = uuid.New()
+	review.ProductID = product.ID
+	review.CustomerID = customerID
+	review.IsVerified = verified
+	review.Status = models.ReviewStatusPublished
+	review.ModerationReason = nil
+	held := s.holdIfFlagged(review)
+
+	return s.repo.Create(ctx, review, held)
+}
+
+// UpdateReview edits the customer's own review and rechecks the verified-purchase
+// flag. The edit is run through the content filter again, and editing a rejected
+// review resubmits it for moderation.
+func (s *reviewService) UpdateReview(ctx context.Context, customerID, id uuid.UUID, update *models.Review) (_ *models.Review, err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.UpdateReview")
+	defer func() { tracing.End(span, err) }()
+
+	if err := validateReview(update); err != nil {
+		return nil, err
+	}
+
+	review, err := s.ownReview(ctx, customerID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	verified, err := s.repo.HasDeliveredOrder(ctx, customerID, review.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	review.Rating = update.Rating
+	review.Title = update.Title
+	review.Comment = update.Comment
+	review.IsVerified = verified
+
+	fromStatus := review.Status
+	var entry *models.ReviewModerationEntry
+	if review.Status == models.ReviewStatusRejected {
+		entry = &models.ReviewModerationEntry{
+			ReviewID:   review.ID,
+			Action:     models.ReviewActionResubmit,
+			FromStatus: review.Status,
+			ToStatus:   models.ReviewStatusPending,
+			ActorID:    &customerID,
+		}
+		review.Status = models.ReviewStatusPending
+		review.ModerationReason = nil
+	}
+	if held := s.holdIfFlagged(review); held != nil {
+		held.FromStatus = fromStatus
+		entry = held
+	}
+
+	if err := s.repo.Update(ctx, review, entry); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (s *reviewService) DeleteReview(ctx context.Context, customerID, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.DeleteReview")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.ownReview(ctx, customerID, id); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(ctx, id)
+}
+
+// GetProductReviews pages through a product's reviews, newest first unless another sort is given
+func (s *reviewService) GetProductReviews(ctx context.Context, productRef string, sort string, page, limit int) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.GetProductReviews")
+	defer func() { tracing.End(span, err) }()
+
+	switch sort {
+	case "", "newest", "helpful", "rating", "rating_asc":
+	default:
+		return nil, errors.New("invalid sort")
+	}
+
+	product, err := findProduct(ctx, s.productRepo, productRef)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New("product not found")
+	}
+
+	// Set default pagination
+	if limit <= 0 {
+		limit = 10
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	reviews, total, err := s.repo.GetByProduct(ctx, product.ID, sort, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Moderation details are for admins only
+	for _, review := range reviews {
+		review.ModerationReason = nil
+		review.ReportCount = 0
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &models.PaginatedResponse{
+		Data: reviews,
+		Pagination: models.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// VoteHelpful marks a published review as helpful. Voting again has no effect.
+func (s *reviewService) VoteHelpful(ctx context.Context, userID, id uuid.UUID) (_ *models.Review, err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.VoteHelpful")
+	defer func() { tracing.End(span, err) }()
+
+	review, err := s.othersPublishedReview(ctx, userID, id, "cannot vote on your own review")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AddHelpfulVote(ctx, review.ID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// RemoveHelpfulVote withdraws the user's helpful vote
+func (s *reviewService) RemoveHelpfulVote(ctx context.Context, userID, id uuid.UUID) (_ *models.Review, err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.RemoveHelpfulVote")
+	defer func() { tracing.End(span, err) }()
+
+	review, err := s.othersPublishedReview(ctx, userID, id, "cannot vote on your own review")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RemoveHelpfulVote(ctx, review.ID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// ReportReview reports a published review as abusive. Once it has enough
+// reports it is held for moderation.
+func (s *reviewService) ReportReview(ctx context.Context, userID, id uuid.UUID, reason string) (err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.ReportReview")
+	defer func() { tracing.End(span, err) }()
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return errors.New("reason is required")
+	}
+	if len([]rune(reason)) > maxReportReasonLength {
+		return errors.New("reason is too long")
+	}
+
+	review, err := s.othersPublishedReview(ctx, userID, id, "cannot report your own review")
+	if err != nil {
+		return err
+	}
+
+	created, err := s.repo.CreateReport(ctx, &models.ReviewReport{
+		ReviewID:   review.ID,
+		ReporterID: userID,
+		Reason:     reason,
+	}, s.reportThreshold)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return errors.New("review already reported")
+	}
+
+	return nil
+}
+
+// SetReply adds or replaces the vendor's single public reply to a published review of one of its products
+func (s *reviewService) SetReply(ctx context.Context, vendorID, id uuid.UUID, body string) (_ *models.Review, err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.SetReply")
+	defer func() { tracing.End(span, err) }()
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errors.New("reply is required")
+	}
+	if len([]rune(body)) > maxReviewReplyLength {
+		return nil, errors.New("reply is too long")
+	}
+	if reason := s.filter.check(&body); reason != "" {
+		return nil, errors.New("reply " + reason)
+	}
+
+	review, err := s.vendorReview(ctx, vendorID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetReply(ctx, review.ID, &body, nil); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *reviewService) DeleteReply(ctx context.Context, vendorID, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.DeleteReply")
+	defer func() { tracing.End(span, err) }()
+
+	review, err := s.vendorReview(ctx, vendorID, id)
+	if err != nil {
+		return err
+	}
+	if review.Reply == nil {
+		return errors.New("reply not found")
+	}
+
+	return s.repo.SetReply(ctx, review.ID, nil, nil)
+}
+
+// GetModerationQueue pages through reviews with the given status, pending by default
+func (s *reviewService) GetModerationQueue(ctx context.Context, status string, page, limit int) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.GetModerationQueue")
+	defer func() { tracing.End(span, err) }()
+
+	switch models.ReviewStatus(status) {
+	case "":
+		status = string(models.ReviewStatusPending)
+	case models.ReviewStatusPending, models.ReviewStatusPublished, models.ReviewStatusRejected:
+	default:
+		if status != "all" {
+			return nil, errors.New("invalid review status")
+		}
+		status = ""
+	}
+
+	// Set default pagination
+	if limit <= 0 {
+		limit = 20
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	reviews, total, err := s.repo.GetByStatus(ctx, status, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &models.PaginatedResponse{
+		Data: reviews,
+		Pagination: models.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// GetReviewModeration returns a review with its reports and moderation history
+func (s *reviewService) GetReviewModeration(ctx context.Context, id uuid.UUID) (_ *models.ReviewModeration, err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.GetReviewModeration")
+	defer func() { tracing.End(span, err) }()
+
+	review, err := s.getReview(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	reports, err := s.repo.GetReports(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := s.repo.GetModerationLog(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ReviewModeration{
+		Review:  review,
+		Reports: reports,
+		History: history,
+	}, nil
+}
+
+// Moderate publishes or rejects a review. Rejections require a reason, which is shown to the customer.
+func (s *reviewService) Moderate(ctx context.Context, id uuid.UUID, status models.ReviewStatus, reason *string, actorID *uuid.UUID) (_ *models.Review, err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.Moderate")
+	defer func() { tracing.End(span, err) }()
+
+	var action string
+	switch status {
+	case models.ReviewStatusPublished:
+		action = models.ReviewActionPublish
+	case models.ReviewStatusRejected:
+		action = models.ReviewActionReject
+	default:
+		return nil, errors.New("status must be published or rejected")
+	}
+
+	reason = trimOptional(reason)
+	if status == models.ReviewStatusRejected && reason == nil {
+		return nil, errors.New("reason is required")
+	}
+
+	review, err := s.getReview(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if review.Status == status {
+		return nil, fmt.Errorf("review is already %s", status)
+	}
+
+	err = s.repo.UpdateStatus(ctx, &models.ReviewModerationEntry{
+		ReviewID: id,
+		Action:   action,
+		ToStatus: status,
+		Reason:   reason,
+		ActorID:  actorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// RemoveReply removes an abusive vendor reply and records why
+func (s *reviewService) RemoveReply(ctx context.Context, id uuid.UUID, reason string, actorID *uuid.UUID) (_ *models.Review, err error) {
+	ctx, span := tracing.Start(ctx, "ReviewService.RemoveReply")
+	defer func() { tracing.End(span, err) }()
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	review, err := s.getReview(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if review.Reply == nil {
+		return nil, errors.New("reply not found")
+	}
+
+	err = s.repo.SetReply(ctx, id, nil, &models.ReviewModerationEntry{
+		ReviewID:   id,
+		Action:     models.ReviewActionRemoveReply,
+		FromStatus: review.Status,
+		ToStatus:   review.Status,
+		Reason:     &reason,
+		ActorID:    actorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// holdIfFlagged marks the review pending if the content filter flags it,
+// returning the audit entry to record with the review
+func (s *reviewService) holdIfFlagged(review *models.Review) *models.ReviewModerationEntry {
+	reason := s.filter.check(review.Title, review.Comment)
+	if reason == "" {
+		return nil
+	}
+
+	entry := &models.ReviewModerationEntry{
+		ReviewID:   review.ID,
+		Action:     models.ReviewActionAutoHold,
+		FromStatus: review.Status,
+		ToStatus:   models.ReviewStatusPending,
+		Reason:     &reason,
+	}
+	review.Status = models.ReviewStatusPending
+	review.ModerationReason = &reason
+	return entry
+}
+
+func (s *reviewService) getReview(ctx context.Context, id uuid.UUID) (*models.Review, error) {
+	review, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if review == nil {
+		return nil, errors.New("review not found")
+	}
+
+	return review, nil
+}
+
+// othersPublishedReview loads a published review written by someone other than the user
+func (s *reviewService) othersPublishedReview(ctx context.Context, userID, id uuid.UUID, ownMessage string) (*models.Review, error) {
+	review, err := s.getReview(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if review.Status != models.ReviewStatusPublished {
+		return nil, errors.New("review not found")
+	}
+	if review.CustomerID == userID {
+		return nil, errors.New(ownMessage)
+	}
+
+	return review, nil
+}
+
+// vendorReview loads a published review of one of the vendor's products
+func (s *reviewService) vendorReview(ctx context.Context, vendorID, id uuid.UUID) (*models.Review, error) {
+	review, err := s.getReview(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if review.Status != models.ReviewStatusPublished {
+		return nil, errors.New("review not found")
+	}
+
+	product, err := s.productRepo.GetByID(ctx, review.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil || product.VendorID != vendorID {
+		return nil, errors.New("review not found")
+	}
+
+	return review, nil
+}
+
+// ownReview loads a review, treating other customers' reviews as not found
+func (s *reviewService) ownReview(ctx context.Context, customerID, id uuid.UUID) (*models.Review, error) {
+	review, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if review == nil || review.CustomerID != customerID {
+		return nil, errors.New("review not found")
+	}
+
+	return review, nil
+}
+
+func validateReview(review *models.Review) error {
+	if review.Rating < 1 || review.Rating > 5 {
+		return errors.New("rating must be between 1 and 5")
+	}
+
+	review.Title = trimOptional(review.Title)
+	review.Comment = trimOptional(review.Comment)
+	if review.Title != nil && len([]rune(*review.Title)) > maxReviewTitleLength {
+		return errors.New("review title is too long")
+	}
+	if review.Comment != nil && len([]rune(*review.Comment)) > maxReviewCommentLength {
+		return errors.New("review comment is too long")
+	}
+
+	return nil
+}
+
+// findProduct resolves a product by numeric ID or UUID, returning nil if neither matches
+func findProduct(ctx context.Context, repo repository.ProductRepository, ref string) (*models.Product, error) {
+	ref = strings.TrimSpace(ref)
+	if numericID, err := strconv.Atoi(ref); err == nil && numericID > 0 {
+		return repo.GetByNumericID(ctx, numericID)
+	}
+
+	id, err := uuid.Parse(ref)
+	if err != nil {
+		return nil, nil
+	}
+
+	return repo.GetByID(ctx, id)
+}
+
+// contentFilter flags review text that should be checked by a moderator
+type contentFilter struct {
+	blockedWords map[string]bool
+	holdLinks    bool
+}
+
+func newContentFilter(cfg config.ReviewConfig) contentFilter {
+	blocked := make(map[string]bool, len(cfg.BlockedWords))
+	for _, word := range cfg.BlockedWords {
+		blocked[strings.ToLower(word)] = true
+	}
+
+	return contentFilter{blockedWords: blocked, holdLinks: cfg.HoldLinks}
+}
+
+// check returns why the text was flagged, or an empty string if it passes.
+// Blocked words are matched as whole words, ignoring case.
+func (f contentFilter) check(texts ...*string) string {
+	for _, t := range texts {
+		if t == nil {
+			continue
+		}
+		text := *t
+
+		if f.holdLinks && linkPattern.MatchString(text) {
+			return "contains a link"
+		}
+
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			if f.blockedWords[word] {
+				return "contains blocked language"
+			}
+		}
+	}
+
+	return ""
+}
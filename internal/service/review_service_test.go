@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// auditedReviewRepository keeps reviews and the moderation entries written with them
+type auditedReviewRepository struct {
+	repository.ReviewRepository
+	reviews   map[uuid.UUID]*models.Review
+	entries   []*models.ReviewModerationEntry
+	delivered bool // Every customer has received every product
+}
+
+func (r *auditedReviewRepository) record(entry *models.ReviewModerationEntry) {
+	if entry != nil {
+		r.entries = append(r.entries, entry)
+	}
+}
+
+func (r *auditedReviewRepository) Create(_ context.Context, review *models.Review, entry *models.ReviewModerationEntry) error {
+	r.reviews[review.ID] = review
+	r.record(entry)
+	return nil
+}
+
+func (r *auditedReviewRepository) Update(_ context.Context, review *models.Review, entry *models.ReviewModerationEntry) error {
+	r.reviews[review.ID] = review
+	r.record(entry)
+	return nil
+}
+
+func (r *auditedReviewRepository) SetReply(_ context.Context, reviewID uuid.UUID, body *string, entry *models.ReviewModerationEntry) error {
+	if body == nil {
+		r.reviews[reviewID].Reply = nil
+	} else {
+		r.reviews[reviewID].Reply = &models.ReviewReply{Body: *body}
+	}
+	r.record(entry)
+	return nil
+}
+
+func (r *auditedReviewRepository) GetByID(_ context.Context, id uuid.UUID) (*models.Review, error) {
+	review, ok := r.reviews[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *review
+	return &copied, nil
+}
+
+func (r *auditedReviewRepository) GetByProductAndCustomer(_ context.Context, productID, customerID uuid.UUID) (*models.Review, error) {
+	for _, review := range r.reviews {
+		if review.ProductID == productID && review.CustomerID == customerID {
+			return review, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *auditedReviewRepository) HasDeliveredOrder(context.Context, uuid.UUID, uuid.UUID) (bool, error) {
+	return r.delivered, nil
+}
+
+func (r *auditedReviewRepository) Delete(_ context.Context, id uuid.UUID) error {
+	delete(r.reviews, id)
+	return nil
+}
+
+func (r *auditedReviewRepository) GetByProduct(_ context.Context, productID uuid.UUID, _ string, _, _ int) ([]*models.Review, int, error) {
+	var reviews []*models.Review
+	for _, review := range r.reviews {
+		if review.ProductID == productID {
+			copied := *review
+			reviews = append(reviews, &copied)
+		}
+	}
+	return reviews, len(reviews), nil
+}
+
+func TestAutomaticModerationIsRecordedWithTheReview(t *testing.T) {
+	ctx := context.Background()
+	product := &models.Product{ID: uuid.New(), Status: models.ProductStatusActive}
+	products := &memoryProductRepository{products: map[uuid.UUID]*models.Product{product.ID: product}}
+	repo := &auditedReviewRepository{reviews: make(map[uuid.UUID]*models.Review), delivered: true}
+	reviews := NewReviewService(repo, products, config.ReviewConfig{HoldLinks: true})
+	customerID, adminID := uuid.New(), uuid.New()
+
+	spam := "Cheaper at https://example.com"
+	review := &models.Review{Rating: 5, Comment: &spam}
+	if err := reviews.CreateReview(ctx, customerID, product.ID.String(), review); err != nil {
+		t.Fatal(err)
+	}
+	if review.Status != models.ReviewStatusPending || len(repo.entries) != 1 {
+		t.Fatalf("created %s review with entries %+v", review.Status, repo.entries)
+	}
+	if held := repo.entries[0]; held.Action != models.ReviewActionAutoHold || held.ReviewID != review.ID || held.FromStatus != models.ReviewStatusPublished {
+		t.Errorf("hold entry %+v", held)
+	}
+
+	// A rejected review edited by its author is resubmitted
+	repo.reviews[review.ID].Status = models.ReviewStatusRejected
+	fixed := "Works well"
+	updated, err := reviews.UpdateReview(ctx, customerID, review.ID, &models.Review{Rating: 4, Comment: &fixed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != models.ReviewStatusPending || len(repo.entries) != 2 || repo.entries[1].Action != models.ReviewActionResubmit {
+		t.Fatalf("updated %s review with entries %+v", updated.Status, repo.entries)
+	}
+
+	// An edit without flagged content or a status change records nothing
+	repo.reviews[review.ID].Status = models.ReviewStatusPublished
+	if _, err := reviews.UpdateReview(ctx, customerID, review.ID, &models.Review{Rating: 3, Comment: &fixed}); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.entries) != 2 {
+		t.Errorf("plain edit recorded %+v", repo.entries[2:])
+	}
+
+	repo.reviews[review.ID].Reply = &models.ReviewReply{Body: "Thanks"}
+	if _, err := reviews.RemoveReply(ctx, review.ID, " ", &adminID); err == nil || err.Error() != "reason is required" {
+		t.Errorf("RemoveReply without reason: %v", err)
+	}
+	removed, err := reviews.RemoveReply(ctx, review.ID, "abusive", &adminID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed.Reply != nil || len(repo.entries) != 3 {
+		t.Fatalf("reply %+v, entries %+v", removed.Reply, repo.entries)
+	}
+	if entry := repo.entries[2]; entry.Action != models.ReviewActionRemoveReply || *entry.ActorID != adminID || *entry.Reason != "abusive" {
+		t.Errorf("remove reply entry %+v", entry)
+	}
+}
+
+func TestCustomerReviews(t *testing.T) {
+	ctx := context.Background()
+	product := &models.Product{ID: uuid.New(), Status: models.ProductStatusActive}
+	draft := &models.Product{ID: uuid.New(), Status: models.ProductStatusDraft}
+	products := &memoryProductRepository{products: map[uuid.UUID]*models.Product{product.ID: product, draft.ID: draft}}
+	repo := &auditedReviewRepository{reviews: make(map[uuid.UUID]*models.Review)}
+	reviews := NewReviewService(repo, products, config.ReviewConfig{})
+	customerID, otherID := uuid.New(), uuid.New()
+
+	longTitle := strings.Repeat("a", maxReviewTitleLength+1)
+	blank := "   "
+	creates := []struct {
+		name    string
+		product string
+		review  models.Review
+		want    string
+	}{
+		{"rating too low", product.ID.String(), models.Review{Rating: 0}, "rating must be between 1 and 5"},
+		{"rating too high", product.ID.String(), models.Review{Rating: 6}, "rating must be between 1 and 5"},
+		{"title too long", product.ID.String(), models.Review{Rating: 4, Title: &longTitle}, "review title is too long"},
+		{"unknown product", uuid.NewString(), models.Review{Rating: 4}, "product not found"},
+		{"unparseable product", "shoes", models.Review{Rating: 4}, "product not found"},
+		{"inactive product", draft.ID.String(), models.Review{Rating: 4}, "product not found"},
+	}
+	for _, tt := range creates {
+		t.Run(tt.name, func(t *testing.T) {
+			review := tt.review
+			if err := reviews.CreateReview(ctx, customerID, tt.product, &review); err == nil || err.Error() != tt.want {
+				t.Errorf("CreateReview = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// Without a delivered order the review is published unverified, and blank text is dropped
+	review := &models.Review{Rating: 4, Title: &blank}
+	if err := reviews.CreateReview(ctx, customerID, product.ID.String(), review); err != nil {
+		t.Fatal(err)
+	}
+	if review.IsVerified || review.Status != models.ReviewStatusPublished || review.Title != nil {
+		t.Errorf("created %+v", review)
+	}
+	if err := reviews.CreateReview(ctx, customerID, product.ID.String(), &models.Review{Rating: 5}); err == nil || err.Error() != "product already reviewed" {
+		t.Errorf("second review = %v", err)
+	}
+
+	// Once the order is delivered an edit marks the review verified
+	repo.delivered = true
+	updated, err := reviews.UpdateReview(ctx, customerID, review.ID, &models.Review{Rating: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.IsVerified || updated.Rating != 5 {
+		t.Errorf("updated %+v", updated)
+	}
+
+	if _, err := reviews.UpdateReview(ctx, otherID, review.ID, &models.Review{Rating: 1}); err == nil || err.Error() != "review not found" {
+		t.Errorf("UpdateReview by another customer = %v", err)
+	}
+	if err := reviews.DeleteReview(ctx, otherID, review.ID); err == nil || err.Error() != "review not found" {
+		t.Errorf("DeleteReview by another customer = %v", err)
+	}
+
+	if _, err := reviews.GetProductReviews(ctx, product.ID.String(), "oldest", 1, 10); err == nil || err.Error() != "invalid sort" {
+		t.Errorf("GetProductReviews with unknown sort = %v", err)
+	}
+	repo.reviews[review.ID].ReportCount = 2
+	page, err := reviews.GetProductReviews(ctx, product.ID.String(), "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := page.Data.([]*models.Review)
+	if page.Pagination.Page != 1 || page.Pagination.Limit != 10 || len(listed) != 1 || listed[0].ReportCount != 0 {
+		t.Errorf("listed %+v with %+v", listed, page.Pagination)
+	}
+
+	if err := reviews.DeleteReview(ctx, customerID, review.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.reviews) != 0 {
+		t.Errorf("reviews left after delete: %+v", repo.reviews)
+	}
+}
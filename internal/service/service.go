@@ -1,34 +1,72 @@
 package service
 
 import (
+	"smrtmart-go-postgresql/internal/carrier"
 	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/email"
+	"smrtmart-go-postgresql/internal/eventsink"
+	"smrtmart-go-postgresql/internal/payout"
 	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/search"
 )
 
 type Services struct {
-	User     UserService
-	Vendor   VendorService
-	Product  ProductService
-	Order    OrderService
-	Cart     CartService
-	Category CategoryService
-	Review   ReviewService
-	Payment  PaymentService
-	Auth     AuthService
-	Upload   UploadService
+	User      UserService
+	Vendor    VendorService
+	Product   ProductService
+	Order     OrderService
+	Cart      CartService
+	Category  CategoryService
+	Review    ReviewService
+	Payment   PaymentService
+	Auth      AuthService
+	Upload    UploadService
+	Ledger    LedgerService
+	Wishlist  WishlistService
+	Promotion PromotionService
+	Pricing   PricingService
+	GiftCard  GiftCardService
+	Shipping  ShippingService
+	Shipment  ShipmentService
+	Email     EmailService
+	Job       JobService
+	Event     EventService
+	Webhook   WebhookService
 }
 
 func NewServices(repos *repository.Repositories, cfg *config.Config) *Services {
+	searchIndex := search.New(cfg.Search.Backend, repos.Product)
+	giftCards := NewGiftCardService(repos.GiftCard)
+	promotions := NewPromotionService(repos.Promotion, repos.Product, repos.Category)
+	jobs := NewJobService(repos.Job, cfg.Jobs)
+	emails := NewEmailService(repos.Email, jobs, email.MustNewRenderer(cfg.Email.AppURL), email.New(cfg.Email), cfg.Email)
+	events := NewEventService(repos.Event, jobs, eventsink.New(cfg.Events))
+
+	// Event subscribers register their jobs, so they must be set up before workers start
+	subscribeNotifications(events, emails, repos.Shipment, cfg.Email.AppURL)
+	webhooks := NewWebhookService(repos.Webhook, repos.Event, events, jobs, cfg.Webhooks)
+
 	return &Services{
-		User:     NewUserService(repos.User),
-		Vendor:   NewVendorService(repos.Vendor),
-		Product:  NewProductService(repos.Product),
-		Order:    NewOrderService(repos.Order, repos.Product),
-		Cart:     NewCartService(repos.Cart, repos.Product),
-		Category: NewCategoryService(repos.Category),
-		Review:   NewReviewService(repos.Review),
-		Payment:  NewPaymentService(cfg.Stripe),
-		Auth:     NewAuthService(repos.User, cfg.JWT),
-		Upload:   NewUploadService(cfg.Upload),
+		User:      NewUserService(repos.User),
+		Vendor:    NewVendorService(repos.Vendor, repos.Product, searchIndex),
+		Product:   NewProductService(repos.Product, repos.Category, repos.Search, repos.Review, repos.Pricing, repos.Event, jobs, searchIndex, cfg.Search, cfg.Events.LowStockThreshold),
+		Order:     NewOrderService(repos.Order, repos.Product),
+		Cart:      NewCartService(repos.Cart, repos.Product),
+		Category:  NewCategoryService(repos.Category),
+		Review:    NewReviewService(repos.Review, repos.Product, cfg.Review),
+		Payment:   NewPaymentService(cfg.Stripe, repos.Product, giftCards, promotions, events),
+		Auth:      NewAuthService(repos.User, emails, cfg.JWT, cfg.Email.AppURL),
+		Upload:    NewUploadService(cfg.Upload),
+		Ledger:    NewLedgerService(repos.Ledger, repos.Vendor, repos.Category, payout.New(cfg.Payout.Backend, cfg.Stripe), cfg.Payout.Currency),
+		Wishlist:  NewWishlistService(repos.Wishlist, repos.Product),
+		Promotion: promotions,
+		Pricing:   NewPricingService(repos.Pricing, repos.Product, searchIndex),
+		GiftCard:  giftCards,
+		Shipping:  NewShippingService(repos.Shipping, repos.Product),
+		Shipment:  NewShipmentService(repos.Shipment, repos.Product, repos.Vendor, carrier.New(cfg.Carrier)),
+		Email:     emails,
+		Job:       jobs,
+		Event:     events,
+		Webhook:   webhooks,
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,486 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/carrier"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+// trackingBatchSize is how many shipments are polled per tracking run
+const trackingBatchSize = 100
+
+var carrierCodePattern = regexp.MustCompile(`^[a-z0-9_-]{2,30}$`)
+
+// ShipmentInput describes a shipment to create. Without items, everything not yet
+// shipped is included.
+type ShipmentInput struct {
+	Carrier        string
+	Service        *string
+	TrackingNumber *string
+	CreateLabel    bool // Book the parcel with the carrier, which assigns the tracking number
+	Items          []models.ShipmentItem
+}
+
+type ShipmentService interface {
+	CreateShipment(ctx context.Context, vendorID *uuid.UUID, orderID uuid.UUID, input ShipmentInput, createdBy *uuid.UUID) (*models.Shipment, error)
+	GetOrderShipments(ctx context.Context, vendorID *uuid.UUID, orderID uuid.UUID) ([]*models.Shipment, error)
+	GetCustomerShipments(ctx context.Context, customerID, orderID uuid.UUID) ([]*models.Shipment, error)
+	GetLabel(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (*models.ShipmentLabel, error)
+	AddEvent(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID, event *models.ShipmentEvent) (*models.Shipment, error)
+	CancelShipment(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) error
+	PollTracking(ctx context.Context, minAge time.Duration) (int, error)
+	RunTrackingPoller(ctx context.Context, interval time.Duration)
+}
+
+type shipmentService struct {
+	repo        repository.ShipmentRepository
+	productRepo repository.ProductRepository
+	vendorRepo  repository.VendorRepository
+	carriers    map[string]carrier.Carrier
+}
+
+func NewShipmentService(repo repository.ShipmentRepository, productRepo repository.ProductRepository, vendorRepo repository.VendorRepository, carriers map[string]carrier.Carrier) ShipmentService {
+	return &shipmentService{
+		repo:        repo,
+		productRepo: productRepo,
+		vendorRepo:  vendorRepo,
+		carriers:    carriers,
+	}
+}
+
+// CreateShipment ships items of an order. vendorID restricts it to that vendor's
+// items; nil is an admin, who may ship items of several vendors together.
+func (s *shipmentService) CreateShipment(ctx context.Context, vendorID *uuid.UUID, orderID uuid.UUID, input ShipmentInput, createdBy *uuid.UUID) (_ *models.Shipment, err error) {
+	ctx, span := tracing.Start(ctx, "ShipmentService.CreateShipment")
+	defer func() { tracing.End(span, err) }()
+
+	order, err := s.ownOrder(ctx, vendorID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	switch order.Status {
+	case models.OrderStatusConfirmed, models.OrderStatusProcessing, models.OrderStatusShipped:
+	default:
+		return nil, errors.New("order cannot be shipped in its current status")
+	}
+
+	items, err := shipmentItems(order, vendorID, input.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	shipment := &models.Shipment{
+		ID:        uuid.New(),
+		OrderID:   orderID,
+		VendorID:  vendorID,
+		Carrier:   strings.ToLower(strings.TrimSpace(input.Carrier)),
+		Service:   trimOptional(input.Service),
+		Status:    models.ShipmentStatusCreated,
+		CreatedBy: createdBy,
+		Items:     items,
+	}
+	if !carrierCodePattern.MatchString(shipment.Carrier) {
+		return nil, errors.New("carrier must be 2-30 lowercase letters, digits, dashes or underscores")
+	}
+	if shipment.VendorID == nil {
+		shipment.VendorID = singleVendor(order, items)
+	}
+
+	var label *models.ShipmentLabel
+	if input.CreateLabel {
+		if trimOptional(input.TrackingNumber) != nil {
+			return nil, errors.New("tracking_number must be empty when creating a label")
+		}
+		booked, err := s.createLabel(ctx, shipment, order)
+		if err != nil {
+			return nil, err
+		}
+		shipment.TrackingNumber = &booked.TrackingNumber
+		if booked.Reference != "" {
+			shipment.LabelReference = &booked.Reference
+		}
+		label = &models.ShipmentLabel{Data: booked.Data, ContentType: booked.ContentType}
+	} else {
+		shipment.TrackingNumber = trimOptional(input.TrackingNumber)
+	}
+
+	event, err := models.NewDomainEvent(models.EventShipmentCreated, "shipment", shipment.ID.String(), models.ShipmentEventPayload{
+		ShipmentID:     shipment.ID,
+		OrderID:        shipment.OrderID,
+		VendorID:       shipment.VendorID,
+		Carrier:        shipment.Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.Create(ctx, shipment, label, event)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		if label != nil {
+			slog.WarnContext(ctx, "Discarding label, its items were shipped concurrently", "carrier", shipment.Carrier, "tracking_number", *shipment.TrackingNumber)
+		}
+		return nil, errors.New("quantity exceeds what is left to ship")
+	}
+
+	return s.repo.GetByID(ctx, shipment.ID)
+}
+
+// GetOrderShipments lists an order's shipments. vendorID limits them to that vendor's; nil is an admin.
+func (s *shipmentService) GetOrderShipments(ctx context.Context, vendorID *uuid.UUID, orderID uuid.UUID) (_ []*models.Shipment, err error) {
+	ctx, span := tracing.Start(ctx, "ShipmentService.GetOrderShipments")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.ownOrder(ctx, vendorID, orderID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByOrder(ctx, orderID, vendorID)
+}
+
+func (s *shipmentService) GetCustomerShipments(ctx context.Context, customerID, orderID uuid.UUID) (_ []*models.Shipment, err error) {
+	ctx, span := tracing.Start(ctx, "ShipmentService.GetCustomerShipments")
+	defer func() { tracing.End(span, err) }()
+
+	order, err := s.repo.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || order.CustomerID != customerID {
+		return nil, errors.New("order not found")
+	}
+
+	return s.repo.GetByOrder(ctx, orderID, nil)
+}
+
+func (s *shipmentService) GetLabel(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (_ *models.ShipmentLabel, err error) {
+	ctx, span := tracing.Start(ctx, "ShipmentService.GetLabel")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.ownShipment(ctx, vendorID, id); err != nil {
+		return nil, err
+	}
+
+	label, err := s.repo.GetLabel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if label == nil {
+		return nil, errors.New("shipment has no label")
+	}
+	return label, nil
+}
+
+// AddEvent records a tracking event by hand, for carriers without an integration
+// or to correct one. A delivered event can complete the order.
+func (s *shipmentService) AddEvent(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID, event *models.ShipmentEvent) (_ *models.Shipment, err error) {
+	ctx, span := tracing.Start(ctx, "ShipmentService.AddEvent")
+	defer func() { tracing.End(span, err) }()
+
+	shipment, err := s.ownShipment(ctx, vendorID, id)
+	if err != nil {
+		return nil, err
+	}
+	if shipment.Status == models.ShipmentStatusCancelled {
+		return nil, errors.New("shipment is cancelled")
+	}
+
+	switch event.Status {
+	case models.ShipmentStatusCreated, models.ShipmentStatusInTransit, models.ShipmentStatusOutForDelivery,
+		models.ShipmentStatusDelivered, models.ShipmentStatusException, models.ShipmentStatusReturned:
+	default:
+		return nil, errors.New("status must be created, in_transit, out_for_delivery, delivered, exception or returned")
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	if event.OccurredAt.After(time.Now().Add(time.Minute)) {
+		return nil, errors.New("occurred_at must not be in the future")
+	}
+	event.OccurredAt = event.OccurredAt.UTC().Truncate(time.Second)
+	event.Description = strings.TrimSpace(event.Description)
+	if event.Description == "" {
+		event.Description = strings.ReplaceAll(string(event.Status), "_", " ")
+	}
+	event.Code = "manual"
+	event.Location = trimOptional(event.Location)
+
+	if _, err := s.repo.AddEvents(ctx, id, []models.ShipmentEvent{*event}, nil); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// CancelShipment cancels a shipment that has not arrived, so its items can be shipped again
+func (s *shipmentService) CancelShipment(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "ShipmentService.CancelShipment")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.ownShipment(ctx, vendorID, id); err != nil {
+		return err
+	}
+
+	cancelled, err := s.repo.Cancel(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return errors.New("shipment can no longer be cancelled")
+	}
+	return nil
+}
+
+// PollTracking fetches tracking events for shipments with an integrated carrier that
+// were not polled within minAge. A shipment whose events cannot be saved is logged
+// and skipped. It returns the number of shipments polled.
+func (s *shipmentService) PollTracking(ctx context.Context, minAge time.Duration) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "ShipmentService.PollTracking")
+	defer func() { tracing.End(span, err) }()
+
+	if len(s.carriers) == 0 {
+		return 0, nil
+	}
+
+	codes := make([]string, 0, len(s.carriers))
+	for code := range s.carriers {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	shipments, err := s.repo.GetTrackable(ctx, codes, time.Now().Add(-minAge), trackingBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	polled := 0
+	for _, shipment := range shipments {
+		now := time.Now()
+		tracked, err := s.carriers[shipment.Carrier].Track(ctx, *shipment.TrackingNumber)
+		if err != nil {
+			// Still mark it polled so one failing shipment does not hold up the others
+			slog.WarnContext(ctx, "Failed to track shipment", "carrier", shipment.Carrier, "shipment_id", shipment.ID, "error", err)
+			tracked = nil
+		}
+
+		events := make([]models.ShipmentEvent, 0, len(tracked))
+		for _, e := range tracked {
+			event := models.ShipmentEvent{
+				Status:      e.Status,
+				Code:        e.Code,
+				Description: e.Description,
+				OccurredAt:  e.OccurredAt.UTC().Truncate(time.Second),
+			}
+			if e.Location != "" {
+				location := e.Location
+				event.Location = &location
+			}
+			events = append(events, event)
+		}
+
+		orderStatus, err := s.repo.AddEvents(ctx, shipment.ID, events, &now)
+		if err != nil {
+			// Left unpolled, so the shipment is tried again on the next run
+			slog.ErrorContext(ctx, "Failed to record tracking events", "shipment_id", shipment.ID, "error", err)
+			continue
+		}
+		polled++
+		if orderStatus == models.OrderStatusDelivered && shipment.Status != models.ShipmentStatusDelivered {
+			slog.InfoContext(ctx, "Order delivered", "order_id", shipment.OrderID)
+		}
+	}
+	return polled, nil
+}
+
+// RunTrackingPoller polls carriers for tracking events every interval until ctx is cancelled
+func (s *shipmentService) RunTrackingPoller(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Half an interval leaves room for the time a run takes
+		if _, err := s.PollTracking(ctx, interval/2); err != nil {
+			slog.ErrorContext(ctx, "Failed to poll shipment tracking", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// createLabel books the shipment with its carrier, sent from the vendor's address
+func (s *shipmentService) createLabel(ctx context.Context, shipment *models.Shipment, order *repository.ShipmentOrder) (*carrier.Label, error) {
+	c, ok := s.carriers[shipment.Carrier]
+	if !ok {
+		return nil, fmt.Errorf("carrier %s does not support labels", shipment.Carrier)
+	}
+	if shipment.VendorID == nil {
+		return nil, errors.New("labels can only be created for the items of one vendor")
+	}
+
+	vendor, err := s.vendorRepo.GetByID(ctx, *shipment.VendorID)
+	if err != nil {
+		return nil, err
+	}
+	if vendor == nil {
+		return nil, errors.New("vendor not found")
+	}
+
+	req := carrier.LabelRequest{
+		ShipmentID: shipment.ID,
+		Sender:     carrier.Party{Name: vendor.BusinessName, Address: vendor.Address},
+		Recipient: carrier.Party{
+			Name:    order.CustomerName,
+			Address: order.ShippingAddress,
+			Email:   order.CustomerEmail,
+		},
+	}
+	if shipment.Service != nil {
+		req.Service = *shipment.Service
+	}
+
+	for _, item := range shipment.Items {
+		product, err := s.productRepo.GetByID(ctx, item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if product == nil {
+			continue
+		}
+		req.Weight += billableWeight(product) * float64(item.Quantity)
+		// Only a single unit is known to ship in its own dimensions
+		if len(shipment.Items) == 1 && item.Quantity == 1 {
+			req.Dimensions = product.Dimensions
+		}
+	}
+	if req.Weight <= 0 {
+		return nil, errors.New("product weights are required to create a label")
+	}
+
+	label, err := c.CreateLabel(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create label: %w", err)
+	}
+	return label, nil
+}
+
+// ownOrder loads an order, treating one without items of the vendor as not found
+func (s *shipmentService) ownOrder(ctx context.Context, vendorID *uuid.UUID, orderID uuid.UUID) (*repository.ShipmentOrder, error) {
+	order, err := s.repo.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("order not found")
+	}
+	if vendorID == nil {
+		return order, nil
+	}
+
+	for _, item := range order.Items {
+		if item.VendorID == *vendorID {
+			return order, nil
+		}
+	}
+	return nil, errors.New("order not found")
+}
+
+// ownShipment loads a shipment, treating another vendor's shipment as not found
+func (s *shipmentService) ownShipment(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (*models.Shipment, error) {
+	shipment, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if shipment == nil || (vendorID != nil && (shipment.VendorID == nil || *shipment.VendorID != *vendorID)) {
+		return nil, errors.New("shipment not found")
+	}
+	return shipment, nil
+}
+
+// shipmentItems checks the requested items against what is left to ship, or picks
+// everything left when none are requested
+func shipmentItems(order *repository.ShipmentOrder, vendorID *uuid.UUID, requested []models.ShipmentItem) ([]models.ShipmentItem, error) {
+	byID := make(map[uuid.UUID]repository.ShippableItem)
+	for _, item := range order.Items {
+		if vendorID == nil || item.VendorID == *vendorID {
+			byID[item.ID] = item
+		}
+	}
+
+	if len(requested) == 0 {
+		var items []models.ShipmentItem
+		for _, item := range order.Items {
+			if _, ok := byID[item.ID]; ok && item.Quantity > item.Shipped {
+				items = append(items, models.ShipmentItem{
+					OrderItemID: item.ID,
+					ProductID:   item.ProductID,
+					Name:        item.Name,
+					Quantity:    item.Quantity - item.Shipped,
+				})
+			}
+		}
+		if len(items) == 0 {
+			return nil, errors.New("order has nothing left to ship")
+		}
+		return items, nil
+	}
+
+	quantities := make(map[uuid.UUID]int)
+	var items []models.ShipmentItem
+	for _, r := range requested {
+		item, ok := byID[r.OrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("order item %s not found", r.OrderItemID)
+		}
+		if r.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than zero")
+		}
+		if _, seen := quantities[item.ID]; !seen {
+			items = append(items, models.ShipmentItem{OrderItemID: item.ID, ProductID: item.ProductID, Name: item.Name})
+		}
+		quantities[item.ID] += r.Quantity
+		if quantities[item.ID] > item.Quantity-item.Shipped {
+			return nil, errors.New("quantity exceeds what is left to ship")
+		}
+	}
+
+	for i := range items {
+		items[i].Quantity = quantities[items[i].OrderItemID]
+	}
+	return items, nil
+}
+
+// singleVendor returns the vendor of the items when they all belong to one
+func singleVendor(order *repository.ShipmentOrder, items []models.ShipmentItem) *uuid.UUID {
+	vendors := make(map[uuid.UUID]uuid.UUID)
+	for _, item := range order.Items {
+		vendors[item.ID] = item.VendorID
+	}
+
+	var vendorID *uuid.UUID
+	for _, item := range items {
+		v := vendors[item.OrderItemID]
+		if vendorID != nil && *vendorID != v {
+			return nil
+		}
+		vendorID = &v
+	}
+	return vendorID
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/carrier"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// trackingShipmentRepository serves trackable shipments and records the events added to them
+type trackingShipmentRepository struct {
+	repository.ShipmentRepository
+	shipments []*models.Shipment
+	events    map[uuid.UUID][]models.ShipmentEvent
+	polled    map[uuid.UUID]bool
+	failFor   uuid.UUID // AddEvents fails for this shipment
+}
+
+func (r *trackingShipmentRepository) GetTrackable(_ context.Context, carriers []string, _ time.Time, limit int) ([]*models.Shipment, error) {
+	var trackable []*models.Shipment
+	for _, shipment := range r.shipments {
+		for _, code := range carriers {
+			if shipment.Carrier == code && !r.polled[shipment.ID] && len(trackable) < limit {
+				trackable = append(trackable, shipment)
+			}
+		}
+	}
+	return trackable, nil
+}
+
+func (r *trackingShipmentRepository) AddEvents(_ context.Context, id uuid.UUID, events []models.ShipmentEvent, polledAt *time.Time) (models.OrderStatus, error) {
+	if id == r.failFor {
+		return "", errors.New("deadlock detected")
+	}
+	r.events[id] = append(r.events[id], events...)
+	r.polled[id] = polledAt != nil
+	for _, e := range events {
+		if e.Status == models.ShipmentStatusDelivered {
+			return models.OrderStatusDelivered, nil
+		}
+	}
+	return models.OrderStatusShipped, nil
+}
+
+func newTrackedShipment(carrierCode, trackingNumber string) *models.Shipment {
+	return &models.Shipment{
+		ID:             uuid.New(),
+		OrderID:        uuid.New(),
+		Carrier:        carrierCode,
+		TrackingNumber: &trackingNumber,
+		Status:         models.ShipmentStatusInTransit,
+	}
+}
+
+func newTrackingRepository(shipments ...*models.Shipment) *trackingShipmentRepository {
+	return &trackingShipmentRepository{
+		shipments: shipments,
+		events:    make(map[uuid.UUID][]models.ShipmentEvent),
+		polled:    make(map[uuid.UUID]bool),
+	}
+}
+
+func TestPollTrackingRecordsCarrierEvents(t *testing.T) {
+	postnord, dhl := carrier.NewFakeCarrier(), carrier.NewFakeCarrier()
+	delivered := newTrackedShipment(carrier.CodePostNord, "PN1")
+	failing := newTrackedShipment(carrier.CodeDHL, "DHL1")
+	manual := newTrackedShipment("bring", "BR1")
+	repo := newTrackingRepository(delivered, failing, manual)
+
+	occurred := time.Date(2026, 3, 2, 14, 30, 15, 500, time.FixedZone("CET", 3600))
+	postnord.SetEvents("PN1", carrier.TrackingEvent{
+		Status:      models.ShipmentStatusInTransit,
+		Code:        "ARRIVED",
+		Description: "Arrived at the terminal",
+		Location:    "Stockholm",
+		OccurredAt:  occurred,
+	})
+	postnord.Deliver("PN1")
+	dhl.Err = errors.New("carrier unavailable")
+
+	shipments := NewShipmentService(repo, nil, nil, map[string]carrier.Carrier{
+		carrier.CodePostNord: postnord,
+		carrier.CodeDHL:      dhl,
+	})
+	polled, err := shipments.PollTracking(context.Background(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polled != 2 {
+		t.Errorf("polled %d shipments, want 2", polled)
+	}
+
+	events := repo.events[delivered.ID]
+	if len(events) != 2 {
+		t.Fatalf("recorded %d events, want 2", len(events))
+	}
+	first := events[0]
+	if first.Code != "ARRIVED" || first.Location == nil || *first.Location != "Stockholm" || !first.OccurredAt.Equal(occurred.Truncate(time.Second)) || first.OccurredAt.Location() != time.UTC {
+		t.Errorf("first event = %+v", first)
+	}
+	if events[1].Status != models.ShipmentStatusDelivered || events[1].Location != nil {
+		t.Errorf("second event = %+v", events[1])
+	}
+
+	// A carrier failure still marks the shipment polled so it does not hold up the others
+	if !repo.polled[failing.ID] || len(repo.events[failing.ID]) != 0 {
+		t.Errorf("failing carrier: polled %v with events %v", repo.polled[failing.ID], repo.events[failing.ID])
+	}
+	if repo.polled[manual.ID] {
+		t.Error("shipment without an integrated carrier was polled")
+	}
+}
+
+func TestPollTrackingContinuesPastFailedSaves(t *testing.T) {
+	fake := carrier.NewFakeCarrier()
+	stuck := newTrackedShipment(carrier.CodePostNord, "PN1")
+	next := newTrackedShipment(carrier.CodePostNord, "PN2")
+	fake.Deliver("PN1")
+	fake.Deliver("PN2")
+	repo := newTrackingRepository(stuck, next)
+	repo.failFor = stuck.ID
+
+	shipments := NewShipmentService(repo, nil, nil, map[string]carrier.Carrier{carrier.CodePostNord: fake})
+	polled, err := shipments.PollTracking(context.Background(), time.Minute)
+	if err != nil {
+		t.Fatalf("a failed save stopped the run: %v", err)
+	}
+	if polled != 1 || len(repo.events[next.ID]) != 1 {
+		t.Errorf("polled %d shipments, recorded %v for the next one", polled, repo.events[next.ID])
+	}
+
+	// The shipment that failed is left unpolled and picked up again
+	repo.failFor = uuid.Nil
+	if polled, err := shipments.PollTracking(context.Background(), time.Minute); err != nil || polled != 1 {
+		t.Fatalf("second run polled %d, err %v", polled, err)
+	}
+	if len(repo.events[stuck.ID]) != 1 {
+		t.Errorf("retried shipment recorded %v", repo.events[stuck.ID])
+	}
+}
@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+// volumetricDivisor converts a parcel's volume in cm³ to its volumetric weight in kg
+const volumetricDivisor = 5000
+
+type ShippingService interface {
+	GetZones(ctx context.Context) ([]*models.ShippingZone, error)
+	GetZone(ctx context.Context, id uuid.UUID) (*models.ShippingZone, error)
+	CreateZone(ctx context.Context, zone *models.ShippingZone) error
+	UpdateZone(ctx context.Context, id uuid.UUID, zone *models.ShippingZone) (*models.ShippingZone, error)
+	DeleteZone(ctx context.Context, id uuid.UUID) error
+	CreateMethod(ctx context.Context, zoneID uuid.UUID, method *models.ShippingMethod) error
+	UpdateMethod(ctx context.Context, id uuid.UUID, method *models.ShippingMethod) (*models.ShippingMethod, error)
+	DeleteMethod(ctx context.Context, id uuid.UUID) error
+	Quote(ctx context.Context, lines []CartLine, country, postalCode string) (*models.ShippingQuote, error)
+	AllowedCountries(ctx context.Context) ([]string, error)
+}
+
+type shippingService struct {
+	repo        repository.ShippingRepository
+	productRepo repository.ProductRepository
+}
+
+func NewShippingService(repo repository.ShippingRepository, productRepo repository.ProductRepository) ShippingService {
+	return &shippingService{
+		repo:        repo,
+		productRepo: productRepo,
+	}
+}
+
+// GetZones returns every zone, including inactive ones, in matching order
+func (s *shippingService) GetZones(ctx context.Context) (_ []*models.ShippingZone, err error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.GetZones")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.GetZones(ctx, false)
+}
+
+func (s *shippingService) GetZone(ctx context.Context, id uuid.UUID) (_ *models.ShippingZone, err error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.GetZone")
+	defer func() { tracing.End(span, err) }()
+
+	zone, err := s.repo.GetZone(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if zone == nil {
+		return nil, errors.New("shipping zone not found")
+	}
+	return zone, nil
+}
+
+func (s *shippingService) CreateZone(ctx context.Context, zone *models.ShippingZone) (err error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.CreateZone")
+	defer func() { tracing.End(span, err) }()
+
+	if err := validateShippingZone(zone); err != nil {
+		return err
+	}
+
+	zone.ID = uuid.Nil
+	if err := s.repo.CreateZone(ctx, zone); err != nil {
+		return err
+	}
+	zone.Methods = []models.ShippingMethod{}
+	return nil
+}
+
+func (s *shippingService) UpdateZone(ctx context.Context, id uuid.UUID, zone *models.ShippingZone) (_ *models.ShippingZone, err error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.UpdateZone")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.GetZone(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := validateShippingZone(zone); err != nil {
+		return nil, err
+	}
+
+	zone.ID = id
+	if err := s.repo.UpdateZone(ctx, zone); err != nil {
+		return nil, err
+	}
+	return s.repo.GetZone(ctx, id)
+}
+
+// DeleteZone removes a zone together with its methods
+func (s *shippingService) DeleteZone(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.DeleteZone")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.GetZone(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteZone(ctx, id)
+}
+
+func (s *shippingService) CreateMethod(ctx context.Context, zoneID uuid.UUID, method *models.ShippingMethod) (err error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.CreateMethod")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.GetZone(ctx, zoneID); err != nil {
+		return err
+	}
+	if err := validateShippingMethod(method); err != nil {
+		return err
+	}
+
+	method.ID = uuid.Nil
+	method.ZoneID = zoneID
+	return s.repo.CreateMethod(ctx, method)
+}
+
+func (s *shippingService) UpdateMethod(ctx context.Context, id uuid.UUID, method *models.ShippingMethod) (_ *models.ShippingMethod, err error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.UpdateMethod")
+	defer func() { tracing.End(span, err) }()
+
+	existing, err := s.repo.GetMethod(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.New("shipping method not found")
+	}
+	if err := validateShippingMethod(method); err != nil {
+		return nil, err
+	}
+
+	method.ID = id
+	if err := s.repo.UpdateMethod(ctx, method); err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+func (s *shippingService) DeleteMethod(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.DeleteMethod")
+	defer func() { tracing.End(span, err) }()
+
+	existing, err := s.repo.GetMethod(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("shipping method not found")
+	}
+	return s.repo.DeleteMethod(ctx, id)
+}
+
+// Quote prices the shipping methods of the first zone covering the destination
+// for the cart, using current product prices, weights and dimensions
+func (s *shippingService) Quote(ctx context.Context, lines []CartLine, country, postalCode string) (_ *models.ShippingQuote, err error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.Quote")
+	defer func() { tracing.End(span, err) }()
+
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if len(country) != 2 {
+		return nil, errors.New("country must be a two-letter ISO code")
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+
+	var subtotal, weight float64
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than zero")
+		}
+
+		product, err := findProduct(ctx, s.productRepo, line.ProductRef)
+		if err != nil {
+			return nil, err
+		}
+		if product == nil || product.Status != models.ProductStatusActive {
+			return nil, fmt.Errorf("product %s not found", line.ProductRef)
+		}
+
+		subtotal += product.Price * float64(line.Quantity)
+		weight += billableWeight(product) * float64(line.Quantity)
+	}
+	subtotal = roundMoney(subtotal)
+	weight = math.Round(weight*1000) / 1000
+
+	zones, err := s.repo.GetZones(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var zone *models.ShippingZone
+	for _, z := range zones {
+		if zoneCovers(z, country, postalCode) {
+			zone = z
+			break
+		}
+	}
+	if zone == nil {
+		return nil, errors.New("shipping is not available to this address")
+	}
+
+	quote := &models.ShippingQuote{
+		Country:    country,
+		PostalCode: strings.TrimSpace(postalCode),
+		ZoneID:     zone.ID,
+		Zone:       zone.Name,
+		Subtotal:   subtotal,
+		Weight:     weight,
+		Options:    []models.ShippingOption{},
+	}
+
+	for _, method := range zone.Methods {
+		if method.MinWeight != nil && weight < *method.MinWeight {
+			continue
+		}
+		if method.MaxWeight != nil && weight > *method.MaxWeight {
+			continue
+		}
+
+		option := models.ShippingOption{
+			MethodID:        method.ID,
+			Name:            method.Name,
+			Description:     method.Description,
+			Rate:            roundMoney(method.BaseRate + method.RatePerKg*math.Ceil(weight)),
+			MinDeliveryDays: method.MinDeliveryDays,
+			MaxDeliveryDays: method.MaxDeliveryDays,
+		}
+		if method.FreeShippingThreshold != nil && subtotal >= *method.FreeShippingThreshold {
+			option.Rate = 0
+			option.FreeShipping = true
+		}
+		quote.Options = append(quote.Options, option)
+	}
+
+	if len(quote.Options) == 0 {
+		return nil, errors.New("no shipping method is available for this cart")
+	}
+	return quote, nil
+}
+
+// AllowedCountries lists the countries covered by an active zone with an active method
+func (s *shippingService) AllowedCountries(ctx context.Context) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "ShippingService.AllowedCountries")
+	defer func() { tracing.End(span, err) }()
+
+	zones, err := s.repo.GetZones(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	countries := []string{}
+	for _, zone := range zones {
+		if len(zone.Methods) == 0 {
+			continue
+		}
+		for _, region := range zone.Regions {
+			if !seen[region.Country] {
+				seen[region.Country] = true
+				countries = append(countries, region.Country)
+			}
+		}
+	}
+
+	sort.Strings(countries)
+	return countries, nil
+}
+
+// billableWeight is the greater of a product's weight and its volumetric weight.
+// Weight is in kg and dimensions in cm; products without either weigh nothing.
+func billableWeight(product *models.Product) float64 {
+	var weight float64
+	if product.Weight != nil {
+		weight = *product.Weight
+	}
+	if d := product.Dimensions; d != nil {
+		if volumetric := d.Length * d.Width * d.Height / volumetricDivisor; volumetric > weight {
+			weight = volumetric
+		}
+	}
+	return weight
+}
+
+func zoneCovers(zone *models.ShippingZone, country, postalCode string) bool {
+	code := normalizePostalCode(postalCode)
+	for _, region := range zone.Regions {
+		if region.Country != country {
+			continue
+		}
+		if region.PostalFrom == nil {
+			return true
+		}
+
+		from := normalizePostalCode(*region.PostalFrom)
+		to := from
+		if region.PostalTo != nil {
+			to = normalizePostalCode(*region.PostalTo)
+		}
+		if code != "" && postalPrefix(code, len(from)) >= from && postalPrefix(code, len(to)) <= to {
+			return true
+		}
+	}
+	return false
+}
+
+func postalPrefix(code string, n int) string {
+	if len(code) > n {
+		return code[:n]
+	}
+	return code
+}
+
+func normalizePostalCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+func validateShippingZone(zone *models.ShippingZone) error {
+	zone.Name = strings.TrimSpace(zone.Name)
+	if zone.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(zone.Regions) == 0 {
+		return errors.New("regions are required")
+	}
+
+	for i := range zone.Regions {
+		region := &zone.Regions[i]
+		region.Country = strings.ToUpper(strings.TrimSpace(region.Country))
+		if len(region.Country) != 2 {
+			return errors.New("country must be a two-letter ISO code")
+		}
+		region.PostalFrom = trimOptional(region.PostalFrom)
+		region.PostalTo = trimOptional(region.PostalTo)
+		if region.PostalTo != nil && region.PostalFrom == nil {
+			return errors.New("postal_to must be used with postal_from")
+		}
+	}
+	return nil
+}
+
+func validateShippingMethod(method *models.ShippingMethod) error {
+	method.Name = strings.TrimSpace(method.Name)
+	method.Description = trimOptional(method.Description)
+	if method.Name == "" {
+		return errors.New("name is required")
+	}
+	if method.BaseRate < 0 || method.RatePerKg < 0 {
+		return errors.New("rates must not be negative")
+	}
+	if method.FreeShippingThreshold != nil && *method.FreeShippingThreshold < 0 {
+		return errors.New("free_shipping_threshold must not be negative")
+	}
+	if method.MinWeight != nil && method.MaxWeight != nil && *method.MinWeight > *method.MaxWeight {
+		return errors.New("min_weight must not exceed max_weight")
+	}
+	if (method.MinDeliveryDays != nil && *method.MinDeliveryDays < 0) || (method.MaxDeliveryDays != nil && *method.MaxDeliveryDays < 0) {
+		return errors.New("delivery days must not be negative")
+	}
+	if method.MinDeliveryDays != nil && method.MaxDeliveryDays != nil && *method.MinDeliveryDays > *method.MaxDeliveryDays {
+		return errors.New("min_delivery_days must not exceed max_delivery_days")
+	}
+
+	method.BaseRate = roundMoney(method.BaseRate)
+	method.RatePerKg = roundMoney(method.RatePerKg)
+	return nil
+}
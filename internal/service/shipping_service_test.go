@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// zonesShippingRepository serves active zones in priority order
+type zonesShippingRepository struct {
+	repository.ShippingRepository
+	zones []*models.ShippingZone
+}
+
+func (r *zonesShippingRepository) GetZones(context.Context, bool) ([]*models.ShippingZone, error) {
+	return r.zones, nil
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+func TestShippingQuote(t *testing.T) {
+	ctx := context.Background()
+	light := &models.Product{ID: uuid.New(), Price: 20, Weight: float64Ptr(0.4), Status: models.ProductStatusActive}
+	// 50 x 40 x 30 cm is 12 kg by volume, more than it weighs
+	bulky := &models.Product{ID: uuid.New(), Price: 60, Weight: float64Ptr(3), Dimensions: &models.Dimensions{Length: 50, Width: 40, Height: 30}, Status: models.ProductStatusActive}
+	draft := &models.Product{ID: uuid.New(), Price: 10, Status: models.ProductStatusDraft}
+	products := &memoryProductRepository{products: map[uuid.UUID]*models.Product{light.ID: light, bulky.ID: bulky, draft.ID: draft}}
+
+	stockholm := &models.ShippingZone{
+		ID:      uuid.New(),
+		Name:    "Stockholm",
+		Regions: models.ShippingRegions{{Country: "SE", PostalFrom: stringPtr("100"), PostalTo: stringPtr("199")}},
+		Methods: []models.ShippingMethod{{Name: "Bike courier", BaseRate: 5, MaxWeight: float64Ptr(5)}},
+	}
+	nordics := &models.ShippingZone{
+		ID:      uuid.New(),
+		Name:    "Nordics",
+		Regions: models.ShippingRegions{{Country: "SE"}, {Country: "NO"}},
+		Methods: []models.ShippingMethod{
+			{Name: "Standard", BaseRate: 4.99, RatePerKg: 1, FreeShippingThreshold: float64Ptr(100)},
+			{Name: "Freight", BaseRate: 30, MinWeight: float64Ptr(10)},
+		},
+	}
+	shipping := NewShippingService(&zonesShippingRepository{zones: []*models.ShippingZone{stockholm, nordics}}, products)
+
+	tests := []struct {
+		name       string
+		lines      []CartLine
+		country    string
+		postalCode string
+		zone       string
+		weight     float64
+		rates      map[string]float64
+	}{
+		{"postal range matched first", []CartLine{{light.ID.String(), 2}}, "se", "114 55", "Stockholm", 0.8, map[string]float64{"Bike courier": 5}},
+		{"outside the postal range", []CartLine{{light.ID.String(), 2}}, "SE", "41101", "Nordics", 0.8, map[string]float64{"Standard": 5.99}},
+		{"no postal code", []CartLine{{light.ID.String(), 1}}, "SE", "", "Nordics", 0.4, map[string]float64{"Standard": 5.99}},
+		{"billed by volume", []CartLine{{bulky.ID.String(), 1}}, "SE", "41101", "Nordics", 12, map[string]float64{"Standard": 16.99, "Freight": 30}},
+		{"free over the threshold", []CartLine{{bulky.ID.String(), 2}}, "NO", "0150", "Nordics", 24, map[string]float64{"Standard": 0, "Freight": 30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := shipping.Quote(ctx, tt.lines, tt.country, tt.postalCode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rates := make(map[string]float64)
+			for _, option := range quote.Options {
+				rates[option.Name] = option.Rate
+				if option.FreeShipping != (option.Rate == 0) {
+					t.Errorf("%s free shipping %v at %v", option.Name, option.FreeShipping, option.Rate)
+				}
+			}
+			if quote.Zone != tt.zone || quote.Weight != tt.weight || !reflect.DeepEqual(rates, tt.rates) {
+				t.Errorf("quoted %s at %v kg with %v, want %s at %v kg with %v", quote.Zone, quote.Weight, rates, tt.zone, tt.weight, tt.rates)
+			}
+		})
+	}
+
+	errs := []struct {
+		name    string
+		lines   []CartLine
+		country string
+		want    string
+	}{
+		{"bad country", []CartLine{{light.ID.String(), 1}}, "Sweden", "country must be a two-letter ISO code"},
+		{"empty cart", nil, "SE", "cart is empty"},
+		{"no quantity", []CartLine{{light.ID.String(), 0}}, "SE", "quantity must be greater than zero"},
+		{"inactive product", []CartLine{{draft.ID.String(), 1}}, "SE", "product " + draft.ID.String() + " not found"},
+		{"no zone", []CartLine{{light.ID.String(), 1}}, "DK", "shipping is not available to this address"},
+	}
+	for _, tt := range errs {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := shipping.Quote(ctx, tt.lines, tt.country, ""); err == nil || err.Error() != tt.want {
+				t.Errorf("Quote = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// The first zone covering the address is used even when none of its methods take the cart
+	if _, err := shipping.Quote(ctx, []CartLine{{bulky.ID.String(), 1}}, "SE", "11455"); err == nil || err.Error() != "no shipping method is available for this cart" {
+		t.Errorf("Quote with no method = %v", err)
+	}
+}
+
+func TestValidateShippingMethod(t *testing.T) {
+	tests := []struct {
+		name   string
+		method models.ShippingMethod
+		want   string
+	}{
+		{"no name", models.ShippingMethod{Name: " "}, "name is required"},
+		{"negative rate", models.ShippingMethod{Name: "Standard", RatePerKg: -1}, "rates must not be negative"},
+		{"negative threshold", models.ShippingMethod{Name: "Standard", FreeShippingThreshold: float64Ptr(-1)}, "free_shipping_threshold must not be negative"},
+		{"weights reversed", models.ShippingMethod{Name: "Standard", MinWeight: float64Ptr(5), MaxWeight: float64Ptr(1)}, "min_weight must not exceed max_weight"},
+		{"delivery days reversed", models.ShippingMethod{Name: "Standard", MinDeliveryDays: intPtr(5), MaxDeliveryDays: intPtr(2)}, "min_delivery_days must not exceed max_delivery_days"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if err := validateShippingMethod(&method); err == nil || err.Error() != tt.want {
+				t.Errorf("validateShippingMethod = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	zone := &models.ShippingZone{Name: "Nordics", Regions: models.ShippingRegions{{Country: "SE", PostalTo: stringPtr("199")}}}
+	if err := validateShippingZone(zone); err == nil || err.Error() != "postal_to must be used with postal_from" {
+		t.Errorf("validateShippingZone = %v", err)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
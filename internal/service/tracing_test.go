@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanCategoryRepository records the span of the context each call gets
+type spanCategoryRepository struct {
+	repository.CategoryRepository
+	spans []trace.SpanContext
+	err   error
+}
+
+func (r *spanCategoryRepository) GetAll(ctx context.Context) ([]models.Category, error) {
+	r.spans = append(r.spans, trace.SpanContextFromContext(ctx))
+	return nil, r.err
+}
+
+func (r *spanCategoryRepository) GetBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	r.spans = append(r.spans, trace.SpanContextFromContext(ctx))
+	return nil, r.err
+}
+
+func newTestTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), "test", 1)
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+	})
+	return exporter
+}
+
+func TestServiceSpansNestUnderCaller(t *testing.T) {
+	exporter := newTestTracer(t)
+	repo := &spanCategoryRepository{}
+	categories := NewCategoryService(repo)
+
+	ctx, request := tracing.Start(context.Background(), "GET /api/v1/categories")
+	if _, err := categories.GetTree(ctx); err != nil {
+		t.Fatal(err)
+	}
+	request.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want the service span and the request span", len(spans))
+	}
+	service := spans[0]
+	if service.Name != "CategoryService.GetTree" {
+		t.Fatalf("service span named %q", service.Name)
+	}
+	if service.Parent.SpanID() != request.SpanContext().SpanID() {
+		t.Error("service span is not a child of the request span")
+	}
+	if len(repo.spans) != 1 || repo.spans[0].SpanID() != service.SpanContext.SpanID() {
+		t.Error("repository was not called with the service span's context")
+	}
+}
+
+func TestServiceSpansRecordErrors(t *testing.T) {
+	exporter := newTestTracer(t)
+	categories := NewCategoryService(&spanCategoryRepository{err: errors.New("connection refused")})
+
+	if _, err := categories.GetByID(context.Background(), "laptops"); err == nil {
+		t.Fatal("expected the repository error")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Status.Code != codes.Error || spans[0].Status.Description != "connection refused" {
+		t.Errorf("span status = %+v", spans[0].Status)
+	}
+	if len(spans[0].Events) == 0 || spans[0].Events[0].Name != "exception" {
+		t.Error("error was not recorded on the span")
+	}
+}
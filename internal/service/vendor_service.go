@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/search"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+type VendorService interface {
+	Apply(ctx context.Context, userID uuid.UUID, application *models.Vendor) error
+	GetProfile(ctx context.Context, userID uuid.UUID) (*models.Vendor, error)
+	UpdateProfile(ctx context.Context, userID uuid.UUID, profile *models.Vendor) (*models.Vendor, error)
+	GetStorefront(ctx context.Context, id uuid.UUID, filters repository.ProductFilters) (*models.VendorStorefront, error)
+	GetVendors(ctx context.Context, status string, page, limit int) (*models.PaginatedResponse, error)
+	GetVendorReview(ctx context.Context, id uuid.UUID) (*models.VendorReview, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status models.VendorStatus, reason string, changedBy *uuid.UUID) (*models.Vendor, error)
+	Verify(ctx context.Context, id uuid.UUID) (*models.Vendor, error)
+	SetPayoutAccount(ctx context.Context, id uuid.UUID, accountID string) (*models.Vendor, error)
+}
+
+type vendorService struct {
+	repo        repository.VendorRepository
+	productRepo repository.ProductRepository
+	searchIndex search.Index
+}
+
+func NewVendorService(repo repository.VendorRepository, productRepo repository.ProductRepository, searchIndex search.Index) VendorService {
+	return &vendorService{
+		repo:        repo,
+		productRepo: productRepo,
+		searchIndex: searchIndex,
+	}
+}
+
+// Apply creates a pending vendor application for the user. Each user can have
+// one vendor; a rejected vendor applies again by replacing its application.
+func (s *vendorService) Apply(ctx context.Context, userID uuid.UUID, application *models.Vendor) (err error) {
+	ctx, span := tracing.Start(ctx, "VendorService.Apply")
+	defer func() { tracing.End(span, err) }()
+
+	if userID == uuid.Nil {
+		return errors.New("invalid user ID")
+	}
+	if err := validateVendorProfile(application); err != nil {
+		return err
+	}
+
+	existing, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Status != models.VendorStatusRejected {
+		return errors.New("vendor application already exists")
+	}
+
+	application.UserID = userID
+	application.Status = models.VendorStatusPending
+	application.VerifiedAt = nil
+
+	if existing != nil {
+		application.ID = existing.ID
+		reapplied, err := s.repo.Reapply(ctx, application)
+		if err != nil {
+			return err
+		}
+		if !reapplied {
+			return errors.New("vendor application already exists")
+		}
+		return nil
+	}
+
+	application.ID = uuid.Nil
+	return s.repo.Create(ctx, application)
+}
+
+func (s *vendorService) GetProfile(ctx context.Context, userID uuid.UUID) (_ *models.Vendor, err error) {
+	ctx, span := tracing.Start(ctx, "VendorService.GetProfile")
+	defer func() { tracing.End(span, err) }()
+
+	vendor, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if vendor == nil {
+		return nil, errors.New("vendor not found")
+	}
+
+	return vendor, nil
+}
+
+// UpdateProfile changes the vendor's business details. Changing a verification
+// document clears the verification and sends an approved vendor back to pending review.
+func (s *vendorService) UpdateProfile(ctx context.Context, userID uuid.UUID, profile *models.Vendor) (_ *models.Vendor, err error) {
+	ctx, span := tracing.Start(ctx, "VendorService.UpdateProfile")
+	defer func() { tracing.End(span, err) }()
+
+	if err := validateVendorProfile(profile); err != nil {
+		return nil, err
+	}
+
+	vendor, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	vendor.BusinessName = profile.BusinessName
+	vendor.BusinessType = profile.BusinessType
+	vendor.Description = profile.Description
+	vendor.Logo = profile.Logo
+	vendor.Website = profile.Website
+	vendor.Address = profile.Address
+	if profile.Documents != nil {
+		vendor.Documents = profile.Documents
+	}
+
+	if err := s.repo.Update(ctx, vendor); err != nil {
+		return nil, err
+	}
+
+	return vendor, nil
+}
+
+// GetStorefront returns an approved vendor with its active products
+func (s *vendorService) GetStorefront(ctx context.Context, id uuid.UUID, filters repository.ProductFilters) (_ *models.VendorStorefront, err error) {
+	ctx, span := tracing.Start(ctx, "VendorService.GetStorefront")
+	defer func() { tracing.End(span, err) }()
+
+	vendor, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if vendor == nil || vendor.Status != models.VendorStatusApproved {
+		return nil, errors.New("vendor not found")
+	}
+
+	// Verification details are private to the vendor and admins
+	vendor.Documents = nil
+	vendor.StatusReason = nil
+	vendor.StripeAccountID = nil
+
+	// Set default pagination
+	if filters.Limit <= 0 {
+		filters.Limit = 20
+	}
+	if filters.Page <= 0 {
+		filters.Page = 1
+	}
+	filters.Status = string(models.ProductStatusActive)
+
+	products, total, err := s.productRepo.GetByVendor(ctx, id, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + filters.Limit - 1) / filters.Limit
+
+	return &models.VendorStorefront{
+		Vendor: vendor,
+		Products: &models.PaginatedResponse{
+			Data: products,
+			Pagination: models.Pagination{
+				Page:       filters.Page,
+				Limit:      filters.Limit,
+				Total:      total,
+				TotalPages: totalPages,
+			},
+		},
+	}, nil
+}
+
+// GetVendors lists vendors for admin review, optionally filtered by status
+func (s *vendorService) GetVendors(ctx context.Context, status string, page, limit int) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "VendorService.GetVendors")
+	defer func() { tracing.End(span, err) }()
+
+	if status != "" && !validVendorStatus(models.VendorStatus(status)) {
+		return nil, errors.New("invalid vendor status")
+	}
+
+	// Set default pagination
+	if limit <= 0 {
+		limit = 20
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	vendors, total, err := s.repo.GetAll(ctx, status, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &models.PaginatedResponse{
+		Data: vendors,
+		Pagination: models.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// GetVendorReview returns a vendor with its verification documents and status history
+func (s *vendorService) GetVendorReview(ctx context.Context, id uuid.UUID) (_ *models.VendorReview, err error) {
+	ctx, span := tracing.Start(ctx, "VendorService.GetVendorReview")
+	defer func() { tracing.End(span, err) }()
+
+	vendor, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if vendor == nil {
+		return nil, errors.New("vendor not found")
+	}
+
+	history, err := s.repo.GetStatusHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.VendorReview{Vendor: vendor, History: history}, nil
+}
+
+// UpdateStatus moves a vendor through review. Every change needs a reason.
+// Only verified vendors can be approved; suspending a vendor takes its
+// products off sale until it is approved again.
+func (s *vendorService) UpdateStatus(ctx context.Context, id uuid.UUID, status models.VendorStatus, reason string, changedBy *uuid.UUID) (_ *models.Vendor, err error) {
+	ctx, span := tracing.Start(ctx, "VendorService.UpdateStatus")
+	defer func() { tracing.End(span, err) }()
+
+	if !validVendorStatus(status) {
+		return nil, errors.New("invalid vendor status")
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	vendor, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if vendor == nil {
+		return nil, errors.New("vendor not found")
+	}
+	if vendor.Status == status {
+		return nil, fmt.Errorf("vendor is already %s", status)
+	}
+	if status == models.VendorStatusApproved && vendor.VerifiedAt == nil {
+		return nil, errors.New("vendor must be verified before approval")
+	}
+
+	changed, err := s.repo.UpdateStatus(ctx, &models.VendorStatusChange{
+		VendorID:  id,
+		ToStatus:  status,
+		Reason:    reason,
+		ChangedBy: changedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.reindexProducts(ctx, changed)
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// Verify marks the vendor's submitted documents as checked
+func (s *vendorService) Verify(ctx context.Context, id uuid.UUID) (_ *models.Vendor, err error) {
+	ctx, span := tracing.Start(ctx, "VendorService.Verify")
+	defer func() { tracing.End(span, err) }()
+
+	vendor, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if vendor == nil {
+		return nil, errors.New("vendor not found")
+	}
+
+	docs := vendor.Documents
+	if docs == nil || docs.OrganizationNumber == nil || docs.BankProofURL == nil {
+		return nil, errors.New("vendor has not submitted verification documents")
+	}
+
+	if err := s.repo.MarkVerified(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// SetPayoutAccount links the vendor to the connected Stripe account that receives
+// its payouts. An empty account ID unlinks it.
+func (s *vendorService) SetPayoutAccount(ctx context.Context, id uuid.UUID, accountID string) (_ *models.Vendor, err error) {
+	ctx, span := tracing.Start(ctx, "VendorService.SetPayoutAccount")
+	defer func() { tracing.End(span, err) }()
+
+	account := trimOptional(&accountID)
+	if account != nil && !strings.HasPrefix(*account, "acct_") {
+		return nil, errors.New("stripe account ID must start with acct_")
+	}
+
+	if err := s.repo.SetStripeAccount(ctx, id, account); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("vendor not found")
+		}
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+// reindexProducts refreshes products whose status changed with the vendor's
+func (s *vendorService) reindexProducts(ctx context.Context, ids []uuid.UUID) {
+	for _, id := range ids {
+		product, err := s.productRepo.GetByID(ctx, id)
+		if err != nil || product == nil {
+			slog.ErrorContext(ctx, "Failed to load product for reindexing", "product_id", id, "error", err)
+			continue
+		}
+		if err := s.searchIndex.Index(product); err != nil {
+			slog.ErrorContext(ctx, "Failed to index product", "product_id", id, "error", err)
+		}
+	}
+}
+
+func validVendorStatus(status models.VendorStatus) bool {
+	switch status {
+	case models.VendorStatusPending, models.VendorStatusApproved, models.VendorStatusRejected, models.VendorStatusSuspended:
+		return true
+	}
+	return false
+}
+
+func validateVendorProfile(vendor *models.Vendor) error {
+	vendor.BusinessName = strings.TrimSpace(vendor.BusinessName)
+	if vendor.BusinessName == "" {
+		return errors.New("business name is required")
+	}
+
+	if vendor.Website != nil && *vendor.Website != "" && !validURL(*vendor.Website) {
+		return errors.New("website must be a valid http or https URL")
+	}
+
+	if docs := vendor.Documents; docs != nil {
+		docs.OrganizationNumber = trimOptional(docs.OrganizationNumber)
+		docs.VATID = trimOptional(docs.VATID)
+		docs.BankProofURL = trimOptional(docs.BankProofURL)
+		if docs.BankProofURL != nil && !validURL(*docs.BankProofURL) {
+			return errors.New("bank proof must be a valid http or https URL")
+		}
+	}
+
+	return nil
+}
+
+func validURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// trimOptional trims an optional string, turning blank values into nil
+func trimOptional(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// applicationVendorRepository holds at most one vendor and records how it was saved
+type applicationVendorRepository struct {
+	repository.VendorRepository
+	vendor    *models.Vendor
+	created   int
+	reapplied int
+	reviewed  bool // Reapply finds the vendor no longer rejected
+}
+
+func (r *applicationVendorRepository) GetByUserID(_ context.Context, userID uuid.UUID) (*models.Vendor, error) {
+	if r.vendor == nil || r.vendor.UserID != userID {
+		return nil, nil
+	}
+	vendor := *r.vendor
+	return &vendor, nil
+}
+
+func (r *applicationVendorRepository) Create(_ context.Context, vendor *models.Vendor) error {
+	vendor.ID = uuid.New()
+	r.vendor = vendor
+	r.created++
+	return nil
+}
+
+func (r *applicationVendorRepository) Reapply(_ context.Context, vendor *models.Vendor) (bool, error) {
+	if r.reviewed || r.vendor.Status != models.VendorStatusRejected {
+		return false, nil
+	}
+	r.vendor = vendor
+	r.reapplied++
+	return true, nil
+}
+
+func TestApplyLetsRejectedVendorsReapply(t *testing.T) {
+	userID := uuid.New()
+	repo := &applicationVendorRepository{}
+	vendors := NewVendorService(repo, nil, nil)
+	ctx := context.Background()
+
+	if err := vendors.Apply(ctx, userID, &models.Vendor{BusinessName: "Acme"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := vendors.Apply(ctx, userID, &models.Vendor{BusinessName: "Acme"}); err == nil || err.Error() != "vendor application already exists" {
+		t.Errorf("second pending application: err = %v", err)
+	}
+
+	id := repo.vendor.ID
+	repo.vendor.Status = models.VendorStatusRejected
+	if err := vendors.Apply(ctx, userID, &models.Vendor{BusinessName: "Acme Trading"}); err != nil {
+		t.Fatal(err)
+	}
+	if repo.created != 1 || repo.reapplied != 1 {
+		t.Fatalf("created %d and reapplied %d applications, want 1 and 1", repo.created, repo.reapplied)
+	}
+	if repo.vendor.ID != id || repo.vendor.Status != models.VendorStatusPending || repo.vendor.BusinessName != "Acme Trading" {
+		t.Errorf("reapplication saved as %+v", repo.vendor)
+	}
+
+	// The vendor was approved between the lookup and the locked update
+	repo.vendor.Status = models.VendorStatusRejected
+	repo.reviewed = true
+	if err := vendors.Apply(ctx, userID, &models.Vendor{BusinessName: "Acme"}); err == nil || err.Error() != "vendor application already exists" {
+		t.Errorf("application reviewed concurrently: err = %v", err)
+	}
+}
+
+func (r *applicationVendorRepository) GetByID(_ context.Context, id uuid.UUID) (*models.Vendor, error) {
+	if r.vendor == nil || r.vendor.ID != id {
+		return nil, nil
+	}
+	vendor := *r.vendor
+	return &vendor, nil
+}
+
+func (r *applicationVendorRepository) UpdateStatus(_ context.Context, change *models.VendorStatusChange) ([]uuid.UUID, error) {
+	r.vendor.Status = change.ToStatus
+	r.vendor.StatusReason = &change.Reason
+	return nil, nil
+}
+
+func (r *applicationVendorRepository) MarkVerified(context.Context, uuid.UUID) error {
+	now := time.Now()
+	r.vendor.VerifiedAt = &now
+	return nil
+}
+
+func TestValidateVendorProfile(t *testing.T) {
+	tests := []struct {
+		name   string
+		vendor models.Vendor
+		want   string
+	}{
+		{"blank business name", models.Vendor{BusinessName: "  "}, "business name is required"},
+		{"website without scheme", models.Vendor{BusinessName: "Acme", Website: stringPtr("acme.example")}, "website must be a valid http or https URL"},
+		{"website with other scheme", models.Vendor{BusinessName: "Acme", Website: stringPtr("ftp://acme.example")}, "website must be a valid http or https URL"},
+		{"bank proof not a URL", models.Vendor{BusinessName: "Acme", Documents: &models.VendorDocuments{BankProofURL: stringPtr("scan.pdf")}}, "bank proof must be a valid http or https URL"},
+		{"empty website", models.Vendor{BusinessName: "Acme", Website: stringPtr("")}, ""},
+		{"valid", models.Vendor{BusinessName: " Acme ", Website: stringPtr("https://acme.example")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vendor := tt.vendor
+			err := validateVendorProfile(&vendor)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("validateVendorProfile = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("validateVendorProfile = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVendorReview(t *testing.T) {
+	ctx := context.Background()
+	vendor := &models.Vendor{ID: uuid.New(), UserID: uuid.New(), BusinessName: "Acme", Status: models.VendorStatusPending}
+	repo := &applicationVendorRepository{vendor: vendor}
+	vendors := NewVendorService(repo, nil, nil)
+
+	statuses := []struct {
+		name   string
+		id     uuid.UUID
+		status models.VendorStatus
+		reason string
+		want   string
+	}{
+		{"unknown status", vendor.ID, "closed", "done", "invalid vendor status"},
+		{"no reason", vendor.ID, models.VendorStatusRejected, " ", "reason is required"},
+		{"unknown vendor", uuid.New(), models.VendorStatusRejected, "spam", "vendor not found"},
+		{"unchanged", vendor.ID, models.VendorStatusPending, "again", "vendor is already pending"},
+		{"approve unverified", vendor.ID, models.VendorStatusApproved, "looks fine", "vendor must be verified before approval"},
+	}
+	for _, tt := range statuses {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := vendors.UpdateStatus(ctx, tt.id, tt.status, tt.reason, nil); err == nil || err.Error() != tt.want {
+				t.Errorf("UpdateStatus = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := vendors.Verify(ctx, vendor.ID); err == nil || err.Error() != "vendor has not submitted verification documents" {
+		t.Errorf("Verify without documents = %v", err)
+	}
+	vendor.Documents = &models.VendorDocuments{OrganizationNumber: stringPtr("556000-0000"), BankProofURL: stringPtr("https://docs.example/bank.pdf")}
+	if _, err := vendors.Verify(ctx, vendor.ID); err != nil {
+		t.Fatal(err)
+	}
+	approved, err := vendors.UpdateStatus(ctx, vendor.ID, models.VendorStatusApproved, "documents checked", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved.Status != models.VendorStatusApproved || *approved.StatusReason != "documents checked" {
+		t.Errorf("approved vendor %+v", approved)
+	}
+
+	if _, err := vendors.SetPayoutAccount(ctx, vendor.ID, "ba_123"); err == nil || err.Error() != "stripe account ID must start with acct_" {
+		t.Errorf("SetPayoutAccount with bank account ID = %v", err)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
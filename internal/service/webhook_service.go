@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/metrics"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+	"smrtmart-go-postgresql/internal/webhook"
+
+	"github.com/google/uuid"
+)
+
+// webhookJobKind is the job kind delivering one event to one endpoint
+const webhookJobKind = "webhook.deliver"
+
+type WebhookService interface {
+	GetEndpoints(ctx context.Context, vendorID *uuid.UUID, page, limit int) (*models.PaginatedResponse, error)
+	GetEndpoint(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (*models.WebhookEndpoint, error)
+	CreateEndpoint(ctx context.Context, vendorID *uuid.UUID, endpoint *models.WebhookEndpoint) error
+	UpdateEndpoint(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID, update *models.WebhookEndpoint) (*models.WebhookEndpoint, error)
+	RotateSecret(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (*models.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) error
+	GetDeliveries(ctx context.Context, vendorID *uuid.UUID, endpointID uuid.UUID, page, limit int) (*models.PaginatedResponse, error)
+	Redeliver(ctx context.Context, vendorID *uuid.UUID, endpointID, deliveryID uuid.UUID) error
+}
+
+// webhookJob is the payload of a webhook.deliver job
+type webhookJob struct {
+	EndpointID uuid.UUID `json:"endpoint_id"`
+	EventID    uuid.UUID `json:"event_id"`
+	Redelivery bool      `json:"redelivery,omitempty"`
+}
+
+type webhookService struct {
+	repo      repository.WebhookRepository
+	eventRepo repository.EventRepository
+	jobs      JobService
+	sender    *webhook.Sender
+	cfg       config.WebhooksConfig
+}
+
+// NewWebhookService creates the webhook service and subscribes it to every domain
+// event. Like other subscribers it must be created before the job workers start.
+func NewWebhookService(repo repository.WebhookRepository, eventRepo repository.EventRepository, events EventService, jobs JobService, cfg config.WebhooksConfig) WebhookService {
+	s := &webhookService{
+		repo:      repo,
+		eventRepo: eventRepo,
+		jobs:      jobs,
+		sender:    webhook.NewSender(cfg.Timeout, cfg.AllowPrivateNetworks),
+		cfg:       cfg,
+	}
+	events.Subscribe("webhooks", nil, s.fanOut)
+	jobs.Register(webhookJobKind, s.deliver)
+	return s
+}
+
+// GetEndpoints pages through endpoints. A vendor only sees its own; admins (nil vendorID) see all.
+func (s *webhookService) GetEndpoints(ctx context.Context, vendorID *uuid.UUID, page, limit int) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetEndpoints")
+	defer func() { tracing.End(span, err) }()
+
+	endpoints, total, err := s.repo.GetAll(ctx, vendorID, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedResponse{
+		Data: endpoints,
+		Pagination: models.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	}, nil
+}
+
+func (s *webhookService) GetEndpoint(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (_ *models.WebhookEndpoint, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetEndpoint")
+	defer func() { tracing.End(span, err) }()
+
+	return s.ownEndpoint(ctx, vendorID, id)
+}
+
+// CreateEndpoint registers an endpoint with a new signing secret. Endpoints
+// created by a vendor only receive events about that vendor.
+func (s *webhookService) CreateEndpoint(ctx context.Context, vendorID *uuid.UUID, endpoint *models.WebhookEndpoint) (err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.CreateEndpoint")
+	defer func() { tracing.End(span, err) }()
+
+	if vendorID != nil {
+		endpoint.VendorID = vendorID
+	}
+	if err := validateWebhookEndpoint(endpoint); err != nil {
+		return err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return err
+	}
+
+	endpoint.ID = uuid.Nil
+	endpoint.Secret = secret
+	endpoint.ConsecutiveFailures = 0
+	endpoint.DisabledAt = nil
+	endpoint.DisabledReason = nil
+	return s.repo.Create(ctx, endpoint)
+}
+
+// UpdateEndpoint replaces an endpoint's URL, description, event types and
+// whether it is active. Activating a disabled endpoint resets its failures.
+func (s *webhookService) UpdateEndpoint(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID, update *models.WebhookEndpoint) (_ *models.WebhookEndpoint, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.UpdateEndpoint")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.ownEndpoint(ctx, vendorID, id); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookEndpoint(update); err != nil {
+		return nil, err
+	}
+
+	update.ID = id
+	if err := s.repo.Update(ctx, update); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// RotateSecret gives an endpoint a new signing secret. Deliveries from then on,
+// retries of earlier events included, are signed with the new secret.
+func (s *webhookService) RotateSecret(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (_ *models.WebhookEndpoint, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.RotateSecret")
+	defer func() { tracing.End(span, err) }()
+
+	endpoint, err := s.ownEndpoint(ctx, vendorID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Secret = secret
+	if err := s.repo.UpdateSecret(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// DeleteEndpoint removes an endpoint with its delivery log. Queued deliveries to it are dropped.
+func (s *webhookService) DeleteEndpoint(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeleteEndpoint")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.ownEndpoint(ctx, vendorID, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *webhookService) GetDeliveries(ctx context.Context, vendorID *uuid.UUID, endpointID uuid.UUID, page, limit int) (_ *models.PaginatedResponse, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.ownEndpoint(ctx, vendorID, endpointID); err != nil {
+		return nil, err
+	}
+
+	deliveries, total, err := s.repo.GetDeliveries(ctx, endpointID, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedResponse{
+		Data: deliveries,
+		Pagination: models.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	}, nil
+}
+
+// Redeliver queues the event of a logged delivery to be sent to its endpoint
+// again, once. The result is added to the delivery log.
+func (s *webhookService) Redeliver(ctx context.Context, vendorID *uuid.UUID, endpointID, deliveryID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.Redeliver")
+	defer func() { tracing.End(span, err) }()
+
+	endpoint, err := s.ownEndpoint(ctx, vendorID, endpointID)
+	if err != nil {
+		return err
+	}
+	if !endpoint.IsActive {
+		return errors.New("webhook endpoint is disabled")
+	}
+
+	delivery, err := s.repo.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return err
+	}
+	if delivery == nil || delivery.EndpointID != endpointID {
+		return errors.New("delivery not found")
+	}
+
+	job, err := s.jobs.Enqueue(ctx, webhookJobKind, webhookJob{EndpointID: endpointID, EventID: delivery.EventID, Redelivery: true}, JobOptions{
+		UniqueKey:   fmt.Sprintf("webhook:redeliver:%s:%s", endpointID, delivery.EventID),
+		MaxAttempts: 1,
+	})
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return errors.New("redelivery already queued")
+	}
+	return nil
+}
+
+// fanOut queues a delivery of an event to every active endpoint subscribed to it.
+// Each endpoint is retried on its own, so a failing one does not hold up the rest.
+func (s *webhookService) fanOut(ctx context.Context, event *models.DomainEvent) error {
+	endpoints, err := s.repo.GetSubscribed(ctx, event.Type, event.VendorIDs())
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		_, err := s.jobs.Enqueue(ctx, webhookJobKind, webhookJob{EndpointID: endpoint.ID, EventID: event.ID}, JobOptions{
+			UniqueKey:   fmt.Sprintf("webhook:%s:%s", endpoint.ID, event.ID),
+			MaxAttempts: s.cfg.MaxAttempts,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to queue webhook delivery to %s: %w", endpoint.ID, err)
+		}
+	}
+	return nil
+}
+
+// deliver sends an event to an endpoint and logs the attempt. A failed attempt is
+// retried by the job queue with exponential backoff.
+func (s *webhookService) deliver(ctx context.Context, job *models.Job) error {
+	var payload webhookJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return PermanentJobError(fmt.Errorf("invalid webhook job payload: %w", err))
+	}
+
+	endpoint, err := s.repo.GetByID(ctx, payload.EndpointID)
+	if err != nil {
+		return err
+	}
+	if endpoint == nil {
+		// Deleted since the delivery was queued
+		return nil
+	}
+	if !endpoint.IsActive {
+		return PermanentJobError(errors.New("webhook endpoint is disabled"))
+	}
+
+	event, err := s.eventRepo.GetByID(ctx, payload.EventID)
+	if err != nil {
+		return err
+	}
+	if event == nil {
+		return PermanentJobError(fmt.Errorf("event %s not found", payload.EventID))
+	}
+
+	body, err := json.Marshal(models.WebhookPayload{
+		ID:            event.ID,
+		Type:          event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		OccurredAt:    event.OccurredAt,
+		Data:          event.Payload,
+	})
+	if err != nil {
+		return PermanentJobError(err)
+	}
+
+	started := time.Now()
+	response, sendErr := s.sender.Send(ctx, webhook.Request{
+		URL:       endpoint.URL,
+		Secret:    endpoint.Secret,
+		EventID:   event.ID.String(),
+		EventType: string(event.Type),
+		Attempt:   job.Attempts,
+		Body:      body,
+	})
+
+	delivery := &models.WebhookDelivery{
+		EndpointID: endpoint.ID,
+		EventID:    event.ID,
+		EventType:  event.Type,
+		Attempt:    job.Attempts,
+		Redelivery: payload.Redelivery,
+		Success:    sendErr == nil,
+		DurationMs: int(time.Since(started).Milliseconds()),
+	}
+	if response != nil {
+		delivery.ResponseStatus = &response.Status
+		if response.Body != "" {
+			delivery.ResponseBody = &response.Body
+		}
+	}
+	if sendErr != nil {
+		msg := sendErr.Error()
+		delivery.Error = &msg
+	}
+
+	disabled, err := s.repo.RecordDelivery(ctx, delivery, s.cfg.DisableAfter)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to log webhook delivery", "event_id", event.ID, "endpoint_id", endpoint.ID, "error", err)
+	}
+
+	outcome := "success"
+	if disabled {
+		outcome = "disabled"
+	} else if sendErr != nil {
+		outcome = "failure"
+	}
+	metrics.WebhookDeliveries.WithLabelValues(outcome).Inc()
+	metrics.WebhookDeliveryDuration.Observe(time.Since(started).Seconds())
+
+	if disabled {
+		slog.WarnContext(ctx, "Webhook endpoint disabled after consecutive failed deliveries",
+			"endpoint_id", endpoint.ID, "url", endpoint.URL, "failures", s.cfg.DisableAfter)
+		return PermanentJobError(fmt.Errorf("webhook endpoint disabled: %w", sendErr))
+	}
+	return sendErr
+}
+
+func (s *webhookService) ownEndpoint(ctx context.Context, vendorID *uuid.UUID, id uuid.UUID) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint == nil || (vendorID != nil && (endpoint.VendorID == nil || *endpoint.VendorID != *vendorID)) {
+		return nil, errors.New("webhook endpoint not found")
+	}
+	return endpoint, nil
+}
+
+func validateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	endpoint.URL = strings.TrimSpace(endpoint.URL)
+	if endpoint.URL == "" {
+		return errors.New("url is required")
+	}
+	if err := webhook.ValidateURL(endpoint.URL); err != nil {
+		return err
+	}
+	endpoint.Description = trimOptional(endpoint.Description)
+
+	known := make(map[models.EventType]bool, len(models.EventTypes))
+	for _, t := range models.EventTypes {
+		known[t] = true
+	}
+	seen := make(map[models.EventType]bool)
+	types := []models.EventType{}
+	for _, t := range endpoint.EventTypes {
+		if !known[t] {
+			return fmt.Errorf("unknown event type %s", t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	endpoint.EventTypes = types
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
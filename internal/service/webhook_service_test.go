@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"smrtmart-go-postgresql/internal/config"
+	"smrtmart-go-postgresql/internal/eventsink"
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// memoryWebhookRepository keeps endpoints and their logged deliveries
+type memoryWebhookRepository struct {
+	repository.WebhookRepository
+	endpoints  map[uuid.UUID]models.WebhookEndpoint
+	deliveries []*models.WebhookDelivery
+}
+
+func (r *memoryWebhookRepository) Create(_ context.Context, endpoint *models.WebhookEndpoint) error {
+	endpoint.ID = uuid.New()
+	r.endpoints[endpoint.ID] = *endpoint
+	return nil
+}
+
+func (r *memoryWebhookRepository) GetByID(_ context.Context, id uuid.UUID) (*models.WebhookEndpoint, error) {
+	endpoint, ok := r.endpoints[id]
+	if !ok {
+		return nil, nil
+	}
+	return &endpoint, nil
+}
+
+func (r *memoryWebhookRepository) UpdateSecret(_ context.Context, endpoint *models.WebhookEndpoint) error {
+	stored := r.endpoints[endpoint.ID]
+	stored.Secret = endpoint.Secret
+	r.endpoints[endpoint.ID] = stored
+	*endpoint = stored
+	return nil
+}
+
+func (r *memoryWebhookRepository) RecordDelivery(_ context.Context, delivery *models.WebhookDelivery, _ int) (bool, error) {
+	r.deliveries = append(r.deliveries, delivery)
+	return false, nil
+}
+
+type staticEventRepository struct {
+	repository.EventRepository
+	event *models.DomainEvent
+}
+
+func (r *staticEventRepository) GetByID(_ context.Context, id uuid.UUID) (*models.DomainEvent, error) {
+	if r.event.ID != id {
+		return nil, nil
+	}
+	return r.event, nil
+}
+
+// registeringJobService collects the handlers of registered job kinds and the
+// jobs queued, skipping those with the unique key of one already queued
+type registeringJobService struct {
+	JobService
+	handlers map[string]JobHandler
+	queued   []*models.Job
+}
+
+func newRegisteringJobService() *registeringJobService {
+	return &registeringJobService{handlers: make(map[string]JobHandler)}
+}
+
+func (s *registeringJobService) Register(kind string, handler JobHandler) {
+	s.handlers[kind] = handler
+}
+
+func (s *registeringJobService) NewJob(kind string, payload interface{}, opts JobOptions) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &models.Job{ID: uuid.New(), Kind: kind, Payload: data, Attempts: 1}
+	if opts.UniqueKey != "" {
+		job.UniqueKey = &opts.UniqueKey
+	}
+	return job, nil
+}
+
+func (s *registeringJobService) Enqueue(_ context.Context, kind string, payload interface{}, opts JobOptions) (*models.Job, error) {
+	job, err := s.NewJob(kind, payload, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !s.queue(job) {
+		return nil, nil
+	}
+	return job, nil
+}
+
+// queue adds a job unless one with its unique key is queued, as a repository
+// storing it in a transaction would
+func (s *registeringJobService) queue(job *models.Job) bool {
+	for _, queued := range s.queued {
+		if job.UniqueKey != nil && queued.UniqueKey != nil && *queued.UniqueKey == *job.UniqueKey {
+			return false
+		}
+	}
+	s.queued = append(s.queued, job)
+	return true
+}
+
+// signedWith reports whether a delivery's signature header was made with secret
+func signedWith(header, secret string, body []byte) bool {
+	timestamp, _, _ := strings.Cut(strings.TrimPrefix(header, "t="), ",")
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(unix, 0)) > 5*time.Minute {
+		return false
+	}
+	return header == eventsink.Signature(secret, time.Unix(unix, 0), body)
+}
+
+func TestRotatedSecretSignsLaterDeliveries(t *testing.T) {
+	ctx := context.Background()
+	var secrets []string // the secret a receiver knows at each delivery
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !signedWith(r.Header.Get("X-Webhook-Signature"), secrets[len(secrets)-1], body) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer receiver.Close()
+
+	event, err := models.NewDomainEvent(models.EventProductUpdated, "product", uuid.NewString(), models.ProductEventPayload{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &memoryWebhookRepository{endpoints: make(map[uuid.UUID]models.WebhookEndpoint)}
+	jobs := newRegisteringJobService()
+	webhooks := NewWebhookService(repo, &staticEventRepository{event: event},
+		&subscribingEventService{handlers: make(map[models.EventType][]EventHandler)}, jobs,
+		config.WebhooksConfig{Timeout: time.Second, DisableAfter: 5, AllowPrivateNetworks: true})
+
+	endpoint := &models.WebhookEndpoint{URL: receiver.URL, IsActive: true}
+	if err := webhooks.CreateEndpoint(ctx, nil, endpoint); err != nil {
+		t.Fatal(err)
+	}
+	created, _ := json.Marshal(models.NewWebhookEndpointWithSecret(endpoint))
+	if !strings.Contains(string(created), `"secret":"whsec_`) {
+		t.Errorf("created endpoint does not show its secret: %s", created)
+	}
+	fetched, err := webhooks.GetEndpoint(ctx, nil, endpoint.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shown, _ := json.Marshal(fetched); strings.Contains(string(shown), "secret") {
+		t.Errorf("fetched endpoint shows its secret: %s", shown)
+	}
+
+	deliver := func() error {
+		payload, _ := json.Marshal(webhookJob{EndpointID: endpoint.ID, EventID: event.ID})
+		return jobs.handlers[webhookJobKind](ctx, &models.Job{Kind: webhookJobKind, Payload: payload, Attempts: 1})
+	}
+	secrets = append(secrets, endpoint.Secret)
+	if err := deliver(); err != nil {
+		t.Fatalf("delivery signed with the created secret: %v", err)
+	}
+
+	rotated, err := webhooks.RotateSecret(ctx, nil, endpoint.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.Secret == "" || rotated.Secret == secrets[0] {
+		t.Fatalf("rotated secret %q", rotated.Secret)
+	}
+
+	// A receiver still on the old secret refuses deliveries, one with the new secret accepts them
+	if err := deliver(); err == nil {
+		t.Error("delivery after rotation verified with the old secret")
+	}
+	secrets = append(secrets, rotated.Secret)
+	if err := deliver(); err != nil {
+		t.Errorf("delivery signed with the rotated secret: %v", err)
+	}
+	if len(repo.deliveries) != 3 || !repo.deliveries[0].Success || repo.deliveries[1].Success || !repo.deliveries[2].Success {
+		t.Errorf("logged %d deliveries", len(repo.deliveries))
+	}
+
+	other := uuid.New()
+	if _, err := webhooks.RotateSecret(ctx, &other, endpoint.ID); err == nil || err.Error() != "webhook endpoint not found" {
+		t.Errorf("another vendor rotated the secret: %v", err)
+	}
+}
@@ -0,0 +1,419 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+	"smrtmart-go-postgresql/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultWishlistName   = "My Wishlist"
+	maxWishlistNameLength = 100
+	maxWishlistsPerUser   = 20
+)
+
+type WishlistService interface {
+	GetWishlists(ctx context.Context, userID uuid.UUID) ([]*models.Wishlist, error)
+	CreateWishlist(ctx context.Context, userID uuid.UUID, name string, isDefault bool) (*models.Wishlist, error)
+	GetWishlist(ctx context.Context, userID, id uuid.UUID) (*models.Wishlist, error)
+	UpdateWishlist(ctx context.Context, userID, id uuid.UUID, name *string, isDefault *bool) (*models.Wishlist, error)
+	DeleteWishlist(ctx context.Context, userID, id uuid.UUID) error
+	AddItem(ctx context.Context, userID uuid.UUID, wishlistID *uuid.UUID, productRef string) (*models.Wishlist, error)
+	RemoveItem(ctx context.Context, userID, id uuid.UUID, productRef string) error
+	Share(ctx context.Context, userID, id uuid.UUID) (*models.Wishlist, error)
+	Unshare(ctx context.Context, userID, id uuid.UUID) (*models.Wishlist, error)
+	GetSharedWishlist(ctx context.Context, token string) (*models.Wishlist, error)
+	MoveToCart(ctx context.Context, userID, id uuid.UUID, productRef string, quantity int) (*models.CartItem, error)
+	MoveFromCart(ctx context.Context, userID uuid.UUID, wishlistID *uuid.UUID, productRef string) (*models.Wishlist, error)
+}
+
+type wishlistService struct {
+	repo        repository.WishlistRepository
+	productRepo repository.ProductRepository
+}
+
+func NewWishlistService(repo repository.WishlistRepository, productRepo repository.ProductRepository) WishlistService {
+	return &wishlistService{
+		repo:        repo,
+		productRepo: productRepo,
+	}
+}
+
+// GetWishlists returns the user's wishlists, creating the default one on first use
+func (s *wishlistService) GetWishlists(ctx context.Context, userID uuid.UUID) (_ []*models.Wishlist, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.GetWishlists")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.GetOrCreateDefault(ctx, userID, defaultWishlistName); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByUser(ctx, userID)
+}
+
+func (s *wishlistService) CreateWishlist(ctx context.Context, userID uuid.UUID, name string, isDefault bool) (_ *models.Wishlist, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.CreateWishlist")
+	defer func() { tracing.End(span, err) }()
+
+	existing, err := s.repo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWishlistsPerUser {
+		return nil, errors.New("wishlist limit reached")
+	}
+
+	name, err = validWishlistName(name, existing, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+
+	wishlist := &models.Wishlist{
+		UserID: userID,
+		Name:   name,
+		// The first wishlist is always the default
+		IsDefault: len(existing) == 0,
+	}
+	if err := s.repo.Create(ctx, wishlist); err != nil {
+		return nil, err
+	}
+
+	if isDefault && !wishlist.IsDefault {
+		if err := s.repo.SetDefault(ctx, userID, wishlist.ID); err != nil {
+			return nil, err
+		}
+		wishlist.IsDefault = true
+	}
+
+	return wishlist, nil
+}
+
+// GetWishlist returns one of the user's wishlists with its items
+func (s *wishlistService) GetWishlist(ctx context.Context, userID, id uuid.UUID) (_ *models.Wishlist, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.GetWishlist")
+	defer func() { tracing.End(span, err) }()
+
+	wishlist, err := s.ownWishlist(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withItems(ctx, wishlist)
+}
+
+// UpdateWishlist renames a wishlist or makes it the default
+func (s *wishlistService) UpdateWishlist(ctx context.Context, userID, id uuid.UUID, name *string, isDefault *bool) (_ *models.Wishlist, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.UpdateWishlist")
+	defer func() { tracing.End(span, err) }()
+
+	wishlist, err := s.ownWishlist(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if isDefault != nil && !*isDefault && wishlist.IsDefault {
+		return nil, errors.New("make another wishlist the default instead")
+	}
+
+	if name != nil {
+		existing, err := s.repo.GetByUser(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		wishlist.Name, err = validWishlistName(*name, existing, wishlist.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.repo.Update(ctx, wishlist); err != nil {
+			return nil, err
+		}
+	}
+
+	if isDefault != nil && *isDefault && !wishlist.IsDefault {
+		if err := s.repo.SetDefault(ctx, userID, wishlist.ID); err != nil {
+			return nil, err
+		}
+		wishlist.IsDefault = true
+	}
+
+	return wishlist, nil
+}
+
+func (s *wishlistService) DeleteWishlist(ctx context.Context, userID, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.DeleteWishlist")
+	defer func() { tracing.End(span, err) }()
+
+	wishlist, err := s.ownWishlist(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if wishlist.IsDefault {
+		return errors.New("cannot delete the default wishlist")
+	}
+
+	return s.repo.Delete(ctx, id)
+}
+
+// AddItem saves an active product to a wishlist, or to the default wishlist if none is given
+func (s *wishlistService) AddItem(ctx context.Context, userID uuid.UUID, wishlistID *uuid.UUID, productRef string) (_ *models.Wishlist, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.AddItem")
+	defer func() { tracing.End(span, err) }()
+
+	wishlist, err := s.targetWishlist(ctx, userID, wishlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := findProduct(ctx, s.productRepo, productRef)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil || product.Status != models.ProductStatusActive {
+		return nil, errors.New("product not found")
+	}
+
+	if err := s.repo.AddItem(ctx, wishlist.ID, product.ID, product.Price); err != nil {
+		return nil, err
+	}
+
+	return s.withItems(ctx, wishlist)
+}
+
+func (s *wishlistService) RemoveItem(ctx context.Context, userID, id uuid.UUID, productRef string) (err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.RemoveItem")
+	defer func() { tracing.End(span, err) }()
+
+	wishlist, err := s.ownWishlist(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	product, err := findProduct(ctx, s.productRepo, productRef)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return errors.New("item not found")
+	}
+
+	removed, err := s.repo.RemoveItem(ctx, wishlist.ID, product.ID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return errors.New("item not found")
+	}
+
+	return nil
+}
+
+// Share makes the wishlist readable by anyone with its share token. Sharing again keeps the same token.
+func (s *wishlistService) Share(ctx context.Context, userID, id uuid.UUID) (_ *models.Wishlist, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.Share")
+	defer func() { tracing.End(span, err) }()
+
+	wishlist, err := s.ownWishlist(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if wishlist.ShareToken == nil {
+		token, err := newShareToken()
+		if err != nil {
+			return nil, err
+		}
+		wishlist.ShareToken = &token
+
+		if err := s.repo.Update(ctx, wishlist); err != nil {
+			return nil, err
+		}
+	}
+
+	return wishlist, nil
+}
+
+// Unshare revokes the wishlist's share token, so existing links stop working
+func (s *wishlistService) Unshare(ctx context.Context, userID, id uuid.UUID) (_ *models.Wishlist, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.Unshare")
+	defer func() { tracing.End(span, err) }()
+
+	wishlist, err := s.ownWishlist(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if wishlist.ShareToken != nil {
+		wishlist.ShareToken = nil
+		if err := s.repo.Update(ctx, wishlist); err != nil {
+			return nil, err
+		}
+	}
+
+	return wishlist, nil
+}
+
+// GetSharedWishlist returns the read-only view of a shared wishlist
+func (s *wishlistService) GetSharedWishlist(ctx context.Context, token string) (_ *models.Wishlist, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.GetSharedWishlist")
+	defer func() { tracing.End(span, err) }()
+
+	wishlist, err := s.repo.GetByShareToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if wishlist == nil {
+		return nil, errors.New("wishlist not found")
+	}
+
+	return s.withItems(ctx, wishlist)
+}
+
+// MoveToCart moves a wishlist item into the user's cart
+func (s *wishlistService) MoveToCart(ctx context.Context, userID, id uuid.UUID, productRef string, quantity int) (_ *models.CartItem, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.MoveToCart")
+	defer func() { tracing.End(span, err) }()
+
+	if quantity <= 0 {
+		quantity = 1
+	}
+
+	wishlist, err := s.ownWishlist(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := findProduct(ctx, s.productRepo, productRef)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New("item not found")
+	}
+	if product.Status != models.ProductStatusActive {
+		return nil, errors.New("product is not available")
+	}
+	if product.Stock < quantity {
+		return nil, errors.New("insufficient stock")
+	}
+
+	item, err := s.repo.MoveToCart(ctx, userID, wishlist.ID, product.ID, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, errors.New("item not found")
+	}
+
+	item.Product = product
+	return item, nil
+}
+
+// MoveFromCart saves a cart item for later in a wishlist, or in the default wishlist if none is given
+func (s *wishlistService) MoveFromCart(ctx context.Context, userID uuid.UUID, wishlistID *uuid.UUID, productRef string) (_ *models.Wishlist, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.MoveFromCart")
+	defer func() { tracing.End(span, err) }()
+
+	wishlist, err := s.targetWishlist(ctx, userID, wishlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := findProduct(ctx, s.productRepo, productRef)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New("product not in cart")
+	}
+
+	moved, err := s.repo.MoveFromCart(ctx, userID, wishlist.ID, product.ID, product.Price)
+	if err != nil {
+		return nil, err
+	}
+	if !moved {
+		return nil, errors.New("product not in cart")
+	}
+
+	return s.withItems(ctx, wishlist)
+}
+
+// ownWishlist loads a wishlist, treating other users' wishlists as not found
+func (s *wishlistService) ownWishlist(ctx context.Context, userID, id uuid.UUID) (*models.Wishlist, error) {
+	wishlist, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if wishlist == nil || wishlist.UserID != userID {
+		return nil, errors.New("wishlist not found")
+	}
+
+	return wishlist, nil
+}
+
+// targetWishlist returns the given wishlist, or the user's default wishlist if id is nil
+func (s *wishlistService) targetWishlist(ctx context.Context, userID uuid.UUID, id *uuid.UUID) (*models.Wishlist, error) {
+	if id != nil {
+		return s.ownWishlist(ctx, userID, *id)
+	}
+
+	return s.repo.GetOrCreateDefault(ctx, userID, defaultWishlistName)
+}
+
+// withItems loads the wishlist's items and flags those that are cheaper than when they were added
+func (s *wishlistService) withItems(ctx context.Context, wishlist *models.Wishlist) (*models.Wishlist, error) {
+	items, err := s.repo.GetItems(ctx, wishlist.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		item := &items[i]
+		if item.Price < item.PriceWhenAdded {
+			item.PriceDrop = roundMoney(item.PriceWhenAdded - item.Price)
+			item.PriceDropped = item.PriceDrop > 0
+		}
+		item.InStock = item.Status == models.ProductStatusActive && item.Stock > 0
+	}
+
+	wishlist.Items = items
+	wishlist.ItemCount = len(items)
+	return wishlist, nil
+}
+
+// validWishlistName trims the name and checks it is unique among the user's other wishlists
+func validWishlistName(name string, existing []*models.Wishlist, id uuid.UUID) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if len([]rune(name)) > maxWishlistNameLength {
+		return "", errors.New("name is too long")
+	}
+
+	for _, wishlist := range existing {
+		if wishlist.ID != id && strings.EqualFold(wishlist.Name, name) {
+			return "", errors.New("wishlist name already in use")
+		}
+	}
+
+	return name, nil
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
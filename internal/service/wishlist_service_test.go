@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"smrtmart-go-postgresql/internal/models"
+	"smrtmart-go-postgresql/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// memoryWishlistRepository keeps wishlists in insertion order and their items by wishlist
+type memoryWishlistRepository struct {
+	repository.WishlistRepository
+	wishlists []*models.Wishlist
+	items     map[uuid.UUID][]models.WishlistItem
+	moved     int
+}
+
+func (r *memoryWishlistRepository) Create(_ context.Context, wishlist *models.Wishlist) error {
+	wishlist.ID = uuid.New()
+	copied := *wishlist
+	r.wishlists = append(r.wishlists, &copied)
+	return nil
+}
+
+func (r *memoryWishlistRepository) GetByID(_ context.Context, id uuid.UUID) (*models.Wishlist, error) {
+	for _, wishlist := range r.wishlists {
+		if wishlist.ID == id {
+			copied := *wishlist
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryWishlistRepository) GetByUser(_ context.Context, userID uuid.UUID) ([]*models.Wishlist, error) {
+	var wishlists []*models.Wishlist
+	for _, wishlist := range r.wishlists {
+		if wishlist.UserID == userID {
+			copied := *wishlist
+			wishlists = append(wishlists, &copied)
+		}
+	}
+	return wishlists, nil
+}
+
+func (r *memoryWishlistRepository) Update(_ context.Context, wishlist *models.Wishlist) error {
+	for i := range r.wishlists {
+		if r.wishlists[i].ID == wishlist.ID {
+			copied := *wishlist
+			r.wishlists[i] = &copied
+		}
+	}
+	return nil
+}
+
+func (r *memoryWishlistRepository) SetDefault(_ context.Context, userID, id uuid.UUID) error {
+	for _, wishlist := range r.wishlists {
+		if wishlist.UserID == userID {
+			wishlist.IsDefault = wishlist.ID == id
+		}
+	}
+	return nil
+}
+
+func (r *memoryWishlistRepository) GetItems(_ context.Context, wishlistID uuid.UUID) ([]models.WishlistItem, error) {
+	return append([]models.WishlistItem(nil), r.items[wishlistID]...), nil
+}
+
+func (r *memoryWishlistRepository) MoveToCart(_ context.Context, _, _, productID uuid.UUID, quantity int) (*models.CartItem, error) {
+	r.moved++
+	return &models.CartItem{ProductID: productID, Quantity: quantity}, nil
+}
+
+func TestWishlistNamesAndDefaults(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryWishlistRepository{}
+	wishlists := NewWishlistService(repo, nil)
+	userID, otherID := uuid.New(), uuid.New()
+
+	first, err := wishlists.CreateWishlist(ctx, userID, " Birthday ", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.IsDefault || first.Name != "Birthday" {
+		t.Errorf("first wishlist %+v", first)
+	}
+
+	creates := []struct {
+		name     string
+		wishlist string
+		want     string
+	}{
+		{"blank name", "  ", "name is required"},
+		{"name too long", strings.Repeat("a", maxWishlistNameLength+1), "name is too long"},
+		{"name in use", "birthday", "wishlist name already in use"},
+	}
+	for _, tt := range creates {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := wishlists.CreateWishlist(ctx, userID, tt.wishlist, false); err == nil || err.Error() != tt.want {
+				t.Errorf("CreateWishlist = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	second, err := wishlists.CreateWishlist(ctx, userID, "Gifts", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.IsDefault || repo.wishlists[0].IsDefault {
+		t.Errorf("default not moved: first %+v, second %+v", repo.wishlists[0], second)
+	}
+
+	no := false
+	if _, err := wishlists.UpdateWishlist(ctx, userID, second.ID, nil, &no); err == nil || err.Error() != "make another wishlist the default instead" {
+		t.Errorf("unsetting the default = %v", err)
+	}
+	if err := wishlists.DeleteWishlist(ctx, userID, second.ID); err == nil || err.Error() != "cannot delete the default wishlist" {
+		t.Errorf("deleting the default = %v", err)
+	}
+	rename := "Gifts"
+	if _, err := wishlists.UpdateWishlist(ctx, userID, first.ID, &rename, nil); err == nil || err.Error() != "wishlist name already in use" {
+		t.Errorf("renaming to a taken name = %v", err)
+	}
+	if _, err := wishlists.UpdateWishlist(ctx, userID, second.ID, &rename, nil); err != nil {
+		t.Errorf("keeping its own name = %v", err)
+	}
+	if _, err := wishlists.GetWishlist(ctx, otherID, first.ID); err == nil || err.Error() != "wishlist not found" {
+		t.Errorf("another user's wishlist = %v", err)
+	}
+
+	for i := len(repo.wishlists); i < maxWishlistsPerUser; i++ {
+		repo.wishlists = append(repo.wishlists, &models.Wishlist{ID: uuid.New(), UserID: userID, Name: fmt.Sprint(i)})
+	}
+	if _, err := wishlists.CreateWishlist(ctx, userID, "One more", false); err == nil || err.Error() != "wishlist limit reached" {
+		t.Errorf("creating past the limit = %v", err)
+	}
+}
+
+func TestWishlistItems(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	wishlist := &models.Wishlist{ID: uuid.New(), UserID: userID, Name: "Mine", IsDefault: true}
+	repo := &memoryWishlistRepository{
+		wishlists: []*models.Wishlist{wishlist},
+		items: map[uuid.UUID][]models.WishlistItem{wishlist.ID: {
+			{Name: "Cheaper", Status: models.ProductStatusActive, Price: 79.99, PriceWhenAdded: 99.99, Stock: 3},
+			{Name: "Dearer", Status: models.ProductStatusActive, Price: 120, PriceWhenAdded: 99.99},
+			{Name: "Archived", Status: models.ProductStatusArchived, Price: 10, PriceWhenAdded: 10, Stock: 5},
+		}},
+	}
+	inStock := &models.Product{ID: uuid.New(), Status: models.ProductStatusActive, Stock: 2}
+	archived := &models.Product{ID: uuid.New(), Status: models.ProductStatusArchived, Stock: 5}
+	products := &memoryProductRepository{products: map[uuid.UUID]*models.Product{inStock.ID: inStock, archived.ID: archived}}
+	wishlists := NewWishlistService(repo, products)
+
+	loaded, err := wishlists.GetWishlist(ctx, userID, wishlist.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		dropped bool
+		drop    float64
+		inStock bool
+	}{{true, 20, true}, {false, 0, false}, {false, 0, false}}
+	for i, item := range loaded.Items {
+		if item.PriceDropped != want[i].dropped || item.PriceDrop != want[i].drop || item.InStock != want[i].inStock {
+			t.Errorf("%s: dropped %v by %v, in stock %v", item.Name, item.PriceDropped, item.PriceDrop, item.InStock)
+		}
+	}
+	if loaded.ItemCount != 3 {
+		t.Errorf("item count %d", loaded.ItemCount)
+	}
+
+	moves := []struct {
+		name     string
+		product  string
+		quantity int
+		want     string
+	}{
+		{"unknown product", uuid.NewString(), 1, "item not found"},
+		{"inactive product", archived.ID.String(), 1, "product is not available"},
+		{"more than in stock", inStock.ID.String(), 3, "insufficient stock"},
+	}
+	for _, tt := range moves {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := wishlists.MoveToCart(ctx, userID, wishlist.ID, tt.product, tt.quantity); err == nil || err.Error() != tt.want {
+				t.Errorf("MoveToCart = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if repo.moved != 0 {
+		t.Fatalf("%d refused moves reached the repository", repo.moved)
+	}
+
+	item, err := wishlists.MoveToCart(ctx, userID, wishlist.ID, inStock.ID.String(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Quantity != 1 || item.Product.ID != inStock.ID {
+		t.Errorf("moved %+v", item)
+	}
+
+	shared, err := wishlists.Share(ctx, userID, wishlist.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := wishlists.Share(ctx, userID, wishlist.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared.ShareToken == nil || len(*shared.ShareToken) != 32 || *again.ShareToken != *shared.ShareToken {
+		t.Errorf("share tokens %v and %v", shared.ShareToken, again.ShareToken)
+	}
+}
@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"smrtmart-go-postgresql/internal/eventsink"
+	"smrtmart-go-postgresql/internal/tracing"
+)
+
+const (
+	userAgent = "SmrtMart-Webhooks/1.0"
+	// maxResponseExcerpt is how much of a response is kept in the delivery log
+	maxResponseExcerpt = 1024
+)
+
+// Request is one signed delivery of an event to an endpoint
+type Request struct {
+	URL       string
+	Secret    string
+	EventID   string
+	EventType string
+	Attempt   int
+	Body      []byte
+}
+
+// Response is what an endpoint answered
+type Response struct {
+	Status int
+	Body   string // The start of the body
+}
+
+// Sender POSTs signed webhook requests. Redirects are not followed, and unless
+// private networks are allowed, connections to loopback, private and link-local
+// addresses are refused so endpoints cannot reach internal services.
+type Sender struct {
+	client *http.Client
+}
+
+func NewSender(timeout time.Duration, allowPrivateNetworks bool) *Sender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateNetworks {
+		dialer.Control = refusePrivateAddresses
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &Sender{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: tracing.Transport("webhook", transport),
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Send delivers a request. The response is returned whenever one was received;
+// the error is set if there was none or its status was not 2xx.
+func (s *Sender) Send(ctx context.Context, r Request) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-Webhook-ID", r.EventID)
+	req.Header.Set("X-Webhook-Event", r.EventType)
+	req.Header.Set("X-Webhook-Attempt", strconv.Itoa(r.Attempt))
+	req.Header.Set("X-Webhook-Signature", eventsink.Signature(r.Secret, time.Now(), r.Body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseExcerpt))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	response := &Response{
+		Status: resp.StatusCode,
+		// Postgres text cannot hold NUL bytes or invalid UTF-8
+		Body: strings.ReplaceAll(strings.ToValidUTF8(string(excerpt), ""), "\x00", ""),
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return response, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return response, nil
+}
+
+// ValidateURL checks that an endpoint URL is an absolute http or https URL
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return errors.New("url must not contain credentials")
+	}
+	return nil
+}
+
+// refusePrivateAddresses is a dialer control that refuses internal addresses. It
+// checks the resolved address, so a public hostname pointing inside is refused too.
+func refusePrivateAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("delivering to %s is not allowed", host)
+	}
+	return nil
+}
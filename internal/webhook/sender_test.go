@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verifySignature checks an X-Webhook-Signature header the way a receiver
+// would: recompute the HMAC over "<timestamp>.<body>" and reject old timestamps
+func verifySignature(header, secret string, body []byte, tolerance time.Duration) error {
+	var timestamp, signature string
+	for _, field := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return errors.New("malformed signature header")
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, mac.Sum(nil)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func TestSendSignsRequestsForTheReceiver(t *testing.T) {
+	const secret = "whsec_test"
+	type received struct {
+		header http.Header
+		body   []byte
+		err    error
+	}
+	requests := make(chan received, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		err := verifySignature(r.Header.Get("X-Webhook-Signature"), secret, body, 5*time.Minute)
+		requests <- received{header: r.Header.Clone(), body: body, err: err}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer receiver.Close()
+
+	sender := NewSender(time.Second, true)
+	body := []byte(`{"id":"evt_1","type":"order.created"}`)
+	response, err := sender.Send(context.Background(), Request{
+		URL:       receiver.URL,
+		Secret:    secret,
+		EventID:   "evt_1",
+		EventType: "order.created",
+		Attempt:   2,
+		Body:      body,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != http.StatusOK || response.Body != "ok" {
+		t.Errorf("response = %+v", response)
+	}
+
+	r := <-requests
+	if r.err != nil {
+		t.Fatalf("receiver rejected the delivery: %v", r.err)
+	}
+	if string(r.body) != string(body) || r.header.Get("Content-Type") != "application/json" {
+		t.Errorf("received %s as %s", r.body, r.header.Get("Content-Type"))
+	}
+	if r.header.Get("X-Webhook-ID") != "evt_1" || r.header.Get("X-Webhook-Event") != "order.created" || r.header.Get("X-Webhook-Attempt") != "2" {
+		t.Errorf("headers = %v", r.header)
+	}
+
+	// Signed with another secret, the receiver refuses the delivery
+	response, err = sender.Send(context.Background(), Request{URL: receiver.URL, Secret: "whsec_other", Body: body})
+	if err == nil || response == nil || response.Status != http.StatusUnauthorized {
+		t.Errorf("wrongly signed delivery: %+v, %v", response, err)
+	}
+	if r := <-requests; r.err == nil || r.err.Error() != "signature mismatch" {
+		t.Errorf("receiver verification = %v", r.err)
+	}
+}
+
+func TestSendRefusesPrivateNetworks(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback receiver")
+	}))
+	defer receiver.Close()
+
+	_, err := NewSender(time.Second, false).Send(context.Background(), Request{URL: receiver.URL, Secret: "whsec_test"})
+	if err == nil || !strings.Contains(err.Error(), "is not allowed") {
+		t.Errorf("Send = %v, want the address refused", err)
+	}
+}
@@ -0,0 +1,12 @@
+-- Rollback stored product search vector
+
+DROP INDEX IF EXISTS idx_products_name_trgm;
+DROP INDEX IF EXISTS idx_products_search_vector;
+
+DROP TRIGGER IF EXISTS products_search_vector_trigger ON products;
+DROP FUNCTION IF EXISTS products_search_vector_update();
+
+ALTER TABLE products DROP COLUMN IF EXISTS search_vector;
+
+DROP FUNCTION IF EXISTS product_search_config();
+DROP TABLE IF EXISTS search_settings;
@@ -0,0 +1,47 @@
+-- Stored full-text search vector for products
+-- name is weighted A, tags and SKU B, description C. The text-search language
+-- is read from search_settings so each store can pick its own stemming.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Single-row settings table holding the active text-search configuration
+CREATE TABLE IF NOT EXISTS search_settings (
+    id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    text_search_config REGCONFIG NOT NULL DEFAULT 'swedish',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO search_settings (id, text_search_config) VALUES (true, 'swedish')
+ON CONFLICT (id) DO NOTHING;
+
+CREATE OR REPLACE FUNCTION product_search_config() RETURNS REGCONFIG AS $$
+    SELECT COALESCE((SELECT text_search_config FROM search_settings LIMIT 1), 'simple'::regconfig)
+$$ LANGUAGE sql STABLE;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+CREATE OR REPLACE FUNCTION products_search_vector_update() RETURNS TRIGGER AS $$
+DECLARE
+    cfg REGCONFIG := product_search_config();
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector(cfg, COALESCE(NEW.name, '')), 'A') ||
+        setweight(to_tsvector(cfg, COALESCE(array_to_string(NEW.tags, ' '), '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(NEW.sku, '')), 'B') ||
+        setweight(to_tsvector(cfg, COALESCE(NEW.description, '')), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS products_search_vector_trigger ON products;
+CREATE TRIGGER products_search_vector_trigger
+    BEFORE INSERT OR UPDATE OF name, description, tags, sku ON products
+    FOR EACH ROW EXECUTE FUNCTION products_search_vector_update();
+
+-- Populate the vector for existing rows
+UPDATE products SET name = name;
+
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+
+-- Trigram index for typo-tolerant matching on product names (e.g. "macbok")
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
@@ -0,0 +1,4 @@
+-- Rollback search query log
+
+DROP INDEX IF EXISTS idx_categories_name_trgm;
+DROP TABLE IF EXISTS search_queries;
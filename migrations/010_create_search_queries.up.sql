@@ -0,0 +1,18 @@
+-- Log of customer search queries, used for autocomplete suggestions and
+-- for merchandisers to see which searches return nothing
+
+CREATE TABLE IF NOT EXISTS search_queries (
+    query VARCHAR(255) PRIMARY KEY, -- normalized: trimmed, lowercased, single-spaced
+    search_count INTEGER NOT NULL DEFAULT 0,
+    zero_result_count INTEGER NOT NULL DEFAULT 0,
+    last_result_count INTEGER NOT NULL DEFAULT 0,
+    first_searched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_searched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_queries_prefix ON search_queries (query text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_search_queries_zero_results ON search_queries (zero_result_count DESC)
+    WHERE zero_result_count > 0;
+
+-- Trigram index so category names can be suggested by prefix or fuzzy match
+CREATE INDEX IF NOT EXISTS idx_categories_name_trgm ON categories USING GIN (name gin_trgm_ops);
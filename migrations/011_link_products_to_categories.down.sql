@@ -0,0 +1,5 @@
+-- Rollback product category linkage
+-- Categories created by the up migration are left in place.
+
+DROP INDEX IF EXISTS idx_products_category_id;
+ALTER TABLE products DROP COLUMN IF EXISTS category_id;
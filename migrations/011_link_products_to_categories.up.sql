@@ -0,0 +1,29 @@
+-- Link products to the categories table by ID
+-- The free-text products.category column is kept as a readable label.
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES categories(id) ON DELETE SET NULL;
+
+-- Create categories for any product category text that has no matching row yet
+INSERT INTO categories (id, name, slug, is_active, sort_order)
+SELECT gen_random_uuid(), INITCAP(t.category), t.slug, true, 100
+FROM (
+    SELECT DISTINCT category,
+        TRIM(BOTH '-' FROM REGEXP_REPLACE(LOWER(category), '[^a-z0-9]+', '-', 'g')) AS slug
+    FROM products
+) t
+WHERE t.slug <> ''
+  AND NOT EXISTS (
+    SELECT 1 FROM categories c
+    WHERE LOWER(c.name) = LOWER(t.category) OR c.slug = t.slug
+)
+ON CONFLICT (slug) DO NOTHING;
+
+-- Link products by slug or case-insensitive name
+UPDATE products p
+SET category_id = c.id
+FROM categories c
+WHERE p.category_id IS NULL
+  AND (c.slug = TRIM(BOTH '-' FROM REGEXP_REPLACE(LOWER(p.category), '[^a-z0-9]+', '-', 'g'))
+       OR LOWER(c.name) = LOWER(p.category));
+
+CREATE INDEX IF NOT EXISTS idx_products_category_id ON products(category_id);
@@ -0,0 +1,12 @@
+-- Rollback vendor verification documents and review history
+
+DROP TABLE IF EXISTS vendor_suspended_products;
+DROP TABLE IF EXISTS vendor_status_history;
+
+DROP INDEX IF EXISTS idx_vendors_status;
+
+ALTER TABLE vendors
+    DROP COLUMN IF EXISTS status_reason,
+    DROP COLUMN IF EXISTS bank_proof_url,
+    DROP COLUMN IF EXISTS vat_id,
+    DROP COLUMN IF EXISTS organization_number;
@@ -0,0 +1,29 @@
+-- Vendor verification documents and review history
+
+ALTER TABLE vendors
+    ADD COLUMN IF NOT EXISTS organization_number VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS vat_id VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS bank_proof_url TEXT,
+    ADD COLUMN IF NOT EXISTS status_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_vendors_status ON vendors(status, created_at);
+
+-- Every status change made during review, with the reason given
+CREATE TABLE IF NOT EXISTS vendor_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vendor_id UUID NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL,
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_vendor_status_history_vendor ON vendor_status_history(vendor_id, created_at DESC);
+
+-- Products deactivated by a suspension, so reinstating the vendor restores only those
+CREATE TABLE IF NOT EXISTS vendor_suspended_products (
+    vendor_id UUID NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    PRIMARY KEY (vendor_id, product_id)
+);
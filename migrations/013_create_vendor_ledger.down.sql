@@ -0,0 +1,8 @@
+-- Rollback marketplace commissions, vendor ledger and payouts
+
+DROP TABLE IF EXISTS vendor_ledger_entries;
+DROP TABLE IF EXISTS vendor_payouts;
+DROP TABLE IF EXISTS payout_batches;
+DROP TABLE IF EXISTS commission_rates;
+
+ALTER TABLE vendors DROP COLUMN IF EXISTS stripe_account_id;
@@ -0,0 +1,79 @@
+-- Marketplace commissions, vendor earnings ledger and payout batches
+
+ALTER TABLE vendors ADD COLUMN IF NOT EXISTS stripe_account_id VARCHAR(255);
+
+-- Commission rates as a fraction of the item total. A vendor rate overrides a
+-- category rate, which overrides the single global rate.
+CREATE TABLE IF NOT EXISTS commission_rates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('global', 'category', 'vendor')),
+    category_id UUID REFERENCES categories(id) ON DELETE CASCADE,
+    vendor_id UUID REFERENCES vendors(id) ON DELETE CASCADE,
+    rate NUMERIC(5,4) NOT NULL CHECK (rate >= 0 AND rate <= 1),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (
+        (scope = 'global' AND category_id IS NULL AND vendor_id IS NULL) OR
+        (scope = 'category' AND category_id IS NOT NULL AND vendor_id IS NULL) OR
+        (scope = 'vendor' AND vendor_id IS NOT NULL AND category_id IS NULL)
+    )
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_commission_rates_global ON commission_rates(scope) WHERE scope = 'global';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_commission_rates_category ON commission_rates(category_id) WHERE scope = 'category';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_commission_rates_vendor ON commission_rates(vendor_id) WHERE scope = 'vendor';
+
+INSERT INTO commission_rates (scope, rate) VALUES ('global', 0.10) ON CONFLICT DO NOTHING;
+
+-- A payout batch rolls up every vendor's unpaid balance up to period_end
+CREATE TABLE IF NOT EXISTS payout_batches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    period_end TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'partially_failed')),
+    total NUMERIC(12,2) NOT NULL DEFAULT 0,
+    payout_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS vendor_payouts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    batch_id UUID NOT NULL REFERENCES payout_batches(id) ON DELETE CASCADE,
+    vendor_id UUID NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    amount NUMERIC(12,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid', 'failed')),
+    transfer_id VARCHAR(255),
+    failure_reason TEXT,
+    period_start TIMESTAMP,
+    period_end TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    paid_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_vendor_payouts_batch ON vendor_payouts(batch_id);
+CREATE INDEX IF NOT EXISTS idx_vendor_payouts_vendor ON vendor_payouts(vendor_id, created_at DESC);
+
+-- Signed amounts owed to the vendor: sales are credits, commissions, refunds
+-- and payouts are debits. Entries are attached to a payout once paid out.
+CREATE TABLE IF NOT EXISTS vendor_ledger_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vendor_id UUID NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    order_item_id UUID REFERENCES order_items(id) ON DELETE SET NULL,
+    payout_id UUID REFERENCES vendor_payouts(id) ON DELETE SET NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('sale', 'commission', 'refund', 'commission_refund', 'payout', 'adjustment')),
+    amount NUMERIC(12,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    commission_rate NUMERIC(5,4),
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_vendor_ledger_vendor ON vendor_ledger_entries(vendor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_vendor_ledger_unpaid ON vendor_ledger_entries(vendor_id) WHERE payout_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_vendor_ledger_order_item ON vendor_ledger_entries(order_item_id);
+
+-- Recording an order twice must not double the vendor's earnings
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vendor_ledger_item_once
+    ON vendor_ledger_entries(order_item_id, type) WHERE type IN ('sale', 'commission');
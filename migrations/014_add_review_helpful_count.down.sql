@@ -0,0 +1,5 @@
+-- Rollback review helpful count
+
+DROP INDEX IF EXISTS idx_reviews_product_created;
+
+ALTER TABLE reviews DROP COLUMN IF EXISTS helpful_count;
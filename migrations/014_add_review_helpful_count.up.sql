@@ -0,0 +1,5 @@
+-- Helpful count for sorting reviews, and an index for paging a product's reviews
+
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS helpful_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_reviews_product_created ON reviews(product_id, created_at DESC);
@@ -0,0 +1,14 @@
+-- Rollback review moderation
+
+DROP TABLE IF EXISTS review_moderation_log;
+DROP TABLE IF EXISTS review_reports;
+DROP TABLE IF EXISTS review_votes;
+
+DROP INDEX IF EXISTS idx_reviews_status;
+
+ALTER TABLE reviews
+    DROP COLUMN IF EXISTS vendor_replied_at,
+    DROP COLUMN IF EXISTS vendor_reply,
+    DROP COLUMN IF EXISTS report_count,
+    DROP COLUMN IF EXISTS moderation_reason,
+    DROP COLUMN IF EXISTS status;
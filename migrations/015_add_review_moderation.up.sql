@@ -0,0 +1,44 @@
+-- Review moderation status, reports, helpful votes, vendor replies and the moderation audit log
+
+ALTER TABLE reviews
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published'
+        CHECK (status IN ('pending', 'published', 'rejected')),
+    ADD COLUMN IF NOT EXISTS moderation_reason TEXT,
+    ADD COLUMN IF NOT EXISTS report_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS vendor_reply TEXT,
+    ADD COLUMN IF NOT EXISTS vendor_replied_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_reviews_status ON reviews(status, created_at);
+
+-- One helpful vote per user and review, counted in reviews.helpful_count
+CREATE TABLE IF NOT EXISTS review_votes (
+    review_id UUID NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (review_id, user_id)
+);
+
+-- Customer reports of abusive reviews, counted in reviews.report_count
+CREATE TABLE IF NOT EXISTS review_reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    review_id UUID NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (review_id, reporter_id)
+);
+
+-- Every moderation action; actor_id is NULL for actions taken automatically.
+-- Entries are kept when the review is deleted.
+CREATE TABLE IF NOT EXISTS review_moderation_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    review_id UUID NOT NULL,
+    action VARCHAR(30) NOT NULL,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_moderation_log_review ON review_moderation_log(review_id, created_at DESC);
@@ -0,0 +1,4 @@
+-- Rollback wishlists
+
+DROP TABLE IF EXISTS wishlist_items;
+DROP TABLE IF EXISTS wishlists;
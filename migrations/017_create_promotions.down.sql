@@ -0,0 +1,4 @@
+-- Rollback promotions
+
+DROP TABLE IF EXISTS promotion_redemptions;
+DROP TABLE IF EXISTS promotions;
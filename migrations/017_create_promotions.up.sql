@@ -0,0 +1,46 @@
+-- Promotions: coupons and automatic discounts with scoping, limits and redemptions
+
+CREATE TABLE IF NOT EXISTS promotions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(50), -- NULL for automatic promotions applied without a coupon
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('percentage', 'fixed_amount', 'free_shipping', 'buy_x_get_y')),
+    value DECIMAL(10,2) NOT NULL DEFAULT 0, -- Percent off, or amount off for fixed_amount
+    max_discount DECIMAL(10,2),
+    min_subtotal DECIMAL(10,2),
+    buy_quantity INTEGER CHECK (buy_quantity > 0),
+    get_quantity INTEGER CHECK (get_quantity > 0),
+    vendor_id UUID REFERENCES vendors(id) ON DELETE CASCADE, -- Set for vendor-managed promotions
+    product_ids INTEGER[] NOT NULL DEFAULT '{}', -- Numeric product IDs
+    category_ids UUID[] NOT NULL DEFAULT '{}',
+    stackable BOOLEAN NOT NULL DEFAULT FALSE,
+    usage_limit INTEGER CHECK (usage_limit > 0),
+    per_customer_limit INTEGER CHECK (per_customer_limit > 0),
+    usage_count INTEGER NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (type <> 'buy_x_get_y' OR (buy_quantity IS NOT NULL AND get_quantity IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promotions_code ON promotions(UPPER(code)) WHERE code IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_promotions_vendor ON promotions(vendor_id);
+CREATE INDEX IF NOT EXISTS idx_promotions_automatic ON promotions(is_active) WHERE code IS NULL;
+
+-- One row per promotion applied to an order, recorded when the order is created
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    promotion_id UUID NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    customer_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    discount DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (promotion_id, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_customer ON promotion_redemptions(promotion_id, customer_id);
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_order ON promotion_redemptions(order_id);
@@ -0,0 +1,6 @@
+-- Rollback sale prices and price history
+
+DROP TABLE IF EXISTS sale_prices;
+DROP TRIGGER IF EXISTS products_price_history_trigger ON products;
+DROP FUNCTION IF EXISTS products_price_history_record();
+DROP TABLE IF EXISTS price_history;
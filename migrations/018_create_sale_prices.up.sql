@@ -0,0 +1,64 @@
+-- Scheduled sale prices and a log of every product price change
+
+-- Every change to a product's price or compare_price is recorded by trigger. The
+-- reason is taken from the smrtmart.price_change_reason setting of the
+-- transaction, so sale switches can be told apart from manual edits.
+CREATE TABLE IF NOT EXISTS price_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price DECIMAL(10,2) NOT NULL,
+    compare_price DECIMAL(10,2),
+    reason VARCHAR(20) NOT NULL DEFAULT 'manual' CHECK (reason IN ('initial', 'manual', 'sale_start', 'sale_end')),
+    changed_at TIMESTAMP NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_history_product ON price_history(product_id, changed_at);
+
+CREATE OR REPLACE FUNCTION products_price_history_record() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.price IS NOT DISTINCT FROM NEW.price
+        AND OLD.compare_price IS NOT DISTINCT FROM NEW.compare_price THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO price_history (product_id, price, compare_price, reason)
+    VALUES (
+        NEW.id, NEW.price, NEW.compare_price,
+        CASE WHEN TG_OP = 'INSERT' THEN 'initial'
+             ELSE COALESCE(NULLIF(current_setting('smrtmart.price_change_reason', true), ''), 'manual')
+        END
+    );
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS products_price_history_trigger ON products;
+CREATE TRIGGER products_price_history_trigger
+    AFTER INSERT OR UPDATE OF price, compare_price ON products
+    FOR EACH ROW EXECUTE FUNCTION products_price_history_record();
+
+-- Seed the history with the current prices of existing products
+INSERT INTO price_history (product_id, price, compare_price, reason, changed_at)
+SELECT id, price, compare_price, 'initial', COALESCE(updated_at, created_at, CURRENT_TIMESTAMP)
+FROM products;
+
+-- A sale replaces the product price between starts_at and ends_at. The prices it
+-- replaced are kept while it is active and restored when it ends.
+CREATE TABLE IF NOT EXISTS sale_prices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sale_price DECIMAL(10,2) NOT NULL CHECK (sale_price > 0),
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'active', 'ended', 'cancelled')),
+    original_price DECIMAL(10,2),
+    original_compare_price DECIMAL(10,2),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sale_prices_product ON sale_prices(product_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_sale_prices_due ON sale_prices(status, starts_at, ends_at) WHERE status IN ('scheduled', 'active');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sale_prices_active ON sale_prices(product_id) WHERE status = 'active';
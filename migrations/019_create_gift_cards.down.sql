@@ -0,0 +1,5 @@
+-- Rollback gift cards and store credit
+
+DROP TABLE IF EXISTS balance_transactions;
+DROP TABLE IF EXISTS store_credit_accounts;
+DROP TABLE IF EXISTS gift_cards;
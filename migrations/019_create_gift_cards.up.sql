@@ -0,0 +1,45 @@
+-- Gift cards, per-user store credit and a transaction ledger for both balances
+
+CREATE TABLE IF NOT EXISTS gift_cards (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(32) NOT NULL,
+    initial_balance DECIMAL(10,2) NOT NULL CHECK (initial_balance > 0),
+    balance DECIMAL(10,2) NOT NULL CHECK (balance >= 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'disabled')),
+    expires_at TIMESTAMP,
+    recipient_email VARCHAR(255),
+    message TEXT,
+    issued_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Codes are matched ignoring case and dashes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_gift_cards_code ON gift_cards(REPLACE(UPPER(code), '-', ''));
+
+CREATE TABLE IF NOT EXISTS store_credit_accounts (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    balance DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every balance change of a gift card or store credit account. amount is signed
+-- and reference ties checkout redemptions to the release that may undo them.
+CREATE TABLE IF NOT EXISTS balance_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    gift_card_id UUID REFERENCES gift_cards(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES store_credit_accounts(user_id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('issue', 'credit', 'redeem', 'release', 'adjust')),
+    amount DECIMAL(10,2) NOT NULL,
+    balance_after DECIMAL(10,2) NOT NULL,
+    reference VARCHAR(100),
+    note TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((gift_card_id IS NULL) <> (user_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_transactions_gift_card ON balance_transactions(gift_card_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_balance_transactions_user ON balance_transactions(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_balance_transactions_reference ON balance_transactions(reference) WHERE reference IS NOT NULL;
@@ -0,0 +1,4 @@
+-- Rollback shipping zones and methods
+
+DROP TABLE IF EXISTS shipping_methods;
+DROP TABLE IF EXISTS shipping_zones;
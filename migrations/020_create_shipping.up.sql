@@ -0,0 +1,50 @@
+-- Admin-managed shipping zones and rated shipping methods
+
+-- regions is a list of {"country", "postal_from", "postal_to"}; a region without
+-- postal bounds covers the whole country. Zones are matched in priority order.
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    regions JSONB NOT NULL DEFAULT '[]',
+    priority INTEGER NOT NULL DEFAULT 100,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- rate = base_rate + rate_per_kg per started kilogram of billable weight, or 0
+-- once the order subtotal reaches free_shipping_threshold
+CREATE TABLE IF NOT EXISTS shipping_methods (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    zone_id UUID NOT NULL REFERENCES shipping_zones(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    base_rate DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (base_rate >= 0),
+    rate_per_kg DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (rate_per_kg >= 0),
+    min_weight DECIMAL(10,3),
+    max_weight DECIMAL(10,3),
+    free_shipping_threshold DECIMAL(10,2),
+    min_delivery_days INTEGER,
+    max_delivery_days INTEGER,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipping_methods_zone ON shipping_methods(zone_id, sort_order);
+
+-- Seed the options checkout offered before zones existed
+WITH zone AS (
+    INSERT INTO shipping_zones (name, regions, priority)
+    SELECT 'Default', (
+        SELECT jsonb_agg(jsonb_build_object('country', country))
+        FROM unnest(ARRAY['US', 'CA', 'GB', 'DE', 'FR', 'ES', 'IT', 'NL', 'BE', 'AT', 'CH', 'SE', 'NO', 'DK', 'FI']) AS country
+    ), 1000
+    WHERE NOT EXISTS (SELECT 1 FROM shipping_zones)
+    RETURNING id
+)
+INSERT INTO shipping_methods (zone_id, name, base_rate, min_delivery_days, max_delivery_days, sort_order)
+SELECT id, 'Free Shipping', 0, NULL, NULL, 0 FROM zone
+UNION ALL
+SELECT id, 'Express Shipping', 9.99, 1, 3, 1 FROM zone;
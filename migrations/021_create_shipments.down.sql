@@ -0,0 +1,5 @@
+-- Rollback shipments and tracking
+
+DROP TABLE IF EXISTS shipment_events;
+DROP TABLE IF EXISTS shipment_items;
+DROP TABLE IF EXISTS shipments;
@@ -0,0 +1,54 @@
+-- Shipments of orders or vendor sub-orders, the items in them and their tracking events
+
+CREATE TABLE IF NOT EXISTS shipments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    vendor_id UUID REFERENCES vendors(id) ON DELETE SET NULL, -- NULL when items of several vendors ship together
+    carrier VARCHAR(30) NOT NULL,
+    service VARCHAR(50),
+    tracking_number VARCHAR(100),
+    label_reference VARCHAR(100),
+    label_data BYTEA,
+    label_format VARCHAR(50),
+    status VARCHAR(20) NOT NULL DEFAULT 'created'
+        CHECK (status IN ('created', 'in_transit', 'out_for_delivery', 'delivered', 'exception', 'returned', 'cancelled')),
+    shipped_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP,
+    last_polled_at TIMESTAMP,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipments_order ON shipments(order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipments_tracking ON shipments(carrier, tracking_number)
+    WHERE tracking_number IS NOT NULL AND status <> 'cancelled';
+-- Shipments the tracking poller still has to follow
+CREATE INDEX IF NOT EXISTS idx_shipments_trackable ON shipments(last_polled_at NULLS FIRST)
+    WHERE tracking_number IS NOT NULL AND status NOT IN ('delivered', 'returned', 'cancelled');
+
+CREATE TABLE IF NOT EXISTS shipment_items (
+    shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    order_item_id UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (shipment_id, order_item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipment_items_order_item ON shipment_items(order_item_id);
+
+-- Tracking events as reported by the carrier, or entered by hand for carriers
+-- without an integration. Polling the same events again is a no-op.
+CREATE TABLE IF NOT EXISTS shipment_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL
+        CHECK (status IN ('created', 'in_transit', 'out_for_delivery', 'delivered', 'exception', 'returned')),
+    code VARCHAR(50) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    location VARCHAR(255),
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (shipment_id, occurred_at, code, description)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipment_events_shipment ON shipment_events(shipment_id, occurred_at);
@@ -0,0 +1,3 @@
+-- Rollback email outbox
+
+DROP TABLE IF EXISTS email_outbox;
@@ -0,0 +1,3 @@
+-- Rollback background job queue
+
+DROP TABLE IF EXISTS jobs;
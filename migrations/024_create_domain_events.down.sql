@@ -0,0 +1,3 @@
+-- Rollback domain events
+
+DROP TABLE IF EXISTS domain_events;
@@ -0,0 +1,19 @@
+-- Outbox of domain events. Events are written in the same transaction as the
+-- change they describe and fanned out to subscribers by a background dispatcher.
+
+CREATE TABLE IF NOT EXISTS domain_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    seq BIGSERIAL NOT NULL, -- Dispatch order
+    type VARCHAR(100) NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_domain_events_seq ON domain_events(seq);
+-- Events the dispatcher still has to fan out
+CREATE INDEX IF NOT EXISTS idx_domain_events_pending ON domain_events(seq) WHERE dispatched_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_domain_events_aggregate ON domain_events(aggregate_type, aggregate_id, seq);
+CREATE INDEX IF NOT EXISTS idx_domain_events_type ON domain_events(type, seq DESC);
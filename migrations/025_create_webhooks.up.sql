@@ -0,0 +1,40 @@
+-- Outbound webhooks. Platform endpoints (no vendor) receive every subscribed
+-- event; vendor endpoints only receive events about that vendor.
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vendor_id UUID REFERENCES vendors(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    description TEXT,
+    secret VARCHAR(100) NOT NULL, -- Signs every delivery
+    event_types TEXT[] NOT NULL DEFAULT '{}', -- Empty for every type
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    disabled_at TIMESTAMP, -- Set when the endpoint was disabled for failing
+    disabled_reason TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_vendor ON webhook_endpoints(vendor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_active ON webhook_endpoints(vendor_id) WHERE is_active;
+
+-- One row per delivery attempt
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    endpoint_id UUID NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL REFERENCES domain_events(id) ON DELETE CASCADE,
+    event_type VARCHAR(100) NOT NULL,
+    attempt INTEGER NOT NULL,
+    redelivery BOOLEAN NOT NULL DEFAULT FALSE,
+    success BOOLEAN NOT NULL,
+    response_status INTEGER, -- NULL if no response was received
+    response_body TEXT, -- The start of the response
+    error TEXT,
+    duration_ms INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint ON webhook_deliveries(endpoint_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries(event_id);
@@ -0,0 +1,7 @@
+-- Rollback checkout redemptions
+
+DELETE FROM promotion_redemptions WHERE order_id IS NULL;
+DROP INDEX IF EXISTS idx_promotion_redemptions_checkout;
+ALTER TABLE promotion_redemptions DROP CONSTRAINT IF EXISTS promotion_redemptions_order_or_checkout;
+ALTER TABLE promotion_redemptions DROP COLUMN IF EXISTS checkout_reference;
+ALTER TABLE promotion_redemptions ALTER COLUMN order_id SET NOT NULL;
@@ -0,0 +1,11 @@
+-- Promotions are redeemed when a checkout is paid, before an order exists, so
+-- redemptions are keyed by the checkout: the Stripe session ID, or the tender
+-- reference of a checkout paid in full with gift cards and store credit
+
+ALTER TABLE promotion_redemptions ALTER COLUMN order_id DROP NOT NULL;
+ALTER TABLE promotion_redemptions ADD COLUMN IF NOT EXISTS checkout_reference VARCHAR(255);
+ALTER TABLE promotion_redemptions ADD CONSTRAINT promotion_redemptions_order_or_checkout
+    CHECK (order_id IS NOT NULL OR checkout_reference IS NOT NULL);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promotion_redemptions_checkout
+    ON promotion_redemptions(promotion_id, checkout_reference) WHERE checkout_reference IS NOT NULL;
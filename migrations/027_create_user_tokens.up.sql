@@ -0,0 +1,16 @@
+-- Single-use tokens emailed to users: email verification and password resets.
+-- Only a hash of each token is stored.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('verify_email', 'password_reset')),
+    token_hash CHAR(64) NOT NULL UNIQUE, -- Hex SHA-256 of the token
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP, -- Set when the token was used or replaced
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user ON user_tokens(user_id, purpose) WHERE used_at IS NULL;
@@ -0,0 +1,7 @@
+-- Rollback payout batch claims
+
+UPDATE payout_batches SET status = 'pending' WHERE status = 'processing';
+ALTER TABLE payout_batches DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE payout_batches DROP CONSTRAINT IF EXISTS payout_batches_status_check;
+ALTER TABLE payout_batches ADD CONSTRAINT payout_batches_status_check
+    CHECK (status IN ('pending', 'completed', 'partially_failed'));
@@ -0,0 +1,8 @@
+-- A batch is claimed by moving it to processing, so two executions cannot
+-- transfer it at once. The claim expires, so a batch left processing by a
+-- crashed execution can be executed again.
+
+ALTER TABLE payout_batches DROP CONSTRAINT IF EXISTS payout_batches_status_check;
+ALTER TABLE payout_batches ADD CONSTRAINT payout_batches_status_check
+    CHECK (status IN ('pending', 'processing', 'completed', 'partially_failed'));
+ALTER TABLE payout_batches ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
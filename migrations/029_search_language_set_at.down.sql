@@ -0,0 +1,3 @@
+-- Rollback search language seeding
+
+ALTER TABLE search_settings DROP COLUMN IF EXISTS language_set_at;
@@ -0,0 +1,6 @@
+-- When the search language was chosen, by an admin or by seeding it from
+-- SEARCH_LANGUAGE. While it is NULL the language is the migration default and
+-- the configured language replaces it on startup; after that the stored
+-- setting is kept.
+
+ALTER TABLE search_settings ADD COLUMN IF NOT EXISTS language_set_at TIMESTAMP;
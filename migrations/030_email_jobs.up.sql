@@ -0,0 +1,23 @@
+-- Transactional email is sent by email.send jobs instead of a separate outbox.
+-- The keys of messages sent once per reference (an order confirmation, a refund)
+-- are kept for good, since a finished job no longer holds its unique key.
+
+CREATE TABLE IF NOT EXISTS email_dedupe_keys (
+    dedupe_key VARCHAR(255) PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO email_dedupe_keys (dedupe_key, created_at)
+SELECT dedupe_key, created_at FROM email_outbox WHERE dedupe_key IS NOT NULL
+ON CONFLICT (dedupe_key) DO NOTHING;
+
+-- Messages still waiting to be sent move to the job queue with fresh attempts
+INSERT INTO jobs (kind, payload, max_attempts, run_at, created_at)
+SELECT 'email.send',
+       jsonb_build_object('kind', kind, 'to', recipient, 'language', language,
+                          'subject', subject, 'text', text_body, 'html', html_body),
+       8, next_attempt_at, created_at
+FROM email_outbox
+WHERE status = 'pending';
+
+DROP TABLE IF EXISTS email_outbox;